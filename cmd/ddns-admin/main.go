@@ -6,11 +6,19 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/grocky/ddns-service/internal/admin"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/handlers"
+	"github.com/grocky/ddns-service/internal/repository"
 )
 
 const (
@@ -30,6 +38,16 @@ func main() {
 	switch command {
 	case "change-subdomain":
 		changeSubdomainCmd(os.Args[2:])
+	case "audit-subdomains":
+		auditSubdomainsCmd(os.Args[2:])
+	case "create-operator":
+		createOperatorCmd(os.Args[2:])
+	case "revoke-operator":
+		revokeOperatorCmd(os.Args[2:])
+	case "set-rate-limit-plan":
+		setRateLimitPlanCmd(os.Args[2:])
+	case "set-owner-rate-limit":
+		setOwnerRateLimitCmd(os.Args[2:])
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -46,11 +64,21 @@ Usage:
   ddns-admin <command> [options]
 
 Commands:
-  change-subdomain  Change the subdomain for an owner's location
-  help              Show this help message
+  change-subdomain      Change the subdomain for an owner's location
+  audit-subdomains      Report subdomains shared by more than one mapping
+  create-operator       Issue an operator key for the /admin API
+  revoke-operator       Revoke an operator key
+  set-rate-limit-plan   Create or replace a rate limit plan
+  set-owner-rate-limit  Assign an owner's rate limit plan or policy
+  help                  Show this help message
 
 Examples:
   ddns-admin change-subdomain --owner grocky --location home --subdomain home
+  ddns-admin audit-subdomains
+  ddns-admin create-operator --name alice
+  ddns-admin revoke-operator --key-id 0123456789ab
+  ddns-admin set-rate-limit-plan --name isp-burst --max-changes 10 --window 1h --algorithm sliding
+  ddns-admin set-owner-rate-limit --owner grocky --plan isp-burst
 
 Run 'ddns-admin <command> --help' for more information on a command.`)
 }
@@ -61,7 +89,6 @@ func changeSubdomainCmd(args []string) {
 	owner := fs.String("owner", "", "Owner ID (required)")
 	location := fs.String("location", "", "Location name (required)")
 	subdomain := fs.String("subdomain", "", "New subdomain (required, without domain suffix)")
-	tableName := fs.String("table", defaultTableName, "DynamoDB table name")
 	hostedZoneID := fs.String("zone-id", defaultHostedZoneID, "Route53 hosted zone ID")
 	dryRun := fs.Bool("dry-run", false, "Show what would be changed without making changes")
 	verbose := fs.Bool("verbose", false, "Enable verbose logging")
@@ -69,8 +96,10 @@ func changeSubdomainCmd(args []string) {
 	fs.Usage = func() {
 		fmt.Println(`Change the subdomain for an owner's location.
 
-This command updates both Route53 and DynamoDB to use a custom subdomain
-instead of the auto-generated hash-based subdomain.
+This command claims the new subdomain, publishes the location's address,
+wildcard and custom records under it, saves the mapping and then removes the
+old records. It fails without changing anything if another location holds the
+subdomain.
 
 Usage:
   ddns-admin change-subdomain [options]
@@ -97,6 +126,78 @@ Examples:
 		os.Exit(1)
 	}
 
+	req := domain.ChangeSubdomainRequest{Subdomain: *subdomain}.Normalize()
+	if err := req.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run mode - no changes will be made")
+		fmt.Println()
+		fmt.Printf("Would change subdomain for:\n")
+		fmt.Printf("  Owner:    %s\n", *owner)
+		fmt.Printf("  Location: %s\n", *location)
+		fmt.Printf("  New subdomain: %s\n", dns.FormatFQDN(req.Subdomain))
+		return
+	}
+
+	ctx := context.Background()
+	repo, logger := operatorRepository(ctx, *verbose)
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		logger.Error("failed to load AWS config", "error", err)
+		os.Exit(1)
+	}
+	// Route53Service retries with its own policy and circuit breaker, so the SDK must not retry as well
+	route53Client := route53.NewFromConfig(cfg, func(o *route53.Options) {
+		o.Retryer = aws.NopRetryer{}
+	})
+	dnsSvc := dns.NewRoute53Service(route53Client, *hostedZoneID, logger)
+
+	// Execute the change through the same code path as the /admin API
+	mapping, oldSubdomain, reqErr := handlers.ChangeSubdomain(ctx, *owner, *location, req.Subdomain, repo, dnsSvc, logger)
+	if reqErr != nil {
+		logger.Error("failed to change subdomain", "error", reqErr, "status", reqErr.Status)
+		os.Exit(1)
+	}
+
+	fmt.Println("Subdomain changed successfully!")
+	fmt.Println()
+	fmt.Printf("  Old: %s\n", dns.FormatFQDN(oldSubdomain))
+	fmt.Printf("  New: %s\n", dns.FormatFQDN(mapping.Subdomain))
+	fmt.Printf("  IP:  %s\n", mapping.IP)
+	fmt.Println()
+	fmt.Println("DNS propagation may take a few minutes.")
+}
+
+func auditSubdomainsCmd(args []string) {
+	fs := flag.NewFlagSet("audit-subdomains", flag.ExitOnError)
+
+	tableName := fs.String("table", defaultTableName, "DynamoDB table name")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+
+	fs.Usage = func() {
+		fmt.Println(`Report subdomains shared by more than one owner/location.
+
+This command scans every mapping. Mappings without a stored subdomain predate
+the configurable hash and are hashed with the original 8 character MD5 scheme.
+
+Exits with status 2 when collisions are found.
+
+Usage:
+  ddns-admin audit-subdomains [options]
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
 	// Set up logger
 	logLevel := slog.LevelInfo
 	if *verbose {
@@ -112,41 +213,300 @@ Examples:
 		os.Exit(1)
 	}
 
-	// Create clients
-	dynamoClient := dynamodb.NewFromConfig(cfg)
-	route53Client := route53.NewFromConfig(cfg)
+	svc := admin.NewAuditService(dynamodb.NewFromConfig(cfg), *tableName, logger)
+
+	result, err := svc.AuditSubdomains(ctx)
+	if err != nil {
+		logger.Error("failed to audit subdomains", "error", err)
+		os.Exit(1)
+	}
 
-	// Create service
-	svc := admin.NewSubdomainService(dynamoClient, route53Client, *tableName, *hostedZoneID, logger)
+	fmt.Printf("Scanned %d mappings\n", result.MappingsScanned)
 
-	input := admin.ChangeSubdomainInput{
-		OwnerID:      *owner,
-		Location:     *location,
-		NewSubdomain: *subdomain,
+	if len(result.Unstored) > 0 {
+		fmt.Println()
+		fmt.Printf("%d mappings have no stored subdomain (legacy hash computed at request time):\n", len(result.Unstored))
+		for _, ref := range result.Unstored {
+			fmt.Printf("  %s/%s -> %s\n", ref.OwnerID, ref.Location, dns.FormatFQDN(ref.Subdomain))
+		}
 	}
 
-	if *dryRun {
-		fmt.Println("Dry run mode - no changes will be made")
+	if len(result.Collisions) == 0 {
 		fmt.Println()
-		fmt.Printf("Would change subdomain for:\n")
-		fmt.Printf("  Owner:    %s\n", input.OwnerID)
-		fmt.Printf("  Location: %s\n", input.Location)
-		fmt.Printf("  New subdomain: %s.grocky.net\n", input.NewSubdomain)
+		fmt.Println("No subdomain collisions found.")
 		return
 	}
 
-	// Execute the change
-	result, err := svc.ChangeSubdomain(ctx, input)
+	fmt.Println()
+	fmt.Printf("Found %d subdomain collisions:\n", len(result.Collisions))
+	for _, c := range result.Collisions {
+		fmt.Printf("  %s\n", dns.FormatFQDN(c.Subdomain))
+		for _, ref := range c.Mappings {
+			fmt.Printf("    %s/%s\n", ref.OwnerID, ref.Location)
+		}
+	}
+	fmt.Println()
+	fmt.Println("Resolve each collision with 'ddns-admin change-subdomain'.")
+	os.Exit(2)
+}
+
+func createOperatorCmd(args []string) {
+	fs := flag.NewFlagSet("create-operator", flag.ExitOnError)
+
+	name := fs.String("name", "", "Operator name recorded in the audit log (required)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+
+	fs.Usage = func() {
+		fmt.Println(`Issue an operator key for the /admin API.
+
+The key is printed once; only its hash is stored.
+
+Usage:
+  ddns-admin create-operator [options]
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if strings.TrimSpace(*name) == "" {
+		fmt.Fprintln(os.Stderr, "Error: --name is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	repo, logger := operatorRepository(ctx, *verbose)
+
+	key, keyID, err := auth.GenerateOperatorKey()
 	if err != nil {
-		logger.Error("failed to change subdomain", "error", err)
+		logger.Error("failed to generate operator key", "error", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Subdomain changed successfully!")
+	operator := domain.Operator{
+		KeyID:     keyID,
+		Name:      strings.TrimSpace(*name),
+		KeyHash:   auth.HashAPIKey(key),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := repo.CreateOperator(ctx, operator); err != nil {
+		logger.Error("failed to create operator", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Operator created.")
 	fmt.Println()
-	fmt.Printf("  Old: %s\n", result.OldFQDN)
-	fmt.Printf("  New: %s\n", result.NewFQDN)
-	fmt.Printf("  IP:  %s\n", result.IP)
+	fmt.Printf("  Name:   %s\n", operator.Name)
+	fmt.Printf("  Key ID: %s\n", keyID)
+	fmt.Printf("  Key:    %s\n", key)
 	fmt.Println()
-	fmt.Println("DNS propagation may take a few minutes.")
+	fmt.Println("Store the key now; it cannot be shown again.")
+}
+
+func revokeOperatorCmd(args []string) {
+	fs := flag.NewFlagSet("revoke-operator", flag.ExitOnError)
+
+	keyID := fs.String("key-id", "", "Key ID of the operator key to revoke (required)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+
+	fs.Usage = func() {
+		fmt.Println(`Revoke an operator key.
+
+Usage:
+  ddns-admin revoke-operator [options]
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *keyID == "" {
+		fmt.Fprintln(os.Stderr, "Error: --key-id is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	repo, logger := operatorRepository(ctx, *verbose)
+
+	if err := repo.DeleteOperator(ctx, *keyID); err != nil {
+		if repository.IsOperatorNotFound(err) {
+			fmt.Fprintf(os.Stderr, "Error: no operator with key ID %s\n", *keyID)
+			os.Exit(1)
+		}
+		logger.Error("failed to revoke operator", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Operator key %s revoked.\n", *keyID)
+}
+
+func setRateLimitPlanCmd(args []string) {
+	fs := flag.NewFlagSet("set-rate-limit-plan", flag.ExitOnError)
+
+	name := fs.String("name", "", "Plan name (required); \"default\" applies to owners without a plan")
+	maxChanges := fs.Int("max-changes", 0, "IP changes allowed per window (required)")
+	window := fs.Duration("window", time.Hour, "Window length, between 1m and 168h")
+	algorithm := fs.String("algorithm", domain.RateLimitFixedWindow, "Rate limit algorithm (fixed or sliding)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+
+	fs.Usage = func() {
+		fmt.Println(`Create or replace a rate limit plan.
+
+Owners on the plan get the new policy on their next IP change. This command
+writes to DynamoDB directly; changes made through the /admin API are audited.
+
+Usage:
+  ddns-admin set-rate-limit-plan [options]
+
+Options:`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	plan := domain.RateLimitPlan{
+		Name:            strings.TrimSpace(*name),
+		RateLimitPolicy: rateLimitPolicyFromFlags(*maxChanges, *window, *algorithm),
+		UpdatedAt:       time.Now().UTC(),
+	}
+	err := domain.ValidateRateLimitPlanName(plan.Name)
+	if err == nil {
+		err = plan.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	repo, logger := operatorRepository(ctx, *verbose)
+
+	if err := repo.PutRateLimitPlan(ctx, plan); err != nil {
+		logger.Error("failed to save rate limit plan", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Rate limit plan %s saved: %s (%s window).\n", plan.Name, plan.RateLimitPolicy, plan.Algorithm)
+}
+
+func setOwnerRateLimitCmd(args []string) {
+	fs := flag.NewFlagSet("set-owner-rate-limit", flag.ExitOnError)
+
+	owner := fs.String("owner", "", "Owner ID (required)")
+	plan := fs.String("plan", "", "Plan to assign; empty for the default plan")
+	maxChanges := fs.Int("max-changes", 0, "Override the plan with this many IP changes per window; 0 for no override")
+	window := fs.Duration("window", time.Hour, "Window length of the override, between 1m and 168h")
+	algorithm := fs.String("algorithm", domain.RateLimitFixedWindow, "Algorithm of the override (fixed or sliding)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+
+	fs.Usage = func() {
+		fmt.Println(`Assign an owner's rate limit plan, optionally overriding it for this owner.
+
+Running the command without --max-changes removes any existing override.
+
+Usage:
+  ddns-admin set-owner-rate-limit [options]
+
+Options:`)
+		fs.PrintDefaults()
+		fmt.Println(`
+Examples:
+  # Put grocky on the isp-burst plan
+  ddns-admin set-owner-rate-limit --owner grocky --plan isp-burst
+
+  # Limit an abusive owner to one change a day
+  ddns-admin set-owner-rate-limit --owner spammer --max-changes 1 --window 24h --algorithm sliding`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *owner == "" {
+		fmt.Fprintln(os.Stderr, "Error: --owner is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	req := domain.SetOwnerRateLimitRequest{Plan: *plan}
+	if *maxChanges != 0 {
+		policy := rateLimitPolicyFromFlags(*maxChanges, *window, *algorithm)
+		req.Policy = &policy
+	}
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	repo, logger := operatorRepository(ctx, *verbose)
+
+	if req.Plan != "" {
+		if _, err := repo.GetRateLimitPlan(ctx, req.Plan); err != nil {
+			if repository.IsRateLimitPlanNotFound(err) {
+				fmt.Fprintf(os.Stderr, "Error: no rate limit plan named %s\n", req.Plan)
+				os.Exit(1)
+			}
+			logger.Error("failed to get rate limit plan", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := repo.SetOwnerRateLimit(ctx, *owner, req.Plan, req.Policy); err != nil {
+		if repository.IsOwnerNotFound(err) {
+			fmt.Fprintf(os.Stderr, "Error: no owner with ID %s\n", *owner)
+			os.Exit(1)
+		}
+		logger.Error("failed to set owner rate limit", "error", err)
+		os.Exit(1)
+	}
+
+	planName := req.Plan
+	if planName == "" {
+		planName = domain.DefaultRateLimitPlan
+	}
+	fmt.Printf("Owner %s is on the %s plan.\n", *owner, planName)
+	if req.Policy != nil {
+		fmt.Printf("Override: %s (%s window).\n", req.Policy, req.Policy.Algorithm)
+	}
+}
+
+// rateLimitPolicyFromFlags builds a rate limit policy from command-line flags.
+func rateLimitPolicyFromFlags(maxChanges int, window time.Duration, algorithm string) domain.RateLimitPolicy {
+	return domain.RateLimitPolicy{
+		MaxChanges:    maxChanges,
+		WindowSeconds: int(window / time.Second),
+		Algorithm:     algorithm,
+	}.Normalize()
+}
+
+// operatorRepository connects to DynamoDB for the commands that manage
+// operators and rate limits.
+func operatorRepository(ctx context.Context, verbose bool) (*repository.DynamoDBRepository, *slog.Logger) {
+	logLevel := slog.LevelInfo
+	if verbose {
+		logLevel = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		logger.Error("failed to load AWS config", "error", err)
+		os.Exit(1)
+	}
+
+	return repository.NewDynamoDBRepository(dynamodb.NewFromConfig(cfg), logger), logger
 }
@@ -21,6 +21,10 @@ type Config struct {
 	IPv6     bool
 	Verbose  bool
 	Cron     bool
+	Sign     bool
+
+	// SigningSecret is the secret issued with the API key, needed by Sign.
+	SigningSecret string
 
 	// ACME mode flags (for certbot integration)
 	ACMEAuth        bool
@@ -53,6 +57,8 @@ func LoadConfig() (Config, error) {
 	ipv6 := flag.Bool("6", false, "Use IPv6 instead of IPv4")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
 	cron := flag.Bool("cron", false, "Run once and exit (for crontab)")
+	sign := flag.Bool("sign", false, "Sign requests with the signing secret instead of sending the API key")
+	signingSecret := flag.String("signing-secret", "", "Signing secret issued with the API key (for --sign)")
 
 	// ACME mode flags
 	acmeAuth := flag.Bool("acme-auth", false, "Run as certbot auth hook (creates TXT record)")
@@ -66,6 +72,7 @@ func LoadConfig() (Config, error) {
 	cfg.APIKey = os.Getenv("DDNS_API_KEY")
 	cfg.Owner = os.Getenv("DDNS_OWNER")
 	cfg.Location = os.Getenv("DDNS_LOCATION")
+	cfg.SigningSecret = os.Getenv("DDNS_SIGNING_SECRET")
 
 	// Override with flags (higher priority)
 	if *apiKey != "" {
@@ -77,6 +84,9 @@ func LoadConfig() (Config, error) {
 	if *location != "" {
 		cfg.Location = *location
 	}
+	if *signingSecret != "" {
+		cfg.SigningSecret = *signingSecret
+	}
 
 	cfg.APIURL = *apiURL
 	cfg.StateDir = *stateDir
@@ -84,6 +94,7 @@ func LoadConfig() (Config, error) {
 	cfg.IPv6 = *ipv6
 	cfg.Verbose = *verbose
 	cfg.Cron = *cron
+	cfg.Sign = *sign
 
 	// ACME mode settings
 	cfg.ACMEAuth = *acmeAuth
@@ -105,6 +116,9 @@ func (c Config) Validate() error {
 	if c.Location == "" {
 		return errors.New("location is required (set DDNS_LOCATION or use --location)")
 	}
+	if c.Sign && c.SigningSecret == "" {
+		return errors.New("--sign requires the signing secret (set DDNS_SIGNING_SECRET or use --signing-secret)")
+	}
 
 	// Check for conflicting modes
 	modeCount := 0
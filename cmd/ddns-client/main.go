@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -79,6 +81,7 @@ Environment Variables:
   DDNS_API_KEY         API key for authentication (preferred over --api-key)
   DDNS_OWNER           Owner ID
   DDNS_LOCATION        Location name
+  DDNS_SIGNING_SECRET  Signing secret issued with the API key (for --sign)
   CERTBOT_VALIDATION   TXT value (set by certbot in --acme-auth mode)
 
 Flags:`)
@@ -97,6 +100,10 @@ Examples:
   # IPv6 mode with verbose logging
   ddns-client -6 --verbose
 
+  # Sign requests instead of sending the API key
+  export DDNS_SIGNING_SECRET=ddns_ss_...
+  ddns-client --sign
+
   # Certbot integration (Let's Encrypt)
   certbot certonly --manual --preferred-challenges dns \
     --manual-auth-hook "ddns-client --acme-auth" \
@@ -118,10 +125,13 @@ func runDaemon(cfg Config, logger *slog.Logger) error {
 	apiClient := client.New(client.Config{
 		APIURL: cfg.APIURL,
 		APIKey: cfg.APIKey,
+		Sign:   cfg.Sign,
+
+		SigningSecret: cfg.SigningSecret,
 	})
 
-	// Track last known IP in memory
-	var lastIP string
+	// Track last known IP and rate limit in memory
+	var st updateState
 
 	// Set up signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -131,7 +141,7 @@ func runDaemon(cfg Config, logger *slog.Logger) error {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Initial check
-	if err := checkAndUpdate(ctx, cfg, apiClient, &lastIP, logger); err != nil {
+	if err := checkAndUpdate(ctx, cfg, apiClient, &st, logger); err != nil {
 		logger.Error("initial check failed", "error", err)
 		// Continue running even if initial check fails
 	}
@@ -142,7 +152,7 @@ func runDaemon(cfg Config, logger *slog.Logger) error {
 	for {
 		select {
 		case <-ticker.C:
-			if err := checkAndUpdate(ctx, cfg, apiClient, &lastIP, logger); err != nil {
+			if err := checkAndUpdate(ctx, cfg, apiClient, &st, logger); err != nil {
 				logger.Error("check failed", "error", err)
 			}
 
@@ -197,6 +207,9 @@ func runCron(cfg Config, logger *slog.Logger) error {
 	apiClient := client.New(client.Config{
 		APIURL: cfg.APIURL,
 		APIKey: cfg.APIKey,
+		Sign:   cfg.Sign,
+
+		SigningSecret: cfg.SigningSecret,
 	})
 
 	ctx, ctxCancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -207,6 +220,13 @@ func runCron(cfg Config, logger *slog.Logger) error {
 		return err
 	}
 
+	// A dampened IP isn't published yet, so keep reporting it
+	if resp.Dampened {
+		logDampened(resp, logger)
+		logRateLimit(resp.RateLimit, logger)
+		return nil
+	}
+
 	// Save new state
 	newState := &state.State{
 		IPHash:    state.HashIP(currentIP),
@@ -225,13 +245,22 @@ func runCron(cfg Config, logger *slog.Logger) error {
 	} else {
 		logger.Info("DNS unchanged (server already had this IP)")
 	}
+	logRateLimit(resp.RateLimit, logger)
 
 	return nil
 }
 
+// updateState is what daemon mode remembers between checks.
+type updateState struct {
+	lastIP string
+	// holdUntil defers updates until the server's rate limit resets.
+	holdUntil time.Time
+}
+
 // checkAndUpdate detects the current IP and updates DNS if it changed.
-// Used by daemon mode with in-memory state tracking.
-func checkAndUpdate(ctx context.Context, cfg Config, apiClient *client.Client, lastIP *string, logger *slog.Logger) error {
+// Used by daemon mode with in-memory state tracking. Once the server reports the
+// rate limit is used up, updates wait until it resets.
+func checkAndUpdate(ctx context.Context, cfg Config, apiClient *client.Client, st *updateState, logger *slog.Logger) error {
 	// Detect current IP
 	version := pubip.IPv4
 	if cfg.IPv6 {
@@ -246,24 +275,42 @@ func checkAndUpdate(ctx context.Context, cfg Config, apiClient *client.Client, l
 	logger.Debug("detected IP", "ip", currentIP)
 
 	// Check if IP changed
-	if currentIP == *lastIP {
+	if currentIP == st.lastIP {
 		logger.Debug("IP unchanged, skipping update")
 		return nil
 	}
 
+	if now := time.Now(); now.Before(st.holdUntil) {
+		logger.Info("IP changed, waiting for rate limit to reset",
+			"new", currentIP,
+			"resetIn", st.holdUntil.Sub(now).Round(time.Second),
+		)
+		return nil
+	}
+
 	logger.Info("IP changed, updating DNS",
-		"old", *lastIP,
+		"old", st.lastIP,
 		"new", currentIP,
 	)
 
 	// Call API with client-detected IP
 	resp, err := apiClient.UpdateDNS(ctx, cfg.Owner, cfg.Location, currentIP)
 	if err != nil {
+		var rateLimitErr *client.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			st.holdUntil = time.Now().Add(rateLimitWait(rateLimitErr))
+		}
 		return err
 	}
 
+	// A dampened IP isn't published yet, so keep reporting it
+	if resp.Dampened {
+		logDampened(resp, logger)
+		return nil
+	}
+
 	// Update last known IP
-	*lastIP = currentIP
+	st.lastIP = currentIP
 
 	if resp.Changed {
 		logger.Info("DNS updated successfully",
@@ -273,10 +320,55 @@ func checkAndUpdate(ctx context.Context, cfg Config, apiClient *client.Client, l
 	} else {
 		logger.Debug("DNS unchanged (server already had this IP)")
 	}
+	logRateLimit(resp.RateLimit, logger)
+
+	if resp.RateLimit != nil && resp.RateLimit.Remaining == 0 {
+		st.holdUntil = time.Now().Add(resp.RateLimit.Reset)
+	}
 
 	return nil
 }
 
+// logDampened logs that the server held back an IP change because the location
+// is flapping.
+func logDampened(resp *client.UpdateResponse, logger *slog.Logger) {
+	logger.Warn("IP change dampened, location is flapping",
+		"published", resp.IP,
+		"pending", resp.PendingIP,
+	)
+}
+
+// logRateLimit logs the server's rate limit status, warning once it is used up.
+func logRateLimit(rateLimit *client.RateLimit, logger *slog.Logger) {
+	if rateLimit == nil {
+		return
+	}
+	if rateLimit.Remaining == 0 {
+		logger.Warn("rate limit reached, further IP changes must wait",
+			"limit", rateLimit.Limit,
+			"resetIn", rateLimit.Reset,
+		)
+		return
+	}
+	logger.Debug("rate limit",
+		"limit", rateLimit.Limit,
+		"remaining", rateLimit.Remaining,
+		"resetIn", rateLimit.Reset,
+	)
+}
+
+// rateLimitWait returns how long to wait after a 429: the reported reset, else
+// Retry-After, else a minute.
+func rateLimitWait(err *client.RateLimitError) time.Duration {
+	if err.RateLimit != nil && err.RateLimit.Reset > 0 {
+		return err.RateLimit.Reset
+	}
+	if seconds, convErr := strconv.Atoi(err.RetryAfter); convErr == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Minute
+}
+
 // runACMEAuth runs as a certbot auth hook to create a TXT record.
 // Reads CERTBOT_VALIDATION from environment and creates the challenge record.
 func runACMEAuth(cfg Config, logger *slog.Logger) error {
@@ -294,6 +386,9 @@ func runACMEAuth(cfg Config, logger *slog.Logger) error {
 	apiClient := client.New(client.Config{
 		APIURL: cfg.APIURL,
 		APIKey: cfg.APIKey,
+		Sign:   cfg.Sign,
+
+		SigningSecret: cfg.SigningSecret,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
@@ -330,6 +425,9 @@ func runACMECleanup(cfg Config, logger *slog.Logger) error {
 	apiClient := client.New(client.Config{
 		APIURL: cfg.APIURL,
 		APIKey: cfg.APIKey,
+		Sign:   cfg.Sign,
+
+		SigningSecret: cfg.SigningSecret,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
@@ -10,29 +10,50 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/grocky/ddns-service/internal/auth"
 	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
 	"github.com/grocky/ddns-service/internal/email"
 	"github.com/grocky/ddns-service/internal/handlers"
 	"github.com/grocky/ddns-service/internal/repository"
 	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/signing"
+	"github.com/grocky/ddns-service/internal/token"
+	"github.com/grocky/ddns-service/internal/webhook"
 )
 
 var (
 	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
-	repo     repository.Repository
-	emailSvc email.Service
-	dnsSvc   dns.Service
-	initOnce sync.Once
-	initErr  error
+	repo       repository.Repository
+	emailSvc   email.Service
+	dnsSvc     dns.Service
+	webhookSvc webhook.Service
+	signer     *token.Signer
+	initOnce   sync.Once
+	initErr    error
+
+	// verificationWindow is how long new owners have to verify their email address.
+	verificationWindow = domain.DefaultVerificationWindow
+
+	// rotationGracePeriod is how long a rotated-out primary key keeps working.
+	rotationGracePeriod = domain.DefaultRotationGracePeriod
+
+	// requestLimits are the per-route request limits.
+	requestLimits = domain.DefaultRouteLimits
+
+	// acmeChallengeLimit is how many ACME challenges an owner may create per window.
+	acmeChallengeLimit = domain.DefaultACMEChallengeLimit
 )
 
 func initServices(ctx context.Context) error {
@@ -50,34 +71,259 @@ func initServices(ctx context.Context) error {
 		dynamoClient := dynamodb.NewFromConfig(cfg)
 		repo = repository.NewDynamoDBRepository(dynamoClient, logger)
 
-		// Initialize SES email service
-		sesClient := ses.NewFromConfig(cfg)
-		emailSvc = email.NewSESService(sesClient, logger)
+		// Initialize the email service, SES unless SMTP is configured
+		if err := configureEmail(cfg); err != nil {
+			logger.Error("invalid email configuration", "error", err)
+			initErr = err
+			return
+		}
 
 		// Initialize Route53 DNS service
 		hostedZoneID := os.Getenv("ROUTE53_HOSTED_ZONE_ID")
 		if hostedZoneID == "" {
 			logger.Warn("ROUTE53_HOSTED_ZONE_ID not set, DNS updates will fail")
 		}
-		route53Client := route53.NewFromConfig(cfg)
+		// Route53Service retries with its own policy and circuit breaker, so the SDK must not retry as well
+		route53Client := route53.NewFromConfig(cfg, func(o *route53.Options) {
+			o.Retryer = aws.NopRetryer{}
+		})
 		dnsSvc = dns.NewRoute53Service(route53Client, hostedZoneID, logger)
 
+		// Initialize webhook delivery
+		webhookSvc = webhook.NewHTTPService(webhook.NewHTTPClient(), logger)
+
+		// Configure how subdomains are derived for new locations
+		if err := configureSubdomains(); err != nil {
+			logger.Error("invalid subdomain configuration", "error", err)
+			initErr = err
+			return
+		}
+
+		// Configure signed tokens for email verification
+		if err := configureVerification(); err != nil {
+			logger.Error("invalid verification configuration", "error", err)
+			initErr = err
+			return
+		}
+
+		// Configure the key that protects stored request signing secrets
+		if err := configureSigning(); err != nil {
+			logger.Error("invalid request signing configuration", "error", err)
+			initErr = err
+			return
+		}
+
+		// Configure how long rotated keys keep working
+		if err := configureRotation(); err != nil {
+			logger.Error("invalid key rotation configuration", "error", err)
+			initErr = err
+			return
+		}
+
+		// Configure per-route request limits
+		if err := configureRequestLimits(); err != nil {
+			logger.Error("invalid request limit configuration", "error", err)
+			initErr = err
+			return
+		}
+
+		// Configure the per-owner ACME challenge limit
+		if err := configureACMEChallengeLimit(); err != nil {
+			logger.Error("invalid ACME challenge limit configuration", "error", err)
+			initErr = err
+			return
+		}
+
 		logger.Info("services initialized")
 	})
 	return initErr
 }
 
+// configureEmail applies EMAIL_PROVIDER, "ses" (the default) or "smtp", and
+// EMAIL_SENDER, the From address. The SMTP provider reads SMTP_HOST, SMTP_PORT,
+// SMTP_SECURITY ("starttls", "tls" or "none"), SMTP_AUTH ("plain" or "login"),
+// SMTP_USERNAME and SMTP_PASSWORD; see email.SMTPConfig for the defaults.
+// EMAIL_TEMPLATE_DIR overrides the embedded email templates.
+func configureEmail(cfg aws.Config) error {
+	sender := os.Getenv("EMAIL_SENDER")
+
+	templates := email.DefaultTemplates()
+	if dir := os.Getenv("EMAIL_TEMPLATE_DIR"); dir != "" {
+		loaded, err := email.LoadTemplates(dir)
+		if err != nil {
+			return fmt.Errorf("invalid EMAIL_TEMPLATE_DIR: %w", err)
+		}
+		templates = loaded
+		logger.Info("email templates loaded", "dir", dir, "locales", templates.Locales())
+	}
+
+	switch provider := strings.ToLower(os.Getenv("EMAIL_PROVIDER")); provider {
+	case "", "ses":
+		sesClient := ses.NewFromConfig(cfg)
+		if sender != "" {
+			emailSvc = email.NewSESServiceWithSender(sesClient, sender, logger).WithTemplates(templates)
+		} else {
+			emailSvc = email.NewSESService(sesClient, logger).WithTemplates(templates)
+		}
+		logger.Info("email configured", "provider", "ses")
+	case "smtp":
+		smtpCfg := email.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Security: os.Getenv("SMTP_SECURITY"),
+			Auth:     os.Getenv("SMTP_AUTH"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Sender:   sender,
+		}
+		if v := os.Getenv("SMTP_PORT"); v != "" {
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid SMTP_PORT %q", v)
+			}
+			smtpCfg.Port = port
+		}
+		svc, err := email.NewSMTPService(smtpCfg, logger)
+		if err != nil {
+			return err
+		}
+		emailSvc = svc.WithTemplates(templates)
+		logger.Info("email configured", "provider", "smtp", "host", smtpCfg.Host, "security", smtpCfg.Normalize().Security)
+	default:
+		return fmt.Errorf("invalid EMAIL_PROVIDER %q", provider)
+	}
+	return nil
+}
+
+// configureSubdomains applies SUBDOMAIN_HASH_ALGORITHM and SUBDOMAIN_HASH_LENGTH.
+// Unset variables keep the default 8 character MD5 scheme.
+func configureSubdomains() error {
+	cfg := dns.SubdomainConfig{
+		Algorithm: os.Getenv("SUBDOMAIN_HASH_ALGORITHM"),
+	}
+	if v := os.Getenv("SUBDOMAIN_HASH_LENGTH"); v != "" {
+		length, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SUBDOMAIN_HASH_LENGTH %q: %w", v, err)
+		}
+		cfg.Length = length
+	}
+	if err := dns.ConfigureSubdomains(cfg); err != nil {
+		return err
+	}
+
+	active := dns.CurrentSubdomainConfig()
+	logger.Info("subdomain hashing configured", "algorithm", active.Algorithm, "length", active.Length)
+	return nil
+}
+
+// configureVerification applies TOKEN_SIGNING_SECRET and OWNER_VERIFICATION_WINDOW.
+// The secret is required; the window defaults to domain.DefaultVerificationWindow.
+func configureVerification() error {
+	var err error
+	signer, err = token.NewSigner(os.Getenv("TOKEN_SIGNING_SECRET"))
+	if err != nil {
+		return fmt.Errorf("invalid TOKEN_SIGNING_SECRET: %w", err)
+	}
+
+	if v := os.Getenv("OWNER_VERIFICATION_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid OWNER_VERIFICATION_WINDOW %q", v)
+		}
+		verificationWindow = window
+	}
+
+	logger.Info("owner verification configured", "window", verificationWindow)
+	return nil
+}
+
+// configureSigning applies SIGNING_SECRET_KEY, which encrypts the signing
+// secrets stored with API keys. It is required.
+func configureSigning() error {
+	secrets, err := signing.NewSecretBox(os.Getenv("SIGNING_SECRET_KEY"))
+	if err != nil {
+		return fmt.Errorf("invalid SIGNING_SECRET_KEY: %w", err)
+	}
+	auth.ConfigureSigning(secrets)
+
+	logger.Info("request signing configured")
+	return nil
+}
+
+// configureRotation applies KEY_ROTATION_GRACE_PERIOD, which defaults to
+// domain.DefaultRotationGracePeriod. Zero revokes rotated keys immediately.
+func configureRotation() error {
+	if v := os.Getenv("KEY_ROTATION_GRACE_PERIOD"); v != "" {
+		grace, err := time.ParseDuration(v)
+		if err != nil || grace < 0 || grace > domain.MaxRotationGracePeriod {
+			return fmt.Errorf("invalid KEY_ROTATION_GRACE_PERIOD %q", v)
+		}
+		rotationGracePeriod = grace
+	}
+
+	logger.Info("key rotation configured", "gracePeriod", rotationGracePeriod)
+	return nil
+}
+
+// configureRequestLimits applies REQUEST_LIMITS, a JSON object of per-route
+// overrides of domain.DefaultRouteLimits; see domain.ParseRouteLimits.
+func configureRequestLimits() error {
+	if v := os.Getenv("REQUEST_LIMITS"); v != "" {
+		limits, err := domain.ParseRouteLimits(v)
+		if err != nil {
+			return fmt.Errorf("invalid REQUEST_LIMITS: %w", err)
+		}
+		requestLimits = limits
+	}
+
+	logger.Info("request limits configured", "routes", len(requestLimits))
+	return nil
+}
+
+// configureACMEChallengeLimit applies ACME_CHALLENGE_LIMIT, the number of ACME
+// challenges an owner may create per ACME_CHALLENGE_WINDOW. They default to
+// domain.DefaultACMEChallengeLimit; a limit of zero turns it off.
+func configureACMEChallengeLimit() error {
+	if v := os.Getenv("ACME_CHALLENGE_LIMIT"); v != "" {
+		maxChallenges, err := strconv.Atoi(v)
+		if err != nil || maxChallenges < 0 {
+			return fmt.Errorf("invalid ACME_CHALLENGE_LIMIT %q", v)
+		}
+		acmeChallengeLimit.MaxChallenges = maxChallenges
+	}
+	if v := os.Getenv("ACME_CHALLENGE_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window < time.Minute {
+			return fmt.Errorf("invalid ACME_CHALLENGE_WINDOW %q", v)
+		}
+		acmeChallengeLimit.Window = window
+	}
+
+	logger.Info("ACME challenge limit configured", "limit", acmeChallengeLimit.MaxChallenges, "window", acmeChallengeLimit.Window)
+	return nil
+}
+
 // EventBridgeEvent represents an EventBridge scheduled event.
 type EventBridgeEvent struct {
 	Source string `json:"source"`
 	Action string `json:"action"`
 }
 
+// scheduledSources are the EventBridge sources of our scheduled jobs.
+var scheduledSources = map[string]bool{
+	"ddns.acme-cleanup":        true,
+	"ddns.key-expiry":          true,
+	"ddns.auth-failure-alerts": true,
+	"ddns.ip-change-digest":    true,
+	"ddns.webhook-retries":     true,
+	"ddns.stale-locations":     true,
+}
+
 // GenericHandler handles both API Gateway and EventBridge events.
 func GenericHandler(ctx context.Context, rawEvent json.RawMessage) (any, error) {
 	// Try to detect if this is an EventBridge event
 	var ebEvent EventBridgeEvent
-	if err := json.Unmarshal(rawEvent, &ebEvent); err == nil && ebEvent.Source == "ddns.acme-cleanup" {
+	if err := json.Unmarshal(rawEvent, &ebEvent); err == nil && scheduledSources[ebEvent.Source] {
 		return handleEventBridge(ctx, ebEvent)
 	}
 
@@ -103,6 +349,16 @@ func handleEventBridge(ctx context.Context, event EventBridgeEvent) (any, error)
 	switch event.Action {
 	case "cleanup-expired-challenges":
 		return handlers.CleanupExpiredChallenges(ctx, repo, dnsSvc, logger)
+	case "send-key-expiry-warnings":
+		return handlers.SendKeyExpiryWarnings(ctx, repo, emailSvc, logger)
+	case "send-auth-failure-alerts":
+		return handlers.SendAuthFailureAlerts(ctx, repo, emailSvc, logger)
+	case "send-ip-change-digests":
+		return handlers.SendIPChangeDigests(ctx, repo, emailSvc, logger)
+	case "retry-webhook-deliveries":
+		return handlers.RetryWebhookDeliveries(ctx, repo, webhookSvc, logger)
+	case "check-stale-locations":
+		return handlers.CheckStaleLocations(ctx, repo, emailSvc, logger)
 	default:
 		logger.Warn("unknown EventBridge action", "action", event.Action)
 		return nil, fmt.Errorf("unknown action: %s", event.Action)
@@ -121,9 +377,36 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return serverError(fmt.Errorf("failed to initialize: %w", err))
 	}
 
+	// Throttle before routing so that every endpoint, including unknown ones, is
+	// limited. Key and owner limits apply once a handler authenticates the request
+	limitRoute := requestLimitRoute(method, route)
+	ctx, reqErr := auth.Throttle(ctx, request, limitRoute, requestLimits[limitRoute], repo, logger)
+	if reqErr != nil {
+		if method == http.MethodGet && route == "/nic/update" {
+			return dynDNSResponse(handlers.NicUpdateRejected(reqErr))
+		}
+		return clientError(reqErr)
+	}
+
 	// POST /owners - create new owner
 	if method == http.MethodPost && route == "/owners" {
-		resp, reqErr := handlers.CreateOwner(ctx, request, repo, logger)
+		resp, reqErr := handlers.CreateOwner(ctx, request, repo, emailSvc, signer, verificationWindow, logger)
+		if reqErr != nil {
+			return clientError(reqErr)
+		}
+		return jsonResponse(resp.Status, resp.Body)
+	}
+
+	// GET /owners/{ownerId} - owner details and primary key usage (requires auth)
+	if method == http.MethodGet && strings.HasPrefix(route, "/owners/") && strings.Count(route, "/") == 2 {
+		ownerID := strings.TrimPrefix(route, "/owners/")
+		if ownerID == "" {
+			return clientError(&response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "invalid owner path",
+			})
+		}
+		resp, reqErr := handlers.GetOwner(ctx, request, ownerID, repo, logger)
 		if reqErr != nil {
 			return clientError(reqErr)
 		}
@@ -139,7 +422,39 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 				Description: "invalid owner path",
 			})
 		}
-		resp, reqErr := handlers.RecoverKey(ctx, request, ownerID, repo, emailSvc, logger)
+		resp, reqErr := handlers.RecoverKey(ctx, request, ownerID, repo, emailSvc, signer, logger)
+		if reqErr != nil {
+			return clientError(reqErr)
+		}
+		return jsonResponse(resp.Status, resp.Body)
+	}
+
+	// POST /owners/{ownerId}/recover/confirm - exchange a recovery token for a new API key
+	if method == http.MethodPost && strings.HasPrefix(route, "/owners/") && strings.HasSuffix(route, "/recover/confirm") {
+		ownerID := extractOwnerIDFromPath(route, "/owners/", "/recover/confirm")
+		if ownerID == "" {
+			return clientError(&response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "invalid owner path",
+			})
+		}
+		resp, reqErr := handlers.ConfirmRecovery(ctx, request, ownerID, repo, emailSvc, signer, logger)
+		if reqErr != nil {
+			return clientError(reqErr)
+		}
+		return jsonResponse(resp.Status, resp.Body)
+	}
+
+	// POST /owners/{ownerId}/verify - verify a new owner's email address
+	if method == http.MethodPost && strings.HasPrefix(route, "/owners/") && strings.HasSuffix(route, "/verify") {
+		ownerID := extractOwnerIDFromPath(route, "/owners/", "/verify")
+		if ownerID == "" {
+			return clientError(&response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "invalid owner path",
+			})
+		}
+		resp, reqErr := handlers.VerifyOwner(ctx, request, ownerID, repo, signer, logger)
 		if reqErr != nil {
 			return clientError(reqErr)
 		}
@@ -155,7 +470,39 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 				Description: "invalid owner path",
 			})
 		}
-		resp, reqErr := handlers.RotateKey(ctx, request, ownerID, repo, logger)
+		resp, reqErr := handlers.RotateKey(ctx, request, ownerID, repo, emailSvc, rotationGracePeriod, logger)
+		if reqErr != nil {
+			return clientError(reqErr)
+		}
+		return jsonResponse(resp.Status, resp.Body)
+	}
+
+	// PUT /owners/{ownerId}/locale - set the language of the owner's emails (requires auth)
+	if method == http.MethodPut && strings.HasPrefix(route, "/owners/") && strings.HasSuffix(route, "/locale") {
+		ownerID := extractOwnerIDFromPath(route, "/owners/", "/locale")
+		if ownerID == "" {
+			return clientError(&response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "invalid owner path",
+			})
+		}
+		resp, reqErr := handlers.SetLocale(ctx, request, ownerID, repo, logger)
+		if reqErr != nil {
+			return clientError(reqErr)
+		}
+		return jsonResponse(resp.Status, resp.Body)
+	}
+
+	// PUT /owners/{ownerId}/notifications - set notification preferences (requires auth)
+	if method == http.MethodPut && strings.HasPrefix(route, "/owners/") && strings.HasSuffix(route, "/notifications") {
+		ownerID := extractOwnerIDFromPath(route, "/owners/", "/notifications")
+		if ownerID == "" {
+			return clientError(&response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "invalid owner path",
+			})
+		}
+		resp, reqErr := handlers.SetNotifications(ctx, request, ownerID, repo, logger)
 		if reqErr != nil {
 			return clientError(reqErr)
 		}
@@ -173,11 +520,17 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	// POST /update - update DNS if IP changed (requires auth)
 	if method == http.MethodPost && route == "/update" {
-		resp, reqErr := handlers.Update(ctx, request, repo, dnsSvc, logger)
+		resp, reqErr := handlers.Update(ctx, request, repo, dnsSvc, emailSvc, webhookSvc, logger)
 		if reqErr != nil {
 			return clientError(reqErr)
 		}
-		return jsonResponse(resp.Status, resp.Body)
+		return rateLimitedJSONResponse(resp.Status, resp.Body, resp.RateLimit)
+	}
+
+	// GET /nic/update - dyndns2 protocol update for routers and ddclient (requires basic auth)
+	if method == http.MethodGet && route == "/nic/update" {
+		resp := handlers.NicUpdate(ctx, request, repo, dnsSvc, emailSvc, webhookSvc, logger)
+		return dynDNSResponse(resp)
 	}
 
 	// GET /lookup/{ownerId}/{location} - lookup IP (requires auth)
@@ -191,20 +544,220 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	// POST /acme-challenge - create ACME challenge TXT record (requires auth)
 	if method == http.MethodPost && route == "/acme-challenge" {
-		resp, reqErr := handlers.CreateACMEChallenge(ctx, request, repo, dnsSvc, logger)
+		resp, reqErr := handlers.CreateACMEChallenge(ctx, request, repo, dnsSvc, webhookSvc, emailSvc, acmeChallengeLimit, logger)
 		if reqErr != nil {
 			return clientError(reqErr)
 		}
-		return jsonResponse(resp.Status, resp.Body)
+		return rateLimitedJSONResponse(resp.Status, resp.Body, resp.RateLimit)
 	}
 
 	// DELETE /acme-challenge - delete ACME challenge TXT record (requires auth)
 	if method == http.MethodDelete && route == "/acme-challenge" {
-		resp, reqErr := handlers.DeleteACMEChallenge(ctx, request, repo, dnsSvc, logger)
+		resp, reqErr := handlers.DeleteACMEChallenge(ctx, request, repo, dnsSvc, webhookSvc, acmeChallengeLimit, logger)
 		if reqErr != nil {
 			return clientError(reqErr)
 		}
-		return jsonResponse(resp.Status, resp.Body)
+		return rateLimitedJSONResponse(resp.Status, resp.Body, resp.RateLimit)
+	}
+
+	// /owners/{ownerId}/locations/{location} - location settings and removal (requires auth)
+	if ownerID, location, rest, ok := parseLocationPath(route); ok && rest == "" {
+		switch method {
+		case http.MethodPatch:
+			resp, reqErr := handlers.UpdateLocation(ctx, request, ownerID, location, repo, dnsSvc, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case http.MethodDelete:
+			resp, reqErr := handlers.DeleteLocation(ctx, request, ownerID, location, repo, dnsSvc, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+	}
+
+	// /owners/{ownerId}/locations/{location}/records - custom record sets (requires auth)
+	if ownerID, location, rest, ok := parseLocationPath(route); ok && rest == "records" {
+		switch method {
+		case http.MethodGet:
+			resp, reqErr := handlers.ListRecordSets(ctx, request, ownerID, location, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case http.MethodPost:
+			resp, reqErr := handlers.CreateRecordSet(ctx, request, ownerID, location, repo, dnsSvc, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case http.MethodPut:
+			resp, reqErr := handlers.UpdateRecordSet(ctx, request, ownerID, location, repo, dnsSvc, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case http.MethodDelete:
+			resp, reqErr := handlers.DeleteRecordSet(ctx, request, ownerID, location, repo, dnsSvc, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+	}
+
+	// /owners/{ownerId}/keys[/{keyId}] - additional API keys (requires auth)
+	if ownerID, keyID, ok := parseKeysPath(route); ok {
+		switch {
+		case method == http.MethodGet && keyID == "":
+			resp, reqErr := handlers.ListAPIKeys(ctx, request, ownerID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case method == http.MethodPost && keyID == "":
+			resp, reqErr := handlers.CreateAPIKey(ctx, request, ownerID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case method == http.MethodDelete && keyID != "":
+			resp, reqErr := handlers.RevokeAPIKey(ctx, request, ownerID, keyID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+	}
+
+	// /owners/{ownerId}/webhooks[/{webhookId}[/deliveries]] - event webhooks (requires auth)
+	if ownerID, webhookID, rest, ok := parseWebhooksPath(route); ok {
+		switch {
+		case method == http.MethodGet && webhookID == "":
+			resp, reqErr := handlers.ListWebhooks(ctx, request, ownerID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case method == http.MethodPost && webhookID == "":
+			resp, reqErr := handlers.CreateWebhook(ctx, request, ownerID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case method == http.MethodDelete && webhookID != "" && rest == "":
+			resp, reqErr := handlers.DeleteWebhook(ctx, request, ownerID, webhookID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		case method == http.MethodGet && webhookID != "" && rest == "deliveries":
+			resp, reqErr := handlers.ListWebhookDeliveries(ctx, request, ownerID, webhookID, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+	}
+
+	// /admin/... - operator endpoints (requires an operator key)
+	if adminRoute, ok := strings.CutPrefix(route, "/admin/"); ok {
+		adminRoute = "/" + adminRoute
+
+		// GET /admin/owners - list and search owners
+		if method == http.MethodGet && adminRoute == "/owners" {
+			resp, reqErr := handlers.AdminListOwners(ctx, request, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+
+		// GET /admin/audit - operator audit log
+		if method == http.MethodGet && adminRoute == "/audit" {
+			resp, reqErr := handlers.AdminListAuditLog(ctx, request, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+
+		// GET /admin/rate-limit-plans - list rate limit plans
+		if method == http.MethodGet && adminRoute == "/rate-limit-plans" {
+			resp, reqErr := handlers.AdminListRateLimitPlans(ctx, request, repo, logger)
+			if reqErr != nil {
+				return clientError(reqErr)
+			}
+			return jsonResponse(resp.Status, resp.Body)
+		}
+
+		// /admin/rate-limit-plans/{name} - create, replace and delete rate limit plans
+		if name, ok := strings.CutPrefix(adminRoute, "/rate-limit-plans/"); ok && name != "" && !strings.Contains(name, "/") {
+			switch method {
+			case http.MethodPut:
+				resp, reqErr := handlers.AdminPutRateLimitPlan(ctx, request, name, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			case http.MethodDelete:
+				resp, reqErr := handlers.AdminDeleteRateLimitPlan(ctx, request, name, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			}
+		}
+
+		// /admin/owners/{ownerId}[/suspend|/unsuspend|/rate-limit] - inspect, suspend and rate limit owners
+		if ownerID, action, ok := parseAdminOwnerPath(adminRoute); ok {
+			switch {
+			case method == http.MethodGet && action == "":
+				resp, reqErr := handlers.AdminGetOwner(ctx, request, ownerID, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			case method == http.MethodPost && action == "suspend":
+				resp, reqErr := handlers.AdminSuspendOwner(ctx, request, ownerID, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			case method == http.MethodPost && action == "unsuspend":
+				resp, reqErr := handlers.AdminUnsuspendOwner(ctx, request, ownerID, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			case method == http.MethodPut && action == "rate-limit":
+				resp, reqErr := handlers.AdminSetOwnerRateLimit(ctx, request, ownerID, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			}
+		}
+
+		// /admin/owners/{ownerId}/locations/{location}/... - rate limits and subdomains
+		if ownerID, location, rest, ok := parseLocationPath(adminRoute); ok {
+			switch {
+			case method == http.MethodPost && rest == "reset-rate-limit":
+				resp, reqErr := handlers.AdminResetRateLimit(ctx, request, ownerID, location, repo, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			case method == http.MethodPut && rest == "subdomain":
+				resp, reqErr := handlers.AdminChangeSubdomain(ctx, request, ownerID, location, repo, dnsSvc, logger)
+				if reqErr != nil {
+					return clientError(reqErr)
+				}
+				return jsonResponse(resp.Status, resp.Body)
+			}
+		}
 	}
 
 	logger.Warn("resource not found", "route", route)
@@ -214,6 +767,27 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	})
 }
 
+// requestLimitRoute returns the group of routes whose request limits apply to a
+// request.
+func requestLimitRoute(method, route string) string {
+	switch {
+	case method == http.MethodPost && route == "/owners":
+		return domain.LimitRouteCreateOwner
+	case strings.HasPrefix(route, "/owners/") && (strings.HasSuffix(route, "/recover") || strings.HasSuffix(route, "/recover/confirm")):
+		return domain.LimitRouteRecover
+	case strings.HasPrefix(route, "/owners/") && strings.HasSuffix(route, "/verify"):
+		return domain.LimitRouteVerify
+	case route == "/update" || route == "/register" || route == "/nic/update":
+		return domain.LimitRouteUpdate
+	case strings.HasPrefix(route, "/lookup/"):
+		return domain.LimitRouteLookup
+	case strings.HasPrefix(route, "/admin/"):
+		return domain.LimitRouteAdmin
+	default:
+		return domain.LimitRouteDefault
+	}
+}
+
 // extractOwnerIDFromPath extracts the owner ID from paths like /owners/{ownerId}/action
 func extractOwnerIDFromPath(path, prefix, suffix string) string {
 	path = strings.TrimPrefix(path, prefix)
@@ -221,6 +795,75 @@ func extractOwnerIDFromPath(path, prefix, suffix string) string {
 	return path
 }
 
+// parseKeysPath splits paths like /owners/{ownerId}/keys and /owners/{ownerId}/keys/{keyId}.
+func parseKeysPath(path string) (ownerID, keyID string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] != "owners" || parts[1] == "" || parts[2] != "keys" {
+		return "", "", false
+	}
+	if len(parts) == 4 {
+		if parts[3] == "" {
+			return "", "", false
+		}
+		keyID = parts[3]
+	}
+	return parts[1], keyID, true
+}
+
+// parseWebhooksPath splits paths like /owners/{ownerId}/webhooks,
+// /owners/{ownerId}/webhooks/{webhookId} and /owners/{ownerId}/webhooks/{webhookId}/deliveries.
+func parseWebhooksPath(path string) (ownerID, webhookID, rest string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 3 || len(parts) > 5 || parts[0] != "owners" || parts[1] == "" || parts[2] != "webhooks" {
+		return "", "", "", false
+	}
+	if len(parts) >= 4 {
+		if parts[3] == "" {
+			return "", "", "", false
+		}
+		webhookID = parts[3]
+	}
+	if len(parts) == 5 {
+		if parts[4] != "deliveries" {
+			return "", "", "", false
+		}
+		rest = parts[4]
+	}
+	return parts[1], webhookID, rest, true
+}
+
+// parseAdminOwnerPath splits paths like /owners/{ownerId} and /owners/{ownerId}/{action},
+// after the /admin prefix has been removed.
+func parseAdminOwnerPath(path string) (ownerID, action string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "owners" || parts[1] == "" {
+		return "", "", false
+	}
+	if len(parts) == 3 {
+		if parts[2] == "" {
+			return "", "", false
+		}
+		action = parts[2]
+	}
+	return parts[1], action, true
+}
+
+// parseLocationPath splits paths like /owners/{ownerId}/locations/{location}/{rest}.
+// rest is empty when the path addresses the location itself.
+func parseLocationPath(path string) (ownerID, location, rest string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 5)
+	if len(parts) < 4 || parts[0] != "owners" || parts[2] != "locations" {
+		return "", "", "", false
+	}
+	if parts[1] == "" || parts[3] == "" {
+		return "", "", "", false
+	}
+	if len(parts) == 5 {
+		rest = parts[4]
+	}
+	return parts[1], parts[3], rest, true
+}
+
 func jsonResponse(status int, body any) (events.APIGatewayProxyResponse, error) {
 	js, err := json.Marshal(body)
 	if err != nil {
@@ -236,6 +879,35 @@ func jsonResponse(status int, body any) (events.APIGatewayProxyResponse, error)
 	}, nil
 }
 
+// rateLimitedJSONResponse is jsonResponse with the RateLimit-* headers, when the
+// status is known.
+func rateLimitedJSONResponse(status int, body any, rateLimit *response.RateLimitStatus) (events.APIGatewayProxyResponse, error) {
+	resp, err := jsonResponse(status, body)
+	if err == nil && rateLimit != nil && resp.StatusCode == status {
+		rateLimit.SetHeaders(resp.Headers)
+	}
+	return resp, err
+}
+
+// dynDNSResponse writes a plain-text dyndns2 reply. A 401 carries a Basic challenge.
+func dynDNSResponse(resp response.DynDNSResponse) (events.APIGatewayProxyResponse, error) {
+	headers := map[string]string{
+		"Content-Type": "text/plain; charset=utf-8",
+	}
+	if resp.Status == http.StatusUnauthorized {
+		headers["WWW-Authenticate"] = `Basic realm="ddns-service"`
+	}
+	if resp.RetryAfter > 0 {
+		headers["Retry-After"] = strconv.Itoa(resp.RetryAfter)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: resp.Status,
+		Headers:    headers,
+		Body:       resp.Body,
+	}, nil
+}
+
 func serverError(err error) (events.APIGatewayProxyResponse, error) {
 	logger.Error("server error", "error", err)
 
@@ -259,11 +931,14 @@ func clientError(reqErr *response.RequestError) (events.APIGatewayProxyResponse,
 	if reqErr.RetryAfter > 0 {
 		headers["Retry-After"] = strconv.Itoa(reqErr.RetryAfter)
 	}
+	if reqErr.RateLimitStatus != nil {
+		reqErr.RateLimitStatus.SetHeaders(headers)
+	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: reqErr.Status,
 		Headers:    headers,
-		Body:       response.BuildErrorJSON(reqErr.Error(), logger),
+		Body:       response.BuildRequestErrorJSON(reqErr, logger),
 	}, nil
 }
 
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/grocky/ddns-service/internal/dns"
+)
+
+// ScanClient defines the DynamoDB operations needed to audit mappings.
+type ScanClient interface {
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// AuditService checks existing mappings for subdomain problems.
+type AuditService struct {
+	dynamoClient ScanClient
+	tableName    string
+	logger       *slog.Logger
+}
+
+// NewAuditService creates a new subdomain audit service.
+func NewAuditService(dynamoClient ScanClient, tableName string, logger *slog.Logger) *AuditService {
+	return &AuditService{
+		dynamoClient: dynamoClient,
+		tableName:    tableName,
+		logger:       logger,
+	}
+}
+
+// MappingRef identifies a mapping and the subdomain it resolves to.
+type MappingRef struct {
+	OwnerID   string
+	Location  string
+	Subdomain string
+	// Stored is false when the mapping has no Subdomain attribute and the
+	// subdomain was derived from the legacy hash instead.
+	Stored bool
+}
+
+// SubdomainCollision lists mappings that resolve to the same subdomain.
+type SubdomainCollision struct {
+	Subdomain string
+	Mappings  []MappingRef
+}
+
+// AuditSubdomainsOutput contains the result of a subdomain audit.
+type AuditSubdomainsOutput struct {
+	MappingsScanned int
+	// Unstored lists mappings that rely on the legacy hash at request time.
+	// They store it on their next IP change.
+	Unstored   []MappingRef
+	Collisions []SubdomainCollision
+}
+
+// AuditSubdomains scans every mapping and reports subdomains used by more than one
+// owner/location. Mappings without a stored subdomain are hashed with the legacy
+// scheme they were published under; see dns.LegacySubdomain.
+func (s *AuditService) AuditSubdomains(ctx context.Context) (*AuditSubdomainsOutput, error) {
+	s.logger.Info("auditing subdomains", "table", s.tableName)
+
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(s.tableName),
+		ProjectionExpression: aws.String("OwnerId, LocationName, Subdomain"),
+	}
+
+	var refs []MappingRef
+	for {
+		result, err := s.dynamoClient.Scan(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mappings: %w", err)
+		}
+
+		for _, item := range result.Items {
+			refs = append(refs, mappingRefFromItem(item))
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	output := &AuditSubdomainsOutput{
+		MappingsScanned: len(refs),
+		Collisions:      findCollisions(refs),
+	}
+	for _, ref := range refs {
+		if !ref.Stored {
+			output.Unstored = append(output.Unstored, ref)
+		}
+	}
+
+	s.logger.Info("subdomain audit complete",
+		"mappings", output.MappingsScanned,
+		"collisions", len(output.Collisions),
+		"unstored", len(output.Unstored),
+	)
+
+	return output, nil
+}
+
+func mappingRefFromItem(item map[string]types.AttributeValue) MappingRef {
+	var ref MappingRef
+	if v, ok := item["OwnerId"].(*types.AttributeValueMemberS); ok {
+		ref.OwnerID = v.Value
+	}
+	if v, ok := item["LocationName"].(*types.AttributeValueMemberS); ok {
+		ref.Location = v.Value
+	}
+	if v, ok := item["Subdomain"].(*types.AttributeValueMemberS); ok && v.Value != "" {
+		ref.Subdomain = v.Value
+		ref.Stored = true
+	} else {
+		ref.Subdomain = dns.LegacySubdomain(ref.OwnerID, ref.Location)
+	}
+	return ref
+}
+
+// findCollisions groups mappings by subdomain and returns the groups with more than one member.
+func findCollisions(refs []MappingRef) []SubdomainCollision {
+	bySubdomain := make(map[string][]MappingRef)
+	for _, ref := range refs {
+		bySubdomain[ref.Subdomain] = append(bySubdomain[ref.Subdomain], ref)
+	}
+
+	var collisions []SubdomainCollision
+	for subdomain, mappings := range bySubdomain {
+		if len(mappings) > 1 {
+			collisions = append(collisions, SubdomainCollision{
+				Subdomain: subdomain,
+				Mappings:  mappings,
+			})
+		}
+	}
+
+	// Stable output for operators and tests
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Subdomain < collisions[j].Subdomain
+	})
+	return collisions
+}
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/grocky/ddns-service/internal/dns"
+	"gotest.tools/assert"
+)
+
+type mockScanClient struct {
+	scanFunc func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+func (m *mockScanClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return m.scanFunc(ctx, params, optFns...)
+}
+
+func mappingItem(ownerID, location, subdomain string) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"OwnerId":      &types.AttributeValueMemberS{Value: ownerID},
+		"LocationName": &types.AttributeValueMemberS{Value: location},
+	}
+	if subdomain != "" {
+		item["Subdomain"] = &types.AttributeValueMemberS{Value: subdomain}
+	}
+	return item
+}
+
+func TestAuditSubdomains(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	legacy := dns.LegacySubdomain("legacy-owner", "home")
+
+	calls := 0
+	client := &mockScanClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			calls++
+			if calls == 1 {
+				return &dynamodb.ScanOutput{
+					Items: []map[string]types.AttributeValue{
+						mappingItem("alice", "home", "abcd1234"),
+						mappingItem("bob", "office", "abcd1234"),
+					},
+					LastEvaluatedKey: map[string]types.AttributeValue{"OwnerId": &types.AttributeValueMemberS{Value: "bob"}},
+				}, nil
+			}
+			assert.Assert(t, params.ExclusiveStartKey != nil)
+			return &dynamodb.ScanOutput{
+				Items: []map[string]types.AttributeValue{
+					mappingItem("carol", "home", "home"),
+					mappingItem("legacy-owner", "home", ""),
+					mappingItem("dave", "cabin", legacy),
+				},
+			}, nil
+		},
+	}
+
+	svc := NewAuditService(client, "DdnsServiceIpMapping", logger)
+	result, err := svc.AuditSubdomains(ctx)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 5, result.MappingsScanned)
+
+	assert.Equal(t, 1, len(result.Unstored))
+	assert.Equal(t, "legacy-owner", result.Unstored[0].OwnerID)
+	assert.Equal(t, legacy, result.Unstored[0].Subdomain)
+
+	assert.Equal(t, 2, len(result.Collisions))
+	for _, c := range result.Collisions {
+		assert.Equal(t, 2, len(c.Mappings), "collision on %s", c.Subdomain)
+	}
+}
+
+func TestAuditSubdomains_ScanError(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	client := &mockScanClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return nil, errors.New("access denied")
+		},
+	}
+
+	svc := NewAuditService(client, "DdnsServiceIpMapping", logger)
+	_, err := svc.AuditSubdomains(ctx)
+
+	assert.ErrorContains(t, err, "access denied")
+}
+
+func TestFindCollisions_None(t *testing.T) {
+	refs := []MappingRef{
+		{OwnerID: "alice", Location: "home", Subdomain: "aaaa1111"},
+		{OwnerID: "alice", Location: "office", Subdomain: "bbbb2222"},
+	}
+
+	assert.Equal(t, 0, len(findCollisions(refs)))
+}
@@ -14,16 +14,40 @@ const (
 	APIKeyPrefix = "ddns_sk_"
 	// APIKeyBytes is the number of random bytes in an API key
 	APIKeyBytes = 32
+	// KeyIDBytes is the number of random bytes in an API key ID
+	KeyIDBytes = 6
+	// KeyIDSeparator separates the key ID from the secret. It is not part of the
+	// base64url alphabet, so keys without an ID can't be mistaken for keys with one.
+	KeyIDSeparator = "."
 )
 
-// GenerateAPIKey creates a new secure random API key with the standard prefix.
-func GenerateAPIKey() (string, error) {
+// GenerateAPIKey creates a new secure random API key of the form
+// ddns_sk_{keyId}.{secret}. The key ID lets the server find the owner from the key alone.
+func GenerateAPIKey() (key, keyID string, err error) {
+	return generateKey(APIKeyPrefix)
+}
+
+// generateKey creates a random key of the form {prefix}{keyId}.{secret}.
+func generateKey(prefix string) (key, keyID string, err error) {
+	keyID, err = GenerateKeyID()
+	if err != nil {
+		return "", "", err
+	}
 	bytes := make([]byte, APIKeyBytes)
 	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+		return "", "", err
 	}
 	encoded := base64.RawURLEncoding.EncodeToString(bytes)
-	return APIKeyPrefix + encoded, nil
+	return prefix + keyID + KeyIDSeparator + encoded, keyID, nil
+}
+
+// GenerateKeyID creates a short random identifier for an additional API key.
+func GenerateKeyID() (string, error) {
+	bytes := make([]byte, KeyIDBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
 }
 
 // HashAPIKey returns the SHA-256 hash of an API key as a hex string.
@@ -33,11 +57,18 @@ func HashAPIKey(key string) string {
 }
 
 // ValidateAPIKeyFormat checks if a key has the correct format.
+// Both ddns_sk_{keyId}.{secret} and the original ddns_sk_{secret} are accepted.
 func ValidateAPIKeyFormat(key string) bool {
 	if !strings.HasPrefix(key, APIKeyPrefix) {
 		return false
 	}
 	encoded := strings.TrimPrefix(key, APIKeyPrefix)
+	if keyID, secret, found := strings.Cut(encoded, KeyIDSeparator); found {
+		if !isKeyID(keyID) {
+			return false
+		}
+		encoded = secret
+	}
 	if len(encoded) == 0 {
 		return false
 	}
@@ -46,6 +77,28 @@ func ValidateAPIKeyFormat(key string) bool {
 	return err == nil
 }
 
+// ParseKeyID returns the key ID embedded in a key. Keys issued before key IDs
+// were introduced don't have one.
+func ParseKeyID(key string) (string, bool) {
+	if !ValidateAPIKeyFormat(key) {
+		return "", false
+	}
+	keyID, _, found := strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), KeyIDSeparator)
+	if !found {
+		return "", false
+	}
+	return keyID, true
+}
+
+// isKeyID reports whether s looks like a key ID from GenerateKeyID.
+func isKeyID(s string) bool {
+	if len(s) != hex.EncodedLen(KeyIDBytes) {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
 // ExtractBearerToken extracts the token from an Authorization header value.
 // Returns empty string if the header is not a valid Bearer token.
 func ExtractBearerToken(authHeader string) string {
@@ -56,6 +109,20 @@ func ExtractBearerToken(authHeader string) string {
 	return strings.TrimPrefix(authHeader, prefix)
 }
 
+// ExtractBasicCredentials extracts the username and password from an HTTP Basic
+// Authorization header, as sent by dyndns2 clients.
+func ExtractBasicCredentials(authHeader string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if !strings.HasPrefix(authHeader, prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authHeader, prefix))
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
 // CompareHashes performs a constant-time comparison of two hash strings.
 // Returns true if they match.
 func CompareHashes(hash1, hash2 string) bool {
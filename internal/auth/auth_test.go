@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/base64"
 	"strings"
 	"testing"
 
@@ -8,26 +9,54 @@ import (
 )
 
 func TestGenerateAPIKey(t *testing.T) {
-	key, err := GenerateAPIKey()
+	key, keyID, err := GenerateAPIKey()
 	assert.NilError(t, err)
 	assert.Assert(t, strings.HasPrefix(key, APIKeyPrefix), "expected key to have prefix %s, got %s", APIKeyPrefix, key)
 
-	// Key should be unique each time
-	key2, err := GenerateAPIKey()
+	// Key and key ID should be unique each time
+	key2, keyID2, err := GenerateAPIKey()
 	assert.NilError(t, err)
 	assert.Assert(t, key != key2, "expected unique keys, got same key twice")
+	assert.Assert(t, keyID != keyID2, "expected unique key IDs, got same ID twice")
 }
 
 func TestGenerateAPIKey_Format(t *testing.T) {
-	key, err := GenerateAPIKey()
+	key, keyID, err := GenerateAPIKey()
 	assert.NilError(t, err)
 
-	// Remove prefix and verify base64url encoding
-	encoded := strings.TrimPrefix(key, APIKeyPrefix)
-	assert.Assert(t, len(encoded) > 0, "expected non-empty encoded part")
+	// The key embeds its ID ahead of the secret
+	assert.Assert(t, strings.HasPrefix(key, APIKeyPrefix+keyID+KeyIDSeparator))
+	secret := strings.TrimPrefix(key, APIKeyPrefix+keyID+KeyIDSeparator)
+	assert.Assert(t, len(secret) > 0, "expected non-empty secret")
 
 	// Validate the key format
 	assert.Assert(t, ValidateAPIKeyFormat(key), "generated key should pass validation")
+
+	parsed, ok := ParseKeyID(key)
+	assert.Assert(t, ok)
+	assert.Equal(t, keyID, parsed)
+}
+
+func TestParseKeyID(t *testing.T) {
+	testCases := []struct {
+		name       string
+		key        string
+		expectedID string
+		expectedOK bool
+	}{
+		{"key with ID", "ddns_sk_9f2c4a1b7e3d.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop", "9f2c4a1b7e3d", true},
+		{"key without ID", "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop", "", false},
+		{"malformed ID", "ddns_sk_not-hex!.ABCDEFGHIJKLMNOP", "", false},
+		{"invalid key", "api_key_9f2c4a1b7e3d.ABCDEF", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyID, ok := ParseKeyID(tc.key)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedID, keyID)
+		})
+	}
 }
 
 func TestHashAPIKey(t *testing.T) {
@@ -91,6 +120,21 @@ func TestValidateAPIKeyFormat(t *testing.T) {
 			key:      "ddns_sk_abc-def_ghi",
 			expected: true,
 		},
+		{
+			name:     "valid key with key ID",
+			key:      "ddns_sk_9f2c4a1b7e3d.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop",
+			expected: true,
+		},
+		{
+			name:     "key ID without secret",
+			key:      "ddns_sk_9f2c4a1b7e3d.",
+			expected: false,
+		},
+		{
+			name:     "short key ID",
+			key:      "ddns_sk_9f2c.ABCDEFGHIJKLMNOP",
+			expected: false,
+		},
 		{
 			name:     "missing prefix",
 			key:      "7Kx9mP2qR5vW8yB3nF6hJ4tL1cA0eD9g",
@@ -182,6 +226,64 @@ func TestExtractBearerToken(t *testing.T) {
 	}
 }
 
+func TestExtractBasicCredentials(t *testing.T) {
+	testCases := []struct {
+		name             string
+		authHeader       string
+		expectedUsername string
+		expectedPassword string
+		expectedOK       bool
+	}{
+		{
+			name:             "valid credentials",
+			authHeader:       "Basic dXNlcjpwYXNz",
+			expectedUsername: "user",
+			expectedPassword: "pass",
+			expectedOK:       true,
+		},
+		{
+			name:             "password containing a colon",
+			authHeader:       "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pa:ss")),
+			expectedUsername: "user",
+			expectedPassword: "pa:ss",
+			expectedOK:       true,
+		},
+		{
+			name:             "empty username",
+			authHeader:       "Basic " + base64.StdEncoding.EncodeToString([]byte(":pass")),
+			expectedPassword: "pass",
+			expectedOK:       true,
+		},
+		{
+			name:       "missing colon",
+			authHeader: "Basic " + base64.StdEncoding.EncodeToString([]byte("userpass")),
+		},
+		{
+			name:       "invalid base64",
+			authHeader: "Basic !!!",
+		},
+		{
+			name:       "bearer token",
+			authHeader: "Bearer ddns_sk_testtoken123",
+		},
+		{
+			name:       "empty header",
+			authHeader: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			username, password, ok := ExtractBasicCredentials(tc.authHeader)
+			assert.Equal(t, tc.expectedOK, ok)
+			if tc.expectedOK {
+				assert.Equal(t, tc.expectedUsername, username)
+				assert.Equal(t, tc.expectedPassword, password)
+			}
+		})
+	}
+}
+
 func TestCompareHashes(t *testing.T) {
 	hash1 := HashAPIKey("ddns_sk_testkey1")
 	hash2 := HashAPIKey("ddns_sk_testkey1")
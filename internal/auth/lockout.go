@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// lockout tracks failed authentication attempts against one subject. Failures are
+// counted per source IP for every authentication, and per owner once the owner
+// is known and a key or signature didn't match. Only source IPs are locked out;
+// owner counts just feed the owner's alerts, so failures from others can't
+// block requests with a valid key.
+//
+// Tracking fails open: if the failure table can't be read or written the attempt
+// proceeds, since locking everyone out during a DynamoDB hiccup is worse than a
+// few uncounted guesses.
+type lockout struct {
+	subject string
+	ownerID string
+	policy  domain.LockoutPolicy
+}
+
+func ipLockout(ip string) lockout {
+	return lockout{
+		subject: domain.AuthFailureSubject(domain.AuthSubjectIP, ip),
+		policy:  domain.IPLockoutPolicy,
+	}
+}
+
+func ownerFailures(ownerID string) lockout {
+	return lockout{
+		subject: domain.AuthFailureSubject(domain.AuthSubjectOwner, ownerID),
+		ownerID: ownerID,
+		policy:  domain.OwnerFailurePolicy,
+	}
+}
+
+// check returns a 429 while the subject is locked out. Otherwise it returns the
+// subject's recent failures, if any, so a later success can clear them.
+func (l lockout) check(ctx context.Context, now time.Time, repo repository.Repository, logger *slog.Logger) (*domain.AuthFailure, *response.RequestError) {
+	failure, err := repo.GetAuthFailure(ctx, l.subject)
+	if err != nil {
+		logger.Warn("failed to check auth lockout", "error", err, "subject", l.subject)
+		return nil, nil
+	}
+	if failure == nil {
+		return nil, nil
+	}
+
+	if retryAfter, locked := failure.RetryAfter(now); locked {
+		logger.Warn("authentication locked out", "subject", l.subject, "failures", failure.Failures, "lockedUntil", failure.LockedUntil)
+		return nil, &response.RequestError{
+			Status:      http.StatusTooManyRequests,
+			Description: domain.ErrTooManyAuthFailures.Error(),
+			RetryAfter:  max(1, int(math.Ceil(retryAfter.Seconds()))),
+		}
+	}
+	return failure, nil
+}
+
+// fail counts a failed attempt against the subject.
+func (l lockout) fail(ctx context.Context, ip string, now time.Time, repo repository.Repository, logger *slog.Logger) {
+	failure, err := repo.RecordAuthFailure(ctx, l.subject, l.ownerID, ip, l.policy, now)
+	if err != nil {
+		logger.Warn("failed to record auth failure", "error", err, "subject", l.subject)
+		return
+	}
+	if failure.LockedUntil != nil {
+		logger.Warn("authentication lockout started", "subject", l.subject, "failures", failure.Failures, "lockedUntil", failure.LockedUntil)
+	}
+}
+
+// clear forgets the subject's failures after a successful authentication. Nothing
+// is written when there were none.
+func (l lockout) clear(ctx context.Context, failure *domain.AuthFailure, repo repository.Repository, logger *slog.Logger) {
+	if failure == nil {
+		return
+	}
+	if err := repo.ClearAuthFailures(ctx, l.subject); err != nil {
+		logger.Warn("failed to clear auth failures", "error", err, "subject", l.subject)
+	}
+}
+
+// guardIP runs authenticate behind the caller's per-IP lockout. Any 401 counts as a
+// failed attempt.
+func guardIP[T any](
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	logger *slog.Logger,
+	authenticate func() (T, *response.RequestError),
+) (T, *response.RequestError) {
+	ip := sourceIP(request)
+	if ip == "" {
+		return authenticate()
+	}
+
+	var zero T
+	l := ipLockout(ip)
+	now := time.Now().UTC()
+	failure, reqErr := l.check(ctx, now, repo, logger)
+	if reqErr != nil {
+		return zero, reqErr
+	}
+
+	result, reqErr := authenticate()
+	switch {
+	case reqErr == nil:
+		l.clear(ctx, failure, repo, logger)
+	case reqErr.Status == http.StatusUnauthorized:
+		l.fail(ctx, ip, now, repo, logger)
+	}
+	return result, reqErr
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+const lockoutTestKey = "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+func newLockoutTestRepo() *mockRepository {
+	return &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: "test-owner", APIKeyHash: HashAPIKey(lockoutTestKey)}, nil
+		},
+	}
+}
+
+func lockoutRequest(key string) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + key},
+	}
+	request.RequestContext.Identity.SourceIP = "198.51.100.7"
+	return request
+}
+
+func TestAuthenticate_LockedOutIP(t *testing.T) {
+	lockedUntil := time.Now().Add(90 * time.Second)
+	repo := newLockoutTestRepo()
+	repo.getAuthFailureFunc = func(ctx context.Context, subject string) (*domain.AuthFailure, error) {
+		if subject == "ip#198.51.100.7" {
+			return &domain.AuthFailure{Subject: subject, Failures: 6, LockedUntil: &lockedUntil}, nil
+		}
+		return nil, nil
+	}
+
+	// Even a valid key is refused while locked out
+	result, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, result == nil)
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusTooManyRequests, err.Status)
+	assert.Equal(t, domain.ErrTooManyAuthFailures.Error(), err.Description)
+	assert.Assert(t, err.RetryAfter >= 89 && err.RetryAfter <= 90, "got Retry-After %d", err.RetryAfter)
+}
+
+func TestAuthenticate_OwnerFailuresDontBlockValidKey(t *testing.T) {
+	lockedUntil := time.Now().Add(time.Minute)
+	repo := newLockoutTestRepo()
+	repo.getAuthFailureFunc = func(ctx context.Context, subject string) (*domain.AuthFailure, error) {
+		if subject == "owner#test-owner" {
+			// Records from before owner lockouts were dropped may still be locked
+			return &domain.AuthFailure{Subject: subject, Failures: 50, LockedUntil: &lockedUntil}, nil
+		}
+		return nil, nil
+	}
+
+	identity, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, "test-owner", identity.Owner.OwnerID)
+}
+
+func TestAuthenticate_OwnerFailuresNeverLock(t *testing.T) {
+	repo := newLockoutTestRepo()
+
+	var ownerFailures int
+	repo.recordAuthFailureFunc = func(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+		if subject != "owner#test-owner" {
+			return &domain.AuthFailure{Subject: subject, Failures: 1}, nil
+		}
+		ownerFailures++
+		return &domain.AuthFailure{Subject: subject, OwnerID: ownerID, Failures: ownerFailures}, nil
+	}
+
+	// Failures from many IPs are counted against the owner but never refuse its key
+	wrongKey := "ddns_sk_ZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZ"
+	for i := 0; i < 3*domain.AuthFailureAlertThreshold; i++ {
+		request := lockoutRequest(wrongKey)
+		request.RequestContext.Identity.SourceIP = "198.51.100." + strconv.Itoa(i)
+		_, err := Authenticate(context.Background(), request, "test-owner", updateHome, repo, newTestLogger())
+		assert.Equal(t, http.StatusUnauthorized, err.Status)
+	}
+
+	_, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, 3*domain.AuthFailureAlertThreshold, ownerFailures)
+}
+
+func TestAuthenticate_RecordsFailures(t *testing.T) {
+	repo := newLockoutTestRepo()
+
+	var failures []domain.AuthFailure
+	var policies []domain.LockoutPolicy
+	repo.recordAuthFailureFunc = func(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+		failures = append(failures, domain.AuthFailure{Subject: subject, OwnerID: ownerID, LastFailureIP: ip})
+		policies = append(policies, policy)
+		return &domain.AuthFailure{Subject: subject, Failures: 1}, nil
+	}
+
+	wrongKey := "ddns_sk_ZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZ"
+	_, err := Authenticate(context.Background(), lockoutRequest(wrongKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusUnauthorized, err.Status)
+	assert.DeepEqual(t, []domain.AuthFailure{
+		{Subject: "owner#test-owner", OwnerID: "test-owner", LastFailureIP: "198.51.100.7"},
+		{Subject: "ip#198.51.100.7", LastFailureIP: "198.51.100.7"},
+	}, failures)
+	assert.DeepEqual(t, []domain.LockoutPolicy{domain.OwnerFailurePolicy, domain.IPLockoutPolicy}, policies)
+}
+
+func TestAuthenticate_UnknownOwnerOnlyCountsIP(t *testing.T) {
+	repo := newLockoutTestRepo()
+	repo.getOwnerFunc = func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+		return nil, domain.ErrOwnerNotFound
+	}
+
+	var subjects []string
+	repo.recordAuthFailureFunc = func(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+		subjects = append(subjects, subject)
+		return &domain.AuthFailure{Subject: subject, Failures: 1}, nil
+	}
+
+	_, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "nobody", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.DeepEqual(t, []string{"ip#198.51.100.7"}, subjects)
+}
+
+func TestAuthenticate_ForbiddenIsNotAFailure(t *testing.T) {
+	repo := newLockoutTestRepo()
+	repo.recordAuthFailureFunc = func(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+		t.Fatalf("a valid key without the scope should not count as a failure, recorded %s", subject)
+		return nil, nil
+	}
+
+	// The primary key has every scope, so use an additional key restricted to another location
+	repo.listAPIKeysFunc = func(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+		return []domain.APIKey{{OwnerID: "test-owner", KeyID: "cabin", KeyHash: HashAPIKey("ddns_sk_cabinkey"), Scopes: []string{domain.ScopeUpdate}, Locations: []string{"cabin"}}}, nil
+	}
+
+	_, err := Authenticate(context.Background(), lockoutRequest("ddns_sk_cabinkey"), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusForbidden, err.Status)
+}
+
+func TestAuthenticate_SuccessClearsIPFailures(t *testing.T) {
+	repo := newLockoutTestRepo()
+	repo.getAuthFailureFunc = func(ctx context.Context, subject string) (*domain.AuthFailure, error) {
+		return &domain.AuthFailure{Subject: subject, Failures: 3}, nil
+	}
+
+	var cleared []string
+	repo.clearAuthFailuresFunc = func(ctx context.Context, subject string) error {
+		cleared = append(cleared, subject)
+		return nil
+	}
+
+	_, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	// The owner's count is kept for its alert; a valid key doesn't prove the failures were the owner's
+	assert.DeepEqual(t, []string{"ip#198.51.100.7"}, cleared)
+}
+
+func TestAuthenticate_SuccessWithoutFailuresWritesNothing(t *testing.T) {
+	repo := newLockoutTestRepo()
+	repo.clearAuthFailuresFunc = func(ctx context.Context, subject string) error {
+		t.Fatal("nothing to clear")
+		return nil
+	}
+
+	_, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+}
+
+func TestAuthenticate_LockoutTrackingFailsOpen(t *testing.T) {
+	repo := newLockoutTestRepo()
+	repo.getAuthFailureFunc = func(ctx context.Context, subject string) (*domain.AuthFailure, error) {
+		return nil, errors.New("throttled")
+	}
+
+	result, err := Authenticate(context.Background(), lockoutRequest(lockoutTestKey), "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, "test-owner", result.Owner.OwnerID)
+}
+
+func TestAuthenticateOperator_CountsIPFailures(t *testing.T) {
+	repo := &mockRepository{
+		getOperatorFunc: func(ctx context.Context, keyID string) (*domain.Operator, error) {
+			return nil, domain.ErrOperatorNotFound
+		},
+	}
+
+	var subjects []string
+	repo.recordAuthFailureFunc = func(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+		subjects = append(subjects, subject)
+		return &domain.AuthFailure{Subject: subject, Failures: 1}, nil
+	}
+
+	_, err := AuthenticateOperator(context.Background(), lockoutRequest("ddns_op_0123456789ab.ABCDEFGH"), repo, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.DeepEqual(t, []string{"ip#198.51.100.7"}, subjects)
+}
@@ -2,36 +2,99 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/grocky/ddns-service/internal/domain"
 	"github.com/grocky/ddns-service/internal/repository"
 	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/signing"
 )
 
-// Authenticate validates the API key from the request and returns the authenticated owner.
-// It extracts the Bearer token from the Authorization header, validates it against the
-// owner specified in the request, and returns an error if authentication fails.
+// Permission describes what a handler needs from the caller's API key.
+type Permission struct {
+	// Scope is the scope the key must grant.
+	Scope string
+	// Location is the location being acted on. Empty means the operation affects
+	// the whole owner, which keys restricted to specific locations may not do.
+	Location string
+	// AnyLocation skips the location check, for handlers that only learn which
+	// locations they act on after authenticating. They must check each location
+	// with Identity.Authorize.
+	AnyLocation bool
+}
+
+// Identity is the result of a successful authentication.
+type Identity struct {
+	Owner *domain.Owner
+	Key   domain.APIKey
+}
+
+// Authorize checks that the identity's key grants permission.
+func (i *Identity) Authorize(permission Permission, logger *slog.Logger) *response.RequestError {
+	return authorize(i.Key, permission, logger)
+}
+
+// Authenticate validates the API key from the request and returns the authenticated
+// owner and key. It extracts the Bearer token from the Authorization header, validates
+// it against the owner's primary key and additional keys, and checks that the key
+// grants the required permission. Signed requests are verified instead; see
+// authenticateSigned. Repeated failures lock out the source IP with a 429 and
+// are counted against the owner for alerts; see lockout.
 func Authenticate(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	ownerID string,
+	permission Permission,
 	repo repository.Repository,
 	logger *slog.Logger,
-) (*domain.Owner, *response.RequestError) {
-	// Extract token from Authorization header
-	authHeader := request.Headers["Authorization"]
-	if authHeader == "" {
-		// Try lowercase (API Gateway normalizes headers)
-		authHeader = request.Headers["authorization"]
+) (*Identity, *response.RequestError) {
+	return guardIP(ctx, request, repo, logger, func() (*Identity, *response.RequestError) {
+		if signing.IsSigned(authorizationHeader(request)) {
+			return authenticateSigned(ctx, request, ownerID, permission, repo, logger)
+		}
+
+		token, reqErr := extractToken(request, logger)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+
+		return verify(ctx, request, ownerID, token, permission, repo, logger)
+	})
+}
+
+// authorizationHeader returns the request's Authorization header.
+func authorizationHeader(request events.APIGatewayProxyRequest) string {
+	return header(request, "Authorization")
+}
+
+// header returns the named request header, matching the name case-insensitively.
+func header(request events.APIGatewayProxyRequest, name string) string {
+	if value, ok := request.Headers[name]; ok {
+		return value
+	}
+	// Try lowercase (API Gateway normalizes headers)
+	if value, ok := request.Headers[strings.ToLower(name)]; ok {
+		return value
 	}
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
 
-	token := ExtractBearerToken(authHeader)
+// extractToken returns the well-formed API key from the Authorization header.
+func extractToken(request events.APIGatewayProxyRequest, logger *slog.Logger) (string, *response.RequestError) {
+	token := ExtractBearerToken(authorizationHeader(request))
 	if token == "" {
 		logger.Warn("missing or invalid authorization header")
-		return nil, &response.RequestError{
+		return "", &response.RequestError{
 			Status:      http.StatusUnauthorized,
 			Description: "missing or invalid authorization header",
 		}
@@ -40,13 +103,44 @@ func Authenticate(
 	// Validate token format
 	if !ValidateAPIKeyFormat(token) {
 		logger.Warn("invalid API key format")
-		return nil, &response.RequestError{
+		return "", &response.RequestError{
 			Status:      http.StatusUnauthorized,
 			Description: "invalid API key format",
 		}
 	}
 
-	// Get owner from repository
+	return token, nil
+}
+
+// verify checks token against ownerID's keys and the required permission.
+func verify(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	token string,
+	permission Permission,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	owner, reqErr := getOwner(ctx, ownerID, repo, logger)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	now := time.Now().UTC()
+	key, reqErr := findKey(ctx, owner, HashAPIKey(token), repo, logger)
+	if reqErr != nil {
+		if reqErr.Status == http.StatusUnauthorized {
+			ownerFailures(owner.OwnerID).fail(ctx, sourceIP(request), now, repo, logger)
+		}
+		return nil, reqErr
+	}
+
+	return admit(ctx, request, owner, key, permission, now, repo, logger)
+}
+
+// getOwner loads the owner being authenticated.
+func getOwner(ctx context.Context, ownerID string, repo repository.Repository, logger *slog.Logger) (*domain.Owner, *response.RequestError) {
 	owner, err := repo.GetOwner(ctx, ownerID)
 	if err != nil {
 		if repository.IsOwnerNotFound(err) {
@@ -62,52 +156,427 @@ func Authenticate(
 			Description: "authentication failed",
 		}
 	}
+	return owner, nil
+}
+
+// admit checks that the owner isn't suspended or awaiting email verification and
+// that a matched key is unexpired and grants permission, then applies the
+// route's key and owner request limits and records the key's use. The owner's
+// state is only revealed to callers holding a valid key.
+func admit(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	owner *domain.Owner,
+	key domain.APIKey,
+	permission Permission,
+	now time.Time,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	if owner.IsSuspended() {
+		logger.Warn("suspended owner authenticated", "ownerId", owner.OwnerID, "keyId", key.KeyID)
+		return nil, &response.RequestError{
+			Status:      http.StatusForbidden,
+			Description: domain.ErrOwnerSuspended.Error(),
+		}
+	}
+
+	if owner.IsPendingVerification() {
+		logger.Warn("unverified owner authenticated", "ownerId", owner.OwnerID, "keyId", key.KeyID)
+		return nil, &response.RequestError{
+			Status:      http.StatusForbidden,
+			Description: domain.ErrOwnerNotVerified.Error(),
+		}
+	}
+
+	if key.IsExpired(now) {
+		logger.Warn("API key expired", "ownerId", owner.OwnerID, "keyId", key.KeyID, "expiresAt", key.ExpiresAt)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: domain.ErrAPIKeyExpired.Error(),
+		}
+	}
+
+	if reqErr := authorize(key, permission, logger); reqErr != nil {
+		return nil, reqErr
+	}
+
+	if reqErr := throttleIdentity(ctx, owner.OwnerID, key.KeyID, now, repo, logger); reqErr != nil {
+		return nil, reqErr
+	}
+
+	recordUse(ctx, key, now, sourceIP(request), repo, logger)
+
+	logger.Debug("authentication successful", "ownerId", owner.OwnerID, "keyId", key.KeyID)
+	return &Identity{Owner: owner, Key: key}, nil
+}
+
+// authenticateSigned verifies an HMAC-signed request. The key is found by the key ID
+// in the Authorization header; when ownerID is set the key must belong to that owner.
+// The nonce is only stored once the signature checks out, so unsigned requests
+// can't use up nonces.
+func authenticateSigned(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	permission Permission,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	creds, err := signing.ParseAuthorization(authorizationHeader(request))
+	if err != nil {
+		logger.Warn("invalid signature header", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: err.Error(),
+		}
+	}
 
-	// Compare API key hash
-	providedHash := HashAPIKey(token)
-	if !CompareHashes(providedHash, owner.APIKeyHash) {
-		logger.Warn("API key mismatch", "ownerId", ownerID)
+	now := time.Now().UTC()
+	timestamp := header(request, signing.TimestampHeader)
+	signedAt, err := signing.CheckTimestamp(timestamp, now)
+	if err != nil {
+		logger.Warn("invalid signature timestamp", "error", err, "keyId", creds.KeyID, "timestamp", timestamp)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: err.Error(),
+		}
+	}
+
+	nonce := header(request, signing.NonceHeader)
+	if err := signing.CheckNonce(nonce); err != nil {
+		logger.Warn("invalid signature nonce", "keyId", creds.KeyID)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: err.Error(),
+		}
+	}
+
+	keyOwnerID, err := repo.GetKeyOwnerID(ctx, creds.KeyID)
+	if err != nil {
+		if repository.IsAPIKeyNotFound(err) {
+			logger.Warn("unknown key ID", "keyId", creds.KeyID)
+			return nil, &response.RequestError{
+				Status:      http.StatusUnauthorized,
+				Description: "invalid credentials",
+			}
+		}
+		logger.Error("failed to look up key owner", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
+		}
+	}
+	if ownerID != "" && keyOwnerID != ownerID {
+		logger.Warn("signing key belongs to another owner", "ownerId", ownerID, "keyId", creds.KeyID)
 		return nil, &response.RequestError{
 			Status:      http.StatusUnauthorized,
 			Description: "invalid credentials",
 		}
 	}
 
-	logger.Debug("authentication successful", "ownerId", ownerID)
-	return owner, nil
+	owner, reqErr := getOwner(ctx, keyOwnerID, repo, logger)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	failures := ownerFailures(owner.OwnerID)
+	key, reqErr := findKeyByID(ctx, owner, creds.KeyID, repo, logger)
+	if reqErr != nil {
+		if reqErr.Status == http.StatusUnauthorized {
+			failures.fail(ctx, sourceIP(request), now, repo, logger)
+		}
+		return nil, reqErr
+	}
+
+	if key.SigningSecret == "" {
+		logger.Warn("key has no signing secret", "ownerId", owner.OwnerID, "keyId", creds.KeyID)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: "API key has no signing secret; rotate it or create a new key to sign requests",
+		}
+	}
+	secret, err := openSigningSecret(key.SigningSecret, creds.KeyID)
+	if err != nil {
+		logger.Error("failed to open signing secret", "error", err, "ownerId", owner.OwnerID, "keyId", creds.KeyID)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
+		}
+	}
+
+	stringToSign := signing.StringToSign(request.HTTPMethod, request.Path, timestamp, nonce, []byte(request.Body))
+	if !signing.Verify(secret, stringToSign, creds.Signature) {
+		logger.Warn("signature mismatch", "ownerId", owner.OwnerID, "keyId", creds.KeyID)
+		failures.fail(ctx, sourceIP(request), now, repo, logger)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: "invalid signature",
+		}
+	}
+
+	if err := repo.UseNonce(ctx, creds.KeyID, nonce, signedAt.Add(signing.MaxClockSkew)); err != nil {
+		if repository.IsNonceReused(err) {
+			logger.Warn("replayed request", "ownerId", owner.OwnerID, "keyId", creds.KeyID)
+			return nil, &response.RequestError{
+				Status:      http.StatusUnauthorized,
+				Description: domain.ErrNonceReused.Error(),
+			}
+		}
+		logger.Error("failed to store nonce", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
+		}
+	}
+
+	return admit(ctx, request, owner, key, permission, now, repo, logger)
+}
+
+// findKey returns the owner's key matching providedHash. The keys stored on the
+// owner are checked first so the common case needs no extra lookup. A previous
+// key past its grace period is still returned, so admit reports it as expired.
+func findKey(ctx context.Context, owner *domain.Owner, providedHash string, repo repository.Repository, logger *slog.Logger) (domain.APIKey, *response.RequestError) {
+	if CompareHashes(providedHash, owner.APIKeyHash) {
+		return domain.PrimaryAPIKey(*owner), nil
+	}
+	if previous, ok := domain.PreviousAPIKey(*owner); ok && CompareHashes(providedHash, previous.KeyHash) {
+		return previous, nil
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, owner.OwnerID)
+	if err != nil {
+		logger.Error("failed to list API keys", "error", err)
+		return domain.APIKey{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
+		}
+	}
+
+	for _, key := range keys {
+		if CompareHashes(providedHash, key.KeyHash) {
+			return key, nil
+		}
+	}
+
+	logger.Warn("API key mismatch", "ownerId", owner.OwnerID)
+	return domain.APIKey{}, &response.RequestError{
+		Status:      http.StatusUnauthorized,
+		Description: "invalid credentials",
+	}
+}
+
+// findKeyByID returns the owner's key with the given embedded key ID.
+func findKeyByID(ctx context.Context, owner *domain.Owner, keyID string, repo repository.Repository, logger *slog.Logger) (domain.APIKey, *response.RequestError) {
+	if owner.APIKeyID != "" && owner.APIKeyID == keyID {
+		return domain.PrimaryAPIKey(*owner), nil
+	}
+	if previous, ok := domain.PreviousAPIKey(*owner); ok && owner.PreviousAPIKeyID == keyID {
+		return previous, nil
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, owner.OwnerID)
+	if err != nil {
+		logger.Error("failed to list API keys", "error", err)
+		return domain.APIKey{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
+		}
+	}
+
+	for _, key := range keys {
+		if key.KeyID == keyID {
+			return key, nil
+		}
+	}
+
+	// The ID index said the key exists, so it was rotated or revoked since
+	logger.Warn("key ID not found for owner", "ownerId", owner.OwnerID, "keyId", keyID)
+	return domain.APIKey{}, &response.RequestError{
+		Status:      http.StatusUnauthorized,
+		Description: "invalid credentials",
+	}
+}
+
+// recordUse stores the key's last use when the stored data is stale. Failures are
+// logged but don't fail the request.
+func recordUse(ctx context.Context, key domain.APIKey, now time.Time, ip string, repo repository.Repository, logger *slog.Logger) {
+	if !key.NeedsUsageUpdate(now, ip) {
+		return
+	}
+	if err := repo.RecordAPIKeyUse(ctx, key.OwnerID, key.KeyID, now, ip); err != nil {
+		logger.Warn("failed to record API key use", "error", err, "ownerId", key.OwnerID, "keyId", key.KeyID)
+	}
+}
+
+// sourceIP returns the caller's IP as seen by API Gateway, falling back to the
+// first X-Forwarded-For entry.
+func sourceIP(request events.APIGatewayProxyRequest) string {
+	if ip := request.RequestContext.Identity.SourceIP; ip != "" {
+		return ip
+	}
+	forwarded := header(request, "X-Forwarded-For")
+	first, _, _ := strings.Cut(forwarded, ",")
+	return strings.TrimSpace(first)
+}
+
+// authorize checks that key grants permission.
+func authorize(key domain.APIKey, permission Permission, logger *slog.Logger) *response.RequestError {
+	if !key.HasScope(permission.Scope) {
+		logger.Warn("API key missing scope", "ownerId", key.OwnerID, "keyId", key.KeyID, "scope", permission.Scope)
+		return &response.RequestError{
+			Status:      http.StatusForbidden,
+			Description: fmt.Sprintf("API key does not have the %s scope", permission.Scope),
+		}
+	}
+
+	if permission.AnyLocation {
+		return nil
+	}
+
+	if permission.Location == "" {
+		if key.IsRestricted() {
+			logger.Warn("location-restricted API key used for owner-wide operation", "ownerId", key.OwnerID, "keyId", key.KeyID)
+			return &response.RequestError{
+				Status:      http.StatusForbidden,
+				Description: "API key is restricted to specific locations",
+			}
+		}
+		return nil
+	}
+
+	if !key.AllowsLocation(permission.Location) {
+		logger.Warn("API key not allowed for location", "ownerId", key.OwnerID, "keyId", key.KeyID, "location", permission.Location)
+		return &response.RequestError{
+			Status:      http.StatusForbidden,
+			Description: "API key is not allowed for this location",
+		}
+	}
+	return nil
 }
 
 // AuthenticateAny validates the API key and returns the owner it belongs to.
-// Unlike Authenticate, this doesn't require knowing the owner ID upfront.
-// It extracts the owner ID from the token validation process.
+// Unlike Authenticate, this doesn't require knowing the owner ID upfront: the owner
+// is looked up from the key ID embedded in the key. Keys issued before key IDs were
+// introduced can't be resolved and are rejected with a 400 asking for the owner ID.
+// Signed requests name their key ID in the Authorization header.
 func AuthenticateAny(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
+	permission Permission,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	return guardIP(ctx, request, repo, logger, func() (*Identity, *response.RequestError) {
+		return authenticateAny(ctx, request, permission, repo, logger)
+	})
+}
+
+func authenticateAny(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	permission Permission,
 	repo repository.Repository,
 	logger *slog.Logger,
-) (*domain.Owner, string, *response.RequestError) {
-	// Extract token from Authorization header
-	authHeader := request.Headers["Authorization"]
-	if authHeader == "" {
-		authHeader = request.Headers["authorization"]
+) (*Identity, *response.RequestError) {
+	if signing.IsSigned(authorizationHeader(request)) {
+		return authenticateSigned(ctx, request, "", permission, repo, logger)
 	}
 
-	token := ExtractBearerToken(authHeader)
-	if token == "" {
-		logger.Warn("missing or invalid authorization header")
-		return nil, "", &response.RequestError{
-			Status:      http.StatusUnauthorized,
-			Description: "missing or invalid authorization header",
+	token, reqErr := extractToken(request, logger)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	return verifyByKeyID(ctx, request, token, permission, repo, logger)
+}
+
+// verifyByKeyID checks token against the keys of the owner its embedded key ID
+// belongs to.
+func verifyByKeyID(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	token string,
+	permission Permission,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	keyID, ok := ParseKeyID(token)
+	if !ok {
+		logger.Warn("API key has no key ID and no owner ID was given")
+		return nil, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: domain.ErrOwnerIDRequired.Error(),
 		}
 	}
 
-	if !ValidateAPIKeyFormat(token) {
-		logger.Warn("invalid API key format")
-		return nil, "", &response.RequestError{
-			Status:      http.StatusUnauthorized,
-			Description: "invalid API key format",
+	ownerID, err := repo.GetKeyOwnerID(ctx, keyID)
+	if err != nil {
+		if repository.IsAPIKeyNotFound(err) {
+			logger.Warn("unknown key ID", "keyId", keyID)
+			return nil, &response.RequestError{
+				Status:      http.StatusUnauthorized,
+				Description: "invalid credentials",
+			}
+		}
+		logger.Error("failed to look up key owner", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
 		}
 	}
 
-	return nil, token, nil
+	return verify(ctx, request, ownerID, token, permission, repo, logger)
+}
+
+// AuthenticateBasic validates HTTP Basic credentials, as sent by dyndns2 clients
+// such as routers and ddclient. The username is the owner ID and the password is
+// an API key; an empty username resolves the owner from the key, as AuthenticateAny
+// does.
+func AuthenticateBasic(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	permission Permission,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	return guardIP(ctx, request, repo, logger, func() (*Identity, *response.RequestError) {
+		ownerID, token, ok := ExtractBasicCredentials(authorizationHeader(request))
+		if !ok {
+			logger.Warn("missing or invalid basic authorization header")
+			return nil, &response.RequestError{
+				Status:      http.StatusUnauthorized,
+				Description: "missing or invalid authorization header",
+			}
+		}
+
+		if !ValidateAPIKeyFormat(token) {
+			logger.Warn("invalid API key format")
+			return nil, &response.RequestError{
+				Status:      http.StatusUnauthorized,
+				Description: "invalid API key format",
+			}
+		}
+
+		if ownerID == "" {
+			return verifyByKeyID(ctx, request, token, permission, repo, logger)
+		}
+		return verify(ctx, request, ownerID, token, permission, repo, logger)
+	})
+}
+
+// AuthenticateOwnerOrKey authenticates against ownerID when the request names one,
+// and resolves the owner from the key otherwise.
+func AuthenticateOwnerOrKey(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	permission Permission,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*Identity, *response.RequestError) {
+	if ownerID != "" {
+		return Authenticate(ctx, request, ownerID, permission, repo, logger)
+	}
+	return AuthenticateAny(ctx, request, permission, repo, logger)
 }
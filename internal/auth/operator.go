@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// OperatorKeyPrefix is prepended to operator keys. Operator keys are never accepted
+// where an owner's API key is expected, and vice versa.
+const OperatorKeyPrefix = "ddns_op_"
+
+// GenerateOperatorKey creates a new operator key of the form ddns_op_{keyId}.{secret}.
+func GenerateOperatorKey() (key, keyID string, err error) {
+	return generateKey(OperatorKeyPrefix)
+}
+
+// ParseOperatorKeyID returns the key ID of a well-formed operator key.
+func ParseOperatorKeyID(key string) (string, bool) {
+	encoded, found := strings.CutPrefix(key, OperatorKeyPrefix)
+	if !found {
+		return "", false
+	}
+	keyID, secret, found := strings.Cut(encoded, KeyIDSeparator)
+	if !found || !isKeyID(keyID) || secret == "" {
+		return "", false
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(secret); err != nil {
+		return "", false
+	}
+	return keyID, true
+}
+
+// AuthenticateOperator validates the operator key from the Authorization header and
+// returns the operator it belongs to. Failures count towards the source IP's lockout;
+// the route's key request limit applies to the operator key once it checks out.
+func AuthenticateOperator(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*domain.Operator, *response.RequestError) {
+	return guardIP(ctx, request, repo, logger, func() (*domain.Operator, *response.RequestError) {
+		return authenticateOperator(ctx, request, repo, logger)
+	})
+}
+
+func authenticateOperator(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (*domain.Operator, *response.RequestError) {
+	token := ExtractBearerToken(authorizationHeader(request))
+	if token == "" {
+		logger.Warn("missing or invalid authorization header")
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: "missing or invalid authorization header",
+		}
+	}
+
+	keyID, ok := ParseOperatorKeyID(token)
+	if !ok {
+		logger.Warn("invalid operator key format")
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: "invalid operator key format",
+		}
+	}
+
+	operator, err := repo.GetOperator(ctx, keyID)
+	if err != nil {
+		if repository.IsOperatorNotFound(err) {
+			logger.Warn("operator not found", "keyId", keyID)
+			return nil, &response.RequestError{
+				Status:      http.StatusUnauthorized,
+				Description: "invalid credentials",
+			}
+		}
+		logger.Error("failed to get operator", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "authentication failed",
+		}
+	}
+
+	if !CompareHashes(HashAPIKey(token), operator.KeyHash) {
+		logger.Warn("operator key mismatch", "keyId", keyID)
+		return nil, &response.RequestError{
+			Status:      http.StatusUnauthorized,
+			Description: "invalid credentials",
+		}
+	}
+
+	if reqErr := throttleIdentity(ctx, "", keyID, time.Now().UTC(), repo, logger); reqErr != nil {
+		return nil, reqErr
+	}
+
+	logger.Debug("operator authenticated", "keyId", keyID, "operator", operator.Name)
+	return operator, nil
+}
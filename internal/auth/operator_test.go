@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+func TestGenerateOperatorKey(t *testing.T) {
+	key, keyID, err := GenerateOperatorKey()
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(key, OperatorKeyPrefix+keyID+KeyIDSeparator))
+
+	parsed, ok := ParseOperatorKeyID(key)
+	assert.Assert(t, ok)
+	assert.Equal(t, keyID, parsed)
+
+	// Operator keys are never valid API keys
+	assert.Assert(t, !ValidateAPIKeyFormat(key))
+}
+
+func TestParseOperatorKeyID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		key      string
+		expected string
+		ok       bool
+	}{
+		{name: "valid", key: "ddns_op_0123456789ab.ABCDEFGH", expected: "0123456789ab", ok: true},
+		{name: "API key", key: "ddns_sk_0123456789ab.ABCDEFGH"},
+		{name: "missing key ID", key: "ddns_op_ABCDEFGH"},
+		{name: "invalid key ID", key: "ddns_op_XYZ.ABCDEFGH"},
+		{name: "missing secret", key: "ddns_op_0123456789ab."},
+		{name: "invalid secret", key: "ddns_op_0123456789ab.!!!"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyID, ok := ParseOperatorKeyID(tc.key)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, keyID)
+		})
+	}
+}
+
+func TestAuthenticateOperator(t *testing.T) {
+	operatorKey := "ddns_op_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	operator := &domain.Operator{
+		KeyID:     "0123456789ab",
+		Name:      "alice",
+		KeyHash:   HashAPIKey(operatorKey),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	testCases := []struct {
+		name           string
+		header         string
+		getErr         error
+		expectedStatus int
+	}{
+		{name: "valid", header: "Bearer " + operatorKey},
+		{name: "missing header", expectedStatus: http.StatusUnauthorized},
+		{name: "API key", header: "Bearer ddns_sk_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong secret", header: "Bearer ddns_op_0123456789ab.ZZZZ", expectedStatus: http.StatusUnauthorized},
+		{name: "unknown operator", header: "Bearer " + operatorKey, getErr: domain.ErrOperatorNotFound, expectedStatus: http.StatusUnauthorized},
+		{name: "lookup error", header: "Bearer " + operatorKey, getErr: errors.New("boom"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockRepository{
+				getOperatorFunc: func(ctx context.Context, keyID string) (*domain.Operator, error) {
+					if tc.getErr != nil {
+						return nil, tc.getErr
+					}
+					assert.Equal(t, "0123456789ab", keyID)
+					return operator, nil
+				},
+			}
+			request := events.APIGatewayProxyRequest{Headers: map[string]string{}}
+			if tc.header != "" {
+				request.Headers["Authorization"] = tc.header
+			}
+
+			result, err := AuthenticateOperator(context.Background(), request, repo, newTestLogger())
+
+			if tc.expectedStatus == 0 {
+				assert.Assert(t, err == nil, "expected no error, got %v", err)
+				assert.Equal(t, "alice", result.Name)
+				return
+			}
+			assert.Assert(t, result == nil)
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestAuthenticateOperator_ThrottlesKey(t *testing.T) {
+	operatorKey := "ddns_op_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	var buckets []string
+	repo := &mockRepository{
+		getOperatorFunc: func(ctx context.Context, keyID string) (*domain.Operator, error) {
+			return &domain.Operator{KeyID: keyID, Name: "alice", KeyHash: HashAPIKey(operatorKey)}, nil
+		},
+		takeRequestTokenFunc: func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+			buckets = append(buckets, bucket)
+			return 0, true, nil
+		},
+	}
+	request := throttleRequest("/admin/owners", "Bearer "+operatorKey)
+
+	ctx, _ := Throttle(context.Background(), request, "admin", throttleTestLimits, repo, newTestLogger())
+	_, err := AuthenticateOperator(ctx, request, repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.DeepEqual(t, []string{"admin#ip#198.51.100.7", "admin#key#0123456789ab"}, buckets)
+}
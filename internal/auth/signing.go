@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/grocky/ddns-service/internal/signing"
+)
+
+// ErrSigningNotConfigured is returned when signing secrets are needed before
+// ConfigureSigning has been called.
+var ErrSigningNotConfigured = errors.New("request signing is not configured")
+
+// signingSecrets seals the signing secrets of new keys and opens them to verify
+// signed requests.
+var signingSecrets *signing.SecretBox
+
+// ConfigureSigning sets the SecretBox that protects stored signing secrets.
+func ConfigureSigning(secrets *signing.SecretBox) {
+	signingSecrets = secrets
+}
+
+// NewSigningSecret generates the signing secret for the key with keyID. The
+// secret is shown to the owner once; sealed is what the server stores.
+func NewSigningSecret(keyID string) (secret, sealed string, err error) {
+	if signingSecrets == nil {
+		return "", "", ErrSigningNotConfigured
+	}
+	secret, err = signing.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err = signingSecrets.Seal(secret, keyID)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, sealed, nil
+}
+
+// openSigningSecret decrypts the stored signing secret of the key with keyID.
+func openSigningSecret(sealed, keyID string) (string, error) {
+	if signingSecrets == nil {
+		return "", ErrSigningNotConfigured
+	}
+	return signingSecrets.Open(sealed, keyID)
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/grocky/ddns-service/internal/signing"
+	"gotest.tools/assert"
+)
+
+func TestNewSigningSecret(t *testing.T) {
+	secrets := configureTestSigning(t)
+
+	secret, sealed, err := NewSigningSecret("0123456789ab")
+
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(secret, signing.SecretPrefix))
+	assert.Assert(t, !strings.Contains(sealed, secret), "the stored form must not contain the secret")
+
+	opened, err := secrets.Open(sealed, "0123456789ab")
+	assert.NilError(t, err)
+	assert.Equal(t, secret, opened)
+}
+
+func TestNewSigningSecret_NotConfigured(t *testing.T) {
+	_, _, err := NewSigningSecret("0123456789ab")
+
+	assert.Assert(t, errors.Is(err, ErrSigningNotConfigured))
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// routeLimitsKey is the context key for the request limits of the route being
+// served.
+type routeLimitsKey struct{}
+
+type routeLimits struct {
+	route  string
+	limits domain.RouteLimits
+}
+
+// Throttle applies a route's request limit to the caller's source IP before the
+// request is authenticated. It returns ctx carrying the route's limits, and the
+// limits per API key and per owner are applied once authentication has
+// established them. Keys and owners a request only claims are never charged, so
+// a caller can't spend the tokens of someone else's key or owner.
+//
+// Like the auth lockout, throttling fails open: if the request limit table can't
+// be read or written the request proceeds.
+func Throttle(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	route string,
+	limits domain.RouteLimits,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (context.Context, *response.RequestError) {
+	ctx = context.WithValue(ctx, routeLimitsKey{}, routeLimits{route: route, limits: limits})
+	now := time.Now().UTC()
+	return ctx, takeRequestToken(ctx, route, domain.LimitSubjectIP, sourceIP(request), limits.IP, now, repo, logger)
+}
+
+// throttleIdentity applies the limits per API key and per owner of the route
+// being served to an authenticated key. ownerID is empty for operator keys.
+// Nothing is limited outside a throttled request.
+func throttleIdentity(
+	ctx context.Context,
+	ownerID string,
+	keyID string,
+	now time.Time,
+	repo repository.Repository,
+	logger *slog.Logger,
+) *response.RequestError {
+	current, ok := ctx.Value(routeLimitsKey{}).(routeLimits)
+	if !ok {
+		return nil
+	}
+
+	// Primary and previous keys share their key IDs across owners
+	keyBucketID := keyID
+	if ownerID != "" {
+		keyBucketID = ownerID + "/" + keyID
+	}
+	if reqErr := takeRequestToken(ctx, current.route, domain.LimitSubjectKey, keyBucketID, current.limits.Key, now, repo, logger); reqErr != nil {
+		return reqErr
+	}
+	return takeRequestToken(ctx, current.route, domain.LimitSubjectOwner, ownerID, current.limits.Owner, now, repo, logger)
+}
+
+// takeRequestToken takes a token from a subject's bucket for route, returning a
+// 429 once the bucket is empty. Subjects without an ID or a limit are skipped.
+func takeRequestToken(
+	ctx context.Context,
+	route string,
+	kind string,
+	id string,
+	limit domain.RequestLimit,
+	now time.Time,
+	repo repository.Repository,
+	logger *slog.Logger,
+) *response.RequestError {
+	if id == "" || limit.Unlimited() {
+		return nil
+	}
+
+	bucket := domain.RequestBucket(route, kind, id)
+	retryAfter, allowed, err := repo.TakeRequestToken(ctx, bucket, limit, now)
+	if err != nil {
+		logger.Warn("failed to check request limit", "error", err, "bucket", bucket)
+		return nil
+	}
+	if !allowed {
+		logger.Warn("request limit exceeded", "bucket", bucket, "retryAfter", retryAfter)
+		return &response.RequestError{
+			Status:      http.StatusTooManyRequests,
+			Description: domain.ErrTooManyRequests.Error(),
+			RetryAfter:  max(1, int(math.Ceil(retryAfter.Seconds()))),
+		}
+	}
+	return nil
+}
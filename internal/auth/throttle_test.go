@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/signing"
+	"gotest.tools/assert"
+)
+
+var throttleTestLimits = domain.RouteLimits{
+	IP:    domain.RequestLimit{Burst: 10, Interval: time.Second},
+	Key:   domain.RequestLimit{Burst: 5, Interval: time.Second},
+	Owner: domain.RequestLimit{Burst: 3, Interval: time.Minute},
+}
+
+func throttleRequest(path, authHeader string) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{
+		Path:    path,
+		Headers: map[string]string{"Authorization": authHeader},
+	}
+	request.RequestContext.Identity.SourceIP = "198.51.100.7"
+	return request
+}
+
+func TestThrottle_LimitsSourceIPOnly(t *testing.T) {
+	basic := func(username, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+
+	// Keys and owners named before authentication are never charged
+	testCases := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+	}{
+		{name: "anonymous", request: throttleRequest("/owners", "")},
+		{name: "bearer key", request: throttleRequest("/update", "Bearer ddns_sk_0123456789ab.ABCDEFGH")},
+		{name: "signed request", request: throttleRequest("/update", signing.FormatAuthorization("0123456789ab", "c2lnbmF0dXJl"))},
+		{name: "owner path", request: throttleRequest("/owners/owner-1/recover", "")},
+		{name: "basic credentials", request: throttleRequest("/nic/update", basic("owner-1", "ddns_sk_0123456789ab.ABCDEFGH"))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buckets []string
+			repo := &mockRepository{
+				takeRequestTokenFunc: func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+					buckets = append(buckets, bucket)
+					return 0, true, nil
+				},
+			}
+
+			_, reqErr := Throttle(context.Background(), tc.request, "route", throttleTestLimits, repo, newTestLogger())
+
+			assert.Assert(t, reqErr == nil)
+			assert.DeepEqual(t, []string{"route#ip#198.51.100.7"}, buckets)
+		})
+	}
+}
+
+func TestThrottle_Exceeded(t *testing.T) {
+	repo := &mockRepository{
+		takeRequestTokenFunc: func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+			return 1500 * time.Millisecond, false, nil
+		},
+	}
+
+	_, reqErr := Throttle(context.Background(), throttleRequest("/update", ""), "update", throttleTestLimits, repo, newTestLogger())
+
+	assert.Assert(t, reqErr != nil)
+	assert.Equal(t, http.StatusTooManyRequests, reqErr.Status)
+	assert.Equal(t, domain.ErrTooManyRequests.Error(), reqErr.Description)
+	assert.Equal(t, 2, reqErr.RetryAfter)
+}
+
+func TestThrottle_RetryAfterAtLeastOneSecond(t *testing.T) {
+	repo := &mockRepository{
+		takeRequestTokenFunc: func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+			return 0, false, nil
+		},
+	}
+
+	_, reqErr := Throttle(context.Background(), throttleRequest("/owners", ""), "create-owner", throttleTestLimits, repo, newTestLogger())
+
+	assert.Assert(t, reqErr != nil)
+	assert.Equal(t, 1, reqErr.RetryAfter)
+}
+
+func TestThrottle_FailsOpen(t *testing.T) {
+	repo := &mockRepository{
+		takeRequestTokenFunc: func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+			return 0, false, errors.New("dynamodb unavailable")
+		},
+	}
+
+	_, reqErr := Throttle(context.Background(), throttleRequest("/update", ""), "update", throttleTestLimits, repo, newTestLogger())
+
+	assert.Assert(t, reqErr == nil)
+}
+
+func TestAuthenticate_ThrottlesAuthenticatedIdentity(t *testing.T) {
+	var buckets []string
+	repo := newLockoutTestRepo()
+	repo.takeRequestTokenFunc = func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+		buckets = append(buckets, bucket)
+		return 0, true, nil
+	}
+	request := lockoutRequest(lockoutTestKey)
+
+	ctx, reqErr := Throttle(context.Background(), request, "update", throttleTestLimits, repo, newTestLogger())
+	assert.Assert(t, reqErr == nil)
+	_, reqErr = Authenticate(ctx, request, "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, reqErr == nil, "expected no error, got %v", reqErr)
+	assert.DeepEqual(t, []string{
+		"update#ip#" + sourceIP(request),
+		"update#key#test-owner/primary",
+		"update#owner#test-owner",
+	}, buckets)
+}
+
+func TestAuthenticate_ThrottleSkipsFailedAuthentication(t *testing.T) {
+	var buckets []string
+	repo := newLockoutTestRepo()
+	repo.takeRequestTokenFunc = func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+		buckets = append(buckets, bucket)
+		return 0, true, nil
+	}
+	request := lockoutRequest("ddns_sk_ZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+	ctx, _ := Throttle(context.Background(), request, "update", throttleTestLimits, repo, newTestLogger())
+	_, reqErr := Authenticate(ctx, request, "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Equal(t, http.StatusUnauthorized, reqErr.Status)
+	assert.DeepEqual(t, []string{"update#ip#" + sourceIP(request)}, buckets)
+}
+
+func TestAuthenticate_KeyLimitExceeded(t *testing.T) {
+	repo := newLockoutTestRepo()
+	repo.takeRequestTokenFunc = func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+		if bucket == "update#key#test-owner/primary" {
+			return 1500 * time.Millisecond, false, nil
+		}
+		return 0, true, nil
+	}
+	request := lockoutRequest(lockoutTestKey)
+
+	ctx, _ := Throttle(context.Background(), request, "update", throttleTestLimits, repo, newTestLogger())
+	_, reqErr := Authenticate(ctx, request, "test-owner", updateHome, repo, newTestLogger())
+
+	assert.Assert(t, reqErr != nil)
+	assert.Equal(t, http.StatusTooManyRequests, reqErr.Status)
+	assert.Equal(t, 2, reqErr.RetryAfter)
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/grocky/ddns-service/internal/signing"
 )
 
 const (
@@ -17,9 +22,11 @@ const (
 
 // Client is the DDNS API client.
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	apiKey     string
+	httpClient    *http.Client
+	baseURL       string
+	apiKey        string
+	signingSecret string
+	sign          bool
 }
 
 // New creates a new DDNS API client.
@@ -35,15 +42,48 @@ func New(cfg Config) *Client {
 	}
 
 	return &Client{
-		httpClient: &http.Client{Timeout: timeout},
-		baseURL:    baseURL,
-		apiKey:     cfg.APIKey,
+		httpClient:    &http.Client{Timeout: timeout},
+		baseURL:       baseURL,
+		apiKey:        cfg.APIKey,
+		signingSecret: cfg.SigningSecret,
+		sign:          cfg.Sign,
 	}
 }
 
+// authorize adds credentials to req: a signature when signing is enabled, the
+// bearer key otherwise. path is the API path without the base URL.
+func (c *Client) authorize(req *http.Request, path string, body []byte) error {
+	if !c.sign {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		return nil
+	}
+
+	keyID, ok := keyIDFromAPIKey(c.apiKey)
+	if !ok {
+		return errors.New("request signing needs an API key with a key ID; rotate your key to get one")
+	}
+	if c.signingSecret == "" {
+		return errors.New("request signing needs the signing secret issued with the API key")
+	}
+	return signing.SignRequest(req, path, keyID, c.signingSecret, body, time.Now())
+}
+
+// keyIDFromAPIKey returns the key ID from keys formatted ddns_sk_{keyId}.{secret}.
+func keyIDFromAPIKey(apiKey string) (string, bool) {
+	rest, found := strings.CutPrefix(apiKey, "ddns_sk_")
+	if !found {
+		return "", false
+	}
+	keyID, _, found := strings.Cut(rest, ".")
+	return keyID, found && keyID != ""
+}
+
 // RateLimitError indicates the request was rate limited.
 type RateLimitError struct {
 	RetryAfter string
+
+	// RateLimit is the exhausted limit, when the server reported it.
+	RateLimit *RateLimit
 }
 
 func (e *RateLimitError) Error() string {
@@ -73,7 +113,9 @@ func (c *Client) UpdateDNS(ctx context.Context, owner, location, ip string) (*Up
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(httpReq, "/update", body); err != nil {
+		return nil, fmt.Errorf("failed to authorize request: %w", err)
+	}
 	httpReq.Header.Set("User-Agent", "ddns-client/1.0")
 
 	resp, err := c.httpClient.Do(httpReq)
@@ -89,7 +131,7 @@ func (c *Client) UpdateDNS(ctx context.Context, owner, location, ip string) (*Up
 
 	if resp.StatusCode == http.StatusTooManyRequests {
 		retryAfter := resp.Header.Get("Retry-After")
-		return nil, &RateLimitError{RetryAfter: retryAfter}
+		return nil, &RateLimitError{RetryAfter: retryAfter, RateLimit: parseRateLimit(resp.Header)}
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -104,10 +146,33 @@ func (c *Client) UpdateDNS(ctx context.Context, owner, location, ip string) (*Up
 	if err := json.Unmarshal(respBody, &updateResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	updateResp.RateLimit = parseRateLimit(resp.Header)
 
 	return &updateResp, nil
 }
 
+// parseRateLimit reads the RateLimit-* response headers. It returns nil unless
+// all three are present and valid.
+func parseRateLimit(header http.Header) *RateLimit {
+	limit, err := strconv.Atoi(header.Get("RateLimit-Limit"))
+	if err != nil || limit < 0 {
+		return nil
+	}
+	remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining"))
+	if err != nil || remaining < 0 {
+		return nil
+	}
+	reset, err := strconv.Atoi(header.Get("RateLimit-Reset"))
+	if err != nil || reset < 0 {
+		return nil
+	}
+	return &RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Duration(reset) * time.Second,
+	}
+}
+
 // CreateACMEChallenge creates an ACME DNS-01 challenge TXT record.
 func (c *Client) CreateACMEChallenge(ctx context.Context, owner, location, txtValue string) (*ACMEChallengeResponse, error) {
 	req := CreateChallengeRequest{
@@ -127,7 +192,9 @@ func (c *Client) CreateACMEChallenge(ctx context.Context, owner, location, txtVa
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(httpReq, "/acme-challenge", body); err != nil {
+		return nil, fmt.Errorf("failed to authorize request: %w", err)
+	}
 	httpReq.Header.Set("User-Agent", "ddns-client/1.0")
 
 	resp, err := c.httpClient.Do(httpReq)
@@ -180,7 +247,9 @@ func (c *Client) DeleteACMEChallenge(ctx context.Context, owner, location string
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(httpReq, "/acme-challenge", body); err != nil {
+		return nil, fmt.Errorf("failed to authorize request: %w", err)
+	}
 	httpReq.Header.Set("User-Agent", "ddns-client/1.0")
 
 	resp, err := c.httpClient.Do(httpReq)
@@ -219,7 +288,9 @@ func (c *Client) Lookup(ctx context.Context, owner, location string) (*LookupRes
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(httpReq, "/lookup", nil); err != nil {
+		return nil, fmt.Errorf("failed to authorize request: %w", err)
+	}
 	httpReq.Header.Set("User-Agent", "ddns-client/1.0")
 
 	resp, err := c.httpClient.Do(httpReq)
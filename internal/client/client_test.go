@@ -3,10 +3,14 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/grocky/ddns-service/internal/signing"
 	"gotest.tools/assert"
 )
 
@@ -59,6 +63,9 @@ func TestUpdateDNS_Success(t *testing.T) {
 			UpdatedAt: "2025-01-15T10:30:00Z",
 		}
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("RateLimit-Limit", "2")
+		w.Header().Set("RateLimit-Remaining", "1")
+		w.Header().Set("RateLimit-Reset", "900")
 		json.NewEncoder(w).Encode(resp)
 	}))
 	defer server.Close()
@@ -76,11 +83,90 @@ func TestUpdateDNS_Success(t *testing.T) {
 	assert.Equal(t, "203.0.113.42", resp.IP)
 	assert.Equal(t, "abc12345.grocky.net", resp.Subdomain)
 	assert.Equal(t, true, resp.Changed)
+	assert.DeepEqual(t, &RateLimit{Limit: 2, Remaining: 1, Reset: 15 * time.Minute}, resp.RateLimit)
+}
+
+func TestUpdateDNS_Dampened(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ownerId":"test-owner","location":"home","ip":"203.0.113.42","subdomain":"abc12345.grocky.net","dampened":true,"pendingIp":"198.51.100.7","updatedAt":"2025-01-15T10:30:00Z"}`))
+	}))
+	defer server.Close()
+
+	c := New(Config{
+		APIURL: server.URL,
+		APIKey: "test-key",
+	})
+
+	resp, err := c.UpdateDNS(context.Background(), "test-owner", "home", "198.51.100.7")
+
+	assert.NilError(t, err)
+	assert.Assert(t, resp.Dampened)
+	assert.Assert(t, !resp.Changed)
+	assert.Equal(t, "203.0.113.42", resp.IP)
+	assert.Equal(t, "198.51.100.7", resp.PendingIP)
+}
+
+func TestUpdateDNS_Signed(t *testing.T) {
+	apiKey := "ddns_sk_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	signingSecret := "ddns_ss_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopq"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds, err := signing.ParseAuthorization(r.Header.Get("Authorization"))
+		assert.NilError(t, err)
+		assert.Equal(t, "0123456789ab", creds.KeyID)
+
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+		s := signing.StringToSign(r.Method, "/update", r.Header.Get(signing.TimestampHeader), r.Header.Get(signing.NonceHeader), body)
+		assert.Assert(t, signing.Verify(signingSecret, s, creds.Signature))
+		assert.Assert(t, !strings.Contains(r.Header.Get("Authorization"), apiKey), "the API key must not be sent")
+
+		json.NewEncoder(w).Encode(UpdateResponse{OwnerID: "test-owner", Location: "home"})
+	}))
+	defer server.Close()
+
+	c := New(Config{
+		APIURL:        server.URL,
+		APIKey:        apiKey,
+		Sign:          true,
+		SigningSecret: signingSecret,
+	})
+
+	_, err := c.UpdateDNS(context.Background(), "test-owner", "home", "203.0.113.42")
+	assert.NilError(t, err)
+}
+
+func TestUpdateDNS_SignedLegacyKey(t *testing.T) {
+	c := New(Config{
+		APIURL: "https://test.example.com",
+		APIKey: "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop",
+		Sign:   true,
+
+		SigningSecret: "ddns_ss_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopq",
+	})
+
+	_, err := c.UpdateDNS(context.Background(), "test-owner", "home", "203.0.113.42")
+	assert.ErrorContains(t, err, "needs an API key with a key ID")
+}
+
+func TestUpdateDNS_SignedWithoutSecret(t *testing.T) {
+	c := New(Config{
+		APIURL: "https://test.example.com",
+		APIKey: "ddns_sk_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop",
+		Sign:   true,
+	})
+
+	_, err := c.UpdateDNS(context.Background(), "test-owner", "home", "203.0.113.42")
+	assert.ErrorContains(t, err, "needs the signing secret")
 }
 
 func TestUpdateDNS_RateLimit(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Retry-After", "1800")
+		w.Header().Set("RateLimit-Limit", "2")
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", "1800")
 		w.WriteHeader(http.StatusTooManyRequests)
 		json.NewEncoder(w).Encode(ErrorResponse{
 			Description: "rate limit exceeded",
@@ -101,6 +187,40 @@ func TestUpdateDNS_RateLimit(t *testing.T) {
 	rateLimitErr, ok := err.(*RateLimitError)
 	assert.Assert(t, ok, "expected RateLimitError")
 	assert.Equal(t, "1800", rateLimitErr.RetryAfter)
+	assert.DeepEqual(t, &RateLimit{Limit: 2, Remaining: 0, Reset: 30 * time.Minute}, rateLimitErr.RateLimit)
+}
+
+func TestParseRateLimit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected *RateLimit
+	}{
+		{
+			name:     "all headers",
+			headers:  map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "3", "RateLimit-Reset": "60"},
+			expected: &RateLimit{Limit: 10, Remaining: 3, Reset: time.Minute},
+		},
+		{name: "no headers"},
+		{
+			name:    "missing reset",
+			headers: map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "3"},
+		},
+		{
+			name:    "invalid remaining",
+			headers: map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "lots", "RateLimit-Reset": "60"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tc.headers {
+				header.Set(name, value)
+			}
+			assert.DeepEqual(t, tc.expected, parseRateLimit(header))
+		})
+	}
 }
 
 func TestUpdateDNS_Unauthorized(t *testing.T) {
@@ -17,6 +17,24 @@ type UpdateResponse struct {
 	Subdomain string `json:"subdomain"`
 	Changed   bool   `json:"changed"`
 	UpdatedAt string `json:"updatedAt"`
+
+	// Dampened is set when the server held back the change to PendingIP because
+	// the location is flapping. IP is the address still published.
+	Dampened  bool   `json:"dampened,omitempty"`
+	PendingIP string `json:"pendingIp,omitempty"`
+
+	// RateLimit is parsed from the response headers. It is nil when the server
+	// didn't send them.
+	RateLimit *RateLimit `json:"-"`
+}
+
+// RateLimit is the location's IP change rate limit status, from the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset response headers.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	// Reset is how long until the limit resets.
+	Reset time.Duration
 }
 
 // ErrorResponse represents an API error.
@@ -31,6 +49,12 @@ type Config struct {
 	Owner    string
 	Location string
 	Timeout  time.Duration
+
+	// Sign signs requests with SigningSecret instead of sending the API key as a
+	// bearer token. It needs a key with an embedded key ID and the signing
+	// secret issued with it.
+	Sign          bool
+	SigningSecret string
 }
 
 // CreateChallengeRequest represents the request to create an ACME challenge.
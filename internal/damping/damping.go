@@ -0,0 +1,93 @@
+package damping
+
+import (
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+)
+
+// maxHistoryAge bounds how long a replaced IP is remembered, since no flap window
+// is longer.
+const maxHistoryAge = domain.MaxFlapWindowSeconds * time.Second
+
+// Result is the outcome of checking an IP change against a location's damping policy.
+type Result struct {
+	// Dampened is true when the change must not be published yet.
+	Dampened bool
+
+	// Started is true when the change starts a new period of damping, which is
+	// when the owner is notified.
+	Started bool
+}
+
+// Check decides whether changing the mapping's IP to ip is held back. Changes
+// to an IP the location was published at within the flap window are flapping:
+// in hold mode they are held until that IP falls out of the window, and in
+// settle mode until ip has been reported for the settle time. A dampened change
+// is recorded on the mapping as pending, and the caller saves it.
+func Check(mapping *domain.IPMapping, ip string, now time.Time) Result {
+	if mapping == nil || !mapping.Damping.Enabled() {
+		return Result{}
+	}
+	policy := *mapping.Damping
+
+	if !IsFlapping(mapping, ip, policy.FlapWindow(), now) {
+		return Result{}
+	}
+
+	if mapping.PendingIP != ip {
+		mapping.PendingIP = ip
+		mapping.PendingIPSince = now
+	}
+	if policy.Mode == domain.DampingSettle && now.Sub(mapping.PendingIPSince) >= policy.SettleTime() {
+		return Result{}
+	}
+
+	started := mapping.LastDampenedAt.IsZero() || now.Sub(mapping.LastDampenedAt) > policy.FlapWindow()
+	mapping.LastDampenedAt = now
+	return Result{Dampened: true, Started: started}
+}
+
+// IsFlapping reports whether the mapping was published at ip less than window ago.
+func IsFlapping(mapping *domain.IPMapping, ip string, window time.Duration, now time.Time) bool {
+	for _, published := range mapping.IPHistory {
+		if published.IP == ip && now.Sub(published.ReplacedAt) < window {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearPending forgets a pending IP after the location reports its published IP
+// again, so a settling IP has to be reported consistently. It returns false if
+// there was nothing to clear.
+func ClearPending(mapping *domain.IPMapping) bool {
+	if mapping.PendingIP == "" {
+		return false
+	}
+	mapping.PendingIP = ""
+	mapping.PendingIPSince = time.Time{}
+	return true
+}
+
+// RecordChange remembers previousIP, which the mapping's new IP replaces, for
+// flap detection, and clears any pending IP. History is kept whether or not
+// damping is on, so turning it on takes effect immediately.
+func RecordChange(mapping *domain.IPMapping, previousIP string, now time.Time) {
+	ClearPending(mapping)
+
+	history := make([]domain.PublishedIP, 0, domain.MaxIPHistory)
+	for _, published := range mapping.IPHistory {
+		if published.IP == previousIP || published.IP == mapping.IP || now.Sub(published.ReplacedAt) >= maxHistoryAge {
+			continue
+		}
+		history = append(history, published)
+	}
+	if previousIP != "" {
+		history = append(history, domain.PublishedIP{IP: previousIP, ReplacedAt: now})
+	}
+	if len(history) > domain.MaxIPHistory {
+		history = history[len(history)-domain.MaxIPHistory:]
+	}
+	mapping.IPHistory = history
+}
@@ -0,0 +1,172 @@
+package damping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+var now = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func flappingMapping(mode string) *domain.IPMapping {
+	return &domain.IPMapping{
+		IP:        "198.51.100.2",
+		Damping:   &domain.DampingPolicy{Mode: mode, FlapWindowSeconds: 3600, SettleSeconds: 600},
+		IPHistory: []domain.PublishedIP{{IP: "198.51.100.1", ReplacedAt: now.Add(-5 * time.Minute)}},
+	}
+}
+
+func TestCheck_NoDamping(t *testing.T) {
+	mapping := flappingMapping(domain.DampingHold)
+	mapping.Damping = nil
+
+	assert.Equal(t, Result{}, Check(mapping, "198.51.100.1", now))
+	assert.Equal(t, Result{}, Check(nil, "198.51.100.1", now))
+
+	mapping.Damping = &domain.DampingPolicy{Mode: domain.DampingOff, FlapWindowSeconds: 3600}
+	assert.Equal(t, Result{}, Check(mapping, "198.51.100.1", now))
+}
+
+func TestCheck_NewIP(t *testing.T) {
+	mapping := flappingMapping(domain.DampingHold)
+
+	result := Check(mapping, "203.0.113.9", now)
+
+	assert.Equal(t, Result{}, result, "an IP the location hasn't had isn't flapping")
+	assert.Equal(t, "", mapping.PendingIP)
+}
+
+func TestCheck_OutsideFlapWindow(t *testing.T) {
+	mapping := flappingMapping(domain.DampingHold)
+	mapping.IPHistory[0].ReplacedAt = now.Add(-time.Hour)
+
+	assert.Equal(t, Result{}, Check(mapping, "198.51.100.1", now))
+}
+
+func TestCheck_Hold(t *testing.T) {
+	mapping := flappingMapping(domain.DampingHold)
+
+	result := Check(mapping, "198.51.100.1", now)
+	assert.Equal(t, Result{Dampened: true, Started: true}, result)
+	assert.Equal(t, "198.51.100.1", mapping.PendingIP)
+	assert.Equal(t, now, mapping.LastDampenedAt)
+
+	// Held however long it is reported, until it leaves the flap window
+	result = Check(mapping, "198.51.100.1", now.Add(50*time.Minute))
+	assert.Equal(t, Result{Dampened: true}, result)
+
+	result = Check(mapping, "198.51.100.1", now.Add(55*time.Minute))
+	assert.Equal(t, Result{}, result)
+}
+
+func TestCheck_Settle(t *testing.T) {
+	mapping := flappingMapping(domain.DampingSettle)
+
+	assert.Equal(t, Result{Dampened: true, Started: true}, Check(mapping, "198.51.100.1", now))
+	assert.Equal(t, Result{Dampened: true}, Check(mapping, "198.51.100.1", now.Add(5*time.Minute)))
+	assert.Equal(t, Result{}, Check(mapping, "198.51.100.1", now.Add(10*time.Minute)))
+}
+
+func TestCheck_SettleRestartsWhenIPChanges(t *testing.T) {
+	mapping := flappingMapping(domain.DampingSettle)
+	mapping.IPHistory = append(mapping.IPHistory, domain.PublishedIP{IP: "198.51.100.3", ReplacedAt: now.Add(-4 * time.Minute)})
+
+	Check(mapping, "198.51.100.1", now)
+	Check(mapping, "198.51.100.3", now.Add(8*time.Minute))
+
+	assert.Equal(t, "198.51.100.3", mapping.PendingIP)
+	assert.Equal(t, now.Add(8*time.Minute), mapping.PendingIPSince)
+	assert.Equal(t, Result{Dampened: true}, Check(mapping, "198.51.100.3", now.Add(12*time.Minute)))
+}
+
+func TestCheck_NewDampingPeriod(t *testing.T) {
+	mapping := flappingMapping(domain.DampingHold)
+	mapping.LastDampenedAt = now.Add(-2 * time.Hour)
+
+	result := Check(mapping, "198.51.100.1", now)
+
+	assert.Assert(t, result.Started, "damping ended more than a flap window ago")
+}
+
+func TestClearPending(t *testing.T) {
+	mapping := flappingMapping(domain.DampingSettle)
+	assert.Assert(t, !ClearPending(mapping))
+
+	Check(mapping, "198.51.100.1", now)
+	assert.Assert(t, ClearPending(mapping))
+	assert.Equal(t, "", mapping.PendingIP)
+	assert.Assert(t, mapping.PendingIPSince.IsZero())
+}
+
+func TestRecordChange(t *testing.T) {
+	testCases := []struct {
+		name       string
+		ip         string
+		history    []domain.PublishedIP
+		previousIP string
+		expected   []domain.PublishedIP
+	}{
+		{
+			name:       "first change",
+			ip:         "198.51.100.2",
+			previousIP: "198.51.100.1",
+			expected:   []domain.PublishedIP{{IP: "198.51.100.1", ReplacedAt: now}},
+		},
+		{
+			name:     "new mapping",
+			ip:       "198.51.100.1",
+			expected: []domain.PublishedIP{},
+		},
+		{
+			name:       "moves a repeated IP to the end",
+			ip:         "198.51.100.3",
+			history:    []domain.PublishedIP{{IP: "198.51.100.1", ReplacedAt: now.Add(-time.Hour)}, {IP: "198.51.100.2", ReplacedAt: now.Add(-time.Minute)}},
+			previousIP: "198.51.100.1",
+			expected:   []domain.PublishedIP{{IP: "198.51.100.2", ReplacedAt: now.Add(-time.Minute)}, {IP: "198.51.100.1", ReplacedAt: now}},
+		},
+		{
+			name:       "drops the new IP",
+			ip:         "198.51.100.1",
+			history:    []domain.PublishedIP{{IP: "198.51.100.1", ReplacedAt: now.Add(-time.Minute)}},
+			previousIP: "198.51.100.2",
+			expected:   []domain.PublishedIP{{IP: "198.51.100.2", ReplacedAt: now}},
+		},
+		{
+			name:       "drops old entries",
+			ip:         "198.51.100.3",
+			history:    []domain.PublishedIP{{IP: "198.51.100.1", ReplacedAt: now.Add(-25 * time.Hour)}},
+			previousIP: "198.51.100.2",
+			expected:   []domain.PublishedIP{{IP: "198.51.100.2", ReplacedAt: now}},
+		},
+		{
+			name: "keeps the latest",
+			ip:   "198.51.100.9",
+			history: []domain.PublishedIP{
+				{IP: "198.51.100.1", ReplacedAt: now.Add(-4 * time.Minute)},
+				{IP: "198.51.100.2", ReplacedAt: now.Add(-3 * time.Minute)},
+				{IP: "198.51.100.3", ReplacedAt: now.Add(-2 * time.Minute)},
+				{IP: "198.51.100.4", ReplacedAt: now.Add(-time.Minute)},
+			},
+			previousIP: "198.51.100.5",
+			expected: []domain.PublishedIP{
+				{IP: "198.51.100.2", ReplacedAt: now.Add(-3 * time.Minute)},
+				{IP: "198.51.100.3", ReplacedAt: now.Add(-2 * time.Minute)},
+				{IP: "198.51.100.4", ReplacedAt: now.Add(-time.Minute)},
+				{IP: "198.51.100.5", ReplacedAt: now},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping := &domain.IPMapping{IP: tc.ip, IPHistory: tc.history, PendingIP: tc.ip}
+
+			RecordChange(mapping, tc.previousIP, now)
+
+			assert.DeepEqual(t, tc.expected, mapping.IPHistory)
+			assert.Equal(t, "", mapping.PendingIP)
+		})
+	}
+}
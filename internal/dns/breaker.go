@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is how many consecutive degraded changes open the breaker.
+	DefaultBreakerThreshold = 3
+
+	// DefaultBreakerCooldown is how long the breaker stays open before a trial call.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// CircuitBreaker stops calling Route53 after repeated throttling or server errors.
+// Once open it fails fast until the cooldown passes, then lets a single trial call
+// through: success closes it, failure opens it again.
+//
+// State lives in memory, so each Lambda container trips independently.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive failures.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed. When it may not, it returns the
+// remaining cooldown.
+func (b *CircuitBreaker) Allow() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return 0, true
+	}
+
+	remaining := b.cooldown - b.now().Sub(b.openedAt)
+	if remaining > 0 {
+		return remaining, false
+	}
+
+	// Half-open: one trial call at a time
+	if b.trial {
+		return b.cooldown, false
+	}
+	b.trial = true
+	return 0, true
+}
+
+// Success records a call that reached a healthy provider and closes the breaker.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openedAt = time.Time{}
+	b.trial = false
+}
+
+// Failure records a degraded call. It returns the cooldown and true when the
+// breaker is open afterwards.
+func (b *CircuitBreaker) Failure() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.trial || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.trial = false
+		return b.cooldown, true
+	}
+	return 0, false
+}
+
+// Abandon records a call that ended without an answer from the provider, such
+// as one its caller canceled. It frees the half-open trial without changing
+// the breaker's state.
+func (b *CircuitBreaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
@@ -0,0 +1,80 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(2, 30*time.Second)
+	breaker.now = func() time.Time { return now }
+
+	_, allowed := breaker.Allow()
+	assert.Assert(t, allowed)
+
+	// First failure keeps the breaker closed
+	_, opened := breaker.Failure()
+	assert.Assert(t, !opened)
+
+	// Second consecutive failure opens it
+	cooldown, opened := breaker.Failure()
+	assert.Assert(t, opened)
+	assert.Equal(t, 30*time.Second, cooldown)
+
+	now = now.Add(10 * time.Second)
+	wait, allowed := breaker.Allow()
+	assert.Assert(t, !allowed)
+	assert.Equal(t, 20*time.Second, wait)
+
+	// After the cooldown a single trial call is allowed
+	now = now.Add(25 * time.Second)
+	_, allowed = breaker.Allow()
+	assert.Assert(t, allowed)
+	_, allowed = breaker.Allow()
+	assert.Assert(t, !allowed, "only one trial call while half-open")
+
+	// A failed trial reopens immediately
+	_, opened = breaker.Failure()
+	assert.Assert(t, opened)
+	_, allowed = breaker.Allow()
+	assert.Assert(t, !allowed)
+
+	// A successful trial closes it
+	now = now.Add(31 * time.Second)
+	_, allowed = breaker.Allow()
+	assert.Assert(t, allowed)
+	breaker.Success()
+	_, allowed = breaker.Allow()
+	assert.Assert(t, allowed)
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	breaker := NewCircuitBreaker(2, time.Minute)
+
+	breaker.Failure()
+	breaker.Success()
+	_, opened := breaker.Failure()
+
+	assert.Assert(t, !opened, "failures must be consecutive")
+}
+
+func TestCircuitBreaker_AbandonFreesTrial(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(1, 30*time.Second)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	now = now.Add(31 * time.Second)
+	_, allowed := breaker.Allow()
+	assert.Assert(t, allowed)
+
+	breaker.Abandon()
+
+	_, allowed = breaker.Allow()
+	assert.Assert(t, allowed, "an abandoned trial should let another through")
+	_, allowed = breaker.Allow()
+	assert.Assert(t, !allowed, "the breaker should stay half-open")
+}
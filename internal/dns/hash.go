@@ -2,23 +2,122 @@ package dns
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"fmt"
 )
 
 const (
-	// SubdomainLength is the number of hex characters in the subdomain hash.
+	// SubdomainLength is the default number of hex characters in the subdomain hash.
 	SubdomainLength = 8
 
+	// MinSubdomainLength is the shortest configurable subdomain hash.
+	MinSubdomainLength = 6
+
 	// RootDomain is the base domain for all dynamic DNS subdomains.
 	RootDomain = "grocky.net"
+
+	// variantExtraLength is how many extra characters collision variants get.
+	variantExtraLength = 2
+)
+
+// Supported subdomain hash algorithms.
+const (
+	HashMD5    = "md5"
+	HashSHA256 = "sha256"
 )
 
+// SubdomainConfig controls how subdomains are derived from ownerId and location.
+// Changing it only affects new locations; existing mappings keep their stored subdomain.
+type SubdomainConfig struct {
+	Algorithm string
+	Length    int
+}
+
+// DefaultSubdomainConfig matches the original 8 character MD5 scheme.
+var DefaultSubdomainConfig = SubdomainConfig{
+	Algorithm: HashMD5,
+	Length:    SubdomainLength,
+}
+
+var subdomainConfig = DefaultSubdomainConfig
+
+// ConfigureSubdomains sets the algorithm and length used for new subdomains.
+// Zero values fall back to the defaults.
+func ConfigureSubdomains(cfg SubdomainConfig) error {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = DefaultSubdomainConfig.Algorithm
+	}
+	if cfg.Length == 0 {
+		cfg.Length = DefaultSubdomainConfig.Length
+	}
+
+	maxLength := hexDigestLength(cfg.Algorithm)
+	if maxLength == 0 {
+		return fmt.Errorf("unsupported subdomain hash algorithm: %s", cfg.Algorithm)
+	}
+	// Leave room for the longer collision variants
+	if cfg.Length < MinSubdomainLength || cfg.Length+variantExtraLength > maxLength {
+		return fmt.Errorf("subdomain length must be between %d and %d for %s",
+			MinSubdomainLength, maxLength-variantExtraLength, cfg.Algorithm)
+	}
+
+	subdomainConfig = cfg
+	return nil
+}
+
+// CurrentSubdomainConfig returns the active subdomain configuration.
+func CurrentSubdomainConfig() SubdomainConfig {
+	return subdomainConfig
+}
+
 // GenerateSubdomain creates a deterministic subdomain hash from ownerId and location.
-// The hash is the first 8 characters of the MD5 hex digest of "ownerId-location".
+// With the default configuration this is the first 8 characters of the MD5 hex digest
+// of "ownerId-location".
 func GenerateSubdomain(ownerID, location string) string {
 	input := fmt.Sprintf("%s-%s", ownerID, location)
-	hash := md5.Sum([]byte(input))
-	return fmt.Sprintf("%x", hash)[:SubdomainLength]
+	return hexDigest(subdomainConfig.Algorithm, input)[:subdomainConfig.Length]
+}
+
+// LegacySubdomain returns the subdomain of a mapping stored without one. Such
+// mappings predate the configurable hash and were published under the original
+// scheme, the first 8 characters of the MD5 hex digest of "ownerId-location",
+// so ConfigureSubdomains doesn't apply to them.
+func LegacySubdomain(ownerID, location string) string {
+	input := fmt.Sprintf("%s-%s", ownerID, location)
+	return hexDigest(HashMD5, input)[:SubdomainLength]
+}
+
+// GenerateSubdomainVariant returns the subdomain to use when earlier candidates collide.
+// Attempt 0 is GenerateSubdomain; later attempts hash "ownerId-location#attempt" and
+// keep two extra characters, so each variant is deterministic and unlikely to collide.
+func GenerateSubdomainVariant(ownerID, location string, attempt int) string {
+	if attempt == 0 {
+		return GenerateSubdomain(ownerID, location)
+	}
+	input := fmt.Sprintf("%s-%s#%d", ownerID, location, attempt)
+	return hexDigest(subdomainConfig.Algorithm, input)[:subdomainConfig.Length+variantExtraLength]
+}
+
+// hexDigest returns the hex digest of input using the named algorithm.
+func hexDigest(algorithm, input string) string {
+	switch algorithm {
+	case HashSHA256:
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(input)))
+	default:
+		return fmt.Sprintf("%x", md5.Sum([]byte(input)))
+	}
+}
+
+// hexDigestLength returns the hex digest length of an algorithm, or 0 if unsupported.
+func hexDigestLength(algorithm string) int {
+	switch algorithm {
+	case HashMD5:
+		return md5.Size * 2
+	case HashSHA256:
+		return sha256.Size * 2
+	default:
+		return 0
+	}
 }
 
 // FormatFQDN formats a subdomain with the root domain.
@@ -26,6 +125,20 @@ func FormatFQDN(subdomain string) string {
 	return fmt.Sprintf("%s.%s", subdomain, RootDomain)
 }
 
+// BuildRecordSetName returns the name of a custom record set relative to RootDomain.
+// An empty name refers to the location subdomain itself.
+func BuildRecordSetName(subdomain, name string) string {
+	if name == "" {
+		return subdomain
+	}
+	return fmt.Sprintf("%s.%s", name, subdomain)
+}
+
+// BuildWildcardName returns the wildcard record name covering every name under subdomain.
+func BuildWildcardName(subdomain string) string {
+	return fmt.Sprintf("*.%s", subdomain)
+}
+
 // BuildACMEChallengeName returns the TXT record name for an ACME challenge.
 func BuildACMEChallengeName(subdomain string) string {
 	return fmt.Sprintf("_acme-challenge.%s", subdomain)
@@ -99,3 +99,68 @@ func TestFormatFQDN(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildRecordSetName(t *testing.T) {
+	assert.Equal(t, "6abf7de6", BuildRecordSetName("6abf7de6", ""))
+	assert.Equal(t, "_minecraft._tcp.6abf7de6", BuildRecordSetName("6abf7de6", "_minecraft._tcp"))
+}
+
+func TestGenerateSubdomainVariant(t *testing.T) {
+	// Attempt 0 is the regular subdomain
+	assert.Equal(t, GenerateSubdomain("my-home-lab", "home"), GenerateSubdomainVariant("my-home-lab", "home", 0))
+
+	first := GenerateSubdomainVariant("my-home-lab", "home", 1)
+	second := GenerateSubdomainVariant("my-home-lab", "home", 2)
+
+	assert.Equal(t, SubdomainLength+2, len(first))
+	assert.Assert(t, first != second, "variants should differ per attempt")
+	assert.Equal(t, first, GenerateSubdomainVariant("my-home-lab", "home", 1), "variants should be deterministic")
+}
+
+func TestConfigureSubdomains(t *testing.T) {
+	t.Cleanup(func() {
+		assert.NilError(t, ConfigureSubdomains(DefaultSubdomainConfig))
+	})
+
+	err := ConfigureSubdomains(SubdomainConfig{Algorithm: HashSHA256, Length: 12})
+	assert.NilError(t, err)
+	assert.Equal(t, 12, len(GenerateSubdomain("my-home-lab", "home")))
+	assert.Equal(t, HashSHA256, CurrentSubdomainConfig().Algorithm)
+
+	// Zero values fall back to the defaults
+	err = ConfigureSubdomains(SubdomainConfig{})
+	assert.NilError(t, err)
+	assert.Equal(t, "6abf7de6", GenerateSubdomain("my-home-lab", "home"))
+}
+
+func TestLegacySubdomain_IgnoresConfiguration(t *testing.T) {
+	t.Cleanup(func() {
+		assert.NilError(t, ConfigureSubdomains(DefaultSubdomainConfig))
+	})
+
+	assert.NilError(t, ConfigureSubdomains(SubdomainConfig{Algorithm: HashSHA256, Length: 12}))
+	assert.Equal(t, "6abf7de6", LegacySubdomain("my-home-lab", "home"))
+}
+
+func TestConfigureSubdomains_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  SubdomainConfig
+	}{
+		{name: "unknown algorithm", cfg: SubdomainConfig{Algorithm: "crc32", Length: 8}},
+		{name: "too short", cfg: SubdomainConfig{Algorithm: HashMD5, Length: 4}},
+		{name: "too long for md5", cfg: SubdomainConfig{Algorithm: HashMD5, Length: 32}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ConfigureSubdomains(tc.cfg)
+			assert.Assert(t, err != nil)
+			assert.DeepEqual(t, DefaultSubdomainConfig, CurrentSubdomainConfig())
+		})
+	}
+}
+
+func TestBuildWildcardName(t *testing.T) {
+	assert.Equal(t, "*.a3f8c2d1", BuildWildcardName("a3f8c2d1"))
+}
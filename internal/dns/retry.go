@@ -0,0 +1,186 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// DefaultUnavailableRetryAfter is suggested to callers when retries are exhausted.
+const DefaultUnavailableRetryAfter = 5 * time.Second
+
+// ErrUnavailable is returned when Route53 is throttling or degraded.
+var ErrUnavailable = errors.New("DNS provider temporarily unavailable")
+
+// UnavailableError reports that a change was not applied because Route53 is
+// throttling or degraded. It wraps ErrUnavailable and the last provider error.
+type UnavailableError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err == nil {
+		return ErrUnavailable.Error()
+	}
+	return fmt.Sprintf("%s: %v", ErrUnavailable, e.Err)
+}
+
+// Unwrap allows errors.Is(err, ErrUnavailable) and inspection of the provider error.
+func (e *UnavailableError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrUnavailable}
+	}
+	return []error{ErrUnavailable, e.Err}
+}
+
+// RetryAfter returns how long a caller should wait when err is an UnavailableError.
+func RetryAfter(err error) (time.Duration, bool) {
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) {
+		return unavailable.RetryAfter, true
+	}
+	return 0, false
+}
+
+// RetryPolicy controls how Route53 changes are retried.
+type RetryPolicy struct {
+	// MaxAttempts includes the first call.
+	MaxAttempts int
+	// BaseDelay is the backoff ceiling for the first retry; it doubles per attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff ceiling.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy fits comfortably inside a Lambda request while riding out
+// Route53's 5 requests per second account limit.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// backoff returns a full-jitter delay for the given retry (1 for the first retry).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.BaseDelay << (retry - 1)
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// retryableCodes are Route53 error codes worth retrying.
+var retryableCodes = map[string]bool{
+	"PriorRequestNotComplete": true,
+	"Throttling":              true,
+	"ThrottlingException":     true,
+	"RequestLimitExceeded":    true,
+	"ServiceUnavailable":      true,
+	"InternalFailure":         true,
+	"InternalError":           true,
+	"RequestTimeout":          true,
+}
+
+// isRetryable classifies a provider error. Throttling, server faults and network
+// errors are transient; invalid input and missing records are not.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if retryableCodes[apiErr.ErrorCode()] {
+			return true
+		}
+		if apiErr.ErrorFault() == smithy.FaultServer {
+			return true
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		code := statusErr.HTTPStatusCode()
+		if code == 429 || code >= 500 {
+			return true
+		}
+	}
+
+	if apiErr != nil {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// withRetry runs call under the breaker, retrying transient errors with jittered
+// backoff. It never sleeps past the context deadline. A call that runs into the
+// deadline counts as a failure; one the caller canceled counts as neither.
+func withRetry(ctx context.Context, policy RetryPolicy, breaker *CircuitBreaker, sleep func(context.Context, time.Duration) error, call func() error) error {
+	if wait, ok := breaker.Allow(); !ok {
+		return &UnavailableError{RetryAfter: wait}
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil {
+			breaker.Success()
+			return nil
+		}
+		if errors.Is(err, context.Canceled) {
+			// The caller gave up; that says nothing about the provider either way
+			breaker.Abandon()
+			return err
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			// A call that hangs until the deadline is the usual outage symptom
+			break
+		}
+		if !isRetryable(err) {
+			// The provider answered; a bad request says nothing about its health
+			breaker.Success()
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			break
+		}
+
+		delay := policy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			break
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			break
+		}
+	}
+
+	retryAfter := DefaultUnavailableRetryAfter
+	if wait, opened := breaker.Failure(); opened {
+		retryAfter = wait
+	}
+	return &UnavailableError{RetryAfter: retryAfter, Err: err}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
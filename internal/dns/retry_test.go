@@ -0,0 +1,244 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"gotest.tools/assert"
+)
+
+func noSleep(ctx context.Context, d time.Duration) error {
+	return nil
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "prior request not complete", err: &types.PriorRequestNotComplete{Message: aws.String("busy")}, expected: true},
+		{name: "throttling", err: &smithy.GenericAPIError{Code: "Throttling"}, expected: true},
+		{name: "server fault", err: &smithy.GenericAPIError{Code: "Unknown", Fault: smithy.FaultServer}, expected: true},
+		{name: "wrapped throttling", err: errors.Join(errors.New("call failed"), &smithy.GenericAPIError{Code: "Throttling"}), expected: true},
+		{name: "invalid change batch", err: &types.InvalidChangeBatch{Message: aws.String("record not found")}, expected: false},
+		{name: "invalid input", err: &types.InvalidInput{Message: aws.String("bad name")}, expected: false},
+		{name: "context canceled", err: context.Canceled, expected: false},
+		{name: "plain error", err: errors.New("boom"), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isRetryable(tc.err))
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	for i := 0; i < 50; i++ {
+		assert.Assert(t, policy.backoff(1) <= 100*time.Millisecond)
+		assert.Assert(t, policy.backoff(2) <= 200*time.Millisecond)
+		assert.Assert(t, policy.backoff(10) <= 300*time.Millisecond, "backoff should be capped")
+		assert.Assert(t, policy.backoff(1) > 0)
+	}
+}
+
+func TestRoute53Service_RetriesThrottling(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	calls := 0
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			calls++
+			if calls < 3 {
+				return nil, &types.PriorRequestNotComplete{Message: aws.String("busy")}
+			}
+			return &route53.ChangeResourceRecordSetsOutput{}, nil
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = noSleep
+
+	err := svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRoute53Service_DoesNotRetryInvalidInput(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	calls := 0
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			calls++
+			return nil, &types.InvalidChangeBatch{Message: aws.String("record not found")}
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = noSleep
+
+	err := svc.DeleteRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	assert.Equal(t, 1, calls)
+	assert.Assert(t, !errors.Is(err, ErrUnavailable))
+	var invalid *types.InvalidChangeBatch
+	assert.Assert(t, errors.As(err, &invalid))
+}
+
+func TestRoute53Service_ExhaustedRetriesAreUnavailable(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	calls := 0
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			calls++
+			return nil, &smithy.GenericAPIError{Code: "Throttling"}
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = noSleep
+
+	err := svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	assert.Equal(t, DefaultRetryPolicy.MaxAttempts, calls)
+	assert.Assert(t, errors.Is(err, ErrUnavailable))
+	retryAfter, ok := RetryAfter(err)
+	assert.Assert(t, ok)
+	assert.Equal(t, DefaultUnavailableRetryAfter, retryAfter)
+}
+
+func TestRoute53Service_StopsAtDeadline(t *testing.T) {
+	logger := newTestLogger()
+
+	calls := 0
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			calls++
+			return nil, &smithy.GenericAPIError{Code: "Throttling"}
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Fatal("should not sleep past the deadline")
+		return nil
+	}
+
+	// Less time left than the smallest possible backoff ceiling
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	assert.Equal(t, 1, calls)
+	assert.Assert(t, errors.Is(err, ErrUnavailable))
+}
+
+func TestRoute53Service_BreakerFailsFast(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	calls := 0
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			calls++
+			return nil, &smithy.GenericAPIError{Code: "ServiceUnavailable"}
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = noSleep
+	svc.retryPolicy = RetryPolicy{MaxAttempts: 1}
+
+	for i := 0; i < DefaultBreakerThreshold; i++ {
+		_ = svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+	}
+	assert.Equal(t, DefaultBreakerThreshold, calls)
+
+	err := svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	assert.Equal(t, DefaultBreakerThreshold, calls, "open breaker should not call Route53")
+	assert.Assert(t, errors.Is(err, ErrUnavailable))
+	retryAfter, ok := RetryAfter(err)
+	assert.Assert(t, ok)
+	assert.Assert(t, retryAfter > 0 && retryAfter <= DefaultBreakerCooldown)
+}
+
+func TestRoute53Service_DeadlineCountsAsFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	calls := 0
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			calls++
+			return nil, context.DeadlineExceeded
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = noSleep
+
+	for i := 0; i < DefaultBreakerThreshold; i++ {
+		err := svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+		assert.Assert(t, errors.Is(err, ErrUnavailable))
+		assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+	}
+	assert.Equal(t, DefaultBreakerThreshold, calls, "deadline errors should not be retried")
+
+	_, allowed := svc.breaker.Allow()
+	assert.Assert(t, !allowed, "deadline errors should open the breaker")
+}
+
+func TestRoute53Service_CancelRecordsNoOutcome(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	canceled := false
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			if canceled {
+				return nil, context.Canceled
+			}
+			return nil, &smithy.GenericAPIError{Code: "ServiceUnavailable"}
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+	svc.sleep = noSleep
+	svc.retryPolicy = RetryPolicy{MaxAttempts: 1}
+
+	for i := 0; i < DefaultBreakerThreshold-1; i++ {
+		_ = svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+	}
+
+	// A canceled call must neither reset nor add to the failure count
+	canceled = true
+	err := svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+	assert.Assert(t, errors.Is(err, context.Canceled))
+	assert.Assert(t, !errors.Is(err, ErrUnavailable))
+
+	_, allowed := svc.breaker.Allow()
+	assert.Assert(t, allowed, "cancellation should not open the breaker")
+
+	canceled = false
+	_ = svc.UpsertRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	_, allowed = svc.breaker.Allow()
+	assert.Assert(t, !allowed, "cancellation should not reset earlier failures")
+}
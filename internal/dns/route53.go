@@ -2,8 +2,12 @@ package dns
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
@@ -17,17 +21,34 @@ const (
 
 // Service defines the interface for DNS operations.
 type Service interface {
-	// UpsertRecord creates or updates an A record for the given subdomain.
+	// UpsertRecord creates or updates the A or AAAA record for the given subdomain.
 	UpsertRecord(ctx context.Context, subdomain, ip string) error
 
-	// DeleteRecord removes the A record for the given subdomain.
-	DeleteRecord(ctx context.Context, subdomain string) error
+	// DeleteRecord removes the A or AAAA record for the given subdomain.
+	// Deleting a record that doesn't exist succeeds, so deletes can be retried.
+	DeleteRecord(ctx context.Context, subdomain, ip string) error
 
 	// UpsertTXTRecord creates or updates a TXT record.
 	UpsertTXTRecord(ctx context.Context, name, value string) error
 
-	// DeleteTXTRecord removes a TXT record.
+	// DeleteTXTRecord removes a TXT record. Deleting one that doesn't exist succeeds.
 	DeleteTXTRecord(ctx context.Context, name, value string) error
+
+	// UpsertRecordSet creates or replaces a record set of any type.
+	UpsertRecordSet(ctx context.Context, rs RecordSet) error
+
+	// DeleteRecordSet removes a record set. The values must match the published ones.
+	// Deleting one that doesn't exist succeeds.
+	DeleteRecordSet(ctx context.Context, rs RecordSet) error
+}
+
+// RecordSet is a generic resource record set.
+// Name is relative to RootDomain, e.g. "_minecraft._tcp.a3f8c2d1".
+type RecordSet struct {
+	Name   string
+	Type   string
+	TTL    int64
+	Values []string
 }
 
 // Route53Client defines the interface for Route53 operations we use.
@@ -36,10 +57,15 @@ type Route53Client interface {
 }
 
 // Route53Service implements Service using AWS Route53.
+// Every change goes through DefaultRetryPolicy and a shared circuit breaker;
+// degraded calls fail with an *UnavailableError.
 type Route53Service struct {
 	client       Route53Client
 	hostedZoneID string
 	logger       *slog.Logger
+	retryPolicy  RetryPolicy
+	breaker      *CircuitBreaker
+	sleep        func(context.Context, time.Duration) error
 }
 
 // NewRoute53Service creates a new Route53 DNS service.
@@ -48,12 +74,35 @@ func NewRoute53Service(client Route53Client, hostedZoneID string, logger *slog.L
 		client:       client,
 		hostedZoneID: hostedZoneID,
 		logger:       logger,
+		retryPolicy:  DefaultRetryPolicy,
+		breaker:      NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		sleep:        sleepContext,
+	}
+}
+
+// change submits a change batch with retries and circuit breaking.
+func (s *Route53Service) change(ctx context.Context, input *route53.ChangeResourceRecordSetsInput) error {
+	attempt := 0
+	err := withRetry(ctx, s.retryPolicy, s.breaker, s.sleep, func() error {
+		attempt++
+		_, err := s.client.ChangeResourceRecordSets(ctx, input)
+		if err != nil && attempt < s.retryPolicy.MaxAttempts && isRetryable(err) {
+			s.logger.Warn("retrying Route53 change", "error", err, "attempt", attempt)
+		}
+		return err
+	})
+
+	if retryAfter, ok := RetryAfter(err); ok {
+		s.logger.Warn("Route53 unavailable", "error", err, "attempts", attempt, "retryAfter", retryAfter)
 	}
+	return err
 }
 
-// UpsertRecord creates or updates an A record for the given subdomain.
+// UpsertRecord creates or updates the address record for the given subdomain.
+// IPv4 addresses publish an A record and IPv6 addresses an AAAA record.
 func (s *Route53Service) UpsertRecord(ctx context.Context, subdomain, ip string) error {
 	recordName := fmt.Sprintf("%s.%s", subdomain, RootDomain)
+	recordType := AddressRecordType(ip)
 
 	input := &route53.ChangeResourceRecordSetsInput{
 		HostedZoneId: aws.String(s.hostedZoneID),
@@ -64,7 +113,7 @@ func (s *Route53Service) UpsertRecord(ctx context.Context, subdomain, ip string)
 					Action: types.ChangeActionUpsert,
 					ResourceRecordSet: &types.ResourceRecordSet{
 						Name: aws.String(recordName),
-						Type: types.RRTypeA,
+						Type: recordType,
 						TTL:  aws.Int64(DefaultTTL),
 						ResourceRecords: []types.ResourceRecord{
 							{
@@ -77,7 +126,7 @@ func (s *Route53Service) UpsertRecord(ctx context.Context, subdomain, ip string)
 		},
 	}
 
-	_, err := s.client.ChangeResourceRecordSets(ctx, input)
+	err := s.change(ctx, input)
 	if err != nil {
 		s.logger.Error("failed to upsert DNS record",
 			"error", err,
@@ -90,24 +139,69 @@ func (s *Route53Service) UpsertRecord(ctx context.Context, subdomain, ip string)
 	s.logger.Info("DNS record upserted",
 		"subdomain", subdomain,
 		"recordName", recordName,
+		"type", recordType,
 		"ip", ip,
 	)
 	return nil
 }
 
-// DeleteRecord removes the A record for the given subdomain.
-func (s *Route53Service) DeleteRecord(ctx context.Context, subdomain string) error {
+// DeleteRecord removes the address record for the given subdomain.
+// Route53 only deletes a record set whose values match, so the published IP is required.
+func (s *Route53Service) DeleteRecord(ctx context.Context, subdomain, ip string) error {
 	recordName := fmt.Sprintf("%s.%s", subdomain, RootDomain)
+	recordType := AddressRecordType(ip)
+
+	input := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(s.hostedZoneID),
+		ChangeBatch: &types.ChangeBatch{
+			Comment: aws.String(fmt.Sprintf("Remove DDNS record for %s", subdomain)),
+			Changes: []types.Change{
+				{
+					Action: types.ChangeActionDelete,
+					ResourceRecordSet: &types.ResourceRecordSet{
+						Name: aws.String(recordName),
+						Type: recordType,
+						TTL:  aws.Int64(DefaultTTL),
+						ResourceRecords: []types.ResourceRecord{
+							{Value: aws.String(ip)},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := s.change(ctx, input)
+	if isRecordNotFound(err) {
+		s.logger.Info("DNS record already deleted", "subdomain", subdomain, "recordName", recordName)
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("failed to delete DNS record",
+			"error", err,
+			"subdomain", subdomain,
+			"ip", ip,
+		)
+		return fmt.Errorf("failed to delete DNS record: %w", err)
+	}
 
-	// To delete, we need to know the current value. For now, we'll skip this
-	// as we don't have a use case for deletion yet.
-	s.logger.Warn("delete record not fully implemented",
+	s.logger.Info("DNS record deleted",
 		"subdomain", subdomain,
 		"recordName", recordName,
+		"type", recordType,
 	)
 	return nil
 }
 
+// AddressRecordType returns AAAA for IPv6 addresses and A otherwise.
+func AddressRecordType(ip string) types.RRType {
+	parsed := net.ParseIP(ip)
+	if parsed != nil && parsed.To4() == nil {
+		return types.RRTypeAaaa
+	}
+	return types.RRTypeA
+}
+
 // UpsertTXTRecord creates or updates a TXT record.
 func (s *Route53Service) UpsertTXTRecord(ctx context.Context, name, value string) error {
 	recordName := fmt.Sprintf("%s.%s", name, RootDomain)
@@ -135,7 +229,7 @@ func (s *Route53Service) UpsertTXTRecord(ctx context.Context, name, value string
 		},
 	}
 
-	_, err := s.client.ChangeResourceRecordSets(ctx, input)
+	err := s.change(ctx, input)
 	if err != nil {
 		s.logger.Error("failed to upsert TXT record",
 			"error", err,
@@ -176,7 +270,11 @@ func (s *Route53Service) DeleteTXTRecord(ctx context.Context, name, value string
 		},
 	}
 
-	_, err := s.client.ChangeResourceRecordSets(ctx, input)
+	err := s.change(ctx, input)
+	if isRecordNotFound(err) {
+		s.logger.Info("TXT record already deleted", "name", name, "recordName", recordName)
+		return nil
+	}
 	if err != nil {
 		s.logger.Error("failed to delete TXT record",
 			"error", err,
@@ -192,5 +290,108 @@ func (s *Route53Service) DeleteTXTRecord(ctx context.Context, name, value string
 	return nil
 }
 
+// UpsertRecordSet creates or replaces a record set of any type.
+func (s *Route53Service) UpsertRecordSet(ctx context.Context, rs RecordSet) error {
+	comment := fmt.Sprintf("Upsert %s record for %s", rs.Type, rs.Name)
+	if err := s.changeRecordSet(ctx, types.ChangeActionUpsert, rs, comment); err != nil {
+		s.logger.Error("failed to upsert record set",
+			"error", err,
+			"name", rs.Name,
+			"type", rs.Type,
+		)
+		return fmt.Errorf("failed to upsert record set: %w", err)
+	}
+
+	s.logger.Info("record set upserted",
+		"name", rs.Name,
+		"type", rs.Type,
+		"values", len(rs.Values),
+	)
+	return nil
+}
+
+// DeleteRecordSet removes a record set. The values must match the published ones.
+func (s *Route53Service) DeleteRecordSet(ctx context.Context, rs RecordSet) error {
+	comment := fmt.Sprintf("Delete %s record for %s", rs.Type, rs.Name)
+	err := s.changeRecordSet(ctx, types.ChangeActionDelete, rs, comment)
+	if isRecordNotFound(err) {
+		s.logger.Info("record set already deleted", "name", rs.Name, "type", rs.Type)
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("failed to delete record set",
+			"error", err,
+			"name", rs.Name,
+			"type", rs.Type,
+		)
+		return fmt.Errorf("failed to delete record set: %w", err)
+	}
+
+	s.logger.Info("record set deleted",
+		"name", rs.Name,
+		"type", rs.Type,
+	)
+	return nil
+}
+
+// isRecordNotFound reports whether Route53 rejected a delete because the record
+// set doesn't exist, such as when an earlier attempt already removed it. A delete
+// whose values don't match the published ones is still an error.
+func isRecordNotFound(err error) bool {
+	var invalid *types.InvalidChangeBatch
+	if !errors.As(err, &invalid) {
+		return false
+	}
+	for _, msg := range append([]string{aws.ToString(invalid.Message)}, invalid.Messages...) {
+		if strings.Contains(msg, "but it was not found") {
+			return true
+		}
+	}
+	return false
+}
+
+// changeRecordSet submits a single-change batch for a generic record set.
+func (s *Route53Service) changeRecordSet(ctx context.Context, action types.ChangeAction, rs RecordSet, comment string) error {
+	ttl := rs.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	records := make([]types.ResourceRecord, 0, len(rs.Values))
+	for _, value := range rs.Values {
+		if rs.Type == string(types.RRTypeTxt) {
+			value = quoteTXT(value)
+		}
+		records = append(records, types.ResourceRecord{Value: aws.String(value)})
+	}
+
+	input := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(s.hostedZoneID),
+		ChangeBatch: &types.ChangeBatch{
+			Comment: aws.String(comment),
+			Changes: []types.Change{
+				{
+					Action: action,
+					ResourceRecordSet: &types.ResourceRecordSet{
+						Name:            aws.String(fmt.Sprintf("%s.%s", rs.Name, RootDomain)),
+						Type:            types.RRType(rs.Type),
+						TTL:             aws.Int64(ttl),
+						ResourceRecords: records,
+					},
+				},
+			},
+		},
+	}
+
+	return s.change(ctx, input)
+}
+
+// quoteTXT quotes a TXT value for Route53, escaping embedded quotes and backslashes.
+func quoteTXT(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
 // Ensure Route53Service implements Service.
 var _ Service = (*Route53Service)(nil)
@@ -7,7 +7,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
 	"gotest.tools/assert"
 )
 
@@ -82,17 +84,203 @@ func TestRoute53Service_UpsertRecord_Error(t *testing.T) {
 	assert.ErrorContains(t, err, "failed to upsert DNS record")
 }
 
+func TestRoute53Service_UpsertRecord_IPv6(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *route53.ChangeResourceRecordSetsInput
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			capturedInput = params
+			return &route53.ChangeResourceRecordSetsOutput{}, nil
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+
+	err := svc.UpsertRecord(ctx, "*.a3f8c2d1", "2001:db8::1")
+
+	assert.NilError(t, err)
+	change := capturedInput.ChangeBatch.Changes[0]
+	assert.Equal(t, "*.a3f8c2d1."+RootDomain, *change.ResourceRecordSet.Name)
+	assert.Equal(t, types.RRTypeAaaa, change.ResourceRecordSet.Type)
+}
+
 func TestRoute53Service_DeleteRecord(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
-	client := &mockRoute53Client{}
+
+	var capturedInput *route53.ChangeResourceRecordSetsInput
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			capturedInput = params
+			return &route53.ChangeResourceRecordSetsOutput{}, nil
+		},
+	}
 
 	svc := NewRoute53Service(client, "Z123456789", logger)
 
-	// DeleteRecord is not fully implemented, should return nil
-	err := svc.DeleteRecord(ctx, "a3f8c2d1")
+	err := svc.DeleteRecord(ctx, "a3f8c2d1", "203.0.113.42")
 
 	assert.NilError(t, err)
+	change := capturedInput.ChangeBatch.Changes[0]
+	assert.Equal(t, types.ChangeActionDelete, change.Action)
+	assert.Equal(t, "a3f8c2d1."+RootDomain, *change.ResourceRecordSet.Name)
+	assert.Equal(t, types.RRTypeA, change.ResourceRecordSet.Type)
+	assert.Equal(t, int64(DefaultTTL), *change.ResourceRecordSet.TTL)
+	assert.Equal(t, "203.0.113.42", *change.ResourceRecordSet.ResourceRecords[0].Value)
+}
+
+func TestRoute53Service_DeleteRecord_Error(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			return nil, errors.New("route53 error")
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+
+	err := svc.DeleteRecord(ctx, "a3f8c2d1", "203.0.113.42")
+
+	assert.ErrorContains(t, err, "failed to delete DNS record")
+}
+
+func TestRoute53Service_DeleteRecord_AlreadyDeleted(t *testing.T) {
+	testCases := []struct {
+		name      string
+		message   string
+		expectErr bool
+	}{
+		{
+			name:    "not found",
+			message: "[Tried to delete resource record set [name='a3f8c2d1.grocky.net.', type='A'] but it was not found]",
+		},
+		{
+			name:      "values differ",
+			message:   "[Tried to delete resource record set [name='a3f8c2d1.grocky.net.', type='A'] but the values provided do not match the current values]",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockRoute53Client{
+				changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+					return nil, &types.InvalidChangeBatch{Messages: []string{tc.message}}
+				},
+			}
+
+			svc := NewRoute53Service(client, "Z123456789", newTestLogger())
+
+			err := svc.DeleteRecord(context.Background(), "a3f8c2d1", "203.0.113.42")
+
+			if tc.expectErr {
+				assert.ErrorContains(t, err, "failed to delete DNS record")
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestRoute53Service_DeleteRecordSet_AlreadyDeleted(t *testing.T) {
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			return nil, &types.InvalidChangeBatch{
+				Message: aws.String("[Tried to delete resource record set [name='mail.a3f8c2d1.grocky.net.', type='MX'] but it was not found]"),
+			}
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", newTestLogger())
+
+	err := svc.DeleteRecordSet(context.Background(), RecordSet{Name: "mail.a3f8c2d1", Type: "MX", Values: []string{"10 mx.example.com"}})
+
+	assert.NilError(t, err)
+}
+
+func TestAddressRecordType(t *testing.T) {
+	assert.Equal(t, types.RRTypeA, AddressRecordType("203.0.113.42"))
+	assert.Equal(t, types.RRTypeA, AddressRecordType("::ffff:203.0.113.42"))
+	assert.Equal(t, types.RRTypeAaaa, AddressRecordType("2001:db8::1"))
+}
+
+func TestRoute53Service_UpsertRecordSet_TXTQuoted(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *route53.ChangeResourceRecordSetsInput
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			capturedInput = params
+			return &route53.ChangeResourceRecordSetsOutput{}, nil
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+
+	err := svc.UpsertRecordSet(ctx, RecordSet{
+		Name:   "a3f8c2d1",
+		Type:   "TXT",
+		Values: []string{`say "hi"`},
+	})
+
+	assert.NilError(t, err)
+	change := capturedInput.ChangeBatch.Changes[0]
+	assert.Equal(t, types.ChangeActionUpsert, change.Action)
+	assert.Equal(t, "a3f8c2d1."+RootDomain, *change.ResourceRecordSet.Name)
+	assert.Equal(t, types.RRTypeTxt, change.ResourceRecordSet.Type)
+	assert.Equal(t, int64(DefaultTTL), *change.ResourceRecordSet.TTL)
+	assert.Equal(t, `"say \"hi\""`, *change.ResourceRecordSet.ResourceRecords[0].Value)
+}
+
+func TestRoute53Service_DeleteRecordSet_SRV(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *route53.ChangeResourceRecordSetsInput
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			capturedInput = params
+			return &route53.ChangeResourceRecordSetsOutput{}, nil
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+
+	err := svc.DeleteRecordSet(ctx, RecordSet{
+		Name:   "_minecraft._tcp.a3f8c2d1",
+		Type:   "SRV",
+		TTL:    600,
+		Values: []string{"0 5 25565 mc.example.com"},
+	})
+
+	assert.NilError(t, err)
+	change := capturedInput.ChangeBatch.Changes[0]
+	assert.Equal(t, types.ChangeActionDelete, change.Action)
+	assert.Equal(t, "_minecraft._tcp.a3f8c2d1."+RootDomain, *change.ResourceRecordSet.Name)
+	assert.Equal(t, int64(600), *change.ResourceRecordSet.TTL)
+	assert.Equal(t, "0 5 25565 mc.example.com", *change.ResourceRecordSet.ResourceRecords[0].Value)
+}
+
+func TestRoute53Service_UpsertRecordSet_Error(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockRoute53Client{
+		changeResourceRecordSetsFunc: func(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+			return nil, errors.New("route53 error")
+		},
+	}
+
+	svc := NewRoute53Service(client, "Z123456789", logger)
+
+	err := svc.UpsertRecordSet(ctx, RecordSet{Name: "a3f8c2d1", Type: "MX", Values: []string{"10 mail.example.com"}})
+
+	assert.ErrorContains(t, err, "failed to upsert record set")
 }
 
 func TestDefaultTTL(t *testing.T) {
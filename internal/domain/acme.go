@@ -1,9 +1,33 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 )
 
+// ACMEChallengeLimit limits how many ACME challenges an owner may create: at
+// most MaxChallenges in each fixed Window. Zero MaxChallenges is unlimited.
+type ACMEChallengeLimit struct {
+	MaxChallenges int
+	Window        time.Duration
+}
+
+// DefaultACMEChallengeLimit applies unless the service is configured otherwise.
+var DefaultACMEChallengeLimit = ACMEChallengeLimit{
+	MaxChallenges: 10,
+	Window:        time.Hour,
+}
+
+// Unlimited reports whether the limit is turned off.
+func (l ACMEChallengeLimit) Unlimited() bool {
+	return l.MaxChallenges == 0
+}
+
+// String describes the limit, e.g. "maximum 10 challenges per hour".
+func (l ACMEChallengeLimit) String() string {
+	return fmt.Sprintf("maximum %d challenges per %s", l.MaxChallenges, describeWindow(l.Window))
+}
+
 // ACMEChallenge represents an active ACME DNS-01 challenge.
 type ACMEChallenge struct {
 	OwnerID      string    `dynamodbav:"OwnerId" json:"ownerId"`
@@ -17,6 +41,7 @@ type ACMEChallenge struct {
 }
 
 // CreateChallengeRequest is the request body for creating an ACME challenge.
+// OwnerID is optional when the API key embeds a key ID.
 type CreateChallengeRequest struct {
 	OwnerID  string `json:"ownerId"`
 	Location string `json:"location"`
@@ -25,9 +50,6 @@ type CreateChallengeRequest struct {
 
 // Validate checks if the request is valid.
 func (r CreateChallengeRequest) Validate() error {
-	if r.OwnerID == "" {
-		return ErrMissingOwnerID
-	}
 	if r.Location == "" {
 		return ErrMissingLocation
 	}
@@ -49,9 +71,6 @@ type DeleteChallengeRequest struct {
 
 // Validate checks if the request is valid.
 func (r DeleteChallengeRequest) Validate() error {
-	if r.OwnerID == "" {
-		return ErrMissingOwnerID
-	}
 	if r.Location == "" {
 		return ErrMissingLocation
 	}
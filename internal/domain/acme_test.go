@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestACMEChallengeLimit(t *testing.T) {
+	assert.Equal(t, "maximum 10 challenges per hour", DefaultACMEChallengeLimit.String())
+	assert.Equal(t, "maximum 3 challenges per 15 minutes", ACMEChallengeLimit{MaxChallenges: 3, Window: 15 * time.Minute}.String())
+	assert.Assert(t, !DefaultACMEChallengeLimit.Unlimited())
+	assert.Assert(t, ACMEChallengeLimit{}.Unlimited())
+}
@@ -0,0 +1,289 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// API key scopes.
+const (
+	// ScopeUpdate allows publishing a location's address via /update.
+	ScopeUpdate = "update"
+	// ScopeLookup allows reading a location's address via /lookup.
+	ScopeLookup = "lookup"
+	// ScopeACME allows creating and deleting ACME challenges.
+	ScopeACME = "acme"
+	// ScopeManage allows changing keys, location settings and record sets.
+	ScopeManage = "manage"
+)
+
+// AllScopes lists every scope in display order.
+var AllScopes = []string{ScopeUpdate, ScopeLookup, ScopeACME, ScopeManage}
+
+const (
+	// PrimaryKeyID identifies the owner's original key stored on the owner record.
+	// It always has every scope and cannot be revoked, only rotated.
+	PrimaryKeyID = "primary"
+
+	// PreviousKeyID identifies the primary key replaced by the last rotation while
+	// it is kept for the rotation grace period. It can be revoked early.
+	PreviousKeyID = "previous"
+
+	// MaxAPIKeys is how many additional keys an owner may create.
+	MaxAPIKeys = 10
+
+	// MaxKeyLabelLength bounds the human-readable key label.
+	MaxKeyLabelLength = 64
+
+	// MaxKeyLifetimeDays bounds the optional key expiry.
+	MaxKeyLifetimeDays = 730
+
+	// LastUsedWriteInterval is how stale last-used data may get before a request
+	// writes it again. Keeping it coarse avoids a write on every request.
+	LastUsedWriteInterval = time.Hour
+
+	// KeyExpiryWarningWindow is how long before expiry owners are emailed.
+	KeyExpiryWarningWindow = 7 * 24 * time.Hour
+
+	// DefaultRotationGracePeriod is how long a rotated-out primary key keeps
+	// working, so clients can be moved to the new key without downtime.
+	DefaultRotationGracePeriod = 24 * time.Hour
+
+	// MaxRotationGracePeriod bounds the grace period.
+	MaxRotationGracePeriod = 7 * 24 * time.Hour
+)
+
+// APIKey is an additional API key for an owner with limited scopes.
+// Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	OwnerID string `dynamodbav:"OwnerId"`
+	KeyID   string `dynamodbav:"KeyId"`
+	Label   string `dynamodbav:"Label"`
+	KeyHash string `dynamodbav:"KeyHash"`
+
+	// SigningSecret is the secret for signed requests, sealed with the server's
+	// signing secret key and bound to the embedded key ID. It is empty for keys
+	// issued before signing secrets were introduced, which can't sign requests.
+	SigningSecret string `dynamodbav:"SigningSecret,omitempty"`
+
+	Scopes    []string  `dynamodbav:"Scopes"`
+	Locations []string  `dynamodbav:"Locations,omitempty"`
+	CreatedAt time.Time `dynamodbav:"CreatedAt"`
+
+	// ExpiresAt is nil for keys that never expire.
+	ExpiresAt *time.Time `dynamodbav:"ExpiresAt,omitempty"`
+
+	// LastUsedAt and LastUsedIP are updated at most every LastUsedWriteInterval.
+	LastUsedAt *time.Time `dynamodbav:"LastUsedAt,omitempty"`
+	LastUsedIP string     `dynamodbav:"LastUsedIp,omitempty"`
+
+	// ExpiryWarnedAt records when the owner was told the key is about to expire.
+	ExpiryWarnedAt *time.Time `dynamodbav:"ExpiryWarnedAt,omitempty"`
+}
+
+// PrimaryAPIKey describes the owner's primary key as an APIKey.
+func PrimaryAPIKey(owner Owner) APIKey {
+	return APIKey{
+		OwnerID:   owner.OwnerID,
+		KeyID:     PrimaryKeyID,
+		Label:     PrimaryKeyID,
+		KeyHash:   owner.APIKeyHash,
+		Scopes:    AllScopes,
+		CreatedAt: owner.CreatedAt,
+
+		SigningSecret: owner.APIKeySigningSecret,
+
+		ExpiresAt:      owner.APIKeyExpiresAt,
+		LastUsedAt:     owner.APIKeyLastUsedAt,
+		LastUsedIP:     owner.APIKeyLastUsedIP,
+		ExpiryWarnedAt: owner.APIKeyExpiryWarnedAt,
+	}
+}
+
+// PreviousKey is a rotated-out primary key that keeps working until ExpiresAt.
+type PreviousKey struct {
+	// KeyID is the key ID embedded in the key; empty for keys without one.
+	KeyID         string
+	KeyHash       string
+	SigningSecret string
+	ExpiresAt     time.Time
+}
+
+// PreviousAPIKey describes the owner's previous primary key as an APIKey, if the
+// owner has one. It has every scope, like the primary key, and expires when its
+// grace period ends.
+func PreviousAPIKey(owner Owner) (APIKey, bool) {
+	if owner.PreviousAPIKeyHash == "" {
+		return APIKey{}, false
+	}
+	return APIKey{
+		OwnerID: owner.OwnerID,
+		KeyID:   PreviousKeyID,
+		Label:   PreviousKeyID,
+		KeyHash: owner.PreviousAPIKeyHash,
+		Scopes:  AllScopes,
+
+		SigningSecret: owner.PreviousAPIKeySigningSecret,
+
+		ExpiresAt:  owner.PreviousAPIKeyExpiresAt,
+		LastUsedAt: owner.PreviousAPIKeyLastUsedAt,
+		LastUsedIP: owner.PreviousAPIKeyLastUsedIP,
+	}, true
+}
+
+// IsPrimary reports whether this is the owner's primary key.
+func (k APIKey) IsPrimary() bool {
+	return k.KeyID == PrimaryKeyID
+}
+
+// HasScope reports whether the key grants scope.
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// AllowsLocation reports whether the key may act on location.
+// A key without a location allow-list may act on every location.
+func (k APIKey) AllowsLocation(location string) bool {
+	return len(k.Locations) == 0 || slices.Contains(k.Locations, location)
+}
+
+// IsRestricted reports whether the key is limited to specific locations.
+func (k APIKey) IsRestricted() bool {
+	return len(k.Locations) > 0
+}
+
+// IsExpired reports whether the key has expired at now.
+func (k APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// ExpiresWithin reports whether an unexpired key expires within d of now.
+func (k APIKey) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return k.ExpiresAt != nil && !k.IsExpired(now) && k.ExpiresAt.Sub(now) <= d
+}
+
+// NeedsUsageUpdate reports whether a use at now from ip should be written.
+// Uses are written when the stored data is missing, older than
+// LastUsedWriteInterval, or from a different IP.
+func (k APIKey) NeedsUsageUpdate(now time.Time, ip string) bool {
+	if k.LastUsedAt == nil || k.LastUsedIP != ip {
+		return true
+	}
+	return now.Sub(*k.LastUsedAt) >= LastUsedWriteInterval
+}
+
+// KeyExpiry returns the expiry for a key created at now that lives for days.
+// Zero days means the key never expires.
+func KeyExpiry(now time.Time, days int) *time.Time {
+	if days == 0 {
+		return nil
+	}
+	expiresAt := now.AddDate(0, 0, days)
+	return &expiresAt
+}
+
+// validateExpiryDays checks an optional key lifetime in days.
+func validateExpiryDays(days int) error {
+	if days < 0 || days > MaxKeyLifetimeDays {
+		return ErrInvalidKeyExpiry
+	}
+	return nil
+}
+
+// CreateAPIKeyRequest is the request body for creating an additional API key.
+type CreateAPIKeyRequest struct {
+	Label     string   `json:"label"`
+	Scopes    []string `json:"scopes"`
+	Locations []string `json:"locations,omitempty"`
+
+	// ExpiresInDays is optional; zero means the key never expires.
+	ExpiresInDays int `json:"expiresInDays,omitempty"`
+}
+
+// Normalize trims the label, lowercases scopes and drops duplicates.
+func (r CreateAPIKeyRequest) Normalize() CreateAPIKeyRequest {
+	r.Label = strings.TrimSpace(r.Label)
+	r.Scopes = normalizeList(r.Scopes, strings.ToLower)
+	r.Locations = normalizeList(r.Locations, func(s string) string { return s })
+	return r
+}
+
+// Validate checks the label, scopes and locations.
+func (r CreateAPIKeyRequest) Validate() error {
+	if r.Label == "" {
+		return ErrMissingKeyLabel
+	}
+	if len(r.Label) > MaxKeyLabelLength {
+		return ErrInvalidKeyLabel
+	}
+	if len(r.Scopes) == 0 {
+		return ErrMissingScopes
+	}
+	for _, scope := range r.Scopes {
+		if !slices.Contains(AllScopes, scope) {
+			return ErrInvalidScope
+		}
+	}
+	return validateExpiryDays(r.ExpiresInDays)
+}
+
+// RotateKeyRequest is the optional request body for rotating the primary key.
+type RotateKeyRequest struct {
+	// ExpiresInDays is optional; zero means the new key never expires.
+	ExpiresInDays int `json:"expiresInDays,omitempty"`
+
+	// GracePeriodMinutes overrides how long the old key keeps working; zero
+	// revokes it immediately. Nil uses the service default.
+	GracePeriodMinutes *int `json:"gracePeriodMinutes,omitempty"`
+}
+
+// Validate checks the optional expiry and grace period.
+func (r RotateKeyRequest) Validate() error {
+	if r.GracePeriodMinutes != nil {
+		grace := time.Duration(*r.GracePeriodMinutes) * time.Minute
+		if grace < 0 || grace > MaxRotationGracePeriod {
+			return ErrInvalidGracePeriod
+		}
+	}
+	return validateExpiryDays(r.ExpiresInDays)
+}
+
+// GracePeriod returns the requested grace period, or def if none was given.
+func (r RotateKeyRequest) GracePeriod(def time.Duration) time.Duration {
+	if r.GracePeriodMinutes == nil {
+		return def
+	}
+	return time.Duration(*r.GracePeriodMinutes) * time.Minute
+}
+
+// PreviousKeyFor returns what remains of owner's primary key when it is rotated at
+// now with the given grace period, or nil if it should stop working immediately.
+// The grace period never extends the key beyond its own expiry.
+func PreviousKeyFor(owner Owner, now time.Time, grace time.Duration) *PreviousKey {
+	expiresAt := now.Add(grace)
+	if owner.APIKeyExpiresAt != nil && owner.APIKeyExpiresAt.Before(expiresAt) {
+		expiresAt = *owner.APIKeyExpiresAt
+	}
+	if !expiresAt.After(now) {
+		return nil
+	}
+	return &PreviousKey{
+		KeyID:         owner.APIKeyID,
+		KeyHash:       owner.APIKeyHash,
+		SigningSecret: owner.APIKeySigningSecret,
+		ExpiresAt:     expiresAt,
+	}
+}
+
+// normalizeList trims entries, applies fn, and removes blanks and duplicates.
+func normalizeList(values []string, fn func(string) string) []string {
+	var out []string
+	for _, v := range values {
+		v = fn(strings.TrimSpace(v))
+		if v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestCreateAPIKeyRequest_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		request     CreateAPIKeyRequest
+		expectedErr error
+	}{
+		{
+			name:        "valid",
+			request:     CreateAPIKeyRequest{Label: "cabin pi", Scopes: []string{"update"}, Locations: []string{"cabin"}},
+			expectedErr: nil,
+		},
+		{
+			name:        "missing label",
+			request:     CreateAPIKeyRequest{Label: "  ", Scopes: []string{"update"}},
+			expectedErr: ErrMissingKeyLabel,
+		},
+		{
+			name:        "label too long",
+			request:     CreateAPIKeyRequest{Label: string(make([]byte, 65)), Scopes: []string{"update"}},
+			expectedErr: ErrInvalidKeyLabel,
+		},
+		{
+			name:        "missing scopes",
+			request:     CreateAPIKeyRequest{Label: "cabin pi"},
+			expectedErr: ErrMissingScopes,
+		},
+		{
+			name:        "expiry too long",
+			request:     CreateAPIKeyRequest{Label: "cabin pi", Scopes: []string{"update"}, ExpiresInDays: MaxKeyLifetimeDays + 1},
+			expectedErr: ErrInvalidKeyExpiry,
+		},
+		{
+			name:        "negative expiry",
+			request:     CreateAPIKeyRequest{Label: "cabin pi", Scopes: []string{"update"}, ExpiresInDays: -1},
+			expectedErr: ErrInvalidKeyExpiry,
+		},
+		{
+			name:        "unknown scope",
+			request:     CreateAPIKeyRequest{Label: "cabin pi", Scopes: []string{"admin"}},
+			expectedErr: ErrInvalidScope,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.request.Normalize().Validate()
+			if tc.expectedErr == nil {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCreateAPIKeyRequest_Normalize(t *testing.T) {
+	req := CreateAPIKeyRequest{
+		Label:     " cabin pi ",
+		Scopes:    []string{"Update", "update", " ACME "},
+		Locations: []string{"cabin", "", "cabin"},
+	}.Normalize()
+
+	assert.Equal(t, "cabin pi", req.Label)
+	assert.DeepEqual(t, []string{"update", "acme"}, req.Scopes)
+	assert.DeepEqual(t, []string{"cabin"}, req.Locations)
+}
+
+func TestAPIKey_Permissions(t *testing.T) {
+	key := APIKey{Scopes: []string{ScopeUpdate}, Locations: []string{"cabin"}}
+
+	assert.Assert(t, key.HasScope(ScopeUpdate))
+	assert.Assert(t, !key.HasScope(ScopeManage))
+	assert.Assert(t, key.AllowsLocation("cabin"))
+	assert.Assert(t, !key.AllowsLocation("home"))
+	assert.Assert(t, key.IsRestricted())
+
+	unrestricted := APIKey{Scopes: []string{ScopeLookup}}
+	assert.Assert(t, unrestricted.AllowsLocation("home"))
+	assert.Assert(t, !unrestricted.IsRestricted())
+}
+
+func TestPrimaryAPIKey(t *testing.T) {
+	created := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	key := PrimaryAPIKey(Owner{OwnerID: "test-owner", APIKeyHash: "hash", CreatedAt: created})
+
+	assert.Assert(t, key.IsPrimary())
+	assert.Equal(t, "hash", key.KeyHash)
+	assert.Equal(t, created, key.CreatedAt)
+	for _, scope := range AllScopes {
+		assert.Assert(t, key.HasScope(scope))
+	}
+	assert.Assert(t, !key.IsRestricted())
+}
+
+func TestPreviousAPIKey(t *testing.T) {
+	_, ok := PreviousAPIKey(Owner{OwnerID: "test-owner", APIKeyHash: "hash"})
+	assert.Assert(t, !ok)
+
+	expiresAt := time.Date(2025, 1, 16, 10, 0, 0, 0, time.UTC)
+	key, ok := PreviousAPIKey(Owner{
+		OwnerID:                 "test-owner",
+		APIKeyHash:              "hash",
+		PreviousAPIKeyHash:      "oldhash",
+		PreviousAPIKeyExpiresAt: &expiresAt,
+	})
+	assert.Assert(t, ok)
+	assert.Equal(t, PreviousKeyID, key.KeyID)
+	assert.Equal(t, "oldhash", key.KeyHash)
+	assert.Assert(t, key.HasScope(ScopeManage))
+	assert.Assert(t, key.IsExpired(expiresAt))
+}
+
+func TestRotateKeyRequest_GracePeriod(t *testing.T) {
+	minutes := func(m int) *int { return &m }
+
+	testCases := []struct {
+		name     string
+		request  RotateKeyRequest
+		expected time.Duration
+		err      error
+	}{
+		{name: "default", request: RotateKeyRequest{}, expected: time.Hour},
+		{name: "immediate", request: RotateKeyRequest{GracePeriodMinutes: minutes(0)}, expected: 0},
+		{name: "override", request: RotateKeyRequest{GracePeriodMinutes: minutes(90)}, expected: 90 * time.Minute},
+		{name: "maximum", request: RotateKeyRequest{GracePeriodMinutes: minutes(10080)}, expected: MaxRotationGracePeriod},
+		{name: "too long", request: RotateKeyRequest{GracePeriodMinutes: minutes(10081)}, err: ErrInvalidGracePeriod},
+		{name: "negative", request: RotateKeyRequest{GracePeriodMinutes: minutes(-1)}, err: ErrInvalidGracePeriod},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.request.Validate()
+			if tc.err != nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, tc.request.GracePeriod(time.Hour))
+		})
+	}
+}
+
+func TestPreviousKeyFor(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	owner := Owner{OwnerID: "test-owner", APIKeyID: "9f2c4a1b7e3d", APIKeyHash: "hash", APIKeySigningSecret: "sealed"}
+
+	previous := PreviousKeyFor(owner, now, time.Hour)
+	assert.Assert(t, previous != nil)
+	assert.Equal(t, "9f2c4a1b7e3d", previous.KeyID)
+	assert.Equal(t, "hash", previous.KeyHash)
+	assert.Equal(t, "sealed", previous.SigningSecret)
+	assert.Equal(t, now.Add(time.Hour), previous.ExpiresAt)
+
+	assert.Assert(t, PreviousKeyFor(owner, now, 0) == nil)
+
+	// The grace period doesn't outlive the key's own expiry
+	soon := now.Add(10 * time.Minute)
+	owner.APIKeyExpiresAt = &soon
+	assert.Equal(t, soon, PreviousKeyFor(owner, now, time.Hour).ExpiresAt)
+
+	expired := now.Add(-time.Minute)
+	owner.APIKeyExpiresAt = &expired
+	assert.Assert(t, PreviousKeyFor(owner, now, time.Hour) == nil)
+}
+
+func TestAPIKey_Expiry(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	never := APIKey{}
+	assert.Assert(t, !never.IsExpired(now))
+	assert.Assert(t, !never.ExpiresWithin(now, KeyExpiryWarningWindow))
+
+	soon := APIKey{ExpiresAt: KeyExpiry(now, 3)}
+	assert.Assert(t, !soon.IsExpired(now))
+	assert.Assert(t, soon.ExpiresWithin(now, KeyExpiryWarningWindow))
+	assert.Assert(t, soon.IsExpired(now.AddDate(0, 0, 3)))
+	assert.Assert(t, !soon.ExpiresWithin(now.AddDate(0, 0, 3), KeyExpiryWarningWindow))
+
+	later := APIKey{ExpiresAt: KeyExpiry(now, 30)}
+	assert.Assert(t, !later.ExpiresWithin(now, KeyExpiryWarningWindow))
+
+	assert.Assert(t, KeyExpiry(now, 0) == nil)
+}
+
+func TestAPIKey_NeedsUsageUpdate(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	recent := now.Add(-10 * time.Minute)
+	stale := now.Add(-LastUsedWriteInterval)
+
+	testCases := []struct {
+		name     string
+		key      APIKey
+		ip       string
+		expected bool
+	}{
+		{"never used", APIKey{}, "203.0.113.50", true},
+		{"recent use from same IP", APIKey{LastUsedAt: &recent, LastUsedIP: "203.0.113.50"}, "203.0.113.50", false},
+		{"recent use from other IP", APIKey{LastUsedAt: &recent, LastUsedIP: "203.0.113.50"}, "198.51.100.7", true},
+		{"stale use", APIKey{LastUsedAt: &stale, LastUsedIP: "203.0.113.50"}, "203.0.113.50", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.key.NeedsUsageUpdate(now, tc.ip))
+		})
+	}
+}
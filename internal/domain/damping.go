@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Damping modes for locations whose IP address flaps between a few values.
+const (
+	// DampingOff publishes every IP change, subject to the rate limit.
+	DampingOff = "off"
+
+	// DampingHold keeps the published IP while the location is flapping.
+	DampingHold = "hold"
+
+	// DampingSettle publishes a flapping location's new IP only once it has been
+	// reported consistently for the settle time.
+	DampingSettle = "settle"
+)
+
+// Bounds and defaults for damping policies.
+const (
+	DefaultFlapWindowSeconds = 60 * 60
+	MinFlapWindowSeconds     = 5 * 60
+	MaxFlapWindowSeconds     = 24 * 60 * 60
+
+	DefaultSettleSeconds = 10 * 60
+	MinSettleSeconds     = 60
+	MaxSettleSeconds     = 24 * 60 * 60
+)
+
+// MaxIPHistory is how many previously published IPs a mapping remembers for
+// flap detection.
+const MaxIPHistory = 4
+
+// DampingPolicy controls how a location reacts to flapping: being asked to
+// return to an IP it was published at less than FlapWindowSeconds ago, as in
+// A→B→A.
+type DampingPolicy struct {
+	Mode              string `dynamodbav:"Mode" json:"mode"`
+	FlapWindowSeconds int    `dynamodbav:"FlapWindowSeconds" json:"flapWindowSeconds,omitempty"`
+	SettleSeconds     int    `dynamodbav:"SettleSeconds,omitempty" json:"settleSeconds,omitempty"`
+
+	// Notify emails the owner when a location starts being dampened.
+	Notify bool `dynamodbav:"Notify,omitempty" json:"notify,omitempty"`
+}
+
+// Enabled reports whether the policy dampens flapping.
+func (p *DampingPolicy) Enabled() bool {
+	return p != nil && (p.Mode == DampingHold || p.Mode == DampingSettle)
+}
+
+// FlapWindow returns how recently an IP must have been published for a return
+// to it to count as flapping.
+func (p DampingPolicy) FlapWindow() time.Duration {
+	return time.Duration(p.FlapWindowSeconds) * time.Second
+}
+
+// SettleTime returns how long a new IP must be reported in settle mode.
+func (p DampingPolicy) SettleTime() time.Duration {
+	return time.Duration(p.SettleSeconds) * time.Second
+}
+
+// Normalize lowercases the mode and fills in default durations.
+func (p DampingPolicy) Normalize() DampingPolicy {
+	p.Mode = strings.ToLower(strings.TrimSpace(p.Mode))
+	if p.FlapWindowSeconds == 0 {
+		p.FlapWindowSeconds = DefaultFlapWindowSeconds
+	}
+	if p.Mode == DampingSettle && p.SettleSeconds == 0 {
+		p.SettleSeconds = DefaultSettleSeconds
+	}
+	if p.Mode != DampingSettle {
+		p.SettleSeconds = 0
+	}
+	return p
+}
+
+// Validate checks the mode and durations.
+func (p DampingPolicy) Validate() error {
+	switch p.Mode {
+	case DampingOff, DampingHold, DampingSettle:
+	default:
+		return ErrInvalidDampingMode
+	}
+	if p.FlapWindowSeconds < MinFlapWindowSeconds || p.FlapWindowSeconds > MaxFlapWindowSeconds {
+		return ErrInvalidFlapWindow
+	}
+	if p.Mode == DampingSettle && (p.SettleSeconds < MinSettleSeconds || p.SettleSeconds > MaxSettleSeconds) {
+		return ErrInvalidSettleTime
+	}
+	return nil
+}
+
+// PublishedIP is an IP a location was published at until ReplacedAt.
+type PublishedIP struct {
+	IP         string    `dynamodbav:"IP"`
+	ReplacedAt time.Time `dynamodbav:"ReplacedAt"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDampingPolicy_Normalize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   DampingPolicy
+		expected DampingPolicy
+	}{
+		{
+			name:     "hold defaults",
+			policy:   DampingPolicy{Mode: "HOLD"},
+			expected: DampingPolicy{Mode: DampingHold, FlapWindowSeconds: DefaultFlapWindowSeconds},
+		},
+		{
+			name:     "settle defaults",
+			policy:   DampingPolicy{Mode: DampingSettle},
+			expected: DampingPolicy{Mode: DampingSettle, FlapWindowSeconds: DefaultFlapWindowSeconds, SettleSeconds: DefaultSettleSeconds},
+		},
+		{
+			name:     "settle time only applies to settle mode",
+			policy:   DampingPolicy{Mode: DampingHold, FlapWindowSeconds: 900, SettleSeconds: 300},
+			expected: DampingPolicy{Mode: DampingHold, FlapWindowSeconds: 900},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.policy.Normalize())
+		})
+	}
+}
+
+func TestDampingPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      DampingPolicy
+		expectedErr error
+	}{
+		{name: "off", policy: DampingPolicy{Mode: DampingOff, FlapWindowSeconds: 3600}},
+		{name: "hold", policy: DampingPolicy{Mode: DampingHold, FlapWindowSeconds: 3600}},
+		{name: "settle", policy: DampingPolicy{Mode: DampingSettle, FlapWindowSeconds: 3600, SettleSeconds: 600}},
+		{name: "unknown mode", policy: DampingPolicy{Mode: "smooth", FlapWindowSeconds: 3600}, expectedErr: ErrInvalidDampingMode},
+		{name: "window too short", policy: DampingPolicy{Mode: DampingHold, FlapWindowSeconds: 60}, expectedErr: ErrInvalidFlapWindow},
+		{name: "window too long", policy: DampingPolicy{Mode: DampingHold, FlapWindowSeconds: MaxFlapWindowSeconds + 1}, expectedErr: ErrInvalidFlapWindow},
+		{name: "settle too short", policy: DampingPolicy{Mode: DampingSettle, FlapWindowSeconds: 3600, SettleSeconds: 10}, expectedErr: ErrInvalidSettleTime},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, tc.policy.Validate())
+		})
+	}
+}
+
+func TestDampingPolicy_Enabled(t *testing.T) {
+	var none *DampingPolicy
+	assert.Assert(t, !none.Enabled())
+	assert.Assert(t, !(&DampingPolicy{Mode: DampingOff}).Enabled())
+	assert.Assert(t, (&DampingPolicy{Mode: DampingHold}).Enabled())
+	assert.Assert(t, (&DampingPolicy{Mode: DampingSettle}).Enabled())
+}
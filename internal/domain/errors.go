@@ -33,8 +33,9 @@ var (
 	// ErrForbidden is returned when the authenticated owner doesn't match the requested resource.
 	ErrForbidden = errors.New("forbidden")
 
-	// ErrRateLimitExceeded is returned when too many IP changes occur in an hour.
-	ErrRateLimitExceeded = errors.New("rate limit exceeded: maximum 2 IP changes per hour")
+	// ErrRateLimitExceeded is returned when a location's IP changes more often than
+	// its rate limit policy allows.
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
 
 	// ErrMissingIP is returned when the client IP cannot be determined.
 	ErrMissingIP = errors.New("could not determine client IP")
@@ -45,12 +46,179 @@ var (
 	// ErrInvalidTxtValue is returned when txtValue format is invalid.
 	ErrInvalidTxtValue = errors.New("invalid txtValue format")
 
+	// ErrMissingKeyLabel is returned when an API key label is empty.
+	ErrMissingKeyLabel = errors.New("label is required")
+
+	// ErrInvalidKeyLabel is returned when an API key label is too long.
+	ErrInvalidKeyLabel = errors.New("label must be at most 64 characters")
+
+	// ErrMissingScopes is returned when an API key has no scopes.
+	ErrMissingScopes = errors.New("at least one scope is required")
+
+	// ErrInvalidScope is returned for an unknown API key scope.
+	ErrInvalidScope = errors.New("scopes must be update, lookup, acme or manage")
+
+	// ErrAPIKeyNotFound is returned when an API key doesn't exist.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// ErrAPIKeyExpired is returned when an API key is past its expiry.
+	ErrAPIKeyExpired = errors.New("API key expired")
+
+	// ErrOwnerIDRequired is returned when ownerId is omitted and the API key has no
+	// embedded key ID to resolve the owner from.
+	ErrOwnerIDRequired = errors.New("ownerId is required for API keys without a key ID; rotate your key to get one")
+
+	// ErrNonceReused is returned when a signed request's nonce was already used.
+	ErrNonceReused = errors.New("request nonce already used")
+
+	// ErrTooManyAuthFailures is returned while a source IP is locked out after
+	// repeated failed authentication attempts.
+	ErrTooManyAuthFailures = errors.New("too many failed authentication attempts")
+
+	// ErrTooManyRequests is returned when a source IP, API key or owner exceeds
+	// its route's request limit.
+	ErrTooManyRequests = errors.New("too many requests")
+
+	// ErrOwnerSuspended is returned when a suspended owner authenticates.
+	ErrOwnerSuspended = errors.New("owner suspended")
+
+	// ErrOwnerNotVerified is returned when an owner whose email address is
+	// unverified authenticates.
+	ErrOwnerNotVerified = errors.New("owner email address not verified")
+
+	// ErrMissingToken is returned when a verification or recovery request has no token.
+	ErrMissingToken = errors.New("token is required")
+
+	// ErrInvalidVerificationToken is returned when a verification token is invalid,
+	// expired, or for another owner.
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+	// ErrInvalidRecoveryToken is returned when a recovery token is invalid, expired,
+	// or was issued for a key that has since changed.
+	ErrInvalidRecoveryToken = errors.New("invalid or expired recovery token")
+
+	// ErrOperatorNotFound is returned when an operator key ID doesn't exist.
+	ErrOperatorNotFound = errors.New("operator not found")
+
+	// ErrMissingSuspendReason is returned when a suspension has no reason.
+	ErrMissingSuspendReason = errors.New("reason is required")
+
+	// ErrInvalidSuspendReason is returned when the suspension reason is too long.
+	ErrInvalidSuspendReason = errors.New("reason must be at most 256 characters")
+
+	// ErrMissingSubdomain is returned when a subdomain change has no subdomain.
+	ErrMissingSubdomain = errors.New("subdomain is required")
+
+	// ErrInvalidSubdomain is returned when a subdomain isn't a single hostname label.
+	ErrInvalidSubdomain = errors.New("subdomain must be a single label of lowercase letters, digits and hyphens")
+
+	// ErrSubdomainTaken is returned when another location already uses a subdomain.
+	ErrSubdomainTaken = errors.New("subdomain is already in use")
+
+	// ErrInvalidKeyExpiry is returned for an out of range key lifetime.
+	ErrInvalidKeyExpiry = errors.New("expiresInDays must be between 1 and 730")
+
+	// ErrInvalidGracePeriod is returned for an out of range rotation grace period.
+	ErrInvalidGracePeriod = errors.New("gracePeriodMinutes must be between 0 and 10080")
+
+	// ErrInvalidRateLimitChanges is returned for an out of range rate limit.
+	ErrInvalidRateLimitChanges = errors.New("maxChanges must be between 1 and 100")
+
+	// ErrInvalidRateLimitWindow is returned for an out of range rate limit window.
+	ErrInvalidRateLimitWindow = errors.New("windowSeconds must be between 60 and 604800")
+
+	// ErrInvalidRateLimitAlgorithm is returned for an unknown rate limit algorithm.
+	ErrInvalidRateLimitAlgorithm = errors.New("algorithm must be \"fixed\" or \"sliding\"")
+
+	// ErrInvalidRateLimitPlanName is returned for a malformed or reserved plan name.
+	ErrInvalidRateLimitPlanName = errors.New("plan name must be at most 32 lowercase letters, digits and hyphens, and not \"owner\"")
+
+	// ErrRateLimitPlanNotFound is returned when a rate limit plan doesn't exist.
+	ErrRateLimitPlanNotFound = errors.New("rate limit plan not found")
+
+	// ErrAPIKeyQuotaExceeded is returned when an owner already has the maximum number of keys.
+	ErrAPIKeyQuotaExceeded = errors.New("API key quota exceeded")
+
+	// ErrMissingLocationSettings is returned when a settings update changes nothing.
+	ErrMissingLocationSettings = errors.New("at least one location setting is required")
+
+	// ErrInvalidDampingMode is returned for an unknown damping mode.
+	ErrInvalidDampingMode = errors.New("damping mode must be \"off\", \"hold\" or \"settle\"")
+
+	// ErrInvalidFlapWindow is returned when a flap window is out of range.
+	ErrInvalidFlapWindow = errors.New("flapWindowSeconds must be between 300 and 86400")
+
+	// ErrInvalidSettleTime is returned when a settle time is out of range.
+	ErrInvalidSettleTime = errors.New("settleSeconds must be between 60 and 86400")
+
+	// ErrInvalidStaleAfter is returned when a stale threshold is out of range.
+	ErrInvalidStaleAfter = errors.New("staleAfterSeconds must be 0 or between 900 and 2592000")
+
+	// ErrSubdomainUnavailable is returned when every subdomain candidate is taken.
+	ErrSubdomainUnavailable = errors.New("could not allocate a unique subdomain")
+
+	// ErrTooManyNotificationLocations is returned when a notification is limited
+	// to too many locations.
+	ErrTooManyNotificationLocations = errors.New("at most 50 notification locations are allowed")
+
+	// ErrInvalidSecurityNotice is returned when a suppressed security notice is unknown.
+	ErrInvalidSecurityNotice = errors.New("security notices must be keyRotated, recoveryRequested or acmeChallenge")
+
+	// ErrInvalidLocale is returned when a locale isn't a language tag.
+	ErrInvalidLocale = errors.New("locale must be a language tag such as \"en\" or \"pt-BR\"")
+
 	// ErrChallengeNotFound is returned when an ACME challenge doesn't exist.
 	ErrChallengeNotFound = errors.New("challenge not found")
 
 	// ErrChallengeExists is returned when an ACME challenge already exists.
 	ErrChallengeExists = errors.New("challenge already exists")
 
-	// ErrACMERateLimitExceeded is returned when too many ACME challenges are created.
-	ErrACMERateLimitExceeded = errors.New("rate limit exceeded: maximum 10 challenges per hour")
+	// ErrACMERateLimitExceeded is returned when an owner creates more ACME
+	// challenges than the ACME challenge limit allows.
+	ErrACMERateLimitExceeded = errors.New("ACME challenge rate limit exceeded")
+
+	// ErrMissingRecordType is returned when a record set type is empty.
+	ErrMissingRecordType = errors.New("type is required")
+
+	// ErrUnsupportedRecordType is returned when a record set type is not whitelisted.
+	ErrUnsupportedRecordType = errors.New("unsupported record type: must be one of TXT, SRV, MX, CAA")
+
+	// ErrInvalidRecordName is returned when a record set name is not a relative DNS name.
+	ErrInvalidRecordName = errors.New("invalid record name")
+
+	// ErrInvalidRecordTTL is returned when a record set TTL is out of range.
+	ErrInvalidRecordTTL = errors.New("invalid ttl: must be between 60 and 86400 seconds")
+
+	// ErrMissingRecordValues is returned when a record set has no values.
+	ErrMissingRecordValues = errors.New("values are required")
+
+	// ErrInvalidRecordValue is returned when a record set value is malformed.
+	ErrInvalidRecordValue = errors.New("invalid record value")
+
+	// ErrRecordSetNotFound is returned when a record set doesn't exist.
+	ErrRecordSetNotFound = errors.New("record set not found")
+
+	// ErrRecordSetExists is returned when creating a record set that already exists.
+	ErrRecordSetExists = errors.New("record set already exists")
+
+	// ErrRecordSetQuotaExceeded is returned when an owner has too many record sets.
+	ErrRecordSetQuotaExceeded = errors.New("record set quota exceeded")
+
+	// ErrMissingWebhookURL is returned when a webhook has no URL.
+	ErrMissingWebhookURL = errors.New("url is required")
+
+	// ErrInvalidWebhookURL is returned when a webhook URL isn't a public https URL.
+	ErrInvalidWebhookURL = errors.New("invalid url: must be an https URL with a public host")
+
+	// ErrMissingWebhookEvents is returned when a webhook subscribes to no events.
+	ErrMissingWebhookEvents = errors.New("events are required")
+
+	// ErrInvalidWebhookEvent is returned for an unknown webhook event.
+	ErrInvalidWebhookEvent = errors.New("invalid event: must be one of ip.changed, acme.created, acme.deleted")
+
+	// ErrWebhookNotFound is returned when a webhook doesn't exist.
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrWebhookQuotaExceeded is returned when an owner already has the maximum number of webhooks.
+	ErrWebhookQuotaExceeded = errors.New("webhook quota exceeded")
 )
@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// Statuses of monitored locations.
+const (
+	// LocationOnline is a location seen within its stale threshold.
+	LocationOnline = "online"
+
+	// LocationOffline is a location that hasn't been seen within its stale threshold.
+	LocationOffline = "offline"
+)
+
+// Bounds for stale thresholds.
+const (
+	MinStaleAfterSeconds = 15 * 60
+	MaxStaleAfterSeconds = 30 * 24 * 60 * 60
+)
+
+// LastSeenResolution is how old LastSeenAt may get before a check-in that
+// changes nothing else writes it again.
+const LastSeenResolution = time.Minute
+
+// LastSeen returns when the location was last seen. Locations that haven't
+// checked in since LastSeenAt was introduced fall back to their last update.
+func (m IPMapping) LastSeen() time.Time {
+	if m.LastSeenAt != nil {
+		return *m.LastSeenAt
+	}
+	return m.UpdatedAt
+}
+
+// LastSeenDue reports whether a check-in at now should be written. It always is
+// while a stale alert is outstanding, so the alert is cleared.
+func (m IPMapping) LastSeenDue(now time.Time) bool {
+	return m.LastSeenAt == nil || m.StaleAlertedAt != nil || now.Sub(*m.LastSeenAt) >= LastSeenResolution
+}
+
+// Monitored reports whether the location has a stale threshold.
+func (m IPMapping) Monitored() bool {
+	return m.StaleAfterSeconds > 0
+}
+
+// StaleAfter returns the location's stale threshold.
+func (m IPMapping) StaleAfter() time.Duration {
+	return time.Duration(m.StaleAfterSeconds) * time.Second
+}
+
+// IsStale reports whether a monitored location has been silent for longer than
+// its threshold at now.
+func (m IPMapping) IsStale(now time.Time) bool {
+	return m.Monitored() && now.Sub(m.LastSeen()) > m.StaleAfter()
+}
+
+// Status returns LocationOnline or LocationOffline for a monitored location,
+// and "" for one without a stale threshold.
+func (m IPMapping) Status(now time.Time) string {
+	switch {
+	case !m.Monitored():
+		return ""
+	case m.IsStale(now):
+		return LocationOffline
+	default:
+		return LocationOnline
+	}
+}
+
+// StaleAlertDue reports whether the owner should be told that the location has
+// gone silent. Each silence is reported once.
+func (m IPMapping) StaleAlertDue(now time.Time) bool {
+	return m.StaleAlertedAt == nil && m.IsStale(now)
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestIPMapping_Status(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	seen := func(ago time.Duration) *time.Time {
+		at := now.Add(-ago)
+		return &at
+	}
+
+	testCases := []struct {
+		name           string
+		mapping        IPMapping
+		expectedStatus string
+		expectedAlert  bool
+	}{
+		{
+			name:    "not monitored",
+			mapping: IPMapping{LastSeenAt: seen(48 * time.Hour)},
+		},
+		{
+			name:           "seen within threshold",
+			mapping:        IPMapping{LastSeenAt: seen(10 * time.Minute), StaleAfterSeconds: 3600},
+			expectedStatus: LocationOnline,
+		},
+		{
+			name:           "silent past threshold",
+			mapping:        IPMapping{LastSeenAt: seen(2 * time.Hour), StaleAfterSeconds: 3600},
+			expectedStatus: LocationOffline,
+			expectedAlert:  true,
+		},
+		{
+			name:           "silence already reported",
+			mapping:        IPMapping{LastSeenAt: seen(2 * time.Hour), StaleAfterSeconds: 3600, StaleAlertedAt: seen(time.Hour)},
+			expectedStatus: LocationOffline,
+		},
+		{
+			name:           "never seen falls back to last update",
+			mapping:        IPMapping{UpdatedAt: now.Add(-2 * time.Hour), StaleAfterSeconds: 3600},
+			expectedStatus: LocationOffline,
+			expectedAlert:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedStatus, tc.mapping.Status(now))
+			assert.Equal(t, tc.expectedAlert, tc.mapping.StaleAlertDue(now))
+		})
+	}
+}
+
+func TestIPMapping_LastSeenDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-30 * time.Second)
+	old := now.Add(-LastSeenResolution)
+
+	assert.Assert(t, IPMapping{}.LastSeenDue(now))
+	assert.Assert(t, !IPMapping{LastSeenAt: &recent}.LastSeenDue(now))
+	assert.Assert(t, IPMapping{LastSeenAt: &old}.LastSeenDue(now))
+	assert.Assert(t, IPMapping{LastSeenAt: &recent, StaleAlertedAt: &old}.LastSeenDue(now), "check-in should clear an outstanding alert")
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// localePattern matches a normalized language tag such as "de" or "pt-br".
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// MaxLocaleLength bounds an owner's locale.
+const MaxLocaleLength = 35
+
+// NormalizeLocale lowercases a locale and uses hyphens, e.g. "pt_BR" becomes "pt-br".
+func NormalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+// ValidateLocale checks that a normalized locale is a language tag. An empty
+// locale is valid and means the default. Locales without email templates fall
+// back to the closest one that has them, so any well-formed tag is accepted.
+func ValidateLocale(locale string) error {
+	if locale == "" {
+		return nil
+	}
+	if len(locale) > MaxLocaleLength || !localePattern.MatchString(locale) {
+		return ErrInvalidLocale
+	}
+	return nil
+}
+
+// SetLocaleRequest sets the locale an owner's emails are written in.
+type SetLocaleRequest struct {
+	// Locale is a language tag such as "de" or "pt-BR"; empty restores the default.
+	Locale string `json:"locale"`
+}
+
+// Normalize normalizes the locale.
+func (r SetLocaleRequest) Normalize() SetLocaleRequest {
+	r.Locale = NormalizeLocale(r.Locale)
+	return r
+}
+
+// Validate checks the locale.
+func (r SetLocaleRequest) Validate() error {
+	return ValidateLocale(r.Locale)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		expected string
+	}{
+		{locale: "", expected: ""},
+		{locale: "en", expected: "en"},
+		{locale: " DE ", expected: "de"},
+		{locale: "pt_BR", expected: "pt-br"},
+		{locale: "zh-Hant-TW", expected: "zh-hant-tw"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.locale, func(t *testing.T) {
+			assert.Equal(t, tc.expected, NormalizeLocale(tc.locale))
+		})
+	}
+}
+
+func TestValidateLocale(t *testing.T) {
+	testCases := []struct {
+		name        string
+		locale      string
+		expectedErr error
+	}{
+		{name: "empty", locale: "", expectedErr: nil},
+		{name: "language", locale: "de", expectedErr: nil},
+		{name: "three letter language", locale: "fil", expectedErr: nil},
+		{name: "language and region", locale: "pt-br", expectedErr: nil},
+		{name: "script and region", locale: "zh-hant-tw", expectedErr: nil},
+		{name: "not normalized", locale: "pt_BR", expectedErr: ErrInvalidLocale},
+		{name: "path", locale: "../en", expectedErr: ErrInvalidLocale},
+		{name: "single letter", locale: "e", expectedErr: ErrInvalidLocale},
+		{name: "empty subtag", locale: "en-", expectedErr: ErrInvalidLocale},
+		{name: "too long", locale: "en" + strings.Repeat("-abcdefgh", 4), expectedErr: ErrInvalidLocale},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateLocale(tc.locale)
+			if tc.expectedErr == nil {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestSetLocaleRequest(t *testing.T) {
+	req := SetLocaleRequest{Locale: " pt_BR "}.Normalize()
+
+	assert.Equal(t, "pt-br", req.Locale)
+	assert.NilError(t, req.Validate())
+	assert.NilError(t, SetLocaleRequest{}.Normalize().Validate())
+	assert.Equal(t, ErrInvalidLocale, SetLocaleRequest{Locale: "not a locale"}.Normalize().Validate())
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"time"
+)
+
+// LockoutPolicy controls how failed authentication attempts against one subject
+// (a source IP or an owner) are throttled. The first FreeAttempts failures within
+// Window are free; each further failure locks the subject out for twice as long as
+// the previous one, starting at BaseDelay and capped at MaxDelay. A policy
+// without delays only counts failures.
+type LockoutPolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// Window is how long a subject must go without failures for its count to reset.
+	Window time.Duration
+}
+
+var (
+	// IPLockoutPolicy throttles guessing from a single source IP.
+	IPLockoutPolicy = LockoutPolicy{
+		FreeAttempts: 5,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       15 * time.Minute,
+	}
+
+	// OwnerFailurePolicy counts failures against one owner from any IP so the
+	// owner can be alerted. It never locks the owner out: anyone who knows an
+	// owner ID could then block the owner's clients.
+	OwnerFailurePolicy = LockoutPolicy{
+		Window: time.Hour,
+	}
+)
+
+// AuthFailureAlertThreshold is how many failures against an owner within the
+// owner policy's window trigger an email to the owner.
+const AuthFailureAlertThreshold = 10
+
+// Auth failure subject kinds.
+const (
+	AuthSubjectIP    = "ip"
+	AuthSubjectOwner = "owner"
+)
+
+// AuthFailureSubject returns the key failures are counted under.
+func AuthFailureSubject(kind, id string) string {
+	return kind + "#" + id
+}
+
+// AuthFailure counts recent failed authentication attempts against a subject.
+type AuthFailure struct {
+	Subject string `dynamodbav:"Subject"`
+	// OwnerID is set for owner subjects so the owner can be alerted.
+	OwnerID string `dynamodbav:"OwnerId,omitempty"`
+
+	Failures       int       `dynamodbav:"Failures"`
+	FirstFailureAt time.Time `dynamodbav:"FirstFailureAt"`
+	LastFailureAt  time.Time `dynamodbav:"LastFailureAt"`
+	LastFailureIP  string    `dynamodbav:"LastFailureIp,omitempty"`
+
+	LockedUntil *time.Time `dynamodbav:"LockedUntil,omitempty"`
+	AlertedAt   *time.Time `dynamodbav:"AlertedAt,omitempty"`
+
+	// ResetAt is the Unix time at which the count resets if no further failures
+	// happen. TTL lets DynamoDB delete the record once it can no longer lock
+	// anyone out.
+	ResetAt int64 `dynamodbav:"ResetAt"`
+	TTL     int64 `dynamodbav:"TTL"`
+}
+
+// RetryAfter returns how long the subject remains locked out, if it is.
+func (f AuthFailure) RetryAfter(now time.Time) (time.Duration, bool) {
+	if f.LockedUntil == nil || !now.Before(*f.LockedUntil) {
+		return 0, false
+	}
+	return f.LockedUntil.Sub(now), true
+}
+
+// LockoutDelay returns how long a subject with the given number of failures is
+// locked out for. It is zero while failures are within the free attempts.
+func (p LockoutPolicy) LockoutDelay(failures int) time.Duration {
+	excess := failures - p.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < excess && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Expiry returns when a failure recorded at lastFailureAt stops mattering: after
+// the window has passed and any lockout it caused has ended.
+func (p LockoutPolicy) Expiry(lastFailureAt time.Time) time.Time {
+	return lastFailureAt.Add(p.Window + p.MaxDelay)
+}
+
+// NeedsAlert reports whether the owner should be emailed about this record.
+func (f AuthFailure) NeedsAlert() bool {
+	return f.OwnerID != "" && f.Failures >= AuthFailureAlertThreshold && f.AlertedAt == nil
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestLockoutPolicy_LockoutDelay(t *testing.T) {
+	policy := LockoutPolicy{FreeAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute, Window: 15 * time.Minute}
+
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 5, expected: 0},
+		{failures: 6, expected: 30 * time.Second},
+		{failures: 7, expected: time.Minute},
+		{failures: 8, expected: 2 * time.Minute},
+		{failures: 9, expected: 4 * time.Minute},
+		{failures: 10, expected: 5 * time.Minute},
+		{failures: 1000, expected: 5 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, policy.LockoutDelay(tc.failures), "failures=%d", tc.failures)
+	}
+}
+
+func TestAuthFailure_RetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	until := now.Add(90 * time.Second)
+
+	retryAfter, locked := AuthFailure{LockedUntil: &until}.RetryAfter(now)
+	assert.Assert(t, locked)
+	assert.Equal(t, 90*time.Second, retryAfter)
+
+	_, locked = AuthFailure{LockedUntil: &until}.RetryAfter(until)
+	assert.Assert(t, !locked, "lockout should end at LockedUntil")
+
+	_, locked = AuthFailure{Failures: 3}.RetryAfter(now)
+	assert.Assert(t, !locked)
+}
+
+func TestAuthFailure_NeedsAlert(t *testing.T) {
+	alertedAt := time.Now()
+
+	assert.Assert(t, AuthFailure{OwnerID: "o", Failures: AuthFailureAlertThreshold}.NeedsAlert())
+	assert.Assert(t, !AuthFailure{OwnerID: "o", Failures: AuthFailureAlertThreshold - 1}.NeedsAlert())
+	assert.Assert(t, !AuthFailure{OwnerID: "o", Failures: AuthFailureAlertThreshold, AlertedAt: &alertedAt}.NeedsAlert())
+	assert.Assert(t, !AuthFailure{Failures: AuthFailureAlertThreshold}.NeedsAlert(), "IP subjects have no owner to alert")
+}
+
+func TestOwnerFailurePolicy_NeverLocks(t *testing.T) {
+	assert.Equal(t, time.Duration(0), OwnerFailurePolicy.LockoutDelay(AuthFailureAlertThreshold*10))
+}
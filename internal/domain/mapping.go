@@ -6,17 +6,59 @@ import (
 
 // IPMapping represents a mapping between an owner's location and their IP address.
 type IPMapping struct {
-	OwnerID           string    `dynamodbav:"OwnerId"`
-	LocationName      string    `dynamodbav:"LocationName"`
-	IP                string    `dynamodbav:"IP"`
-	Subdomain         string    `dynamodbav:"Subdomain"`
-	UpdatedAt         time.Time `dynamodbav:"UpdatedAt"`
-	LastIPChangeAt    time.Time `dynamodbav:"LastIPChangeAt"`
-	HourlyChangeCount int       `dynamodbav:"HourlyChangeCount"`
+	OwnerID        string    `dynamodbav:"OwnerId"`
+	LocationName   string    `dynamodbav:"LocationName"`
+	IP             string    `dynamodbav:"IP"`
+	Subdomain      string    `dynamodbav:"Subdomain"`
+	UpdatedAt      time.Time `dynamodbav:"UpdatedAt"`
+	LastIPChangeAt time.Time `dynamodbav:"LastIPChangeAt"`
+
+	// HourlyChangeCount counts the IP changes in the fixed rate limit window
+	// containing LastIPChangeAt; the name predates configurable windows.
+	HourlyChangeCount int `dynamodbav:"HourlyChangeCount"`
+
+	// RecentIPChanges holds the times of the latest IP changes, oldest first,
+	// for sliding window rate limits.
+	RecentIPChanges []time.Time `dynamodbav:"RecentIpChanges,omitempty"`
+
+	// Wildcard also publishes *.{subdomain} with the same address.
+	Wildcard bool `dynamodbav:"Wildcard,omitempty"`
+
+	// Damping is the location's flap damping policy, if any.
+	Damping *DampingPolicy `dynamodbav:"Damping,omitempty"`
+
+	// IPHistory holds the IPs the location was published at before IP, oldest
+	// first, for flap detection.
+	IPHistory []PublishedIP `dynamodbav:"IpHistory,omitempty"`
+
+	// PendingIP is the IP a location in settle mode has been reporting since
+	// PendingIPSince without it being published.
+	PendingIP      string    `dynamodbav:"PendingIp,omitempty"`
+	PendingIPSince time.Time `dynamodbav:"PendingIpSince,omitempty"`
+
+	// LastDampenedAt is when an IP change was last held back by damping.
+	LastDampenedAt time.Time `dynamodbav:"LastDampenedAt,omitempty"`
+
+	// PendingIPChanges are the IP changes the owner has opted in to hear about
+	// but hasn't been emailed about yet; IPChangeNotifiedAt is when the last
+	// IP change email was sent.
+	PendingIPChanges   []IPChange `dynamodbav:"PendingIpChanges,omitempty"`
+	IPChangeNotifiedAt *time.Time `dynamodbav:"IpChangeNotifiedAt,omitempty"`
+
+	// LastSeenAt is when the location last called /update, whether or not its
+	// IP changed. It is written at most once per LastSeenResolution.
+	LastSeenAt *time.Time `dynamodbav:"LastSeenAt,omitempty"`
+
+	// StaleAfterSeconds, when set, alerts the owner once the location hasn't
+	// been seen for that long; StaleAlertedAt is when the current silence was
+	// reported.
+	StaleAfterSeconds int        `dynamodbav:"StaleAfterSeconds,omitempty"`
+	StaleAlertedAt    *time.Time `dynamodbav:"StaleAlertedAt,omitempty"`
 }
 
 // UpdateRequest is the request body for updating a DNS mapping.
 // IP can optionally be provided by the client; if not, it's detected from the request.
+// OwnerID is optional when the API key embeds a key ID the owner can be resolved from.
 type UpdateRequest struct {
 	OwnerID  string `json:"ownerId"`
 	Location string `json:"location"`
@@ -25,9 +67,6 @@ type UpdateRequest struct {
 
 // Validate checks if the update request is valid.
 func (r UpdateRequest) Validate() error {
-	if r.OwnerID == "" {
-		return ErrMissingOwnerID
-	}
 	if r.Location == "" {
 		return ErrMissingLocation
 	}
@@ -38,3 +77,40 @@ func (r UpdateRequest) Validate() error {
 // Kept for backward compatibility.
 type RegisterRequest = UpdateRequest
 
+// LocationSettingsRequest is the request body for changing a location's settings.
+// Omitted fields are left unchanged.
+type LocationSettingsRequest struct {
+	Wildcard *bool          `json:"wildcard,omitempty"`
+	Damping  *DampingPolicy `json:"damping,omitempty"`
+
+	// StaleAfterSeconds sets the location's stale threshold; 0 stops monitoring it.
+	StaleAfterSeconds *int `json:"staleAfterSeconds,omitempty"`
+}
+
+// Normalize normalizes the damping policy.
+func (r LocationSettingsRequest) Normalize() LocationSettingsRequest {
+	if r.Damping != nil {
+		damping := r.Damping.Normalize()
+		r.Damping = &damping
+	}
+	return r
+}
+
+// Validate checks that at least one setting is provided, and that the damping
+// policy and stale threshold are valid.
+func (r LocationSettingsRequest) Validate() error {
+	if r.Wildcard == nil && r.Damping == nil && r.StaleAfterSeconds == nil {
+		return ErrMissingLocationSettings
+	}
+	if r.Damping != nil {
+		if err := r.Damping.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.StaleAfterSeconds != nil && *r.StaleAfterSeconds != 0 {
+		if *r.StaleAfterSeconds < MinStaleAfterSeconds || *r.StaleAfterSeconds > MaxStaleAfterSeconds {
+			return ErrInvalidStaleAfter
+		}
+	}
+	return nil
+}
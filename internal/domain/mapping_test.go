@@ -30,12 +30,12 @@ func TestUpdateRequest_Validate(t *testing.T) {
 			expectedErr: nil,
 		},
 		{
-			name: "missing ownerId",
+			name: "missing ownerId is resolved from the key",
 			request: UpdateRequest{
 				OwnerID:  "",
 				Location: "home",
 			},
-			expectedErr: ErrMissingOwnerID,
+			expectedErr: nil,
 		},
 		{
 			name: "missing location",
@@ -51,7 +51,7 @@ func TestUpdateRequest_Validate(t *testing.T) {
 				OwnerID:  "",
 				Location: "",
 			},
-			expectedErr: ErrMissingOwnerID, // OwnerID is checked first
+			expectedErr: ErrMissingLocation,
 		},
 	}
 
@@ -66,3 +66,24 @@ func TestUpdateRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestLocationSettingsRequest_Validate(t *testing.T) {
+	enabled := true
+
+	assert.NilError(t, LocationSettingsRequest{Wildcard: &enabled}.Validate())
+
+	err := LocationSettingsRequest{}.Validate()
+	assert.Assert(t, errors.Is(err, ErrMissingLocationSettings))
+
+	req := LocationSettingsRequest{Damping: &DampingPolicy{Mode: " Settle "}}.Normalize()
+	assert.NilError(t, req.Validate())
+	assert.Equal(t, DampingSettle, req.Damping.Mode)
+
+	req = LocationSettingsRequest{Damping: &DampingPolicy{Mode: "sometimes"}}.Normalize()
+	assert.Equal(t, ErrInvalidDampingMode, req.Validate())
+
+	off, hourly, tooShort := 0, 3600, 60
+	assert.NilError(t, LocationSettingsRequest{StaleAfterSeconds: &off}.Validate())
+	assert.NilError(t, LocationSettingsRequest{StaleAfterSeconds: &hourly}.Validate())
+	assert.Equal(t, ErrInvalidStaleAfter, LocationSettingsRequest{StaleAfterSeconds: &tooShort}.Validate())
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// IPChangeDigestInterval is the least time between IP change emails for a
+// location. Changes in between are queued and sent together as a digest.
+const IPChangeDigestInterval = time.Hour
+
+// MaxPendingIPChanges bounds how many queued changes a digest lists; older
+// ones are dropped.
+const MaxPendingIPChanges = 20
+
+// MaxNotificationLocations bounds the locations a notification can be limited to.
+const MaxNotificationLocations = 50
+
+// Security notices tell an owner about sensitive actions on their account.
+// Unlike other notifications they are sent unless suppressed.
+const (
+	// SecurityNoticeKeyRotated is sent when the primary API key is rotated.
+	SecurityNoticeKeyRotated = "keyRotated"
+	// SecurityNoticeRecoveryRequested is sent when API key recovery is requested.
+	SecurityNoticeRecoveryRequested = "recoveryRequested"
+	// SecurityNoticeACMEChallenge is sent when an ACME challenge is created.
+	SecurityNoticeACMEChallenge = "acmeChallenge"
+)
+
+// SecurityNotices lists every security notice.
+var SecurityNotices = []string{
+	SecurityNoticeKeyRotated,
+	SecurityNoticeRecoveryRequested,
+	SecurityNoticeACMEChallenge,
+}
+
+// NotificationPreferences are the emails an owner has opted in to.
+type NotificationPreferences struct {
+	// IPChange, when set, emails the owner when a location's IP changes.
+	IPChange *IPChangeNotifications `dynamodbav:"IpChange,omitempty" json:"ipChange,omitempty"`
+	// Security, when set, suppresses security notices.
+	Security *SecurityNotifications `dynamodbav:"Security,omitempty" json:"security,omitempty"`
+}
+
+// IPChangeNotifications selects the locations whose IP changes are emailed.
+type IPChangeNotifications struct {
+	// Locations limits the notifications to these locations; empty means every
+	// location.
+	Locations []string `dynamodbav:"Locations,omitempty" json:"locations,omitempty"`
+}
+
+// SecurityNotifications selects the security notices the owner doesn't want.
+type SecurityNotifications struct {
+	// Suppress lists security notices, such as SecurityNoticeKeyRotated, that
+	// aren't sent.
+	Suppress []string `dynamodbav:"Suppress,omitempty" json:"suppress,omitempty"`
+}
+
+// NotifiesIPChange reports whether the owner wants to know when location's IP changes.
+func (p *NotificationPreferences) NotifiesIPChange(location string) bool {
+	if p == nil || p.IPChange == nil {
+		return false
+	}
+	return len(p.IPChange.Locations) == 0 || slices.Contains(p.IPChange.Locations, location)
+}
+
+// NotifiesSecurity reports whether the owner wants the security notice, one of
+// SecurityNotices.
+func (p *NotificationPreferences) NotifiesSecurity(notice string) bool {
+	if p == nil || p.Security == nil {
+		return true
+	}
+	return !slices.Contains(p.Security.Suppress, notice)
+}
+
+// IsEmpty reports whether the preferences are the defaults: no IP change
+// notifications and every security notice.
+func (p NotificationPreferences) IsEmpty() bool {
+	return p.IPChange == nil && p.Security == nil
+}
+
+// Normalize trims location names and security notices and drops duplicates.
+// Security is cleared when nothing is suppressed.
+func (p NotificationPreferences) Normalize() NotificationPreferences {
+	if p.IPChange != nil {
+		ipChange := IPChangeNotifications{
+			Locations: normalizeList(p.IPChange.Locations, func(s string) string { return s }),
+		}
+		p.IPChange = &ipChange
+	}
+	if p.Security != nil {
+		security := SecurityNotifications{
+			Suppress: normalizeList(p.Security.Suppress, func(s string) string { return s }),
+		}
+		p.Security = &security
+		if len(security.Suppress) == 0 {
+			p.Security = nil
+		}
+	}
+	return p
+}
+
+// Validate checks the location lists and security notices.
+func (p NotificationPreferences) Validate() error {
+	if p.IPChange != nil && len(p.IPChange.Locations) > MaxNotificationLocations {
+		return ErrTooManyNotificationLocations
+	}
+	if p.Security != nil {
+		for _, notice := range p.Security.Suppress {
+			if !slices.Contains(SecurityNotices, notice) {
+				return ErrInvalidSecurityNotice
+			}
+		}
+	}
+	return nil
+}
+
+// IPChange is a published IP change that the owner hasn't been emailed about yet.
+type IPChange struct {
+	PreviousIP string    `dynamodbav:"PreviousIp"`
+	IP         string    `dynamodbav:"Ip"`
+	ChangedAt  time.Time `dynamodbav:"ChangedAt"`
+}
+
+// QueueIPChange adds a change to the mapping's pending IP change notifications,
+// keeping the latest MaxPendingIPChanges.
+func (m *IPMapping) QueueIPChange(change IPChange) {
+	m.PendingIPChanges = append(m.PendingIPChanges, change)
+	if len(m.PendingIPChanges) > MaxPendingIPChanges {
+		m.PendingIPChanges = m.PendingIPChanges[len(m.PendingIPChanges)-MaxPendingIPChanges:]
+	}
+}
+
+// IPChangeNotificationDue reports whether the mapping has pending IP changes
+// and its last IP change email is at least IPChangeDigestInterval old.
+func (m IPMapping) IPChangeNotificationDue(now time.Time) bool {
+	if len(m.PendingIPChanges) == 0 {
+		return false
+	}
+	return m.IPChangeNotifiedAt == nil || now.Sub(*m.IPChangeNotifiedAt) >= IPChangeDigestInterval
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestNotificationPreferences_NotifiesIPChange(t *testing.T) {
+	testCases := []struct {
+		name        string
+		preferences *NotificationPreferences
+		expected    bool
+	}{
+		{name: "no preferences", preferences: nil, expected: false},
+		{name: "not opted in", preferences: &NotificationPreferences{}, expected: false},
+		{name: "every location", preferences: &NotificationPreferences{IPChange: &IPChangeNotifications{}}, expected: true},
+		{name: "listed location", preferences: &NotificationPreferences{IPChange: &IPChangeNotifications{Locations: []string{"office", "home"}}}, expected: true},
+		{name: "other location", preferences: &NotificationPreferences{IPChange: &IPChangeNotifications{Locations: []string{"office"}}}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.preferences.NotifiesIPChange("home"))
+		})
+	}
+}
+
+func TestNotificationPreferences_NotifiesSecurity(t *testing.T) {
+	testCases := []struct {
+		name        string
+		preferences *NotificationPreferences
+		expected    bool
+	}{
+		{name: "no preferences", preferences: nil, expected: true},
+		{name: "nothing suppressed", preferences: &NotificationPreferences{IPChange: &IPChangeNotifications{}}, expected: true},
+		{name: "other notice suppressed", preferences: &NotificationPreferences{Security: &SecurityNotifications{Suppress: []string{SecurityNoticeACMEChallenge}}}, expected: true},
+		{name: "suppressed", preferences: &NotificationPreferences{Security: &SecurityNotifications{Suppress: []string{SecurityNoticeKeyRotated}}}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.preferences.NotifiesSecurity(SecurityNoticeKeyRotated))
+		})
+	}
+}
+
+func TestNotificationPreferences_Normalize(t *testing.T) {
+	prefs := NotificationPreferences{
+		IPChange: &IPChangeNotifications{Locations: []string{" home ", "", "home", "office"}},
+		Security: &SecurityNotifications{Suppress: []string{"acmeChallenge ", "acmeChallenge"}},
+	}.Normalize()
+
+	assert.DeepEqual(t, []string{"home", "office"}, prefs.IPChange.Locations)
+	assert.DeepEqual(t, []string{SecurityNoticeACMEChallenge}, prefs.Security.Suppress)
+	assert.Assert(t, NotificationPreferences{}.Normalize().IsEmpty())
+	assert.Assert(t, NotificationPreferences{Security: &SecurityNotifications{}}.Normalize().IsEmpty(), "nothing suppressed is the default")
+}
+
+func TestNotificationPreferences_Validate(t *testing.T) {
+	locations := make([]string, MaxNotificationLocations+1)
+	for i := range locations {
+		locations[i] = fmt.Sprintf("site-%d", i)
+	}
+
+	assert.NilError(t, NotificationPreferences{}.Validate())
+	assert.NilError(t, NotificationPreferences{IPChange: &IPChangeNotifications{Locations: locations[:MaxNotificationLocations]}}.Validate())
+	assert.Equal(t, ErrTooManyNotificationLocations, NotificationPreferences{IPChange: &IPChangeNotifications{Locations: locations}}.Validate())
+	assert.NilError(t, NotificationPreferences{Security: &SecurityNotifications{Suppress: SecurityNotices}}.Validate())
+	assert.Equal(t, ErrInvalidSecurityNotice, NotificationPreferences{Security: &SecurityNotifications{Suppress: []string{"ipChange"}}}.Validate())
+}
+
+func TestIPMapping_QueueIPChange(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var mapping IPMapping
+
+	for i := 0; i < MaxPendingIPChanges+2; i++ {
+		mapping.QueueIPChange(IPChange{IP: fmt.Sprintf("198.51.100.%d", i), ChangedAt: now})
+	}
+
+	assert.Equal(t, MaxPendingIPChanges, len(mapping.PendingIPChanges))
+	assert.Equal(t, "198.51.100.2", mapping.PendingIPChanges[0].IP, "the oldest changes are dropped")
+}
+
+func TestIPMapping_IPChangeNotificationDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-10 * time.Minute)
+	old := now.Add(-IPChangeDigestInterval)
+	pending := []IPChange{{PreviousIP: "198.51.100.1", IP: "198.51.100.2", ChangedAt: now}}
+
+	testCases := []struct {
+		name     string
+		mapping  IPMapping
+		expected bool
+	}{
+		{name: "nothing pending", mapping: IPMapping{}, expected: false},
+		{name: "never notified", mapping: IPMapping{PendingIPChanges: pending}, expected: true},
+		{name: "notified recently", mapping: IPMapping{PendingIPChanges: pending, IPChangeNotifiedAt: &recent}, expected: false},
+		{name: "digest interval passed", mapping: IPMapping{PendingIPChanges: pending, IPChangeNotifiedAt: &old}, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.mapping.IPChangeNotificationDue(now))
+		})
+	}
+}
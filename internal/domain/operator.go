@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxSuspendReasonLength bounds the reason stored with a suspension.
+const MaxSuspendReasonLength = 256
+
+// Operator is a person allowed to use the /admin endpoints.
+// Only the SHA-256 hash of the operator key is stored.
+type Operator struct {
+	KeyID     string    `dynamodbav:"KeyId"`
+	Name      string    `dynamodbav:"Name"`
+	KeyHash   string    `dynamodbav:"KeyHash"`
+	CreatedAt time.Time `dynamodbav:"CreatedAt"`
+}
+
+// Audited operator actions.
+const (
+	AuditListOwners      = "list-owners"
+	AuditGetOwner        = "get-owner"
+	AuditSuspendOwner    = "suspend-owner"
+	AuditUnsuspendOwner  = "unsuspend-owner"
+	AuditResetRateLimit  = "reset-rate-limit"
+	AuditChangeSubdomain = "change-subdomain"
+	AuditListAuditLog    = "list-audit-log"
+
+	AuditSetOwnerRateLimit   = "set-owner-rate-limit"
+	AuditListRateLimitPlans  = "list-rate-limit-plans"
+	AuditPutRateLimitPlan    = "put-rate-limit-plan"
+	AuditDeleteRateLimitPlan = "delete-rate-limit-plan"
+)
+
+// Audit outcomes.
+const (
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+)
+
+// AuditEntry records one operator action. Entries are partitioned by UTC day
+// and sorted by EntryID, which starts with the time of the action.
+type AuditEntry struct {
+	AuditDate    string            `dynamodbav:"AuditDate"`
+	EntryID      string            `dynamodbav:"EntryId"`
+	OperatorID   string            `dynamodbav:"OperatorId"`
+	OperatorName string            `dynamodbav:"OperatorName"`
+	Action       string            `dynamodbav:"Action"`
+	OwnerID      string            `dynamodbav:"OwnerId,omitempty"`
+	Location     string            `dynamodbav:"Location,omitempty"`
+	Details      map[string]string `dynamodbav:"Details,omitempty"`
+	Outcome      string            `dynamodbav:"Outcome"`
+	Error        string            `dynamodbav:"Error,omitempty"`
+	SourceIP     string            `dynamodbav:"SourceIp,omitempty"`
+	CreatedAt    time.Time         `dynamodbav:"CreatedAt"`
+}
+
+// AuditDate returns the audit log partition for t.
+func AuditDate(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// SuspendOwnerRequest is the request body for suspending an owner.
+type SuspendOwnerRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Validate checks that a reason is given.
+func (r SuspendOwnerRequest) Validate() error {
+	reason := strings.TrimSpace(r.Reason)
+	if reason == "" {
+		return ErrMissingSuspendReason
+	}
+	if len(reason) > MaxSuspendReasonLength {
+		return ErrInvalidSuspendReason
+	}
+	return nil
+}
+
+// ChangeSubdomainRequest is the request body for forcing a location's subdomain.
+type ChangeSubdomainRequest struct {
+	Subdomain string `json:"subdomain"`
+}
+
+// Normalize trims and lowercases the subdomain.
+func (r ChangeSubdomainRequest) Normalize() ChangeSubdomainRequest {
+	r.Subdomain = strings.ToLower(strings.TrimSpace(r.Subdomain))
+	return r
+}
+
+// Validate checks that the subdomain is a single hostname label.
+func (r ChangeSubdomainRequest) Validate() error {
+	if r.Subdomain == "" {
+		return ErrMissingSubdomain
+	}
+	if !isValidRecordLabel(r.Subdomain) || strings.Contains(r.Subdomain, "_") {
+		return ErrInvalidSubdomain
+	}
+	return nil
+}
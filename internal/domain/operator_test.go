@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestSuspendOwnerRequest_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		reason      string
+		expectedErr error
+	}{
+		{name: "valid", reason: "abuse report #42"},
+		{name: "missing", reason: "", expectedErr: ErrMissingSuspendReason},
+		{name: "whitespace only", reason: "   ", expectedErr: ErrMissingSuspendReason},
+		{name: "too long", reason: strings.Repeat("x", MaxSuspendReasonLength+1), expectedErr: ErrInvalidSuspendReason},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := SuspendOwnerRequest{Reason: tc.reason}.Validate()
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+}
+
+func TestChangeSubdomainRequest_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		subdomain   string
+		expectedErr error
+	}{
+		{name: "valid", subdomain: "home"},
+		{name: "normalized", subdomain: "  Home-Lab "},
+		{name: "digits", subdomain: "a3f8c2d1"},
+		{name: "missing", subdomain: "", expectedErr: ErrMissingSubdomain},
+		{name: "multiple labels", subdomain: "home.lab", expectedErr: ErrInvalidSubdomain},
+		{name: "underscore", subdomain: "home_lab", expectedErr: ErrInvalidSubdomain},
+		{name: "leading hyphen", subdomain: "-home", expectedErr: ErrInvalidSubdomain},
+		{name: "too long", subdomain: strings.Repeat("a", 64), expectedErr: ErrInvalidSubdomain},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ChangeSubdomainRequest{Subdomain: tc.subdomain}.Normalize().Validate()
+			assert.Equal(t, tc.expectedErr, err)
+		})
+	}
+}
+
+func TestOwner_IsSuspended(t *testing.T) {
+	now := time.Now()
+	assert.Assert(t, !Owner{}.IsSuspended())
+	assert.Assert(t, Owner{SuspendedAt: &now}.IsSuspended())
+}
+
+func TestAuditDate(t *testing.T) {
+	at := time.Date(2025, 1, 15, 23, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+	assert.Equal(t, "2025-01-16", AuditDate(at))
+}
@@ -11,12 +11,109 @@ type Owner struct {
 	Email      string    `dynamodbav:"Email"`
 	APIKeyHash string    `dynamodbav:"ApiKeyHash"`
 	CreatedAt  time.Time `dynamodbav:"CreatedAt"`
+
+	// APIKeyID is the key ID embedded in the primary key. It is empty for keys
+	// issued before key IDs were introduced.
+	APIKeyID string `dynamodbav:"ApiKeyId,omitempty"`
+
+	// APIKeySigningSecret is the primary key's sealed signing secret; see
+	// APIKey.SigningSecret.
+	APIKeySigningSecret string `dynamodbav:"ApiKeySigningSecret,omitempty"`
+
+	// RecordSetQuota overrides DefaultRecordSetQuota when non-zero.
+	RecordSetQuota int `dynamodbav:"RecordSetQuota,omitempty"`
+
+	// RecordSetCount counts the owner's custom record sets. It changes in the
+	// same transaction as the record sets so the quota holds under concurrent
+	// creates. Owners from before it was kept get it on their next create.
+	RecordSetCount int `dynamodbav:"RecordSetCount,omitempty"`
+
+	// APIKeyCount counts the owner's additional API keys the same way
+	// RecordSetCount counts record sets, so MaxAPIKeys holds under concurrent creates.
+	APIKeyCount int `dynamodbav:"ApiKeyCount,omitempty"`
+
+	// Expiry and usage of the primary key; see APIKey for the meaning of each field.
+	APIKeyExpiresAt      *time.Time `dynamodbav:"ApiKeyExpiresAt,omitempty"`
+	APIKeyLastUsedAt     *time.Time `dynamodbav:"ApiKeyLastUsedAt,omitempty"`
+	APIKeyLastUsedIP     string     `dynamodbav:"ApiKeyLastUsedIp,omitempty"`
+	APIKeyExpiryWarnedAt *time.Time `dynamodbav:"ApiKeyExpiryWarnedAt,omitempty"`
+
+	// The primary key replaced by the last rotation, kept until
+	// PreviousAPIKeyExpiresAt so clients can move to the new key.
+	PreviousAPIKeyHash          string     `dynamodbav:"PreviousApiKeyHash,omitempty"`
+	PreviousAPIKeyID            string     `dynamodbav:"PreviousApiKeyId,omitempty"`
+	PreviousAPIKeySigningSecret string     `dynamodbav:"PreviousApiKeySigningSecret,omitempty"`
+	PreviousAPIKeyExpiresAt     *time.Time `dynamodbav:"PreviousApiKeyExpiresAt,omitempty"`
+	PreviousAPIKeyLastUsedAt    *time.Time `dynamodbav:"PreviousApiKeyLastUsedAt,omitempty"`
+	PreviousAPIKeyLastUsedIP    string     `dynamodbav:"PreviousApiKeyLastUsedIp,omitempty"`
+
+	// RateLimitPlan names the owner's rate limit plan; empty means
+	// DefaultRateLimitPlan. RateLimit, when set, overrides the plan.
+	RateLimitPlan string           `dynamodbav:"RateLimitPlan,omitempty"`
+	RateLimit     *RateLimitPolicy `dynamodbav:"RateLimit,omitempty"`
+
+	// ACMEChallengeCount counts the ACME challenges created in the fixed
+	// ACMEChallengeLimit window starting at ACMEChallengeWindow, in Unix seconds.
+	ACMEChallengeCount  int        `dynamodbav:"AcmeChallengeCount,omitempty"`
+	ACMEChallengeWindow int64      `dynamodbav:"AcmeChallengeWindow,omitempty"`
+	LastACMEChallengeAt *time.Time `dynamodbav:"LastAcmeChallengeAt,omitempty"`
+
+	// Notifications are the emails the owner has opted in to.
+	Notifications *NotificationPreferences `dynamodbav:"Notifications,omitempty"`
+
+	// Locale selects the language of the owner's emails; empty means the
+	// default. It is stored normalized; see NormalizeLocale.
+	Locale string `dynamodbav:"Locale,omitempty"`
+
+	// SuspendedAt is set while an operator has suspended the owner.
+	SuspendedAt     *time.Time `dynamodbav:"SuspendedAt,omitempty"`
+	SuspendedReason string     `dynamodbav:"SuspendedReason,omitempty"`
+
+	// VerifyBy is set while the owner's email address is unverified. The owner
+	// can't authenticate until it is verified, and expires if that doesn't happen
+	// by VerifyBy. TTL mirrors VerifyBy as Unix time so DynamoDB deletes expired
+	// owners; both are removed on verification. Owners created before email
+	// verification was introduced have neither and are active.
+	VerifyBy   *time.Time `dynamodbav:"VerifyBy,omitempty"`
+	TTL        int64      `dynamodbav:"TTL,omitempty"`
+	VerifiedAt *time.Time `dynamodbav:"VerifiedAt,omitempty"`
+}
+
+// DefaultVerificationWindow is how long a new owner has to verify their email address.
+const DefaultVerificationWindow = 24 * time.Hour
+
+// RecoveryWindow is how long an emailed key recovery token stays valid.
+const RecoveryWindow = 30 * time.Minute
+
+// IsSuspended reports whether an operator has suspended the owner.
+func (o Owner) IsSuspended() bool {
+	return o.SuspendedAt != nil
+}
+
+// IsPendingVerification reports whether the owner's email address is unverified.
+func (o Owner) IsPendingVerification() bool {
+	return o.VerifyBy != nil
+}
+
+// IsVerificationExpired reports whether a pending owner missed its verification deadline.
+func (o Owner) IsVerificationExpired(now time.Time) bool {
+	return o.VerifyBy != nil && !now.Before(*o.VerifyBy)
+}
+
+// RecordSetLimit returns the maximum number of custom record sets the owner may publish.
+func (o Owner) RecordSetLimit() int {
+	if o.RecordSetQuota > 0 {
+		return o.RecordSetQuota
+	}
+	return DefaultRecordSetQuota
 }
 
 // CreateOwnerRequest represents a request to create a new owner.
 type CreateOwnerRequest struct {
 	OwnerID string `json:"ownerId"`
 	Email   string `json:"email"`
+	// Locale optionally selects the language of the owner's emails.
+	Locale string `json:"locale,omitempty"`
 }
 
 // Validate checks that the request has all required fields.
@@ -30,6 +127,19 @@ func (r CreateOwnerRequest) Validate() error {
 	if !isValidEmail(r.Email) {
 		return ErrInvalidEmail
 	}
+	return ValidateLocale(NormalizeLocale(r.Locale))
+}
+
+// VerifyOwnerRequest represents a request to verify an owner's email address.
+type VerifyOwnerRequest struct {
+	Token string `json:"token"`
+}
+
+// Validate checks that the request has all required fields.
+func (r VerifyOwnerRequest) Validate() error {
+	if strings.TrimSpace(r.Token) == "" {
+		return ErrMissingToken
+	}
 	return nil
 }
 
@@ -46,6 +156,20 @@ func (r RecoverKeyRequest) Validate() error {
 	return nil
 }
 
+// ConfirmRecoveryRequest represents a request to complete key recovery with the
+// emailed token.
+type ConfirmRecoveryRequest struct {
+	Token string `json:"token"`
+}
+
+// Validate checks that the request has all required fields.
+func (r ConfirmRecoveryRequest) Validate() error {
+	if strings.TrimSpace(r.Token) == "" {
+		return ErrMissingToken
+	}
+	return nil
+}
+
 // isValidEmail performs basic email validation.
 func isValidEmail(email string) bool {
 	// Basic check: contains @ and has something before and after
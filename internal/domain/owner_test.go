@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 )
@@ -29,6 +30,24 @@ func TestCreateOwnerRequest_Validate(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "valid with locale",
+			request: CreateOwnerRequest{
+				OwnerID: "test-owner",
+				Email:   "user@example.com",
+				Locale:  "pt_BR",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "invalid locale",
+			request: CreateOwnerRequest{
+				OwnerID: "test-owner",
+				Email:   "user@example.com",
+				Locale:  "../en",
+			},
+			expectedErr: ErrInvalidLocale,
+		},
 		{
 			name: "missing ownerId",
 			request: CreateOwnerRequest{
@@ -156,6 +175,30 @@ func TestRecoverKeyRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestOwner_Verification(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	active := Owner{OwnerID: "test-owner"}
+	assert.Assert(t, !active.IsPendingVerification())
+	assert.Assert(t, !active.IsVerificationExpired(now))
+
+	pending := Owner{OwnerID: "test-owner", VerifyBy: &later}
+	assert.Assert(t, pending.IsPendingVerification())
+	assert.Assert(t, !pending.IsVerificationExpired(now))
+	assert.Assert(t, pending.IsVerificationExpired(later))
+}
+
+func TestVerifyOwnerRequest_Validate(t *testing.T) {
+	assert.NilError(t, VerifyOwnerRequest{Token: "abc.def"}.Validate())
+	assert.Equal(t, ErrMissingToken, VerifyOwnerRequest{Token: "  "}.Validate())
+}
+
+func TestConfirmRecoveryRequest_Validate(t *testing.T) {
+	assert.NilError(t, ConfirmRecoveryRequest{Token: "abc.def"}.Validate())
+	assert.Equal(t, ErrMissingToken, ConfirmRecoveryRequest{}.Validate())
+}
+
 func TestIsValidEmail(t *testing.T) {
 	testCases := []struct {
 		email    string
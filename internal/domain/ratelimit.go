@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Rate limit algorithms.
+const (
+	// RateLimitFixedWindow counts IP changes in consecutive windows aligned to
+	// multiples of the window length. A burst that straddles a window boundary
+	// can make up to twice the limit.
+	RateLimitFixedWindow = "fixed"
+
+	// RateLimitSlidingWindow counts IP changes in the window ending now.
+	RateLimitSlidingWindow = "sliding"
+)
+
+// Bounds on rate limit policies.
+const (
+	MaxRateLimitChanges       = 100
+	MinRateLimitWindowSeconds = 60
+	MaxRateLimitWindowSeconds = 7 * 24 * 60 * 60
+)
+
+// DefaultRateLimitPlan is the plan owners without an assigned plan are on.
+// Storing a plan with this name changes the limit for all of them.
+const DefaultRateLimitPlan = "default"
+
+// OwnerRateLimitPlan is the plan name reported for a policy set directly on an
+// owner. It is reserved and can't be stored as a plan.
+const OwnerRateLimitPlan = "owner"
+
+// MaxRateLimitPlanNameLength bounds rate limit plan names.
+const MaxRateLimitPlanNameLength = 32
+
+var rateLimitPlanNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// RateLimitPolicy limits how often a location's IP address may change: at most
+// MaxChanges changes per WindowSeconds.
+type RateLimitPolicy struct {
+	MaxChanges    int    `dynamodbav:"MaxChanges" json:"maxChanges"`
+	WindowSeconds int    `dynamodbav:"WindowSeconds" json:"windowSeconds"`
+	Algorithm     string `dynamodbav:"Algorithm" json:"algorithm"`
+}
+
+// DefaultRateLimitPolicy applies to the default plan until an operator stores one.
+var DefaultRateLimitPolicy = RateLimitPolicy{
+	MaxChanges:    2,
+	WindowSeconds: 60 * 60,
+	Algorithm:     RateLimitFixedWindow,
+}
+
+// Window returns the policy's window length.
+func (p RateLimitPolicy) Window() time.Duration {
+	return time.Duration(p.WindowSeconds) * time.Second
+}
+
+// Normalize defaults an empty algorithm to the fixed window.
+func (p RateLimitPolicy) Normalize() RateLimitPolicy {
+	p.Algorithm = strings.ToLower(strings.TrimSpace(p.Algorithm))
+	if p.Algorithm == "" {
+		p.Algorithm = RateLimitFixedWindow
+	}
+	return p
+}
+
+// Validate checks the policy's bounds and algorithm.
+func (p RateLimitPolicy) Validate() error {
+	if p.MaxChanges < 1 || p.MaxChanges > MaxRateLimitChanges {
+		return ErrInvalidRateLimitChanges
+	}
+	if p.WindowSeconds < MinRateLimitWindowSeconds || p.WindowSeconds > MaxRateLimitWindowSeconds {
+		return ErrInvalidRateLimitWindow
+	}
+	if p.Algorithm != RateLimitFixedWindow && p.Algorithm != RateLimitSlidingWindow {
+		return ErrInvalidRateLimitAlgorithm
+	}
+	return nil
+}
+
+// String describes the limit, e.g. "maximum 2 IP changes per hour".
+func (p RateLimitPolicy) String() string {
+	return fmt.Sprintf("maximum %d IP changes per %s", p.MaxChanges, describeWindow(p.Window()))
+}
+
+func describeWindow(window time.Duration) string {
+	switch {
+	case window == time.Hour:
+		return "hour"
+	case window == time.Minute:
+		return "minute"
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%d hours", int(window/time.Hour))
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", int(window/time.Minute))
+	default:
+		return window.String()
+	}
+}
+
+// RateLimitPlan is a named rate limit policy that owners can be assigned to.
+type RateLimitPlan struct {
+	Name string `dynamodbav:"PlanName"`
+	RateLimitPolicy
+	UpdatedAt time.Time `dynamodbav:"UpdatedAt"`
+}
+
+// ValidateRateLimitPlanName checks that a plan name is a short lowercase label
+// other than the reserved OwnerRateLimitPlan.
+func ValidateRateLimitPlanName(name string) error {
+	if len(name) > MaxRateLimitPlanNameLength || !rateLimitPlanNamePattern.MatchString(name) {
+		return ErrInvalidRateLimitPlanName
+	}
+	if name == OwnerRateLimitPlan {
+		return ErrInvalidRateLimitPlanName
+	}
+	return nil
+}
+
+// SetOwnerRateLimitRequest is the request body for choosing an owner's rate
+// limit. An empty Plan puts the owner on the default plan; Policy, when set,
+// overrides the plan for this owner alone.
+type SetOwnerRateLimitRequest struct {
+	Plan   string           `json:"plan,omitempty"`
+	Policy *RateLimitPolicy `json:"policy,omitempty"`
+}
+
+// Normalize trims the plan name and normalizes the override.
+func (r SetOwnerRateLimitRequest) Normalize() SetOwnerRateLimitRequest {
+	r.Plan = strings.ToLower(strings.TrimSpace(r.Plan))
+	if r.Plan == DefaultRateLimitPlan {
+		r.Plan = ""
+	}
+	if r.Policy != nil {
+		policy := r.Policy.Normalize()
+		r.Policy = &policy
+	}
+	return r
+}
+
+// Validate checks the plan name and override.
+func (r SetOwnerRateLimitRequest) Validate() error {
+	if r.Plan != "" {
+		if err := ValidateRateLimitPlanName(r.Plan); err != nil {
+			return err
+		}
+	}
+	if r.Policy != nil {
+		return r.Policy.Validate()
+	}
+	return nil
+}
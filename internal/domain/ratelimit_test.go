@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRateLimitPolicy_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      RateLimitPolicy
+		expectedErr error
+	}{
+		{name: "default", policy: DefaultRateLimitPolicy},
+		{name: "sliding", policy: RateLimitPolicy{MaxChanges: 10, WindowSeconds: 3600, Algorithm: RateLimitSlidingWindow}},
+		{name: "no changes", policy: RateLimitPolicy{MaxChanges: 0, WindowSeconds: 3600, Algorithm: RateLimitFixedWindow}, expectedErr: ErrInvalidRateLimitChanges},
+		{name: "too many changes", policy: RateLimitPolicy{MaxChanges: MaxRateLimitChanges + 1, WindowSeconds: 3600, Algorithm: RateLimitFixedWindow}, expectedErr: ErrInvalidRateLimitChanges},
+		{name: "window too short", policy: RateLimitPolicy{MaxChanges: 2, WindowSeconds: 59, Algorithm: RateLimitFixedWindow}, expectedErr: ErrInvalidRateLimitWindow},
+		{name: "window too long", policy: RateLimitPolicy{MaxChanges: 2, WindowSeconds: MaxRateLimitWindowSeconds + 1, Algorithm: RateLimitFixedWindow}, expectedErr: ErrInvalidRateLimitWindow},
+		{name: "unknown algorithm", policy: RateLimitPolicy{MaxChanges: 2, WindowSeconds: 3600, Algorithm: "leaky"}, expectedErr: ErrInvalidRateLimitAlgorithm},
+		{name: "missing algorithm", policy: RateLimitPolicy{MaxChanges: 2, WindowSeconds: 3600}, expectedErr: ErrInvalidRateLimitAlgorithm},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, tc.policy.Validate())
+		})
+	}
+}
+
+func TestRateLimitPolicy_Normalize(t *testing.T) {
+	assert.Equal(t, RateLimitFixedWindow, RateLimitPolicy{}.Normalize().Algorithm)
+	assert.Equal(t, RateLimitSlidingWindow, RateLimitPolicy{Algorithm: " Sliding "}.Normalize().Algorithm)
+}
+
+func TestRateLimitPolicy_String(t *testing.T) {
+	testCases := []struct {
+		windowSeconds int
+		expected      string
+	}{
+		{windowSeconds: 3600, expected: "maximum 2 IP changes per hour"},
+		{windowSeconds: 60, expected: "maximum 2 IP changes per minute"},
+		{windowSeconds: 86400, expected: "maximum 2 IP changes per 24 hours"},
+		{windowSeconds: 900, expected: "maximum 2 IP changes per 15 minutes"},
+		{windowSeconds: 90, expected: "maximum 2 IP changes per 1m30s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			policy := RateLimitPolicy{MaxChanges: 2, WindowSeconds: tc.windowSeconds}
+			assert.Equal(t, tc.expected, policy.String())
+		})
+	}
+}
+
+func TestValidateRateLimitPlanName(t *testing.T) {
+	testCases := []struct {
+		name        string
+		plan        string
+		expectedErr error
+	}{
+		{name: "valid", plan: "isp-burst"},
+		{name: "default", plan: DefaultRateLimitPlan},
+		{name: "reserved", plan: OwnerRateLimitPlan, expectedErr: ErrInvalidRateLimitPlanName},
+		{name: "empty", plan: "", expectedErr: ErrInvalidRateLimitPlanName},
+		{name: "uppercase", plan: "Gold", expectedErr: ErrInvalidRateLimitPlanName},
+		{name: "leading hyphen", plan: "-gold", expectedErr: ErrInvalidRateLimitPlanName},
+		{name: "too long", plan: strings.Repeat("a", MaxRateLimitPlanNameLength+1), expectedErr: ErrInvalidRateLimitPlanName},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, ValidateRateLimitPlanName(tc.plan))
+		})
+	}
+}
+
+func TestSetOwnerRateLimitRequest_Normalize(t *testing.T) {
+	req := SetOwnerRateLimitRequest{
+		Plan:   " Default ",
+		Policy: &RateLimitPolicy{MaxChanges: 5, WindowSeconds: 3600},
+	}.Normalize()
+
+	assert.Equal(t, "", req.Plan, "the default plan is stored as no plan")
+	assert.Equal(t, RateLimitFixedWindow, req.Policy.Algorithm)
+	assert.NilError(t, req.Validate())
+}
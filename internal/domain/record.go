@@ -0,0 +1,241 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRecordSetQuota is the number of custom record sets an owner may
+	// publish across all locations unless the owner has an explicit quota.
+	DefaultRecordSetQuota = 20
+
+	// MaxRecordSetValues is the maximum number of values in a single record set.
+	MaxRecordSetValues = 10
+
+	// MinRecordSetTTL and MaxRecordSetTTL bound the TTL of custom record sets.
+	MinRecordSetTTL = 60
+	MaxRecordSetTTL = 86400
+
+	// maxRecordNameLength keeps the relative name well inside the 253 character
+	// FQDN limit once the location subdomain and root domain are appended.
+	maxRecordNameLength = 160
+
+	// maxTXTValueLength is the maximum length of a single TXT character string.
+	maxTXTValueLength = 255
+)
+
+// Record types that owners may publish under their location subdomain.
+const (
+	RecordTypeTXT = "TXT"
+	RecordTypeSRV = "SRV"
+	RecordTypeMX  = "MX"
+	RecordTypeCAA = "CAA"
+)
+
+// allowedRecordTypes is the whitelist of custom record types.
+var allowedRecordTypes = map[string]bool{
+	RecordTypeTXT: true,
+	RecordTypeSRV: true,
+	RecordTypeMX:  true,
+	RecordTypeCAA: true,
+}
+
+// reservedRecordLabel is managed by the ACME challenge endpoints.
+const reservedRecordLabel = "_acme-challenge"
+
+// RecordSet is a custom DNS record set published under a location's subdomain.
+// Name is relative to the location subdomain; an empty name is the subdomain itself.
+type RecordSet struct {
+	OwnerID      string    `dynamodbav:"OwnerId"`
+	RecordKey    string    `dynamodbav:"RecordKey"`
+	LocationName string    `dynamodbav:"LocationName"`
+	Name         string    `dynamodbav:"Name"`
+	Type         string    `dynamodbav:"Type"`
+	TTL          int64     `dynamodbav:"RecordTTL"`
+	Values       []string  `dynamodbav:"Values"`
+	CreatedAt    time.Time `dynamodbav:"CreatedAt"`
+	UpdatedAt    time.Time `dynamodbav:"UpdatedAt"`
+}
+
+// RecordSetKey builds the sort key for a record set within an owner's partition.
+func RecordSetKey(location, name, recordType string) string {
+	return fmt.Sprintf("%s#%s#%s", location, recordType, name)
+}
+
+// RecordSetRequest is the request body for creating or replacing a record set.
+type RecordSetRequest struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int64    `json:"ttl,omitempty"`
+	Values []string `json:"values"`
+}
+
+// Normalize lowercases the name and uppercases the type.
+func (r RecordSetRequest) Normalize() RecordSetRequest {
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	r.Type = strings.ToUpper(strings.TrimSpace(r.Type))
+	return r
+}
+
+// Validate checks if the request is valid. It expects a normalized request.
+func (r RecordSetRequest) Validate() error {
+	if err := validateRecordSetIdentity(r.Name, r.Type); err != nil {
+		return err
+	}
+	if r.TTL != 0 && (r.TTL < MinRecordSetTTL || r.TTL > MaxRecordSetTTL) {
+		return ErrInvalidRecordTTL
+	}
+	if len(r.Values) == 0 {
+		return ErrMissingRecordValues
+	}
+	if len(r.Values) > MaxRecordSetValues {
+		return fmt.Errorf("%w: at most %d values allowed", ErrInvalidRecordValue, MaxRecordSetValues)
+	}
+	if r.Type == RecordTypeSRV && !isServiceName(r.Name) {
+		return fmt.Errorf("%w: SRV records must be named _service._proto", ErrInvalidRecordName)
+	}
+	for _, value := range r.Values {
+		if err := validateRecordValue(r.Type, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRecordSetRequest is the request body for deleting a record set.
+type DeleteRecordSetRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Normalize lowercases the name and uppercases the type.
+func (r DeleteRecordSetRequest) Normalize() DeleteRecordSetRequest {
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	r.Type = strings.ToUpper(strings.TrimSpace(r.Type))
+	return r
+}
+
+// Validate checks if the request is valid. It expects a normalized request.
+func (r DeleteRecordSetRequest) Validate() error {
+	return validateRecordSetIdentity(r.Name, r.Type)
+}
+
+// validateRecordSetIdentity checks the name and type that identify a record set.
+// Names may only contain relative DNS labels so the record always stays inside
+// the owner's location subdomain.
+func validateRecordSetIdentity(name, recordType string) error {
+	if recordType == "" {
+		return ErrMissingRecordType
+	}
+	if !allowedRecordTypes[recordType] {
+		return ErrUnsupportedRecordType
+	}
+	if name == "" {
+		return nil
+	}
+	if len(name) > maxRecordNameLength {
+		return fmt.Errorf("%w: name is too long", ErrInvalidRecordName)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !isValidRecordLabel(label) {
+			return ErrInvalidRecordName
+		}
+		if label == reservedRecordLabel {
+			return fmt.Errorf("%w: %s is managed by the ACME challenge API", ErrInvalidRecordName, reservedRecordLabel)
+		}
+	}
+	return nil
+}
+
+// isValidRecordLabel reports whether label is a single DNS label made of
+// lowercase letters, digits, hyphens and underscores.
+func isValidRecordLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 {
+		return false
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isServiceName reports whether name starts with _service._proto labels.
+func isServiceName(name string) bool {
+	labels := strings.Split(name, ".")
+	return len(labels) >= 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_")
+}
+
+// validateRecordValue checks a single value for the given record type.
+func validateRecordValue(recordType, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return ErrMissingRecordValues
+	}
+	for _, c := range value {
+		if c < 0x20 || c == 0x7f {
+			return fmt.Errorf("%w: control characters are not allowed", ErrInvalidRecordValue)
+		}
+	}
+
+	fields := strings.Fields(value)
+	switch recordType {
+	case RecordTypeTXT:
+		if len(value) > maxTXTValueLength {
+			return fmt.Errorf("%w: TXT values are limited to %d characters", ErrInvalidRecordValue, maxTXTValueLength)
+		}
+	case RecordTypeMX:
+		// "preference exchange", e.g. "10 mail.example.com"
+		if len(fields) != 2 || !isUint16(fields[0]) || !isHostname(fields[1]) {
+			return fmt.Errorf("%w: MX values must be \"preference host\"", ErrInvalidRecordValue)
+		}
+	case RecordTypeSRV:
+		// "priority weight port target", e.g. "10 5 25565 mc.example.com"
+		if len(fields) != 4 || !isUint16(fields[0]) || !isUint16(fields[1]) || !isUint16(fields[2]) || !isHostname(fields[3]) {
+			return fmt.Errorf("%w: SRV values must be \"priority weight port target\"", ErrInvalidRecordValue)
+		}
+	case RecordTypeCAA:
+		// "flags tag value", e.g. `0 issue "letsencrypt.org"`
+		if len(fields) < 3 || !isUint8(fields[0]) {
+			return fmt.Errorf("%w: CAA values must be \"flags tag value\"", ErrInvalidRecordValue)
+		}
+		switch fields[1] {
+		case "issue", "issuewild", "iodef":
+		default:
+			return fmt.Errorf("%w: unsupported CAA tag %q", ErrInvalidRecordValue, fields[1])
+		}
+	}
+	return nil
+}
+
+// isHostname reports whether s looks like a hostname, optionally fully qualified.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(strings.ToLower(s), ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !isValidRecordLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUint16(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 16)
+	return err == nil
+}
+
+func isUint8(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 8)
+	return err == nil
+}
@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRecordSetRequest_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		request     RecordSetRequest
+		expectedErr error
+	}{
+		{
+			name: "valid apex TXT",
+			request: RecordSetRequest{
+				Type:   "TXT",
+				Values: []string{"google-site-verification=abc123"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "valid SRV",
+			request: RecordSetRequest{
+				Name:   "_minecraft._tcp",
+				Type:   "SRV",
+				TTL:    300,
+				Values: []string{"0 5 25565 mc.example.com"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "valid MX",
+			request: RecordSetRequest{
+				Type:   "MX",
+				Values: []string{"10 mail.example.com.", "20 backup.example.com"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "valid CAA",
+			request: RecordSetRequest{
+				Type:   "CAA",
+				Values: []string{`0 issue "letsencrypt.org"`},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "missing type",
+			request: RecordSetRequest{
+				Values: []string{"hello"},
+			},
+			expectedErr: ErrMissingRecordType,
+		},
+		{
+			name: "unsupported type",
+			request: RecordSetRequest{
+				Type:   "CNAME",
+				Values: []string{"example.com"},
+			},
+			expectedErr: ErrUnsupportedRecordType,
+		},
+		{
+			name: "name escapes subdomain with trailing dot",
+			request: RecordSetRequest{
+				Name:   "www.",
+				Type:   "TXT",
+				Values: []string{"hello"},
+			},
+			expectedErr: ErrInvalidRecordName,
+		},
+		{
+			name: "wildcard name",
+			request: RecordSetRequest{
+				Name:   "*",
+				Type:   "TXT",
+				Values: []string{"hello"},
+			},
+			expectedErr: ErrInvalidRecordName,
+		},
+		{
+			name: "reserved ACME label",
+			request: RecordSetRequest{
+				Name:   "_acme-challenge",
+				Type:   "TXT",
+				Values: []string{"hello"},
+			},
+			expectedErr: ErrInvalidRecordName,
+		},
+		{
+			name: "SRV without service labels",
+			request: RecordSetRequest{
+				Name:   "minecraft",
+				Type:   "SRV",
+				Values: []string{"0 5 25565 mc.example.com"},
+			},
+			expectedErr: ErrInvalidRecordName,
+		},
+		{
+			name: "TTL out of range",
+			request: RecordSetRequest{
+				Type:   "TXT",
+				TTL:    5,
+				Values: []string{"hello"},
+			},
+			expectedErr: ErrInvalidRecordTTL,
+		},
+		{
+			name: "missing values",
+			request: RecordSetRequest{
+				Type: "TXT",
+			},
+			expectedErr: ErrMissingRecordValues,
+		},
+		{
+			name: "malformed MX",
+			request: RecordSetRequest{
+				Type:   "MX",
+				Values: []string{"mail.example.com"},
+			},
+			expectedErr: ErrInvalidRecordValue,
+		},
+		{
+			name: "SRV port out of range",
+			request: RecordSetRequest{
+				Name:   "_sip._udp",
+				Type:   "SRV",
+				Values: []string{"0 5 70000 sip.example.com"},
+			},
+			expectedErr: ErrInvalidRecordValue,
+		},
+		{
+			name: "TXT with control character",
+			request: RecordSetRequest{
+				Type:   "TXT",
+				Values: []string{"hello\nworld"},
+			},
+			expectedErr: ErrInvalidRecordValue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.request.Normalize().Validate()
+			if tc.expectedErr == nil {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRecordSetRequest_Normalize(t *testing.T) {
+	req := RecordSetRequest{Name: " _Minecraft._TCP ", Type: "srv"}.Normalize()
+
+	assert.Equal(t, "_minecraft._tcp", req.Name)
+	assert.Equal(t, "SRV", req.Type)
+}
+
+func TestRecordSetKey(t *testing.T) {
+	assert.Equal(t, "home#TXT#", RecordSetKey("home", "", "TXT"))
+	assert.Equal(t, "home#SRV#_minecraft._tcp", RecordSetKey("home", "_minecraft._tcp", "SRV"))
+}
+
+func TestOwner_RecordSetLimit(t *testing.T) {
+	assert.Equal(t, DefaultRecordSetQuota, Owner{}.RecordSetLimit())
+	assert.Equal(t, 50, Owner{RecordSetQuota: 50}.RecordSetLimit())
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RequestLimit is a token bucket: a subject may make up to Burst requests at
+// once and regains one request every Interval. A zero limit is unlimited.
+type RequestLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// Unlimited reports whether the limit lets every request through.
+func (l RequestLimit) Unlimited() bool {
+	return l.Burst <= 0 || l.Interval <= 0
+}
+
+// RouteLimits are the request limits for one group of routes, applied to the
+// caller's source IP and, once the request is authenticated, to its API key and
+// owner.
+type RouteLimits struct {
+	IP    RequestLimit
+	Key   RequestLimit
+	Owner RequestLimit
+}
+
+// Route groups that request limits are configured for.
+const (
+	LimitRouteCreateOwner = "create-owner"
+	LimitRouteRecover     = "recover"
+	LimitRouteVerify      = "verify"
+	LimitRouteUpdate      = "update"
+	LimitRouteLookup      = "lookup"
+	LimitRouteAdmin       = "admin"
+	LimitRouteDefault     = "default"
+)
+
+// Request limit subject kinds.
+const (
+	LimitSubjectIP    = "ip"
+	LimitSubjectKey   = "key"
+	LimitSubjectOwner = "owner"
+)
+
+// DefaultRouteLimits are generous enough for well-behaved clients polling every
+// minute, and stop a client looping every second within a few seconds. Routes
+// that don't authenticate, such as recovery and verification, are only limited
+// per source IP: the owner they name is unproven, and limiting it would let
+// anyone lock the owner out.
+var DefaultRouteLimits = map[string]RouteLimits{
+	LimitRouteCreateOwner: {
+		IP: RequestLimit{Burst: 5, Interval: 10 * time.Minute},
+	},
+	LimitRouteRecover: {
+		IP: RequestLimit{Burst: 5, Interval: 10 * time.Minute},
+	},
+	LimitRouteVerify: {
+		IP: RequestLimit{Burst: 10, Interval: time.Minute},
+	},
+	LimitRouteUpdate: {
+		IP:  RequestLimit{Burst: 30, Interval: 5 * time.Second},
+		Key: RequestLimit{Burst: 10, Interval: 10 * time.Second},
+	},
+	LimitRouteLookup: {
+		IP:  RequestLimit{Burst: 60, Interval: time.Second},
+		Key: RequestLimit{Burst: 30, Interval: 2 * time.Second},
+	},
+	LimitRouteAdmin: {
+		IP: RequestLimit{Burst: 60, Interval: time.Second},
+	},
+	LimitRouteDefault: {
+		IP:  RequestLimit{Burst: 60, Interval: time.Second},
+		Key: RequestLimit{Burst: 30, Interval: 2 * time.Second},
+	},
+}
+
+// RequestBucket returns the key a subject's tokens for a route are stored under.
+func RequestBucket(route, kind, id string) string {
+	return route + "#" + kind + "#" + id
+}
+
+// TakeRequestToken applies the limit to a bucket whose tokens are all back at
+// readyAt, using the generic cell rate algorithm: each request pushes readyAt one
+// Interval later, and a request is allowed while readyAt is no more than Burst-1
+// intervals away. It returns the new readyAt, or how long until a token is
+// available when the bucket is empty.
+func (l RequestLimit) TakeRequestToken(readyAt, now time.Time) (time.Time, time.Duration, bool) {
+	if readyAt.Before(now) {
+		readyAt = now
+	}
+	latest := l.LatestReadyAt(now)
+	if readyAt.After(latest) {
+		return readyAt, readyAt.Sub(latest), false
+	}
+	return readyAt.Add(l.Interval), 0, true
+}
+
+// LatestReadyAt is the furthest readyAt can be from now for a request to be allowed.
+func (l RequestLimit) LatestReadyAt(now time.Time) time.Time {
+	return now.Add(time.Duration(l.Burst-1) * l.Interval)
+}
+
+// RequestBucketExpiry returns when a bucket is full again at the latest, after
+// which its record can be deleted.
+func (l RequestLimit) RequestBucketExpiry(now time.Time) time.Time {
+	return now.Add(time.Duration(l.Burst) * l.Interval)
+}
+
+// requestLimitConfig is how a request limit is written in configuration, e.g.
+// {"burst": 30, "interval": "5s"}.
+type requestLimitConfig struct {
+	Burst    int    `json:"burst"`
+	Interval string `json:"interval"`
+}
+
+// ParseRouteLimits reads request limit overrides from JSON keyed by route and
+// subject, e.g. {"update": {"ip": {"burst": 30, "interval": "5s"}}}, and applies
+// them to DefaultRouteLimits. A route that is configured replaces all of its
+// defaults, so subjects it leaves out are unlimited; a burst of 0 disables a limit.
+func ParseRouteLimits(data string) (map[string]RouteLimits, error) {
+	var config map[string]map[string]requestLimitConfig
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("invalid request limits: %w", err)
+	}
+
+	limits := make(map[string]RouteLimits, len(DefaultRouteLimits))
+	for route, routeLimits := range DefaultRouteLimits {
+		limits[route] = routeLimits
+	}
+
+	for route, subjects := range config {
+		if _, ok := DefaultRouteLimits[route]; !ok {
+			return nil, fmt.Errorf("invalid request limits: unknown route %q", route)
+		}
+
+		var routeLimits RouteLimits
+		for kind, c := range subjects {
+			limit, err := c.parse()
+			if err != nil {
+				return nil, fmt.Errorf("invalid request limits for %s %s: %w", route, kind, err)
+			}
+			switch kind {
+			case LimitSubjectIP:
+				routeLimits.IP = limit
+			case LimitSubjectKey:
+				routeLimits.Key = limit
+			case LimitSubjectOwner:
+				routeLimits.Owner = limit
+			default:
+				return nil, fmt.Errorf("invalid request limits for %s: unknown subject %q", route, kind)
+			}
+		}
+		limits[route] = routeLimits
+	}
+	return limits, nil
+}
+
+func (c requestLimitConfig) parse() (RequestLimit, error) {
+	if c.Burst < 0 {
+		return RequestLimit{}, fmt.Errorf("negative burst %d", c.Burst)
+	}
+	if c.Burst == 0 {
+		return RequestLimit{}, nil
+	}
+	interval, err := time.ParseDuration(c.Interval)
+	if err != nil || interval <= 0 {
+		return RequestLimit{}, fmt.Errorf("invalid interval %q", c.Interval)
+	}
+	return RequestLimit{Burst: c.Burst, Interval: interval}, nil
+}
@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRequestLimit_Unlimited(t *testing.T) {
+	assert.Assert(t, RequestLimit{}.Unlimited())
+	assert.Assert(t, RequestLimit{Burst: 5}.Unlimited())
+	assert.Assert(t, !RequestLimit{Burst: 5, Interval: time.Second}.Unlimited())
+}
+
+func TestRequestLimit_TakeRequestToken(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := RequestLimit{Burst: 3, Interval: 10 * time.Second}
+
+	testCases := []struct {
+		name               string
+		readyAt            time.Time
+		expectedReadyAt    time.Time
+		expectedRetryAfter time.Duration
+		expectedAllowed    bool
+	}{
+		{
+			name:            "full bucket",
+			readyAt:         now.Add(-time.Hour),
+			expectedReadyAt: now.Add(10 * time.Second),
+			expectedAllowed: true,
+		},
+		{
+			name:            "last token",
+			readyAt:         now.Add(20 * time.Second),
+			expectedReadyAt: now.Add(30 * time.Second),
+			expectedAllowed: true,
+		},
+		{
+			name:               "empty bucket",
+			readyAt:            now.Add(30 * time.Second),
+			expectedReadyAt:    now.Add(30 * time.Second),
+			expectedRetryAfter: 10 * time.Second,
+		},
+		{
+			name:               "partly refilled",
+			readyAt:            now.Add(24 * time.Second),
+			expectedReadyAt:    now.Add(24 * time.Second),
+			expectedRetryAfter: 4 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			readyAt, retryAfter, allowed := limit.TakeRequestToken(tc.readyAt, now)
+
+			assert.Equal(t, tc.expectedAllowed, allowed)
+			assert.Equal(t, tc.expectedRetryAfter, retryAfter)
+			assert.Assert(t, readyAt.Equal(tc.expectedReadyAt), "got readyAt %s", readyAt)
+		})
+	}
+}
+
+func TestRequestLimit_Burst(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := RequestLimit{Burst: 5, Interval: time.Second}
+
+	var readyAt time.Time
+	for i := 0; i < limit.Burst; i++ {
+		var allowed bool
+		readyAt, _, allowed = limit.TakeRequestToken(readyAt, now)
+		assert.Assert(t, allowed, "request %d", i+1)
+	}
+
+	_, retryAfter, allowed := limit.TakeRequestToken(readyAt, now)
+	assert.Assert(t, !allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// One token is back an interval later
+	_, _, allowed = limit.TakeRequestToken(readyAt, now.Add(time.Second))
+	assert.Assert(t, allowed)
+}
+
+func TestRequestBucket(t *testing.T) {
+	assert.Equal(t, "update#ip#198.51.100.7", RequestBucket(LimitRouteUpdate, LimitSubjectIP, "198.51.100.7"))
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	limits, err := ParseRouteLimits(`{
+		"update": {"ip": {"burst": 60, "interval": "2s"}},
+		"create-owner": {"ip": {"burst": 0}}
+	}`)
+
+	assert.NilError(t, err)
+	assert.Equal(t, RouteLimits{IP: RequestLimit{Burst: 60, Interval: 2 * time.Second}}, limits[LimitRouteUpdate], "a configured route replaces its defaults")
+	assert.Assert(t, limits[LimitRouteCreateOwner].IP.Unlimited())
+	assert.Equal(t, DefaultRouteLimits[LimitRouteRecover], limits[LimitRouteRecover])
+	assert.Equal(t, RequestLimit{Burst: 30, Interval: 5 * time.Second}, DefaultRouteLimits[LimitRouteUpdate].IP, "defaults are unchanged")
+}
+
+func TestParseRouteLimits_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{name: "malformed", data: `{"update":`, expected: "invalid request limits"},
+		{name: "unknown route", data: `{"everything": {}}`, expected: `unknown route "everything"`},
+		{name: "unknown subject", data: `{"update": {"user": {"burst": 1, "interval": "1s"}}}`, expected: `unknown subject "user"`},
+		{name: "negative burst", data: `{"update": {"ip": {"burst": -1, "interval": "1s"}}}`, expected: "negative burst"},
+		{name: "missing interval", data: `{"update": {"ip": {"burst": 5}}}`, expected: `invalid interval ""`},
+		{name: "zero interval", data: `{"update": {"ip": {"burst": 5, "interval": "0s"}}}`, expected: `invalid interval "0s"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRouteLimits(tc.data)
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook events.
+const (
+	// WebhookEventIPChanged is sent when a location's published IP changes.
+	WebhookEventIPChanged = "ip.changed"
+	// WebhookEventACMECreated is sent when an ACME challenge is created.
+	WebhookEventACMECreated = "acme.created"
+	// WebhookEventACMEDeleted is sent when an ACME challenge is deleted.
+	WebhookEventACMEDeleted = "acme.deleted"
+)
+
+// AllWebhookEvents lists every webhook event in display order.
+var AllWebhookEvents = []string{WebhookEventIPChanged, WebhookEventACMECreated, WebhookEventACMEDeleted}
+
+// Webhook delivery statuses.
+const (
+	// WebhookDeliveryPending is a delivery that failed and will be retried.
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliverySucceeded is a delivery the endpoint accepted with a 2xx status.
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryDeadLetter is a delivery that failed every attempt. It is
+	// kept, like every delivery, for WebhookDeliveryRetention.
+	WebhookDeliveryDeadLetter = "dead-letter"
+)
+
+const (
+	// MaxWebhooks is how many webhooks an owner may register.
+	MaxWebhooks = 5
+
+	// MaxWebhookURLLength bounds a webhook's URL.
+	MaxWebhookURLLength = 2048
+
+	// WebhookDeliveryRetention is how long delivery history is kept.
+	WebhookDeliveryRetention = 30 * 24 * time.Hour
+
+	// MaxWebhookDeliveryHistory is how many of a webhook's latest deliveries are listed.
+	MaxWebhookDeliveryHistory = 50
+)
+
+// WebhookRetryDelays are the waits after each failed delivery attempt before
+// the next one. A delivery that fails once more after the last delay is dead-lettered.
+var WebhookRetryDelays = []time.Duration{
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	4 * time.Hour,
+	12 * time.Hour,
+}
+
+// MaxWebhookAttempts is how many times a delivery is attempted.
+var MaxWebhookAttempts = len(WebhookRetryDelays) + 1
+
+// Webhook is an owner's HTTPS endpoint that is sent the events it subscribes
+// to. Payloads are signed with Secret, so unlike API keys the secret itself
+// is stored.
+type Webhook struct {
+	OwnerID   string    `dynamodbav:"OwnerId"`
+	WebhookID string    `dynamodbav:"WebhookId"`
+	URL       string    `dynamodbav:"Url"`
+	Events    []string  `dynamodbav:"Events"`
+	Secret    string    `dynamodbav:"Secret"`
+	CreatedAt time.Time `dynamodbav:"CreatedAt"`
+}
+
+// Subscribes reports whether the webhook is sent event.
+func (w Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// CreateWebhookRequest is the request body for registering a webhook.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// Normalize trims the URL and lowercases and deduplicates the events.
+func (r CreateWebhookRequest) Normalize() CreateWebhookRequest {
+	r.URL = strings.TrimSpace(r.URL)
+	r.Events = normalizeList(r.Events, strings.ToLower)
+	return r
+}
+
+// Validate checks the URL and events.
+func (r CreateWebhookRequest) Validate() error {
+	if r.URL == "" {
+		return ErrMissingWebhookURL
+	}
+	if err := validateWebhookURL(r.URL); err != nil {
+		return err
+	}
+	if len(r.Events) == 0 {
+		return ErrMissingWebhookEvents
+	}
+	for _, event := range r.Events {
+		if !slices.Contains(AllWebhookEvents, event) {
+			return ErrInvalidWebhookEvent
+		}
+	}
+	return nil
+}
+
+// validateWebhookURL accepts absolute https URLs without credentials whose host
+// isn't localhost or an address IsPublicAddress rejects. Hostnames are checked
+// again when deliveries connect, since they can resolve to anything.
+func validateWebhookURL(raw string) error {
+	if len(raw) > MaxWebhookURLLength {
+		return ErrInvalidWebhookURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return ErrInvalidWebhookURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidWebhookURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddress(addr) {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, 100.64.0.0/10.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddress reports whether webhooks may be delivered to addr. Loopback,
+// private, link-local, unspecified, multicast, carrier-grade NAT and IPv6
+// unique local (fc00::/7) addresses are rejected.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// WebhookDelivery is one event sent, or being sent, to a webhook. Deliveries
+// are sorted by DeliveryID, which starts with the time of the event.
+type WebhookDelivery struct {
+	WebhookID  string    `dynamodbav:"WebhookId"`
+	DeliveryID string    `dynamodbav:"DeliveryId"`
+	OwnerID    string    `dynamodbav:"OwnerId"`
+	Event      string    `dynamodbav:"Event"`
+	Payload    string    `dynamodbav:"Payload"`
+	Status     string    `dynamodbav:"Status"`
+	Attempts   int       `dynamodbav:"Attempts"`
+	CreatedAt  time.Time `dynamodbav:"CreatedAt"`
+
+	LastAttemptAt  *time.Time `dynamodbav:"LastAttemptAt,omitempty"`
+	LastStatusCode int        `dynamodbav:"LastStatusCode,omitempty"`
+	LastError      string     `dynamodbav:"LastError,omitempty"`
+
+	// NextAttemptAt is set while the delivery is pending.
+	NextAttemptAt *time.Time `dynamodbav:"NextAttemptAt,omitempty"`
+
+	// TTL removes the delivery from the history after WebhookDeliveryRetention.
+	TTL int64 `dynamodbav:"TTL"`
+}
+
+// NewWebhookDelivery starts a delivery of an event's payload to a webhook.
+func NewWebhookDelivery(webhook Webhook, deliveryID, event, payload string, now time.Time) WebhookDelivery {
+	return WebhookDelivery{
+		WebhookID:  webhook.WebhookID,
+		DeliveryID: deliveryID,
+		OwnerID:    webhook.OwnerID,
+		Event:      event,
+		Payload:    payload,
+		Status:     WebhookDeliveryPending,
+		CreatedAt:  now,
+		TTL:        now.Add(WebhookDeliveryRetention).Unix(),
+	}
+}
+
+// RecordAttempt records the outcome of a delivery attempt: the endpoint's
+// status code, if it responded, and the error if the attempt failed. A failed
+// delivery is scheduled for a retry, or dead-lettered after MaxWebhookAttempts.
+func (d *WebhookDelivery) RecordAttempt(now time.Time, statusCode int, err error) {
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.LastStatusCode = statusCode
+	d.LastError = ""
+	d.NextAttemptAt = nil
+
+	switch {
+	case err == nil:
+		d.Status = WebhookDeliverySucceeded
+	case d.Attempts >= MaxWebhookAttempts:
+		d.Status = WebhookDeliveryDeadLetter
+		d.LastError = err.Error()
+	default:
+		d.Status = WebhookDeliveryPending
+		d.LastError = err.Error()
+		next := now.Add(WebhookRetryDelays[d.Attempts-1])
+		d.NextAttemptAt = &next
+	}
+}
+
+// DeadLetter gives up on a pending delivery, e.g. because its webhook was deleted.
+func (d *WebhookDelivery) DeadLetter(reason string) {
+	d.Status = WebhookDeliveryDeadLetter
+	d.LastError = reason
+	d.NextAttemptAt = nil
+}
+
+// RetryDue reports whether a pending delivery should be attempted again at now.
+func (d WebhookDelivery) RetryDue(now time.Time) bool {
+	return d.Status == WebhookDeliveryPending && d.NextAttemptAt != nil && !now.Before(*d.NextAttemptAt)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ses"
@@ -20,12 +21,171 @@ const (
 
 	// EmailSubject is the subject line for API key emails.
 	EmailSubject = "Your DDNS Service API Key"
+
+	// KeyExpirySubject is the subject line for key expiry warnings.
+	KeyExpirySubject = "Your DDNS Service API Key Expires Soon"
+
+	// AuthFailureSubject is the subject line for failed authentication alerts.
+	AuthFailureSubject = "Failed Sign-In Attempts on Your DDNS Service Account"
+
+	// VerificationSubject is the subject line for email address verification.
+	VerificationSubject = "Verify Your DDNS Service Account"
+
+	// RecoverySubject is the subject line for API key recovery confirmations.
+	RecoverySubject = "Confirm Your DDNS Service API Key Recovery"
+
+	// FlapAlertSubject is the subject line for IP flap damping alerts.
+	FlapAlertSubject = "Your DDNS Service Location's IP Address Is Flapping"
+
+	// IPChangeSubject is the subject line for IP change notifications.
+	IPChangeSubject = "Your DDNS Service Location's IP Address Changed"
+
+	// StaleLocationSubject is the subject line for stale location alerts.
+	StaleLocationSubject = "Your DDNS Service Location Stopped Checking In"
+
+	// KeyRotatedSubject is the subject line for key rotation notices.
+	KeyRotatedSubject = "Your DDNS Service API Key Was Rotated"
+
+	// RecoveryRequestedSubject is the subject line for key recovery request notices.
+	RecoveryRequestedSubject = "API Key Recovery Requested for Your DDNS Service Account"
+
+	// ACMEChallengeSubject is the subject line for ACME challenge notices.
+	ACMEChallengeSubject = "Certificate Challenge Created for Your DDNS Service Location"
 )
 
+// KeyExpiry describes a key that is about to expire.
+type KeyExpiry struct {
+	KeyID     string
+	Label     string
+	ExpiresAt time.Time
+	// Primary keys are rotated rather than replaced.
+	Primary bool
+}
+
+// AuthFailureAlert describes repeated failed authentication attempts against an owner.
+type AuthFailureAlert struct {
+	Failures       int
+	FirstFailureAt time.Time
+	LastFailureAt  time.Time
+	LastFailureIP  string
+}
+
+// FlapAlert describes a location whose IP changes are being held back because
+// its IP address keeps flapping.
+type FlapAlert struct {
+	Location    string
+	Subdomain   string
+	PublishedIP string
+	ReportedIP  string
+	Mode        string
+	FlapWindow  time.Duration
+	// SettleTime is how long the reported IP must be reported in settle mode.
+	SettleTime time.Duration
+}
+
+// IPChange is one published IP change of a location.
+type IPChange struct {
+	PreviousIP string
+	IP         string
+	ChangedAt  time.Time
+}
+
+// IPChangeNotice lists a location's IP changes, oldest first. A notice with
+// more than one change is a digest.
+type IPChangeNotice struct {
+	Location  string
+	Subdomain string
+	Changes   []IPChange
+	// DigestInterval is how long further changes are collected into a digest.
+	DigestInterval time.Duration
+}
+
+// StaleLocationAlert describes a location that hasn't called /update for
+// longer than its stale threshold.
+type StaleLocationAlert struct {
+	Location   string
+	Subdomain  string
+	IP         string
+	LastSeenAt time.Time
+	StaleAfter time.Duration
+}
+
+// RequestSource identifies the request behind a security notice.
+type RequestSource struct {
+	IP        string
+	UserAgent string
+	At        time.Time
+}
+
+// KeyRotatedNotice tells an owner that their primary API key was rotated.
+type KeyRotatedNotice struct {
+	Source RequestSource
+	// PreviousKeyExpiresAt is set while the replaced key keeps working.
+	PreviousKeyExpiresAt *time.Time
+}
+
+// RecoveryRequestedNotice tells an owner that API key recovery was requested.
+type RecoveryRequestedNotice struct {
+	Source RequestSource
+	// EmailMatched reports whether the request gave the owner's email address,
+	// so a confirmation token was sent.
+	EmailMatched bool
+}
+
+// ACMEChallengeNotice tells an owner that an ACME challenge was created for
+// one of their locations.
+type ACMEChallengeNotice struct {
+	Source    RequestSource
+	Location  string
+	Subdomain string
+	// KeyID is the API key that created the challenge.
+	KeyID     string
+	ExpiresAt time.Time
+}
+
+// Recipient is the owner an email is sent to.
+type Recipient struct {
+	Email   string
+	OwnerID string
+	// Locale selects the templates the email is rendered with; empty means
+	// DefaultLocale.
+	Locale string
+}
+
 // Service defines the interface for sending emails.
 type Service interface {
-	// SendAPIKey sends an API key to the specified email address.
-	SendAPIKey(ctx context.Context, toEmail, ownerID, apiKey string) error
+	// SendAPIKey sends an API key and its signing secret to the specified email address.
+	SendAPIKey(ctx context.Context, to Recipient, apiKey, signingSecret string) error
+
+	// SendKeyExpiryWarning tells an owner that one of their API keys expires soon.
+	SendKeyExpiryWarning(ctx context.Context, to Recipient, key KeyExpiry) error
+
+	// SendAuthFailureAlert tells an owner about repeated failed authentication attempts.
+	SendAuthFailureAlert(ctx context.Context, to Recipient, alert AuthFailureAlert) error
+
+	// SendVerification sends a new owner the token that verifies their email address.
+	SendVerification(ctx context.Context, to Recipient, token string, expiresAt time.Time) error
+
+	// SendRecovery sends an owner the token that confirms an API key recovery.
+	SendRecovery(ctx context.Context, to Recipient, token string, expiresAt time.Time) error
+
+	// SendFlapAlert tells an owner that a location's IP changes are being dampened.
+	SendFlapAlert(ctx context.Context, to Recipient, alert FlapAlert) error
+
+	// SendIPChangeNotice tells an owner that a location's IP address changed.
+	SendIPChangeNotice(ctx context.Context, to Recipient, notice IPChangeNotice) error
+
+	// SendStaleLocationAlert tells an owner that a location stopped checking in.
+	SendStaleLocationAlert(ctx context.Context, to Recipient, alert StaleLocationAlert) error
+
+	// SendKeyRotatedNotice tells an owner that their primary API key was rotated.
+	SendKeyRotatedNotice(ctx context.Context, to Recipient, notice KeyRotatedNotice) error
+
+	// SendRecoveryRequestedNotice tells an owner that API key recovery was requested.
+	SendRecoveryRequestedNotice(ctx context.Context, to Recipient, notice RecoveryRequestedNotice) error
+
+	// SendACMEChallengeNotice tells an owner that an ACME challenge was created.
+	SendACMEChallengeNotice(ctx context.Context, to Recipient, notice ACMEChallengeNotice) error
 }
 
 // SESClient defines the interface for SES operations we use.
@@ -37,6 +197,7 @@ type SESClient interface {
 type SESService struct {
 	client      SESClient
 	senderEmail string
+	templates   *Templates
 	logger      *slog.Logger
 }
 
@@ -45,6 +206,7 @@ func NewSESService(client SESClient, logger *slog.Logger) *SESService {
 	return &SESService{
 		client:      client,
 		senderEmail: DefaultSenderEmail,
+		templates:   DefaultTemplates(),
 		logger:      logger,
 	}
 }
@@ -54,123 +216,169 @@ func NewSESServiceWithSender(client SESClient, senderEmail string, logger *slog.
 	return &SESService{
 		client:      client,
 		senderEmail: senderEmail,
+		templates:   DefaultTemplates(),
 		logger:      logger,
 	}
 }
 
-// SendAPIKey sends an API key to the specified email address.
-func (s *SESService) SendAPIKey(ctx context.Context, toEmail, ownerID, apiKey string) error {
-	body := buildAPIKeyEmailBody(ownerID, apiKey)
+// WithTemplates renders emails with templates instead of the embedded ones.
+func (s *SESService) WithTemplates(templates *Templates) *SESService {
+	s.templates = templates
+	return s
+}
+
+// SendAPIKey sends an API key and its signing secret to the specified email address.
+func (s *SESService) SendAPIKey(ctx context.Context, to Recipient, apiKey, signingSecret string) error {
+	if err := s.send(ctx, to, TemplateAPIKey, apiKeyData{newTemplateData(to), apiKey, signingSecret}); err != nil {
+		return err
+	}
+
+	s.logger.Info("API key email sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendKeyExpiryWarning tells an owner that one of their API keys expires soon.
+func (s *SESService) SendKeyExpiryWarning(ctx context.Context, to Recipient, key KeyExpiry) error {
+	if err := s.send(ctx, to, TemplateKeyExpiry, keyExpiryData{newTemplateData(to), key}); err != nil {
+		return err
+	}
+
+	s.logger.Info("key expiry email sent", "toEmail", to.Email, "ownerId", to.OwnerID, "keyId", key.KeyID)
+	return nil
+}
+
+// SendAuthFailureAlert tells an owner about repeated failed authentication attempts.
+func (s *SESService) SendAuthFailureAlert(ctx context.Context, to Recipient, alert AuthFailureAlert) error {
+	if err := s.send(ctx, to, TemplateAuthFailure, authFailureData{newTemplateData(to), alert}); err != nil {
+		return err
+	}
+
+	s.logger.Info("auth failure alert sent", "toEmail", to.Email, "ownerId", to.OwnerID, "failures", alert.Failures)
+	return nil
+}
+
+// SendVerification sends a new owner the token that verifies their email address.
+func (s *SESService) SendVerification(ctx context.Context, to Recipient, token string, expiresAt time.Time) error {
+	if err := s.send(ctx, to, TemplateVerification, tokenData{newTemplateData(to), token, expiresAt}); err != nil {
+		return err
+	}
+
+	s.logger.Info("verification email sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendRecovery sends an owner the token that confirms an API key recovery.
+func (s *SESService) SendRecovery(ctx context.Context, to Recipient, token string, expiresAt time.Time) error {
+	if err := s.send(ctx, to, TemplateRecovery, tokenData{newTemplateData(to), token, expiresAt}); err != nil {
+		return err
+	}
+
+	s.logger.Info("recovery email sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendFlapAlert tells an owner that a location's IP changes are being dampened.
+func (s *SESService) SendFlapAlert(ctx context.Context, to Recipient, alert FlapAlert) error {
+	if err := s.send(ctx, to, TemplateFlapAlert, flapAlertData{newTemplateData(to), alert}); err != nil {
+		return err
+	}
+
+	s.logger.Info("flap alert sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", alert.Location)
+	return nil
+}
+
+// SendIPChangeNotice tells an owner that a location's IP address changed.
+func (s *SESService) SendIPChangeNotice(ctx context.Context, to Recipient, notice IPChangeNotice) error {
+	if err := s.send(ctx, to, TemplateIPChange, ipChangeData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("IP change notice sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", notice.Location, "changes", len(notice.Changes))
+	return nil
+}
+
+// SendStaleLocationAlert tells an owner that a location stopped checking in.
+func (s *SESService) SendStaleLocationAlert(ctx context.Context, to Recipient, alert StaleLocationAlert) error {
+	if err := s.send(ctx, to, TemplateStaleLocation, staleLocationData{newTemplateData(to), alert}); err != nil {
+		return err
+	}
+
+	s.logger.Info("stale location alert sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", alert.Location)
+	return nil
+}
+
+// SendKeyRotatedNotice tells an owner that their primary API key was rotated.
+func (s *SESService) SendKeyRotatedNotice(ctx context.Context, to Recipient, notice KeyRotatedNotice) error {
+	if err := s.send(ctx, to, TemplateKeyRotated, keyRotatedData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("key rotated notice sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendRecoveryRequestedNotice tells an owner that API key recovery was requested.
+func (s *SESService) SendRecoveryRequestedNotice(ctx context.Context, to Recipient, notice RecoveryRequestedNotice) error {
+	if err := s.send(ctx, to, TemplateRecoveryRequested, recoveryRequestedData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("recovery requested notice sent", "toEmail", to.Email, "ownerId", to.OwnerID, "emailMatched", notice.EmailMatched)
+	return nil
+}
+
+// SendACMEChallengeNotice tells an owner that an ACME challenge was created.
+func (s *SESService) SendACMEChallengeNotice(ctx context.Context, to Recipient, notice ACMEChallengeNotice) error {
+	if err := s.send(ctx, to, TemplateACMEChallenge, acmeChallengeData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("ACME challenge notice sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", notice.Location)
+	return nil
+}
+
+// send renders the named template and sends it through SES.
+func (s *SESService) send(ctx context.Context, to Recipient, name string, data any) error {
+	msg, err := s.templates.render(name, to.Locale, data)
+	if err != nil {
+		s.logger.Error("failed to render email", "error", err, "template", name, "locale", to.Locale)
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	body := &types.Body{
+		Text: &types.Content{
+			Data:    aws.String(msg.Text),
+			Charset: aws.String("UTF-8"),
+		},
+	}
+	if msg.HTML != "" {
+		body.Html = &types.Content{
+			Data:    aws.String(msg.HTML),
+			Charset: aws.String("UTF-8"),
+		}
+	}
 
 	input := &ses.SendEmailInput{
 		Source: aws.String(s.senderEmail),
 		Destination: &types.Destination{
-			ToAddresses: []string{toEmail},
+			ToAddresses: []string{to.Email},
 		},
 		Message: &types.Message{
 			Subject: &types.Content{
-				Data:    aws.String(EmailSubject),
+				Data:    aws.String(msg.Subject),
 				Charset: aws.String("UTF-8"),
 			},
-			Body: &types.Body{
-				Text: &types.Content{
-					Data:    aws.String(body),
-					Charset: aws.String("UTF-8"),
-				},
-				Html: &types.Content{
-					Data:    aws.String(buildAPIKeyEmailHTML(ownerID, apiKey)),
-					Charset: aws.String("UTF-8"),
-				},
-			},
+			Body: body,
 		},
 	}
 
-	_, err := s.client.SendEmail(ctx, input)
+	_, err = s.client.SendEmail(ctx, input)
 	if err != nil {
-		s.logger.Error("failed to send email", "error", err, "toEmail", toEmail)
+		s.logger.Error("failed to send email", "error", err, "toEmail", to.Email)
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-
-	s.logger.Info("API key email sent", "toEmail", toEmail, "ownerId", ownerID)
 	return nil
 }
 
-func buildAPIKeyEmailBody(ownerID, apiKey string) string {
-	return fmt.Sprintf(`Your DDNS Service API Key
-
-Owner ID: %s
-
-Your new API key is:
-%s
-
-IMPORTANT: Save this key securely. It will not be shown again.
-
-Use this key in the Authorization header for all API requests:
-Authorization: Bearer %s
-
-Example usage:
-curl -X POST %s/update \
-  -H "Authorization: Bearer %s" \
-  -H "Content-Type: application/json" \
-  -d '{"ownerId":"%s","location":"home"}'
-
-If you did not request this key, please ignore this email.
-
----
-DDNS Service
-%s
-`, ownerID, apiKey, apiKey, APIEndpoint, apiKey, ownerID, APIEndpoint)
-}
-
-func buildAPIKeyEmailHTML(ownerID, apiKey string) string {
-	return fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-  <style>
-    body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #333; }
-    .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-    .key-box { background: #f5f5f5; border: 1px solid #ddd; border-radius: 4px; padding: 15px; margin: 20px 0; font-family: monospace; word-break: break-all; }
-    .warning { background: #fff3cd; border: 1px solid #ffc107; border-radius: 4px; padding: 15px; margin: 20px 0; }
-    code { background: #f5f5f5; padding: 2px 6px; border-radius: 3px; font-family: monospace; }
-    pre { background: #f5f5f5; padding: 15px; border-radius: 4px; overflow-x: auto; }
-  </style>
-</head>
-<body>
-  <div class="container">
-    <h1>Your DDNS Service API Key</h1>
-
-    <p><strong>Owner ID:</strong> <code>%s</code></p>
-
-    <p>Your new API key is:</p>
-    <div class="key-box">%s</div>
-
-    <div class="warning">
-      <strong>Important:</strong> Save this key securely. It will not be shown again.
-    </div>
-
-    <h2>Usage</h2>
-    <p>Include this key in the Authorization header for all API requests:</p>
-    <pre>Authorization: Bearer %s</pre>
-
-    <h3>Example</h3>
-    <pre>curl -X POST %s/update \
-  -H "Authorization: Bearer %s" \
-  -H "Content-Type: application/json" \
-  -d '{"ownerId":"%s","location":"home"}'</pre>
-
-    <p style="color: #666; font-size: 14px; margin-top: 40px;">
-      If you did not request this key, please ignore this email.
-    </p>
-
-    <hr style="border: none; border-top: 1px solid #ddd; margin: 30px 0;">
-    <p style="color: #999; font-size: 12px;">
-      DDNS Service<br>
-      <a href="%s">%s</a>
-    </p>
-  </div>
-</body>
-</html>`, ownerID, apiKey, apiKey, APIEndpoint, apiKey, ownerID, APIEndpoint, APIEndpoint)
-}
-
 // Ensure SESService implements Service.
 var _ Service = (*SESService)(nil)
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/ses"
 	"gotest.tools/assert"
@@ -28,6 +29,17 @@ func newTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 }
 
+// testTemplateData is the common template data of the test owner.
+var testTemplateData = templateData{OwnerID: "test-owner", APIEndpoint: APIEndpoint}
+
+// renderEmail renders the named embedded template in the default locale.
+func renderEmail(t *testing.T, name string, data any) message {
+	t.Helper()
+	msg, err := DefaultTemplates().render(name, "", data)
+	assert.NilError(t, err)
+	return msg
+}
+
 func TestNewSESService(t *testing.T) {
 	client := &mockSESClient{}
 	logger := newTestLogger()
@@ -64,7 +76,7 @@ func TestSendAPIKey_Success(t *testing.T) {
 
 	svc := NewSESService(client, logger)
 
-	err := svc.SendAPIKey(ctx, "user@example.com", "test-owner", "ddns_sk_testkey123")
+	err := svc.SendAPIKey(ctx, Recipient{Email: "user@example.com", OwnerID: "test-owner"}, "ddns_sk_testkey123", "ddns_ss_secret")
 
 	assert.NilError(t, err)
 
@@ -96,7 +108,7 @@ func TestSendAPIKey_Error(t *testing.T) {
 
 	svc := NewSESService(client, logger)
 
-	err := svc.SendAPIKey(ctx, "user@example.com", "test-owner", "ddns_sk_testkey123")
+	err := svc.SendAPIKey(ctx, Recipient{Email: "user@example.com", OwnerID: "test-owner"}, "ddns_sk_testkey123", "ddns_ss_secret")
 
 	assert.Assert(t, err != nil)
 	assert.Assert(t, strings.Contains(err.Error(), "failed to send email"))
@@ -118,35 +130,378 @@ func TestSendAPIKey_CustomSender(t *testing.T) {
 	customSender := "support@mycompany.com"
 	svc := NewSESServiceWithSender(client, customSender, logger)
 
-	err := svc.SendAPIKey(ctx, "user@example.com", "owner", "key")
+	err := svc.SendAPIKey(ctx, Recipient{Email: "user@example.com", OwnerID: "owner"}, "key", "ddns_ss_secret")
 
 	assert.NilError(t, err)
 	assert.Equal(t, customSender, capturedSource)
 }
 
-func TestBuildAPIKeyEmailBody(t *testing.T) {
-	body := buildAPIKeyEmailBody("test-owner", "ddns_sk_abc123")
+func TestRenderAPIKeyText(t *testing.T) {
+	body := renderEmail(t, TemplateAPIKey, apiKeyData{testTemplateData, "ddns_sk_abc123", "ddns_ss_abc123"}).Text
 
 	// Verify essential content is present
 	assert.Assert(t, strings.Contains(body, "test-owner"))
 	assert.Assert(t, strings.Contains(body, "ddns_sk_abc123"))
+	assert.Assert(t, strings.Contains(body, "ddns_ss_abc123"))
 	assert.Assert(t, strings.Contains(body, "Authorization: Bearer"))
 	assert.Assert(t, strings.Contains(body, "IMPORTANT"))
 	assert.Assert(t, strings.Contains(body, APIEndpoint))
 }
 
-func TestBuildAPIKeyEmailHTML(t *testing.T) {
-	html := buildAPIKeyEmailHTML("test-owner", "ddns_sk_abc123")
+func TestRenderAPIKeyHTML(t *testing.T) {
+	html := renderEmail(t, TemplateAPIKey, apiKeyData{testTemplateData, "ddns_sk_abc123", "ddns_ss_abc123"}).HTML
 
 	// Verify essential content is present
 	assert.Assert(t, strings.Contains(html, "test-owner"))
 	assert.Assert(t, strings.Contains(html, "ddns_sk_abc123"))
+	assert.Assert(t, strings.Contains(html, "ddns_ss_abc123"))
 	assert.Assert(t, strings.Contains(html, "Authorization: Bearer"))
 	assert.Assert(t, strings.Contains(html, "<!DOCTYPE html>"))
 	assert.Assert(t, strings.Contains(html, "</html>"))
 	assert.Assert(t, strings.Contains(html, APIEndpoint))
 }
 
+func TestSendKeyExpiryWarning(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, logger)
+	expiresAt := time.Date(2025, 1, 22, 10, 0, 0, 0, time.UTC)
+
+	err := svc.SendKeyExpiryWarning(ctx, Recipient{Email: "user@example.com", OwnerID: "test-owner"}, KeyExpiry{
+		KeyID:     "9f2c4a1b7e3d",
+		Label:     "cabin pi",
+		ExpiresAt: expiresAt,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, KeyExpirySubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "cabin pi"))
+	assert.Assert(t, strings.Contains(body, "Wed, 22 Jan 2025 10:00:00 UTC"))
+	assert.Assert(t, strings.Contains(body, "/keys/9f2c4a1b7e3d"))
+}
+
+func TestRenderKeyExpiry_Primary(t *testing.T) {
+	body := renderEmail(t, TemplateKeyExpiry, keyExpiryData{testTemplateData, KeyExpiry{KeyID: "primary", Label: "primary", Primary: true}}).Text
+
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/rotate"))
+	assert.Assert(t, !strings.Contains(body, "/keys/"))
+}
+
+func TestSendAuthFailureAlert(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, logger)
+
+	err := svc.SendAuthFailureAlert(ctx, Recipient{Email: "user@example.com", OwnerID: "test-owner"}, AuthFailureAlert{
+		Failures:       12,
+		FirstFailureAt: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+		LastFailureAt:  time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+		LastFailureIP:  "198.51.100.7",
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, AuthFailureSubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "Failed attempts: 12"))
+	assert.Assert(t, strings.Contains(body, "198.51.100.7"))
+	assert.Assert(t, strings.Contains(body, "valid key keep working"))
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/rotate"))
+}
+
+func TestRenderAuthFailure_NotLocked(t *testing.T) {
+	body := renderEmail(t, TemplateAuthFailure, authFailureData{testTemplateData, AuthFailureAlert{Failures: 10}}).Text
+
+	assert.Assert(t, !strings.Contains(body, "blocked until"))
+	assert.Assert(t, strings.Contains(body, "from unknown"))
+}
+
+func TestSendVerification(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, logger)
+	expiresAt := time.Date(2025, 1, 16, 10, 0, 0, 0, time.UTC)
+
+	err := svc.SendVerification(ctx, Recipient{Email: "user@example.com", OwnerID: "test-owner"}, "payload.signature", expiresAt)
+
+	assert.NilError(t, err)
+	assert.Equal(t, "user@example.com", capturedInput.Destination.ToAddresses[0])
+	assert.Equal(t, VerificationSubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/verify"))
+	assert.Assert(t, strings.Contains(body, `{"token":"payload.signature"}`))
+	assert.Assert(t, strings.Contains(body, "Thu, 16 Jan 2025 10:00:00 UTC"))
+}
+
+func TestSendRecovery(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, logger)
+	expiresAt := time.Date(2025, 1, 16, 10, 0, 0, 0, time.UTC)
+
+	err := svc.SendRecovery(ctx, Recipient{Email: "user@example.com", OwnerID: "test-owner"}, "payload.signature", expiresAt)
+
+	assert.NilError(t, err)
+	assert.Equal(t, RecoverySubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/recover/confirm"))
+	assert.Assert(t, strings.Contains(body, `{"token":"payload.signature"}`))
+	assert.Assert(t, strings.Contains(body, "Thu, 16 Jan 2025 10:00:00 UTC"))
+}
+
+func TestSendFlapAlert(t *testing.T) {
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, newTestLogger())
+	err := svc.SendFlapAlert(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner"}, FlapAlert{
+		Location:    "home",
+		Subdomain:   "a3f8c2d1.grocky.net",
+		PublishedIP: "198.51.100.2",
+		ReportedIP:  "198.51.100.1",
+		Mode:        "settle",
+		FlapWindow:  time.Hour,
+		SettleTime:  10 * time.Minute,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, FlapAlertSubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "Location: home (a3f8c2d1.grocky.net)"))
+	assert.Assert(t, strings.Contains(body, "Published address: 198.51.100.2"))
+	assert.Assert(t, strings.Contains(body, "reported for\n10 minutes."))
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/locations/home"))
+}
+
+func TestRenderFlapAlert_Hold(t *testing.T) {
+	body := renderEmail(t, TemplateFlapAlert, flapAlertData{testTemplateData, FlapAlert{Location: "home", Mode: "hold", FlapWindow: 2 * time.Hour}}).Text
+
+	assert.Assert(t, strings.Contains(body, "for 2 hours."))
+}
+
+func TestSendIPChangeNotice(t *testing.T) {
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, newTestLogger())
+	err := svc.SendIPChangeNotice(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner"}, IPChangeNotice{
+		Location:  "home",
+		Subdomain: "a3f8c2d1.grocky.net",
+		Changes: []IPChange{
+			{PreviousIP: "198.51.100.1", IP: "198.51.100.2", ChangedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		DigestInterval: time.Hour,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, IPChangeSubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "Location: home (a3f8c2d1.grocky.net)"))
+	assert.Assert(t, strings.Contains(body, "The location's IP address changed:\n"))
+	assert.Assert(t, strings.Contains(body, "Sun, 01 Mar 2026 12:00:00 UTC  198.51.100.1 -> 198.51.100.2"))
+	assert.Assert(t, strings.Contains(body, "within 1 hour"))
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/notifications"))
+}
+
+func TestRenderIPChange_Digest(t *testing.T) {
+	changedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	body := renderEmail(t, TemplateIPChange, ipChangeData{testTemplateData, IPChangeNotice{
+		Location: "home",
+		Changes: []IPChange{
+			{PreviousIP: "198.51.100.1", IP: "198.51.100.2", ChangedAt: changedAt},
+			{PreviousIP: "198.51.100.2", IP: "198.51.100.3", ChangedAt: changedAt.Add(10 * time.Minute)},
+		},
+		DigestInterval: time.Hour,
+	}}).Text
+
+	assert.Assert(t, strings.Contains(body, "changed 2 times since the last notice"))
+	assert.Assert(t, strings.Contains(body, "198.51.100.2 -> 198.51.100.3"))
+}
+
+func TestSendStaleLocationAlert(t *testing.T) {
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	svc := NewSESService(client, newTestLogger())
+	err := svc.SendStaleLocationAlert(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner"}, StaleLocationAlert{
+		Location:   "cabin",
+		Subdomain:  "a3f8c2d1.grocky.net",
+		IP:         "198.51.100.2",
+		LastSeenAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		StaleAfter: 6 * time.Hour,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, StaleLocationSubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "Location: cabin (a3f8c2d1.grocky.net)"))
+	assert.Assert(t, strings.Contains(body, "Last seen: Sun, 01 Mar 2026 12:00:00 UTC"))
+	assert.Assert(t, strings.Contains(body, "more than 6 hours"))
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/locations/cabin"))
+}
+
+// testRequestSource is the request source of the security notice tests.
+var testRequestSource = RequestSource{IP: "198.51.100.7", UserAgent: "curl/8.5.0", At: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+
+func TestSendKeyRotatedNotice(t *testing.T) {
+	var capturedInput *ses.SendEmailInput
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			capturedInput = params
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+
+	previousExpiresAt := testRequestSource.At.Add(24 * time.Hour)
+	svc := NewSESService(client, newTestLogger())
+	err := svc.SendKeyRotatedNotice(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner"}, KeyRotatedNotice{
+		Source:               testRequestSource,
+		PreviousKeyExpiresAt: &previousExpiresAt,
+	})
+
+	assert.NilError(t, err)
+	assert.Equal(t, KeyRotatedSubject, *capturedInput.Message.Subject.Data)
+	body := *capturedInput.Message.Body.Text.Data
+	assert.Assert(t, strings.Contains(body, "Time: Sun, 01 Mar 2026 12:00:00 UTC\nIP address: 198.51.100.7\nUser agent: curl/8.5.0\n"))
+	assert.Assert(t, strings.Contains(body, "The old key keeps working until\nMon, 02 Mar 2026 12:00:00 UTC."))
+	assert.Assert(t, strings.Contains(body, "/owners/test-owner/recover"))
+	assert.Assert(t, strings.Contains(body, `{"security":{"suppress":["keyRotated"]}}`))
+}
+
+func TestRenderKeyRotated_NoGracePeriod(t *testing.T) {
+	body := renderEmail(t, TemplateKeyRotated, keyRotatedData{testTemplateData, KeyRotatedNotice{}}).Text
+
+	assert.Assert(t, strings.Contains(body, "IP address: unknown\nUser agent: unknown\n"))
+	assert.Assert(t, strings.Contains(body, "The old key no longer works."))
+}
+
+func TestSendRecoveryRequestedNotice(t *testing.T) {
+	testCases := []struct {
+		name         string
+		emailMatched bool
+		expected     string
+	}{
+		{name: "email matched", emailMatched: true, expected: "A confirmation token\nwas sent in a separate email."},
+		{name: "email mismatched", emailMatched: false, expected: "so nothing was sent and your key has not changed."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var capturedInput *ses.SendEmailInput
+			client := &mockSESClient{
+				sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+					capturedInput = params
+					return &ses.SendEmailOutput{}, nil
+				},
+			}
+
+			svc := NewSESService(client, newTestLogger())
+			err := svc.SendRecoveryRequestedNotice(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner"}, RecoveryRequestedNotice{
+				Source:       testRequestSource,
+				EmailMatched: tc.emailMatched,
+			})
+
+			assert.NilError(t, err)
+			assert.Equal(t, RecoveryRequestedSubject, *capturedInput.Message.Subject.Data)
+			body := *capturedInput.Message.Body.Text.Data
+			assert.Assert(t, strings.Contains(body, "IP address: 198.51.100.7"))
+			assert.Assert(t, strings.Contains(body, tc.expected), body)
+			assert.Assert(t, strings.Contains(body, `{"security":{"suppress":["recoveryRequested"]}}`))
+		})
+	}
+}
+
+func TestSendACMEChallengeNotice(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keyID    string
+		expected string
+	}{
+		{name: "additional key", keyID: "9f2c4a1b7e3d", expected: "curl -X DELETE " + APIEndpoint + "/owners/test-owner/keys/9f2c4a1b7e3d"},
+		{name: "primary key", keyID: "primary", expected: "curl -X POST " + APIEndpoint + "/owners/test-owner/recover"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var capturedInput *ses.SendEmailInput
+			client := &mockSESClient{
+				sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+					capturedInput = params
+					return &ses.SendEmailOutput{}, nil
+				},
+			}
+
+			svc := NewSESService(client, newTestLogger())
+			err := svc.SendACMEChallengeNotice(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner"}, ACMEChallengeNotice{
+				Source:    testRequestSource,
+				Location:  "home",
+				Subdomain: "a3f8c2d1.grocky.net",
+				KeyID:     tc.keyID,
+				ExpiresAt: testRequestSource.At.Add(time.Hour),
+			})
+
+			assert.NilError(t, err)
+			assert.Equal(t, ACMEChallengeSubject, *capturedInput.Message.Subject.Data)
+			body := *capturedInput.Message.Body.Text.Data
+			assert.Assert(t, strings.Contains(body, "Location: home (a3f8c2d1.grocky.net)\nKey: "+tc.keyID+"\n"))
+			assert.Assert(t, strings.Contains(body, "User agent: curl/8.5.0"))
+			assert.Assert(t, strings.Contains(body, `-d '{"ownerId":"test-owner","location":"home"}'`))
+			assert.Assert(t, strings.Contains(body, tc.expected), body)
+		})
+	}
+}
+
 func TestSESServiceImplementsInterface(t *testing.T) {
 	var _ Service = (*SESService)(nil)
 }
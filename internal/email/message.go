@@ -0,0 +1,147 @@
+package email
+
+import "time"
+
+// message is a rendered email, sent the same way by every Service.
+type message struct {
+	Subject string
+	Text    string
+	// HTML is the alternative HTML body.
+	HTML string
+}
+
+// templateData is available to every template as .OwnerID and .APIEndpoint.
+type templateData struct {
+	OwnerID     string
+	APIEndpoint string
+}
+
+func newTemplateData(to Recipient) templateData {
+	return templateData{OwnerID: to.OwnerID, APIEndpoint: APIEndpoint}
+}
+
+// apiKeyData is the data of the api-key template.
+type apiKeyData struct {
+	templateData
+	APIKey        string
+	SigningSecret string
+}
+
+// tokenData is the data of the verification and recovery templates.
+type tokenData struct {
+	templateData
+	Token     string
+	ExpiresAt time.Time
+}
+
+// keyExpiryData is the data of the key-expiry template.
+type keyExpiryData struct {
+	templateData
+	Key KeyExpiry
+}
+
+// authFailureData is the data of the auth-failure template.
+type authFailureData struct {
+	templateData
+	Alert AuthFailureAlert
+}
+
+// flapAlertData is the data of the flap-alert template.
+type flapAlertData struct {
+	templateData
+	Alert FlapAlert
+}
+
+// ipChangeData is the data of the ip-change template.
+type ipChangeData struct {
+	templateData
+	Notice IPChangeNotice
+}
+
+// staleLocationData is the data of the stale-location template.
+type staleLocationData struct {
+	templateData
+	Alert StaleLocationAlert
+}
+
+// keyRotatedData is the data of the key-rotated template.
+type keyRotatedData struct {
+	templateData
+	Notice KeyRotatedNotice
+}
+
+// recoveryRequestedData is the data of the recovery-requested template.
+type recoveryRequestedData struct {
+	templateData
+	Notice RecoveryRequestedNotice
+}
+
+// acmeChallengeData is the data of the acme-challenge template.
+type acmeChallengeData struct {
+	templateData
+	Notice ACMEChallengeNotice
+}
+
+// sampleData returns example data for the named template, used to check
+// templates when they are loaded.
+func sampleData(name string) any {
+	at := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	data := templateData{OwnerID: "my-home-lab", APIEndpoint: APIEndpoint}
+	source := RequestSource{IP: "198.51.100.7", UserAgent: "curl/8.5.0", At: at}
+
+	switch name {
+	case TemplateAPIKey:
+		return apiKeyData{data, "ddns_sk_example", "ddns_ss_example"}
+	case TemplateVerification, TemplateRecovery:
+		return tokenData{data, "payload.signature", at}
+	case TemplateKeyExpiry:
+		return keyExpiryData{data, KeyExpiry{KeyID: "9f2c4a1b7e3d", Label: "cabin pi", ExpiresAt: at}}
+	case TemplateAuthFailure:
+		return authFailureData{data, AuthFailureAlert{
+			Failures:       10,
+			FirstFailureAt: at,
+			LastFailureAt:  at.Add(30 * time.Minute),
+			LastFailureIP:  "198.51.100.7",
+		}}
+	case TemplateFlapAlert:
+		return flapAlertData{data, FlapAlert{
+			Location:    "home",
+			Subdomain:   "a3f8c2d1.grocky.net",
+			PublishedIP: "198.51.100.2",
+			ReportedIP:  "198.51.100.1",
+			Mode:        "hold",
+			FlapWindow:  time.Hour,
+			SettleTime:  10 * time.Minute,
+		}}
+	case TemplateIPChange:
+		return ipChangeData{data, IPChangeNotice{
+			Location:       "home",
+			Subdomain:      "a3f8c2d1.grocky.net",
+			Changes:        []IPChange{{PreviousIP: "198.51.100.1", IP: "198.51.100.2", ChangedAt: at}},
+			DigestInterval: time.Hour,
+		}}
+	case TemplateStaleLocation:
+		return staleLocationData{data, StaleLocationAlert{
+			Location:   "cabin",
+			Subdomain:  "a3f8c2d1.grocky.net",
+			IP:         "198.51.100.2",
+			LastSeenAt: at,
+			StaleAfter: 6 * time.Hour,
+		}}
+	case TemplateKeyRotated:
+		previousExpiresAt := at.Add(24 * time.Hour)
+		return keyRotatedData{data, KeyRotatedNotice{Source: source, PreviousKeyExpiresAt: &previousExpiresAt}}
+	case TemplateRecoveryRequested:
+		return recoveryRequestedData{data, RecoveryRequestedNotice{Source: source, EmailMatched: true}}
+	case TemplateACMEChallenge:
+		return acmeChallengeData{data, ACMEChallengeNotice{
+			Source:    source,
+			Location:  "home",
+			Subdomain: "a3f8c2d1.grocky.net",
+			KeyID:     "9f2c4a1b7e3d",
+			ExpiresAt: at.Add(time.Hour),
+		}}
+	default:
+		return data
+	}
+}
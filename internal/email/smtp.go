@@ -0,0 +1,492 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP connection security modes.
+const (
+	// SMTPStartTLS upgrades a plain connection with STARTTLS, and fails if the
+	// server doesn't offer it.
+	SMTPStartTLS = "starttls"
+
+	// SMTPTLS connects over TLS from the start, usually on port 465.
+	SMTPTLS = "tls"
+
+	// SMTPNone sends over an unencrypted connection. Credentials are only sent
+	// to localhost this way.
+	SMTPNone = "none"
+)
+
+// SMTP authentication mechanisms.
+const (
+	SMTPAuthPlain = "plain"
+	SMTPAuthLogin = "login"
+)
+
+// DefaultSMTPTimeout bounds sending one email, from connecting to QUIT.
+const DefaultSMTPTimeout = 5 * time.Second
+
+// SMTPConfig configures an SMTPService.
+type SMTPConfig struct {
+	Host string
+	// Port defaults to 587 for STARTTLS, 465 for TLS and 25 otherwise.
+	Port int
+	// Security is SMTPStartTLS (the default), SMTPTLS or SMTPNone.
+	Security string
+
+	// Auth is SMTPAuthPlain or SMTPAuthLogin. It defaults to SMTPAuthPlain
+	// when a username is set; without one, no authentication is attempted.
+	Auth     string
+	Username string
+	Password string
+
+	// Sender is the From address, optionally with a display name, e.g.
+	// "DDNS Service <ddns@example.com>". It defaults to DefaultSenderEmail.
+	Sender string
+
+	// Timeout defaults to DefaultSMTPTimeout.
+	Timeout time.Duration
+
+	// TLSConfig overrides the TLS settings, e.g. to trust a private CA.
+	TLSConfig *tls.Config
+}
+
+// Normalize lowercases the modes and fills in defaults.
+func (c SMTPConfig) Normalize() SMTPConfig {
+	c.Host = strings.TrimSpace(c.Host)
+	c.Security = strings.ToLower(strings.TrimSpace(c.Security))
+	if c.Security == "" {
+		c.Security = SMTPStartTLS
+	}
+	if c.Port == 0 {
+		switch c.Security {
+		case SMTPStartTLS:
+			c.Port = 587
+		case SMTPTLS:
+			c.Port = 465
+		default:
+			c.Port = 25
+		}
+	}
+	c.Auth = strings.ToLower(strings.TrimSpace(c.Auth))
+	if c.Auth == "" && c.Username != "" {
+		c.Auth = SMTPAuthPlain
+	}
+	if strings.TrimSpace(c.Sender) == "" {
+		c.Sender = DefaultSenderEmail
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultSMTPTimeout
+	}
+	return c
+}
+
+// Validate checks the server, modes, credentials and sender.
+func (c SMTPConfig) Validate() error {
+	if c.Host == "" {
+		return errors.New("SMTP host is required")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("invalid SMTP port %d", c.Port)
+	}
+	switch c.Security {
+	case SMTPStartTLS, SMTPTLS, SMTPNone:
+	default:
+		return fmt.Errorf("SMTP security must be %q, %q or %q", SMTPStartTLS, SMTPTLS, SMTPNone)
+	}
+	switch c.Auth {
+	case "":
+	case SMTPAuthPlain, SMTPAuthLogin:
+		if c.Username == "" || c.Password == "" {
+			return errors.New("SMTP authentication needs a username and password")
+		}
+	default:
+		return fmt.Errorf("SMTP auth must be %q or %q", SMTPAuthPlain, SMTPAuthLogin)
+	}
+	if _, err := mail.ParseAddress(c.Sender); err != nil {
+		return fmt.Errorf("invalid sender %q: %w", c.Sender, err)
+	}
+	if c.Timeout < 0 {
+		return errors.New("SMTP timeout must be positive")
+	}
+	return nil
+}
+
+// SMTPService implements Service by sending multipart text and HTML emails
+// through an SMTP server, for deployments without SES.
+type SMTPService struct {
+	config    SMTPConfig
+	sender    *mail.Address
+	templates *Templates
+	logger    *slog.Logger
+}
+
+// NewSMTPService creates a new SMTP email service.
+func NewSMTPService(config SMTPConfig, logger *slog.Logger) (*SMTPService, error) {
+	config = config.Normalize()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	sender, _ := mail.ParseAddress(config.Sender)
+
+	return &SMTPService{
+		config:    config,
+		sender:    sender,
+		templates: DefaultTemplates(),
+		logger:    logger,
+	}, nil
+}
+
+// WithTemplates renders emails with templates instead of the embedded ones.
+func (s *SMTPService) WithTemplates(templates *Templates) *SMTPService {
+	s.templates = templates
+	return s
+}
+
+// SendAPIKey sends an API key and its signing secret to the specified email address.
+func (s *SMTPService) SendAPIKey(ctx context.Context, to Recipient, apiKey, signingSecret string) error {
+	if err := s.send(ctx, to, TemplateAPIKey, apiKeyData{newTemplateData(to), apiKey, signingSecret}); err != nil {
+		return err
+	}
+
+	s.logger.Info("API key email sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendKeyExpiryWarning tells an owner that one of their API keys expires soon.
+func (s *SMTPService) SendKeyExpiryWarning(ctx context.Context, to Recipient, key KeyExpiry) error {
+	if err := s.send(ctx, to, TemplateKeyExpiry, keyExpiryData{newTemplateData(to), key}); err != nil {
+		return err
+	}
+
+	s.logger.Info("key expiry email sent", "toEmail", to.Email, "ownerId", to.OwnerID, "keyId", key.KeyID)
+	return nil
+}
+
+// SendAuthFailureAlert tells an owner about repeated failed authentication attempts.
+func (s *SMTPService) SendAuthFailureAlert(ctx context.Context, to Recipient, alert AuthFailureAlert) error {
+	if err := s.send(ctx, to, TemplateAuthFailure, authFailureData{newTemplateData(to), alert}); err != nil {
+		return err
+	}
+
+	s.logger.Info("auth failure alert sent", "toEmail", to.Email, "ownerId", to.OwnerID, "failures", alert.Failures)
+	return nil
+}
+
+// SendVerification sends a new owner the token that verifies their email address.
+func (s *SMTPService) SendVerification(ctx context.Context, to Recipient, token string, expiresAt time.Time) error {
+	if err := s.send(ctx, to, TemplateVerification, tokenData{newTemplateData(to), token, expiresAt}); err != nil {
+		return err
+	}
+
+	s.logger.Info("verification email sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendRecovery sends an owner the token that confirms an API key recovery.
+func (s *SMTPService) SendRecovery(ctx context.Context, to Recipient, token string, expiresAt time.Time) error {
+	if err := s.send(ctx, to, TemplateRecovery, tokenData{newTemplateData(to), token, expiresAt}); err != nil {
+		return err
+	}
+
+	s.logger.Info("recovery email sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendFlapAlert tells an owner that a location's IP changes are being dampened.
+func (s *SMTPService) SendFlapAlert(ctx context.Context, to Recipient, alert FlapAlert) error {
+	if err := s.send(ctx, to, TemplateFlapAlert, flapAlertData{newTemplateData(to), alert}); err != nil {
+		return err
+	}
+
+	s.logger.Info("flap alert sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", alert.Location)
+	return nil
+}
+
+// SendIPChangeNotice tells an owner that a location's IP address changed.
+func (s *SMTPService) SendIPChangeNotice(ctx context.Context, to Recipient, notice IPChangeNotice) error {
+	if err := s.send(ctx, to, TemplateIPChange, ipChangeData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("IP change notice sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", notice.Location, "changes", len(notice.Changes))
+	return nil
+}
+
+// SendStaleLocationAlert tells an owner that a location stopped checking in.
+func (s *SMTPService) SendStaleLocationAlert(ctx context.Context, to Recipient, alert StaleLocationAlert) error {
+	if err := s.send(ctx, to, TemplateStaleLocation, staleLocationData{newTemplateData(to), alert}); err != nil {
+		return err
+	}
+
+	s.logger.Info("stale location alert sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", alert.Location)
+	return nil
+}
+
+// SendKeyRotatedNotice tells an owner that their primary API key was rotated.
+func (s *SMTPService) SendKeyRotatedNotice(ctx context.Context, to Recipient, notice KeyRotatedNotice) error {
+	if err := s.send(ctx, to, TemplateKeyRotated, keyRotatedData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("key rotated notice sent", "toEmail", to.Email, "ownerId", to.OwnerID)
+	return nil
+}
+
+// SendRecoveryRequestedNotice tells an owner that API key recovery was requested.
+func (s *SMTPService) SendRecoveryRequestedNotice(ctx context.Context, to Recipient, notice RecoveryRequestedNotice) error {
+	if err := s.send(ctx, to, TemplateRecoveryRequested, recoveryRequestedData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("recovery requested notice sent", "toEmail", to.Email, "ownerId", to.OwnerID, "emailMatched", notice.EmailMatched)
+	return nil
+}
+
+// SendACMEChallengeNotice tells an owner that an ACME challenge was created.
+func (s *SMTPService) SendACMEChallengeNotice(ctx context.Context, to Recipient, notice ACMEChallengeNotice) error {
+	if err := s.send(ctx, to, TemplateACMEChallenge, acmeChallengeData{newTemplateData(to), notice}); err != nil {
+		return err
+	}
+
+	s.logger.Info("ACME challenge notice sent", "toEmail", to.Email, "ownerId", to.OwnerID, "location", notice.Location)
+	return nil
+}
+
+// send renders the named template and delivers it in one SMTP session.
+func (s *SMTPService) send(ctx context.Context, to Recipient, name string, data any) error {
+	msg, err := s.templates.render(name, to.Locale, data)
+	if err != nil {
+		s.logger.Error("failed to render email", "error", err, "template", name, "locale", to.Locale)
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	if err := s.deliver(ctx, to.Email, msg); err != nil {
+		s.logger.Error("failed to send email", "error", err, "toEmail", to.Email, "host", s.config.Host)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTPService) deliver(ctx context.Context, toEmail string, msg message) error {
+	to, err := mail.ParseAddress(toEmail)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	data, err := buildMIMEMessage(s.sender, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.config.Security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if auth := s.auth(); auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("SMTP server does not support authentication")
+		}
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.sender.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *SMTPService) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	if s.config.Security == SMTPTLS {
+		dialer := &tls.Dialer{Config: s.tlsConfig()}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (s *SMTPService) tlsConfig() *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.config.TLSConfig != nil {
+		config = s.config.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = s.config.Host
+	}
+	return config
+}
+
+// auth returns the configured authentication, or nil for none. Both mechanisms
+// refuse to send credentials over an unencrypted connection to another host.
+func (s *SMTPService) auth() smtp.Auth {
+	switch s.config.Auth {
+	case SMTPAuthPlain:
+		return smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	case SMTPAuthLogin:
+		return &loginAuth{username: s.config.Username, password: s.config.Password, host: s.config.Host}
+	default:
+		return nil
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp lacks but some
+// servers still require.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	challenge := strings.ToLower(string(fromServer))
+	switch {
+	case strings.HasPrefix(challenge, "user"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(challenge, "pass"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// buildMIMEMessage renders a message as a multipart/alternative email with a
+// quoted-printable text part and, if the message has one, an HTML part.
+func buildMIMEMessage(from, to *mail.Address, msg message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", from.String())
+	header.Set("To", to.String())
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", now.Format(time.RFC1123Z))
+	header.Set("Message-Id", messageID(from.Address))
+	header.Set("Mime-Version", "1.0")
+	header.Set("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+
+	var out bytes.Buffer
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-Id", "Mime-Version", "Content-Type"} {
+		fmt.Fprintf(&out, "%s: %s\r\n", key, header.Get(key))
+	}
+	out.WriteString("\r\n")
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	}
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID in the sender's domain.
+func messageID(sender string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at+1:]
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// Ensure SMTPService implements Service.
+var _ Service = (*SMTPService)(nil)
@@ -0,0 +1,516 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// smtpStandIn is a minimal in-process SMTP server that records what it receives.
+type smtpStandIn struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	implicitTLS bool
+	startTLS    bool
+	username    string
+	password    string
+
+	mu       sync.Mutex
+	received []receivedEmail
+}
+
+type receivedEmail struct {
+	From     string
+	To       []string
+	Data     string
+	AuthMech string
+	TLS      bool
+}
+
+func newSMTPStandIn(t *testing.T, cert tls.Certificate, configure func(*smtpStandIn)) *smtpStandIn {
+	t.Helper()
+
+	s := &smtpStandIn{
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		startTLS:  true,
+	}
+	if configure != nil {
+		configure(s)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	if s.implicitTLS {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	s.listener = listener
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpStandIn) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) messages() []receivedEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedEmail(nil), s.received...)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tp := textproto.NewConn(conn)
+	_, isTLS := conn.(*tls.Conn)
+	var current receivedEmail
+
+	_ = tp.PrintfLine("220 localhost ESMTP stand-in")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"localhost"}
+			if s.startTLS && !isTLS {
+				lines = append(lines, "STARTTLS")
+			}
+			if s.username != "" {
+				lines = append(lines, "AUTH PLAIN LOGIN")
+			}
+			lines = append(lines, "8BITMIME")
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				_ = tp.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			isTLS = true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			var username, password string
+			switch strings.ToUpper(mech) {
+			case "PLAIN":
+				decoded, _ := base64.StdEncoding.DecodeString(initial)
+				fields := strings.Split(string(decoded), "\x00")
+				if len(fields) == 3 {
+					username, password = fields[1], fields[2]
+				}
+			case "LOGIN":
+				_ = tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+				u, _ := tp.ReadLine()
+				_ = tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+				p, _ := tp.ReadLine()
+				ub, _ := base64.StdEncoding.DecodeString(u)
+				pb, _ := base64.StdEncoding.DecodeString(p)
+				username, password = string(ub), string(pb)
+			default:
+				_ = tp.PrintfLine("504 unrecognized authentication type")
+				continue
+			}
+			if username != s.username || password != s.password {
+				_ = tp.PrintfLine("535 authentication credentials invalid")
+				continue
+			}
+			current.AuthMech = strings.ToUpper(mech)
+			_ = tp.PrintfLine("235 authentication successful")
+		case "MAIL":
+			current.From = angleAddr(arg)
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			current.To = append(current.To, angleAddr(arg))
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			current.TLS = isTLS
+			s.mu.Lock()
+			s.received = append(s.received, current)
+			s.mu.Unlock()
+			current = receivedEmail{AuthMech: current.AuthMech}
+			_ = tp.PrintfLine("250 queued")
+		case "RSET", "NOOP":
+			_ = tp.PrintfLine("250 ok")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// angleAddr returns the address between the angle brackets of a MAIL or RCPT argument.
+func angleAddr(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1 and a
+// pool that trusts it.
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp stand-in"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// parseMultipart returns the decoded parts of a received email by content type.
+func parseMultipart(t *testing.T, data string) (*mail.Message, map[string]string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	assert.NilError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NilError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NilError(t, err)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(part)
+		assert.NilError(t, err)
+		parts[contentType] = string(body)
+	}
+	return msg, parts
+}
+
+func TestSMTPService_SendAPIKey(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+
+	tests := []struct {
+		name      string
+		configure func(*smtpStandIn)
+		security  string
+		auth      string
+		wantMech  string
+	}{
+		{
+			name:      "STARTTLS with PLAIN auth",
+			configure: func(s *smtpStandIn) { s.username, s.password = "ddns", "secret" },
+			security:  SMTPStartTLS,
+			auth:      SMTPAuthPlain,
+			wantMech:  "PLAIN",
+		},
+		{
+			name:      "STARTTLS with LOGIN auth",
+			configure: func(s *smtpStandIn) { s.username, s.password = "ddns", "secret" },
+			security:  SMTPStartTLS,
+			auth:      SMTPAuthLogin,
+			wantMech:  "LOGIN",
+		},
+		{
+			name: "implicit TLS with PLAIN auth",
+			configure: func(s *smtpStandIn) {
+				s.implicitTLS = true
+				s.username, s.password = "ddns", "secret"
+			},
+			security: SMTPTLS,
+			auth:     SMTPAuthPlain,
+			wantMech: "PLAIN",
+		},
+		{
+			name:      "unencrypted without auth",
+			configure: func(s *smtpStandIn) { s.startTLS = false },
+			security:  SMTPNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPStandIn(t, cert, tt.configure)
+
+			config := SMTPConfig{
+				Host:      "127.0.0.1",
+				Port:      server.port(),
+				Security:  tt.security,
+				Auth:      tt.auth,
+				Sender:    "DDNS Service <ddns@example.com>",
+				TLSConfig: &tls.Config{RootCAs: pool},
+			}
+			if tt.auth != "" {
+				config.Username, config.Password = "ddns", "secret"
+			}
+			svc, err := NewSMTPService(config, newTestLogger())
+			assert.NilError(t, err)
+
+			err = svc.SendAPIKey(context.Background(), Recipient{Email: "owner@example.com", OwnerID: "test-owner"}, "ddns_sk_test123", "ddns_ss_secret")
+			assert.NilError(t, err)
+
+			received := server.messages()
+			assert.Equal(t, 1, len(received))
+			got := received[0]
+			assert.Equal(t, "ddns@example.com", got.From)
+			assert.DeepEqual(t, []string{"owner@example.com"}, got.To)
+			assert.Equal(t, tt.wantMech, got.AuthMech)
+			assert.Equal(t, tt.security != SMTPNone, got.TLS)
+
+			msg, parts := parseMultipart(t, got.Data)
+			assert.Equal(t, `"DDNS Service" <ddns@example.com>`, msg.Header.Get("From"))
+			assert.Equal(t, "<owner@example.com>", msg.Header.Get("To"))
+			assert.Equal(t, EmailSubject, msg.Header.Get("Subject"))
+			assert.Assert(t, strings.HasSuffix(msg.Header.Get("Message-Id"), "@example.com>"))
+			assert.Assert(t, strings.Contains(parts["text/plain"], "ddns_sk_test123"))
+			assert.Assert(t, strings.Contains(parts["text/html"], "ddns_sk_test123"))
+		})
+	}
+}
+
+func TestSMTPService_TextOnlyMessage(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+	server := newSMTPStandIn(t, cert, nil)
+
+	svc, err := NewSMTPService(SMTPConfig{
+		Host:      "127.0.0.1",
+		Port:      server.port(),
+		TLSConfig: &tls.Config{RootCAs: pool},
+	}, newTestLogger())
+	assert.NilError(t, err)
+
+	err = svc.deliver(context.Background(), "owner@example.com", message{Subject: "Ünïcode subject", Text: "plain only"})
+	assert.NilError(t, err)
+
+	received := server.messages()
+	assert.Equal(t, 1, len(received))
+	msg, parts := parseMultipart(t, received[0].Data)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NilError(t, err)
+	assert.Equal(t, "Ünïcode subject", subject)
+	assert.DeepEqual(t, map[string]string{"text/plain": "plain only"}, parts)
+}
+
+func TestSMTPService_Errors(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+
+	t.Run("wrong password", func(t *testing.T) {
+		server := newSMTPStandIn(t, cert, func(s *smtpStandIn) { s.username, s.password = "ddns", "secret" })
+
+		svc, err := NewSMTPService(SMTPConfig{
+			Host:      "127.0.0.1",
+			Port:      server.port(),
+			Username:  "ddns",
+			Password:  "wrong",
+			TLSConfig: &tls.Config{RootCAs: pool},
+		}, newTestLogger())
+		assert.NilError(t, err)
+
+		err = svc.SendAPIKey(context.Background(), Recipient{Email: "owner@example.com", OwnerID: "test-owner"}, "ddns_sk_test123", "ddns_ss_secret")
+
+		assert.ErrorContains(t, err, "SMTP authentication failed")
+		assert.Equal(t, 0, len(server.messages()))
+	})
+
+	t.Run("STARTTLS not offered", func(t *testing.T) {
+		server := newSMTPStandIn(t, cert, func(s *smtpStandIn) { s.startTLS = false })
+
+		svc, err := NewSMTPService(SMTPConfig{Host: "127.0.0.1", Port: server.port()}, newTestLogger())
+		assert.NilError(t, err)
+
+		err = svc.SendAPIKey(context.Background(), Recipient{Email: "owner@example.com", OwnerID: "test-owner"}, "ddns_sk_test123", "ddns_ss_secret")
+
+		assert.ErrorContains(t, err, "does not support STARTTLS")
+		assert.Equal(t, 0, len(server.messages()))
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		server := newSMTPStandIn(t, cert, nil)
+
+		svc, err := NewSMTPService(SMTPConfig{Host: "127.0.0.1", Port: server.port()}, newTestLogger())
+		assert.NilError(t, err)
+
+		err = svc.SendAPIKey(context.Background(), Recipient{Email: "owner@example.com", OwnerID: "test-owner"}, "ddns_sk_test123", "ddns_ss_secret")
+
+		assert.ErrorContains(t, err, "STARTTLS failed")
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+
+		svc, err := NewSMTPService(SMTPConfig{Host: "127.0.0.1", Port: port}, newTestLogger())
+		assert.NilError(t, err)
+
+		err = svc.SendAPIKey(context.Background(), Recipient{Email: "owner@example.com", OwnerID: "test-owner"}, "ddns_sk_test123", "ddns_ss_secret")
+
+		assert.ErrorContains(t, err, "failed to send email")
+	})
+
+	t.Run("invalid recipient", func(t *testing.T) {
+		svc, err := NewSMTPService(SMTPConfig{Host: "127.0.0.1", Port: 2525}, newTestLogger())
+		assert.NilError(t, err)
+
+		err = svc.SendAPIKey(context.Background(), Recipient{Email: "owner@example.com\r\nBcc: x@example.com", OwnerID: "test-owner"}, "ddns_sk_test123", "ddns_ss_secret")
+
+		assert.ErrorContains(t, err, "invalid recipient")
+	})
+}
+
+func TestSMTPConfig_Normalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SMTPConfig
+		want   SMTPConfig
+	}{
+		{
+			name:   "defaults",
+			config: SMTPConfig{Host: " smtp.example.com "},
+			want:   SMTPConfig{Host: "smtp.example.com", Port: 587, Security: SMTPStartTLS, Sender: DefaultSenderEmail, Timeout: DefaultSMTPTimeout},
+		},
+		{
+			name:   "implicit TLS port",
+			config: SMTPConfig{Host: "smtp.example.com", Security: "TLS"},
+			want:   SMTPConfig{Host: "smtp.example.com", Port: 465, Security: SMTPTLS, Sender: DefaultSenderEmail, Timeout: DefaultSMTPTimeout},
+		},
+		{
+			name:   "unencrypted port",
+			config: SMTPConfig{Host: "localhost", Security: SMTPNone},
+			want:   SMTPConfig{Host: "localhost", Port: 25, Security: SMTPNone, Sender: DefaultSenderEmail, Timeout: DefaultSMTPTimeout},
+		},
+		{
+			name:   "username implies PLAIN",
+			config: SMTPConfig{Host: "smtp.example.com", Port: 2525, Username: "u", Password: "p", Sender: "ddns@example.com"},
+			want:   SMTPConfig{Host: "smtp.example.com", Port: 2525, Security: SMTPStartTLS, Auth: SMTPAuthPlain, Username: "u", Password: "p", Sender: "ddns@example.com", Timeout: DefaultSMTPTimeout},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, tt.want, tt.config.Normalize())
+		})
+	}
+}
+
+func TestSMTPConfig_Validate(t *testing.T) {
+	valid := SMTPConfig{Host: "smtp.example.com"}.Normalize()
+
+	tests := []struct {
+		name    string
+		modify  func(*SMTPConfig)
+		wantErr string
+	}{
+		{name: "valid", modify: func(c *SMTPConfig) {}},
+		{name: "missing host", modify: func(c *SMTPConfig) { c.Host = "" }, wantErr: "host is required"},
+		{name: "bad port", modify: func(c *SMTPConfig) { c.Port = 70000 }, wantErr: "invalid SMTP port"},
+		{name: "bad security", modify: func(c *SMTPConfig) { c.Security = "ssl" }, wantErr: "SMTP security must be"},
+		{name: "bad auth", modify: func(c *SMTPConfig) { c.Auth = "cram-md5" }, wantErr: "SMTP auth must be"},
+		{name: "auth without password", modify: func(c *SMTPConfig) { c.Auth, c.Username = SMTPAuthLogin, "u" }, wantErr: "needs a username and password"},
+		{name: "bad sender", modify: func(c *SMTPConfig) { c.Sender = "not an address" }, wantErr: "invalid sender"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+
+			err := config.Validate()
+
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoginAuth(t *testing.T) {
+	auth := &loginAuth{username: "ddns", password: "secret", host: "smtp.example.com"}
+
+	_, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: false})
+	assert.ErrorContains(t, err, "unencrypted connection")
+
+	mech, resp, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true})
+	assert.NilError(t, err)
+	assert.Equal(t, "LOGIN", mech)
+	assert.Assert(t, resp == nil)
+
+	user, err := auth.Next([]byte("Username:"), true)
+	assert.NilError(t, err)
+	assert.Equal(t, "ddns", string(user))
+
+	pass, err := auth.Next([]byte("Password:"), true)
+	assert.NilError(t, err)
+	assert.Equal(t, "secret", string(pass))
+
+	_, err = auth.Next([]byte("Token:"), true)
+	assert.ErrorContains(t, err, "unexpected LOGIN challenge")
+}
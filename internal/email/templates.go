@@ -0,0 +1,333 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+// DefaultLocale is the locale of the embedded templates. It is used for owners
+// without a locale, and for any email their locale has no template for.
+const DefaultLocale = "en"
+
+// Template names, one per email the service sends. Each locale directory holds
+// <name>.txt, a text/template that also defines the "subject" template, and
+// optionally <name>.html, an html/template for the HTML part. Emails without
+// an HTML template are sent with layout.html, which wraps the text part.
+const (
+	TemplateAPIKey        = "api-key"
+	TemplateKeyExpiry     = "key-expiry"
+	TemplateAuthFailure   = "auth-failure"
+	TemplateVerification  = "verification"
+	TemplateRecovery      = "recovery"
+	TemplateFlapAlert     = "flap-alert"
+	TemplateIPChange      = "ip-change"
+	TemplateStaleLocation = "stale-location"
+
+	// Security notices.
+	TemplateKeyRotated        = "key-rotated"
+	TemplateRecoveryRequested = "recovery-requested"
+	TemplateACMEChallenge     = "acme-challenge"
+
+	layoutTemplate = "layout"
+)
+
+// templateNames lists every email template.
+var templateNames = []string{
+	TemplateAPIKey,
+	TemplateKeyExpiry,
+	TemplateAuthFailure,
+	TemplateVerification,
+	TemplateRecovery,
+	TemplateFlapAlert,
+	TemplateIPChange,
+	TemplateStaleLocation,
+	TemplateKeyRotated,
+	TemplateRecoveryRequested,
+	TemplateACMEChallenge,
+}
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// templateFuncs are available to every template.
+var templateFuncs = map[string]any{
+	"formatTime": formatTime,
+	"duration":   describeDuration,
+}
+
+// Templates renders emails from per-locale templates.
+type Templates struct {
+	// locales maps a normalized locale to its templates by name.
+	locales map[string]map[string]*emailTemplate
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var defaultTemplates = sync.OnceValues(func() (*Templates, error) {
+	return loadTemplates(nil)
+})
+
+// DefaultTemplates returns the embedded templates.
+func DefaultTemplates() *Templates {
+	t, err := defaultTemplates()
+	if err != nil {
+		panic(fmt.Sprintf("email: invalid embedded templates: %v", err))
+	}
+	return t
+}
+
+// LoadTemplates returns the embedded templates overridden by the ones in dir,
+// which has the same <locale>/<name> layout. A file in dir replaces the
+// embedded file of the same name, and a new locale directory adds a locale.
+// Every template is rendered with sample data, so mistakes surface here rather
+// than when an email is sent.
+func LoadTemplates(dir string) (*Templates, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("email template directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("email template directory: %s is not a directory", dir)
+	}
+	return loadTemplates(os.DirFS(dir))
+}
+
+// loadTemplates parses the embedded templates, overridden by override if it
+// isn't nil.
+func loadTemplates(override fs.FS) (*Templates, error) {
+	embedded, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	layers := []fs.FS{embedded}
+	if override != nil {
+		layers = append(layers, override)
+	}
+
+	// Locale directory names per layer, by normalized locale.
+	dirs := make(map[string][]string)
+	for i, layer := range layers {
+		entries, err := fs.ReadDir(layer, ".")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			locale := normalizeLocale(entry.Name())
+			if dirs[locale] == nil {
+				dirs[locale] = make([]string, len(layers))
+			}
+			dirs[locale][i] = entry.Name()
+		}
+	}
+
+	t := &Templates{locales: make(map[string]map[string]*emailTemplate)}
+	for locale, localeDirs := range dirs {
+		templates := make(map[string]*emailTemplate)
+
+		for _, name := range append(slices.Clone(templateNames), layoutTemplate) {
+			tmpl := &emailTemplate{}
+
+			if name != layoutTemplate {
+				src, path, err := readLayered(layers, localeDirs, name+".txt")
+				if err != nil {
+					return nil, err
+				}
+				if src != nil {
+					tmpl.text, err = texttemplate.New(name).Funcs(templateFuncs).Parse(string(src))
+					if err != nil {
+						return nil, fmt.Errorf("%s: %w", path, err)
+					}
+					if tmpl.text.Lookup("subject") == nil {
+						return nil, fmt.Errorf("%s: missing subject template", path)
+					}
+				}
+			}
+
+			src, path, err := readLayered(layers, localeDirs, name+".html")
+			if err != nil {
+				return nil, err
+			}
+			if src != nil {
+				tmpl.html, err = htmltemplate.New(name).Funcs(templateFuncs).Parse(string(src))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+
+			if tmpl.text != nil || tmpl.html != nil {
+				templates[name] = tmpl
+			}
+		}
+
+		t.locales[locale] = templates
+	}
+
+	for _, name := range templateNames {
+		if t.lookup(DefaultLocale, name) == nil {
+			return nil, fmt.Errorf("missing %s/%s.txt", DefaultLocale, name)
+		}
+	}
+	if t.locales[DefaultLocale][layoutTemplate] == nil {
+		return nil, fmt.Errorf("missing %s/%s.html", DefaultLocale, layoutTemplate)
+	}
+
+	for locale := range t.locales {
+		for _, name := range templateNames {
+			if _, err := t.render(name, locale, sampleData(name)); err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", locale, name, err)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// readLayered returns the file from the last layer that has it, and its path
+// for error messages. It returns nil if no layer has the file.
+func readLayered(layers []fs.FS, dirs []string, file string) ([]byte, string, error) {
+	for i := len(layers) - 1; i >= 0; i-- {
+		if dirs[i] == "" {
+			continue
+		}
+		path := dirs[i] + "/" + file
+		src, err := fs.ReadFile(layers[i], path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, path, err
+		}
+		return src, path, nil
+	}
+	return nil, "", nil
+}
+
+// lookup returns the named text template of a locale, or nil.
+func (t *Templates) lookup(locale, name string) *emailTemplate {
+	tmpl := t.locales[locale][name]
+	if tmpl == nil || tmpl.text == nil {
+		return nil
+	}
+	return tmpl
+}
+
+// Locales lists the locales that have templates.
+func (t *Templates) Locales() []string {
+	return slices.Sorted(maps.Keys(t.locales))
+}
+
+// render renders the named email in the closest locale that has it: the
+// locale itself, then its language, then DefaultLocale.
+func (t *Templates) render(name, locale string, data any) (message, error) {
+	for _, candidate := range localeCandidates(locale) {
+		tmpl := t.lookup(candidate, name)
+		if tmpl == nil {
+			continue
+		}
+
+		var subject, text bytes.Buffer
+		if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+			return message{}, err
+		}
+		if err := tmpl.text.Execute(&text, data); err != nil {
+			return message{}, err
+		}
+		msg := message{
+			Subject: strings.TrimSpace(subject.String()),
+			Text:    text.String(),
+		}
+
+		var html bytes.Buffer
+		if tmpl.html != nil {
+			if err := tmpl.html.Execute(&html, data); err != nil {
+				return message{}, err
+			}
+		} else if err := t.layout(candidate).Execute(&html, msg); err != nil {
+			return message{}, err
+		}
+		msg.HTML = html.String()
+
+		return msg, nil
+	}
+	return message{}, fmt.Errorf("no %s template", name)
+}
+
+// layout returns the HTML layout of a locale, falling back to DefaultLocale's.
+func (t *Templates) layout(locale string) *htmltemplate.Template {
+	if tmpl := t.locales[locale][layoutTemplate]; tmpl != nil && tmpl.html != nil {
+		return tmpl.html
+	}
+	return t.locales[DefaultLocale][layoutTemplate].html
+}
+
+// normalizeLocale lowercases a locale and uses hyphens, e.g. "pt_BR" becomes
+// "pt-br". Locale directories and owner locales are matched in this form.
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+// localeCandidates returns the locales to try for locale, most specific first.
+func localeCandidates(locale string) []string {
+	locale = normalizeLocale(locale)
+	var candidates []string
+	for locale != "" {
+		candidates = append(candidates, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	if !slices.Contains(candidates, DefaultLocale) {
+		candidates = append(candidates, DefaultLocale)
+	}
+	return candidates
+}
+
+// formatTime formats a time, or a time pointer, in UTC.
+func formatTime(v any) string {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UTC().Format(time.RFC1123)
+	case *time.Time:
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC1123)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// describeDuration formats whole hours and minutes in words, e.g. "10 minutes".
+func describeDuration(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "1 hour"
+	case d == time.Minute:
+		return "1 minute"
+	case d > 0 && d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	case d > 0 && d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", int(d/time.Minute))
+	default:
+		return d.String()
+	}
+}
@@ -0,0 +1,219 @@
+package email
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"gotest.tools/assert"
+)
+
+func TestDefaultTemplates_RenderEveryEmail(t *testing.T) {
+	subjects := map[string]string{
+		TemplateAPIKey:        EmailSubject,
+		TemplateKeyExpiry:     KeyExpirySubject,
+		TemplateAuthFailure:   AuthFailureSubject,
+		TemplateVerification:  VerificationSubject,
+		TemplateRecovery:      RecoverySubject,
+		TemplateFlapAlert:     FlapAlertSubject,
+		TemplateIPChange:      IPChangeSubject,
+		TemplateStaleLocation: StaleLocationSubject,
+
+		TemplateKeyRotated:        KeyRotatedSubject,
+		TemplateRecoveryRequested: RecoveryRequestedSubject,
+		TemplateACMEChallenge:     ACMEChallengeSubject,
+	}
+
+	for _, name := range templateNames {
+		t.Run(name, func(t *testing.T) {
+			msg := renderEmail(t, name, sampleData(name))
+
+			assert.Equal(t, subjects[name], msg.Subject)
+			assert.Assert(t, strings.HasPrefix(msg.Text, msg.Subject+"\n\nOwner ID: my-home-lab\n"))
+			assert.Assert(t, strings.HasSuffix(msg.Text, "---\nDDNS Service\n"+APIEndpoint+"\n"))
+			assert.Assert(t, strings.HasPrefix(msg.HTML, "<!DOCTYPE html>"))
+			assert.Assert(t, strings.Contains(msg.HTML, "my-home-lab"))
+		})
+	}
+}
+
+func TestDefaultTemplates_HTMLLayoutEscapesText(t *testing.T) {
+	data := staleLocationData{testTemplateData, StaleLocationAlert{Location: "<script>", StaleAfter: time.Hour}}
+
+	msg := renderEmail(t, TemplateStaleLocation, data)
+
+	assert.Assert(t, strings.Contains(msg.Text, "Location: <script> ("))
+	assert.Assert(t, strings.Contains(msg.HTML, "Location: &lt;script&gt; ("))
+	assert.Assert(t, !strings.Contains(msg.HTML, "<script>"))
+}
+
+func TestTemplates_Locales(t *testing.T) {
+	templates, err := loadTemplates(fstest.MapFS{
+		"de/verification.txt": {Data: []byte(`{{define "subject"}}Bestätigen Sie Ihr Konto{{end}}Owner: {{.OwnerID}}, gültig bis {{formatTime .ExpiresAt}}`)},
+		"de/layout.html":      {Data: []byte(`<html lang="de"><pre>{{.Text}}</pre></html>`)},
+		"pt_BR/recovery.txt":  {Data: []byte(`{{define "subject"}}Confirme a recuperação{{end}}{{.Token}}`)},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"de", "en", "pt-br"}, templates.Locales())
+
+	expiresAt := time.Date(2025, 1, 16, 10, 0, 0, 0, time.UTC)
+	data := tokenData{testTemplateData, "payload.signature", expiresAt}
+
+	tests := []struct {
+		name        string
+		template    string
+		locale      string
+		wantSubject string
+		wantHTML    string
+	}{
+		{name: "exact locale", template: TemplateVerification, locale: "de", wantSubject: "Bestätigen Sie Ihr Konto", wantHTML: `<html lang="de">`},
+		{name: "region falls back to language", template: TemplateVerification, locale: "de-AT", wantSubject: "Bestätigen Sie Ihr Konto", wantHTML: `<html lang="de">`},
+		{name: "missing template falls back to default", template: TemplateRecovery, locale: "de", wantSubject: RecoverySubject, wantHTML: "<!DOCTYPE html>"},
+		{name: "unknown locale falls back to default", template: TemplateVerification, locale: "fr", wantSubject: VerificationSubject, wantHTML: "<!DOCTYPE html>"},
+		{name: "no locale", template: TemplateVerification, wantSubject: VerificationSubject, wantHTML: "<!DOCTYPE html>"},
+		{name: "normalized directory name", template: TemplateRecovery, locale: "pt-BR", wantSubject: "Confirme a recuperação", wantHTML: "<!DOCTYPE html>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := templates.render(tt.template, tt.locale, data)
+
+			assert.NilError(t, err)
+			assert.Equal(t, tt.wantSubject, msg.Subject)
+			assert.Assert(t, strings.HasPrefix(msg.HTML, tt.wantHTML), msg.HTML)
+		})
+	}
+
+	msg, err := templates.render(TemplateVerification, "de", data)
+	assert.NilError(t, err)
+	assert.Equal(t, "Owner: test-owner, gültig bis Thu, 16 Jan 2025 10:00:00 UTC", msg.Text)
+}
+
+func TestTemplates_OverrideDefaultLocale(t *testing.T) {
+	templates, err := loadTemplates(fstest.MapFS{
+		"en/api-key.txt": {Data: []byte(`{{define "subject"}}Welcome to Example DDNS{{end}}Key: {{.APIKey}}`)},
+	})
+	assert.NilError(t, err)
+
+	msg, err := templates.render(TemplateAPIKey, "", apiKeyData{testTemplateData, "ddns_sk_abc123", "ddns_ss_abc123"})
+
+	assert.NilError(t, err)
+	assert.Equal(t, "Welcome to Example DDNS", msg.Subject)
+	assert.Equal(t, "Key: ddns_sk_abc123", msg.Text)
+	// The embedded HTML template still applies until it is overridden too.
+	assert.Assert(t, strings.Contains(msg.HTML, `<div class="key-box">ddns_sk_abc123</div>`))
+
+	// Other emails keep their embedded templates.
+	msg, err = templates.render(TemplateRecovery, "", sampleData(TemplateRecovery))
+	assert.NilError(t, err)
+	assert.Equal(t, RecoverySubject, msg.Subject)
+}
+
+func TestLoadTemplates_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name:    "parse error",
+			files:   fstest.MapFS{"en/recovery.txt": {Data: []byte(`{{define "subject"}}x{{end}}{{if}}`)}},
+			wantErr: "en/recovery.txt",
+		},
+		{
+			name:    "missing subject",
+			files:   fstest.MapFS{"de/recovery.txt": {Data: []byte(`no subject`)}},
+			wantErr: "de/recovery.txt: missing subject template",
+		},
+		{
+			name:    "unknown field",
+			files:   fstest.MapFS{"de/recovery.txt": {Data: []byte(`{{define "subject"}}x{{end}}{{.Nonce}}`)}},
+			wantErr: "de/recovery",
+		},
+		{
+			name:    "unknown function",
+			files:   fstest.MapFS{"de/recovery.txt": {Data: []byte(`{{define "subject"}}x{{end}}{{upper .Token}}`)}},
+			wantErr: `function "upper" not defined`,
+		},
+		{
+			name:    "HTML parse error",
+			files:   fstest.MapFS{"de/layout.html": {Data: []byte(`{{.Text`)}},
+			wantErr: "de/layout.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTemplates(tt.files)
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadTemplates_Directory(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "es"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "es", "stale-location.txt"),
+		[]byte(`{{define "subject"}}Su ubicación dejó de reportarse{{end}}{{.Alert.Location}} sin reportarse desde {{formatTime .Alert.LastSeenAt}}`), 0o644))
+
+	templates, err := LoadTemplates(dir)
+	assert.NilError(t, err)
+
+	msg, err := templates.render(TemplateStaleLocation, "es-MX", sampleData(TemplateStaleLocation))
+	assert.NilError(t, err)
+	assert.Equal(t, "Su ubicación dejó de reportarse", msg.Subject)
+	assert.Equal(t, "cabin sin reportarse desde Wed, 15 Jan 2025 10:00:00 UTC", msg.Text)
+
+	_, err = LoadTemplates(filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, err, "email template directory")
+
+	_, err = LoadTemplates(filepath.Join(dir, "es", "stale-location.txt"))
+	assert.ErrorContains(t, err, "is not a directory")
+}
+
+func TestLocaleCandidates(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{locale: "", want: []string{"en"}},
+		{locale: "en", want: []string{"en"}},
+		{locale: "en-GB", want: []string{"en-gb", "en"}},
+		{locale: "de", want: []string{"de", "en"}},
+		{locale: "pt_BR", want: []string{"pt-br", "pt", "en"}},
+		{locale: "zh-Hant-TW", want: []string{"zh-hant-tw", "zh-hant", "zh", "en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			assert.DeepEqual(t, tt.want, localeCandidates(tt.locale))
+		})
+	}
+}
+
+func TestSESService_RendersRecipientLocale(t *testing.T) {
+	templates, err := loadTemplates(fstest.MapFS{
+		"de/api-key.txt": {Data: []byte(`{{define "subject"}}Ihr API-Schlüssel{{end}}{{.APIKey}}`)},
+	})
+	assert.NilError(t, err)
+
+	var subject string
+	client := &mockSESClient{
+		sendEmailFunc: func(ctx context.Context, params *ses.SendEmailInput, optFns ...func(*ses.Options)) (*ses.SendEmailOutput, error) {
+			subject = *params.Message.Subject.Data
+			return &ses.SendEmailOutput{}, nil
+		},
+	}
+	svc := NewSESService(client, newTestLogger()).WithTemplates(templates)
+
+	err = svc.SendAPIKey(context.Background(), Recipient{Email: "user@example.com", OwnerID: "test-owner", Locale: "de-DE"}, "ddns_sk_abc123", "ddns_ss_secret")
+
+	assert.NilError(t, err)
+	assert.Equal(t, "Ihr API-Schlüssel", subject)
+}
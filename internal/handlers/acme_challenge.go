@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"time"
 
@@ -12,8 +13,11 @@ import (
 	"github.com/grocky/ddns-service/internal/auth"
 	"github.com/grocky/ddns-service/internal/dns"
 	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/ratelimit"
 	"github.com/grocky/ddns-service/internal/repository"
 	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/webhook"
 )
 
 const (
@@ -22,12 +26,17 @@ const (
 )
 
 // CreateACMEChallenge handles POST /acme-challenge requests.
-// It creates a TXT record for DNS-01 ACME challenges.
+// It creates a TXT record for DNS-01 ACME challenges. Each owner may create at
+// most limit.MaxChallenges challenges per window. The owner is sent a security
+// notice unless they suppressed it.
 func CreateACMEChallenge(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	repo repository.Repository,
 	dnsService dns.Service,
+	webhookSvc webhook.Service,
+	emailSvc email.Service,
+	limit domain.ACMEChallengeLimit,
 	logger *slog.Logger,
 ) (response.ACMEChallengeResponse, *response.RequestError) {
 	logger.Info("handler started", "handler", "CreateACMEChallenge")
@@ -54,11 +63,13 @@ func CreateACMEChallenge(
 		}
 	}
 
-	// Authenticate - verify API key matches the ownerId in the request
-	_, authErr := auth.Authenticate(ctx, request, req.OwnerID, repo, logger)
+	// Authenticate - verify API key matches the ownerId in the request, or resolve
+	// the owner from the key when ownerId is omitted
+	identity, authErr := auth.AuthenticateOwnerOrKey(ctx, request, req.OwnerID, auth.Permission{Scope: domain.ScopeACME, Location: req.Location}, repo, logger)
 	if authErr != nil {
 		return response.ACMEChallengeResponse{}, authErr
 	}
+	req.OwnerID = identity.Owner.OwnerID
 
 	// Verify owner has an IP mapping for this location (proves ownership)
 	mapping, err := repo.Get(ctx, req.OwnerID, req.Location)
@@ -87,6 +98,41 @@ func CreateACMEChallenge(
 		}
 	}
 
+	// Claim a challenge under the owner's limit before touching DNS. The claim
+	// checks and counts in one update, so concurrent requests can't overrun the
+	// limit. If it can't be recorded the challenge goes ahead uncounted.
+	owner := identity.Owner
+	var rateLimit *response.RateLimitStatus
+	if !limit.Unlimited() {
+		count, err := repo.ClaimACMEChallenge(ctx, owner.OwnerID, limit, now)
+		switch {
+		case err == nil:
+			owner.ACMEChallengeCount = count
+			owner.ACMEChallengeWindow = now.Truncate(limit.Window).Unix()
+			owner.LastACMEChallengeAt = &now
+			rateLimit = acmeRateLimitStatus(owner, limit, now)
+		case errors.Is(err, domain.ErrACMERateLimitExceeded):
+			retryAfterSeconds := max(1, int(math.Ceil(now.Truncate(limit.Window).Add(limit.Window).Sub(now).Seconds())))
+			logger.Warn("ACME challenge rate limit exceeded",
+				"ownerId", req.OwnerID,
+				"location", req.Location,
+				"retryAfter", retryAfterSeconds,
+			)
+			return response.ACMEChallengeResponse{}, &response.RequestError{
+				Status:      http.StatusTooManyRequests,
+				Description: domain.ErrACMERateLimitExceeded.Error() + ": " + limit.String(),
+				RetryAfter:  retryAfterSeconds,
+				RateLimitStatus: &response.RateLimitStatus{
+					Limit:     limit.MaxChallenges,
+					Remaining: 0,
+					Reset:     retryAfterSeconds,
+				},
+			}
+		default:
+			logger.Warn("failed to claim ACME challenge", "error", err, "ownerId", owner.OwnerID)
+		}
+	}
+
 	// Build TXT record name
 	subdomain := mapping.Subdomain
 	txtRecordName := dns.BuildACMEChallengeName(subdomain)
@@ -95,10 +141,7 @@ func CreateACMEChallenge(
 	// Create Route53 TXT record
 	if err := dnsService.UpsertTXTRecord(ctx, txtRecordName, req.TxtValue); err != nil {
 		logger.Error("failed to create TXT record", "error", err)
-		return response.ACMEChallengeResponse{}, &response.RequestError{
-			Status:      http.StatusInternalServerError,
-			Description: "failed to create DNS record",
-		}
+		return response.ACMEChallengeResponse{}, dnsError(err, "failed to create DNS record")
 	}
 
 	// Store challenge in DynamoDB
@@ -131,6 +174,15 @@ func CreateACMEChallenge(
 		"txtRecord", fullTxtRecord,
 	)
 
+	dispatchWebhooks(ctx, owner.OwnerID, domain.WebhookEventACMECreated, webhook.ACMEChallengeData{
+		Location:  challenge.LocationName,
+		Subdomain: dns.FormatFQDN(subdomain),
+		TxtRecord: fullTxtRecord,
+		ExpiresAt: &challenge.ExpiresAt,
+	}, repo, webhookSvc, logger)
+
+	notifyACMEChallenge(ctx, request, owner, identity.Key.KeyID, challenge, emailSvc, logger)
+
 	return response.ACMEChallengeResponse{
 		Status: http.StatusCreated,
 		Body: response.ACMEChallengeBody{
@@ -142,16 +194,20 @@ func CreateACMEChallenge(
 			CreatedAt: challenge.CreatedAt.Format(time.RFC3339),
 			ExpiresAt: challenge.ExpiresAt.Format(time.RFC3339),
 		},
+		RateLimit: rateLimit,
 	}, nil
 }
 
 // DeleteACMEChallenge handles DELETE /acme-challenge requests.
-// It removes the TXT record after certificate issuance.
+// It removes the TXT record after certificate issuance. Deleting a challenge
+// doesn't give it back under limit.
 func DeleteACMEChallenge(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	repo repository.Repository,
 	dnsService dns.Service,
+	webhookSvc webhook.Service,
+	limit domain.ACMEChallengeLimit,
 	logger *slog.Logger,
 ) (response.ACMEDeleteResponse, *response.RequestError) {
 	logger.Info("handler started", "handler", "DeleteACMEChallenge")
@@ -176,11 +232,13 @@ func DeleteACMEChallenge(
 		}
 	}
 
-	// Authenticate - verify API key matches the ownerId in the request
-	_, authErr := auth.Authenticate(ctx, request, req.OwnerID, repo, logger)
+	// Authenticate - verify API key matches the ownerId in the request, or resolve
+	// the owner from the key when ownerId is omitted
+	identity, authErr := auth.AuthenticateOwnerOrKey(ctx, request, req.OwnerID, auth.Permission{Scope: domain.ScopeACME, Location: req.Location}, repo, logger)
 	if authErr != nil {
 		return response.ACMEDeleteResponse{}, authErr
 	}
+	req.OwnerID = identity.Owner.OwnerID
 
 	// Get existing challenge
 	challenge, err := repo.GetChallenge(ctx, req.OwnerID, req.Location)
@@ -219,6 +277,12 @@ func DeleteACMEChallenge(
 		"location", req.Location,
 	)
 
+	dispatchWebhooks(ctx, req.OwnerID, domain.WebhookEventACMEDeleted, webhook.ACMEChallengeData{
+		Location:  challenge.LocationName,
+		Subdomain: dns.FormatFQDN(challenge.Subdomain),
+		TxtRecord: challenge.TxtRecord,
+	}, repo, webhookSvc, logger)
+
 	return response.ACMEDeleteResponse{
 		Status: http.StatusOK,
 		Body: response.ACMEDeleteBody{
@@ -228,9 +292,24 @@ func DeleteACMEChallenge(
 			TxtRecord: challenge.TxtRecord,
 			Deleted:   true,
 		},
+		RateLimit: acmeRateLimitStatus(identity.Owner, limit, time.Now().UTC()),
 	}, nil
 }
 
+// acmeRateLimitStatus reports how much of the ACME challenge limit the owner has
+// left, or nil when the limit is off.
+func acmeRateLimitStatus(owner *domain.Owner, limit domain.ACMEChallengeLimit, now time.Time) *response.RateLimitStatus {
+	if limit.Unlimited() {
+		return nil
+	}
+	status := ratelimit.ACMEChallengeStatus(owner, limit, now)
+	return &response.RateLimitStatus{
+		Limit:     status.Limit,
+		Remaining: status.Remaining,
+		Reset:     int(math.Ceil(status.Reset.Seconds())),
+	}
+}
+
 // CleanupResult represents the result of the cleanup operation.
 type CleanupResult struct {
 	Processed int `json:"processed"`
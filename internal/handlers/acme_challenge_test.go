@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/webhook"
+	"gotest.tools/assert"
+)
+
+const testTxtValue = "gfj9Xq-Rs3kZGkA2H1T0x8Y3nqG0fR8vGk7c2xLmQ0s"
+
+func acmeChallengeRequest(apiKey string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","txtValue":"` + testTxtValue + `"}`,
+	}
+}
+
+func TestCreateACMEChallenge_CountsChallenge(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var claimedLimit domain.ACMEChallengeLimit
+	repo.claimACMEChallengeFunc = func(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error) {
+		assert.Equal(t, "test-owner", ownerID)
+		claimedLimit = limit
+		return 4, nil
+	}
+
+	var txtRecord string
+	dnsSvc := &mockDNSService{
+		upsertTXTRecordFunc: func(ctx context.Context, name, value string) error {
+			txtRecord = name
+			return nil
+		},
+	}
+
+	resp, err := CreateACMEChallenge(context.Background(), acmeChallengeRequest(apiKey), repo, dnsSvc, &mockWebhookService{}, &mockEmailService{}, domain.DefaultACMEChallengeLimit, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, "_acme-challenge.a3f8c2d1", txtRecord)
+	assert.Equal(t, domain.DefaultACMEChallengeLimit, claimedLimit)
+
+	// The headers report the ACME challenge limit, not the IP change limit
+	assert.Assert(t, resp.RateLimit != nil)
+	assert.Equal(t, 10, resp.RateLimit.Limit)
+	assert.Equal(t, 6, resp.RateLimit.Remaining)
+	assert.Assert(t, resp.RateLimit.Reset >= 1 && resp.RateLimit.Reset <= 3600, "got reset %d", resp.RateLimit.Reset)
+}
+
+func TestCreateACMEChallenge_LimitExceeded(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.claimACMEChallengeFunc = func(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error) {
+		return 0, domain.ErrACMERateLimitExceeded
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertTXTRecordFunc: func(ctx context.Context, name, value string) error {
+			t.Fatal("TXT record should not be created past the limit")
+			return nil
+		},
+	}
+	limit := domain.ACMEChallengeLimit{MaxChallenges: 3, Window: time.Hour}
+
+	_, err := CreateACMEChallenge(context.Background(), acmeChallengeRequest(apiKey), repo, dnsSvc, &mockWebhookService{}, &mockEmailService{}, limit, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusTooManyRequests, err.Status)
+	assert.Equal(t, "ACME challenge rate limit exceeded: maximum 3 challenges per hour", err.Description)
+	assert.Assert(t, err.RetryAfter >= 1 && err.RetryAfter <= 3600, "got Retry-After %d", err.RetryAfter)
+	assert.Assert(t, err.RateLimitStatus != nil)
+	assert.Equal(t, response.RateLimitStatus{Limit: 3, Remaining: 0, Reset: err.RetryAfter}, *err.RateLimitStatus)
+}
+
+func TestCreateACMEChallenge_ClaimFailureFailsOpen(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.claimACMEChallengeFunc = func(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error) {
+		return 0, errors.New("dynamodb unavailable")
+	}
+
+	resp, err := CreateACMEChallenge(context.Background(), acmeChallengeRequest(apiKey), repo, &mockDNSService{}, &mockWebhookService{}, &mockEmailService{}, domain.DefaultACMEChallengeLimit, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Assert(t, resp.RateLimit == nil)
+}
+
+func TestCreateACMEChallenge_Unlimited(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.claimACMEChallengeFunc = func(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error) {
+		t.Fatal("challenges should not be counted without a limit")
+		return 0, nil
+	}
+
+	resp, err := CreateACMEChallenge(context.Background(), acmeChallengeRequest(apiKey), repo, &mockDNSService{}, &mockWebhookService{}, &mockEmailService{}, domain.ACMEChallengeLimit{}, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Assert(t, resp.RateLimit == nil)
+}
+
+func TestDeleteACMEChallenge_ReportsACMELimit(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	now := time.Now().UTC()
+
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getOwnerFunc = func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+		return &domain.Owner{
+			OwnerID:             "test-owner",
+			APIKeyHash:          auth.HashAPIKey(apiKey),
+			ACMEChallengeCount:  2,
+			ACMEChallengeWindow: now.Truncate(time.Hour).Unix(),
+		}, nil
+	}
+	repo.getChallengeFunc = func(ctx context.Context, ownerID, location string) (*domain.ACMEChallenge, error) {
+		return &domain.ACMEChallenge{OwnerID: ownerID, LocationName: location, Subdomain: "a3f8c2d1", TxtValue: testTxtValue}, nil
+	}
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home"}`,
+	}
+	limit := domain.ACMEChallengeLimit{MaxChallenges: 3, Window: time.Hour}
+
+	resp, err := DeleteACMEChallenge(context.Background(), request, repo, &mockDNSService{}, &mockWebhookService{}, limit, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, resp.RateLimit != nil)
+	assert.Equal(t, 3, resp.RateLimit.Limit)
+	assert.Equal(t, 1, resp.RateLimit.Remaining)
+}
+
+func TestCreateACMEChallenge_DispatchesWebhook(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.listWebhooksFunc = func(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+		return []domain.Webhook{
+			{OwnerID: ownerID, WebhookID: "acme", URL: "https://acme.example.com", Events: []string{domain.WebhookEventACMECreated}},
+			{OwnerID: ownerID, WebhookID: "ip", URL: "https://ip.example.com", Events: []string{domain.WebhookEventIPChanged}},
+		}, nil
+	}
+
+	var sent []webhook.Delivery
+	webhookSvc := &mockWebhookService{
+		sendFunc: func(ctx context.Context, delivery webhook.Delivery) (int, error) {
+			sent = append(sent, delivery)
+			return http.StatusOK, nil
+		},
+	}
+
+	_, err := CreateACMEChallenge(context.Background(), acmeChallengeRequest(apiKey), repo, &mockDNSService{}, webhookSvc, &mockEmailService{}, domain.DefaultACMEChallengeLimit, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, 1, len(sent))
+	assert.Equal(t, "https://acme.example.com", sent[0].URL)
+	assert.Equal(t, domain.WebhookEventACMECreated, sent[0].Event)
+	assert.Assert(t, strings.Contains(string(sent[0].Body), `"txtRecord":"_acme-challenge.a3f8c2d1.grocky.net"`), "got %s", sent[0].Body)
+}
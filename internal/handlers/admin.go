@@ -0,0 +1,970 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// auditEntryTimeFormat prefixes audit entry IDs. Unlike RFC3339Nano it keeps
+// trailing zeros, so entry IDs sort in the order the actions happened.
+const auditEntryTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// AdminListOwners handles GET /admin/owners requests.
+// The optional q parameter matches owner IDs and emails case-insensitively, and
+// suspended=true or suspended=false filters by suspension.
+func AdminListOwners(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AdminOwnerListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminListOwners")
+	defer logger.Info("handler completed", "handler", "AdminListOwners")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminOwnerListResponse{}, authErr
+	}
+
+	query := strings.ToLower(strings.TrimSpace(request.QueryStringParameters["q"]))
+	suspended := request.QueryStringParameters["suspended"]
+
+	entry := newAuditEntry(request, operator, domain.AuditListOwners, "", "")
+	entry.Details = map[string]string{"q": query, "suspended": suspended}
+
+	resp, reqErr := adminListOwners(ctx, query, suspended, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminListOwners(ctx context.Context, query, suspended string, repo repository.Repository, logger *slog.Logger) (response.AdminOwnerListResponse, *response.RequestError) {
+	var wantSuspended *bool
+	if suspended != "" {
+		value, err := strconv.ParseBool(suspended)
+		if err != nil {
+			return response.AdminOwnerListResponse{}, &response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "suspended must be true or false",
+			}
+		}
+		wantSuspended = &value
+	}
+
+	owners, err := repo.ListOwners(ctx)
+	if err != nil {
+		logger.Error("failed to list owners", "error", err)
+		return response.AdminOwnerListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list owners",
+		}
+	}
+
+	bodies := make([]response.AdminOwnerBody, 0, len(owners))
+	for _, owner := range owners {
+		if query != "" &&
+			!strings.Contains(strings.ToLower(owner.OwnerID), query) &&
+			!strings.Contains(strings.ToLower(owner.Email), query) {
+			continue
+		}
+		if wantSuspended != nil && owner.IsSuspended() != *wantSuspended {
+			continue
+		}
+		bodies = append(bodies, adminOwnerBody(owner))
+	}
+	slices.SortFunc(bodies, func(a, b response.AdminOwnerBody) int {
+		return strings.Compare(a.OwnerID, b.OwnerID)
+	})
+
+	return response.AdminOwnerListResponse{
+		Status: http.StatusOK,
+		Body:   response.AdminOwnerListBody{Owners: bodies},
+	}, nil
+}
+
+// AdminGetOwner handles GET /admin/owners/{ownerId} requests.
+// It returns the owner along with every mapping and its rate limit state.
+func AdminGetOwner(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AdminOwnerResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminGetOwner", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "AdminGetOwner")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminOwnerResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditGetOwner, ownerID, "")
+	resp, reqErr := adminGetOwner(ctx, ownerID, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminGetOwner(ctx context.Context, ownerID string, repo repository.Repository, logger *slog.Logger) (response.AdminOwnerResponse, *response.RequestError) {
+	owner, reqErr := adminFindOwner(ctx, ownerID, repo, logger)
+	if reqErr != nil {
+		return response.AdminOwnerResponse{}, reqErr
+	}
+
+	mappings, err := repo.ListMappingsByOwner(ctx, ownerID)
+	if err != nil {
+		logger.Error("failed to list mappings", "error", err)
+		return response.AdminOwnerResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list mappings",
+		}
+	}
+
+	body := adminOwnerBody(*owner)
+	body.RateLimit = adminRateLimitBody(ctx, owner, repo, logger)
+	body.Mappings = make([]response.AdminMappingBody, 0, len(mappings))
+	for _, mapping := range mappings {
+		body.Mappings = append(body.Mappings, adminMappingBody(mapping))
+	}
+
+	return response.AdminOwnerResponse{
+		Status: http.StatusOK,
+		Body:   body,
+	}, nil
+}
+
+// AdminSuspendOwner handles POST /admin/owners/{ownerId}/suspend requests.
+// A suspended owner's keys are rejected until the owner is unsuspended; their
+// DNS records are left in place.
+func AdminSuspendOwner(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AdminOwnerResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminSuspendOwner", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "AdminSuspendOwner")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminOwnerResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditSuspendOwner, ownerID, "")
+
+	var req domain.SuspendOwnerRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		reqErr := &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+		recordAudit(ctx, repo, entry, reqErr, logger)
+		return response.AdminOwnerResponse{}, reqErr
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	entry.Details = map[string]string{"reason": req.Reason}
+
+	resp, reqErr := adminSuspendOwner(ctx, ownerID, req, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminSuspendOwner(ctx context.Context, ownerID string, req domain.SuspendOwnerRequest, repo repository.Repository, logger *slog.Logger) (response.AdminOwnerResponse, *response.RequestError) {
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.AdminOwnerResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	now := time.Now().UTC()
+	if reqErr := setOwnerSuspension(ctx, ownerID, &now, req.Reason, repo, logger); reqErr != nil {
+		return response.AdminOwnerResponse{}, reqErr
+	}
+
+	logger.Info("owner suspended", "ownerId", ownerID, "reason", req.Reason)
+	return adminOwnerResponse(ctx, ownerID, repo, logger)
+}
+
+// AdminUnsuspendOwner handles POST /admin/owners/{ownerId}/unsuspend requests.
+func AdminUnsuspendOwner(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AdminOwnerResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminUnsuspendOwner", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "AdminUnsuspendOwner")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminOwnerResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditUnsuspendOwner, ownerID, "")
+	resp, reqErr := adminUnsuspendOwner(ctx, ownerID, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminUnsuspendOwner(ctx context.Context, ownerID string, repo repository.Repository, logger *slog.Logger) (response.AdminOwnerResponse, *response.RequestError) {
+	if reqErr := setOwnerSuspension(ctx, ownerID, nil, "", repo, logger); reqErr != nil {
+		return response.AdminOwnerResponse{}, reqErr
+	}
+
+	logger.Info("owner unsuspended", "ownerId", ownerID)
+	return adminOwnerResponse(ctx, ownerID, repo, logger)
+}
+
+// AdminResetRateLimit handles POST /admin/owners/{ownerId}/locations/{location}/reset-rate-limit
+// requests. It clears the location's hourly IP change count so the next update is accepted.
+func AdminResetRateLimit(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AdminMappingResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminResetRateLimit", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "AdminResetRateLimit")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminMappingResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditResetRateLimit, ownerID, location)
+	resp, reqErr := adminResetRateLimit(ctx, ownerID, location, &entry, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminResetRateLimit(ctx context.Context, ownerID, location string, entry *domain.AuditEntry, repo repository.Repository, logger *slog.Logger) (response.AdminMappingResponse, *response.RequestError) {
+	mapping, reqErr := getLocation(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.AdminMappingResponse{}, reqErr
+	}
+	entry.Details = map[string]string{"hourlyChangeCount": strconv.Itoa(mapping.HourlyChangeCount)}
+
+	mapping.HourlyChangeCount = 0
+	mapping.LastIPChangeAt = time.Time{}
+	mapping.RecentIPChanges = nil
+	if err := repo.Put(ctx, *mapping); err != nil {
+		logger.Error("failed to save mapping", "error", err)
+		return response.AdminMappingResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save mapping",
+		}
+	}
+
+	logger.Info("rate limit reset", "ownerId", ownerID, "location", location)
+	return response.AdminMappingResponse{
+		Status: http.StatusOK,
+		Body:   adminMappingBody(*mapping),
+	}, nil
+}
+
+// AdminSetOwnerRateLimit handles PUT /admin/owners/{ownerId}/rate-limit requests.
+// It assigns the owner's rate limit plan and sets or clears a policy that
+// overrides the plan for this owner alone.
+func AdminSetOwnerRateLimit(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AdminOwnerResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminSetOwnerRateLimit", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "AdminSetOwnerRateLimit")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminOwnerResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditSetOwnerRateLimit, ownerID, "")
+
+	var req domain.SetOwnerRateLimitRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		reqErr := &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+		recordAudit(ctx, repo, entry, reqErr, logger)
+		return response.AdminOwnerResponse{}, reqErr
+	}
+	req = req.Normalize()
+	entry.Details = map[string]string{"plan": req.Plan}
+	if req.Policy != nil {
+		addPolicyDetails(entry.Details, *req.Policy)
+	}
+
+	resp, reqErr := adminSetOwnerRateLimit(ctx, ownerID, req, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminSetOwnerRateLimit(ctx context.Context, ownerID string, req domain.SetOwnerRateLimitRequest, repo repository.Repository, logger *slog.Logger) (response.AdminOwnerResponse, *response.RequestError) {
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.AdminOwnerResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	if req.Plan != "" {
+		if _, err := repo.GetRateLimitPlan(ctx, req.Plan); err != nil {
+			if repository.IsRateLimitPlanNotFound(err) {
+				return response.AdminOwnerResponse{}, &response.RequestError{
+					Status:      http.StatusBadRequest,
+					Description: domain.ErrRateLimitPlanNotFound.Error(),
+				}
+			}
+			logger.Error("failed to get rate limit plan", "error", err)
+			return response.AdminOwnerResponse{}, &response.RequestError{
+				Status:      http.StatusInternalServerError,
+				Description: "failed to get rate limit plan",
+			}
+		}
+	}
+
+	if err := repo.SetOwnerRateLimit(ctx, ownerID, req.Plan, req.Policy); err != nil {
+		if repository.IsOwnerNotFound(err) {
+			return response.AdminOwnerResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "owner not found",
+			}
+		}
+		logger.Error("failed to update owner rate limit", "error", err)
+		return response.AdminOwnerResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to update owner rate limit",
+		}
+	}
+
+	logger.Info("owner rate limit set", "ownerId", ownerID, "plan", req.Plan, "override", req.Policy != nil)
+	return adminOwnerResponse(ctx, ownerID, repo, logger)
+}
+
+// AdminListRateLimitPlans handles GET /admin/rate-limit-plans requests.
+// The default plan is always listed, with its built-in policy until one is stored.
+func AdminListRateLimitPlans(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.RateLimitPlanListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminListRateLimitPlans")
+	defer logger.Info("handler completed", "handler", "AdminListRateLimitPlans")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.RateLimitPlanListResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditListRateLimitPlans, "", "")
+	resp, reqErr := adminListRateLimitPlans(ctx, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminListRateLimitPlans(ctx context.Context, repo repository.Repository, logger *slog.Logger) (response.RateLimitPlanListResponse, *response.RequestError) {
+	plans, err := repo.ListRateLimitPlans(ctx)
+	if err != nil {
+		logger.Error("failed to list rate limit plans", "error", err)
+		return response.RateLimitPlanListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list rate limit plans",
+		}
+	}
+
+	if !slices.ContainsFunc(plans, func(p domain.RateLimitPlan) bool { return p.Name == domain.DefaultRateLimitPlan }) {
+		plans = append(plans, domain.RateLimitPlan{Name: domain.DefaultRateLimitPlan, RateLimitPolicy: domain.DefaultRateLimitPolicy})
+	}
+
+	bodies := make([]response.RateLimitBody, 0, len(plans))
+	for _, plan := range plans {
+		bodies = append(bodies, rateLimitBody(plan))
+	}
+	slices.SortFunc(bodies, func(a, b response.RateLimitBody) int {
+		return strings.Compare(a.Plan, b.Plan)
+	})
+
+	return response.RateLimitPlanListResponse{
+		Status: http.StatusOK,
+		Body:   response.RateLimitPlanListBody{Plans: bodies},
+	}, nil
+}
+
+// AdminPutRateLimitPlan handles PUT /admin/rate-limit-plans/{name} requests.
+// It creates or replaces a plan; owners on the plan get the new policy on their
+// next IP change.
+func AdminPutRateLimitPlan(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	name string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.RateLimitPlanResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminPutRateLimitPlan", "plan", name)
+	defer logger.Info("handler completed", "handler", "AdminPutRateLimitPlan")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.RateLimitPlanResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditPutRateLimitPlan, "", "")
+
+	var policy domain.RateLimitPolicy
+	if err := json.Unmarshal([]byte(request.Body), &policy); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		reqErr := &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+		recordAudit(ctx, repo, entry, reqErr, logger)
+		return response.RateLimitPlanResponse{}, reqErr
+	}
+	policy = policy.Normalize()
+	entry.Details = map[string]string{"plan": name}
+	addPolicyDetails(entry.Details, policy)
+
+	resp, reqErr := adminPutRateLimitPlan(ctx, name, policy, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminPutRateLimitPlan(ctx context.Context, name string, policy domain.RateLimitPolicy, repo repository.Repository, logger *slog.Logger) (response.RateLimitPlanResponse, *response.RequestError) {
+	err := domain.ValidateRateLimitPlanName(name)
+	if err == nil {
+		err = policy.Validate()
+	}
+	if err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.RateLimitPlanResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	plan := domain.RateLimitPlan{
+		Name:            name,
+		RateLimitPolicy: policy,
+		UpdatedAt:       time.Now().UTC(),
+	}
+	if err := repo.PutRateLimitPlan(ctx, plan); err != nil {
+		logger.Error("failed to save rate limit plan", "error", err)
+		return response.RateLimitPlanResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save rate limit plan",
+		}
+	}
+
+	logger.Info("rate limit plan saved", "plan", name)
+	return response.RateLimitPlanResponse{
+		Status: http.StatusOK,
+		Body:   rateLimitBody(plan),
+	}, nil
+}
+
+// AdminDeleteRateLimitPlan handles DELETE /admin/rate-limit-plans/{name} requests.
+// Owners still assigned to the plan fall back to the default plan, and deleting
+// the default plan restores its built-in policy.
+func AdminDeleteRateLimitPlan(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	name string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.MessageResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminDeleteRateLimitPlan", "plan", name)
+	defer logger.Info("handler completed", "handler", "AdminDeleteRateLimitPlan")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.MessageResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditDeleteRateLimitPlan, "", "")
+	entry.Details = map[string]string{"plan": name}
+
+	resp, reqErr := adminDeleteRateLimitPlan(ctx, name, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminDeleteRateLimitPlan(ctx context.Context, name string, repo repository.Repository, logger *slog.Logger) (response.MessageResponse, *response.RequestError) {
+	if err := repo.DeleteRateLimitPlan(ctx, name); err != nil {
+		if repository.IsRateLimitPlanNotFound(err) {
+			return response.MessageResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: domain.ErrRateLimitPlanNotFound.Error(),
+			}
+		}
+		logger.Error("failed to delete rate limit plan", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to delete rate limit plan",
+		}
+	}
+
+	logger.Info("rate limit plan deleted", "plan", name)
+	return response.MessageResponse{
+		Status: http.StatusOK,
+		Body:   response.MessageBody{Message: "Rate limit plan deleted."},
+	}, nil
+}
+
+// addPolicyDetails records a rate limit policy in an audit entry's details.
+func addPolicyDetails(details map[string]string, policy domain.RateLimitPolicy) {
+	details["maxChanges"] = strconv.Itoa(policy.MaxChanges)
+	details["windowSeconds"] = strconv.Itoa(policy.WindowSeconds)
+	details["algorithm"] = policy.Algorithm
+}
+
+// AdminChangeSubdomain handles PUT /admin/owners/{ownerId}/locations/{location}/subdomain
+// requests. The location's address, wildcard and custom records are published under
+// the new subdomain before the old ones are removed, so the location never goes dark.
+func AdminChangeSubdomain(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.AdminMappingResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminChangeSubdomain", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "AdminChangeSubdomain")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AdminMappingResponse{}, authErr
+	}
+
+	entry := newAuditEntry(request, operator, domain.AuditChangeSubdomain, ownerID, location)
+
+	var req domain.ChangeSubdomainRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		reqErr := &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+		recordAudit(ctx, repo, entry, reqErr, logger)
+		return response.AdminMappingResponse{}, reqErr
+	}
+	req = req.Normalize()
+	entry.Details = map[string]string{"subdomain": req.Subdomain}
+
+	resp, reqErr := adminChangeSubdomain(ctx, ownerID, location, req, &entry, repo, dnsService, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminChangeSubdomain(
+	ctx context.Context,
+	ownerID, location string,
+	req domain.ChangeSubdomainRequest,
+	entry *domain.AuditEntry,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.AdminMappingResponse, *response.RequestError) {
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.AdminMappingResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	mapping, oldSubdomain, reqErr := ChangeSubdomain(ctx, ownerID, location, req.Subdomain, repo, dnsService, logger)
+	if oldSubdomain != "" {
+		entry.Details["previousSubdomain"] = oldSubdomain
+	}
+	if reqErr != nil {
+		return response.AdminMappingResponse{}, reqErr
+	}
+
+	return response.AdminMappingResponse{
+		Status: http.StatusOK,
+		Body:   adminMappingBody(*mapping),
+	}, nil
+}
+
+// ChangeSubdomain moves an owner's location to a validated subdomain and returns
+// the updated mapping and the previous subdomain, which is also returned on
+// failure once the location has been found. The new subdomain is claimed before
+// anything is published, and the old records are removed and the old claim
+// released only after the mapping is saved. The /admin API and ddns-admin both
+// change subdomains through it.
+func ChangeSubdomain(
+	ctx context.Context,
+	ownerID, location, subdomain string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (*domain.IPMapping, string, *response.RequestError) {
+	mapping, reqErr := getLocation(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return nil, "", reqErr
+	}
+	oldSubdomain := mappingSubdomain(*mapping)
+
+	if oldSubdomain == subdomain {
+		return mapping, oldSubdomain, nil
+	}
+
+	existing, err := repo.ListMappingsBySubdomain(ctx, subdomain)
+	if err != nil {
+		logger.Error("failed to check subdomain availability", "error", err)
+		return nil, oldSubdomain, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to check subdomain availability",
+		}
+	}
+	for _, m := range existing {
+		if m.OwnerID != ownerID || m.LocationName != location {
+			logger.Warn("subdomain taken", "subdomain", subdomain, "takenBy", m.OwnerID)
+			return nil, oldSubdomain, &response.RequestError{
+				Status:      http.StatusConflict,
+				Description: domain.ErrSubdomainTaken.Error(),
+			}
+		}
+	}
+	if err := repo.ClaimSubdomain(ctx, subdomain, ownerID, location); err != nil {
+		if errors.Is(err, domain.ErrSubdomainTaken) {
+			logger.Warn("subdomain claimed", "subdomain", subdomain)
+			return nil, oldSubdomain, &response.RequestError{
+				Status:      http.StatusConflict,
+				Description: domain.ErrSubdomainTaken.Error(),
+			}
+		}
+		logger.Error("failed to claim subdomain", "error", err)
+		return nil, oldSubdomain, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to check subdomain availability",
+		}
+	}
+
+	recordSets, err := repo.ListRecordSets(ctx, ownerID, location)
+	if err != nil {
+		logger.Error("failed to list record sets", "error", err)
+		return nil, oldSubdomain, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list record sets",
+		}
+	}
+
+	// Publish under the new subdomain first
+	if err := dnsService.UpsertRecord(ctx, subdomain, mapping.IP); err != nil {
+		logger.Error("failed to create DNS record", "error", err)
+		return nil, oldSubdomain, dnsError(err, "failed to update DNS record")
+	}
+	if mapping.Wildcard {
+		if err := dnsService.UpsertRecord(ctx, dns.BuildWildcardName(subdomain), mapping.IP); err != nil {
+			logger.Error("failed to create wildcard DNS record", "error", err)
+			return nil, oldSubdomain, dnsError(err, "failed to update DNS record")
+		}
+	}
+	for _, rs := range recordSets {
+		if err := dnsService.UpsertRecordSet(ctx, dnsRecordSet(subdomain, rs)); err != nil {
+			logger.Error("failed to create DNS record set", "error", err, "name", rs.Name, "type", rs.Type)
+			return nil, oldSubdomain, dnsError(err, "failed to update DNS record")
+		}
+	}
+
+	mapping.Subdomain = subdomain
+	if err := repo.Put(ctx, *mapping); err != nil {
+		logger.Error("failed to save mapping", "error", err)
+		return nil, oldSubdomain, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save mapping",
+		}
+	}
+
+	// Then remove the old records
+	for _, rs := range recordSets {
+		if err := dnsService.DeleteRecordSet(ctx, dnsRecordSet(oldSubdomain, rs)); err != nil {
+			logger.Error("failed to delete old DNS record set", "error", err, "name", rs.Name, "type", rs.Type)
+			return nil, oldSubdomain, dnsError(err, "failed to delete old DNS record")
+		}
+	}
+	if err := removeAddressRecords(ctx, dnsService, oldSubdomain, mapping.IP, mapping.Wildcard); err != nil {
+		logger.Error("failed to delete old DNS record", "error", err)
+		return nil, oldSubdomain, dnsError(err, "failed to delete old DNS record")
+	}
+
+	// A claim left behind only keeps the old name from being reused
+	if err := repo.ReleaseSubdomain(ctx, oldSubdomain, ownerID, location); err != nil {
+		logger.Warn("failed to release old subdomain", "error", err, "subdomain", oldSubdomain)
+	}
+
+	logger.Info("subdomain changed",
+		"ownerId", ownerID,
+		"location", location,
+		"previousSubdomain", oldSubdomain,
+		"subdomain", subdomain,
+	)
+
+	return mapping, oldSubdomain, nil
+}
+
+// AdminListAuditLog handles GET /admin/audit requests.
+// It returns the entries for one UTC day (the date parameter, defaulting to
+// today), newest first, optionally filtered by ownerId.
+func AdminListAuditLog(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.AuditListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "AdminListAuditLog")
+	defer logger.Info("handler completed", "handler", "AdminListAuditLog")
+
+	operator, authErr := auth.AuthenticateOperator(ctx, request, repo, logger)
+	if authErr != nil {
+		return response.AuditListResponse{}, authErr
+	}
+
+	date := request.QueryStringParameters["date"]
+	if date == "" {
+		date = domain.AuditDate(time.Now())
+	}
+	ownerID := request.QueryStringParameters["ownerId"]
+
+	entry := newAuditEntry(request, operator, domain.AuditListAuditLog, ownerID, "")
+	entry.Details = map[string]string{"date": date}
+
+	resp, reqErr := adminListAuditLog(ctx, date, ownerID, repo, logger)
+	recordAudit(ctx, repo, entry, reqErr, logger)
+	return resp, reqErr
+}
+
+func adminListAuditLog(ctx context.Context, date, ownerID string, repo repository.Repository, logger *slog.Logger) (response.AuditListResponse, *response.RequestError) {
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return response.AuditListResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "date must be YYYY-MM-DD",
+		}
+	}
+
+	entries, err := repo.ListAuditEntries(ctx, date)
+	if err != nil {
+		logger.Error("failed to list audit entries", "error", err)
+		return response.AuditListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list audit entries",
+		}
+	}
+
+	bodies := make([]response.AuditEntryBody, 0, len(entries))
+	for _, e := range entries {
+		if ownerID != "" && e.OwnerID != ownerID {
+			continue
+		}
+		bodies = append(bodies, auditEntryBody(e))
+	}
+
+	return response.AuditListResponse{
+		Status: http.StatusOK,
+		Body: response.AuditListBody{
+			Date:    date,
+			Entries: bodies,
+		},
+	}, nil
+}
+
+// adminFindOwner loads an owner, mapping not-found to a 404.
+func adminFindOwner(ctx context.Context, ownerID string, repo repository.Repository, logger *slog.Logger) (*domain.Owner, *response.RequestError) {
+	owner, err := repo.GetOwner(ctx, ownerID)
+	if err != nil {
+		if repository.IsOwnerNotFound(err) {
+			return nil, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "owner not found",
+			}
+		}
+		logger.Error("failed to get owner", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to get owner",
+		}
+	}
+	return owner, nil
+}
+
+// adminOwnerResponse reloads an owner after a change.
+func adminOwnerResponse(ctx context.Context, ownerID string, repo repository.Repository, logger *slog.Logger) (response.AdminOwnerResponse, *response.RequestError) {
+	owner, reqErr := adminFindOwner(ctx, ownerID, repo, logger)
+	if reqErr != nil {
+		return response.AdminOwnerResponse{}, reqErr
+	}
+	body := adminOwnerBody(*owner)
+	body.RateLimit = adminRateLimitBody(ctx, owner, repo, logger)
+	return response.AdminOwnerResponse{
+		Status: http.StatusOK,
+		Body:   body,
+	}, nil
+}
+
+// adminRateLimitBody describes the rate limit that applies to an owner. It is
+// omitted, rather than failing the request, if the owner's plan can't be loaded.
+func adminRateLimitBody(ctx context.Context, owner *domain.Owner, repo repository.Repository, logger *slog.Logger) *response.RateLimitBody {
+	plan, err := resolveRateLimit(ctx, owner, repo, logger)
+	if err != nil {
+		logger.Warn("failed to get rate limit plan", "error", err, "ownerId", owner.OwnerID)
+		return nil
+	}
+	body := rateLimitBody(plan)
+	return &body
+}
+
+// setOwnerSuspension suspends (suspendedAt set) or unsuspends (nil) an owner.
+func setOwnerSuspension(ctx context.Context, ownerID string, suspendedAt *time.Time, reason string, repo repository.Repository, logger *slog.Logger) *response.RequestError {
+	if err := repo.SetOwnerSuspension(ctx, ownerID, suspendedAt, reason); err != nil {
+		if repository.IsOwnerNotFound(err) {
+			return &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "owner not found",
+			}
+		}
+		logger.Error("failed to update owner suspension", "error", err)
+		return &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to update owner",
+		}
+	}
+	return nil
+}
+
+// newAuditEntry starts an audit entry for an operator's action.
+func newAuditEntry(request events.APIGatewayProxyRequest, operator *domain.Operator, action, ownerID, location string) domain.AuditEntry {
+	return domain.AuditEntry{
+		OperatorID:   operator.KeyID,
+		OperatorName: operator.Name,
+		Action:       action,
+		OwnerID:      ownerID,
+		Location:     location,
+		SourceIP:     extractClientIP(request),
+	}
+}
+
+// recordAudit completes an audit entry with the action's outcome and stores it.
+// The entry is always logged, so the action is still traceable if storing it fails.
+func recordAudit(ctx context.Context, repo repository.Repository, entry domain.AuditEntry, reqErr *response.RequestError, logger *slog.Logger) {
+	now := time.Now().UTC()
+	entry.CreatedAt = now
+	entry.AuditDate = domain.AuditDate(now)
+	entry.EntryID = now.Format(auditEntryTimeFormat) + "#" + auditEntrySuffix()
+	entry.Outcome = domain.AuditSucceeded
+	if reqErr != nil {
+		entry.Outcome = domain.AuditFailed
+		entry.Error = reqErr.Description
+	}
+
+	logger.Info("operator action",
+		"operatorId", entry.OperatorID,
+		"operator", entry.OperatorName,
+		"action", entry.Action,
+		"ownerId", entry.OwnerID,
+		"location", entry.Location,
+		"details", entry.Details,
+		"outcome", entry.Outcome,
+		"error", entry.Error,
+		"sourceIp", entry.SourceIP,
+	)
+
+	if err := repo.PutAuditEntry(ctx, entry); err != nil {
+		logger.Error("failed to store audit entry", "error", err, "entryId", entry.EntryID)
+	}
+}
+
+// auditEntrySuffix disambiguates entries recorded in the same nanosecond.
+func auditEntrySuffix() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "0"
+	}
+	return hex.EncodeToString(b)
+}
+
+func adminOwnerBody(owner domain.Owner) response.AdminOwnerBody {
+	body := response.AdminOwnerBody{
+		OwnerID:         owner.OwnerID,
+		Email:           owner.Email,
+		Suspended:       owner.IsSuspended(),
+		SuspendedReason: owner.SuspendedReason,
+	}
+	if !owner.CreatedAt.IsZero() {
+		body.CreatedAt = owner.CreatedAt.Format(time.RFC3339)
+	}
+	if owner.SuspendedAt != nil {
+		body.SuspendedAt = owner.SuspendedAt.Format(time.RFC3339)
+	}
+	if owner.VerifyBy != nil {
+		body.VerifyBy = owner.VerifyBy.Format(time.RFC3339)
+	}
+	return body
+}
+
+func adminMappingBody(mapping domain.IPMapping) response.AdminMappingBody {
+	body := response.AdminMappingBody{
+		OwnerID:           mapping.OwnerID,
+		Location:          mapping.LocationName,
+		IP:                mapping.IP,
+		Subdomain:         dns.FormatFQDN(mappingSubdomain(mapping)),
+		Wildcard:          mapping.Wildcard,
+		HourlyChangeCount: mapping.HourlyChangeCount,
+	}
+	if !mapping.UpdatedAt.IsZero() {
+		body.UpdatedAt = mapping.UpdatedAt.Format(time.RFC3339)
+	}
+	if !mapping.LastIPChangeAt.IsZero() {
+		body.LastIPChangeAt = mapping.LastIPChangeAt.Format(time.RFC3339)
+	}
+	for _, changedAt := range mapping.RecentIPChanges {
+		body.RecentIPChanges = append(body.RecentIPChanges, changedAt.Format(time.RFC3339))
+	}
+	return body
+}
+
+func auditEntryBody(entry domain.AuditEntry) response.AuditEntryBody {
+	return response.AuditEntryBody{
+		EntryID:      entry.EntryID,
+		OperatorID:   entry.OperatorID,
+		OperatorName: entry.OperatorName,
+		Action:       entry.Action,
+		OwnerID:      entry.OwnerID,
+		Location:     entry.Location,
+		Details:      entry.Details,
+		Outcome:      entry.Outcome,
+		Error:        entry.Error,
+		SourceIP:     entry.SourceIP,
+		CreatedAt:    entry.CreatedAt.Format(time.RFC3339),
+	}
+}
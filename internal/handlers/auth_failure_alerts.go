@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/repository"
+)
+
+// AuthFailureAlertResult represents the result of the auth failure alert run.
+type AuthFailureAlertResult struct {
+	Processed int `json:"processed"`
+	Alerted   int `json:"alerted"`
+	Errors    int `json:"errors"`
+}
+
+// SendAuthFailureAlerts emails owners whose accounts saw at least
+// domain.AuthFailureAlertThreshold failed authentication attempts. Called by
+// EventBridge every few minutes; each run of failures is only alerted about once.
+func SendAuthFailureAlerts(
+	ctx context.Context,
+	repo repository.Repository,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) (*AuthFailureAlertResult, error) {
+	logger.Info("starting auth failure alerts")
+
+	result := &AuthFailureAlertResult{}
+	now := time.Now().UTC()
+
+	failures, err := repo.ScanAuthFailureAlerts(ctx)
+	if err != nil {
+		logger.Error("failed to scan auth failures", "error", err)
+		return nil, err
+	}
+
+	result.Processed = len(failures)
+	logger.Info("found auth failures to alert", "count", len(failures))
+
+	for _, failure := range failures {
+		if !failure.NeedsAlert() {
+			continue
+		}
+
+		owner, err := repo.GetOwner(ctx, failure.OwnerID)
+		if err != nil {
+			logger.Error("failed to get owner", "error", err, "ownerId", failure.OwnerID)
+			result.Errors++
+			continue
+		}
+
+		alert := email.AuthFailureAlert{
+			Failures:       failure.Failures,
+			FirstFailureAt: failure.FirstFailureAt,
+			LastFailureAt:  failure.LastFailureAt,
+			LastFailureIP:  failure.LastFailureIP,
+		}
+		if err := emailSvc.SendAuthFailureAlert(ctx, emailRecipient(owner), alert); err != nil {
+			logger.Error("failed to send auth failure alert", "error", err, "ownerId", owner.OwnerID)
+			result.Errors++
+			continue
+		}
+
+		// A failure here means the owner may be alerted again on the next run
+		if err := repo.MarkAuthFailureAlerted(ctx, failure.Subject, now); err != nil {
+			logger.Error("failed to mark auth failure alert", "error", err, "ownerId", owner.OwnerID)
+			result.Errors++
+			continue
+		}
+
+		result.Alerted++
+		logger.Info("auth failure alert sent", "ownerId", owner.OwnerID, "failures", failure.Failures)
+	}
+
+	logger.Info("auth failure alerts completed",
+		"processed", result.Processed,
+		"alerted", result.Alerted,
+		"errors", result.Errors,
+	)
+
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"gotest.tools/assert"
+)
+
+func TestSendAuthFailureAlerts(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var marked []string
+
+	repo := &mockRepository{
+		scanAuthFailureAlertsFunc: func(ctx context.Context) ([]domain.AuthFailure, error) {
+			return []domain.AuthFailure{
+				{Subject: "owner#test-owner", OwnerID: "test-owner", Failures: 12, LastFailureIP: "198.51.100.7"},
+				{Subject: "owner#broken", OwnerID: "broken", Failures: 10},
+				{Subject: "owner#missing", OwnerID: "missing", Failures: 10},
+			}, nil
+		},
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			if ownerID == "missing" {
+				return nil, domain.ErrOwnerNotFound
+			}
+			return &domain.Owner{OwnerID: ownerID, Email: ownerID + "@example.com"}, nil
+		},
+		markAuthFailureAlertedFunc: func(ctx context.Context, subject string, alertedAt time.Time) error {
+			marked = append(marked, subject)
+			return nil
+		},
+	}
+
+	var sent []email.AuthFailureAlert
+	emailSvc := &mockEmailService{
+		sendAuthFailureAlertFunc: func(ctx context.Context, to email.Recipient, alert email.AuthFailureAlert) error {
+			if to.OwnerID == "broken" {
+				return errors.New("SES error")
+			}
+			assert.Equal(t, "test-owner@example.com", to.Email)
+			sent = append(sent, alert)
+			return nil
+		},
+	}
+
+	result, err := SendAuthFailureAlerts(ctx, repo, emailSvc, logger)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 3, result.Processed)
+	assert.Equal(t, 1, result.Alerted)
+	assert.Equal(t, 2, result.Errors)
+	assert.DeepEqual(t, []string{"owner#test-owner"}, marked)
+	assert.Equal(t, 12, sent[0].Failures)
+	assert.Equal(t, "198.51.100.7", sent[0].LastFailureIP)
+}
+
+func TestSendAuthFailureAlerts_ScanError(t *testing.T) {
+	repo := &mockRepository{
+		scanAuthFailureAlertsFunc: func(ctx context.Context) ([]domain.AuthFailure, error) {
+			return nil, errors.New("database connection failed")
+		},
+	}
+
+	result, err := SendAuthFailureAlerts(context.Background(), repo, &mockEmailService{}, newTestLogger())
+
+	assert.Assert(t, result == nil)
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// dnsError maps a DNS service failure to a request error.
+// Throttling or an open circuit breaker becomes a 503 with Retry-After so clients
+// back off instead of treating it as a server bug.
+func dnsError(err error, description string) *response.RequestError {
+	if retryAfter, ok := dns.RetryAfter(err); ok {
+		return &response.RequestError{
+			Status:      http.StatusServiceUnavailable,
+			Description: dns.ErrUnavailable.Error(),
+			RetryAfter:  max(1, int(math.Ceil(retryAfter.Seconds()))),
+		}
+	}
+	return &response.RequestError{
+		Status:      http.StatusInternalServerError,
+		Description: description,
+	}
+}
@@ -3,9 +3,37 @@ package handlers
 import (
 	"io"
 	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/signing"
+	"github.com/grocky/ddns-service/internal/token"
+	"gotest.tools/assert"
 )
 
+// testSigningSecrets seals the signing secrets of keys the handlers issue.
+var testSigningSecrets *signing.SecretBox
+
+func TestMain(m *testing.M) {
+	secrets, err := signing.NewSecretBox("test-signing-secret-key-0123456789abcdef")
+	if err != nil {
+		panic(err)
+	}
+	testSigningSecrets = secrets
+	auth.ConfigureSigning(secrets)
+	os.Exit(m.Run())
+}
+
 // newTestLogger creates a logger that discards output for testing.
 func newTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
+
+// newTestSigner creates a token signer with a fixed secret for testing.
+func newTestSigner(t *testing.T) *token.Signer {
+	t.Helper()
+	signer, err := token.NewSigner("0123456789abcdef0123456789abcdef")
+	assert.NilError(t, err)
+	return signer
+}
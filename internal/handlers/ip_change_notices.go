@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/repository"
+)
+
+// IPChangeDigestResult represents the result of the IP change digest run.
+type IPChangeDigestResult struct {
+	Processed int `json:"processed"`
+	Sent      int `json:"sent"`
+	Errors    int `json:"errors"`
+}
+
+// SendIPChangeDigests emails owners the IP changes that were queued because
+// they came within domain.IPChangeDigestInterval of the previous email, one
+// digest per location. Called by EventBridge every few minutes. Changes of
+// owners that have since opted out are dropped.
+func SendIPChangeDigests(
+	ctx context.Context,
+	repo repository.Repository,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) (*IPChangeDigestResult, error) {
+	logger.Info("starting IP change digests")
+
+	result := &IPChangeDigestResult{}
+	now := time.Now().UTC()
+
+	mappings, err := repo.ScanPendingIPChanges(ctx)
+	if err != nil {
+		logger.Error("failed to scan pending IP changes", "error", err)
+		return nil, err
+	}
+
+	result.Processed = len(mappings)
+	logger.Info("found locations with pending IP changes", "count", len(mappings))
+
+	owners := make(map[string]*domain.Owner)
+	for _, mapping := range mappings {
+		if !mapping.IPChangeNotificationDue(now) {
+			continue
+		}
+
+		owner, ok := owners[mapping.OwnerID]
+		if !ok {
+			owner, err = repo.GetOwner(ctx, mapping.OwnerID)
+			if err != nil {
+				logger.Error("failed to get owner", "error", err, "ownerId", mapping.OwnerID)
+				result.Errors++
+				continue
+			}
+			owners[mapping.OwnerID] = owner
+		}
+
+		if !owner.Notifications.NotifiesIPChange(mapping.LocationName) {
+			if err := repo.MarkIPChangesNotified(ctx, mapping.OwnerID, mapping.LocationName, now); err != nil {
+				logger.Error("failed to drop IP changes", "error", err, "ownerId", mapping.OwnerID, "location", mapping.LocationName)
+				result.Errors++
+			}
+			continue
+		}
+
+		// A failure marking the changes means they may be sent again on the next run
+		if err := sendIPChangeNotice(ctx, owner, mapping, now, repo, emailSvc); err != nil {
+			logger.Error("failed to send IP change digest", "error", err, "ownerId", mapping.OwnerID, "location", mapping.LocationName)
+			result.Errors++
+			continue
+		}
+
+		result.Sent++
+		logger.Info("IP change digest sent", "ownerId", mapping.OwnerID, "location", mapping.LocationName, "changes", len(mapping.PendingIPChanges))
+	}
+
+	logger.Info("IP change digests completed",
+		"processed", result.Processed,
+		"sent", result.Sent,
+		"errors", result.Errors,
+	)
+
+	return result, nil
+}
+
+// sendIPChangeNotice emails the owner a mapping's pending IP changes and marks
+// them sent.
+func sendIPChangeNotice(
+	ctx context.Context,
+	owner *domain.Owner,
+	mapping domain.IPMapping,
+	now time.Time,
+	repo repository.Repository,
+	emailSvc email.Service,
+) error {
+	notice := email.IPChangeNotice{
+		Location:       mapping.LocationName,
+		Subdomain:      dns.FormatFQDN(mappingSubdomain(mapping)),
+		Changes:        make([]email.IPChange, 0, len(mapping.PendingIPChanges)),
+		DigestInterval: domain.IPChangeDigestInterval,
+	}
+	for _, change := range mapping.PendingIPChanges {
+		notice.Changes = append(notice.Changes, email.IPChange{
+			PreviousIP: change.PreviousIP,
+			IP:         change.IP,
+			ChangedAt:  change.ChangedAt,
+		})
+	}
+
+	if err := emailSvc.SendIPChangeNotice(ctx, emailRecipient(owner), notice); err != nil {
+		return err
+	}
+	return repo.MarkIPChangesNotified(ctx, mapping.OwnerID, mapping.LocationName, now)
+}
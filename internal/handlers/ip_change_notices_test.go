@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"gotest.tools/assert"
+)
+
+func TestSendIPChangeDigests(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	now := time.Now().UTC()
+	longAgo := now.Add(-2 * time.Hour)
+	recently := now.Add(-10 * time.Minute)
+	changes := []domain.IPChange{
+		{PreviousIP: "192.0.2.1", IP: "203.0.113.50", ChangedAt: now.Add(-30 * time.Minute)},
+		{PreviousIP: "203.0.113.50", IP: "198.51.100.7", ChangedAt: now.Add(-20 * time.Minute)},
+	}
+
+	repo := &mockRepository{
+		scanPendingIPChangesFunc: func(ctx context.Context) ([]domain.IPMapping, error) {
+			return []domain.IPMapping{
+				{OwnerID: "test-owner", LocationName: "home", Subdomain: "a3f8c2d1", PendingIPChanges: changes, IPChangeNotifiedAt: &longAgo},
+				{OwnerID: "test-owner", LocationName: "office", PendingIPChanges: changes, IPChangeNotifiedAt: &recently},
+				{OwnerID: "test-owner", LocationName: "cabin", PendingIPChanges: changes, IPChangeNotifiedAt: &longAgo},
+				{OwnerID: "opted-out", LocationName: "home", PendingIPChanges: changes, IPChangeNotifiedAt: &longAgo},
+				{OwnerID: "broken", LocationName: "home", PendingIPChanges: changes, IPChangeNotifiedAt: &longAgo},
+				{OwnerID: "missing", LocationName: "home", PendingIPChanges: changes, IPChangeNotifiedAt: &longAgo},
+			}, nil
+		},
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			switch ownerID {
+			case "missing":
+				return nil, domain.ErrOwnerNotFound
+			case "opted-out":
+				return &domain.Owner{OwnerID: ownerID, Email: ownerID + "@example.com"}, nil
+			}
+			return &domain.Owner{
+				OwnerID:       ownerID,
+				Email:         ownerID + "@example.com",
+				Notifications: &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{Locations: []string{"home", "office"}}},
+			}, nil
+		},
+	}
+
+	var marked []string
+	repo.markIPChangesNotifiedFunc = func(ctx context.Context, ownerID, location string, notifiedAt time.Time) error {
+		marked = append(marked, ownerID+"/"+location)
+		return nil
+	}
+
+	var sent []email.IPChangeNotice
+	emailSvc := &mockEmailService{
+		sendIPChangeNoticeFunc: func(ctx context.Context, to email.Recipient, notice email.IPChangeNotice) error {
+			if to.OwnerID == "broken" {
+				return errors.New("SES error")
+			}
+			assert.Equal(t, "test-owner@example.com", to.Email)
+			sent = append(sent, notice)
+			return nil
+		},
+	}
+
+	result, err := SendIPChangeDigests(ctx, repo, emailSvc, logger)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 6, result.Processed)
+	assert.Equal(t, 1, result.Sent)
+	assert.Equal(t, 2, result.Errors)
+	assert.DeepEqual(t, []string{"test-owner/home", "test-owner/cabin", "opted-out/home"}, marked)
+	assert.Equal(t, 1, len(sent))
+	assert.Equal(t, "home", sent[0].Location)
+	assert.Equal(t, "a3f8c2d1.grocky.net", sent[0].Subdomain)
+	assert.Equal(t, 2, len(sent[0].Changes))
+	assert.Equal(t, domain.IPChangeDigestInterval, sent[0].DigestInterval)
+}
+
+func TestSendIPChangeDigests_ScanError(t *testing.T) {
+	repo := &mockRepository{
+		scanPendingIPChangesFunc: func(ctx context.Context) ([]domain.IPMapping, error) {
+			return nil, errors.New("database connection failed")
+		},
+	}
+
+	result, err := SendIPChangeDigests(context.Background(), repo, &mockEmailService{}, newTestLogger())
+
+	assert.Assert(t, result == nil)
+	assert.Assert(t, err != nil)
+}
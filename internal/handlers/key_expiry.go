@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/repository"
+)
+
+// KeyExpiryResult represents the result of the key expiry warning run.
+type KeyExpiryResult struct {
+	Processed int `json:"processed"`
+	Warned    int `json:"warned"`
+	Errors    int `json:"errors"`
+}
+
+// SendKeyExpiryWarnings emails owners whose API keys expire within
+// domain.KeyExpiryWarningWindow. Called by EventBridge on a daily schedule;
+// each key is only warned about once.
+func SendKeyExpiryWarnings(
+	ctx context.Context,
+	repo repository.Repository,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) (*KeyExpiryResult, error) {
+	logger.Info("starting API key expiry warnings")
+
+	result := &KeyExpiryResult{}
+	now := time.Now().UTC()
+
+	keys, err := repo.ScanExpiringAPIKeys(ctx, now.Add(domain.KeyExpiryWarningWindow))
+	if err != nil {
+		logger.Error("failed to scan expiring API keys", "error", err)
+		return nil, err
+	}
+
+	result.Processed = len(keys)
+	logger.Info("found expiring API keys", "count", len(keys))
+
+	owners := make(map[string]*domain.Owner)
+	for _, key := range keys {
+		owner, ok := owners[key.OwnerID]
+		if !ok {
+			owner, err = repo.GetOwner(ctx, key.OwnerID)
+			if err != nil {
+				logger.Error("failed to get owner", "error", err, "ownerId", key.OwnerID)
+				result.Errors++
+				continue
+			}
+			owners[key.OwnerID] = owner
+		}
+
+		warning := email.KeyExpiry{
+			KeyID:     key.KeyID,
+			Label:     key.Label,
+			ExpiresAt: *key.ExpiresAt,
+			Primary:   key.IsPrimary(),
+		}
+		if err := emailSvc.SendKeyExpiryWarning(ctx, emailRecipient(owner), warning); err != nil {
+			logger.Error("failed to send key expiry warning", "error", err, "ownerId", key.OwnerID, "keyId", key.KeyID)
+			result.Errors++
+			continue
+		}
+
+		// A failure here means the owner may be warned again tomorrow, which is acceptable
+		if err := repo.MarkAPIKeyExpiryWarned(ctx, key.OwnerID, key.KeyID, now); err != nil {
+			logger.Error("failed to mark key expiry warning", "error", err, "ownerId", key.OwnerID, "keyId", key.KeyID)
+			result.Errors++
+			continue
+		}
+
+		result.Warned++
+		logger.Info("key expiry warning sent", "ownerId", key.OwnerID, "keyId", key.KeyID, "expiresAt", key.ExpiresAt)
+	}
+
+	logger.Info("API key expiry warnings completed",
+		"processed", result.Processed,
+		"warned", result.Warned,
+		"errors", result.Errors,
+	)
+
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"gotest.tools/assert"
+)
+
+func TestSendKeyExpiryWarnings(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	expiresAt := time.Now().Add(3 * 24 * time.Hour).UTC()
+	getOwnerCalls := 0
+	var marked []string
+
+	repo := &mockRepository{
+		scanExpiringAPIKeysFunc: func(ctx context.Context, before time.Time) ([]domain.APIKey, error) {
+			assert.Assert(t, time.Until(before) > 6*24*time.Hour)
+			return []domain.APIKey{
+				{OwnerID: "test-owner", KeyID: domain.PrimaryKeyID, Label: domain.PrimaryKeyID, ExpiresAt: &expiresAt},
+				{OwnerID: "test-owner", KeyID: "k1", Label: "cabin pi", ExpiresAt: &expiresAt},
+				{OwnerID: "test-owner", KeyID: "k2", Label: "broken", ExpiresAt: &expiresAt},
+			}, nil
+		},
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			getOwnerCalls++
+			return &domain.Owner{OwnerID: "test-owner", Email: "user@example.com"}, nil
+		},
+		markAPIKeyExpiryWarnedFunc: func(ctx context.Context, ownerID, keyID string, warnedAt time.Time) error {
+			marked = append(marked, keyID)
+			return nil
+		},
+	}
+
+	var sent []email.KeyExpiry
+	emailSvc := &mockEmailService{
+		sendKeyExpiryWarningFunc: func(ctx context.Context, to email.Recipient, key email.KeyExpiry) error {
+			assert.Equal(t, "user@example.com", to.Email)
+			if key.KeyID == "k2" {
+				return errors.New("SES error")
+			}
+			sent = append(sent, key)
+			return nil
+		},
+	}
+
+	result, err := SendKeyExpiryWarnings(ctx, repo, emailSvc, logger)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 3, result.Processed)
+	assert.Equal(t, 2, result.Warned)
+	assert.Equal(t, 1, result.Errors)
+	assert.Equal(t, 1, getOwnerCalls, "owners should be fetched once")
+	assert.DeepEqual(t, []string{domain.PrimaryKeyID, "k1"}, marked)
+	assert.Assert(t, sent[0].Primary)
+	assert.Assert(t, !sent[1].Primary)
+}
+
+func TestSendKeyExpiryWarnings_ScanError(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	repo := &mockRepository{
+		scanExpiringAPIKeysFunc: func(ctx context.Context, before time.Time) ([]domain.APIKey, error) {
+			return nil, errors.New("database connection failed")
+		},
+	}
+
+	result, err := SendKeyExpiryWarnings(ctx, repo, &mockEmailService{}, logger)
+
+	assert.Assert(t, result == nil)
+	assert.Assert(t, err != nil)
+}
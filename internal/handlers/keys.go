@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// ListAPIKeys handles GET /owners/{ownerId}/keys requests.
+// It returns the primary key followed by the owner's additional keys, without secrets.
+func ListAPIKeys(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.APIKeyListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "ListAPIKeys", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "ListAPIKeys")
+
+	identity, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.APIKeyListResponse{}, authErr
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, ownerID)
+	if err != nil {
+		logger.Error("failed to list API keys", "error", err)
+		return response.APIKeyListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list API keys",
+		}
+	}
+
+	bodies := make([]response.APIKeyBody, 0, len(keys)+2)
+	bodies = append(bodies, apiKeyBody(domain.PrimaryAPIKey(*identity.Owner)))
+	if previous, ok := activePreviousKey(identity.Owner, time.Now().UTC()); ok {
+		bodies = append(bodies, apiKeyBody(previous))
+	}
+	for _, key := range keys {
+		bodies = append(bodies, apiKeyBody(key))
+	}
+
+	return response.APIKeyListResponse{
+		Status: http.StatusOK,
+		Body: response.APIKeyListBody{
+			OwnerID: ownerID,
+			Keys:    bodies,
+		},
+	}, nil
+}
+
+// CreateAPIKey handles POST /owners/{ownerId}/keys requests.
+// The new key is only returned in this response; only its hash is stored.
+func CreateAPIKey(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.APIKeyResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "CreateAPIKey", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "CreateAPIKey")
+
+	var req domain.CreateAPIKeyRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.APIKeyResponse{}, authErr
+	}
+
+	apiKey, keyID, err := auth.GenerateAPIKey()
+	if err != nil {
+		logger.Error("failed to generate API key", "error", err)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to generate API key",
+		}
+	}
+	signingSecret, sealedSecret, err := auth.NewSigningSecret(keyID)
+	if err != nil {
+		logger.Error("failed to generate signing secret", "error", err)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to generate API key",
+		}
+	}
+
+	now := time.Now().UTC()
+	key := domain.APIKey{
+		OwnerID:       ownerID,
+		KeyID:         keyID,
+		Label:         req.Label,
+		KeyHash:       auth.HashAPIKey(apiKey),
+		SigningSecret: sealedSecret,
+		Scopes:        req.Scopes,
+		Locations:     req.Locations,
+		CreatedAt:     now,
+		ExpiresAt:     domain.KeyExpiry(now, req.ExpiresInDays),
+	}
+
+	// The repository enforces the quota in the same write that stores the key
+	if err := repo.CreateAPIKey(ctx, key); err != nil {
+		if errors.Is(err, domain.ErrAPIKeyQuotaExceeded) {
+			logger.Warn("API key quota exceeded", "ownerId", ownerID)
+			return response.APIKeyResponse{}, &response.RequestError{
+				Status:      http.StatusForbidden,
+				Description: fmt.Sprintf("%s: maximum %d keys", domain.ErrAPIKeyQuotaExceeded, domain.MaxAPIKeys),
+			}
+		}
+		logger.Error("failed to save API key", "error", err)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save API key",
+		}
+	}
+
+	logger.Info("API key created", "ownerId", ownerID, "keyId", keyID, "scopes", key.Scopes, "locations", key.Locations)
+
+	body := apiKeyBody(key)
+	body.APIKey = apiKey
+	body.SigningSecret = signingSecret
+	return response.APIKeyResponse{
+		Status: http.StatusCreated,
+		Body:   body,
+	}, nil
+}
+
+// RevokeAPIKey handles DELETE /owners/{ownerId}/keys/{keyId} requests.
+// The primary key can't be revoked; rotate it instead. The previous key can be
+// revoked to end a rotation grace period early.
+func RevokeAPIKey(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	keyID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.APIKeyResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "RevokeAPIKey", "ownerId", ownerID, "keyId", keyID)
+	defer logger.Info("handler completed", "handler", "RevokeAPIKey")
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.APIKeyResponse{}, authErr
+	}
+
+	if keyID == domain.PrimaryKeyID {
+		logger.Warn("attempt to revoke primary key", "ownerId", ownerID)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "the primary key cannot be revoked, rotate it instead",
+		}
+	}
+
+	var err error
+	if keyID == domain.PreviousKeyID {
+		err = repo.RevokePreviousOwnerKey(ctx, ownerID)
+	} else {
+		err = repo.DeleteAPIKey(ctx, ownerID, keyID)
+	}
+	if err != nil {
+		if repository.IsAPIKeyNotFound(err) {
+			logger.Warn("API key not found", "ownerId", ownerID, "keyId", keyID)
+			return response.APIKeyResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "API key not found",
+			}
+		}
+		logger.Error("failed to delete API key", "error", err)
+		return response.APIKeyResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to revoke API key",
+		}
+	}
+
+	logger.Info("API key revoked", "ownerId", ownerID, "keyId", keyID)
+
+	return response.APIKeyResponse{
+		Status: http.StatusOK,
+		Body: response.APIKeyBody{
+			OwnerID: ownerID,
+			KeyID:   keyID,
+			Revoked: true,
+		},
+	}, nil
+}
+
+// activePreviousKey returns the owner's previous key while its grace period lasts.
+func activePreviousKey(owner *domain.Owner, now time.Time) (domain.APIKey, bool) {
+	previous, ok := domain.PreviousAPIKey(*owner)
+	if !ok || previous.IsExpired(now) {
+		return domain.APIKey{}, false
+	}
+	return previous, true
+}
+
+func apiKeyBody(key domain.APIKey) response.APIKeyBody {
+	body := response.APIKeyBody{
+		OwnerID:   key.OwnerID,
+		KeyID:     key.KeyID,
+		Label:     key.Label,
+		Scopes:    key.Scopes,
+		Locations: key.Locations,
+	}
+	if !key.CreatedAt.IsZero() {
+		body.CreatedAt = key.CreatedAt.Format(time.RFC3339)
+	}
+	if key.ExpiresAt != nil {
+		body.ExpiresAt = key.ExpiresAt.Format(time.RFC3339)
+		body.Expired = key.IsExpired(time.Now())
+	}
+	if key.LastUsedAt != nil {
+		body.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+		body.LastUsedIP = key.LastUsedIP
+	}
+	return body
+}
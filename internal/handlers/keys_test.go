@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+func TestCreateAPIKey_Success(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var created domain.APIKey
+	repo.createAPIKeyFunc = func(ctx context.Context, key domain.APIKey) error {
+		created = key
+		return nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"label":"cabin pi","scopes":["update","Update"],"locations":["cabin"]}`,
+	}
+
+	resp, err := CreateAPIKey(ctx, request, "test-owner", repo, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Assert(t, auth.ValidateAPIKeyFormat(resp.Body.APIKey))
+	assert.Equal(t, auth.HashAPIKey(resp.Body.APIKey), created.KeyHash)
+	assert.Equal(t, created.KeyID, resp.Body.KeyID)
+	opened, openErr := testSigningSecrets.Open(created.SigningSecret, created.KeyID)
+	assert.NilError(t, openErr)
+	assert.Equal(t, resp.Body.SigningSecret, opened)
+	assert.DeepEqual(t, []string{domain.ScopeUpdate}, created.Scopes)
+	assert.DeepEqual(t, []string{"cabin"}, created.Locations)
+}
+
+func TestCreateAPIKey_Errors(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	restrictedKey := "ddns_sk_restrictedABCDEFGHIJKLMNOPQRSTUVWXYZabc"
+
+	testCases := []struct {
+		name           string
+		token          string
+		body           string
+		keys           []domain.APIKey
+		expectedStatus int
+	}{
+		{
+			name:           "invalid scope",
+			token:          apiKey,
+			body:           `{"label":"cabin pi","scopes":["admin"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "key without manage scope",
+			token: restrictedKey,
+			body:  `{"label":"cabin pi","scopes":["update"]}`,
+			keys: []domain.APIKey{
+				{OwnerID: "test-owner", KeyID: "cabin", KeyHash: auth.HashAPIKey(restrictedKey), Scopes: []string{domain.ScopeUpdate}},
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:  "location-restricted manage key",
+			token: restrictedKey,
+			body:  `{"label":"cabin pi","scopes":["update"]}`,
+			keys: []domain.APIKey{
+				{OwnerID: "test-owner", KeyID: "cabin", KeyHash: auth.HashAPIKey(restrictedKey), Scopes: domain.AllScopes, Locations: []string{"cabin"}},
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.listAPIKeysFunc = func(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+				return tc.keys, nil
+			}
+			repo.createAPIKeyFunc = func(ctx context.Context, key domain.APIKey) error {
+				t.Fatal("key should not be created")
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + tc.token},
+				Body:    tc.body,
+			}
+
+			_, err := CreateAPIKey(ctx, request, "test-owner", repo, logger)
+
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestCreateAPIKey_QuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.createAPIKeyFunc = func(ctx context.Context, key domain.APIKey) error {
+		return domain.ErrAPIKeyQuotaExceeded
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"label":"cabin pi","scopes":["update"]}`,
+	}
+
+	_, err := CreateAPIKey(ctx, request, "test-owner", repo, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusForbidden, err.Status)
+	assert.Assert(t, strings.Contains(err.Description, domain.ErrAPIKeyQuotaExceeded.Error()))
+}
+
+func TestListAPIKeys_IncludesPrimary(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.listAPIKeysFunc = func(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+		return []domain.APIKey{
+			{OwnerID: "test-owner", KeyID: "k1", Label: "cabin pi", KeyHash: "secret", Scopes: []string{domain.ScopeUpdate}},
+		}, nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := ListAPIKeys(ctx, request, "test-owner", repo, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, 2, len(resp.Body.Keys))
+	assert.Equal(t, domain.PrimaryKeyID, resp.Body.Keys[0].KeyID)
+	assert.Equal(t, "k1", resp.Body.Keys[1].KeyID)
+	assert.Equal(t, "", resp.Body.Keys[1].APIKey)
+}
+
+func TestListAPIKeys_IncludesPreviousDuringGracePeriod(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name         string
+		expiresIn    time.Duration
+		wantPrevious bool
+	}{
+		{name: "grace period running", expiresIn: time.Hour, wantPrevious: true},
+		{name: "grace period over", expiresIn: -time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expiresAt := time.Now().UTC().Add(tc.expiresIn)
+			repo := newRecordSetTestRepo(apiKey)
+			repo.getOwnerFunc = func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+				return &domain.Owner{
+					OwnerID:                 "test-owner",
+					APIKeyHash:              auth.HashAPIKey(apiKey),
+					PreviousAPIKeyHash:      "oldhash",
+					PreviousAPIKeyExpiresAt: &expiresAt,
+				}, nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+			}
+
+			resp, err := ListAPIKeys(context.Background(), request, "test-owner", repo, newTestLogger())
+
+			assert.Assert(t, err == nil, "expected no error, got %v", err)
+			if !tc.wantPrevious {
+				assert.Equal(t, 1, len(resp.Body.Keys))
+				return
+			}
+			assert.Equal(t, 2, len(resp.Body.Keys))
+			assert.Equal(t, domain.PreviousKeyID, resp.Body.Keys[1].KeyID)
+			assert.Equal(t, expiresAt.Format(time.RFC3339), resp.Body.Keys[1].ExpiresAt)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name           string
+		keyID          string
+		expectedStatus int
+	}{
+		{"existing key", "k1", http.StatusOK},
+		{"unknown key", "missing", http.StatusNotFound},
+		{"primary key", domain.PrimaryKeyID, http.StatusBadRequest},
+		{"previous key", domain.PreviousKeyID, http.StatusOK},
+		{"no previous key", domain.PreviousKeyID, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.deleteAPIKeyFunc = func(ctx context.Context, ownerID, keyID string) error {
+				if keyID == "k1" {
+					return nil
+				}
+				return domain.ErrAPIKeyNotFound
+			}
+			repo.revokePreviousOwnerKeyFunc = func(ctx context.Context, ownerID string) error {
+				if tc.name == "previous key" {
+					return nil
+				}
+				return domain.ErrAPIKeyNotFound
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+			}
+
+			resp, err := RevokeAPIKey(ctx, request, "test-owner", tc.keyID, repo, logger)
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.Assert(t, err == nil, "expected no error, got %v", err)
+				assert.Assert(t, resp.Body.Revoked)
+				return
+			}
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestUpdate_RestrictedKeyOtherLocation(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	cabinKey := "ddns_sk_cabinKeyABCDEFGHIJKLMNOPQRSTUVWXYZabcdef"
+	repo := newRecordSetTestRepo("ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop")
+	repo.listAPIKeysFunc = func(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+		return []domain.APIKey{
+			{OwnerID: "test-owner", KeyID: "cabin", KeyHash: auth.HashAPIKey(cabinKey), Scopes: []string{domain.ScopeUpdate}, Locations: []string{"cabin"}},
+		}, nil
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			t.Fatal("DNS should not be updated")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + cabinKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"203.0.113.99"}`,
+	}
+
+	_, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusForbidden, err.Status)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// SetLocale handles PUT /owners/{ownerId}/locale requests.
+// It sets the language the owner's emails are written in; an empty locale
+// restores the default.
+func SetLocale(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.LocaleResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "SetLocale", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "SetLocale")
+
+	var req domain.SetLocaleRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.LocaleResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.LocaleResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.LocaleResponse{}, authErr
+	}
+
+	if err := repo.SetOwnerLocale(ctx, ownerID, req.Locale); err != nil {
+		if errors.Is(err, domain.ErrOwnerNotFound) {
+			return response.LocaleResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: domain.ErrOwnerNotFound.Error(),
+			}
+		}
+		logger.Error("failed to save locale", "error", err)
+		return response.LocaleResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save locale",
+		}
+	}
+
+	logger.Info("locale updated", "ownerId", ownerID, "locale", req.Locale)
+
+	return response.LocaleResponse{
+		Status: http.StatusOK,
+		Body: response.LocaleBody{
+			OwnerID: ownerID,
+			Locale:  req.Locale,
+		},
+	}, nil
+}
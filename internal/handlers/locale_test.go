@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"gotest.tools/assert"
+)
+
+func TestSetLocale(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedLocale string
+	}{
+		{name: "language", body: `{"locale":"de"}`, expectedLocale: "de"},
+		{name: "normalized", body: `{"locale":" pt_BR "}`, expectedLocale: "pt-br"},
+		{name: "clear", body: `{"locale":""}`, expectedLocale: ""},
+		{name: "omitted", body: `{}`, expectedLocale: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+			repo := newRecordSetTestRepo(apiKey)
+
+			called := false
+			repo.setOwnerLocaleFunc = func(ctx context.Context, ownerID, locale string) error {
+				assert.Equal(t, "test-owner", ownerID)
+				assert.Equal(t, tc.expectedLocale, locale)
+				called = true
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    tc.body,
+			}
+
+			resp, err := SetLocale(context.Background(), request, "test-owner", repo, newTestLogger())
+
+			assert.Assert(t, err == nil, "expected no error, got %v", err)
+			assert.Equal(t, http.StatusOK, resp.Status)
+			assert.Assert(t, called)
+			assert.Equal(t, "test-owner", resp.Body.OwnerID)
+			assert.Equal(t, tc.expectedLocale, resp.Body.Locale)
+		})
+	}
+}
+
+func TestSetLocale_Errors(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name           string
+		apiKey         string
+		body           string
+		saveErr        error
+		expectedStatus int
+	}{
+		{name: "invalid JSON", apiKey: apiKey, body: `{"locale":`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid locale", apiKey: apiKey, body: `{"locale":"../en"}`, expectedStatus: http.StatusBadRequest},
+		{name: "wrong key", apiKey: "ddns_sk_ZYXWVUTSRQPONMLKJIHGFEDCBAzyxwvutsrqponmlk", body: `{"locale":"de"}`, expectedStatus: http.StatusUnauthorized},
+		{name: "owner deleted", apiKey: apiKey, body: `{"locale":"de"}`, saveErr: domain.ErrOwnerNotFound, expectedStatus: http.StatusNotFound},
+		{name: "save error", apiKey: apiKey, body: `{"locale":"de"}`, saveErr: errors.New("throttled"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.setOwnerLocaleFunc = func(ctx context.Context, ownerID, locale string) error {
+				if tc.saveErr == nil {
+					t.Fatal("locale should not be saved")
+				}
+				return tc.saveErr
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + tc.apiKey},
+				Body:    tc.body,
+			}
+
+			_, err := SetLocale(context.Background(), request, "test-owner", repo, newTestLogger())
+
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestGetOwner_ShowsLocale(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:    "test-owner",
+				Email:      "user@example.com",
+				APIKeyHash: auth.HashAPIKey(apiKey),
+				Locale:     "de",
+			}, nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := GetOwner(context.Background(), request, "test-owner", repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, "de", resp.Body.Locale)
+}
+
+func TestCreateOwner_Locale(t *testing.T) {
+	var createdOwner domain.Owner
+	repo := &mockRepository{
+		createOwnerFunc: func(ctx context.Context, owner domain.Owner) error {
+			createdOwner = owner
+			return nil
+		},
+	}
+
+	var sentTo email.Recipient
+	emailSvc := &mockEmailService{
+		sendVerificationFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			sentTo = to
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"ownerId":"my-home-lab","email":"user@example.com","locale":"pt_BR"}`,
+	}
+
+	_, err := CreateOwner(context.Background(), request, repo, emailSvc, newTestSigner(t), time.Hour, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, "pt-br", createdOwner.Locale)
+	assert.DeepEqual(t, email.Recipient{Email: "user@example.com", OwnerID: "my-home-lab", Locale: "pt-br"}, sentTo)
+}
+
+func TestCreateOwner_InvalidLocale(t *testing.T) {
+	repo := &mockRepository{
+		createOwnerFunc: func(ctx context.Context, owner domain.Owner) error {
+			t.Fatal("owner should not be created")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"ownerId":"my-home-lab","email":"user@example.com","locale":"not a locale"}`,
+	}
+
+	_, err := CreateOwner(context.Background(), request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusBadRequest, err.Status)
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/damping"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// UpdateLocation handles PATCH /owners/{ownerId}/locations/{location} requests.
+// Turning the wildcard setting on publishes *.{subdomain} with the location's current
+// address; turning it off removes that record. Setting a damping policy with mode
+// "off" removes the location's policy, along with any pending IP. A stale
+// threshold of 0 stops monitoring the location.
+func UpdateLocation(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.LocationResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "UpdateLocation", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "UpdateLocation")
+
+	var req domain.LocationSettingsRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.LocationResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.LocationResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage, Location: location}, repo, logger)
+	if authErr != nil {
+		return response.LocationResponse{}, authErr
+	}
+
+	mapping, reqErr := getLocation(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.LocationResponse{}, reqErr
+	}
+	subdomain := mappingSubdomain(*mapping)
+
+	changed := false
+	if req.Wildcard != nil && *req.Wildcard != mapping.Wildcard {
+		wildcardName := dns.BuildWildcardName(subdomain)
+
+		var err error
+		if *req.Wildcard {
+			err = dnsService.UpsertRecord(ctx, wildcardName, mapping.IP)
+		} else {
+			err = dnsService.DeleteRecord(ctx, wildcardName, mapping.IP)
+		}
+		if err != nil {
+			logger.Error("failed to update wildcard DNS record", "error", err)
+			return response.LocationResponse{}, dnsError(err, "failed to update DNS record")
+		}
+
+		mapping.Wildcard = *req.Wildcard
+		changed = true
+	}
+
+	if req.Damping != nil {
+		if req.Damping.Enabled() {
+			mapping.Damping = req.Damping
+		} else {
+			mapping.Damping = nil
+			damping.ClearPending(mapping)
+		}
+		changed = true
+	}
+
+	if req.StaleAfterSeconds != nil && *req.StaleAfterSeconds != mapping.StaleAfterSeconds {
+		mapping.StaleAfterSeconds = *req.StaleAfterSeconds
+		if !mapping.Monitored() {
+			mapping.StaleAlertedAt = nil
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := repo.Put(ctx, *mapping); err != nil {
+			logger.Error("failed to save mapping", "error", err)
+			return response.LocationResponse{}, &response.RequestError{
+				Status:      http.StatusInternalServerError,
+				Description: "failed to save mapping",
+			}
+		}
+
+		logger.Info("location settings updated",
+			"ownerId", ownerID,
+			"location", location,
+			"wildcard", mapping.Wildcard,
+			"damping", mapping.Damping.Enabled(),
+			"staleAfterSeconds", mapping.StaleAfterSeconds,
+		)
+	}
+
+	return response.LocationResponse{
+		Status: http.StatusOK,
+		Body:   locationBody(*mapping, subdomain),
+	}, nil
+}
+
+// DeleteLocation handles DELETE /owners/{ownerId}/locations/{location} requests.
+// It removes the location's custom record sets, any pending ACME challenge, the
+// wildcard record and the address record before deleting the mapping itself.
+// Each step is persisted as it completes, and deleting a DNS record that an
+// earlier attempt already removed succeeds, so a failed delete can be retried.
+func DeleteLocation(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.LocationResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "DeleteLocation", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "DeleteLocation")
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage, Location: location}, repo, logger)
+	if authErr != nil {
+		return response.LocationResponse{}, authErr
+	}
+
+	mapping, reqErr := getLocation(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.LocationResponse{}, reqErr
+	}
+	subdomain := mappingSubdomain(*mapping)
+	body := locationBody(*mapping, subdomain)
+
+	// Custom record sets
+	recordSets, err := repo.ListRecordSets(ctx, ownerID, location)
+	if err != nil {
+		logger.Error("failed to list record sets", "error", err)
+		return response.LocationResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list record sets",
+		}
+	}
+	for _, rs := range recordSets {
+		if err := dnsService.DeleteRecordSet(ctx, dnsRecordSet(subdomain, rs)); err != nil {
+			logger.Error("failed to delete DNS record set", "error", err, "name", rs.Name, "type", rs.Type)
+			return response.LocationResponse{}, dnsError(err, "failed to delete DNS record")
+		}
+		if err := repo.DeleteRecordSet(ctx, ownerID, location, rs.Name, rs.Type); err != nil {
+			logger.Error("failed to delete record set", "error", err)
+			return response.LocationResponse{}, &response.RequestError{
+				Status:      http.StatusInternalServerError,
+				Description: "failed to delete record set",
+			}
+		}
+	}
+
+	// Pending ACME challenge
+	challenge, err := repo.GetChallenge(ctx, ownerID, location)
+	if err != nil && !repository.IsChallengeNotFound(err) {
+		logger.Error("failed to get challenge", "error", err)
+		return response.LocationResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to get challenge",
+		}
+	}
+	if challenge != nil {
+		txtRecordName := dns.BuildACMEChallengeName(challenge.Subdomain)
+		if err := dnsService.DeleteTXTRecord(ctx, txtRecordName, challenge.TxtValue); err != nil {
+			logger.Error("failed to delete TXT record", "error", err)
+			// Continue anyway - the scheduled cleanup only removes stored challenges
+		}
+		if err := repo.DeleteChallenge(ctx, ownerID, location); err != nil {
+			logger.Error("failed to delete challenge from DB", "error", err)
+			return response.LocationResponse{}, &response.RequestError{
+				Status:      http.StatusInternalServerError,
+				Description: "failed to delete challenge",
+			}
+		}
+	}
+
+	// Wildcard record, recorded as removed before touching the address record
+	if mapping.Wildcard {
+		if err := dnsService.DeleteRecord(ctx, dns.BuildWildcardName(subdomain), mapping.IP); err != nil {
+			logger.Error("failed to delete wildcard DNS record", "error", err)
+			return response.LocationResponse{}, dnsError(err, "failed to delete DNS record")
+		}
+		mapping.Wildcard = false
+		if err := repo.Put(ctx, *mapping); err != nil {
+			logger.Error("failed to save mapping", "error", err)
+			return response.LocationResponse{}, &response.RequestError{
+				Status:      http.StatusInternalServerError,
+				Description: "failed to save mapping",
+			}
+		}
+	}
+
+	// Address record
+	if err := dnsService.DeleteRecord(ctx, subdomain, mapping.IP); err != nil {
+		logger.Error("failed to delete DNS record", "error", err)
+		return response.LocationResponse{}, dnsError(err, "failed to delete DNS record")
+	}
+
+	if err := repo.Delete(ctx, ownerID, location); err != nil {
+		logger.Error("failed to delete mapping", "error", err)
+		return response.LocationResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to delete mapping",
+		}
+	}
+
+	logger.Info("location deleted",
+		"ownerId", ownerID,
+		"location", location,
+		"subdomain", subdomain,
+		"recordSets", len(recordSets),
+	)
+
+	body.Deleted = true
+	return response.LocationResponse{
+		Status: http.StatusOK,
+		Body:   body,
+	}, nil
+}
+
+// getLocation loads a location's IP mapping, mapping not-found to a 404.
+func getLocation(ctx context.Context, repo repository.Repository, ownerID, location string, logger *slog.Logger) (*domain.IPMapping, *response.RequestError) {
+	mapping, err := repo.Get(ctx, ownerID, location)
+	if err != nil {
+		if repository.IsMappingNotFound(err) {
+			logger.Warn("no IP mapping for location", "ownerId", ownerID, "location", location)
+			return nil, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "location not found",
+			}
+		}
+		logger.Error("failed to get mapping", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to get mapping",
+		}
+	}
+	return mapping, nil
+}
+
+// mappingSubdomain returns the stored subdomain, falling back to the legacy hash for older mappings.
+func mappingSubdomain(mapping domain.IPMapping) string {
+	if mapping.Subdomain != "" {
+		return mapping.Subdomain
+	}
+	return dns.LegacySubdomain(mapping.OwnerID, mapping.LocationName)
+}
+
+// removeAddressRecords deletes the address record and, if enabled, the wildcard record.
+func removeAddressRecords(ctx context.Context, dnsService dns.Service, subdomain, ip string, wildcard bool) error {
+	if wildcard {
+		if err := dnsService.DeleteRecord(ctx, dns.BuildWildcardName(subdomain), ip); err != nil {
+			return err
+		}
+	}
+	return dnsService.DeleteRecord(ctx, subdomain, ip)
+}
+
+func locationBody(mapping domain.IPMapping, subdomain string) response.LocationBody {
+	body := response.LocationBody{
+		OwnerID:   mapping.OwnerID,
+		Location:  mapping.LocationName,
+		IP:        mapping.IP,
+		Subdomain: dns.FormatFQDN(subdomain),
+		Wildcard:  mapping.Wildcard,
+	}
+	if mapping.Wildcard {
+		body.WildcardName = dns.FormatFQDN(dns.BuildWildcardName(subdomain))
+	}
+	if !mapping.UpdatedAt.IsZero() {
+		body.UpdatedAt = mapping.UpdatedAt.Format(time.RFC3339)
+	}
+	if mapping.LastSeenAt != nil {
+		body.LastSeenAt = mapping.LastSeenAt.Format(time.RFC3339)
+	}
+	if mapping.Monitored() {
+		body.StaleAfterSeconds = mapping.StaleAfterSeconds
+		body.Status = mapping.Status(time.Now().UTC())
+	}
+	if mapping.Damping.Enabled() {
+		body.Damping = &response.DampingBody{
+			Mode:              mapping.Damping.Mode,
+			FlapWindowSeconds: mapping.Damping.FlapWindowSeconds,
+			SettleSeconds:     mapping.Damping.SettleSeconds,
+			Notify:            mapping.Damping.Notify,
+			PendingIP:         mapping.PendingIP,
+		}
+		if mapping.PendingIP != "" {
+			body.Damping.PendingSince = mapping.PendingIPSince.Format(time.RFC3339)
+		}
+	}
+	return body
+}
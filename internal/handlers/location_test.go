@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+func TestUpdateLocation_EnableWildcard(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var saved domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = mapping
+		return nil
+	}
+
+	var upserted []string
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			upserted = append(upserted, subdomain+"="+ip)
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"wildcard":true}`,
+	}
+
+	resp, err := UpdateLocation(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.DeepEqual(t, []string{"*.a3f8c2d1=203.0.113.50"}, upserted)
+	assert.Assert(t, saved.Wildcard)
+	assert.Assert(t, resp.Body.Wildcard)
+	assert.Equal(t, "*.a3f8c2d1.grocky.net", resp.Body.WildcardName)
+}
+
+func TestUpdateLocation_DisableWildcard(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{OwnerID: "test-owner", LocationName: "home", IP: "203.0.113.50", Subdomain: "a3f8c2d1", Wildcard: true}, nil
+	}
+
+	var saved domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = mapping
+		return nil
+	}
+
+	var deleted string
+	dnsSvc := &mockDNSService{
+		deleteRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			deleted = subdomain
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"wildcard":false}`,
+	}
+
+	resp, err := UpdateLocation(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, "*.a3f8c2d1", deleted)
+	assert.Assert(t, !saved.Wildcard)
+	assert.Assert(t, !resp.Body.Wildcard)
+	assert.Equal(t, "", resp.Body.WildcardName)
+}
+
+func TestUpdateLocation_Unchanged(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		t.Fatal("mapping should not be saved when nothing changes")
+		return nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"wildcard":false}`,
+	}
+
+	resp, err := UpdateLocation(ctx, request, "test-owner", "home", repo, &mockDNSService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+}
+
+func TestUpdateLocation_Errors(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name           string
+		location       string
+		body           string
+		dnsErr         error
+		expectedStatus int
+	}{
+		{name: "invalid JSON", location: "home", body: `{`, expectedStatus: http.StatusBadRequest},
+		{name: "no settings", location: "home", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid damping mode", location: "home", body: `{"damping":{"mode":"sometimes"}}`, expectedStatus: http.StatusBadRequest},
+		{name: "flap window too short", location: "home", body: `{"damping":{"mode":"hold","flapWindowSeconds":10}}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown location", location: "office", body: `{"wildcard":true}`, expectedStatus: http.StatusNotFound},
+		{name: "DNS failure", location: "home", body: `{"wildcard":true}`, dnsErr: errors.New("route53 error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			dnsSvc := &mockDNSService{
+				upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+					return tc.dnsErr
+				},
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    tc.body,
+			}
+
+			_, err := UpdateLocation(context.Background(), request, "test-owner", tc.location, repo, dnsSvc, newTestLogger())
+
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestDeleteLocation_RemovesEverything(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{OwnerID: "test-owner", LocationName: "home", IP: "203.0.113.50", Subdomain: "a3f8c2d1", Wildcard: true}, nil
+	}
+	repo.listRecordSetsFunc = func(ctx context.Context, ownerID, location string) ([]domain.RecordSet, error) {
+		return []domain.RecordSet{{OwnerID: "test-owner", LocationName: "home", Type: "TXT", Values: []string{"hello"}}}, nil
+	}
+	repo.getChallengeFunc = func(ctx context.Context, ownerID, location string) (*domain.ACMEChallenge, error) {
+		return &domain.ACMEChallenge{OwnerID: "test-owner", LocationName: "home", Subdomain: "a3f8c2d1", TxtValue: "token"}, nil
+	}
+
+	var steps []string
+	repo.deleteRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) error {
+		steps = append(steps, "repo:recordset:"+recordType)
+		return nil
+	}
+	repo.deleteChallengeFunc = func(ctx context.Context, ownerID, location string) error {
+		steps = append(steps, "repo:challenge")
+		return nil
+	}
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		assert.Assert(t, !mapping.Wildcard)
+		steps = append(steps, "repo:put")
+		return nil
+	}
+	repo.deleteFunc = func(ctx context.Context, ownerID, location string) error {
+		steps = append(steps, "repo:mapping")
+		return nil
+	}
+
+	dnsSvc := &mockDNSService{
+		deleteRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			steps = append(steps, "dns:"+rs.Type+":"+rs.Name)
+			return nil
+		},
+		deleteTXTRecordFunc: func(ctx context.Context, name, value string) error {
+			steps = append(steps, "dns:acme:"+name)
+			return nil
+		},
+		deleteRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			steps = append(steps, "dns:address:"+subdomain)
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := DeleteLocation(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, resp.Body.Deleted)
+	assert.DeepEqual(t, []string{
+		"dns:TXT:a3f8c2d1",
+		"repo:recordset:TXT",
+		"dns:acme:_acme-challenge.a3f8c2d1",
+		"repo:challenge",
+		"dns:address:*.a3f8c2d1",
+		"repo:put",
+		"dns:address:a3f8c2d1",
+		"repo:mapping",
+	}, steps)
+}
+
+func TestDeleteLocation_DNSFailureKeepsMapping(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.deleteFunc = func(ctx context.Context, ownerID, location string) error {
+		t.Fatal("mapping should be kept when DNS removal fails")
+		return nil
+	}
+
+	dnsSvc := &mockDNSService{
+		deleteRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			return errors.New("route53 error")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	_, err := DeleteLocation(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Status)
+}
+
+// fakeRoute53Client publishes record sets in memory and rejects deletes of
+// missing ones the way Route53 does.
+type fakeRoute53Client struct {
+	published map[string]bool
+}
+
+func (f *fakeRoute53Client) ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	for _, change := range params.ChangeBatch.Changes {
+		key := *change.ResourceRecordSet.Name + " " + string(change.ResourceRecordSet.Type)
+		if change.Action == route53types.ChangeActionDelete {
+			if !f.published[key] {
+				return nil, &route53types.InvalidChangeBatch{Messages: []string{
+					fmt.Sprintf("Tried to delete resource record set [name='%s.', type='%s'] but it was not found",
+						*change.ResourceRecordSet.Name, change.ResourceRecordSet.Type),
+				}}
+			}
+			delete(f.published, key)
+			continue
+		}
+		f.published[key] = true
+	}
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func TestDeleteLocation_RetryAfterPersistenceFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	stored := domain.IPMapping{OwnerID: "test-owner", LocationName: "home", IP: "203.0.113.50", Subdomain: "a3f8c2d1", Wildcard: true}
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		mapping := stored
+		return &mapping, nil
+	}
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		stored = mapping
+		return nil
+	}
+	deleteErr := errors.New("dynamodb error")
+	repo.deleteFunc = func(ctx context.Context, ownerID, location string) error {
+		return deleteErr
+	}
+
+	client := &fakeRoute53Client{published: map[string]bool{
+		"a3f8c2d1.grocky.net A":   true,
+		"*.a3f8c2d1.grocky.net A": true,
+	}}
+	dnsSvc := dns.NewRoute53Service(client, "Z123456789", logger)
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	_, err := DeleteLocation(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Status)
+	assert.Equal(t, 0, len(client.published), "DNS records are removed before the mapping")
+
+	// The records are already gone when the delete is retried
+	deleteErr = nil
+	resp, err := DeleteLocation(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Assert(t, resp.Body.Deleted)
+}
+
+func TestDeleteLocation_NotFound(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	_, err := DeleteLocation(context.Background(), request, "test-owner", "office", repo, &mockDNSService{}, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusNotFound, err.Status)
+}
+
+func TestUpdateLocation_Damping(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name     string
+		existing *domain.DampingPolicy
+		body     string
+		expected *domain.DampingPolicy
+	}{
+		{
+			name:     "hold with defaults",
+			body:     `{"damping":{"mode":"hold"}}`,
+			expected: &domain.DampingPolicy{Mode: domain.DampingHold, FlapWindowSeconds: domain.DefaultFlapWindowSeconds},
+		},
+		{
+			name:     "settle",
+			body:     `{"damping":{"mode":"Settle","flapWindowSeconds":1800,"settleSeconds":300,"notify":true}}`,
+			expected: &domain.DampingPolicy{Mode: domain.DampingSettle, FlapWindowSeconds: 1800, SettleSeconds: 300, Notify: true},
+		},
+		{
+			name:     "off",
+			existing: &domain.DampingPolicy{Mode: domain.DampingHold, FlapWindowSeconds: 3600},
+			body:     `{"damping":{"mode":"off"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+				return &domain.IPMapping{
+					OwnerID:      "test-owner",
+					LocationName: "home",
+					IP:           "203.0.113.50",
+					Subdomain:    "a3f8c2d1",
+					Damping:      tc.existing,
+					PendingIP:    "198.51.100.7",
+				}, nil
+			}
+
+			var saved domain.IPMapping
+			repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+				saved = mapping
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    tc.body,
+			}
+
+			resp, err := UpdateLocation(context.Background(), request, "test-owner", "home", repo, &mockDNSService{}, newTestLogger())
+
+			assert.Assert(t, err == nil)
+			assert.DeepEqual(t, tc.expected, saved.Damping)
+			if tc.expected == nil {
+				assert.Assert(t, resp.Body.Damping == nil)
+				assert.Equal(t, "", saved.PendingIP, "turning damping off drops the pending IP")
+				return
+			}
+			assert.Equal(t, tc.expected.Mode, resp.Body.Damping.Mode)
+			assert.Equal(t, tc.expected.FlapWindowSeconds, resp.Body.Damping.FlapWindowSeconds)
+			assert.Equal(t, "198.51.100.7", resp.Body.Damping.PendingIP)
+		})
+	}
+}
+
+func TestUpdateLocation_StaleThreshold(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	lastSeenAt := time.Now().UTC().Add(-2 * time.Hour)
+	alertedAt := time.Now().UTC().Add(-time.Hour)
+
+	testCases := []struct {
+		name           string
+		existing       int
+		body           string
+		expected       int
+		expectedStatus string
+		expectAlerted  bool
+	}{
+		{name: "monitor", body: `{"staleAfterSeconds":3600}`, expected: 3600, expectedStatus: domain.LocationOffline, expectAlerted: true},
+		{name: "stop monitoring", existing: 3600, body: `{"staleAfterSeconds":0}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+				return &domain.IPMapping{
+					OwnerID:           "test-owner",
+					LocationName:      "home",
+					IP:                "203.0.113.50",
+					Subdomain:         "a3f8c2d1",
+					LastSeenAt:        &lastSeenAt,
+					StaleAfterSeconds: tc.existing,
+					StaleAlertedAt:    &alertedAt,
+				}, nil
+			}
+
+			var saved domain.IPMapping
+			repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+				saved = mapping
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    tc.body,
+			}
+
+			resp, err := UpdateLocation(context.Background(), request, "test-owner", "home", repo, &mockDNSService{}, newTestLogger())
+
+			assert.Assert(t, err == nil)
+			assert.Equal(t, tc.expected, saved.StaleAfterSeconds)
+			assert.Equal(t, tc.expectAlerted, saved.StaleAlertedAt != nil)
+			assert.Equal(t, tc.expected, resp.Body.StaleAfterSeconds)
+			assert.Equal(t, tc.expectedStatus, resp.Body.Status)
+			assert.Equal(t, lastSeenAt.Format(time.RFC3339), resp.Body.LastSeenAt)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/grocky/ddns-service/internal/auth"
 	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
 	"github.com/grocky/ddns-service/internal/repository"
 	"github.com/grocky/ddns-service/internal/response"
 )
@@ -46,7 +47,7 @@ func Lookup(
 	}
 
 	// Authenticate - verify API key matches the ownerId in the path
-	_, authErr := auth.Authenticate(ctx, request, ownerID, repo, logger)
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeLookup, Location: location}, repo, logger)
 	if authErr != nil {
 		return response.MappingResponse{}, authErr
 	}
@@ -69,28 +70,36 @@ func Lookup(
 		}
 	}
 
-	// Use stored subdomain if set, otherwise generate hash
+	// Use stored subdomain if set, otherwise the legacy hash
 	subdomain := mapping.Subdomain
 	if subdomain == "" {
-		subdomain = dns.GenerateSubdomain(mapping.OwnerID, mapping.LocationName)
+		subdomain = dns.LegacySubdomain(mapping.OwnerID, mapping.LocationName)
 	}
 	fullSubdomain := dns.FormatFQDN(subdomain)
 
+	status := mapping.Status(time.Now().UTC())
 	logger.Info("mapping found",
 		"ownerId", mapping.OwnerID,
 		"location", mapping.LocationName,
 		"ip", mapping.IP,
 		"subdomain", fullSubdomain,
+		"status", status,
 	)
 
+	body := response.MappingBody{
+		OwnerID:   mapping.OwnerID,
+		Location:  mapping.LocationName,
+		IP:        mapping.IP,
+		Subdomain: fullSubdomain,
+		UpdatedAt: mapping.UpdatedAt.Format(time.RFC3339),
+		Status:    status,
+	}
+	if mapping.LastSeenAt != nil {
+		body.LastSeenAt = mapping.LastSeenAt.Format(time.RFC3339)
+	}
+
 	return response.MappingResponse{
 		Status: http.StatusOK,
-		Body: response.MappingBody{
-			OwnerID:   mapping.OwnerID,
-			Location:  mapping.LocationName,
-			IP:        mapping.IP,
-			Subdomain: fullSubdomain,
-			UpdatedAt: mapping.UpdatedAt.Format(time.RFC3339),
-		},
+		Body:   body,
 	}, nil
 }
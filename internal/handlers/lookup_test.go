@@ -272,3 +272,48 @@ func TestLookup_PathParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestLookup_Status(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	now := time.Now().UTC()
+	recently := now.Add(-5 * time.Minute)
+	longAgo := now.Add(-2 * time.Hour)
+
+	testCases := []struct {
+		name              string
+		lastSeenAt        *time.Time
+		staleAfterSeconds int
+		expectedStatus    string
+	}{
+		{name: "not monitored", lastSeenAt: &longAgo},
+		{name: "online", lastSeenAt: &recently, staleAfterSeconds: 3600, expectedStatus: domain.LocationOnline},
+		{name: "offline", lastSeenAt: &longAgo, staleAfterSeconds: 3600, expectedStatus: domain.LocationOffline},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+				return &domain.IPMapping{
+					OwnerID:           "test-owner",
+					LocationName:      "home",
+					IP:                "203.0.113.50",
+					UpdatedAt:         longAgo,
+					LastSeenAt:        tc.lastSeenAt,
+					StaleAfterSeconds: tc.staleAfterSeconds,
+				}, nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Path:    "/lookup/test-owner/home",
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+			}
+
+			resp, err := Lookup(context.Background(), request, repo, newTestLogger())
+
+			assert.Assert(t, err == nil)
+			assert.Equal(t, tc.expectedStatus, resp.Body.Status)
+			assert.Equal(t, tc.lastSeenAt.Format(time.RFC3339), resp.Body.LastSeenAt)
+		})
+	}
+}
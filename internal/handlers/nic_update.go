@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/webhook"
+)
+
+// dyndns2 return codes.
+const (
+	dynGood     = "good"
+	dynNoChange = "nochg"
+	dynBadAuth  = "badauth"
+	dynNotFQDN  = "notfqdn"
+	dynNoHost   = "nohost"
+	dynNumHost  = "numhost"
+	dynAbuse    = "abuse"
+	dynBadAgent = "badagent"
+	dynDNSErr   = "dnserr"
+	dynServer   = "911"
+)
+
+// maxDynHostnames is the most hostnames one dyndns2 request may update.
+const maxDynHostnames = 20
+
+// NicUpdate handles dyndns2 update requests (GET /nic/update) from routers and
+// ddclient, which can't run ddns-client. Clients authenticate with HTTP Basic auth,
+// using the owner ID as the username and an API key as the password, and name the
+// locations to update by hostname, e.g. hostname=a1b2c3d4.grocky.net. myip is
+// optional; the caller's IP is used when it's missing.
+//
+// The reply is plain text with one return code per hostname. Updates go through
+// the same path as Update, including rate limiting, which is reported as abuse.
+// A dampened change is reported as nochg with the published address.
+func NicUpdate(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	repo repository.Repository,
+	dnsService dns.Service,
+	emailSvc email.Service,
+	webhookSvc webhook.Service,
+	logger *slog.Logger,
+) response.DynDNSResponse {
+	logger.Info("handler started", "handler", "NicUpdate")
+	defer logger.Info("handler completed", "handler", "NicUpdate")
+
+	now := time.Now().UTC()
+
+	identity, authErr := auth.AuthenticateBasic(ctx, request, auth.Permission{Scope: domain.ScopeUpdate, AnyLocation: true}, repo, logger)
+	if authErr != nil {
+		return dynAuthError(authErr)
+	}
+	ownerID := identity.Owner.OwnerID
+
+	hostnames := splitHostnames(request.QueryStringParameters["hostname"])
+	if len(hostnames) == 0 {
+		return dynReply(dynNotFQDN)
+	}
+	if len(hostnames) > maxDynHostnames {
+		logger.Warn("too many hostnames", "ownerId", ownerID, "count", len(hostnames))
+		return dynReply(dynNumHost)
+	}
+
+	ip := request.QueryStringParameters["myip"]
+	if ip == "" {
+		ip = extractClientIP(request)
+		if ip == "" {
+			logger.Warn("could not determine client IP")
+			return dynReply(dynServer)
+		}
+	} else if net.ParseIP(ip) == nil {
+		logger.Warn("invalid IP provided", "ip", ip)
+		return dynReply(dynBadAgent)
+	}
+
+	mappings, err := repo.ListMappingsByOwner(ctx, ownerID)
+	if err != nil {
+		logger.Error("failed to list mappings", "error", err, "ownerId", ownerID)
+		return dynReply(dynServer)
+	}
+
+	result := response.DynDNSResponse{Status: http.StatusOK}
+	codes := make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		code, retryAfter := updateHostname(ctx, identity, hostname, mappings, ip, now, repo, dnsService, emailSvc, webhookSvc, logger)
+		codes = append(codes, code)
+		result.RetryAfter = max(result.RetryAfter, retryAfter)
+	}
+	result.Body = strings.Join(codes, "\n")
+	return result
+}
+
+// updateHostname updates the location published at hostname and returns its
+// dyndns2 return code, plus the seconds to wait when it was rate limited.
+func updateHostname(
+	ctx context.Context,
+	identity *auth.Identity,
+	hostname string,
+	mappings []domain.IPMapping,
+	ip string,
+	now time.Time,
+	repo repository.Repository,
+	dnsService dns.Service,
+	emailSvc email.Service,
+	webhookSvc webhook.Service,
+	logger *slog.Logger,
+) (string, int) {
+	subdomain, ok := parseHostname(hostname)
+	if !ok {
+		logger.Warn("hostname is not a service subdomain", "hostname", hostname)
+		return dynNotFQDN, 0
+	}
+
+	mapping := findMappingBySubdomain(mappings, subdomain)
+	if mapping == nil {
+		logger.Warn("hostname not found for owner", "hostname", hostname, "ownerId", identity.Owner.OwnerID)
+		return dynNoHost, 0
+	}
+
+	// A key restricted to other locations can't see this host
+	if authErr := identity.Authorize(auth.Permission{Scope: domain.ScopeUpdate, Location: mapping.LocationName}, logger); authErr != nil {
+		return dynNoHost, 0
+	}
+
+	resp, reqErr := updateMapping(ctx, identity.Owner, mapping.LocationName, ip, now, repo, dnsService, emailSvc, webhookSvc, logger)
+	if reqErr != nil {
+		switch reqErr.Status {
+		case http.StatusTooManyRequests:
+			return dynAbuse, reqErr.RetryAfter
+		case http.StatusServiceUnavailable:
+			return dynDNSErr, 0
+		default:
+			return dynServer, 0
+		}
+	}
+
+	if resp.Body.Changed {
+		return dynGood + " " + resp.Body.IP, 0
+	}
+	return dynNoChange + " " + resp.Body.IP, 0
+}
+
+// splitHostnames splits the comma-separated hostname parameter, normalising case
+// and dropping any trailing dot.
+func splitHostnames(param string) []string {
+	var hostnames []string
+	for _, hostname := range strings.Split(param, ",") {
+		hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+		if hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames
+}
+
+// parseHostname returns the subdomain of a hostname directly under dns.RootDomain.
+func parseHostname(hostname string) (string, bool) {
+	subdomain, ok := strings.CutSuffix(hostname, "."+dns.RootDomain)
+	if !ok || subdomain == "" || strings.Contains(subdomain, ".") {
+		return "", false
+	}
+	return subdomain, true
+}
+
+// findMappingBySubdomain returns the mapping published at subdomain. Mappings
+// created before subdomains were stored are matched by their hash.
+func findMappingBySubdomain(mappings []domain.IPMapping, subdomain string) *domain.IPMapping {
+	for i, m := range mappings {
+		stored := m.Subdomain
+		if stored == "" {
+			stored = dns.LegacySubdomain(m.OwnerID, m.LocationName)
+		}
+		if stored == subdomain {
+			return &mappings[i]
+		}
+	}
+	return nil
+}
+
+// NicUpdateRejected maps an error from before the update is handled, such as
+// exceeding a request limit, to a dyndns2 reply.
+func NicUpdateRejected(reqErr *response.RequestError) response.DynDNSResponse {
+	return dynAuthError(reqErr)
+}
+
+// dynAuthError maps an authentication failure to a dyndns2 reply. Bad credentials
+// get a 401 so clients that wait for a challenge send theirs.
+func dynAuthError(authErr *response.RequestError) response.DynDNSResponse {
+	switch {
+	case authErr.Status == http.StatusTooManyRequests:
+		return response.DynDNSResponse{Status: http.StatusOK, Body: dynAbuse, RetryAfter: authErr.RetryAfter}
+	case authErr.Status == http.StatusForbidden && authErr.Description == domain.ErrOwnerSuspended.Error():
+		return dynReply(dynAbuse)
+	case authErr.Status >= http.StatusInternalServerError:
+		return dynReply(dynServer)
+	default:
+		return response.DynDNSResponse{Status: http.StatusUnauthorized, Body: dynBadAuth}
+	}
+}
+
+// dynReply returns a single return code.
+func dynReply(code string) response.DynDNSResponse {
+	return response.DynDNSResponse{Status: http.StatusOK, Body: code}
+}
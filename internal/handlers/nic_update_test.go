@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/response"
+	"gotest.tools/assert"
+)
+
+// nicUpdateRequest builds a dyndns2 update request authenticated as ownerID.
+func nicUpdateRequest(ownerID, apiKey string, query map[string]string) events.APIGatewayProxyRequest {
+	credentials := base64.StdEncoding.EncodeToString([]byte(ownerID + ":" + apiKey))
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/nic/update",
+		Headers: map[string]string{
+			"Authorization":   "Basic " + credentials,
+			"X-Forwarded-For": "198.51.100.7",
+		},
+		QueryStringParameters: query,
+	}
+}
+
+func TestNicUpdate(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	now := time.Now().UTC()
+	legacySubdomain := dns.LegacySubdomain("test-owner", "office")
+
+	testCases := []struct {
+		name           string
+		ownerID        string
+		apiKey         string
+		query          map[string]string
+		mapping        domain.IPMapping
+		dnsErr         error
+		expectedStatus int
+		expectedBody   string
+		expectedDNS    bool
+	}{
+		{
+			name:           "IP changed",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net", "myip": "203.0.113.50"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "good 203.0.113.50",
+			expectedDNS:    true,
+		},
+		{
+			name:           "IP unchanged",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net", "myip": "192.168.1.100"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "nochg 192.168.1.100",
+		},
+		{
+			name:           "IP detected from the request",
+			query:          map[string]string{"hostname": "A3F8C2D1.grocky.net."},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "good 198.51.100.7",
+			expectedDNS:    true,
+		},
+		{
+			name:           "mapping without a stored subdomain",
+			query:          map[string]string{"hostname": legacySubdomain + ".grocky.net", "myip": "203.0.113.50"},
+			mapping:        domain.IPMapping{OwnerID: "test-owner", LocationName: "office", IP: "192.168.1.100"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "good 203.0.113.50",
+			expectedDNS:    true,
+		},
+		{
+			name:           "several hostnames",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net,ffffffff.grocky.net,example.com", "myip": "203.0.113.50"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "good 203.0.113.50\nnohost\nnotfqdn",
+			expectedDNS:    true,
+		},
+		{
+			name:           "rate limited",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net", "myip": "203.0.113.50"},
+			mapping:        domain.IPMapping{OwnerID: "test-owner", LocationName: "home", IP: "192.168.1.100", Subdomain: "a3f8c2d1", LastIPChangeAt: now.Add(-time.Minute), HourlyChangeCount: domain.DefaultRateLimitPolicy.MaxChanges},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "abuse",
+		},
+		{
+			name:           "DNS unavailable",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net", "myip": "203.0.113.50"},
+			dnsErr:         &dns.UnavailableError{RetryAfter: time.Minute},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "dnserr",
+			expectedDNS:    true,
+		},
+		{
+			name:           "DNS failure",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net", "myip": "203.0.113.50"},
+			dnsErr:         errors.New("route53 error"),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "911",
+			expectedDNS:    true,
+		},
+		{
+			name:           "wrong key",
+			apiKey:         "ddns_sk_wrongkeywrongkeywrongkeywrongkeywrongkey",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net"},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "badauth",
+		},
+		{
+			name:           "missing hostname",
+			query:          map[string]string{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "notfqdn",
+		},
+		{
+			name:           "nested hostname",
+			query:          map[string]string{"hostname": "www.a3f8c2d1.grocky.net"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "notfqdn",
+		},
+		{
+			name:           "too many hostnames",
+			query:          map[string]string{"hostname": "a.grocky.net,b.grocky.net,c.grocky.net,d.grocky.net,e.grocky.net,f.grocky.net,g.grocky.net,h.grocky.net,i.grocky.net,j.grocky.net,k.grocky.net,l.grocky.net,m.grocky.net,n.grocky.net,o.grocky.net,p.grocky.net,q.grocky.net,r.grocky.net,s.grocky.net,t.grocky.net,u.grocky.net"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "numhost",
+		},
+		{
+			name:           "invalid IP",
+			query:          map[string]string{"hostname": "a3f8c2d1.grocky.net", "myip": "not-an-ip"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "badagent",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping := tc.mapping
+			if mapping.OwnerID == "" {
+				mapping = domain.IPMapping{OwnerID: "test-owner", LocationName: "home", IP: "192.168.1.100", Subdomain: "a3f8c2d1", LastIPChangeAt: now.Add(-2 * time.Hour), HourlyChangeCount: 1}
+			}
+			key := tc.apiKey
+			if key == "" {
+				key = apiKey
+			}
+
+			repo := &mockRepository{
+				getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+					return &domain.Owner{OwnerID: "test-owner", APIKeyHash: auth.HashAPIKey(apiKey)}, nil
+				},
+				listMappingsByOwnerFunc: func(ctx context.Context, ownerID string) ([]domain.IPMapping, error) {
+					assert.Equal(t, "test-owner", ownerID)
+					return []domain.IPMapping{mapping}, nil
+				},
+				getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+					assert.Equal(t, mapping.LocationName, location)
+					return &mapping, nil
+				},
+			}
+
+			dnsUpdated := false
+			dnsSvc := &mockDNSService{
+				upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+					dnsUpdated = true
+					return tc.dnsErr
+				},
+			}
+
+			resp := NicUpdate(ctx, nicUpdateRequest("test-owner", key, tc.query), repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+			assert.Equal(t, tc.expectedStatus, resp.Status)
+			assert.Equal(t, tc.expectedBody, resp.Body)
+			assert.Equal(t, tc.expectedDNS, dnsUpdated)
+		})
+	}
+}
+
+func TestNicUpdate_RestrictedKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: ownerID, APIKeyHash: "other-hash"}, nil
+		},
+		listAPIKeysFunc: func(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+			return []domain.APIKey{{
+				OwnerID:   ownerID,
+				KeyID:     "0123456789ab",
+				KeyHash:   auth.HashAPIKey(apiKey),
+				Scopes:    []string{domain.ScopeUpdate},
+				Locations: []string{"home"},
+			}}, nil
+		},
+		listMappingsByOwnerFunc: func(ctx context.Context, ownerID string) ([]domain.IPMapping, error) {
+			return []domain.IPMapping{
+				{OwnerID: ownerID, LocationName: "home", IP: "203.0.113.50", Subdomain: "a3f8c2d1"},
+				{OwnerID: ownerID, LocationName: "office", IP: "203.0.113.50", Subdomain: "b4e9d3e2"},
+			}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			assert.Equal(t, "home", location)
+			return &domain.IPMapping{OwnerID: ownerID, LocationName: "home", IP: "203.0.113.50", Subdomain: "a3f8c2d1"}, nil
+		},
+	}
+
+	request := nicUpdateRequest("test-owner", apiKey, map[string]string{
+		"hostname": "a3f8c2d1.grocky.net,b4e9d3e2.grocky.net",
+		"myip":     "203.0.113.50",
+	})
+	resp := NicUpdate(ctx, request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, "nochg 203.0.113.50\nnohost", resp.Body)
+}
+
+func TestNicUpdate_SuspendedOwner(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	suspendedAt := time.Now().UTC()
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: ownerID, APIKeyHash: auth.HashAPIKey(apiKey), SuspendedAt: &suspendedAt}, nil
+		},
+	}
+
+	resp := NicUpdate(ctx, nicUpdateRequest("test-owner", apiKey, map[string]string{"hostname": "a3f8c2d1.grocky.net"}), repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, "abuse", resp.Body)
+}
+
+func TestNicUpdateRejected(t *testing.T) {
+	resp := NicUpdateRejected(&response.RequestError{
+		Status:      http.StatusTooManyRequests,
+		Description: domain.ErrTooManyRequests.Error(),
+		RetryAfter:  3,
+	})
+
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, "abuse", resp.Body)
+	assert.Equal(t, 3, resp.RetryAfter)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// SetNotifications handles PUT /owners/{ownerId}/notifications requests.
+// The body replaces the owner's notification preferences; an empty object opts
+// out of every notification.
+func SetNotifications(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.NotificationsResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "SetNotifications", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "SetNotifications")
+
+	var prefs domain.NotificationPreferences
+	if err := json.Unmarshal([]byte(request.Body), &prefs); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.NotificationsResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	prefs = prefs.Normalize()
+	if err := prefs.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.NotificationsResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.NotificationsResponse{}, authErr
+	}
+
+	var stored *domain.NotificationPreferences
+	if !prefs.IsEmpty() {
+		stored = &prefs
+	}
+
+	if err := repo.SetOwnerNotifications(ctx, ownerID, stored); err != nil {
+		if errors.Is(err, domain.ErrOwnerNotFound) {
+			return response.NotificationsResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: domain.ErrOwnerNotFound.Error(),
+			}
+		}
+		logger.Error("failed to save notification preferences", "error", err)
+		return response.NotificationsResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save notification preferences",
+		}
+	}
+
+	logger.Info("notification preferences updated",
+		"ownerId", ownerID,
+		"ipChange", prefs.IPChange != nil,
+		"suppressedSecurityNotices", suppressedSecurityNotices(prefs),
+	)
+
+	body := notificationsBody(prefs)
+	body.OwnerID = ownerID
+	return response.NotificationsResponse{
+		Status: http.StatusOK,
+		Body:   body,
+	}, nil
+}
+
+// notificationsBody converts notification preferences to their JSON body.
+func notificationsBody(prefs domain.NotificationPreferences) response.NotificationsBody {
+	var body response.NotificationsBody
+	if prefs.IPChange != nil {
+		body.IPChange = &response.IPChangeNotificationsBody{Locations: prefs.IPChange.Locations}
+	}
+	if prefs.Security != nil {
+		body.Security = &response.SecurityNotificationsBody{Suppress: prefs.Security.Suppress}
+	}
+	return body
+}
+
+// suppressedSecurityNotices returns the security notices prefs suppresses.
+func suppressedSecurityNotices(prefs domain.NotificationPreferences) []string {
+	if prefs.Security == nil {
+		return nil
+	}
+	return prefs.Security.Suppress
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+func TestSetNotifications(t *testing.T) {
+	testCases := []struct {
+		name              string
+		body              string
+		expectedStored    *domain.NotificationPreferences
+		expectedLocations []string
+		expectedSuppress  []string
+	}{
+		{
+			name:           "every location",
+			body:           `{"ipChange":{}}`,
+			expectedStored: &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{}},
+		},
+		{
+			name:              "some locations",
+			body:              `{"ipChange":{"locations":[" home ","office","home"]}}`,
+			expectedStored:    &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{Locations: []string{"home", "office"}}},
+			expectedLocations: []string{"home", "office"},
+		},
+		{
+			name:             "suppress security notices",
+			body:             `{"security":{"suppress":["keyRotated","acmeChallenge","keyRotated"]}}`,
+			expectedStored:   &domain.NotificationPreferences{Security: &domain.SecurityNotifications{Suppress: []string{"keyRotated", "acmeChallenge"}}},
+			expectedSuppress: []string{"keyRotated", "acmeChallenge"},
+		},
+		{
+			name: "nothing suppressed",
+			body: `{"security":{"suppress":[]}}`,
+		},
+		{
+			name: "opt out",
+			body: `{}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+			repo := newRecordSetTestRepo(apiKey)
+
+			called := false
+			var stored *domain.NotificationPreferences
+			repo.setOwnerNotificationsFunc = func(ctx context.Context, ownerID string, prefs *domain.NotificationPreferences) error {
+				assert.Equal(t, "test-owner", ownerID)
+				called = true
+				stored = prefs
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    tc.body,
+			}
+
+			resp, err := SetNotifications(context.Background(), request, "test-owner", repo, newTestLogger())
+
+			assert.Assert(t, err == nil, "expected no error, got %v", err)
+			assert.Equal(t, http.StatusOK, resp.Status)
+			assert.Assert(t, called)
+			assert.DeepEqual(t, tc.expectedStored, stored)
+			assert.Equal(t, "test-owner", resp.Body.OwnerID)
+			assert.Equal(t, tc.expectedStored != nil && tc.expectedStored.IPChange != nil, resp.Body.IPChange != nil)
+			if resp.Body.IPChange != nil {
+				assert.DeepEqual(t, tc.expectedLocations, resp.Body.IPChange.Locations)
+			}
+			assert.Equal(t, tc.expectedSuppress != nil, resp.Body.Security != nil)
+			if resp.Body.Security != nil {
+				assert.DeepEqual(t, tc.expectedSuppress, resp.Body.Security.Suppress)
+			}
+		})
+	}
+}
+
+func TestSetNotifications_Errors(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	locations := make([]string, domain.MaxNotificationLocations+1)
+	for i := range locations {
+		locations[i] = fmt.Sprintf("%q", fmt.Sprintf("location-%d", i))
+	}
+
+	testCases := []struct {
+		name           string
+		apiKey         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "invalid JSON", apiKey: apiKey, body: `{"ipChange":`, expectedStatus: http.StatusBadRequest},
+		{name: "too many locations", apiKey: apiKey, body: `{"ipChange":{"locations":[` + strings.Join(locations, ",") + `]}}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown security notice", apiKey: apiKey, body: `{"security":{"suppress":["ipChange"]}}`, expectedStatus: http.StatusBadRequest},
+		{name: "wrong key", apiKey: "ddns_sk_ZYXWVUTSRQPONMLKJIHGFEDCBAzyxwvutsrqponmlk", body: `{}`, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.setOwnerNotificationsFunc = func(ctx context.Context, ownerID string, prefs *domain.NotificationPreferences) error {
+				t.Fatal("preferences should not be saved")
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + tc.apiKey},
+				Body:    tc.body,
+			}
+
+			_, err := SetNotifications(context.Background(), request, "test-owner", repo, newTestLogger())
+
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestGetOwner_ShowsNotifications(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:       "test-owner",
+				Email:         "user@example.com",
+				APIKeyHash:    auth.HashAPIKey(apiKey),
+				Notifications: &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{Locations: []string{"home"}}},
+			}, nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := GetOwner(context.Background(), request, "test-owner", repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Assert(t, resp.Body.Notifications != nil)
+	assert.Equal(t, "", resp.Body.Notifications.OwnerID)
+	assert.DeepEqual(t, []string{"home"}, resp.Body.Notifications.IPChange.Locations)
+}
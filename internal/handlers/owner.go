@@ -14,13 +14,20 @@ import (
 	"github.com/grocky/ddns-service/internal/email"
 	"github.com/grocky/ddns-service/internal/repository"
 	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/token"
 )
 
-// CreateOwner handles owner creation requests.
+// CreateOwner handles owner creation requests. The new owner's key doesn't work
+// until the owner follows the verification email sent to their address; owners
+// that aren't verified within verificationWindow expire. Signing up again with the
+// same email address replaces a pending owner and sends a new verification email.
 func CreateOwner(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	repo repository.Repository,
+	emailSvc email.Service,
+	signer *token.Signer,
+	verificationWindow time.Duration,
 	logger *slog.Logger,
 ) (response.OwnerResponse, *response.RequestError) {
 	logger.Info("handler started", "handler", "CreateOwner")
@@ -46,7 +53,7 @@ func CreateOwner(
 	}
 
 	// Generate API key
-	apiKey, err := auth.GenerateAPIKey()
+	apiKey, keyID, err := auth.GenerateAPIKey()
 	if err != nil {
 		logger.Error("failed to generate API key", "error", err)
 		return response.OwnerResponse{}, &response.RequestError{
@@ -54,14 +61,28 @@ func CreateOwner(
 			Description: "failed to generate API key",
 		}
 	}
+	signingSecret, sealedSecret, err := auth.NewSigningSecret(keyID)
+	if err != nil {
+		logger.Error("failed to generate signing secret", "error", err)
+		return response.OwnerResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to generate API key",
+		}
+	}
 
-	// Create owner
+	// Create owner, pending verification
 	now := time.Now().UTC()
+	verifyBy := now.Add(verificationWindow)
 	owner := domain.Owner{
-		OwnerID:    req.OwnerID,
-		Email:      strings.ToLower(req.Email),
-		APIKeyHash: auth.HashAPIKey(apiKey),
-		CreatedAt:  now,
+		OwnerID:             req.OwnerID,
+		Email:               strings.ToLower(req.Email),
+		APIKeyHash:          auth.HashAPIKey(apiKey),
+		CreatedAt:           now,
+		APIKeyID:            keyID,
+		APIKeySigningSecret: sealedSecret,
+		VerifyBy:            &verifyBy,
+		TTL:                 verifyBy.Unix(),
+		Locale:              domain.NormalizeLocale(req.Locale),
 	}
 
 	if err := repo.CreateOwner(ctx, owner); err != nil {
@@ -79,26 +100,124 @@ func CreateOwner(
 		}
 	}
 
-	logger.Info("owner created", "ownerId", owner.OwnerID)
+	logger.Info("owner created", "ownerId", owner.OwnerID, "verifyBy", verifyBy)
+
+	// The token is bound to the key hash, so signing up again invalidates it
+	verification := signer.Sign(token.PurposeVerifyEmail, owner.OwnerID, owner.APIKeyHash, verifyBy)
+	if err := emailSvc.SendVerification(ctx, emailRecipient(&owner), verification, verifyBy); err != nil {
+		logger.Error("failed to send verification email", "error", err, "ownerId", owner.OwnerID)
+		return response.OwnerResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to send verification email; sign up again to retry",
+		}
+	}
 
 	return response.OwnerResponse{
 		Status: http.StatusCreated,
 		Body: response.OwnerBody{
-			OwnerID:   owner.OwnerID,
-			Email:     owner.Email,
-			APIKey:    apiKey,
-			CreatedAt: now.Format(time.RFC3339),
+			OwnerID:       owner.OwnerID,
+			Email:         owner.Email,
+			APIKey:        apiKey,
+			SigningSecret: signingSecret,
+			CreatedAt:     now.Format(time.RFC3339),
+			VerifyBy:      verifyBy.Format(time.RFC3339),
 		},
 	}, nil
 }
 
-// RecoverKey handles API key recovery requests.
+// VerifyOwner handles email verification requests, activating a pending owner
+// when the token from the verification email checks out.
+func VerifyOwner(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	signer *token.Signer,
+	logger *slog.Logger,
+) (response.MessageResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "VerifyOwner", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "VerifyOwner")
+
+	var req domain.VerifyOwnerRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	invalidToken := &response.RequestError{
+		Status:      http.StatusBadRequest,
+		Description: domain.ErrInvalidVerificationToken.Error(),
+	}
+
+	owner, err := repo.GetOwner(ctx, ownerID)
+	if err != nil {
+		if repository.IsOwnerNotFound(err) {
+			logger.Warn("owner not found for verification", "ownerId", ownerID)
+			return response.MessageResponse{}, invalidToken
+		}
+		logger.Error("failed to get owner", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to verify owner",
+		}
+	}
+
+	now := time.Now().UTC()
+	if err := signer.Verify(req.Token, token.PurposeVerifyEmail, ownerID, owner.APIKeyHash, now); err != nil {
+		logger.Warn("invalid verification token", "error", err, "ownerId", ownerID)
+		return response.MessageResponse{}, invalidToken
+	}
+
+	if !owner.IsPendingVerification() {
+		logger.Info("owner already verified", "ownerId", ownerID)
+		return response.MessageResponse{
+			Status: http.StatusOK,
+			Body:   response.MessageBody{Message: "email address already verified"},
+		}, nil
+	}
+
+	if err := repo.VerifyOwner(ctx, ownerID, owner.APIKeyHash, now); err != nil {
+		if repository.IsOwnerNotFound(err) {
+			// Expired or replaced since we read it
+			logger.Warn("pending owner changed during verification", "ownerId", ownerID)
+			return response.MessageResponse{}, invalidToken
+		}
+		logger.Error("failed to verify owner", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to verify owner",
+		}
+	}
+
+	logger.Info("owner verified", "ownerId", ownerID)
+	return response.MessageResponse{
+		Status: http.StatusOK,
+		Body:   response.MessageBody{Message: "email address verified; your API key is now active"},
+	}, nil
+}
+
+// RecoverKey handles API key recovery requests. It emails the owner a token
+// that ConfirmRecovery exchanges for a new key; the current key keeps working
+// until then. Requests for a verified owner also send a security notice, even
+// when the email address doesn't match, unless the owner suppressed it.
 func RecoverKey(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	ownerID string,
 	repo repository.Repository,
 	emailSvc email.Service,
+	signer *token.Signer,
 	logger *slog.Logger,
 ) (response.MessageResponse, *response.RequestError) {
 	logger.Info("handler started", "handler", "RecoverKey", "ownerId", ownerID)
@@ -127,7 +246,7 @@ func RecoverKey(
 	successMsg := response.MessageResponse{
 		Status: http.StatusOK,
 		Body: response.MessageBody{
-			Message: "If this email matches our records, a new API key has been sent.",
+			Message: "If this email matches our records, a confirmation token has been sent.",
 		},
 	}
 
@@ -142,55 +261,177 @@ func RecoverKey(
 		return successMsg, nil
 	}
 
+	// Pending owners can't use a key yet; signing up again sends a new verification email
+	if owner.IsPendingVerification() {
+		logger.Info("recovery requested for unverified owner", "ownerId", ownerID)
+		return successMsg, nil
+	}
+
 	// Check if email matches (case-insensitive)
 	if strings.ToLower(req.Email) != strings.ToLower(owner.Email) {
 		logger.Info("email mismatch for recovery", "ownerId", ownerID)
+		notifyRecoveryRequested(ctx, request, owner, false, emailSvc, logger)
 		return successMsg, nil
 	}
 
-	// Generate new API key
-	newAPIKey, err := auth.GenerateAPIKey()
+	// The token is bound to the current key hash, so it is spent once the key changes
+	expiresAt := time.Now().UTC().Add(domain.RecoveryWindow)
+	confirmation := signer.Sign(token.PurposeRecoverKey, ownerID, owner.APIKeyHash, expiresAt)
+	if err := emailSvc.SendRecovery(ctx, emailRecipient(owner), confirmation, expiresAt); err != nil {
+		logger.Error("failed to send recovery email", "error", err)
+		return successMsg, nil
+	}
+
+	logger.Info("recovery token sent", "ownerId", ownerID)
+	notifyRecoveryRequested(ctx, request, owner, true, emailSvc, logger)
+	return successMsg, nil
+}
+
+// ConfirmRecovery exchanges a recovery token from RecoverKey for a new primary
+// API key, which is emailed to the owner.
+func ConfirmRecovery(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	emailSvc email.Service,
+	signer *token.Signer,
+	logger *slog.Logger,
+) (response.MessageResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "ConfirmRecovery", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "ConfirmRecovery")
+
+	var req domain.ConfirmRecoveryRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	invalidToken := &response.RequestError{
+		Status:      http.StatusBadRequest,
+		Description: domain.ErrInvalidRecoveryToken.Error(),
+	}
+
+	owner, err := repo.GetOwner(ctx, ownerID)
+	if err != nil {
+		if repository.IsOwnerNotFound(err) {
+			logger.Warn("owner not found for recovery confirmation", "ownerId", ownerID)
+			return response.MessageResponse{}, invalidToken
+		}
+		logger.Error("failed to get owner", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to recover API key",
+		}
+	}
+
+	if err := signer.Verify(req.Token, token.PurposeRecoverKey, ownerID, owner.APIKeyHash, time.Now().UTC()); err != nil {
+		logger.Warn("invalid recovery token", "error", err, "ownerId", ownerID)
+		return response.MessageResponse{}, invalidToken
+	}
+
+	newAPIKey, keyID, err := auth.GenerateAPIKey()
 	if err != nil {
 		logger.Error("failed to generate new API key", "error", err)
-		return successMsg, nil
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to generate new API key",
+		}
+	}
+	signingSecret, sealedSecret, err := auth.NewSigningSecret(keyID)
+	if err != nil {
+		logger.Error("failed to generate signing secret", "error", err)
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to generate new API key",
+		}
 	}
 
-	// Update the key in the database
-	if err := repo.UpdateOwnerKey(ctx, ownerID, auth.HashAPIKey(newAPIKey)); err != nil {
+	// Recovered keys don't expire until rotated with an expiry
+	if err := repo.ReplaceOwnerKey(ctx, ownerID, owner.APIKeyHash, keyID, auth.HashAPIKey(newAPIKey), sealedSecret); err != nil {
+		if repository.IsOwnerNotFound(err) {
+			// Rotated or recovered since we read it, which spends the token
+			logger.Warn("owner key changed during recovery", "ownerId", ownerID)
+			return response.MessageResponse{}, invalidToken
+		}
 		logger.Error("failed to update owner key", "error", err)
-		return successMsg, nil
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to recover API key",
+		}
 	}
 
-	// Send email with new key
-	if err := emailSvc.SendAPIKey(ctx, owner.Email, ownerID, newAPIKey); err != nil {
+	if err := emailSvc.SendAPIKey(ctx, emailRecipient(owner), newAPIKey, signingSecret); err != nil {
 		logger.Error("failed to send recovery email", "error", err)
-		// Key is already updated, so we still return success
-		// The user will need to try recovery again
+		// The key is already replaced; the owner will need to recover again
+		return response.MessageResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to send new API key; request recovery again",
+		}
 	}
 
 	logger.Info("API key recovered and sent", "ownerId", ownerID)
-	return successMsg, nil
+	return response.MessageResponse{
+		Status: http.StatusOK,
+		Body:   response.MessageBody{Message: "A new API key has been sent to your email address."},
+	}, nil
 }
 
-// RotateKey handles API key rotation requests.
+// RotateKey handles API key rotation requests. The old key keeps working for
+// gracePeriod, or as long as the request asks for, so clients can be moved to the
+// new key without downtime. The owner is sent a security notice unless they
+// suppressed it.
 func RotateKey(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	ownerID string,
 	repo repository.Repository,
+	emailSvc email.Service,
+	gracePeriod time.Duration,
 	logger *slog.Logger,
 ) (response.OwnerResponse, *response.RequestError) {
 	logger.Info("handler started", "handler", "RotateKey", "ownerId", ownerID)
 	defer logger.Info("handler completed", "handler", "RotateKey")
 
+	// The body is optional; it only carries the new key's expiry
+	var req domain.RotateKeyRequest
+	if strings.TrimSpace(request.Body) != "" {
+		if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+			logger.Warn("invalid request body", "error", err)
+			return response.OwnerResponse{}, &response.RequestError{
+				Status:      http.StatusBadRequest,
+				Description: "invalid request body",
+			}
+		}
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.OwnerResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
 	// Authenticate
-	_, authErr := auth.Authenticate(ctx, request, ownerID, repo, logger)
+	identity, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
 	if authErr != nil {
 		return response.OwnerResponse{}, authErr
 	}
 
 	// Generate new API key
-	newAPIKey, err := auth.GenerateAPIKey()
+	newAPIKey, keyID, err := auth.GenerateAPIKey()
 	if err != nil {
 		logger.Error("failed to generate new API key", "error", err)
 		return response.OwnerResponse{}, &response.RequestError{
@@ -198,9 +439,28 @@ func RotateKey(
 			Description: "failed to generate new API key",
 		}
 	}
+	signingSecret, sealedSecret, err := auth.NewSigningSecret(keyID)
+	if err != nil {
+		logger.Error("failed to generate signing secret", "error", err)
+		return response.OwnerResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to generate new API key",
+		}
+	}
+
+	now := time.Now().UTC()
+	expiresAt := domain.KeyExpiry(now, req.ExpiresInDays)
+	previous := domain.PreviousKeyFor(*identity.Owner, now, req.GracePeriod(gracePeriod))
 
 	// Update the key in the database
-	if err := repo.UpdateOwnerKey(ctx, ownerID, auth.HashAPIKey(newAPIKey)); err != nil {
+	if err := repo.UpdateOwnerKey(ctx, ownerID, keyID, auth.HashAPIKey(newAPIKey), sealedSecret, expiresAt, previous); err != nil {
+		if repository.IsOwnerNotFound(err) {
+			logger.Warn("primary key changed during rotation", "ownerId", ownerID)
+			return response.OwnerResponse{}, &response.RequestError{
+				Status:      http.StatusConflict,
+				Description: "API key was rotated concurrently, try again",
+			}
+		}
 		logger.Error("failed to update owner key", "error", err)
 		return response.OwnerResponse{}, &response.RequestError{
 			Status:      http.StatusInternalServerError,
@@ -208,15 +468,72 @@ func RotateKey(
 		}
 	}
 
-	now := time.Now().UTC()
-	logger.Info("API key rotated", "ownerId", ownerID)
+	body := response.OwnerBody{
+		OwnerID:       ownerID,
+		APIKey:        newAPIKey,
+		SigningSecret: signingSecret,
+		RotatedAt:     now.Format(time.RFC3339),
+	}
+	if expiresAt != nil {
+		body.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	if previous != nil {
+		body.PreviousKeyExpiresAt = previous.ExpiresAt.Format(time.RFC3339)
+		logger.Info("API key rotated", "ownerId", ownerID, "previousKeyExpiresAt", previous.ExpiresAt)
+	} else {
+		logger.Info("API key rotated", "ownerId", ownerID)
+	}
+
+	notifyKeyRotated(ctx, request, identity.Owner, now, previous, emailSvc, logger)
 
 	return response.OwnerResponse{
 		Status: http.StatusOK,
-		Body: response.OwnerBody{
-			OwnerID:   ownerID,
-			APIKey:    newAPIKey,
-			RotatedAt: now.Format(time.RFC3339),
-		},
+		Body:   body,
 	}, nil
 }
+
+// GetOwner handles GET /owners/{ownerId} requests.
+// It returns the owner's details and the expiry and last use of the primary key
+// and, during a rotation grace period, the previous key.
+func GetOwner(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.OwnerResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "GetOwner", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "GetOwner")
+
+	identity, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.OwnerResponse{}, authErr
+	}
+	owner := identity.Owner
+
+	primary := apiKeyBody(domain.PrimaryAPIKey(*owner))
+	body := response.OwnerBody{
+		OwnerID:    owner.OwnerID,
+		Email:      owner.Email,
+		CreatedAt:  owner.CreatedAt.Format(time.RFC3339),
+		Locale:     owner.Locale,
+		PrimaryKey: &primary,
+	}
+	if previous, ok := activePreviousKey(owner, time.Now().UTC()); ok {
+		previousBody := apiKeyBody(previous)
+		body.PreviousKey = &previousBody
+	}
+	if owner.Notifications != nil {
+		notifications := notificationsBody(*owner.Notifications)
+		body.Notifications = &notifications
+	}
+	return response.OwnerResponse{
+		Status: http.StatusOK,
+		Body:   body,
+	}, nil
+}
+
+// emailRecipient addresses an email to the owner in their locale.
+func emailRecipient(owner *domain.Owner) email.Recipient {
+	return email.Recipient{Email: owner.Email, OwnerID: owner.OwnerID, Locale: owner.Locale}
+}
@@ -11,17 +11,100 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/grocky/ddns-service/internal/auth"
 	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/signing"
+	"github.com/grocky/ddns-service/internal/token"
 	"gotest.tools/assert"
 )
 
 // mockEmailService is a mock implementation of email.Service for testing.
 type mockEmailService struct {
-	sendAPIKeyFunc func(ctx context.Context, toEmail, ownerID, apiKey string) error
+	sendAPIKeyFunc             func(ctx context.Context, to email.Recipient, apiKey, signingSecret string) error
+	sendKeyExpiryWarningFunc   func(ctx context.Context, to email.Recipient, key email.KeyExpiry) error
+	sendAuthFailureAlertFunc   func(ctx context.Context, to email.Recipient, alert email.AuthFailureAlert) error
+	sendVerificationFunc       func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error
+	sendRecoveryFunc           func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error
+	sendFlapAlertFunc          func(ctx context.Context, to email.Recipient, alert email.FlapAlert) error
+	sendIPChangeNoticeFunc     func(ctx context.Context, to email.Recipient, notice email.IPChangeNotice) error
+	sendStaleLocationAlertFunc func(ctx context.Context, to email.Recipient, alert email.StaleLocationAlert) error
+	sendKeyRotatedNoticeFunc   func(ctx context.Context, to email.Recipient, notice email.KeyRotatedNotice) error
+	sendRecoveryRequestedFunc  func(ctx context.Context, to email.Recipient, notice email.RecoveryRequestedNotice) error
+	sendACMEChallengeFunc      func(ctx context.Context, to email.Recipient, notice email.ACMEChallengeNotice) error
 }
 
-func (m *mockEmailService) SendAPIKey(ctx context.Context, toEmail, ownerID, apiKey string) error {
+func (m *mockEmailService) SendAPIKey(ctx context.Context, to email.Recipient, apiKey, signingSecret string) error {
 	if m.sendAPIKeyFunc != nil {
-		return m.sendAPIKeyFunc(ctx, toEmail, ownerID, apiKey)
+		return m.sendAPIKeyFunc(ctx, to, apiKey, signingSecret)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendKeyExpiryWarning(ctx context.Context, to email.Recipient, key email.KeyExpiry) error {
+	if m.sendKeyExpiryWarningFunc != nil {
+		return m.sendKeyExpiryWarningFunc(ctx, to, key)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendAuthFailureAlert(ctx context.Context, to email.Recipient, alert email.AuthFailureAlert) error {
+	if m.sendAuthFailureAlertFunc != nil {
+		return m.sendAuthFailureAlertFunc(ctx, to, alert)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendVerification(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+	if m.sendVerificationFunc != nil {
+		return m.sendVerificationFunc(ctx, to, token, expiresAt)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendRecovery(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+	if m.sendRecoveryFunc != nil {
+		return m.sendRecoveryFunc(ctx, to, token, expiresAt)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendFlapAlert(ctx context.Context, to email.Recipient, alert email.FlapAlert) error {
+	if m.sendFlapAlertFunc != nil {
+		return m.sendFlapAlertFunc(ctx, to, alert)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendIPChangeNotice(ctx context.Context, to email.Recipient, notice email.IPChangeNotice) error {
+	if m.sendIPChangeNoticeFunc != nil {
+		return m.sendIPChangeNoticeFunc(ctx, to, notice)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendStaleLocationAlert(ctx context.Context, to email.Recipient, alert email.StaleLocationAlert) error {
+	if m.sendStaleLocationAlertFunc != nil {
+		return m.sendStaleLocationAlertFunc(ctx, to, alert)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendKeyRotatedNotice(ctx context.Context, to email.Recipient, notice email.KeyRotatedNotice) error {
+	if m.sendKeyRotatedNoticeFunc != nil {
+		return m.sendKeyRotatedNoticeFunc(ctx, to, notice)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendRecoveryRequestedNotice(ctx context.Context, to email.Recipient, notice email.RecoveryRequestedNotice) error {
+	if m.sendRecoveryRequestedFunc != nil {
+		return m.sendRecoveryRequestedFunc(ctx, to, notice)
+	}
+	return nil
+}
+
+func (m *mockEmailService) SendACMEChallengeNotice(ctx context.Context, to email.Recipient, notice email.ACMEChallengeNotice) error {
+	if m.sendACMEChallengeFunc != nil {
+		return m.sendACMEChallengeFunc(ctx, to, notice)
 	}
 	return nil
 }
@@ -46,7 +129,7 @@ func TestCreateOwner_Success(t *testing.T) {
 		Body: `{"ownerId":"my-home-lab","email":"user@example.com"}`,
 	}
 
-	resp, err := CreateOwner(ctx, request, repo, logger)
+	resp, err := CreateOwner(ctx, request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusCreated, resp.Status)
@@ -59,6 +142,11 @@ func TestCreateOwner_Success(t *testing.T) {
 	assert.Equal(t, "my-home-lab", createdOwner.OwnerID)
 	assert.Equal(t, "user@example.com", createdOwner.Email)
 	assert.Assert(t, createdOwner.APIKeyHash != "", "API key hash should be set")
+
+	// The key embeds the stored key ID so the owner can be resolved from it
+	keyID, ok := auth.ParseKeyID(resp.Body.APIKey)
+	assert.Assert(t, ok)
+	assert.Equal(t, keyID, createdOwner.APIKeyID)
 }
 
 func TestCreateOwner_EmailNormalized(t *testing.T) {
@@ -77,7 +165,7 @@ func TestCreateOwner_EmailNormalized(t *testing.T) {
 		Body: `{"ownerId":"test","email":"USER@EXAMPLE.COM"}`,
 	}
 
-	resp, err := CreateOwner(ctx, request, repo, logger)
+	resp, err := CreateOwner(ctx, request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, "user@example.com", resp.Body.Email)
@@ -94,7 +182,7 @@ func TestCreateOwner_InvalidJSON(t *testing.T) {
 		Body: `{invalid json}`,
 	}
 
-	resp, err := CreateOwner(ctx, request, repo, logger)
+	resp, err := CreateOwner(ctx, request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -136,7 +224,7 @@ func TestCreateOwner_ValidationErrors(t *testing.T) {
 				Body: tc.body,
 			}
 
-			resp, err := CreateOwner(ctx, request, repo, logger)
+			resp, err := CreateOwner(ctx, request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, logger)
 
 			assert.Assert(t, err != nil)
 			assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -160,7 +248,7 @@ func TestCreateOwner_AlreadyExists(t *testing.T) {
 		Body: `{"ownerId":"existing-owner","email":"user@example.com"}`,
 	}
 
-	resp, err := CreateOwner(ctx, request, repo, logger)
+	resp, err := CreateOwner(ctx, request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusConflict, err.Status)
@@ -182,7 +270,7 @@ func TestCreateOwner_RepositoryError(t *testing.T) {
 		Body: `{"ownerId":"test","email":"user@example.com"}`,
 	}
 
-	resp, err := CreateOwner(ctx, request, repo, logger)
+	resp, err := CreateOwner(ctx, request, repo, &mockEmailService{}, newTestSigner(t), time.Hour, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusInternalServerError, err.Status)
@@ -190,16 +278,213 @@ func TestCreateOwner_RepositoryError(t *testing.T) {
 	assert.Equal(t, 0, resp.Status)
 }
 
+func TestCreateOwner_SendsVerification(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+	signer := newTestSigner(t)
+
+	var createdOwner domain.Owner
+	repo := &mockRepository{
+		createOwnerFunc: func(ctx context.Context, owner domain.Owner) error {
+			createdOwner = owner
+			return nil
+		},
+	}
+
+	var sentTo, sentToken string
+	emailSvc := &mockEmailService{
+		sendVerificationFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			sentTo = to.Email
+			sentToken = token
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"ownerId":"my-home-lab","email":"user@example.com"}`,
+	}
+
+	resp, err := CreateOwner(ctx, request, repo, emailSvc, signer, time.Hour, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, resp.Body.VerifyBy != "", "VerifyBy should be set")
+
+	// The owner stays pending until verified and expires with the window
+	assert.Assert(t, createdOwner.IsPendingVerification())
+	assert.Equal(t, createdOwner.VerifyBy.Unix(), createdOwner.TTL)
+
+	assert.Equal(t, "user@example.com", sentTo)
+	verifyErr := signer.Verify(sentToken, token.PurposeVerifyEmail, "my-home-lab", createdOwner.APIKeyHash, time.Now())
+	assert.NilError(t, verifyErr)
+}
+
+func TestCreateOwner_VerificationEmailFails(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	repo := &mockRepository{}
+	emailSvc := &mockEmailService{
+		sendVerificationFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			return errors.New("SES unavailable")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"ownerId":"my-home-lab","email":"user@example.com"}`,
+	}
+
+	_, err := CreateOwner(ctx, request, repo, emailSvc, newTestSigner(t), time.Hour, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Status)
+}
+
+// =============================================================================
+// VerifyOwner Tests
+// =============================================================================
+
+func TestVerifyOwner(t *testing.T) {
+	signer := newTestSigner(t)
+	now := time.Now().UTC()
+	verifyBy := now.Add(time.Hour)
+
+	pendingOwner := func() *domain.Owner {
+		return &domain.Owner{
+			OwnerID:    "test-owner",
+			Email:      "user@example.com",
+			APIKeyHash: "keyhash",
+			CreatedAt:  now,
+			VerifyBy:   &verifyBy,
+			TTL:        verifyBy.Unix(),
+		}
+	}
+	validToken := signer.Sign(token.PurposeVerifyEmail, "test-owner", "keyhash", verifyBy)
+
+	tests := []struct {
+		name        string
+		body        string
+		owner       *domain.Owner
+		getErr      error
+		verifyErr   error
+		wantStatus  int
+		wantVerify  bool
+		wantMessage string
+	}{
+		{
+			name:        "valid token",
+			body:        `{"token":"` + validToken + `"}`,
+			owner:       pendingOwner(),
+			wantStatus:  http.StatusOK,
+			wantVerify:  true,
+			wantMessage: "email address verified; your API key is now active",
+		},
+		{
+			name:       "invalid JSON",
+			body:       `{invalid`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing token",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "tampered token",
+			body:       `{"token":"` + validToken + `x"}`,
+			owner:      pendingOwner(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "token for a replaced key",
+			body:       `{"token":"` + signer.Sign(token.PurposeVerifyEmail, "test-owner", "otherhash", verifyBy) + `"}`,
+			owner:      pendingOwner(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "expired token",
+			body:       `{"token":"` + signer.Sign(token.PurposeVerifyEmail, "test-owner", "keyhash", now.Add(-time.Minute)) + `"}`,
+			owner:      pendingOwner(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "already verified",
+			body: `{"token":"` + validToken + `"}`,
+			owner: &domain.Owner{
+				OwnerID:    "test-owner",
+				Email:      "user@example.com",
+				APIKeyHash: "keyhash",
+				CreatedAt:  now,
+			},
+			wantStatus:  http.StatusOK,
+			wantMessage: "email address already verified",
+		},
+		{
+			name:       "owner not found",
+			body:       `{"token":"` + validToken + `"}`,
+			getErr:     domain.ErrOwnerNotFound,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "repository error",
+			body:       `{"token":"` + validToken + `"}`,
+			getErr:     errors.New("database error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "owner changed before update",
+			body:       `{"token":"` + validToken + `"}`,
+			owner:      pendingOwner(),
+			verifyErr:  domain.ErrOwnerNotFound,
+			wantStatus: http.StatusBadRequest,
+			wantVerify: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verified bool
+			repo := &mockRepository{
+				getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return tt.owner, nil
+				},
+				verifyOwnerFunc: func(ctx context.Context, ownerID, keyHash string, verifiedAt time.Time) error {
+					verified = true
+					assert.Equal(t, "keyhash", keyHash)
+					return tt.verifyErr
+				},
+			}
+
+			request := events.APIGatewayProxyRequest{Body: tt.body}
+			resp, err := VerifyOwner(context.Background(), request, "test-owner", repo, signer, newTestLogger())
+
+			assert.Equal(t, tt.wantVerify, verified)
+			if tt.wantStatus != http.StatusOK {
+				assert.Assert(t, err != nil)
+				assert.Equal(t, tt.wantStatus, err.Status)
+				return
+			}
+			assert.Assert(t, err == nil)
+			assert.Equal(t, tt.wantMessage, resp.Body.Message)
+		})
+	}
+}
+
 // =============================================================================
 // RecoverKey Tests
 // =============================================================================
 
+const recoverySentMessage = "If this email matches our records, a confirmation token has been sent."
+
 func TestRecoverKey_Success(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
+	signer := newTestSigner(t)
 
-	var updatedKeyHash string
-	var sentEmail, sentOwnerID, sentAPIKey string
+	var keyUpdated bool
+	var sentEmail, sentOwnerID, sentToken string
 
 	repo := &mockRepository{
 		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
@@ -210,17 +495,17 @@ func TestRecoverKey_Success(t *testing.T) {
 				CreatedAt:  time.Now().UTC(),
 			}, nil
 		},
-		updateOwnerKeyFunc: func(ctx context.Context, ownerID, newKeyHash string) error {
-			updatedKeyHash = newKeyHash
+		updateOwnerKeyFunc: func(ctx context.Context, ownerID, keyID, newKeyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
+			keyUpdated = true
 			return nil
 		},
 	}
 
 	emailSvc := &mockEmailService{
-		sendAPIKeyFunc: func(ctx context.Context, toEmail, ownerID, apiKey string) error {
-			sentEmail = toEmail
-			sentOwnerID = ownerID
-			sentAPIKey = apiKey
+		sendRecoveryFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			sentEmail = to.Email
+			sentOwnerID = to.OwnerID
+			sentToken = token
 			return nil
 		},
 	}
@@ -229,25 +514,24 @@ func TestRecoverKey_Success(t *testing.T) {
 		Body: `{"email":"user@example.com"}`,
 	}
 
-	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, logger)
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, signer, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
-	assert.Equal(t, "If this email matches our records, a new API key has been sent.", resp.Body.Message)
+	assert.Equal(t, recoverySentMessage, resp.Body.Message)
 
-	// Verify key was updated and email was sent
-	assert.Assert(t, updatedKeyHash != "", "Key should be updated")
-	assert.Assert(t, updatedKeyHash != "oldhash", "Key hash should change")
+	// The key only changes once the emailed token is confirmed
+	assert.Assert(t, !keyUpdated, "Key should not be updated before confirmation")
 	assert.Equal(t, "user@example.com", sentEmail)
 	assert.Equal(t, "test-owner", sentOwnerID)
-	assert.Assert(t, strings.HasPrefix(sentAPIKey, auth.APIKeyPrefix), "Sent API key should have correct prefix")
+	assert.NilError(t, signer.Verify(sentToken, token.PurposeRecoverKey, "test-owner", "oldhash", time.Now()))
 }
 
 func TestRecoverKey_EmailCaseInsensitive(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
 
-	var keyUpdated bool
+	var tokenSent bool
 
 	repo := &mockRepository{
 		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
@@ -258,23 +542,24 @@ func TestRecoverKey_EmailCaseInsensitive(t *testing.T) {
 				CreatedAt:  time.Now().UTC(),
 			}, nil
 		},
-		updateOwnerKeyFunc: func(ctx context.Context, ownerID, newKeyHash string) error {
-			keyUpdated = true
+	}
+
+	emailSvc := &mockEmailService{
+		sendRecoveryFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			tokenSent = true
 			return nil
 		},
 	}
 
-	emailSvc := &mockEmailService{}
-
 	request := events.APIGatewayProxyRequest{
 		Body: `{"email":"USER@EXAMPLE.COM"}`, // uppercase
 	}
 
-	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, logger)
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, newTestSigner(t), logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
-	assert.Assert(t, keyUpdated, "Key should be updated even with different case email")
+	assert.Assert(t, tokenSent, "Token should be sent even with different case email")
 }
 
 func TestRecoverKey_InvalidJSON(t *testing.T) {
@@ -288,7 +573,7 @@ func TestRecoverKey_InvalidJSON(t *testing.T) {
 		Body: `{invalid}`,
 	}
 
-	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, logger)
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, newTestSigner(t), logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -307,7 +592,7 @@ func TestRecoverKey_MissingEmail(t *testing.T) {
 		Body: `{}`,
 	}
 
-	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, logger)
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, newTestSigner(t), logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -332,18 +617,18 @@ func TestRecoverKey_OwnerNotFound_NoEnumeration(t *testing.T) {
 	}
 
 	// Should return success to prevent enumeration
-	resp, err := RecoverKey(ctx, request, "nonexistent", repo, emailSvc, logger)
+	resp, err := RecoverKey(ctx, request, "nonexistent", repo, emailSvc, newTestSigner(t), logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
-	assert.Equal(t, "If this email matches our records, a new API key has been sent.", resp.Body.Message)
+	assert.Equal(t, recoverySentMessage, resp.Body.Message)
 }
 
 func TestRecoverKey_EmailMismatch_NoEnumeration(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
 
-	var keyUpdated bool
+	var tokenSent bool
 
 	repo := &mockRepository{
 		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
@@ -354,24 +639,226 @@ func TestRecoverKey_EmailMismatch_NoEnumeration(t *testing.T) {
 				CreatedAt:  time.Now().UTC(),
 			}, nil
 		},
-		updateOwnerKeyFunc: func(ctx context.Context, ownerID, newKeyHash string) error {
-			keyUpdated = true
+	}
+
+	emailSvc := &mockEmailService{
+		sendRecoveryFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			tokenSent = true
 			return nil
 		},
 	}
 
-	emailSvc := &mockEmailService{}
-
 	request := events.APIGatewayProxyRequest{
 		Body: `{"email":"wrong@example.com"}`,
 	}
 
-	// Should return success to prevent enumeration, but NOT update key
-	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, logger)
+	// Should return success to prevent enumeration, but NOT send a token
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, newTestSigner(t), logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
-	assert.Assert(t, !keyUpdated, "Key should NOT be updated for wrong email")
+	assert.Equal(t, recoverySentMessage, resp.Body.Message)
+	assert.Assert(t, !tokenSent, "Token should NOT be sent for wrong email")
+}
+
+func TestRecoverKey_EmailFails_NoEnumeration(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:    "test-owner",
+				Email:      "user@example.com",
+				APIKeyHash: "oldhash",
+				CreatedAt:  time.Now().UTC(),
+			}, nil
+		},
+	}
+
+	emailSvc := &mockEmailService{
+		sendRecoveryFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			return errors.New("SES unavailable")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"email":"user@example.com"}`,
+	}
+
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, newTestSigner(t), logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, recoverySentMessage, resp.Body.Message)
+}
+
+func TestRecoverKey_UnverifiedOwner(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	verifyBy := time.Now().UTC().Add(time.Hour)
+	var tokenSent bool
+
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:    "test-owner",
+				Email:      "user@example.com",
+				APIKeyHash: "oldhash",
+				CreatedAt:  time.Now().UTC(),
+				VerifyBy:   &verifyBy,
+			}, nil
+		},
+	}
+
+	emailSvc := &mockEmailService{
+		sendRecoveryFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+			tokenSent = true
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"email":"user@example.com"}`,
+	}
+
+	// Pending owners sign up again instead; recovery must not bypass verification
+	resp, err := RecoverKey(ctx, request, "test-owner", repo, emailSvc, newTestSigner(t), logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, !tokenSent, "No token should be sent to an unverified owner")
+}
+
+// =============================================================================
+// ConfirmRecovery Tests
+// =============================================================================
+
+func TestConfirmRecovery(t *testing.T) {
+	signer := newTestSigner(t)
+	now := time.Now().UTC()
+	expiresAt := now.Add(domain.RecoveryWindow)
+
+	owner := &domain.Owner{
+		OwnerID:    "test-owner",
+		Email:      "user@example.com",
+		APIKeyHash: "oldhash",
+		CreatedAt:  now,
+	}
+	validToken := signer.Sign(token.PurposeRecoverKey, "test-owner", "oldhash", expiresAt)
+
+	tests := []struct {
+		name        string
+		body        string
+		getErr      error
+		replaceErr  error
+		emailErr    error
+		wantStatus  int
+		wantReplace bool
+	}{
+		{
+			name:        "valid token",
+			body:        `{"token":"` + validToken + `"}`,
+			wantStatus:  http.StatusOK,
+			wantReplace: true,
+		},
+		{
+			name:       "invalid JSON",
+			body:       `{invalid`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing token",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "key changed since token was issued",
+			body:       `{"token":"` + signer.Sign(token.PurposeRecoverKey, "test-owner", "olderhash", expiresAt) + `"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "expired token",
+			body:       `{"token":"` + signer.Sign(token.PurposeRecoverKey, "test-owner", "oldhash", now.Add(-time.Minute)) + `"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "verification token",
+			body:       `{"token":"` + signer.Sign(token.PurposeVerifyEmail, "test-owner", "oldhash", expiresAt) + `"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "owner not found",
+			body:       `{"token":"` + validToken + `"}`,
+			getErr:     domain.ErrOwnerNotFound,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "repository error",
+			body:       `{"token":"` + validToken + `"}`,
+			getErr:     errors.New("database error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:        "token used concurrently",
+			body:        `{"token":"` + validToken + `"}`,
+			replaceErr:  domain.ErrOwnerNotFound,
+			wantStatus:  http.StatusBadRequest,
+			wantReplace: true,
+		},
+		{
+			name:        "email fails",
+			body:        `{"token":"` + validToken + `"}`,
+			emailErr:    errors.New("SES unavailable"),
+			wantStatus:  http.StatusInternalServerError,
+			wantReplace: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var replacedHash, replacedKeyID, sealedSecret, sentKey, sentSecret string
+			repo := &mockRepository{
+				getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return owner, nil
+				},
+				replaceOwnerKeyFunc: func(ctx context.Context, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret string) error {
+					assert.Equal(t, "oldhash", oldKeyHash)
+					replacedHash = newKeyHash
+					replacedKeyID = keyID
+					sealedSecret = signingSecret
+					return tt.replaceErr
+				},
+			}
+			emailSvc := &mockEmailService{
+				sendAPIKeyFunc: func(ctx context.Context, to email.Recipient, apiKey, signingSecret string) error {
+					sentKey = apiKey
+					sentSecret = signingSecret
+					return tt.emailErr
+				},
+			}
+
+			request := events.APIGatewayProxyRequest{Body: tt.body}
+			resp, err := ConfirmRecovery(context.Background(), request, "test-owner", repo, emailSvc, signer, newTestLogger())
+
+			assert.Equal(t, tt.wantReplace, replacedHash != "")
+			if tt.wantStatus != http.StatusOK {
+				assert.Assert(t, err != nil)
+				assert.Equal(t, tt.wantStatus, err.Status)
+				return
+			}
+			assert.Assert(t, err == nil)
+			assert.Equal(t, http.StatusOK, resp.Status)
+			assert.Equal(t, auth.HashAPIKey(sentKey), replacedHash)
+			assert.Assert(t, strings.HasPrefix(sentKey, auth.APIKeyPrefix))
+			opened, openErr := testSigningSecrets.Open(sealedSecret, replacedKeyID)
+			assert.NilError(t, openErr)
+			assert.Equal(t, sentSecret, opened)
+		})
+	}
 }
 
 // =============================================================================
@@ -385,7 +872,8 @@ func TestRotateKey_Success(t *testing.T) {
 	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
 	apiKeyHash := auth.HashAPIKey(apiKey)
 
-	var newKeyHash string
+	var newKeyHash, newKeyID, sealedSecret string
+	var storedPrevious *domain.PreviousKey
 
 	repo := &mockRepository{
 		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
@@ -396,8 +884,11 @@ func TestRotateKey_Success(t *testing.T) {
 				CreatedAt:  time.Now().UTC(),
 			}, nil
 		},
-		updateOwnerKeyFunc: func(ctx context.Context, ownerID, keyHash string) error {
+		updateOwnerKeyFunc: func(ctx context.Context, ownerID, keyID, keyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
 			newKeyHash = keyHash
+			newKeyID = keyID
+			sealedSecret = signingSecret
+			storedPrevious = previous
 			return nil
 		},
 	}
@@ -408,7 +899,7 @@ func TestRotateKey_Success(t *testing.T) {
 		},
 	}
 
-	resp, err := RotateKey(ctx, request, "test-owner", repo, logger)
+	resp, err := RotateKey(ctx, request, "test-owner", repo, &mockEmailService{}, time.Hour, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
@@ -420,6 +911,18 @@ func TestRotateKey_Success(t *testing.T) {
 	// Verify the new hash is different from old
 	assert.Assert(t, newKeyHash != apiKeyHash, "Key hash should change")
 	assert.Equal(t, auth.HashAPIKey(resp.Body.APIKey), newKeyHash, "Returned key should match stored hash")
+
+	// The signing secret is returned once and stored sealed
+	assert.Assert(t, strings.HasPrefix(resp.Body.SigningSecret, signing.SecretPrefix))
+	opened, openErr := testSigningSecrets.Open(sealedSecret, newKeyID)
+	assert.NilError(t, openErr)
+	assert.Equal(t, resp.Body.SigningSecret, opened)
+
+	// The old key keeps working for the grace period
+	assert.Assert(t, storedPrevious != nil)
+	assert.Equal(t, apiKeyHash, storedPrevious.KeyHash)
+	assert.Assert(t, time.Until(storedPrevious.ExpiresAt) > 59*time.Minute)
+	assert.Equal(t, storedPrevious.ExpiresAt.Format(time.RFC3339), resp.Body.PreviousKeyExpiresAt)
 }
 
 func TestRotateKey_Unauthorized_MissingAuth(t *testing.T) {
@@ -432,7 +935,7 @@ func TestRotateKey_Unauthorized_MissingAuth(t *testing.T) {
 		Headers: map[string]string{},
 	}
 
-	resp, err := RotateKey(ctx, request, "test-owner", repo, logger)
+	resp, err := RotateKey(ctx, request, "test-owner", repo, &mockEmailService{}, time.Hour, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusUnauthorized, err.Status)
@@ -460,7 +963,7 @@ func TestRotateKey_Unauthorized_WrongKey(t *testing.T) {
 		},
 	}
 
-	resp, err := RotateKey(ctx, request, "test-owner", repo, logger)
+	resp, err := RotateKey(ctx, request, "test-owner", repo, &mockEmailService{}, time.Hour, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusUnauthorized, err.Status)
@@ -484,7 +987,7 @@ func TestRotateKey_UpdateError(t *testing.T) {
 				CreatedAt:  time.Now().UTC(),
 			}, nil
 		},
-		updateOwnerKeyFunc: func(ctx context.Context, ownerID, keyHash string) error {
+		updateOwnerKeyFunc: func(ctx context.Context, ownerID, keyID, keyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
 			return errors.New("database error")
 		},
 	}
@@ -495,10 +998,158 @@ func TestRotateKey_UpdateError(t *testing.T) {
 		},
 	}
 
-	resp, err := RotateKey(ctx, request, "test-owner", repo, logger)
+	resp, err := RotateKey(ctx, request, "test-owner", repo, &mockEmailService{}, time.Hour, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusInternalServerError, err.Status)
 	assert.Equal(t, "failed to rotate API key", err.Description)
 	assert.Equal(t, 0, resp.Status)
 }
+
+func TestRotateKey_WithExpiry(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var storedExpiry *time.Time
+	repo.updateOwnerKeyFunc = func(ctx context.Context, ownerID, keyID, keyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
+		storedExpiry = expiresAt
+		return nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"expiresInDays":90}`,
+	}
+
+	resp, err := RotateKey(ctx, request, "test-owner", repo, &mockEmailService{}, time.Hour, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Assert(t, storedExpiry != nil)
+	assert.Assert(t, time.Until(*storedExpiry) > 89*24*time.Hour)
+	assert.Equal(t, storedExpiry.Format(time.RFC3339), resp.Body.ExpiresAt)
+}
+
+func TestRotateKey_InvalidExpiry(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"expiresInDays":1000}`,
+	}
+
+	_, err := RotateKey(ctx, request, "test-owner", repo, &mockEmailService{}, time.Hour, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusBadRequest, err.Status)
+}
+
+func TestRotateKey_GracePeriod(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name         string
+		body         string
+		wantPrevious time.Duration
+		wantStatus   int
+	}{
+		{name: "service default", wantPrevious: time.Hour},
+		{name: "override", body: `{"gracePeriodMinutes":10}`, wantPrevious: 10 * time.Minute},
+		{name: "revoke immediately", body: `{"gracePeriodMinutes":0}`},
+		{name: "too long", body: `{"gracePeriodMinutes":20000}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			var storedPrevious *domain.PreviousKey
+			repo.updateOwnerKeyFunc = func(ctx context.Context, ownerID, keyID, keyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
+				storedPrevious = previous
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    tc.body,
+			}
+
+			resp, err := RotateKey(context.Background(), request, "test-owner", repo, &mockEmailService{}, time.Hour, newTestLogger())
+
+			if tc.wantStatus != 0 {
+				assert.Assert(t, err != nil)
+				assert.Equal(t, tc.wantStatus, err.Status)
+				return
+			}
+			assert.Assert(t, err == nil, "expected no error, got %v", err)
+			if tc.wantPrevious == 0 {
+				assert.Assert(t, storedPrevious == nil)
+				assert.Equal(t, "", resp.Body.PreviousKeyExpiresAt)
+				return
+			}
+			assert.Assert(t, storedPrevious != nil)
+			remaining := time.Until(storedPrevious.ExpiresAt)
+			assert.Assert(t, remaining > tc.wantPrevious-time.Minute && remaining <= tc.wantPrevious)
+		})
+	}
+}
+
+func TestRotateKey_ConcurrentRotation(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.updateOwnerKeyFunc = func(ctx context.Context, ownerID, keyID, keyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
+		return domain.ErrOwnerNotFound
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	_, err := RotateKey(context.Background(), request, "test-owner", repo, &mockEmailService{}, time.Hour, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusConflict, err.Status)
+}
+
+func TestGetOwner_ShowsPrimaryKeyUsage(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	lastUsed := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	expiresAt := time.Now().Add(30 * 24 * time.Hour).UTC()
+
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:          "test-owner",
+				Email:            "user@example.com",
+				APIKeyHash:       auth.HashAPIKey(apiKey),
+				APIKeyExpiresAt:  &expiresAt,
+				APIKeyLastUsedAt: &lastUsed,
+				APIKeyLastUsedIP: "203.0.113.50",
+			}, nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := GetOwner(ctx, request, "test-owner", repo, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, "user@example.com", resp.Body.Email)
+	assert.Equal(t, "", resp.Body.APIKey)
+	assert.Assert(t, resp.Body.PrimaryKey != nil)
+	assert.Equal(t, domain.PrimaryKeyID, resp.Body.PrimaryKey.KeyID)
+	assert.Equal(t, "2025-01-15T10:00:00Z", resp.Body.PrimaryKey.LastUsedAt)
+	assert.Equal(t, "203.0.113.50", resp.Body.PrimaryKey.LastUsedIP)
+	assert.Equal(t, expiresAt.Format(time.RFC3339), resp.Body.PrimaryKey.ExpiresAt)
+	assert.Assert(t, !resp.Body.PrimaryKey.Expired)
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// ListRecordSets handles GET /owners/{ownerId}/locations/{location}/records requests.
+// It returns the custom record sets published under the location's subdomain.
+func ListRecordSets(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.RecordSetListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "ListRecordSets", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "ListRecordSets")
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeLookup, Location: location}, repo, logger)
+	if authErr != nil {
+		return response.RecordSetListResponse{}, authErr
+	}
+
+	subdomain, reqErr := locationSubdomain(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.RecordSetListResponse{}, reqErr
+	}
+
+	recordSets, err := repo.ListRecordSets(ctx, ownerID, location)
+	if err != nil {
+		logger.Error("failed to list record sets", "error", err)
+		return response.RecordSetListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list record sets",
+		}
+	}
+
+	bodies := make([]response.RecordSetBody, 0, len(recordSets))
+	for _, rs := range recordSets {
+		bodies = append(bodies, recordSetBody(subdomain, rs))
+	}
+
+	return response.RecordSetListResponse{
+		Status: http.StatusOK,
+		Body: response.RecordSetListBody{
+			OwnerID:    ownerID,
+			Location:   location,
+			Subdomain:  dns.FormatFQDN(subdomain),
+			RecordSets: bodies,
+		},
+	}, nil
+}
+
+// CreateRecordSet handles POST /owners/{ownerId}/locations/{location}/records requests.
+// It publishes a new custom record set, subject to the owner's record set quota.
+func CreateRecordSet(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.RecordSetResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "CreateRecordSet", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "CreateRecordSet")
+
+	now := time.Now().UTC()
+
+	req, reqErr := parseRecordSetRequest(request, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	identity, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage, Location: location}, repo, logger)
+	if authErr != nil {
+		return response.RecordSetResponse{}, authErr
+	}
+
+	subdomain, reqErr := locationSubdomain(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	rs := domain.RecordSet{
+		OwnerID:      ownerID,
+		LocationName: location,
+		Name:         req.Name,
+		Type:         req.Type,
+		TTL:          recordSetTTL(req.TTL),
+		Values:       req.Values,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// Claim the record set before publishing so concurrent creates can't both win.
+	// The claim also enforces the per-owner quota across all locations
+	limit := identity.Owner.RecordSetLimit()
+	if err := repo.CreateRecordSet(ctx, rs, limit); err != nil {
+		if repository.IsRecordSetExists(err) {
+			logger.Warn("record set already exists", "ownerId", ownerID, "location", location, "name", req.Name, "type", req.Type)
+			return response.RecordSetResponse{}, &response.RequestError{
+				Status:      http.StatusConflict,
+				Description: "record set already exists, use PUT to replace it",
+			}
+		}
+		if errors.Is(err, domain.ErrRecordSetQuotaExceeded) {
+			logger.Warn("record set quota exceeded", "ownerId", ownerID, "limit", limit)
+			return response.RecordSetResponse{}, &response.RequestError{
+				Status:      http.StatusForbidden,
+				Description: fmt.Sprintf("%s: maximum %d record sets", domain.ErrRecordSetQuotaExceeded, limit),
+			}
+		}
+		logger.Error("failed to save record set", "error", err)
+		return response.RecordSetResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save record set",
+		}
+	}
+
+	if err := dnsService.UpsertRecordSet(ctx, dnsRecordSet(subdomain, rs)); err != nil {
+		logger.Error("failed to publish record set", "error", err)
+		// Release the claim so the client can retry
+		if delErr := repo.DeleteRecordSet(ctx, ownerID, location, rs.Name, rs.Type); delErr != nil {
+			logger.Error("failed to remove unpublished record set", "error", delErr)
+		}
+		return response.RecordSetResponse{}, dnsError(err, "failed to create DNS record")
+	}
+
+	logger.Info("record set created",
+		"ownerId", ownerID,
+		"location", location,
+		"name", rs.Name,
+		"type", rs.Type,
+	)
+
+	return response.RecordSetResponse{
+		Status: http.StatusCreated,
+		Body:   recordSetBody(subdomain, rs),
+	}, nil
+}
+
+// UpdateRecordSet handles PUT /owners/{ownerId}/locations/{location}/records requests.
+// It replaces the TTL and values of an existing record set identified by name and type.
+func UpdateRecordSet(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.RecordSetResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "UpdateRecordSet", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "UpdateRecordSet")
+
+	now := time.Now().UTC()
+
+	req, reqErr := parseRecordSetRequest(request, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage, Location: location}, repo, logger)
+	if authErr != nil {
+		return response.RecordSetResponse{}, authErr
+	}
+
+	subdomain, reqErr := locationSubdomain(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	existing, reqErr := getRecordSet(ctx, repo, ownerID, location, req.Name, req.Type, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	rs := *existing
+	rs.TTL = recordSetTTL(req.TTL)
+	rs.Values = req.Values
+	rs.UpdatedAt = now
+
+	if err := dnsService.UpsertRecordSet(ctx, dnsRecordSet(subdomain, rs)); err != nil {
+		logger.Error("failed to publish record set", "error", err)
+		return response.RecordSetResponse{}, dnsError(err, "failed to update DNS record")
+	}
+
+	if err := repo.PutRecordSet(ctx, rs); err != nil {
+		if repository.IsRecordSetNotFound(err) {
+			logger.Warn("record set deleted during update", "ownerId", ownerID, "location", location, "name", rs.Name, "type", rs.Type)
+			// Withdraw what the upsert just published again
+			if err := dnsService.DeleteRecordSet(ctx, dnsRecordSet(subdomain, rs)); err != nil {
+				logger.Error("failed to delete DNS record set", "error", err)
+			}
+			return response.RecordSetResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "record set not found",
+			}
+		}
+		logger.Error("failed to save record set", "error", err)
+		return response.RecordSetResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save record set",
+		}
+	}
+
+	logger.Info("record set updated",
+		"ownerId", ownerID,
+		"location", location,
+		"name", rs.Name,
+		"type", rs.Type,
+	)
+
+	return response.RecordSetResponse{
+		Status: http.StatusOK,
+		Body:   recordSetBody(subdomain, rs),
+	}, nil
+}
+
+// DeleteRecordSet handles DELETE /owners/{ownerId}/locations/{location}/records requests.
+// It removes the record set from DNS and then from storage.
+func DeleteRecordSet(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	location string,
+	repo repository.Repository,
+	dnsService dns.Service,
+	logger *slog.Logger,
+) (response.RecordSetResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "DeleteRecordSet", "ownerId", ownerID, "location", location)
+	defer logger.Info("handler completed", "handler", "DeleteRecordSet")
+
+	var req domain.DeleteRecordSetRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.RecordSetResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.RecordSetResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage, Location: location}, repo, logger)
+	if authErr != nil {
+		return response.RecordSetResponse{}, authErr
+	}
+
+	subdomain, reqErr := locationSubdomain(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	existing, reqErr := getRecordSet(ctx, repo, ownerID, location, req.Name, req.Type, logger)
+	if reqErr != nil {
+		return response.RecordSetResponse{}, reqErr
+	}
+
+	// Keep the stored record set if DNS removal fails so the delete can be retried
+	if err := dnsService.DeleteRecordSet(ctx, dnsRecordSet(subdomain, *existing)); err != nil {
+		logger.Error("failed to delete DNS record set", "error", err)
+		return response.RecordSetResponse{}, dnsError(err, "failed to delete DNS record")
+	}
+
+	if err := repo.DeleteRecordSet(ctx, ownerID, location, existing.Name, existing.Type); err != nil {
+		logger.Error("failed to delete record set", "error", err)
+		return response.RecordSetResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to delete record set",
+		}
+	}
+
+	logger.Info("record set deleted",
+		"ownerId", ownerID,
+		"location", location,
+		"name", existing.Name,
+		"type", existing.Type,
+	)
+
+	body := recordSetBody(subdomain, *existing)
+	body.Deleted = true
+	return response.RecordSetResponse{
+		Status: http.StatusOK,
+		Body:   body,
+	}, nil
+}
+
+// parseRecordSetRequest decodes, normalizes and validates a record set request body.
+func parseRecordSetRequest(request events.APIGatewayProxyRequest, logger *slog.Logger) (domain.RecordSetRequest, *response.RequestError) {
+	var req domain.RecordSetRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return req, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return req, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+	return req, nil
+}
+
+// locationSubdomain returns the subdomain of an existing location.
+// Record sets can only be published for locations that have an IP mapping.
+func locationSubdomain(ctx context.Context, repo repository.Repository, ownerID, location string, logger *slog.Logger) (string, *response.RequestError) {
+	mapping, reqErr := getLocation(ctx, repo, ownerID, location, logger)
+	if reqErr != nil {
+		return "", reqErr
+	}
+	return mappingSubdomain(*mapping), nil
+}
+
+// getRecordSet loads an existing record set, mapping not-found to a 404.
+func getRecordSet(ctx context.Context, repo repository.Repository, ownerID, location, name, recordType string, logger *slog.Logger) (*domain.RecordSet, *response.RequestError) {
+	rs, err := repo.GetRecordSet(ctx, ownerID, location, name, recordType)
+	if err != nil {
+		if repository.IsRecordSetNotFound(err) {
+			return nil, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: "record set not found",
+			}
+		}
+		logger.Error("failed to get record set", "error", err)
+		return nil, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to get record set",
+		}
+	}
+	return rs, nil
+}
+
+// recordSetTTL applies the default TTL when none was requested.
+func recordSetTTL(ttl int64) int64 {
+	if ttl == 0 {
+		return dns.DefaultTTL
+	}
+	return ttl
+}
+
+// dnsRecordSet converts a stored record set into the DNS representation.
+func dnsRecordSet(subdomain string, rs domain.RecordSet) dns.RecordSet {
+	return dns.RecordSet{
+		Name:   dns.BuildRecordSetName(subdomain, rs.Name),
+		Type:   rs.Type,
+		TTL:    rs.TTL,
+		Values: rs.Values,
+	}
+}
+
+// recordSetBody converts a stored record set into its JSON representation.
+func recordSetBody(subdomain string, rs domain.RecordSet) response.RecordSetBody {
+	return response.RecordSetBody{
+		Location:  rs.LocationName,
+		Name:      rs.Name,
+		FQDN:      dns.FormatFQDN(dns.BuildRecordSetName(subdomain, rs.Name)),
+		Type:      rs.Type,
+		TTL:       rs.TTL,
+		Values:    rs.Values,
+		CreatedAt: rs.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: rs.UpdatedAt.Format(time.RFC3339),
+	}
+}
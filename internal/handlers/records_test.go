@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+// newRecordSetTestRepo returns a repository with an authenticated owner and a mapping for "home".
+func newRecordSetTestRepo(apiKey string) *mockRepository {
+	return &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:    "test-owner",
+				Email:      "user@example.com",
+				APIKeyHash: auth.HashAPIKey(apiKey),
+				CreatedAt:  time.Now().UTC(),
+			}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			if location != "home" {
+				return nil, domain.ErrMappingNotFound
+			}
+			return &domain.IPMapping{
+				OwnerID:      "test-owner",
+				LocationName: "home",
+				IP:           "203.0.113.50",
+				Subdomain:    "a3f8c2d1",
+			}, nil
+		},
+	}
+}
+
+func TestCreateRecordSet_Success(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var created domain.RecordSet
+	repo.createRecordSetFunc = func(ctx context.Context, rs domain.RecordSet, limit int) error {
+		created = rs
+		return nil
+	}
+
+	var published dns.RecordSet
+	dnsSvc := &mockDNSService{
+		upsertRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			published = rs
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"name":"_Minecraft._TCP","type":"srv","values":["0 5 25565 mc.example.com"]}`,
+	}
+
+	resp, err := CreateRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, "_minecraft._tcp.a3f8c2d1.grocky.net", resp.Body.FQDN)
+	assert.Equal(t, "SRV", resp.Body.Type)
+	assert.Equal(t, int64(dns.DefaultTTL), resp.Body.TTL)
+
+	assert.Equal(t, "home", created.LocationName)
+	assert.Equal(t, "_minecraft._tcp", created.Name)
+	assert.Equal(t, "_minecraft._tcp.a3f8c2d1", published.Name)
+	assert.Equal(t, "SRV", published.Type)
+}
+
+func TestCreateRecordSet_QuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	var gotLimit int
+	repo.createRecordSetFunc = func(ctx context.Context, rs domain.RecordSet, limit int) error {
+		gotLimit = limit
+		return domain.ErrRecordSetQuotaExceeded
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			t.Fatal("should not publish a record set over quota")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","values":["hello"]}`,
+	}
+
+	_, err := CreateRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusForbidden, err.Status)
+	assert.Equal(t, domain.DefaultRecordSetQuota, gotLimit)
+}
+
+func TestCreateRecordSet_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.createRecordSetFunc = func(ctx context.Context, rs domain.RecordSet, limit int) error {
+		return domain.ErrRecordSetExists
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			t.Fatal("should not publish an existing record set")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","values":["hello"]}`,
+	}
+
+	_, err := CreateRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusConflict, err.Status)
+}
+
+func TestCreateRecordSet_DNSFailureReleasesClaim(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	deleted := false
+	repo.deleteRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) error {
+		deleted = true
+		assert.Equal(t, "TXT", recordType)
+		return nil
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			return errors.New("route53 error")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","values":["hello"]}`,
+	}
+
+	_, err := CreateRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Status)
+	assert.Assert(t, deleted, "stored record set should be released")
+}
+
+func TestCreateRecordSet_InvalidName(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"name":"evil.","type":"TXT","values":["hello"]}`,
+	}
+
+	_, err := CreateRecordSet(ctx, request, "test-owner", "home", &mockRepository{}, &mockDNSService{}, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusBadRequest, err.Status)
+}
+
+func TestCreateRecordSet_LocationNotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","values":["hello"]}`,
+	}
+
+	_, err := CreateRecordSet(ctx, request, "test-owner", "cabin", repo, &mockDNSService{}, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusNotFound, err.Status)
+}
+
+func TestUpdateRecordSet_Success(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	createdAt := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error) {
+		return &domain.RecordSet{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			Type:         "TXT",
+			TTL:          300,
+			Values:       []string{"old"},
+			CreatedAt:    createdAt,
+		}, nil
+	}
+
+	var saved domain.RecordSet
+	repo.putRecordSetFunc = func(ctx context.Context, rs domain.RecordSet) error {
+		saved = rs
+		return nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","ttl":600,"values":["new"]}`,
+	}
+
+	resp, err := UpdateRecordSet(ctx, request, "test-owner", "home", repo, &mockDNSService{}, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, int64(600), saved.TTL)
+	assert.DeepEqual(t, []string{"new"}, saved.Values)
+	assert.Equal(t, createdAt, saved.CreatedAt)
+}
+
+func TestUpdateRecordSet_NotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","values":["new"]}`,
+	}
+
+	_, err := UpdateRecordSet(ctx, request, "test-owner", "home", repo, &mockDNSService{}, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusNotFound, err.Status)
+}
+
+func TestUpdateRecordSet_DeletedDuringUpdate(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error) {
+		return &domain.RecordSet{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			Type:         "TXT",
+			TTL:          300,
+			Values:       []string{"old"},
+		}, nil
+	}
+	repo.putRecordSetFunc = func(ctx context.Context, rs domain.RecordSet) error {
+		return domain.ErrRecordSetNotFound
+	}
+
+	var withdrawn dns.RecordSet
+	dnsSvc := &mockDNSService{
+		deleteRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			withdrawn = rs
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT","values":["new"]}`,
+	}
+
+	_, err := UpdateRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusNotFound, err.Status)
+	assert.Equal(t, "a3f8c2d1", withdrawn.Name)
+	assert.Equal(t, "TXT", withdrawn.Type)
+}
+
+func TestDeleteRecordSet_Success(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error) {
+		return &domain.RecordSet{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			Type:         "MX",
+			TTL:          300,
+			Values:       []string{"10 mail.example.com"},
+		}, nil
+	}
+
+	var removed dns.RecordSet
+	dnsSvc := &mockDNSService{
+		deleteRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			removed = rs
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"MX"}`,
+	}
+
+	resp, err := DeleteRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Assert(t, resp.Body.Deleted)
+	assert.Equal(t, "a3f8c2d1", removed.Name)
+	assert.DeepEqual(t, []string{"10 mail.example.com"}, removed.Values)
+}
+
+func TestDeleteRecordSet_DNSFailureKeepsRecord(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error) {
+		return &domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT", Values: []string{"hello"}}, nil
+	}
+	repo.deleteRecordSetFunc = func(ctx context.Context, ownerID, location, name, recordType string) error {
+		t.Fatal("should keep the record set when DNS removal fails")
+		return nil
+	}
+
+	dnsSvc := &mockDNSService{
+		deleteRecordSetFunc: func(ctx context.Context, rs dns.RecordSet) error {
+			return errors.New("route53 error")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"type":"TXT"}`,
+	}
+
+	_, err := DeleteRecordSet(ctx, request, "test-owner", "home", repo, dnsSvc, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Status)
+}
+
+func TestListRecordSets_Success(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.listRecordSetsFunc = func(ctx context.Context, ownerID, location string) ([]domain.RecordSet, error) {
+		return []domain.RecordSet{
+			{OwnerID: "test-owner", LocationName: "home", Type: "TXT", TTL: 300, Values: []string{"hello"}},
+			{OwnerID: "test-owner", LocationName: "home", Name: "_sip._udp", Type: "SRV", TTL: 300, Values: []string{"0 5 5060 sip.example.com"}},
+		}, nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := ListRecordSets(ctx, request, "test-owner", "home", repo, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, "a3f8c2d1.grocky.net", resp.Body.Subdomain)
+	assert.Equal(t, 2, len(resp.Body.RecordSets))
+	assert.Equal(t, "a3f8c2d1.grocky.net", resp.Body.RecordSets[0].FQDN)
+	assert.Equal(t, "_sip._udp.a3f8c2d1.grocky.net", resp.Body.RecordSets[1].FQDN)
+}
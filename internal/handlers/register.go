@@ -46,11 +46,13 @@ func Register(
 		}
 	}
 
-	// Authenticate - verify API key matches the ownerId in the request
-	_, authErr := auth.Authenticate(ctx, request, req.OwnerID, repo, logger)
+	// Authenticate - verify API key matches the ownerId in the request, or resolve
+	// the owner from the key when ownerId is omitted
+	identity, authErr := auth.AuthenticateOwnerOrKey(ctx, request, req.OwnerID, auth.Permission{Scope: domain.ScopeUpdate, Location: req.Location}, repo, logger)
 	if authErr != nil {
 		return response.MappingResponse{}, authErr
 	}
+	req.OwnerID = identity.Owner.OwnerID
 
 	// Determine IP address from request headers
 	ip := request.Headers["X-Forwarded-For"]
@@ -75,8 +77,11 @@ func Register(
 		}
 	}
 
-	// Generate subdomain
-	subdomain := dns.GenerateSubdomain(req.OwnerID, req.Location)
+	// Generate subdomain, skipping any hash already used by another mapping
+	subdomain, err := resolveSubdomain(ctx, repo, req.OwnerID, req.Location, logger)
+	if err != nil {
+		return response.MappingResponse{}, subdomainError(err, logger)
+	}
 	fullSubdomain := dns.FormatFQDN(subdomain)
 
 	// Create mapping
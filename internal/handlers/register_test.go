@@ -15,15 +15,70 @@ import (
 
 // mockRepository is a mock implementation of repository.Repository for testing.
 type mockRepository struct {
-	getOwnerFunc              func(ctx context.Context, ownerID string) (*domain.Owner, error)
-	createOwnerFunc           func(ctx context.Context, owner domain.Owner) error
-	updateOwnerKeyFunc        func(ctx context.Context, ownerID, newKeyHash string) error
-	putFunc                   func(ctx context.Context, mapping domain.IPMapping) error
-	getFunc                   func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error)
-	putChallengeFunc          func(ctx context.Context, challenge domain.ACMEChallenge) error
-	getChallengeFunc          func(ctx context.Context, ownerID, location string) (*domain.ACMEChallenge, error)
-	deleteChallengeFunc       func(ctx context.Context, ownerID, location string) error
-	scanExpiredChallengesFunc func(ctx context.Context) ([]domain.ACMEChallenge, error)
+	getOwnerFunc                     func(ctx context.Context, ownerID string) (*domain.Owner, error)
+	createOwnerFunc                  func(ctx context.Context, owner domain.Owner) error
+	verifyOwnerFunc                  func(ctx context.Context, ownerID, keyHash string, verifiedAt time.Time) error
+	updateOwnerKeyFunc               func(ctx context.Context, ownerID, keyID, newKeyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error
+	revokePreviousOwnerKeyFunc       func(ctx context.Context, ownerID string) error
+	replaceOwnerKeyFunc              func(ctx context.Context, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret string) error
+	putFunc                          func(ctx context.Context, mapping domain.IPMapping) error
+	getFunc                          func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error)
+	deleteFunc                       func(ctx context.Context, ownerID, location string) error
+	listMappingsBySubdomainFunc      func(ctx context.Context, subdomain string) ([]domain.IPMapping, error)
+	claimSubdomainFunc               func(ctx context.Context, subdomain, ownerID, location string) error
+	releaseSubdomainFunc             func(ctx context.Context, subdomain, ownerID, location string) error
+	putChallengeFunc                 func(ctx context.Context, challenge domain.ACMEChallenge) error
+	getChallengeFunc                 func(ctx context.Context, ownerID, location string) (*domain.ACMEChallenge, error)
+	deleteChallengeFunc              func(ctx context.Context, ownerID, location string) error
+	scanExpiredChallengesFunc        func(ctx context.Context) ([]domain.ACMEChallenge, error)
+	createRecordSetFunc              func(ctx context.Context, rs domain.RecordSet, limit int) error
+	putRecordSetFunc                 func(ctx context.Context, rs domain.RecordSet) error
+	getRecordSetFunc                 func(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error)
+	listRecordSetsFunc               func(ctx context.Context, ownerID, location string) ([]domain.RecordSet, error)
+	countRecordSetsFunc              func(ctx context.Context, ownerID string) (int, error)
+	deleteRecordSetFunc              func(ctx context.Context, ownerID, location, name, recordType string) error
+	createAPIKeyFunc                 func(ctx context.Context, key domain.APIKey) error
+	listAPIKeysFunc                  func(ctx context.Context, ownerID string) ([]domain.APIKey, error)
+	deleteAPIKeyFunc                 func(ctx context.Context, ownerID, keyID string) error
+	getKeyOwnerIDFunc                func(ctx context.Context, keyID string) (string, error)
+	recordAPIKeyUseFunc              func(ctx context.Context, ownerID, keyID string, usedAt time.Time, ip string) error
+	markAPIKeyExpiryWarnedFunc       func(ctx context.Context, ownerID, keyID string, warnedAt time.Time) error
+	scanExpiringAPIKeysFunc          func(ctx context.Context, before time.Time) ([]domain.APIKey, error)
+	useNonceFunc                     func(ctx context.Context, keyID, nonce string, expiresAt time.Time) error
+	listMappingsByOwnerFunc          func(ctx context.Context, ownerID string) ([]domain.IPMapping, error)
+	listOwnersFunc                   func(ctx context.Context) ([]domain.Owner, error)
+	setOwnerSuspensionFunc           func(ctx context.Context, ownerID string, suspendedAt *time.Time, reason string) error
+	setOwnerRateLimitFunc            func(ctx context.Context, ownerID, plan string, policy *domain.RateLimitPolicy) error
+	claimACMEChallengeFunc           func(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error)
+	setOwnerNotificationsFunc        func(ctx context.Context, ownerID string, prefs *domain.NotificationPreferences) error
+	setOwnerLocaleFunc               func(ctx context.Context, ownerID, locale string) error
+	scanPendingIPChangesFunc         func(ctx context.Context) ([]domain.IPMapping, error)
+	markIPChangesNotifiedFunc        func(ctx context.Context, ownerID, location string, notifiedAt time.Time) error
+	touchLocationFunc                func(ctx context.Context, ownerID, location string, seenAt time.Time) error
+	scanMonitoredLocationsFunc       func(ctx context.Context) ([]domain.IPMapping, error)
+	markLocationStaleAlertedFunc     func(ctx context.Context, ownerID, location string, alertedAt time.Time) error
+	createWebhookFunc                func(ctx context.Context, webhook domain.Webhook) error
+	listWebhooksFunc                 func(ctx context.Context, ownerID string) ([]domain.Webhook, error)
+	getWebhookFunc                   func(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error)
+	deleteWebhookFunc                func(ctx context.Context, ownerID, webhookID string) error
+	putWebhookDeliveryFunc           func(ctx context.Context, delivery domain.WebhookDelivery) error
+	listWebhookDeliveriesFunc        func(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error)
+	scanPendingWebhookDeliveriesFunc func(ctx context.Context) ([]domain.WebhookDelivery, error)
+	getRateLimitPlanFunc             func(ctx context.Context, name string) (*domain.RateLimitPlan, error)
+	listRateLimitPlansFunc           func(ctx context.Context) ([]domain.RateLimitPlan, error)
+	putRateLimitPlanFunc             func(ctx context.Context, plan domain.RateLimitPlan) error
+	deleteRateLimitPlanFunc          func(ctx context.Context, name string) error
+	createOperatorFunc               func(ctx context.Context, operator domain.Operator) error
+	getOperatorFunc                  func(ctx context.Context, keyID string) (*domain.Operator, error)
+	deleteOperatorFunc               func(ctx context.Context, keyID string) error
+	putAuditEntryFunc                func(ctx context.Context, entry domain.AuditEntry) error
+	listAuditEntriesFunc             func(ctx context.Context, date string) ([]domain.AuditEntry, error)
+	getAuthFailureFunc               func(ctx context.Context, subject string) (*domain.AuthFailure, error)
+	recordAuthFailureFunc            func(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error)
+	clearAuthFailuresFunc            func(ctx context.Context, subject string) error
+	scanAuthFailureAlertsFunc        func(ctx context.Context) ([]domain.AuthFailure, error)
+	markAuthFailureAlertedFunc       func(ctx context.Context, subject string, alertedAt time.Time) error
+	takeRequestTokenFunc             func(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error)
 }
 
 func (m *mockRepository) GetOwner(ctx context.Context, ownerID string) (*domain.Owner, error) {
@@ -40,9 +95,30 @@ func (m *mockRepository) CreateOwner(ctx context.Context, owner domain.Owner) er
 	return nil
 }
 
-func (m *mockRepository) UpdateOwnerKey(ctx context.Context, ownerID, newKeyHash string) error {
+func (m *mockRepository) VerifyOwner(ctx context.Context, ownerID, keyHash string, verifiedAt time.Time) error {
+	if m.verifyOwnerFunc != nil {
+		return m.verifyOwnerFunc(ctx, ownerID, keyHash, verifiedAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) UpdateOwnerKey(ctx context.Context, ownerID, keyID, newKeyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
 	if m.updateOwnerKeyFunc != nil {
-		return m.updateOwnerKeyFunc(ctx, ownerID, newKeyHash)
+		return m.updateOwnerKeyFunc(ctx, ownerID, keyID, newKeyHash, signingSecret, expiresAt, previous)
+	}
+	return nil
+}
+
+func (m *mockRepository) RevokePreviousOwnerKey(ctx context.Context, ownerID string) error {
+	if m.revokePreviousOwnerKeyFunc != nil {
+		return m.revokePreviousOwnerKeyFunc(ctx, ownerID)
+	}
+	return nil
+}
+
+func (m *mockRepository) ReplaceOwnerKey(ctx context.Context, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret string) error {
+	if m.replaceOwnerKeyFunc != nil {
+		return m.replaceOwnerKeyFunc(ctx, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret)
 	}
 	return nil
 }
@@ -61,6 +137,34 @@ func (m *mockRepository) Get(ctx context.Context, ownerID, location string) (*do
 	return nil, domain.ErrMappingNotFound
 }
 
+func (m *mockRepository) Delete(ctx context.Context, ownerID, location string) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, ownerID, location)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListMappingsBySubdomain(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+	if m.listMappingsBySubdomainFunc != nil {
+		return m.listMappingsBySubdomainFunc(ctx, subdomain)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) ClaimSubdomain(ctx context.Context, subdomain, ownerID, location string) error {
+	if m.claimSubdomainFunc != nil {
+		return m.claimSubdomainFunc(ctx, subdomain, ownerID, location)
+	}
+	return nil
+}
+
+func (m *mockRepository) ReleaseSubdomain(ctx context.Context, subdomain, ownerID, location string) error {
+	if m.releaseSubdomainFunc != nil {
+		return m.releaseSubdomainFunc(ctx, subdomain, ownerID, location)
+	}
+	return nil
+}
+
 func (m *mockRepository) PutChallenge(ctx context.Context, challenge domain.ACMEChallenge) error {
 	if m.putChallengeFunc != nil {
 		return m.putChallengeFunc(ctx, challenge)
@@ -89,6 +193,342 @@ func (m *mockRepository) ScanExpiredChallenges(ctx context.Context) ([]domain.AC
 	return nil, nil
 }
 
+func (m *mockRepository) CreateRecordSet(ctx context.Context, rs domain.RecordSet, limit int) error {
+	if m.createRecordSetFunc != nil {
+		return m.createRecordSetFunc(ctx, rs, limit)
+	}
+	return nil
+}
+
+func (m *mockRepository) PutRecordSet(ctx context.Context, rs domain.RecordSet) error {
+	if m.putRecordSetFunc != nil {
+		return m.putRecordSetFunc(ctx, rs)
+	}
+	return nil
+}
+
+func (m *mockRepository) GetRecordSet(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error) {
+	if m.getRecordSetFunc != nil {
+		return m.getRecordSetFunc(ctx, ownerID, location, name, recordType)
+	}
+	return nil, domain.ErrRecordSetNotFound
+}
+
+func (m *mockRepository) ListRecordSets(ctx context.Context, ownerID, location string) ([]domain.RecordSet, error) {
+	if m.listRecordSetsFunc != nil {
+		return m.listRecordSetsFunc(ctx, ownerID, location)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) CountRecordSets(ctx context.Context, ownerID string) (int, error) {
+	if m.countRecordSetsFunc != nil {
+		return m.countRecordSetsFunc(ctx, ownerID)
+	}
+	return 0, nil
+}
+
+func (m *mockRepository) DeleteRecordSet(ctx context.Context, ownerID, location, name, recordType string) error {
+	if m.deleteRecordSetFunc != nil {
+		return m.deleteRecordSetFunc(ctx, ownerID, location, name, recordType)
+	}
+	return nil
+}
+
+func (m *mockRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	if m.createAPIKeyFunc != nil {
+		return m.createAPIKeyFunc(ctx, key)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListAPIKeys(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+	if m.listAPIKeysFunc != nil {
+		return m.listAPIKeysFunc(ctx, ownerID)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) GetKeyOwnerID(ctx context.Context, keyID string) (string, error) {
+	if m.getKeyOwnerIDFunc != nil {
+		return m.getKeyOwnerIDFunc(ctx, keyID)
+	}
+	return "", domain.ErrAPIKeyNotFound
+}
+
+func (m *mockRepository) DeleteAPIKey(ctx context.Context, ownerID, keyID string) error {
+	if m.deleteAPIKeyFunc != nil {
+		return m.deleteAPIKeyFunc(ctx, ownerID, keyID)
+	}
+	return domain.ErrAPIKeyNotFound
+}
+
+func (m *mockRepository) RecordAPIKeyUse(ctx context.Context, ownerID, keyID string, usedAt time.Time, ip string) error {
+	if m.recordAPIKeyUseFunc != nil {
+		return m.recordAPIKeyUseFunc(ctx, ownerID, keyID, usedAt, ip)
+	}
+	return nil
+}
+
+func (m *mockRepository) MarkAPIKeyExpiryWarned(ctx context.Context, ownerID, keyID string, warnedAt time.Time) error {
+	if m.markAPIKeyExpiryWarnedFunc != nil {
+		return m.markAPIKeyExpiryWarnedFunc(ctx, ownerID, keyID, warnedAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) ScanExpiringAPIKeys(ctx context.Context, before time.Time) ([]domain.APIKey, error) {
+	if m.scanExpiringAPIKeysFunc != nil {
+		return m.scanExpiringAPIKeysFunc(ctx, before)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) UseNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) error {
+	if m.useNonceFunc != nil {
+		return m.useNonceFunc(ctx, keyID, nonce, expiresAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListMappingsByOwner(ctx context.Context, ownerID string) ([]domain.IPMapping, error) {
+	if m.listMappingsByOwnerFunc != nil {
+		return m.listMappingsByOwnerFunc(ctx, ownerID)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) ListOwners(ctx context.Context) ([]domain.Owner, error) {
+	if m.listOwnersFunc != nil {
+		return m.listOwnersFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) SetOwnerSuspension(ctx context.Context, ownerID string, suspendedAt *time.Time, reason string) error {
+	if m.setOwnerSuspensionFunc != nil {
+		return m.setOwnerSuspensionFunc(ctx, ownerID, suspendedAt, reason)
+	}
+	return nil
+}
+
+func (m *mockRepository) SetOwnerRateLimit(ctx context.Context, ownerID, plan string, policy *domain.RateLimitPolicy) error {
+	if m.setOwnerRateLimitFunc != nil {
+		return m.setOwnerRateLimitFunc(ctx, ownerID, plan, policy)
+	}
+	return nil
+}
+
+func (m *mockRepository) ClaimACMEChallenge(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error) {
+	if m.claimACMEChallengeFunc != nil {
+		return m.claimACMEChallengeFunc(ctx, ownerID, limit, now)
+	}
+	return 1, nil
+}
+
+func (m *mockRepository) SetOwnerNotifications(ctx context.Context, ownerID string, prefs *domain.NotificationPreferences) error {
+	if m.setOwnerNotificationsFunc != nil {
+		return m.setOwnerNotificationsFunc(ctx, ownerID, prefs)
+	}
+	return nil
+}
+
+func (m *mockRepository) SetOwnerLocale(ctx context.Context, ownerID, locale string) error {
+	if m.setOwnerLocaleFunc != nil {
+		return m.setOwnerLocaleFunc(ctx, ownerID, locale)
+	}
+	return nil
+}
+
+func (m *mockRepository) ScanPendingIPChanges(ctx context.Context) ([]domain.IPMapping, error) {
+	if m.scanPendingIPChangesFunc != nil {
+		return m.scanPendingIPChangesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) MarkIPChangesNotified(ctx context.Context, ownerID, location string, notifiedAt time.Time) error {
+	if m.markIPChangesNotifiedFunc != nil {
+		return m.markIPChangesNotifiedFunc(ctx, ownerID, location, notifiedAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) TouchLocation(ctx context.Context, ownerID, location string, seenAt time.Time) error {
+	if m.touchLocationFunc != nil {
+		return m.touchLocationFunc(ctx, ownerID, location, seenAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) ScanMonitoredLocations(ctx context.Context) ([]domain.IPMapping, error) {
+	if m.scanMonitoredLocationsFunc != nil {
+		return m.scanMonitoredLocationsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) MarkLocationStaleAlerted(ctx context.Context, ownerID, location string, alertedAt time.Time) error {
+	if m.markLocationStaleAlertedFunc != nil {
+		return m.markLocationStaleAlertedFunc(ctx, ownerID, location, alertedAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	if m.createWebhookFunc != nil {
+		return m.createWebhookFunc(ctx, webhook)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListWebhooks(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+	if m.listWebhooksFunc != nil {
+		return m.listWebhooksFunc(ctx, ownerID)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) GetWebhook(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error) {
+	if m.getWebhookFunc != nil {
+		return m.getWebhookFunc(ctx, ownerID, webhookID)
+	}
+	return nil, domain.ErrWebhookNotFound
+}
+
+func (m *mockRepository) DeleteWebhook(ctx context.Context, ownerID, webhookID string) error {
+	if m.deleteWebhookFunc != nil {
+		return m.deleteWebhookFunc(ctx, ownerID, webhookID)
+	}
+	return nil
+}
+
+func (m *mockRepository) PutWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	if m.putWebhookDeliveryFunc != nil {
+		return m.putWebhookDeliveryFunc(ctx, delivery)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	if m.listWebhookDeliveriesFunc != nil {
+		return m.listWebhookDeliveriesFunc(ctx, webhookID, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) ScanPendingWebhookDeliveries(ctx context.Context) ([]domain.WebhookDelivery, error) {
+	if m.scanPendingWebhookDeliveriesFunc != nil {
+		return m.scanPendingWebhookDeliveriesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) GetRateLimitPlan(ctx context.Context, name string) (*domain.RateLimitPlan, error) {
+	if m.getRateLimitPlanFunc != nil {
+		return m.getRateLimitPlanFunc(ctx, name)
+	}
+	return nil, domain.ErrRateLimitPlanNotFound
+}
+
+func (m *mockRepository) ListRateLimitPlans(ctx context.Context) ([]domain.RateLimitPlan, error) {
+	if m.listRateLimitPlansFunc != nil {
+		return m.listRateLimitPlansFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) PutRateLimitPlan(ctx context.Context, plan domain.RateLimitPlan) error {
+	if m.putRateLimitPlanFunc != nil {
+		return m.putRateLimitPlanFunc(ctx, plan)
+	}
+	return nil
+}
+
+func (m *mockRepository) DeleteRateLimitPlan(ctx context.Context, name string) error {
+	if m.deleteRateLimitPlanFunc != nil {
+		return m.deleteRateLimitPlanFunc(ctx, name)
+	}
+	return nil
+}
+
+func (m *mockRepository) CreateOperator(ctx context.Context, operator domain.Operator) error {
+	if m.createOperatorFunc != nil {
+		return m.createOperatorFunc(ctx, operator)
+	}
+	return nil
+}
+
+func (m *mockRepository) GetOperator(ctx context.Context, keyID string) (*domain.Operator, error) {
+	if m.getOperatorFunc != nil {
+		return m.getOperatorFunc(ctx, keyID)
+	}
+	return nil, domain.ErrOperatorNotFound
+}
+
+func (m *mockRepository) DeleteOperator(ctx context.Context, keyID string) error {
+	if m.deleteOperatorFunc != nil {
+		return m.deleteOperatorFunc(ctx, keyID)
+	}
+	return nil
+}
+
+func (m *mockRepository) PutAuditEntry(ctx context.Context, entry domain.AuditEntry) error {
+	if m.putAuditEntryFunc != nil {
+		return m.putAuditEntryFunc(ctx, entry)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListAuditEntries(ctx context.Context, date string) ([]domain.AuditEntry, error) {
+	if m.listAuditEntriesFunc != nil {
+		return m.listAuditEntriesFunc(ctx, date)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) GetAuthFailure(ctx context.Context, subject string) (*domain.AuthFailure, error) {
+	if m.getAuthFailureFunc != nil {
+		return m.getAuthFailureFunc(ctx, subject)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) RecordAuthFailure(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+	if m.recordAuthFailureFunc != nil {
+		return m.recordAuthFailureFunc(ctx, subject, ownerID, ip, policy, now)
+	}
+	return &domain.AuthFailure{Subject: subject, OwnerID: ownerID, Failures: 1}, nil
+}
+
+func (m *mockRepository) ClearAuthFailures(ctx context.Context, subject string) error {
+	if m.clearAuthFailuresFunc != nil {
+		return m.clearAuthFailuresFunc(ctx, subject)
+	}
+	return nil
+}
+
+func (m *mockRepository) ScanAuthFailureAlerts(ctx context.Context) ([]domain.AuthFailure, error) {
+	if m.scanAuthFailureAlertsFunc != nil {
+		return m.scanAuthFailureAlertsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) MarkAuthFailureAlerted(ctx context.Context, subject string, alertedAt time.Time) error {
+	if m.markAuthFailureAlertedFunc != nil {
+		return m.markAuthFailureAlertedFunc(ctx, subject, alertedAt)
+	}
+	return nil
+}
+
+func (m *mockRepository) TakeRequestToken(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+	if m.takeRequestTokenFunc != nil {
+		return m.takeRequestTokenFunc(ctx, bucket, limit, now)
+	}
+	return 0, true, nil
+}
+
 func TestRegister_Success_AutoIP(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -211,7 +651,7 @@ func TestRegister_MissingOwnerID(t *testing.T) {
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
-	assert.Equal(t, "ownerId is required", err.Description)
+	assert.Equal(t, domain.ErrOwnerIDRequired.Error(), err.Description)
 	assert.Equal(t, 0, resp.Status)
 }
 
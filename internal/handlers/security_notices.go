@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+)
+
+// Security notices tell owners about sensitive actions on their account, with
+// where the request came from. They are sent unless the owner suppressed them
+// in their notification preferences, and a failure to send one is logged
+// without failing the request.
+
+// notifyKeyRotated tells the owner that their primary key was rotated.
+func notifyKeyRotated(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	owner *domain.Owner,
+	rotatedAt time.Time,
+	previous *domain.PreviousKey,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) {
+	if !owner.Notifications.NotifiesSecurity(domain.SecurityNoticeKeyRotated) {
+		return
+	}
+
+	notice := email.KeyRotatedNotice{Source: requestSource(request, rotatedAt)}
+	if previous != nil {
+		notice.PreviousKeyExpiresAt = &previous.ExpiresAt
+	}
+	if err := emailSvc.SendKeyRotatedNotice(ctx, emailRecipient(owner), notice); err != nil {
+		logger.Warn("failed to send key rotated notice", "error", err, "ownerId", owner.OwnerID)
+	}
+}
+
+// notifyRecoveryRequested tells the owner that key recovery was requested.
+// emailMatched reports whether the request gave the owner's email address.
+func notifyRecoveryRequested(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	owner *domain.Owner,
+	emailMatched bool,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) {
+	if !owner.Notifications.NotifiesSecurity(domain.SecurityNoticeRecoveryRequested) {
+		return
+	}
+
+	notice := email.RecoveryRequestedNotice{
+		Source:       requestSource(request, time.Now().UTC()),
+		EmailMatched: emailMatched,
+	}
+	if err := emailSvc.SendRecoveryRequestedNotice(ctx, emailRecipient(owner), notice); err != nil {
+		logger.Warn("failed to send recovery requested notice", "error", err, "ownerId", owner.OwnerID)
+	}
+}
+
+// notifyACMEChallenge tells the owner that keyID created an ACME challenge for
+// one of their locations.
+func notifyACMEChallenge(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	owner *domain.Owner,
+	keyID string,
+	challenge domain.ACMEChallenge,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) {
+	if !owner.Notifications.NotifiesSecurity(domain.SecurityNoticeACMEChallenge) {
+		return
+	}
+
+	notice := email.ACMEChallengeNotice{
+		Source:    requestSource(request, challenge.CreatedAt),
+		Location:  challenge.LocationName,
+		Subdomain: dns.FormatFQDN(challenge.Subdomain),
+		KeyID:     keyID,
+		ExpiresAt: challenge.ExpiresAt,
+	}
+	if err := emailSvc.SendACMEChallengeNotice(ctx, emailRecipient(owner), notice); err != nil {
+		logger.Warn("failed to send ACME challenge notice", "error", err, "ownerId", owner.OwnerID, "location", challenge.LocationName)
+	}
+}
+
+// requestSource describes where request came from, as seen by API Gateway.
+// X-Forwarded-For and User-Agent headers are only used when the request context
+// lacks them, since callers can set them to anything.
+func requestSource(request events.APIGatewayProxyRequest, at time.Time) email.RequestSource {
+	source := email.RequestSource{
+		IP:        request.RequestContext.Identity.SourceIP,
+		UserAgent: request.RequestContext.Identity.UserAgent,
+		At:        at,
+	}
+	if source.IP == "" {
+		source.IP = extractClientIP(request)
+	}
+	if source.UserAgent == "" {
+		source.UserAgent = request.Headers["User-Agent"]
+		if source.UserAgent == "" {
+			source.UserAgent = request.Headers["user-agent"]
+		}
+	}
+	return source
+}
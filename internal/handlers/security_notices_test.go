@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"gotest.tools/assert"
+)
+
+// withSuppressedNotices makes repo's owner suppress the security notices.
+func withSuppressedNotices(repo *mockRepository, notices ...string) {
+	getOwner := repo.getOwnerFunc
+	repo.getOwnerFunc = func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+		owner, err := getOwner(ctx, ownerID)
+		if err == nil {
+			owner.Notifications = &domain.NotificationPreferences{Security: &domain.SecurityNotifications{Suppress: notices}}
+		}
+		return owner, err
+	}
+}
+
+// withRequestIdentity sets the source IP and user agent API Gateway reports for request.
+func withRequestIdentity(request events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	request.RequestContext.Identity.SourceIP = "198.51.100.7"
+	request.RequestContext.Identity.UserAgent = "curl/8.5.0"
+	return request
+}
+
+func TestRotateKey_SendsSecurityNotice(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var sent []email.KeyRotatedNotice
+	var recipient email.Recipient
+	emailSvc := &mockEmailService{
+		sendKeyRotatedNoticeFunc: func(ctx context.Context, to email.Recipient, notice email.KeyRotatedNotice) error {
+			recipient = to
+			sent = append(sent, notice)
+			return nil
+		},
+	}
+
+	request := withRequestIdentity(events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	})
+
+	resp, err := RotateKey(context.Background(), request, "test-owner", repo, emailSvc, time.Hour, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, 1, len(sent))
+	assert.Equal(t, "user@example.com", recipient.Email)
+	assert.Equal(t, "198.51.100.7", sent[0].Source.IP)
+	assert.Equal(t, "curl/8.5.0", sent[0].Source.UserAgent)
+	assert.Equal(t, resp.Body.RotatedAt, sent[0].Source.At.Format(time.RFC3339))
+	assert.Assert(t, sent[0].PreviousKeyExpiresAt != nil)
+	assert.Equal(t, resp.Body.PreviousKeyExpiresAt, sent[0].PreviousKeyExpiresAt.Format(time.RFC3339))
+}
+
+func TestRotateKey_SecurityNoticeSuppressed(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	withSuppressedNotices(repo, domain.SecurityNoticeKeyRotated)
+
+	emailSvc := &mockEmailService{
+		sendKeyRotatedNoticeFunc: func(ctx context.Context, to email.Recipient, notice email.KeyRotatedNotice) error {
+			t.Fatal("suppressed notice should not be sent")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := RotateKey(context.Background(), request, "test-owner", repo, emailSvc, time.Hour, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusOK, resp.Status)
+}
+
+func TestRotateKey_SecurityNoticeFailureIgnored(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	emailSvc := &mockEmailService{
+		sendKeyRotatedNoticeFunc: func(ctx context.Context, to email.Recipient, notice email.KeyRotatedNotice) error {
+			return errors.New("SES unavailable")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}
+
+	resp, err := RotateKey(context.Background(), request, "test-owner", repo, emailSvc, time.Hour, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, resp.Body.APIKey != "", "the new key is still returned")
+}
+
+func TestRecoverKey_SendsSecurityNotice(t *testing.T) {
+	testCases := []struct {
+		name         string
+		email        string
+		suppress     []string
+		expectToken  bool
+		expectNotice bool
+	}{
+		{name: "email matched", email: "user@example.com", expectToken: true, expectNotice: true},
+		{name: "email mismatched", email: "attacker@example.com", expectNotice: true},
+		{name: "suppressed", email: "user@example.com", suppress: []string{domain.SecurityNoticeRecoveryRequested}, expectToken: true},
+		{name: "other notice suppressed", email: "attacker@example.com", suppress: []string{domain.SecurityNoticeKeyRotated}, expectNotice: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo("ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop")
+			if tc.suppress != nil {
+				withSuppressedNotices(repo, tc.suppress...)
+			}
+
+			tokenSent := false
+			var notices []email.RecoveryRequestedNotice
+			emailSvc := &mockEmailService{
+				sendRecoveryFunc: func(ctx context.Context, to email.Recipient, token string, expiresAt time.Time) error {
+					tokenSent = true
+					return nil
+				},
+				sendRecoveryRequestedFunc: func(ctx context.Context, to email.Recipient, notice email.RecoveryRequestedNotice) error {
+					assert.Equal(t, "user@example.com", to.Email)
+					notices = append(notices, notice)
+					return nil
+				},
+			}
+
+			request := withRequestIdentity(events.APIGatewayProxyRequest{
+				Body: `{"email":"` + tc.email + `"}`,
+			})
+
+			resp, err := RecoverKey(context.Background(), request, "test-owner", repo, emailSvc, newTestSigner(t), newTestLogger())
+
+			assert.Assert(t, err == nil, "expected no error, got %v", err)
+			assert.Equal(t, recoverySentMessage, resp.Body.Message)
+			assert.Equal(t, tc.expectToken, tokenSent)
+			assert.Equal(t, tc.expectNotice, len(notices) == 1)
+			if tc.expectNotice {
+				assert.Equal(t, tc.expectToken, notices[0].EmailMatched)
+				assert.Equal(t, "198.51.100.7", notices[0].Source.IP)
+				assert.Equal(t, "curl/8.5.0", notices[0].Source.UserAgent)
+			}
+		})
+	}
+}
+
+func TestRecoverKey_NoSecurityNoticeForUnverifiedOwner(t *testing.T) {
+	verifyBy := time.Now().UTC().Add(time.Hour)
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: "test-owner", Email: "user@example.com", VerifyBy: &verifyBy}, nil
+		},
+	}
+
+	emailSvc := &mockEmailService{
+		sendRecoveryRequestedFunc: func(ctx context.Context, to email.Recipient, notice email.RecoveryRequestedNotice) error {
+			t.Fatal("unverified owners should not be notified")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{Body: `{"email":"user@example.com"}`}
+
+	_, err := RecoverKey(context.Background(), request, "test-owner", repo, emailSvc, newTestSigner(t), newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+}
+
+func TestCreateACMEChallenge_SendsSecurityNotice(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var sent []email.ACMEChallengeNotice
+	emailSvc := &mockEmailService{
+		sendACMEChallengeFunc: func(ctx context.Context, to email.Recipient, notice email.ACMEChallengeNotice) error {
+			assert.Equal(t, "test-owner", to.OwnerID)
+			sent = append(sent, notice)
+			return nil
+		},
+	}
+
+	resp, err := CreateACMEChallenge(context.Background(), withRequestIdentity(acmeChallengeRequest(apiKey)), repo, &mockDNSService{}, &mockWebhookService{}, emailSvc, domain.DefaultACMEChallengeLimit, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, 1, len(sent))
+	assert.Equal(t, "home", sent[0].Location)
+	assert.Equal(t, resp.Body.Subdomain, sent[0].Subdomain)
+	assert.Equal(t, domain.PrimaryKeyID, sent[0].KeyID)
+	assert.Equal(t, "198.51.100.7", sent[0].Source.IP)
+	assert.Equal(t, "curl/8.5.0", sent[0].Source.UserAgent)
+	assert.Equal(t, resp.Body.ExpiresAt, sent[0].ExpiresAt.Format(time.RFC3339))
+}
+
+func TestCreateACMEChallenge_SecurityNoticeSuppressed(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	withSuppressedNotices(repo, domain.SecurityNoticeACMEChallenge)
+
+	emailSvc := &mockEmailService{
+		sendACMEChallengeFunc: func(ctx context.Context, to email.Recipient, notice email.ACMEChallengeNotice) error {
+			t.Fatal("suppressed notice should not be sent")
+			return nil
+		},
+	}
+
+	resp, err := CreateACMEChallenge(context.Background(), acmeChallengeRequest(apiKey), repo, &mockDNSService{}, &mockWebhookService{}, emailSvc, domain.DefaultACMEChallengeLimit, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+}
+
+func TestRequestSource(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		request  events.APIGatewayProxyRequest
+		expected email.RequestSource
+	}{
+		{
+			name:     "request context",
+			request:  withRequestIdentity(events.APIGatewayProxyRequest{Headers: map[string]string{"X-Forwarded-For": "203.0.113.9", "User-Agent": "spoofed"}}),
+			expected: email.RequestSource{IP: "198.51.100.7", UserAgent: "curl/8.5.0", At: at},
+		},
+		{
+			name:     "headers",
+			request:  events.APIGatewayProxyRequest{Headers: map[string]string{"x-forwarded-for": "203.0.113.9, 10.0.0.1", "user-agent": "ddns-client/1.0"}},
+			expected: email.RequestSource{IP: "203.0.113.9", UserAgent: "ddns-client/1.0", At: at},
+		},
+		{
+			name:     "unknown",
+			request:  events.APIGatewayProxyRequest{},
+			expected: email.RequestSource{At: at},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.DeepEqual(t, tc.expected, requestSource(tc.request, at))
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/repository"
+)
+
+// StaleLocationResult represents the result of the stale location check.
+type StaleLocationResult struct {
+	Processed int `json:"processed"`
+	Stale     int `json:"stale"`
+	Alerted   int `json:"alerted"`
+	Errors    int `json:"errors"`
+}
+
+// CheckStaleLocations emails owners about monitored locations that haven't
+// checked in for longer than their stale threshold. Called by EventBridge
+// every few minutes. Each silence is reported once; the location's next
+// check-in clears it.
+func CheckStaleLocations(
+	ctx context.Context,
+	repo repository.Repository,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) (*StaleLocationResult, error) {
+	logger.Info("starting stale location check")
+
+	result := &StaleLocationResult{}
+	now := time.Now().UTC()
+
+	mappings, err := repo.ScanMonitoredLocations(ctx)
+	if err != nil {
+		logger.Error("failed to scan monitored locations", "error", err)
+		return nil, err
+	}
+
+	result.Processed = len(mappings)
+	logger.Info("found monitored locations", "count", len(mappings))
+
+	owners := make(map[string]*domain.Owner)
+	for _, mapping := range mappings {
+		if !mapping.IsStale(now) {
+			continue
+		}
+		result.Stale++
+		if !mapping.StaleAlertDue(now) {
+			continue
+		}
+
+		owner, ok := owners[mapping.OwnerID]
+		if !ok {
+			owner, err = repo.GetOwner(ctx, mapping.OwnerID)
+			if err != nil {
+				logger.Error("failed to get owner", "error", err, "ownerId", mapping.OwnerID)
+				result.Errors++
+				continue
+			}
+			owners[mapping.OwnerID] = owner
+		}
+
+		alert := email.StaleLocationAlert{
+			Location:   mapping.LocationName,
+			Subdomain:  dns.FormatFQDN(mappingSubdomain(mapping)),
+			IP:         mapping.IP,
+			LastSeenAt: mapping.LastSeen(),
+			StaleAfter: mapping.StaleAfter(),
+		}
+		if err := emailSvc.SendStaleLocationAlert(ctx, emailRecipient(owner), alert); err != nil {
+			logger.Error("failed to send stale location alert", "error", err, "ownerId", mapping.OwnerID, "location", mapping.LocationName)
+			result.Errors++
+			continue
+		}
+
+		// A failure marking the alert means it may be sent again on the next run
+		if err := repo.MarkLocationStaleAlerted(ctx, mapping.OwnerID, mapping.LocationName, now); err != nil {
+			logger.Error("failed to mark location stale alerted", "error", err, "ownerId", mapping.OwnerID, "location", mapping.LocationName)
+			result.Errors++
+			continue
+		}
+
+		result.Alerted++
+		logger.Info("stale location alert sent",
+			"ownerId", mapping.OwnerID,
+			"location", mapping.LocationName,
+			"lastSeenAt", alert.LastSeenAt,
+		)
+	}
+
+	logger.Info("stale location check completed",
+		"processed", result.Processed,
+		"stale", result.Stale,
+		"alerted", result.Alerted,
+		"errors", result.Errors,
+	)
+
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
+	"gotest.tools/assert"
+)
+
+func TestCheckStaleLocations(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	now := time.Now().UTC()
+	recently := now.Add(-10 * time.Minute)
+	longAgo := now.Add(-3 * time.Hour)
+
+	repo := &mockRepository{
+		scanMonitoredLocationsFunc: func(ctx context.Context) ([]domain.IPMapping, error) {
+			return []domain.IPMapping{
+				{OwnerID: "test-owner", LocationName: "cabin", Subdomain: "a3f8c2d1", IP: "203.0.113.50", LastSeenAt: &longAgo, StaleAfterSeconds: 3600},
+				{OwnerID: "test-owner", LocationName: "home", LastSeenAt: &recently, StaleAfterSeconds: 3600},
+				{OwnerID: "test-owner", LocationName: "office", LastSeenAt: &longAgo, StaleAfterSeconds: 3600, StaleAlertedAt: &recently},
+				{OwnerID: "broken", LocationName: "home", LastSeenAt: &longAgo, StaleAfterSeconds: 3600},
+				{OwnerID: "missing", LocationName: "home", LastSeenAt: &longAgo, StaleAfterSeconds: 3600},
+			}, nil
+		},
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			if ownerID == "missing" {
+				return nil, domain.ErrOwnerNotFound
+			}
+			return &domain.Owner{OwnerID: ownerID, Email: ownerID + "@example.com"}, nil
+		},
+	}
+
+	var marked []string
+	repo.markLocationStaleAlertedFunc = func(ctx context.Context, ownerID, location string, alertedAt time.Time) error {
+		marked = append(marked, ownerID+"/"+location)
+		return nil
+	}
+
+	var sent []email.StaleLocationAlert
+	emailSvc := &mockEmailService{
+		sendStaleLocationAlertFunc: func(ctx context.Context, to email.Recipient, alert email.StaleLocationAlert) error {
+			if to.OwnerID == "broken" {
+				return errors.New("SES error")
+			}
+			assert.Equal(t, "test-owner@example.com", to.Email)
+			sent = append(sent, alert)
+			return nil
+		},
+	}
+
+	result, err := CheckStaleLocations(ctx, repo, emailSvc, logger)
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &StaleLocationResult{Processed: 5, Stale: 4, Alerted: 1, Errors: 2}, result)
+	assert.DeepEqual(t, []string{"test-owner/cabin"}, marked)
+	assert.Equal(t, 1, len(sent))
+	assert.Equal(t, "cabin", sent[0].Location)
+	assert.Equal(t, "a3f8c2d1.grocky.net", sent[0].Subdomain)
+	assert.Equal(t, "203.0.113.50", sent[0].IP)
+	assert.Equal(t, longAgo, sent[0].LastSeenAt)
+	assert.Equal(t, time.Hour, sent[0].StaleAfter)
+}
+
+func TestCheckStaleLocations_ScanError(t *testing.T) {
+	repo := &mockRepository{
+		scanMonitoredLocationsFunc: func(ctx context.Context) ([]domain.IPMapping, error) {
+			return nil, errors.New("scan failed")
+		},
+	}
+
+	_, err := CheckStaleLocations(context.Background(), repo, &mockEmailService{}, newTestLogger())
+
+	assert.ErrorContains(t, err, "scan failed")
+}
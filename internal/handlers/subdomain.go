@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+)
+
+// maxSubdomainAttempts bounds how many variants are tried before giving up.
+const maxSubdomainAttempts = 5
+
+// resolveSubdomain picks the subdomain for a location that is published for the first time.
+// It walks the deterministic variants from dns.GenerateSubdomainVariant and claims the
+// first one no other owner/location holds, so a hash collision never repoints someone
+// else's record. The claim is a conditional write, so concurrent requests can't both
+// win the same variant; mappings from before claims are found through the subdomain
+// index instead.
+func resolveSubdomain(ctx context.Context, repo repository.Repository, ownerID, location string, logger *slog.Logger) (string, error) {
+	for attempt := 0; attempt < maxSubdomainAttempts; attempt++ {
+		candidate := dns.GenerateSubdomainVariant(ownerID, location, attempt)
+
+		mappings, err := repo.ListMappingsBySubdomain(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		taken := false
+		for _, m := range mappings {
+			if m.OwnerID != ownerID || m.LocationName != location {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			err := repo.ClaimSubdomain(ctx, candidate, ownerID, location)
+			if err == nil {
+				return candidate, nil
+			}
+			if !errors.Is(err, domain.ErrSubdomainTaken) {
+				return "", err
+			}
+		}
+
+		logger.Warn("subdomain collision detected",
+			"ownerId", ownerID,
+			"location", location,
+			"subdomain", candidate,
+			"attempt", attempt,
+		)
+	}
+
+	return "", domain.ErrSubdomainUnavailable
+}
+
+// subdomainError maps a resolveSubdomain failure to a request error.
+func subdomainError(err error, logger *slog.Logger) *response.RequestError {
+	if errors.Is(err, domain.ErrSubdomainUnavailable) {
+		logger.Error("no collision-free subdomain available", "error", err)
+		return &response.RequestError{
+			Status:      http.StatusConflict,
+			Description: err.Error(),
+		}
+	}
+	logger.Error("failed to check subdomain availability", "error", err)
+	return &response.RequestError{
+		Status:      http.StatusInternalServerError,
+		Description: "failed to check subdomain availability",
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
+	"github.com/grocky/ddns-service/internal/domain"
+	"gotest.tools/assert"
+)
+
+func TestResolveSubdomain_NoCollision(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	repo := &mockRepository{}
+
+	subdomain, err := resolveSubdomain(ctx, repo, "test-owner", "home", logger)
+
+	assert.NilError(t, err)
+	assert.Equal(t, dns.GenerateSubdomain("test-owner", "home"), subdomain)
+}
+
+func TestResolveSubdomain_OwnMappingIsNotACollision(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	repo := &mockRepository{
+		listMappingsBySubdomainFunc: func(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+			return []domain.IPMapping{{OwnerID: "test-owner", LocationName: "home", Subdomain: subdomain}}, nil
+		},
+	}
+
+	subdomain, err := resolveSubdomain(ctx, repo, "test-owner", "home", logger)
+
+	assert.NilError(t, err)
+	assert.Equal(t, dns.GenerateSubdomain("test-owner", "home"), subdomain)
+}
+
+func TestResolveSubdomain_CollisionFallsBackToVariant(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	primary := dns.GenerateSubdomain("test-owner", "home")
+	repo := &mockRepository{
+		listMappingsBySubdomainFunc: func(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+			if subdomain == primary {
+				return []domain.IPMapping{{OwnerID: "other-owner", LocationName: "office", Subdomain: subdomain}}, nil
+			}
+			return nil, nil
+		},
+	}
+
+	subdomain, err := resolveSubdomain(ctx, repo, "test-owner", "home", logger)
+
+	assert.NilError(t, err)
+	assert.Equal(t, dns.GenerateSubdomainVariant("test-owner", "home", 1), subdomain)
+}
+
+func TestResolveSubdomain_ClaimedFallsBackToVariant(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	// Another request claimed the primary subdomain but hasn't stored its mapping yet
+	primary := dns.GenerateSubdomain("test-owner", "home")
+	var claimed []string
+	repo := &mockRepository{
+		claimSubdomainFunc: func(ctx context.Context, subdomain, ownerID, location string) error {
+			assert.Equal(t, "test-owner", ownerID)
+			assert.Equal(t, "home", location)
+			claimed = append(claimed, subdomain)
+			if subdomain == primary {
+				return domain.ErrSubdomainTaken
+			}
+			return nil
+		},
+	}
+
+	subdomain, err := resolveSubdomain(ctx, repo, "test-owner", "home", logger)
+
+	assert.NilError(t, err)
+	variant := dns.GenerateSubdomainVariant("test-owner", "home", 1)
+	assert.Equal(t, variant, subdomain)
+	assert.DeepEqual(t, []string{primary, variant}, claimed)
+}
+
+func TestResolveSubdomain_ClaimError(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	claimErr := errors.New("dynamodb unavailable")
+	repo := &mockRepository{
+		claimSubdomainFunc: func(ctx context.Context, subdomain, ownerID, location string) error {
+			return claimErr
+		},
+	}
+
+	_, err := resolveSubdomain(ctx, repo, "test-owner", "home", logger)
+
+	assert.Assert(t, errors.Is(err, claimErr))
+}
+
+func TestResolveSubdomain_Exhausted(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	repo := &mockRepository{
+		listMappingsBySubdomainFunc: func(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+			return []domain.IPMapping{{OwnerID: "other-owner", LocationName: "office", Subdomain: subdomain}}, nil
+		},
+	}
+
+	_, err := resolveSubdomain(ctx, repo, "test-owner", "home", logger)
+
+	assert.Assert(t, errors.Is(err, domain.ErrSubdomainUnavailable))
+}
+
+func TestUpdate_NewMappingSubdomainCollision(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	apiKeyHash := auth.HashAPIKey(apiKey)
+
+	primary := dns.GenerateSubdomain("test-owner", "home")
+	variant := dns.GenerateSubdomainVariant("test-owner", "home", 1)
+
+	var savedMapping domain.IPMapping
+	var upsertedSubdomain string
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: "test-owner", APIKeyHash: apiKeyHash, CreatedAt: time.Now().UTC()}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			return nil, domain.ErrMappingNotFound
+		},
+		listMappingsBySubdomainFunc: func(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+			if subdomain == primary {
+				return []domain.IPMapping{{OwnerID: "other-owner", LocationName: "office", Subdomain: subdomain}}, nil
+			}
+			return nil, nil
+		},
+		putFunc: func(ctx context.Context, mapping domain.IPMapping) error {
+			savedMapping = mapping
+			return nil
+		},
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			upsertedSubdomain = subdomain
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"Authorization":   "Bearer " + apiKey,
+			"X-Forwarded-For": "203.0.113.50",
+		},
+		Body: `{"ownerId":"test-owner","location":"home"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, variant, upsertedSubdomain, "the other owner's record must not be touched")
+	assert.Equal(t, variant, savedMapping.Subdomain)
+	assert.Equal(t, dns.FormatFQDN(variant), resp.Body.Subdomain)
+}
+
+func TestUpdate_SubdomainLookupError(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	apiKeyHash := auth.HashAPIKey(apiKey)
+
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: "test-owner", APIKeyHash: apiKeyHash, CreatedAt: time.Now().UTC()}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			return nil, domain.ErrMappingNotFound
+		},
+		listMappingsBySubdomainFunc: func(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+			return nil, errors.New("dynamodb error")
+		},
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			t.Fatal("DNS should not be updated when the subdomain check fails")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"Authorization":   "Bearer " + apiKey,
+			"X-Forwarded-For": "203.0.113.50",
+		},
+		Body: `{"ownerId":"test-owner","location":"home"}`,
+	}
+
+	_, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusInternalServerError, err.Status)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"strings"
@@ -11,22 +12,28 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/damping"
 	"github.com/grocky/ddns-service/internal/dns"
 	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/email"
 	"github.com/grocky/ddns-service/internal/ratelimit"
 	"github.com/grocky/ddns-service/internal/repository"
 	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/webhook"
 )
 
 // Update handles IP update requests.
 // This is the main endpoint for DDNS clients to poll.
 // IP can be provided by the client or detected from the request context.
-// Rate limited to 2 IP changes per hour.
+// IP changes are rate limited by the owner's rate limit plan, and dampened when
+// the location is flapping and has damping turned on.
 func Update(
 	ctx context.Context,
 	request events.APIGatewayProxyRequest,
 	repo repository.Repository,
 	dnsService dns.Service,
+	emailSvc email.Service,
+	webhookSvc webhook.Service,
 	logger *slog.Logger,
 ) (response.MappingResponse, *response.RequestError) {
 	logger.Info("handler started", "handler", "Update")
@@ -53,11 +60,13 @@ func Update(
 		}
 	}
 
-	// Authenticate - verify API key matches the ownerId in the request
-	_, authErr := auth.Authenticate(ctx, request, req.OwnerID, repo, logger)
+	// Authenticate - verify API key matches the ownerId in the request, or resolve
+	// the owner from the key when ownerId is omitted
+	identity, authErr := auth.AuthenticateOwnerOrKey(ctx, request, req.OwnerID, auth.Permission{Scope: domain.ScopeUpdate, Location: req.Location}, repo, logger)
 	if authErr != nil {
 		return response.MappingResponse{}, authErr
 	}
+	req.OwnerID = identity.Owner.OwnerID
 
 	// Resolve IP: prefer client-provided IP, fall back to server detection
 	var ip string
@@ -99,8 +108,28 @@ func Update(
 
 	logger.Info("client IP resolved", "ip", ip, "clientProvided", clientProvidedIP)
 
+	return updateMapping(ctx, identity.Owner, req.Location, ip, now, repo, dnsService, emailSvc, webhookSvc, logger)
+}
+
+// updateMapping points an owner's location at ip, creating the mapping on first use.
+// IP changes are rate limited by the owner's rate limit plan; an unchanged IP is
+// reported without touching DNS. A change back to a recently published IP is
+// held, and reported as dampened, when the location's damping policy says so.
+func updateMapping(
+	ctx context.Context,
+	owner *domain.Owner,
+	location, ip string,
+	now time.Time,
+	repo repository.Repository,
+	dnsService dns.Service,
+	emailSvc email.Service,
+	webhookSvc webhook.Service,
+	logger *slog.Logger,
+) (response.MappingResponse, *response.RequestError) {
+	ownerID := owner.OwnerID
+
 	// Get existing mapping (may not exist yet)
-	existing, err := repo.Get(ctx, req.OwnerID, req.Location)
+	existing, err := repo.Get(ctx, ownerID, location)
 	if err != nil && !repository.IsMappingNotFound(err) {
 		logger.Error("failed to get mapping", "error", err)
 		return response.MappingResponse{}, &response.RequestError{
@@ -109,26 +138,44 @@ func Update(
 		}
 	}
 
+	// Every call is a sign of life, whether or not the IP changed
+	if existing != nil && existing.LastSeenDue(now) {
+		recordLastSeen(ctx, existing, now, repo, logger)
+	}
+
 	// Check if this is a new mapping or if IP has changed
 	isNew := existing == nil
 	ipChanged := isNew || existing.IP != ip
 
-	// Determine subdomain: use existing custom subdomain or generate hash
+	// Determine subdomain: use existing custom subdomain, or pick a collision-free hash for new mappings
 	var subdomain string
-	if existing != nil && existing.Subdomain != "" {
+	switch {
+	case existing != nil && existing.Subdomain != "":
 		subdomain = existing.Subdomain
-	} else {
-		subdomain = dns.GenerateSubdomain(req.OwnerID, req.Location)
+	case isNew:
+		subdomain, err = resolveSubdomain(ctx, repo, ownerID, location, logger)
+		if err != nil {
+			return response.MappingResponse{}, subdomainError(err, logger)
+		}
+	default:
+		// Mappings from before subdomains were stored keep their original name
+		subdomain = dns.LegacySubdomain(ownerID, location)
 	}
 	fullSubdomain := dns.FormatFQDN(subdomain)
 
 	if !ipChanged {
 		// IP hasn't changed - just return current state
 		logger.Info("IP unchanged, no update needed",
-			"ownerId", req.OwnerID,
-			"location", req.Location,
+			"ownerId", ownerID,
+			"location", location,
 			"ip", ip,
 		)
+		// A settling IP has to be reported consistently
+		if damping.ClearPending(existing) {
+			if err := repo.Put(ctx, *existing); err != nil {
+				logger.Warn("failed to clear pending IP", "error", err, "ownerId", ownerID, "location", location)
+			}
+		}
 		return response.MappingResponse{
 			Status: http.StatusOK,
 			Body: response.MappingBody{
@@ -139,36 +186,66 @@ func Update(
 				Changed:   false,
 				UpdatedAt: existing.UpdatedAt.Format(time.RFC3339),
 			},
+			RateLimit: currentRateLimitStatus(ctx, owner, existing, now, repo, logger),
 		}, nil
 	}
 
-	// IP has changed - check rate limit
+	// IP has changed - hold it back if the location is flapping
+	if result := damping.Check(existing, ip, now); result.Dampened {
+		return dampenedUpdate(ctx, owner, existing, fullSubdomain, ip, result, now, repo, emailSvc, logger)
+	}
+
+	// Check rate limit
+	plan, err := resolveRateLimit(ctx, owner, repo, logger)
+	if err != nil {
+		logger.Error("failed to get rate limit plan", "error", err)
+		return response.MappingResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to get rate limit plan",
+		}
+	}
+
 	var mappingForRateLimit *domain.IPMapping
 	if existing != nil {
 		mappingForRateLimit = existing
 	}
 
-	rateLimitResult := ratelimit.Check(mappingForRateLimit, now)
+	rateLimitResult := ratelimit.Check(mappingForRateLimit, plan.RateLimitPolicy, now)
 	if !rateLimitResult.Allowed {
 		retryAfterSeconds := int(rateLimitResult.RetryAfter.Seconds())
 		logger.Warn("rate limit exceeded",
-			"ownerId", req.OwnerID,
-			"location", req.Location,
+			"ownerId", ownerID,
+			"location", location,
+			"plan", plan.Name,
 			"retryAfter", retryAfterSeconds,
 		)
+		rateLimit := rateLimitBody(plan)
 		return response.MappingResponse{}, &response.RequestError{
-			Status:      http.StatusTooManyRequests,
-			Description: domain.ErrRateLimitExceeded.Error(),
-			RetryAfter:  retryAfterSeconds,
+			Status:          http.StatusTooManyRequests,
+			Description:     domain.ErrRateLimitExceeded.Error() + ": " + plan.String(),
+			RetryAfter:      retryAfterSeconds,
+			RateLimit:       &rateLimit,
+			RateLimitStatus: rateLimitStatus(existing, plan, now),
 		}
 	}
 
-	// Update Route53 DNS record
+	// Update Route53 DNS record, plus the wildcard record when the location has one
+	wildcard := existing != nil && existing.Wildcard
 	if err := dnsService.UpsertRecord(ctx, subdomain, ip); err != nil {
 		logger.Error("failed to update DNS record", "error", err)
-		return response.MappingResponse{}, &response.RequestError{
-			Status:      http.StatusInternalServerError,
-			Description: "failed to update DNS record",
+		return response.MappingResponse{}, dnsError(err, "failed to update DNS record")
+	}
+	if wildcard {
+		if err := dnsService.UpsertRecord(ctx, dns.BuildWildcardName(subdomain), ip); err != nil {
+			logger.Error("failed to update wildcard DNS record", "error", err)
+			return response.MappingResponse{}, dnsError(err, "failed to update DNS record")
+		}
+	}
+
+	// Switching between IPv4 and IPv6 leaves the old record type behind
+	if existing != nil && existing.IP != "" && dns.AddressRecordType(existing.IP) != dns.AddressRecordType(ip) {
+		if err := removeAddressRecords(ctx, dnsService, subdomain, existing.IP, wildcard); err != nil {
+			logger.Warn("failed to remove previous address record", "error", err, "previousIp", existing.IP)
 		}
 	}
 
@@ -178,22 +255,38 @@ func Update(
 		mapping = *existing
 	} else {
 		mapping = domain.IPMapping{
-			OwnerID:      req.OwnerID,
-			LocationName: req.Location,
+			OwnerID:      ownerID,
+			LocationName: location,
 			Subdomain:    subdomain,
+			LastSeenAt:   &now,
 		}
 	}
 
-	// Update IP and timestamps
+	// Update IP and timestamps, remembering the replaced IP for flap detection
 	mapping.IP = ip
 	mapping.UpdatedAt = now
-	// Only set subdomain for new mappings; preserve existing custom subdomains
+	if existing != nil {
+		damping.RecordChange(&mapping, existing.IP, now)
+	}
+
+	// Queue an email if the owner wants to hear about this location's changes
+	notify := existing != nil && owner.Notifications.NotifiesIPChange(location)
+	if notify {
+		mapping.QueueIPChange(domain.IPChange{PreviousIP: existing.IP, IP: ip, ChangedAt: now})
+	}
+	// Only set subdomain for new and legacy mappings; preserve existing custom subdomains
 	if isNew {
 		mapping.Subdomain = subdomain
+	} else if mapping.Subdomain == "" {
+		mapping.Subdomain = subdomain
+		// Legacy subdomains predate claims; the name is already published either way
+		if err := repo.ClaimSubdomain(ctx, subdomain, ownerID, location); err != nil {
+			logger.Warn("failed to claim legacy subdomain", "error", err, "subdomain", subdomain)
+		}
 	}
 
 	// Update rate limit counters
-	ratelimit.UpdateCounters(&mapping, now)
+	ratelimit.UpdateCounters(&mapping, plan.RateLimitPolicy, now)
 
 	// Save to repository
 	if err := repo.Put(ctx, mapping); err != nil {
@@ -212,6 +305,19 @@ func Update(
 		"isNew", isNew,
 	)
 
+	// Changes soon after the last email wait for the next digest
+	if notify && mapping.IPChangeNotificationDue(now) {
+		if err := sendIPChangeNotice(ctx, owner, mapping, now, repo, emailSvc); err != nil {
+			logger.Warn("failed to send IP change notice", "error", err, "ownerId", ownerID, "location", location)
+		}
+	}
+
+	changed := webhook.IPChangedData{Location: location, Subdomain: fullSubdomain, IP: ip}
+	if existing != nil {
+		changed.PreviousIP = existing.IP
+	}
+	dispatchWebhooks(ctx, ownerID, domain.WebhookEventIPChanged, changed, repo, webhookSvc, logger)
+
 	return response.MappingResponse{
 		Status: http.StatusOK,
 		Body: response.MappingBody{
@@ -222,6 +328,79 @@ func Update(
 			Changed:   true,
 			UpdatedAt: mapping.UpdatedAt.Format(time.RFC3339),
 		},
+		RateLimit: rateLimitStatus(&mapping, plan, now),
+	}, nil
+}
+
+// recordLastSeen writes a location's check-in and clears any stale alert. A
+// failure is logged rather than failing the update.
+func recordLastSeen(ctx context.Context, mapping *domain.IPMapping, now time.Time, repo repository.Repository, logger *slog.Logger) {
+	if err := repo.TouchLocation(ctx, mapping.OwnerID, mapping.LocationName, now); err != nil {
+		logger.Warn("failed to record location check-in", "error", err, "ownerId", mapping.OwnerID, "location", mapping.LocationName)
+		return
+	}
+	// Later saves of the mapping keep the check-in
+	mapping.LastSeenAt = &now
+	mapping.StaleAlertedAt = nil
+}
+
+// dampenedUpdate saves a held IP change as pending and reports the published IP
+// as unchanged. The owner is emailed when the location starts being dampened and
+// its policy asks for it.
+func dampenedUpdate(
+	ctx context.Context,
+	owner *domain.Owner,
+	mapping *domain.IPMapping,
+	fullSubdomain, ip string,
+	result damping.Result,
+	now time.Time,
+	repo repository.Repository,
+	emailSvc email.Service,
+	logger *slog.Logger,
+) (response.MappingResponse, *response.RequestError) {
+	if err := repo.Put(ctx, *mapping); err != nil {
+		logger.Error("failed to save mapping", "error", err)
+		return response.MappingResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save mapping",
+		}
+	}
+
+	logger.Warn("IP change dampened",
+		"ownerId", mapping.OwnerID,
+		"location", mapping.LocationName,
+		"publishedIp", mapping.IP,
+		"reportedIp", ip,
+		"mode", mapping.Damping.Mode,
+	)
+
+	if result.Started && mapping.Damping.Notify && owner.Email != "" {
+		alert := email.FlapAlert{
+			Location:    mapping.LocationName,
+			Subdomain:   fullSubdomain,
+			PublishedIP: mapping.IP,
+			ReportedIP:  ip,
+			Mode:        mapping.Damping.Mode,
+			FlapWindow:  mapping.Damping.FlapWindow(),
+			SettleTime:  mapping.Damping.SettleTime(),
+		}
+		if err := emailSvc.SendFlapAlert(ctx, emailRecipient(owner), alert); err != nil {
+			logger.Warn("failed to send flap alert", "error", err, "ownerId", owner.OwnerID, "location", mapping.LocationName)
+		}
+	}
+
+	return response.MappingResponse{
+		Status: http.StatusOK,
+		Body: response.MappingBody{
+			OwnerID:   mapping.OwnerID,
+			Location:  mapping.LocationName,
+			IP:        mapping.IP,
+			Subdomain: fullSubdomain,
+			Dampened:  true,
+			PendingIP: ip,
+			UpdatedAt: mapping.UpdatedAt.Format(time.RFC3339),
+		},
+		RateLimit: currentRateLimitStatus(ctx, owner, mapping, now, repo, logger),
 	}, nil
 }
 
@@ -248,3 +427,75 @@ func extractClientIP(request events.APIGatewayProxyRequest) string {
 
 	return ""
 }
+
+// resolveRateLimit returns the rate limit that applies to an owner: the owner's
+// own policy if an operator set one, else the owner's plan, else the default
+// plan. Owners on a plan that has since been deleted get the default plan.
+func resolveRateLimit(ctx context.Context, owner *domain.Owner, repo repository.Repository, logger *slog.Logger) (domain.RateLimitPlan, error) {
+	if owner.RateLimit != nil {
+		return domain.RateLimitPlan{Name: domain.OwnerRateLimitPlan, RateLimitPolicy: *owner.RateLimit}, nil
+	}
+
+	if owner.RateLimitPlan != "" {
+		plan, err := repo.GetRateLimitPlan(ctx, owner.RateLimitPlan)
+		if err == nil {
+			return *plan, nil
+		}
+		if !repository.IsRateLimitPlanNotFound(err) {
+			return domain.RateLimitPlan{}, err
+		}
+		logger.Warn("rate limit plan not found, using default plan", "ownerId", owner.OwnerID, "plan", owner.RateLimitPlan)
+	}
+
+	plan, err := repo.GetRateLimitPlan(ctx, domain.DefaultRateLimitPlan)
+	if err == nil {
+		return *plan, nil
+	}
+	if !repository.IsRateLimitPlanNotFound(err) {
+		return domain.RateLimitPlan{}, err
+	}
+	return domain.RateLimitPlan{Name: domain.DefaultRateLimitPlan, RateLimitPolicy: domain.DefaultRateLimitPolicy}, nil
+}
+
+// rateLimitStatus reports how many IP changes the mapping has left under plan,
+// for the RateLimit-* response headers. A nil mapping has made no changes.
+func rateLimitStatus(mapping *domain.IPMapping, plan domain.RateLimitPlan, now time.Time) *response.RateLimitStatus {
+	status := ratelimit.CurrentStatus(mapping, plan.RateLimitPolicy, now)
+	return &response.RateLimitStatus{
+		Limit:     status.Limit,
+		Remaining: status.Remaining,
+		Reset:     int(math.Ceil(status.Reset.Seconds())),
+	}
+}
+
+// currentRateLimitStatus is rateLimitStatus under the owner's rate limit. The
+// headers are informational, so it returns nil rather than failing the request
+// when the rate limit plan can't be read.
+func currentRateLimitStatus(
+	ctx context.Context,
+	owner *domain.Owner,
+	mapping *domain.IPMapping,
+	now time.Time,
+	repo repository.Repository,
+	logger *slog.Logger,
+) *response.RateLimitStatus {
+	plan, err := resolveRateLimit(ctx, owner, repo, logger)
+	if err != nil {
+		logger.Warn("failed to get rate limit plan for status", "error", err, "ownerId", owner.OwnerID)
+		return nil
+	}
+	return rateLimitStatus(mapping, plan, now)
+}
+
+func rateLimitBody(plan domain.RateLimitPlan) response.RateLimitBody {
+	body := response.RateLimitBody{
+		Plan:          plan.Name,
+		MaxChanges:    plan.MaxChanges,
+		WindowSeconds: plan.WindowSeconds,
+		Algorithm:     plan.Algorithm,
+	}
+	if !plan.UpdatedAt.IsZero() {
+		body.UpdatedAt = plan.UpdatedAt.Format(time.RFC3339)
+	}
+	return body
+}
@@ -2,24 +2,31 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/dns"
 	"github.com/grocky/ddns-service/internal/domain"
-	"github.com/grocky/ddns-service/internal/ratelimit"
+	"github.com/grocky/ddns-service/internal/email"
+	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/webhook"
 	"gotest.tools/assert"
 )
 
 // mockDNSService is a mock implementation of dns.Service for testing.
 type mockDNSService struct {
 	upsertRecordFunc    func(ctx context.Context, subdomain, ip string) error
-	deleteRecordFunc    func(ctx context.Context, subdomain string) error
+	deleteRecordFunc    func(ctx context.Context, subdomain, ip string) error
 	upsertTXTRecordFunc func(ctx context.Context, name, value string) error
 	deleteTXTRecordFunc func(ctx context.Context, name, value string) error
+	upsertRecordSetFunc func(ctx context.Context, rs dns.RecordSet) error
+	deleteRecordSetFunc func(ctx context.Context, rs dns.RecordSet) error
 }
 
 func (m *mockDNSService) UpsertRecord(ctx context.Context, subdomain, ip string) error {
@@ -29,9 +36,9 @@ func (m *mockDNSService) UpsertRecord(ctx context.Context, subdomain, ip string)
 	return nil
 }
 
-func (m *mockDNSService) DeleteRecord(ctx context.Context, subdomain string) error {
+func (m *mockDNSService) DeleteRecord(ctx context.Context, subdomain, ip string) error {
 	if m.deleteRecordFunc != nil {
-		return m.deleteRecordFunc(ctx, subdomain)
+		return m.deleteRecordFunc(ctx, subdomain, ip)
 	}
 	return nil
 }
@@ -50,6 +57,20 @@ func (m *mockDNSService) DeleteTXTRecord(ctx context.Context, name, value string
 	return nil
 }
 
+func (m *mockDNSService) UpsertRecordSet(ctx context.Context, rs dns.RecordSet) error {
+	if m.upsertRecordSetFunc != nil {
+		return m.upsertRecordSetFunc(ctx, rs)
+	}
+	return nil
+}
+
+func (m *mockDNSService) DeleteRecordSet(ctx context.Context, rs dns.RecordSet) error {
+	if m.deleteRecordSetFunc != nil {
+		return m.deleteRecordSetFunc(ctx, rs)
+	}
+	return nil
+}
+
 func TestUpdate_NewMapping(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -86,7 +107,7 @@ func TestUpdate_NewMapping(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
@@ -98,6 +119,50 @@ func TestUpdate_NewMapping(t *testing.T) {
 	assert.Equal(t, 1, savedMapping.HourlyChangeCount)
 }
 
+func TestUpdate_OwnerResolvedFromKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_0123456789ab.ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	var savedMapping domain.IPMapping
+	repo := &mockRepository{
+		getKeyOwnerIDFunc: func(ctx context.Context, keyID string) (string, error) {
+			assert.Equal(t, "0123456789ab", keyID)
+			return "test-owner", nil
+		},
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{
+				OwnerID:    "test-owner",
+				APIKeyHash: auth.HashAPIKey(apiKey),
+				APIKeyID:   "0123456789ab",
+			}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			return nil, domain.ErrMappingNotFound
+		},
+		putFunc: func(ctx context.Context, mapping domain.IPMapping) error {
+			savedMapping = mapping
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"Authorization":   "Bearer " + apiKey,
+			"X-Forwarded-For": "203.0.113.50",
+		},
+		Body: `{"location":"home"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, "test-owner", resp.Body.OwnerID)
+	assert.Equal(t, "test-owner", savedMapping.OwnerID)
+}
+
 func TestUpdate_IPUnchanged(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -144,12 +209,44 @@ func TestUpdate_IPUnchanged(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
 	assert.Assert(t, !resp.Body.Changed, "should indicate IP not changed")
 	assert.Equal(t, "203.0.113.50", resp.Body.IP)
+	assert.Equal(t, 2, resp.RateLimit.Limit)
+	assert.Equal(t, 2, resp.RateLimit.Remaining, "no changes this hour")
+}
+
+func TestUpdate_IPUnchanged_RateLimitPlanUnavailable(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: "test-owner", APIKeyHash: auth.HashAPIKey(apiKey)}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			return &domain.IPMapping{OwnerID: "test-owner", LocationName: "home", IP: "203.0.113.50", Subdomain: "a3f8c2d1"}, nil
+		},
+		getRateLimitPlanFunc: func(ctx context.Context, name string) (*domain.RateLimitPlan, error) {
+			return nil, errors.New("dynamodb unavailable")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"Authorization":   "Bearer " + apiKey,
+			"X-Forwarded-For": "203.0.113.50",
+		},
+		Body: `{"ownerId":"test-owner","location":"home"}`,
+	}
+
+	resp, err := Update(context.Background(), request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, newTestLogger())
+
+	// Polling still succeeds, without the status headers
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, resp.RateLimit == nil)
 }
 
 func TestUpdate_IPChanged(t *testing.T) {
@@ -204,7 +301,7 @@ func TestUpdate_IPChanged(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
@@ -212,6 +309,71 @@ func TestUpdate_IPChanged(t *testing.T) {
 	assert.Equal(t, "203.0.113.50", resp.Body.IP)
 	assert.Assert(t, dnsUpdated, "DNS should have been updated")
 	assert.Equal(t, "203.0.113.50", savedMapping.IP)
+	assert.Equal(t, 2, resp.RateLimit.Limit)
+	assert.Equal(t, 1, resp.RateLimit.Remaining, "this change counts")
+	assert.Assert(t, resp.RateLimit.Reset > 0 && resp.RateLimit.Reset <= 3600)
+}
+
+func TestUpdate_LegacyMappingKeepsOriginalSubdomain(t *testing.T) {
+	t.Cleanup(func() {
+		assert.NilError(t, dns.ConfigureSubdomains(dns.DefaultSubdomainConfig))
+	})
+	assert.NilError(t, dns.ConfigureSubdomains(dns.SubdomainConfig{Algorithm: dns.HashSHA256, Length: 12}))
+
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	legacy := dns.LegacySubdomain("test-owner", "home")
+
+	var claimed string
+	var savedMapping domain.IPMapping
+	repo := &mockRepository{
+		getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+			return &domain.Owner{OwnerID: "test-owner", APIKeyHash: auth.HashAPIKey(apiKey)}, nil
+		},
+		getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+			return &domain.IPMapping{
+				OwnerID:        "test-owner",
+				LocationName:   "home",
+				IP:             "192.168.1.100",
+				UpdatedAt:      time.Now().UTC(),
+				LastIPChangeAt: time.Now().Add(-2 * time.Hour),
+			}, nil
+		},
+		claimSubdomainFunc: func(ctx context.Context, subdomain, ownerID, location string) error {
+			claimed = subdomain
+			return nil
+		},
+		putFunc: func(ctx context.Context, mapping domain.IPMapping) error {
+			savedMapping = mapping
+			return nil
+		},
+	}
+
+	var published string
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			published = subdomain
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{
+			"Authorization":   "Bearer " + apiKey,
+			"X-Forwarded-For": "203.0.113.50",
+		},
+		Body: `{"ownerId":"test-owner","location":"home"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, dns.FormatFQDN(legacy), resp.Body.Subdomain)
+	assert.Equal(t, legacy, published)
+	assert.Equal(t, legacy, savedMapping.Subdomain)
+	assert.Equal(t, legacy, claimed)
 }
 
 func TestUpdate_RateLimitExceeded(t *testing.T) {
@@ -239,7 +401,7 @@ func TestUpdate_RateLimitExceeded(t *testing.T) {
 				Subdomain:         "a3f8c2d1",
 				UpdatedAt:         now,
 				LastIPChangeAt:    now.Add(-time.Minute), // Changed recently
-				HourlyChangeCount: ratelimit.MaxChangesPerHour,
+				HourlyChangeCount: domain.DefaultRateLimitPolicy.MaxChanges,
 			}, nil
 		},
 	}
@@ -254,15 +416,135 @@ func TestUpdate_RateLimitExceeded(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusTooManyRequests, err.Status)
-	assert.Equal(t, domain.ErrRateLimitExceeded.Error(), err.Description)
+	assert.Equal(t, "rate limit exceeded: maximum 2 IP changes per hour", err.Description)
 	assert.Assert(t, err.RetryAfter > 0, "should have retry after")
+	assert.DeepEqual(t, &response.RateLimitBody{Plan: "default", MaxChanges: 2, WindowSeconds: 3600, Algorithm: "fixed"}, err.RateLimit)
+	assert.Equal(t, 2, err.RateLimitStatus.Limit)
+	assert.Equal(t, 0, err.RateLimitStatus.Remaining)
+	assert.Assert(t, err.RateLimitStatus.Reset >= err.RetryAfter)
 	assert.Equal(t, 0, resp.Status)
 }
 
+func TestUpdate_RateLimitPolicies(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	now := time.Now().UTC()
+
+	plans := map[string]domain.RateLimitPlan{
+		"isp-burst": {Name: "isp-burst", RateLimitPolicy: domain.RateLimitPolicy{MaxChanges: 10, WindowSeconds: 3600, Algorithm: domain.RateLimitFixedWindow}},
+	}
+
+	testCases := []struct {
+		name              string
+		owner             domain.Owner
+		storedDefault     *domain.RateLimitPlan
+		planErr           error
+		expectedStatus    int
+		expectedRateLimit *response.RateLimitBody
+		expectedDesc      string
+	}{
+		{
+			name:           "owner plan allows burst",
+			owner:          domain.Owner{RateLimitPlan: "isp-burst"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "owner policy allows burst",
+			owner:          domain.Owner{RateLimitPlan: "isp-burst", RateLimit: &domain.RateLimitPolicy{MaxChanges: 3, WindowSeconds: 3600, Algorithm: domain.RateLimitSlidingWindow}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "owner policy tightens limit",
+			owner:             domain.Owner{RateLimit: &domain.RateLimitPolicy{MaxChanges: 1, WindowSeconds: 86400, Algorithm: domain.RateLimitSlidingWindow}},
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedRateLimit: &response.RateLimitBody{Plan: "owner", MaxChanges: 1, WindowSeconds: 86400, Algorithm: "sliding"},
+			expectedDesc:      "rate limit exceeded: maximum 1 IP changes per 24 hours",
+		},
+		{
+			name:              "stored default plan",
+			storedDefault:     &domain.RateLimitPlan{Name: "default", RateLimitPolicy: domain.RateLimitPolicy{MaxChanges: 2, WindowSeconds: 1800, Algorithm: domain.RateLimitSlidingWindow}},
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedRateLimit: &response.RateLimitBody{Plan: "default", MaxChanges: 2, WindowSeconds: 1800, Algorithm: "sliding"},
+			expectedDesc:      "rate limit exceeded: maximum 2 IP changes per 30 minutes",
+		},
+		{
+			name:              "deleted plan falls back to default",
+			owner:             domain.Owner{RateLimitPlan: "retired"},
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedRateLimit: &response.RateLimitBody{Plan: "default", MaxChanges: 2, WindowSeconds: 3600, Algorithm: "fixed"},
+			expectedDesc:      "rate limit exceeded: maximum 2 IP changes per hour",
+		},
+		{
+			name:           "plan lookup fails",
+			owner:          domain.Owner{RateLimitPlan: "isp-burst"},
+			planErr:        errors.New("dynamodb unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedDesc:   "failed to get rate limit plan",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			owner := tc.owner
+			owner.OwnerID = "test-owner"
+			owner.APIKeyHash = auth.HashAPIKey(apiKey)
+
+			repo := &mockRepository{
+				getOwnerFunc: func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+					return &owner, nil
+				},
+				getFunc: func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+					// Two changes within the last half hour, both in the current fixed window
+					return &domain.IPMapping{
+						OwnerID:           "test-owner",
+						LocationName:      "home",
+						IP:                "192.168.1.100",
+						Subdomain:         "a3f8c2d1",
+						LastIPChangeAt:    now,
+						HourlyChangeCount: 2,
+						RecentIPChanges:   []time.Time{now.Add(-20 * time.Minute), now.Add(-10 * time.Minute)},
+					}, nil
+				},
+				getRateLimitPlanFunc: func(ctx context.Context, name string) (*domain.RateLimitPlan, error) {
+					if tc.planErr != nil {
+						return nil, tc.planErr
+					}
+					if name == domain.DefaultRateLimitPlan && tc.storedDefault != nil {
+						return tc.storedDefault, nil
+					}
+					if plan, ok := plans[name]; ok {
+						return &plan, nil
+					}
+					return nil, domain.ErrRateLimitPlanNotFound
+				},
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{
+					"Authorization":   "Bearer " + apiKey,
+					"X-Forwarded-For": "203.0.113.50",
+				},
+				Body: `{"ownerId":"test-owner","location":"home"}`,
+			}
+
+			resp, err := Update(context.Background(), request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, newTestLogger())
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.Assert(t, err == nil, "unexpected error: %v", err)
+				assert.Assert(t, resp.Body.Changed)
+				return
+			}
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+			assert.Equal(t, tc.expectedDesc, err.Description)
+			assert.DeepEqual(t, tc.expectedRateLimit, err.RateLimit)
+		})
+	}
+}
+
 func TestUpdate_DNSError(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -298,7 +580,7 @@ func TestUpdate_DNSError(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusInternalServerError, err.Status)
@@ -334,7 +616,7 @@ func TestUpdate_MissingIP(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -354,7 +636,7 @@ func TestUpdate_Unauthorized(t *testing.T) {
 		Body:    `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusUnauthorized, err.Status)
@@ -375,7 +657,7 @@ func TestUpdate_InvalidJSON(t *testing.T) {
 		Body: `{invalid json}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -419,7 +701,7 @@ func TestUpdate_MultipleIPsInForwardedFor(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
@@ -465,7 +747,7 @@ func TestUpdate_ClientProvidedIP(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home","ip":"203.0.113.42"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
@@ -503,7 +785,7 @@ func TestUpdate_ClientProvidedIP_Invalid(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home","ip":"not-an-ip"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err != nil)
 	assert.Equal(t, http.StatusBadRequest, err.Status)
@@ -548,10 +830,570 @@ func TestUpdate_ClientProvidedIPv6(t *testing.T) {
 		Body: `{"ownerId":"test-owner","location":"home","ip":"2001:db8::1"}`,
 	}
 
-	resp, err := Update(ctx, request, repo, dnsSvc, logger)
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
 
 	assert.Assert(t, err == nil)
 	assert.Equal(t, http.StatusOK, resp.Status)
 	assert.Equal(t, "2001:db8::1", savedIP)
 	assert.Equal(t, "2001:db8::1", resp.Body.IP)
 }
+
+func TestUpdate_MaintainsWildcardRecord(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			IP:           "203.0.113.50",
+			Subdomain:    "a3f8c2d1",
+			Wildcard:     true,
+			UpdatedAt:    time.Now().UTC().Add(-2 * time.Hour),
+		}, nil
+	}
+
+	var saved domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = mapping
+		return nil
+	}
+
+	var upserted []string
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			upserted = append(upserted, subdomain+"="+ip)
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, resp.Body.Changed)
+	assert.DeepEqual(t, []string{"a3f8c2d1=198.51.100.7", "*.a3f8c2d1=198.51.100.7"}, upserted)
+	assert.Assert(t, saved.Wildcard, "wildcard setting should be preserved")
+}
+
+func TestUpdate_AddressFamilyChangeRemovesOldRecords(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			IP:           "203.0.113.50",
+			Subdomain:    "a3f8c2d1",
+			Wildcard:     true,
+		}, nil
+	}
+
+	var deleted []string
+	dnsSvc := &mockDNSService{
+		deleteRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			deleted = append(deleted, subdomain+"="+ip)
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"2001:db8::1"}`,
+	}
+
+	_, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.DeepEqual(t, []string{"*.a3f8c2d1=203.0.113.50", "a3f8c2d1=203.0.113.50"}, deleted)
+}
+
+func TestUpdate_DNSUnavailable(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		t.Fatal("mapping should not be saved when DNS is unavailable")
+		return nil
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			return fmt.Errorf("failed to upsert DNS record: %w", &dns.UnavailableError{RetryAfter: 1500 * time.Millisecond})
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	_, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusServiceUnavailable, err.Status)
+	assert.Equal(t, 2, err.RetryAfter)
+}
+
+func TestUpdate_FlappingIsDampened(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			IP:           "203.0.113.50",
+			Subdomain:    "a3f8c2d1",
+			Damping:      &domain.DampingPolicy{Mode: domain.DampingHold, FlapWindowSeconds: 3600, Notify: true},
+			IPHistory:    []domain.PublishedIP{{IP: "198.51.100.7", ReplacedAt: time.Now().UTC().Add(-10 * time.Minute)}},
+		}, nil
+	}
+
+	var saved domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = mapping
+		return nil
+	}
+
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			t.Fatal("a dampened change should not be published")
+			return nil
+		},
+	}
+
+	var alert email.FlapAlert
+	emailSvc := &mockEmailService{
+		sendFlapAlertFunc: func(ctx context.Context, to email.Recipient, a email.FlapAlert) error {
+			assert.Equal(t, "user@example.com", to.Email)
+			alert = a
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, dnsSvc, emailSvc, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, resp.Body.Dampened)
+	assert.Assert(t, !resp.Body.Changed)
+	assert.Equal(t, "203.0.113.50", resp.Body.IP)
+	assert.Equal(t, "198.51.100.7", resp.Body.PendingIP)
+	assert.Equal(t, "198.51.100.7", saved.PendingIP)
+	assert.Equal(t, "203.0.113.50", saved.IP)
+	assert.Equal(t, 0, saved.HourlyChangeCount, "a dampened change doesn't count against the rate limit")
+	assert.Equal(t, "198.51.100.7", alert.ReportedIP)
+	assert.Equal(t, "a3f8c2d1.grocky.net", alert.Subdomain)
+}
+
+func TestUpdate_DampenedWithoutNotify(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{
+			OwnerID:      "test-owner",
+			LocationName: "home",
+			IP:           "203.0.113.50",
+			Subdomain:    "a3f8c2d1",
+			Damping:      &domain.DampingPolicy{Mode: domain.DampingHold, FlapWindowSeconds: 3600},
+			IPHistory:    []domain.PublishedIP{{IP: "198.51.100.7", ReplacedAt: time.Now().UTC().Add(-10 * time.Minute)}},
+		}, nil
+	}
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error { return nil }
+
+	emailSvc := &mockEmailService{
+		sendFlapAlertFunc: func(ctx context.Context, to email.Recipient, alert email.FlapAlert) error {
+			t.Fatal("owner should not be notified")
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, &mockDNSService{}, emailSvc, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, resp.Body.Dampened)
+}
+
+func TestUpdate_SettledIPIsPublished(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{
+			OwnerID:        "test-owner",
+			LocationName:   "home",
+			IP:             "203.0.113.50",
+			Subdomain:      "a3f8c2d1",
+			Damping:        &domain.DampingPolicy{Mode: domain.DampingSettle, FlapWindowSeconds: 3600, SettleSeconds: 600},
+			IPHistory:      []domain.PublishedIP{{IP: "198.51.100.7", ReplacedAt: time.Now().UTC().Add(-20 * time.Minute)}},
+			PendingIP:      "198.51.100.7",
+			PendingIPSince: time.Now().UTC().Add(-11 * time.Minute),
+		}, nil
+	}
+
+	var saved domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = mapping
+		return nil
+	}
+
+	var upserted []string
+	dnsSvc := &mockDNSService{
+		upsertRecordFunc: func(ctx context.Context, subdomain, ip string) error {
+			upserted = append(upserted, subdomain+"="+ip)
+			return nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, dnsSvc, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, resp.Body.Changed)
+	assert.Assert(t, !resp.Body.Dampened)
+	assert.DeepEqual(t, []string{"a3f8c2d1=198.51.100.7"}, upserted)
+	assert.Equal(t, "", saved.PendingIP)
+	assert.Equal(t, 1, len(saved.IPHistory))
+	assert.Equal(t, "203.0.113.50", saved.IPHistory[0].IP)
+}
+
+func TestUpdate_UnchangedIPClearsPending(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+		return &domain.IPMapping{
+			OwnerID:        "test-owner",
+			LocationName:   "home",
+			IP:             "203.0.113.50",
+			Subdomain:      "a3f8c2d1",
+			Damping:        &domain.DampingPolicy{Mode: domain.DampingSettle, FlapWindowSeconds: 3600, SettleSeconds: 600},
+			PendingIP:      "198.51.100.7",
+			PendingIPSince: time.Now().UTC().Add(-5 * time.Minute),
+		}, nil
+	}
+
+	var saved *domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = &mapping
+		return nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"203.0.113.50"}`,
+	}
+
+	resp, err := Update(ctx, request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, logger)
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, !resp.Body.Changed)
+	assert.Assert(t, saved != nil, "pending IP should be cleared")
+	assert.Equal(t, "", saved.PendingIP)
+}
+
+func TestUpdate_IPChangeNotification(t *testing.T) {
+	recentlyNotified := time.Now().UTC().Add(-10 * time.Minute)
+	earlier := domain.IPChange{PreviousIP: "192.0.2.1", IP: "203.0.113.50", ChangedAt: recentlyNotified}
+
+	testCases := []struct {
+		name          string
+		notifications *domain.NotificationPreferences
+		notifiedAt    *time.Time
+		pending       []domain.IPChange
+		expectedSent  int
+		// expectedPending is the number of changes saved with the mapping,
+		// which are cleared once they have been sent
+		expectedPending int
+	}{
+		{
+			name:          "not opted in",
+			notifications: nil,
+		},
+		{
+			name:          "other location",
+			notifications: &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{Locations: []string{"office"}}},
+		},
+		{
+			name:            "sent immediately",
+			notifications:   &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{}},
+			expectedSent:    1,
+			expectedPending: 1,
+		},
+		{
+			name:            "sent with earlier changes",
+			notifications:   &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{Locations: []string{"home"}}},
+			pending:         []domain.IPChange{earlier},
+			expectedSent:    2,
+			expectedPending: 2,
+		},
+		{
+			name:            "queued for the digest",
+			notifications:   &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{}},
+			notifiedAt:      &recentlyNotified,
+			pending:         []domain.IPChange{earlier},
+			expectedPending: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+			repo := newRecordSetTestRepo(apiKey)
+			repo.getOwnerFunc = func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+				return &domain.Owner{
+					OwnerID:       "test-owner",
+					Email:         "user@example.com",
+					APIKeyHash:    auth.HashAPIKey(apiKey),
+					Notifications: tc.notifications,
+				}, nil
+			}
+			repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+				return &domain.IPMapping{
+					OwnerID:            "test-owner",
+					LocationName:       "home",
+					IP:                 "203.0.113.50",
+					Subdomain:          "a3f8c2d1",
+					PendingIPChanges:   tc.pending,
+					IPChangeNotifiedAt: tc.notifiedAt,
+				}, nil
+			}
+
+			var saved domain.IPMapping
+			repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+				saved = mapping
+				return nil
+			}
+			marked := false
+			repo.markIPChangesNotifiedFunc = func(ctx context.Context, ownerID, location string, notifiedAt time.Time) error {
+				assert.Equal(t, "home", location)
+				marked = true
+				return nil
+			}
+
+			var notice email.IPChangeNotice
+			emailSvc := &mockEmailService{
+				sendIPChangeNoticeFunc: func(ctx context.Context, to email.Recipient, n email.IPChangeNotice) error {
+					assert.Equal(t, "user@example.com", to.Email)
+					notice = n
+					return nil
+				},
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+			}
+
+			resp, err := Update(context.Background(), request, repo, &mockDNSService{}, emailSvc, &mockWebhookService{}, newTestLogger())
+
+			assert.Assert(t, err == nil)
+			assert.Assert(t, resp.Body.Changed)
+			assert.Equal(t, tc.expectedPending, len(saved.PendingIPChanges))
+			assert.Equal(t, tc.expectedSent, len(notice.Changes))
+			assert.Equal(t, tc.expectedSent > 0, marked)
+			if tc.expectedSent > 0 {
+				assert.Equal(t, "a3f8c2d1.grocky.net", notice.Subdomain)
+				latest := notice.Changes[len(notice.Changes)-1]
+				assert.Equal(t, "203.0.113.50", latest.PreviousIP)
+				assert.Equal(t, "198.51.100.7", latest.IP)
+			}
+		})
+	}
+}
+
+func TestUpdate_IPChangeNotificationFailureIsNotFatal(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getOwnerFunc = func(ctx context.Context, ownerID string) (*domain.Owner, error) {
+		return &domain.Owner{
+			OwnerID:       "test-owner",
+			Email:         "user@example.com",
+			APIKeyHash:    auth.HashAPIKey(apiKey),
+			Notifications: &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{}},
+		}, nil
+	}
+	repo.markIPChangesNotifiedFunc = func(ctx context.Context, ownerID, location string, notifiedAt time.Time) error {
+		t.Fatal("changes should stay pending when the email fails")
+		return nil
+	}
+
+	emailSvc := &mockEmailService{
+		sendIPChangeNoticeFunc: func(ctx context.Context, to email.Recipient, n email.IPChangeNotice) error {
+			return errors.New("SES error")
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(context.Background(), request, repo, &mockDNSService{}, emailSvc, &mockWebhookService{}, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Assert(t, resp.Body.Changed)
+}
+
+func TestUpdate_DispatchesIPChangedWebhook(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.listWebhooksFunc = func(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+		return []domain.Webhook{{OwnerID: ownerID, WebhookID: "0123456789ab", URL: "https://hooks.example.com", Events: []string{domain.WebhookEventIPChanged}}}, nil
+	}
+
+	var saved domain.WebhookDelivery
+	repo.putWebhookDeliveryFunc = func(ctx context.Context, delivery domain.WebhookDelivery) error {
+		saved = delivery
+		return nil
+	}
+
+	var sent webhook.Delivery
+	webhookSvc := &mockWebhookService{
+		sendFunc: func(ctx context.Context, delivery webhook.Delivery) (int, error) {
+			sent = delivery
+			return http.StatusOK, nil
+		},
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(context.Background(), request, repo, &mockDNSService{}, &mockEmailService{}, webhookSvc, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, resp.Body.Changed)
+	assert.Equal(t, domain.WebhookEventIPChanged, sent.Event)
+	assert.Equal(t, domain.WebhookDeliverySucceeded, saved.Status)
+
+	var payload webhook.Payload
+	payload.Data = &webhook.IPChangedData{}
+	assert.NilError(t, json.Unmarshal(sent.Body, &payload))
+	assert.DeepEqual(t, &webhook.IPChangedData{
+		Location:   "home",
+		Subdomain:  "a3f8c2d1.grocky.net",
+		PreviousIP: "203.0.113.50",
+		IP:         "198.51.100.7",
+	}, payload.Data)
+}
+
+func TestUpdate_RecordsLastSeen(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	now := time.Now().UTC()
+	recently := now.Add(-10 * time.Second)
+	longAgo := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name           string
+		lastSeenAt     *time.Time
+		staleAlertedAt *time.Time
+		expectTouch    bool
+	}{
+		{name: "never seen", expectTouch: true},
+		{name: "seen long ago", lastSeenAt: &longAgo, expectTouch: true},
+		{name: "seen moments ago", lastSeenAt: &recently},
+		{name: "stale alert outstanding", lastSeenAt: &recently, staleAlertedAt: &longAgo, expectTouch: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.getFunc = func(ctx context.Context, ownerID, location string) (*domain.IPMapping, error) {
+				return &domain.IPMapping{
+					OwnerID:        "test-owner",
+					LocationName:   "home",
+					IP:             "203.0.113.50",
+					Subdomain:      "a3f8c2d1",
+					LastSeenAt:     tc.lastSeenAt,
+					StaleAlertedAt: tc.staleAlertedAt,
+				}, nil
+			}
+			repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+				t.Fatal("an unchanged IP should not rewrite the mapping")
+				return nil
+			}
+
+			touched := false
+			repo.touchLocationFunc = func(ctx context.Context, ownerID, location string, seenAt time.Time) error {
+				assert.Equal(t, "test-owner", ownerID)
+				assert.Equal(t, "home", location)
+				touched = true
+				return nil
+			}
+
+			request := events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+				Body:    `{"ownerId":"test-owner","location":"home","ip":"203.0.113.50"}`,
+			}
+
+			resp, err := Update(context.Background(), request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, newTestLogger())
+
+			assert.Assert(t, err == nil)
+			assert.Assert(t, !resp.Body.Changed)
+			assert.Equal(t, tc.expectTouch, touched)
+		})
+	}
+}
+
+func TestUpdate_LastSeenFailureIsNotFatal(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.touchLocationFunc = func(ctx context.Context, ownerID, location string, seenAt time.Time) error {
+		return errors.New("throttled")
+	}
+
+	var saved domain.IPMapping
+	repo.putFunc = func(ctx context.Context, mapping domain.IPMapping) error {
+		saved = mapping
+		return nil
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    `{"ownerId":"test-owner","location":"home","ip":"198.51.100.7"}`,
+	}
+
+	resp, err := Update(context.Background(), request, repo, &mockDNSService{}, &mockEmailService{}, &mockWebhookService{}, newTestLogger())
+
+	assert.Assert(t, err == nil)
+	assert.Assert(t, resp.Body.Changed)
+	assert.Equal(t, "198.51.100.7", saved.IP)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/webhook"
+)
+
+// WebhookRetryResult represents the result of the webhook retry run.
+type WebhookRetryResult struct {
+	Processed    int `json:"processed"`
+	Delivered    int `json:"delivered"`
+	Rescheduled  int `json:"rescheduled"`
+	DeadLettered int `json:"deadLettered"`
+	Errors       int `json:"errors"`
+}
+
+// RetryWebhookDeliveries attempts the failed webhook deliveries whose retry is
+// due. Called by EventBridge every few minutes. Deliveries that fail their last
+// attempt, or whose webhook was deleted, are dead-lettered.
+func RetryWebhookDeliveries(
+	ctx context.Context,
+	repo repository.Repository,
+	webhookSvc webhook.Service,
+	logger *slog.Logger,
+) (*WebhookRetryResult, error) {
+	logger.Info("starting webhook delivery retries")
+
+	result := &WebhookRetryResult{}
+	now := time.Now().UTC()
+
+	deliveries, err := repo.ScanPendingWebhookDeliveries(ctx)
+	if err != nil {
+		logger.Error("failed to scan pending webhook deliveries", "error", err)
+		return nil, err
+	}
+
+	result.Processed = len(deliveries)
+	logger.Info("found pending webhook deliveries", "count", len(deliveries))
+
+	for _, delivery := range deliveries {
+		if !delivery.RetryDue(now) {
+			continue
+		}
+
+		hook, err := repo.GetWebhook(ctx, delivery.OwnerID, delivery.WebhookID)
+		switch {
+		case repository.IsWebhookNotFound(err):
+			delivery.DeadLetter("webhook deleted")
+		case err != nil:
+			logger.Error("failed to get webhook", "error", err, "webhookId", delivery.WebhookID)
+			result.Errors++
+			continue
+		default:
+			attemptDelivery(ctx, *hook, &delivery, webhookSvc)
+		}
+
+		if err := repo.PutWebhookDelivery(ctx, delivery); err != nil {
+			logger.Error("failed to save webhook delivery", "error", err, "webhookId", delivery.WebhookID, "deliveryId", delivery.DeliveryID)
+			result.Errors++
+			continue
+		}
+
+		switch delivery.Status {
+		case domain.WebhookDeliverySucceeded:
+			result.Delivered++
+		case domain.WebhookDeliveryDeadLetter:
+			result.DeadLettered++
+			logger.Warn("webhook delivery dead-lettered",
+				"ownerId", delivery.OwnerID,
+				"webhookId", delivery.WebhookID,
+				"deliveryId", delivery.DeliveryID,
+				"attempts", delivery.Attempts,
+				"lastError", delivery.LastError,
+			)
+		default:
+			result.Rescheduled++
+		}
+	}
+
+	logger.Info("webhook delivery retries completed",
+		"processed", result.Processed,
+		"delivered", result.Delivered,
+		"rescheduled", result.Rescheduled,
+		"deadLettered", result.DeadLettered,
+		"errors", result.Errors,
+	)
+
+	return result, nil
+}
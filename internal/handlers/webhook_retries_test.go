@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/webhook"
+	"gotest.tools/assert"
+)
+
+func TestRetryWebhookDeliveries(t *testing.T) {
+	now := time.Now().UTC()
+	due := now.Add(-time.Minute)
+	notDue := now.Add(time.Hour)
+
+	pending := func(deliveryID, webhookID string, attempts int, next time.Time) domain.WebhookDelivery {
+		return domain.WebhookDelivery{
+			WebhookID:     webhookID,
+			DeliveryID:    deliveryID,
+			OwnerID:       "test-owner",
+			Event:         domain.WebhookEventIPChanged,
+			Payload:       `{"event":"ip.changed"}`,
+			Status:        domain.WebhookDeliveryPending,
+			Attempts:      attempts,
+			NextAttemptAt: &next,
+		}
+	}
+
+	repo := &mockRepository{
+		scanPendingWebhookDeliveriesFunc: func(ctx context.Context) ([]domain.WebhookDelivery, error) {
+			return []domain.WebhookDelivery{
+				pending("delivered", "ok", 1, due),
+				pending("rescheduled", "failing", 1, due),
+				pending("exhausted", "failing", domain.MaxWebhookAttempts-1, due),
+				pending("orphaned", "deleted", 2, due),
+				pending("waiting", "ok", 1, notDue),
+			}, nil
+		},
+		getWebhookFunc: func(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error) {
+			if webhookID == "deleted" {
+				return nil, domain.ErrWebhookNotFound
+			}
+			return &domain.Webhook{OwnerID: ownerID, WebhookID: webhookID, URL: "https://" + webhookID + ".example.com", Secret: "whsec_" + webhookID}, nil
+		},
+	}
+
+	saved := make(map[string]domain.WebhookDelivery)
+	repo.putWebhookDeliveryFunc = func(ctx context.Context, delivery domain.WebhookDelivery) error {
+		saved[delivery.DeliveryID] = delivery
+		return nil
+	}
+
+	webhookSvc := &mockWebhookService{
+		sendFunc: func(ctx context.Context, delivery webhook.Delivery) (int, error) {
+			assert.Assert(t, delivery.DeliveryID != "waiting", "delivery should not be retried before it is due")
+			if delivery.URL == "https://failing.example.com" {
+				return http.StatusServiceUnavailable, errors.New("unexpected status 503")
+			}
+			return http.StatusOK, nil
+		},
+	}
+
+	result, err := RetryWebhookDeliveries(context.Background(), repo, webhookSvc, newTestLogger())
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &WebhookRetryResult{Processed: 5, Delivered: 1, Rescheduled: 1, DeadLettered: 2}, result)
+
+	assert.Equal(t, domain.WebhookDeliverySucceeded, saved["delivered"].Status)
+	assert.Equal(t, 2, saved["delivered"].Attempts)
+
+	assert.Equal(t, domain.WebhookDeliveryPending, saved["rescheduled"].Status)
+	assert.Assert(t, saved["rescheduled"].NextAttemptAt.After(now))
+
+	assert.Equal(t, domain.WebhookDeliveryDeadLetter, saved["exhausted"].Status)
+	assert.Equal(t, domain.MaxWebhookAttempts, saved["exhausted"].Attempts)
+
+	assert.Equal(t, domain.WebhookDeliveryDeadLetter, saved["orphaned"].Status)
+	assert.Equal(t, "webhook deleted", saved["orphaned"].LastError)
+	assert.Equal(t, 2, saved["orphaned"].Attempts)
+
+	_, ok := saved["waiting"]
+	assert.Assert(t, !ok)
+}
+
+func TestRetryWebhookDeliveries_Errors(t *testing.T) {
+	t.Run("scan error", func(t *testing.T) {
+		repo := &mockRepository{
+			scanPendingWebhookDeliveriesFunc: func(ctx context.Context) ([]domain.WebhookDelivery, error) {
+				return nil, errors.New("scan failed")
+			},
+		}
+
+		_, err := RetryWebhookDeliveries(context.Background(), repo, &mockWebhookService{}, newTestLogger())
+
+		assert.ErrorContains(t, err, "scan failed")
+	})
+
+	t.Run("webhook lookup error", func(t *testing.T) {
+		next := time.Now().UTC().Add(-time.Minute)
+		repo := &mockRepository{
+			scanPendingWebhookDeliveriesFunc: func(ctx context.Context) ([]domain.WebhookDelivery, error) {
+				return []domain.WebhookDelivery{{WebhookID: "ok", DeliveryID: "d1", Status: domain.WebhookDeliveryPending, NextAttemptAt: &next}}, nil
+			},
+			getWebhookFunc: func(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error) {
+				return nil, errors.New("throttled")
+			},
+			putWebhookDeliveryFunc: func(ctx context.Context, delivery domain.WebhookDelivery) error {
+				t.Fatal("delivery should not be saved")
+				return nil
+			},
+		}
+
+		result, err := RetryWebhookDeliveries(context.Background(), repo, &mockWebhookService{}, newTestLogger())
+
+		assert.NilError(t, err)
+		assert.Equal(t, 1, result.Errors)
+	})
+}
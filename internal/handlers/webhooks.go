@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/auth"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/repository"
+	"github.com/grocky/ddns-service/internal/response"
+	"github.com/grocky/ddns-service/internal/webhook"
+)
+
+// ListWebhooks handles GET /owners/{ownerId}/webhooks requests.
+// Secrets are not included.
+func ListWebhooks(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.WebhookListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "ListWebhooks", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "ListWebhooks")
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.WebhookListResponse{}, authErr
+	}
+
+	webhooks, err := repo.ListWebhooks(ctx, ownerID)
+	if err != nil {
+		logger.Error("failed to list webhooks", "error", err)
+		return response.WebhookListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list webhooks",
+		}
+	}
+
+	bodies := make([]response.WebhookBody, 0, len(webhooks))
+	for _, hook := range webhooks {
+		bodies = append(bodies, webhookBody(hook))
+	}
+
+	return response.WebhookListResponse{
+		Status: http.StatusOK,
+		Body: response.WebhookListBody{
+			OwnerID:  ownerID,
+			Webhooks: bodies,
+		},
+	}, nil
+}
+
+// CreateWebhook handles POST /owners/{ownerId}/webhooks requests.
+// The signing secret is only returned in this response.
+func CreateWebhook(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.WebhookResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "CreateWebhook", "ownerId", ownerID)
+	defer logger.Info("handler completed", "handler", "CreateWebhook")
+
+	var req domain.CreateWebhookRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		logger.Warn("invalid request body", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: "invalid request body",
+		}
+	}
+
+	req = req.Normalize()
+	if err := req.Validate(); err != nil {
+		logger.Warn("validation failed", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusBadRequest,
+			Description: err.Error(),
+		}
+	}
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.WebhookResponse{}, authErr
+	}
+
+	existing, err := repo.ListWebhooks(ctx, ownerID)
+	if err != nil {
+		logger.Error("failed to list webhooks", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to create webhook",
+		}
+	}
+	if len(existing) >= domain.MaxWebhooks {
+		logger.Warn("webhook quota exceeded", "ownerId", ownerID, "count", len(existing))
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusForbidden,
+			Description: fmt.Sprintf("%s: maximum %d webhooks", domain.ErrWebhookQuotaExceeded, domain.MaxWebhooks),
+		}
+	}
+
+	webhookID, err := webhook.GenerateID()
+	if err != nil {
+		logger.Error("failed to generate webhook ID", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to create webhook",
+		}
+	}
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		logger.Error("failed to generate webhook secret", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to create webhook",
+		}
+	}
+
+	hook := domain.Webhook{
+		OwnerID:   ownerID,
+		WebhookID: webhookID,
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := repo.CreateWebhook(ctx, hook); err != nil {
+		logger.Error("failed to save webhook", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to save webhook",
+		}
+	}
+
+	logger.Info("webhook created", "ownerId", ownerID, "webhookId", webhookID, "events", hook.Events)
+
+	body := webhookBody(hook)
+	body.Secret = secret
+	return response.WebhookResponse{
+		Status: http.StatusCreated,
+		Body:   body,
+	}, nil
+}
+
+// DeleteWebhook handles DELETE /owners/{ownerId}/webhooks/{webhookId} requests.
+// Deliveries still waiting to be retried are dead-lettered by the next retry run.
+func DeleteWebhook(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	webhookID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.WebhookResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "DeleteWebhook", "ownerId", ownerID, "webhookId", webhookID)
+	defer logger.Info("handler completed", "handler", "DeleteWebhook")
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.WebhookResponse{}, authErr
+	}
+
+	if err := repo.DeleteWebhook(ctx, ownerID, webhookID); err != nil {
+		if repository.IsWebhookNotFound(err) {
+			logger.Warn("webhook not found", "ownerId", ownerID, "webhookId", webhookID)
+			return response.WebhookResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: domain.ErrWebhookNotFound.Error(),
+			}
+		}
+		logger.Error("failed to delete webhook", "error", err)
+		return response.WebhookResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to delete webhook",
+		}
+	}
+
+	logger.Info("webhook deleted", "ownerId", ownerID, "webhookId", webhookID)
+
+	return response.WebhookResponse{
+		Status: http.StatusOK,
+		Body: response.WebhookBody{
+			OwnerID:   ownerID,
+			WebhookID: webhookID,
+			Deleted:   true,
+		},
+	}, nil
+}
+
+// ListWebhookDeliveries handles GET /owners/{ownerId}/webhooks/{webhookId}/deliveries
+// requests. It returns the webhook's latest deliveries, newest first, including
+// dead-lettered ones, with their payloads.
+func ListWebhookDeliveries(
+	ctx context.Context,
+	request events.APIGatewayProxyRequest,
+	ownerID string,
+	webhookID string,
+	repo repository.Repository,
+	logger *slog.Logger,
+) (response.WebhookDeliveryListResponse, *response.RequestError) {
+	logger.Info("handler started", "handler", "ListWebhookDeliveries", "ownerId", ownerID, "webhookId", webhookID)
+	defer logger.Info("handler completed", "handler", "ListWebhookDeliveries")
+
+	_, authErr := auth.Authenticate(ctx, request, ownerID, auth.Permission{Scope: domain.ScopeManage}, repo, logger)
+	if authErr != nil {
+		return response.WebhookDeliveryListResponse{}, authErr
+	}
+
+	// Deliveries are stored by webhook, so check that the webhook is the owner's
+	if _, err := repo.GetWebhook(ctx, ownerID, webhookID); err != nil {
+		if repository.IsWebhookNotFound(err) {
+			return response.WebhookDeliveryListResponse{}, &response.RequestError{
+				Status:      http.StatusNotFound,
+				Description: domain.ErrWebhookNotFound.Error(),
+			}
+		}
+		logger.Error("failed to get webhook", "error", err)
+		return response.WebhookDeliveryListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list webhook deliveries",
+		}
+	}
+
+	deliveries, err := repo.ListWebhookDeliveries(ctx, webhookID, domain.MaxWebhookDeliveryHistory)
+	if err != nil {
+		logger.Error("failed to list webhook deliveries", "error", err)
+		return response.WebhookDeliveryListResponse{}, &response.RequestError{
+			Status:      http.StatusInternalServerError,
+			Description: "failed to list webhook deliveries",
+		}
+	}
+
+	bodies := make([]response.WebhookDeliveryBody, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		bodies = append(bodies, webhookDeliveryBody(delivery))
+	}
+
+	return response.WebhookDeliveryListResponse{
+		Status: http.StatusOK,
+		Body: response.WebhookDeliveryListBody{
+			OwnerID:    ownerID,
+			WebhookID:  webhookID,
+			Deliveries: bodies,
+		},
+	}, nil
+}
+
+// dispatchWebhooks sends an event to each of the owner's webhooks that
+// subscribes to it. Deliveries are attempted concurrently, and failed ones are
+// left pending for RetryWebhookDeliveries. Errors are logged, never returned:
+// the event has already happened.
+func dispatchWebhooks(
+	ctx context.Context,
+	ownerID string,
+	event string,
+	data any,
+	repo repository.Repository,
+	webhookSvc webhook.Service,
+	logger *slog.Logger,
+) {
+	webhooks, err := repo.ListWebhooks(ctx, ownerID)
+	if err != nil {
+		logger.Warn("failed to list webhooks", "error", err, "ownerId", ownerID, "event", event)
+		return
+	}
+
+	now := time.Now().UTC()
+	var wg sync.WaitGroup
+	for _, hook := range webhooks {
+		if !hook.Subscribes(event) {
+			continue
+		}
+
+		deliveryID, err := webhook.NewDeliveryID(now)
+		if err != nil {
+			logger.Warn("failed to generate delivery ID", "error", err, "webhookId", hook.WebhookID)
+			continue
+		}
+		payload, err := json.Marshal(webhook.Payload{
+			DeliveryID: deliveryID,
+			Event:      event,
+			OwnerID:    ownerID,
+			CreatedAt:  now,
+			Data:       data,
+		})
+		if err != nil {
+			logger.Warn("failed to marshal webhook payload", "error", err, "event", event)
+			return
+		}
+		delivery := domain.NewWebhookDelivery(hook, deliveryID, event, string(payload), now)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attemptDelivery(ctx, hook, &delivery, webhookSvc)
+			if err := repo.PutWebhookDelivery(ctx, delivery); err != nil {
+				logger.Warn("failed to save webhook delivery", "error", err, "webhookId", hook.WebhookID, "deliveryId", deliveryID)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// attemptDelivery sends a delivery to its webhook and records the outcome.
+func attemptDelivery(ctx context.Context, hook domain.Webhook, delivery *domain.WebhookDelivery, webhookSvc webhook.Service) {
+	ctx, cancel := context.WithTimeout(ctx, webhook.DefaultTimeout)
+	defer cancel()
+
+	status, err := webhookSvc.Send(ctx, webhook.Delivery{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		Event:      delivery.Event,
+		DeliveryID: delivery.DeliveryID,
+		Body:       []byte(delivery.Payload),
+	})
+	delivery.RecordAttempt(time.Now().UTC(), status, err)
+}
+
+func webhookBody(hook domain.Webhook) response.WebhookBody {
+	body := response.WebhookBody{
+		OwnerID:   hook.OwnerID,
+		WebhookID: hook.WebhookID,
+		URL:       hook.URL,
+		Events:    hook.Events,
+	}
+	if !hook.CreatedAt.IsZero() {
+		body.CreatedAt = hook.CreatedAt.Format(time.RFC3339)
+	}
+	return body
+}
+
+func webhookDeliveryBody(delivery domain.WebhookDelivery) response.WebhookDeliveryBody {
+	body := response.WebhookDeliveryBody{
+		DeliveryID:     delivery.DeliveryID,
+		Event:          delivery.Event,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		Payload:        json.RawMessage(delivery.Payload),
+	}
+	if delivery.LastAttemptAt != nil {
+		body.LastAttemptAt = delivery.LastAttemptAt.Format(time.RFC3339)
+	}
+	if delivery.NextAttemptAt != nil {
+		body.NextAttemptAt = delivery.NextAttemptAt.Format(time.RFC3339)
+	}
+	if !json.Valid(body.Payload) {
+		body.Payload = json.RawMessage("null")
+	}
+	return body
+}
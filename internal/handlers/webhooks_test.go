@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grocky/ddns-service/internal/domain"
+	"github.com/grocky/ddns-service/internal/webhook"
+	"gotest.tools/assert"
+)
+
+// mockWebhookService is a mock implementation of webhook.Service for testing.
+type mockWebhookService struct {
+	sendFunc func(ctx context.Context, delivery webhook.Delivery) (int, error)
+}
+
+func (m *mockWebhookService) Send(ctx context.Context, delivery webhook.Delivery) (int, error) {
+	if m.sendFunc != nil {
+		return m.sendFunc(ctx, delivery)
+	}
+	return http.StatusOK, nil
+}
+
+func webhookRequest(apiKey, body string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+		Body:    body,
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+
+	var created domain.Webhook
+	repo.createWebhookFunc = func(ctx context.Context, hook domain.Webhook) error {
+		created = hook
+		return nil
+	}
+
+	body := `{"url":"https://hooks.example.com/ddns","events":["ip.changed","acme.created"]}`
+	resp, err := CreateWebhook(context.Background(), webhookRequest(apiKey, body), "test-owner", repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, "test-owner", created.OwnerID)
+	assert.Equal(t, "https://hooks.example.com/ddns", created.URL)
+	assert.DeepEqual(t, []string{domain.WebhookEventIPChanged, domain.WebhookEventACMECreated}, created.Events)
+	assert.Assert(t, strings.HasPrefix(created.Secret, webhook.SecretPrefix))
+	assert.Equal(t, created.WebhookID, resp.Body.WebhookID)
+	assert.Equal(t, created.Secret, resp.Body.Secret, "secret should be returned on creation")
+}
+
+func TestCreateWebhook_Errors(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	valid := `{"url":"https://hooks.example.com/ddns","events":["ip.changed"]}`
+
+	testCases := []struct {
+		name           string
+		apiKey         string
+		body           string
+		existing       int
+		expectedStatus int
+	}{
+		{name: "invalid JSON", apiKey: apiKey, body: `{"url":`, expectedStatus: http.StatusBadRequest},
+		{name: "plain HTTP", apiKey: apiKey, body: `{"url":"http://hooks.example.com","events":["ip.changed"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown event", apiKey: apiKey, body: `{"url":"https://hooks.example.com","events":["ip.lost"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "quota exceeded", apiKey: apiKey, body: valid, existing: domain.MaxWebhooks, expectedStatus: http.StatusForbidden},
+		{name: "wrong key", apiKey: "ddns_sk_ZYXWVUTSRQPONMLKJIHGFEDCBAzyxwvutsrqponmlk", body: valid, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.listWebhooksFunc = func(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+				return make([]domain.Webhook, tc.existing), nil
+			}
+			repo.createWebhookFunc = func(ctx context.Context, hook domain.Webhook) error {
+				t.Fatal("webhook should not be created")
+				return nil
+			}
+
+			_, err := CreateWebhook(context.Background(), webhookRequest(tc.apiKey, tc.body), "test-owner", repo, newTestLogger())
+
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestListWebhooks_OmitsSecrets(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.listWebhooksFunc = func(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+		return []domain.Webhook{{
+			OwnerID:   ownerID,
+			WebhookID: "0123456789ab",
+			URL:       "https://hooks.example.com/ddns",
+			Events:    []string{domain.WebhookEventIPChanged},
+			Secret:    "whsec_secret",
+		}}, nil
+	}
+
+	resp, err := ListWebhooks(context.Background(), webhookRequest(apiKey, ""), "test-owner", repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, 1, len(resp.Body.Webhooks))
+	assert.Equal(t, "0123456789ab", resp.Body.Webhooks[0].WebhookID)
+	assert.Equal(t, "", resp.Body.Webhooks[0].Secret)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+
+	testCases := []struct {
+		name           string
+		deleteErr      error
+		expectedStatus int
+	}{
+		{name: "deleted", expectedStatus: http.StatusOK},
+		{name: "not found", deleteErr: domain.ErrWebhookNotFound, expectedStatus: http.StatusNotFound},
+		{name: "repository error", deleteErr: errors.New("boom"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRecordSetTestRepo(apiKey)
+			repo.deleteWebhookFunc = func(ctx context.Context, ownerID, webhookID string) error {
+				assert.Equal(t, "test-owner", ownerID)
+				assert.Equal(t, "0123456789ab", webhookID)
+				return tc.deleteErr
+			}
+
+			resp, err := DeleteWebhook(context.Background(), webhookRequest(apiKey, ""), "test-owner", "0123456789ab", repo, newTestLogger())
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.Assert(t, err == nil, "expected no error, got %v", err)
+				assert.Assert(t, resp.Body.Deleted)
+				return
+			}
+			assert.Assert(t, err != nil)
+			assert.Equal(t, tc.expectedStatus, err.Status)
+		})
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	next := now.Add(5 * time.Minute)
+
+	repo := newRecordSetTestRepo(apiKey)
+	repo.getWebhookFunc = func(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error) {
+		return &domain.Webhook{OwnerID: ownerID, WebhookID: webhookID}, nil
+	}
+	repo.listWebhookDeliveriesFunc = func(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+		assert.Equal(t, "0123456789ab", webhookID)
+		assert.Equal(t, domain.MaxWebhookDeliveryHistory, limit)
+		return []domain.WebhookDelivery{{
+			WebhookID:      webhookID,
+			DeliveryID:     "20261018120000-deadbeef",
+			Event:          domain.WebhookEventIPChanged,
+			Payload:        `{"event":"ip.changed"}`,
+			Status:         domain.WebhookDeliveryPending,
+			Attempts:       1,
+			CreatedAt:      now,
+			LastAttemptAt:  &now,
+			LastStatusCode: http.StatusBadGateway,
+			LastError:      "unexpected status 502",
+			NextAttemptAt:  &next,
+		}}, nil
+	}
+
+	resp, err := ListWebhookDeliveries(context.Background(), webhookRequest(apiKey, ""), "test-owner", "0123456789ab", repo, newTestLogger())
+
+	assert.Assert(t, err == nil, "expected no error, got %v", err)
+	assert.Equal(t, 1, len(resp.Body.Deliveries))
+	delivery := resp.Body.Deliveries[0]
+	assert.Equal(t, domain.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusBadGateway, delivery.LastStatusCode)
+	assert.Equal(t, next.Format(time.RFC3339), delivery.NextAttemptAt)
+	assert.Equal(t, `{"event":"ip.changed"}`, string(delivery.Payload))
+}
+
+func TestListWebhookDeliveries_OtherOwnersWebhook(t *testing.T) {
+	apiKey := "ddns_sk_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnop"
+	repo := newRecordSetTestRepo(apiKey)
+	repo.listWebhookDeliveriesFunc = func(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+		t.Fatal("deliveries should not be listed")
+		return nil, nil
+	}
+
+	_, err := ListWebhookDeliveries(context.Background(), webhookRequest(apiKey, ""), "test-owner", "0123456789ab", repo, newTestLogger())
+
+	assert.Assert(t, err != nil)
+	assert.Equal(t, http.StatusNotFound, err.Status)
+}
+
+func TestDispatchWebhooks(t *testing.T) {
+	repo := &mockRepository{
+		listWebhooksFunc: func(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+			return []domain.Webhook{
+				{OwnerID: ownerID, WebhookID: "ok", URL: "https://ok.example.com", Events: []string{domain.WebhookEventIPChanged}, Secret: "whsec_ok"},
+				{OwnerID: ownerID, WebhookID: "failing", URL: "https://failing.example.com", Events: []string{domain.WebhookEventIPChanged}, Secret: "whsec_failing"},
+				{OwnerID: ownerID, WebhookID: "acme", URL: "https://acme.example.com", Events: []string{domain.WebhookEventACMECreated}, Secret: "whsec_acme"},
+			}, nil
+		},
+	}
+
+	saved := make(chan domain.WebhookDelivery, 3)
+	repo.putWebhookDeliveryFunc = func(ctx context.Context, delivery domain.WebhookDelivery) error {
+		saved <- delivery
+		return nil
+	}
+
+	webhookSvc := &mockWebhookService{
+		sendFunc: func(ctx context.Context, delivery webhook.Delivery) (int, error) {
+			assert.Assert(t, delivery.URL != "https://acme.example.com", "unsubscribed webhook should not be called")
+			assert.Equal(t, domain.WebhookEventIPChanged, delivery.Event)
+			if delivery.URL == "https://failing.example.com" {
+				return http.StatusBadGateway, errors.New("unexpected status 502")
+			}
+			return http.StatusOK, nil
+		},
+	}
+
+	data := webhook.IPChangedData{Location: "home", Subdomain: "a3f8c2d1.grocky.net", PreviousIP: "203.0.113.50", IP: "203.0.113.51"}
+	dispatchWebhooks(context.Background(), "test-owner", domain.WebhookEventIPChanged, data, repo, webhookSvc, newTestLogger())
+	close(saved)
+
+	deliveries := make(map[string]domain.WebhookDelivery)
+	for delivery := range saved {
+		deliveries[delivery.WebhookID] = delivery
+	}
+	assert.Equal(t, 2, len(deliveries))
+	assert.Equal(t, domain.WebhookDeliverySucceeded, deliveries["ok"].Status)
+	assert.Equal(t, domain.WebhookDeliveryPending, deliveries["failing"].Status)
+	assert.Equal(t, 1, deliveries["failing"].Attempts)
+	assert.Assert(t, deliveries["failing"].NextAttemptAt != nil)
+
+	var payload struct {
+		Event   string                `json:"event"`
+		OwnerID string                `json:"ownerId"`
+		Data    webhook.IPChangedData `json:"data"`
+	}
+	assert.NilError(t, json.Unmarshal([]byte(deliveries["ok"].Payload), &payload))
+	assert.Equal(t, domain.WebhookEventIPChanged, payload.Event)
+	assert.Equal(t, "test-owner", payload.OwnerID)
+	assert.DeepEqual(t, data, payload.Data)
+}
@@ -6,55 +6,155 @@ import (
 	"github.com/grocky/ddns-service/internal/domain"
 )
 
-const (
-	// MaxChangesPerHour is the maximum number of IP changes allowed per hour.
-	MaxChangesPerHour = 2
-)
-
 // CheckResult contains the result of a rate limit check.
 type CheckResult struct {
 	Allowed    bool
 	RetryAfter time.Duration
 }
 
-// Check determines if an IP change is allowed for the given mapping.
+// Check determines if an IP change is allowed for the given mapping under policy.
 // It returns whether the change is allowed and how long until the rate limit resets.
-func Check(mapping *domain.IPMapping, now time.Time) CheckResult {
+func Check(mapping *domain.IPMapping, policy domain.RateLimitPolicy, now time.Time) CheckResult {
 	if mapping == nil {
 		// New mapping - check if we're within the limit (starts at 0)
 		return CheckResult{Allowed: true, RetryAfter: 0}
 	}
 
-	currentHour := now.Truncate(time.Hour)
-	lastChangeHour := mapping.LastIPChangeAt.Truncate(time.Hour)
+	if policy.Algorithm == domain.RateLimitSlidingWindow {
+		return checkSlidingWindow(mapping, policy, now)
+	}
+	return checkFixedWindow(mapping, policy, now)
+}
+
+// checkFixedWindow counts the changes made since the start of the current window.
+func checkFixedWindow(mapping *domain.IPMapping, policy domain.RateLimitPolicy, now time.Time) CheckResult {
+	return checkWindowCount(mapping.HourlyChangeCount, mapping.LastIPChangeAt, policy.MaxChanges, policy.Window(), now)
+}
+
+// checkWindowCount checks a fixed window counter: count events happened in the
+// window containing last, and at most limit are allowed per window.
+func checkWindowCount(count int, last time.Time, limit int, window time.Duration, now time.Time) CheckResult {
+	currentWindow := now.Truncate(window)
+	lastWindow := last.Truncate(window)
 
-	// If the last change was in a different hour, the counter has reset
-	if !lastChangeHour.Equal(currentHour) {
+	// If the last event was in a different window, the counter has reset
+	if !lastWindow.Equal(currentWindow) {
 		return CheckResult{Allowed: true, RetryAfter: 0}
 	}
 
 	// Check if we've exceeded the limit
-	if mapping.HourlyChangeCount >= MaxChangesPerHour {
-		nextHour := currentHour.Add(time.Hour)
-		retryAfter := nextHour.Sub(now)
-		return CheckResult{Allowed: false, RetryAfter: retryAfter}
+	if count >= limit {
+		nextWindow := currentWindow.Add(window)
+		return CheckResult{Allowed: false, RetryAfter: nextWindow.Sub(now)}
 	}
 
 	return CheckResult{Allowed: true, RetryAfter: 0}
 }
 
+// countInWindow returns a fixed window counter after one more event at now.
+func countInWindow(count int, last time.Time, window time.Duration, now time.Time) int {
+	if !last.Truncate(window).Equal(now.Truncate(window)) {
+		return 1
+	}
+	return count + 1
+}
+
+// checkSlidingWindow counts the changes made within one window of now. When the
+// limit is reached, a change is allowed again once the oldest counted one falls
+// out of the window.
+func checkSlidingWindow(mapping *domain.IPMapping, policy domain.RateLimitPolicy, now time.Time) CheckResult {
+	recent := changesSince(mapping.RecentIPChanges, now.Add(-policy.Window()))
+	if len(recent) < policy.MaxChanges {
+		return CheckResult{Allowed: true, RetryAfter: 0}
+	}
+
+	oldest := recent[len(recent)-policy.MaxChanges]
+	return CheckResult{Allowed: false, RetryAfter: oldest.Add(policy.Window()).Sub(now)}
+}
+
+// Status describes how much of a rate limit is left, as reported in the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset response headers.
+type Status struct {
+	Limit     int
+	Remaining int
+	// Reset is how long until the fixed window's count starts over, or until the
+	// oldest change counted by a sliding window stops counting. It is zero when a
+	// sliding window counts no changes.
+	Reset time.Duration
+}
+
+// CurrentStatus reports how many more IP changes the mapping may make under
+// policy. A nil mapping has made none.
+func CurrentStatus(mapping *domain.IPMapping, policy domain.RateLimitPolicy, now time.Time) Status {
+	status := Status{Limit: policy.MaxChanges, Remaining: policy.MaxChanges}
+	window := policy.Window()
+
+	if policy.Algorithm == domain.RateLimitSlidingWindow {
+		if mapping == nil {
+			return status
+		}
+		recent := changesSince(mapping.RecentIPChanges, now.Add(-window))
+		if len(recent) == 0 {
+			return status
+		}
+		status.Remaining = max(0, policy.MaxChanges-len(recent))
+		if len(recent) > policy.MaxChanges {
+			recent = recent[len(recent)-policy.MaxChanges:]
+		}
+		status.Reset = recent[0].Add(window).Sub(now)
+		return status
+	}
+
+	currentWindow := now.Truncate(window)
+	status.Reset = currentWindow.Add(window).Sub(now)
+	if mapping != nil && mapping.LastIPChangeAt.Truncate(window).Equal(currentWindow) {
+		status.Remaining = max(0, policy.MaxChanges-mapping.HourlyChangeCount)
+	}
+	return status
+}
+
 // UpdateCounters updates the rate limit counters on a mapping after an IP change.
-// This should be called after a successful IP change.
-func UpdateCounters(mapping *domain.IPMapping, now time.Time) {
-	currentHour := now.Truncate(time.Hour)
-	lastChangeHour := mapping.LastIPChangeAt.Truncate(time.Hour)
+// This should be called after a successful IP change. Both the fixed window count
+// and the recent change history are kept, so a policy can switch algorithms
+// without losing track of earlier changes.
+func UpdateCounters(mapping *domain.IPMapping, policy domain.RateLimitPolicy, now time.Time) {
+	window := policy.Window()
+
+	// If the last change was in a different window, the counter resets
+	mapping.HourlyChangeCount = countInWindow(mapping.HourlyChangeCount, mapping.LastIPChangeAt, window, now)
 
-	// If the last change was in a different hour, reset the counter
-	if !lastChangeHour.Equal(currentHour) {
-		mapping.HourlyChangeCount = 1
-	} else {
-		mapping.HourlyChangeCount++
+	// Only the changes that can still count against the limit are kept
+	recent := append(changesSince(mapping.RecentIPChanges, now.Add(-window)), now)
+	if len(recent) > policy.MaxChanges {
+		recent = recent[len(recent)-policy.MaxChanges:]
 	}
+	mapping.RecentIPChanges = recent
 
 	mapping.LastIPChangeAt = now
 }
+
+// ACMEChallengeStatus reports how many more ACME challenges the owner may create
+// under limit in the current fixed window.
+func ACMEChallengeStatus(owner *domain.Owner, limit domain.ACMEChallengeLimit, now time.Time) Status {
+	currentWindow := now.Truncate(limit.Window)
+	status := Status{
+		Limit:     limit.MaxChallenges,
+		Remaining: limit.MaxChallenges,
+		Reset:     currentWindow.Add(limit.Window).Sub(now),
+	}
+	if owner.ACMEChallengeWindow == currentWindow.Unix() {
+		status.Remaining = max(0, limit.MaxChallenges-owner.ACMEChallengeCount)
+	}
+	return status
+}
+
+// changesSince returns a copy of the changes made after since.
+func changesSince(changes []time.Time, since time.Time) []time.Time {
+	recent := make([]time.Time, 0, len(changes)+1)
+	for _, changedAt := range changes {
+		if changedAt.After(since) {
+			recent = append(recent, changedAt)
+		}
+	}
+	return recent
+}
@@ -8,9 +8,11 @@ import (
 	"gotest.tools/assert"
 )
 
+var defaultPolicy = domain.DefaultRateLimitPolicy
+
 func TestCheck_NilMapping(t *testing.T) {
 	now := time.Now().UTC()
-	result := Check(nil, now)
+	result := Check(nil, defaultPolicy, now)
 
 	assert.Assert(t, result.Allowed, "nil mapping should be allowed (new registration)")
 	assert.Equal(t, time.Duration(0), result.RetryAfter)
@@ -23,7 +25,7 @@ func TestCheck_FirstChangeInHour(t *testing.T) {
 		HourlyChangeCount: 1,
 	}
 
-	result := Check(mapping, now)
+	result := Check(mapping, defaultPolicy, now)
 
 	assert.Assert(t, result.Allowed, "should allow second change in same hour")
 	assert.Equal(t, time.Duration(0), result.RetryAfter)
@@ -33,10 +35,10 @@ func TestCheck_AtLimit(t *testing.T) {
 	now := time.Now().UTC()
 	mapping := &domain.IPMapping{
 		LastIPChangeAt:    now.Add(-time.Minute),
-		HourlyChangeCount: MaxChangesPerHour,
+		HourlyChangeCount: defaultPolicy.MaxChanges,
 	}
 
-	result := Check(mapping, now)
+	result := Check(mapping, defaultPolicy, now)
 
 	assert.Assert(t, !result.Allowed, "should not allow change at limit")
 	assert.Assert(t, result.RetryAfter > 0, "should have retry after duration")
@@ -47,10 +49,10 @@ func TestCheck_ExceedsLimit(t *testing.T) {
 	now := time.Now().UTC()
 	mapping := &domain.IPMapping{
 		LastIPChangeAt:    now.Add(-time.Minute),
-		HourlyChangeCount: MaxChangesPerHour + 1,
+		HourlyChangeCount: defaultPolicy.MaxChanges + 1,
 	}
 
-	result := Check(mapping, now)
+	result := Check(mapping, defaultPolicy, now)
 
 	assert.Assert(t, !result.Allowed, "should not allow change over limit")
 }
@@ -58,11 +60,11 @@ func TestCheck_ExceedsLimit(t *testing.T) {
 func TestCheck_DifferentHour(t *testing.T) {
 	now := time.Now().UTC()
 	mapping := &domain.IPMapping{
-		LastIPChangeAt:    now.Add(-2 * time.Hour), // Changed 2 hours ago
-		HourlyChangeCount: MaxChangesPerHour + 5,   // Was over limit in previous hour
+		LastIPChangeAt:    now.Add(-2 * time.Hour),      // Changed 2 hours ago
+		HourlyChangeCount: defaultPolicy.MaxChanges + 5, // Was over limit in previous hour
 	}
 
-	result := Check(mapping, now)
+	result := Check(mapping, defaultPolicy, now)
 
 	assert.Assert(t, result.Allowed, "should allow change in new hour")
 	assert.Equal(t, time.Duration(0), result.RetryAfter)
@@ -73,10 +75,10 @@ func TestCheck_RetryAfterCalculation(t *testing.T) {
 	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
 	mapping := &domain.IPMapping{
 		LastIPChangeAt:    now.Add(-time.Minute),
-		HourlyChangeCount: MaxChangesPerHour,
+		HourlyChangeCount: defaultPolicy.MaxChanges,
 	}
 
-	result := Check(mapping, now)
+	result := Check(mapping, defaultPolicy, now)
 
 	assert.Assert(t, !result.Allowed)
 	// Should be approximately 30 minutes until next hour
@@ -91,7 +93,7 @@ func TestUpdateCounters_FirstChangeInHour(t *testing.T) {
 		HourlyChangeCount: 5,
 	}
 
-	UpdateCounters(mapping, now)
+	UpdateCounters(mapping, defaultPolicy, now)
 
 	assert.Equal(t, 1, mapping.HourlyChangeCount, "counter should reset to 1")
 	assert.Equal(t, now, mapping.LastIPChangeAt)
@@ -104,14 +106,16 @@ func TestUpdateCounters_SubsequentChangeInHour(t *testing.T) {
 		HourlyChangeCount: 1,
 	}
 
-	UpdateCounters(mapping, now)
+	UpdateCounters(mapping, defaultPolicy, now)
 
 	assert.Equal(t, 2, mapping.HourlyChangeCount, "counter should increment")
 	assert.Equal(t, now, mapping.LastIPChangeAt)
 }
 
-func TestMaxChangesPerHour(t *testing.T) {
-	assert.Equal(t, 2, MaxChangesPerHour, "max changes per hour should be 2")
+func TestDefaultPolicy(t *testing.T) {
+	assert.Equal(t, 2, defaultPolicy.MaxChanges, "max changes per hour should be 2")
+	assert.Equal(t, time.Hour, defaultPolicy.Window())
+	assert.Equal(t, domain.RateLimitFixedWindow, defaultPolicy.Algorithm)
 }
 
 func TestCheck_EdgeCase_ExactlyAtHourBoundary(t *testing.T) {
@@ -119,10 +123,258 @@ func TestCheck_EdgeCase_ExactlyAtHourBoundary(t *testing.T) {
 	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
 	mapping := &domain.IPMapping{
 		LastIPChangeAt:    time.Date(2025, 1, 15, 9, 59, 59, 0, time.UTC), // Just before new hour
-		HourlyChangeCount: MaxChangesPerHour,
+		HourlyChangeCount: defaultPolicy.MaxChanges,
 	}
 
-	result := Check(mapping, now)
+	result := Check(mapping, defaultPolicy, now)
 
 	assert.Assert(t, result.Allowed, "should allow change at new hour boundary")
 }
+
+func TestCheck_FixedWindowLength(t *testing.T) {
+	policy := domain.RateLimitPolicy{MaxChanges: 5, WindowSeconds: 15 * 60, Algorithm: domain.RateLimitFixedWindow}
+	now := time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		lastChangeAt  time.Time
+		count         int
+		expectAllowed bool
+		expectRetry   time.Duration
+	}{
+		{name: "under limit", lastChangeAt: now.Add(-time.Minute), count: 4, expectAllowed: true},
+		{name: "at limit", lastChangeAt: now.Add(-time.Minute), count: 5, expectAllowed: false, expectRetry: 10 * time.Minute},
+		{name: "previous window", lastChangeAt: time.Date(2025, 1, 15, 10, 14, 0, 0, time.UTC), count: 5, expectAllowed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping := &domain.IPMapping{LastIPChangeAt: tc.lastChangeAt, HourlyChangeCount: tc.count}
+
+			result := Check(mapping, policy, now)
+
+			assert.Equal(t, tc.expectAllowed, result.Allowed)
+			assert.Equal(t, tc.expectRetry, result.RetryAfter)
+		})
+	}
+}
+
+func TestCheck_SlidingWindow(t *testing.T) {
+	policy := domain.RateLimitPolicy{MaxChanges: 2, WindowSeconds: 60 * 60, Algorithm: domain.RateLimitSlidingWindow}
+	now := time.Date(2025, 1, 15, 10, 5, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		changes       []time.Time
+		expectAllowed bool
+		expectRetry   time.Duration
+	}{
+		{name: "no history", expectAllowed: true},
+		{name: "under limit", changes: []time.Time{now.Add(-10 * time.Minute)}, expectAllowed: true},
+		{
+			// A fixed window would reset at 10:00; the sliding window still counts both
+			name:          "at limit across hour boundary",
+			changes:       []time.Time{now.Add(-20 * time.Minute), now.Add(-10 * time.Minute)},
+			expectAllowed: false,
+			expectRetry:   40 * time.Minute,
+		},
+		{
+			name:          "oldest change outside window",
+			changes:       []time.Time{now.Add(-61 * time.Minute), now.Add(-10 * time.Minute)},
+			expectAllowed: true,
+		},
+		{
+			name:          "change exactly one window ago",
+			changes:       []time.Time{now.Add(-time.Hour), now.Add(-10 * time.Minute)},
+			expectAllowed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping := &domain.IPMapping{RecentIPChanges: tc.changes}
+
+			result := Check(mapping, policy, now)
+
+			assert.Equal(t, tc.expectAllowed, result.Allowed)
+			assert.Equal(t, tc.expectRetry, result.RetryAfter)
+		})
+	}
+}
+
+func TestUpdateCounters_RecentChanges(t *testing.T) {
+	policy := domain.RateLimitPolicy{MaxChanges: 2, WindowSeconds: 60 * 60, Algorithm: domain.RateLimitSlidingWindow}
+	now := time.Date(2025, 1, 15, 10, 5, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		changes  []time.Time
+		expected []time.Time
+	}{
+		{name: "first change", expected: []time.Time{now}},
+		{
+			name:     "appends",
+			changes:  []time.Time{now.Add(-10 * time.Minute)},
+			expected: []time.Time{now.Add(-10 * time.Minute), now},
+		},
+		{
+			name:     "drops changes outside window",
+			changes:  []time.Time{now.Add(-2 * time.Hour), now.Add(-10 * time.Minute)},
+			expected: []time.Time{now.Add(-10 * time.Minute), now},
+		},
+		{
+			name:     "keeps at most the limit",
+			changes:  []time.Time{now.Add(-30 * time.Minute), now.Add(-20 * time.Minute), now.Add(-10 * time.Minute)},
+			expected: []time.Time{now.Add(-10 * time.Minute), now},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping := &domain.IPMapping{RecentIPChanges: tc.changes}
+
+			UpdateCounters(mapping, policy, now)
+
+			assert.DeepEqual(t, tc.expected, mapping.RecentIPChanges)
+			assert.Equal(t, now, mapping.LastIPChangeAt)
+		})
+	}
+}
+
+func TestCurrentStatus_FixedWindow(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name              string
+		mapping           *domain.IPMapping
+		expectedRemaining int
+	}{
+		{name: "new mapping", expectedRemaining: 2},
+		{
+			name:              "change this hour",
+			mapping:           &domain.IPMapping{LastIPChangeAt: now.Add(-10 * time.Minute), HourlyChangeCount: 1},
+			expectedRemaining: 1,
+		},
+		{
+			name:              "limit reached",
+			mapping:           &domain.IPMapping{LastIPChangeAt: now.Add(-10 * time.Minute), HourlyChangeCount: 2},
+			expectedRemaining: 0,
+		},
+		{
+			name:              "over a lowered limit",
+			mapping:           &domain.IPMapping{LastIPChangeAt: now.Add(-10 * time.Minute), HourlyChangeCount: 5},
+			expectedRemaining: 0,
+		},
+		{
+			name:              "changes last hour",
+			mapping:           &domain.IPMapping{LastIPChangeAt: now.Add(-time.Hour), HourlyChangeCount: 2},
+			expectedRemaining: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := CurrentStatus(tc.mapping, defaultPolicy, now)
+
+			assert.Equal(t, 2, status.Limit)
+			assert.Equal(t, tc.expectedRemaining, status.Remaining)
+			assert.Equal(t, 15*time.Minute, status.Reset, "the window ends at 11:00")
+		})
+	}
+}
+
+func TestCurrentStatus_SlidingWindow(t *testing.T) {
+	policy := domain.RateLimitPolicy{MaxChanges: 2, WindowSeconds: 60 * 60, Algorithm: domain.RateLimitSlidingWindow}
+	now := time.Date(2025, 1, 15, 10, 5, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name              string
+		changes           []time.Time
+		expectedRemaining int
+		expectedReset     time.Duration
+	}{
+		{name: "no history", expectedRemaining: 2},
+		{
+			name:              "one change",
+			changes:           []time.Time{now.Add(-10 * time.Minute)},
+			expectedRemaining: 1,
+			expectedReset:     50 * time.Minute,
+		},
+		{
+			name:              "limit reached",
+			changes:           []time.Time{now.Add(-20 * time.Minute), now.Add(-10 * time.Minute)},
+			expectedRemaining: 0,
+			expectedReset:     40 * time.Minute,
+		},
+		{
+			name:              "old changes don't count",
+			changes:           []time.Time{now.Add(-2 * time.Hour)},
+			expectedRemaining: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := CurrentStatus(&domain.IPMapping{RecentIPChanges: tc.changes}, policy, now)
+
+			assert.Equal(t, 2, status.Limit)
+			assert.Equal(t, tc.expectedRemaining, status.Remaining)
+			assert.Equal(t, tc.expectedReset, status.Reset)
+		})
+	}
+}
+
+func TestCurrentStatus_AgreesWithCheck(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)
+	mapping := &domain.IPMapping{LastIPChangeAt: now.Add(-10 * time.Minute), HourlyChangeCount: 2}
+
+	status := CurrentStatus(mapping, defaultPolicy, now)
+	result := Check(mapping, defaultPolicy, now)
+
+	assert.Assert(t, !result.Allowed)
+	assert.Equal(t, result.RetryAfter, status.Reset)
+}
+
+func TestACMEChallengeStatus(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 40, 0, 0, time.UTC)
+	limit := domain.ACMEChallengeLimit{MaxChallenges: 3, Window: time.Hour}
+	currentWindow := now.Truncate(time.Hour).Unix()
+	previousWindow := now.Add(-time.Hour).Truncate(time.Hour).Unix()
+
+	testCases := []struct {
+		name              string
+		owner             domain.Owner
+		expectedRemaining int
+	}{
+		{
+			name:              "no challenges",
+			owner:             domain.Owner{},
+			expectedRemaining: 3,
+		},
+		{
+			name:              "under limit",
+			owner:             domain.Owner{ACMEChallengeCount: 2, ACMEChallengeWindow: currentWindow},
+			expectedRemaining: 1,
+		},
+		{
+			name:              "at limit",
+			owner:             domain.Owner{ACMEChallengeCount: 3, ACMEChallengeWindow: currentWindow},
+			expectedRemaining: 0,
+		},
+		{
+			name:              "previous window",
+			owner:             domain.Owner{ACMEChallengeCount: 3, ACMEChallengeWindow: previousWindow},
+			expectedRemaining: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := ACMEChallengeStatus(&tc.owner, limit, now)
+
+			assert.Equal(t, 3, status.Limit)
+			assert.Equal(t, tc.expectedRemaining, status.Remaining)
+			assert.Equal(t, 20*time.Minute, status.Reset)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -18,6 +19,22 @@ const (
 	mappingsTableName       = "DdnsServiceIpMapping"
 	ownersTableName         = "DdnsServiceOwners"
 	acmeChallengesTableName = "DdnsServiceAcmeChallenges"
+	recordSetsTableName     = "DdnsServiceRecordSets"
+	apiKeysTableName        = "DdnsServiceApiKeys"
+	noncesTableName         = "DdnsServiceNonces"
+	subdomainsTableName     = "DdnsServiceSubdomains"
+	operatorsTableName      = "DdnsServiceOperators"
+	auditLogTableName       = "DdnsServiceAuditLog"
+	authFailuresTableName   = "DdnsServiceAuthFailures"
+	rateLimitPlansTableName = "DdnsServiceRateLimitPlans"
+	requestLimitsTableName  = "DdnsServiceRequestLimits"
+	webhooksTableName       = "DdnsServiceWebhooks"
+	deliveriesTableName     = "DdnsServiceWebhookDeliveries"
+
+	subdomainIndexName     = "SubdomainIndex"
+	ownerKeyIDIndexName    = "ApiKeyIdIndex"
+	previousKeyIDIndexName = "PreviousApiKeyIdIndex"
+	apiKeyIDIndexName      = "KeyIdIndex"
 )
 
 // DynamoDBClient defines the interface for DynamoDB operations we use.
@@ -27,6 +44,8 @@ type DynamoDBClient interface {
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
 	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 // DynamoDBRepository implements Repository using DynamoDB.
@@ -107,8 +126,131 @@ func (r *DynamoDBRepository) Get(ctx context.Context, ownerID, location string)
 	return &mapping, nil
 }
 
+// Delete removes an IP mapping from DynamoDB.
+func (r *DynamoDBRepository) Delete(ctx context.Context, ownerID, location string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(mappingsTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":      &types.AttributeValueMemberS{Value: ownerID},
+			"LocationName": &types.AttributeValueMemberS{Value: location},
+		},
+	}
+
+	_, err := r.client.DeleteItem(ctx, input)
+	if err != nil {
+		r.logger.Error("failed to delete mapping",
+			"error", err,
+			"ownerId", ownerID,
+			"location", location,
+		)
+		return err
+	}
+
+	r.logger.Info("mapping deleted",
+		"ownerId", ownerID,
+		"location", location,
+	)
+	return nil
+}
+
+// ListMappingsBySubdomain returns every mapping that stores the given subdomain.
+// Uses the SubdomainIndex GSI, so results are eventually consistent.
+func (r *DynamoDBRepository) ListMappingsBySubdomain(ctx context.Context, subdomain string) ([]domain.IPMapping, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(mappingsTableName),
+		IndexName:              aws.String(subdomainIndexName),
+		KeyConditionExpression: aws.String("Subdomain = :subdomain"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":subdomain": &types.AttributeValueMemberS{Value: subdomain},
+		},
+	}
+
+	var mappings []domain.IPMapping
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to query mappings by subdomain", "error", err, "subdomain", subdomain)
+			return nil, err
+		}
+
+		var page []domain.IPMapping
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			r.logger.Error("failed to unmarshal mappings", "error", err)
+			return nil, err
+		}
+		mappings = append(mappings, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return mappings, nil
+}
+
+// ClaimSubdomain reserves a subdomain for an owner's location with a conditional
+// put keyed on the subdomain, so concurrent requests can't be given the same one.
+// Claims outlive the location: a deleted location's subdomain is never handed to
+// anyone else, though the same owner and location get it back. Only moving the
+// location to another subdomain releases the claim; see ReleaseSubdomain.
+func (r *DynamoDBRepository) ClaimSubdomain(ctx context.Context, subdomain, ownerID, location string) error {
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(subdomainsTableName),
+		Item: map[string]types.AttributeValue{
+			"Subdomain":    &types.AttributeValueMemberS{Value: subdomain},
+			"OwnerId":      &types.AttributeValueMemberS{Value: ownerID},
+			"LocationName": &types.AttributeValueMemberS{Value: location},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(Subdomain) OR (OwnerId = :owner AND LocationName = :location)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner":    &types.AttributeValueMemberS{Value: ownerID},
+			":location": &types.AttributeValueMemberS{Value: location},
+		},
+	}
+
+	_, err := r.client.PutItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrSubdomainTaken
+		}
+		r.logger.Error("failed to claim subdomain", "error", err, "subdomain", subdomain)
+		return err
+	}
+	return nil
+}
+
+// ReleaseSubdomain deletes a subdomain claim with a delete conditional on the
+// claim belonging to the owner's location.
+func (r *DynamoDBRepository) ReleaseSubdomain(ctx context.Context, subdomain, ownerID, location string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(subdomainsTableName),
+		Key: map[string]types.AttributeValue{
+			"Subdomain": &types.AttributeValueMemberS{Value: subdomain},
+		},
+		ConditionExpression: aws.String("OwnerId = :owner AND LocationName = :location"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner":    &types.AttributeValueMemberS{Value: ownerID},
+			":location": &types.AttributeValueMemberS{Value: location},
+		},
+	}
+
+	_, err := r.client.DeleteItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil
+		}
+		r.logger.Error("failed to release subdomain", "error", err, "subdomain", subdomain)
+		return err
+	}
+	return nil
+}
+
 // CreateOwner creates a new owner in DynamoDB.
-// Uses a conditional write to fail if the owner already exists.
+// Uses a conditional write to fail if the owner already exists and isn't a
+// replaceable unverified owner.
 func (r *DynamoDBRepository) CreateOwner(ctx context.Context, owner domain.Owner) error {
 	item, err := attributevalue.MarshalMap(owner)
 	if err != nil {
@@ -116,10 +258,19 @@ func (r *DynamoDBRepository) CreateOwner(ctx context.Context, owner domain.Owner
 		return err
 	}
 
+	// An unverified owner may be replaced once it has expired, or by signing up
+	// again with the same email address to get a new verification email
 	input := &dynamodb.PutItemInput{
 		TableName:           aws.String(ownersTableName),
 		Item:                item,
-		ConditionExpression: aws.String("attribute_not_exists(OwnerId)"),
+		ConditionExpression: aws.String("attribute_not_exists(OwnerId) OR (attribute_exists(VerifyBy) AND (#ttl <= :now OR Email = :email))"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "TTL",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(owner.CreatedAt.Unix(), 10)},
+			":email": &types.AttributeValueMemberS{Value: owner.Email},
+		},
 	}
 
 	_, err = r.client.PutItem(ctx, input)
@@ -164,18 +315,84 @@ func (r *DynamoDBRepository) GetOwner(ctx context.Context, ownerID string) (*dom
 	return &owner, nil
 }
 
-// UpdateOwnerKey updates the API key hash for an owner.
-func (r *DynamoDBRepository) UpdateOwnerKey(ctx context.Context, ownerID, newKeyHash string) error {
+// UpdateOwnerKey replaces the primary API key hash, sealed signing secret and
+// expiry for an owner.
+// Usage data and expiry warnings belong to the old key, so they are cleared.
+// When previous is set, the old key is kept as the previous key, and the update
+// only succeeds if it is still the primary key; otherwise any previous key is
+// removed.
+func (r *DynamoDBRepository) UpdateOwnerKey(ctx context.Context, ownerID, keyID, newKeyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
+	oldKeyHash := ""
+	if previous != nil {
+		oldKeyHash = previous.KeyHash
+	}
+	return r.updateOwnerKey(ctx, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret, expiresAt, previous)
+}
+
+// ReplaceOwnerKey replaces the primary API key only if its hash is still
+// oldKeyHash, so two requests racing to replace the same key can't both succeed.
+// Any previous key stops working.
+func (r *DynamoDBRepository) ReplaceOwnerKey(ctx context.Context, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret string) error {
+	return r.updateOwnerKey(ctx, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret, nil, nil)
+}
+
+// updateOwnerKey replaces the primary API key, conditional on the current key
+// hash when oldKeyHash is set.
+func (r *DynamoDBRepository) updateOwnerKey(ctx context.Context, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error {
+	update := "SET ApiKeyHash = :hash, ApiKeyId = :keyId"
+	values := map[string]types.AttributeValue{
+		":hash":  &types.AttributeValueMemberS{Value: newKeyHash},
+		":keyId": &types.AttributeValueMemberS{Value: keyID},
+	}
+	remove := "REMOVE ApiKeyLastUsedAt, ApiKeyLastUsedIp, ApiKeyExpiryWarnedAt"
+	if signingSecret != "" {
+		update += ", ApiKeySigningSecret = :secret"
+		values[":secret"] = &types.AttributeValueMemberS{Value: signingSecret}
+	} else {
+		remove += ", ApiKeySigningSecret"
+	}
+	if expiresAt != nil {
+		update += ", ApiKeyExpiresAt = :expires"
+		values[":expires"] = timeAttribute(*expiresAt)
+	} else {
+		remove += ", ApiKeyExpiresAt"
+	}
+
+	if previous != nil {
+		update += ", PreviousApiKeyHash = :prevHash, PreviousApiKeyExpiresAt = :prevExpires"
+		values[":prevHash"] = &types.AttributeValueMemberS{Value: previous.KeyHash}
+		values[":prevExpires"] = timeAttribute(previous.ExpiresAt)
+		if previous.KeyID != "" {
+			update += ", PreviousApiKeyId = :prevKeyId"
+			values[":prevKeyId"] = &types.AttributeValueMemberS{Value: previous.KeyID}
+		} else {
+			remove += ", PreviousApiKeyId"
+		}
+		if previous.SigningSecret != "" {
+			update += ", PreviousApiKeySigningSecret = :prevSecret"
+			values[":prevSecret"] = &types.AttributeValueMemberS{Value: previous.SigningSecret}
+		} else {
+			remove += ", PreviousApiKeySigningSecret"
+		}
+		remove += ", PreviousApiKeyLastUsedAt, PreviousApiKeyLastUsedIp"
+	} else {
+		remove += ", " + previousKeyAttributes
+	}
+
+	condition := "attribute_exists(OwnerId)"
+	if oldKeyHash != "" {
+		condition += " AND ApiKeyHash = :oldHash"
+		values[":oldHash"] = &types.AttributeValueMemberS{Value: oldKeyHash}
+	}
+
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(ownersTableName),
 		Key: map[string]types.AttributeValue{
 			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
 		},
-		UpdateExpression: aws.String("SET ApiKeyHash = :hash"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":hash": &types.AttributeValueMemberS{Value: newKeyHash},
-		},
-		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+		UpdateExpression:          aws.String(update + " " + remove),
+		ExpressionAttributeValues: values,
+		ConditionExpression:       aws.String(condition),
 	}
 
 	_, err := r.client.UpdateItem(ctx, input)
@@ -188,7 +405,36 @@ func (r *DynamoDBRepository) UpdateOwnerKey(ctx context.Context, ownerID, newKey
 		return err
 	}
 
-	r.logger.Info("owner key updated", "ownerId", ownerID)
+	r.logger.Info("owner key updated", "ownerId", ownerID, "previousKept", previous != nil)
+	return nil
+}
+
+// previousKeyAttributes lists the owner attributes describing the previous key.
+const previousKeyAttributes = "PreviousApiKeyHash, PreviousApiKeyId, PreviousApiKeySigningSecret, PreviousApiKeyExpiresAt, PreviousApiKeyLastUsedAt, PreviousApiKeyLastUsedIp"
+
+// RevokePreviousOwnerKey removes the owner's previous primary key before its grace
+// period ends. Returns ErrAPIKeyNotFound if the owner has no previous key.
+func (r *DynamoDBRepository) RevokePreviousOwnerKey(ctx context.Context, ownerID string) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("REMOVE " + previousKeyAttributes),
+		ConditionExpression: aws.String("attribute_exists(PreviousApiKeyHash)"),
+	}
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrAPIKeyNotFound
+		}
+		r.logger.Error("failed to revoke previous owner key", "error", err, "ownerId", ownerID)
+		return err
+	}
+
+	r.logger.Info("previous owner key revoked", "ownerId", ownerID)
 	return nil
 }
 
@@ -314,5 +560,1947 @@ func (r *DynamoDBRepository) ScanExpiredChallenges(ctx context.Context) ([]domai
 	return challenges, nil
 }
 
+// maxRecordSetCreateAttempts allows one retry after counting the record sets of
+// an owner from before RecordSetCount was kept.
+const maxRecordSetCreateAttempts = 2
+
+// CreateRecordSet stores a new custom record set. The conditional put and the
+// increment of the owner's RecordSetCount, conditional on the quota, run in one
+// transaction, so concurrent creates can't exceed the quota or leave the count
+// out of step.
+func (r *DynamoDBRepository) CreateRecordSet(ctx context.Context, rs domain.RecordSet, limit int) error {
+	rs.RecordKey = domain.RecordSetKey(rs.LocationName, rs.Name, rs.Type)
+
+	item, err := attributevalue.MarshalMap(rs)
+	if err != nil {
+		r.logger.Error("failed to marshal record set", "error", err)
+		return err
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(recordSetsTableName),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(RecordKey)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName: aws.String(ownersTableName),
+					Key: map[string]types.AttributeValue{
+						"OwnerId": &types.AttributeValueMemberS{Value: rs.OwnerID},
+					},
+					UpdateExpression:    aws.String("ADD RecordSetCount :one"),
+					ConditionExpression: aws.String("RecordSetCount < :limit"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":one":   &types.AttributeValueMemberN{Value: "1"},
+						":limit": &types.AttributeValueMemberN{Value: strconv.Itoa(limit)},
+					},
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			},
+		},
+	}
+
+	for attempt := 0; attempt < maxRecordSetCreateAttempts; attempt++ {
+		_, err = r.client.TransactWriteItems(ctx, input)
+		if err == nil {
+			r.logger.Info("record set created",
+				"ownerId", rs.OwnerID,
+				"location", rs.LocationName,
+				"recordKey", rs.RecordKey,
+			)
+			return nil
+		}
+
+		reasons, canceled := cancellationReasons(err)
+		if !canceled || len(reasons) != 2 {
+			r.logger.Error("failed to create record set",
+				"error", err,
+				"ownerId", rs.OwnerID,
+				"location", rs.LocationName,
+				"recordKey", rs.RecordKey,
+			)
+			return err
+		}
+		if conditionFailed(reasons[0]) {
+			return domain.ErrRecordSetExists
+		}
+		if !conditionFailed(reasons[1]) {
+			r.logger.Error("record set transaction canceled", "error", err, "ownerId", rs.OwnerID)
+			return err
+		}
+
+		owner := reasons[1].Item
+		if owner == nil {
+			return domain.ErrOwnerNotFound
+		}
+		if _, counted := owner["RecordSetCount"]; counted {
+			return domain.ErrRecordSetQuotaExceeded
+		}
+		if err := r.startRecordSetCount(ctx, rs.OwnerID); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Warn("gave up creating record set after counting", "ownerId", rs.OwnerID)
+	return errors.New("record set count conflict")
+}
+
+// startRecordSetCount sets RecordSetCount for an owner from before it was kept
+// by counting the owner's record sets. It does nothing if the owner already has
+// a count.
+func (r *DynamoDBRepository) startRecordSetCount(ctx context.Context, ownerID string) error {
+	count, err := r.CountRecordSets(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("SET RecordSetCount = :count"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId) AND attribute_not_exists(RecordSetCount)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":count": &types.AttributeValueMemberN{Value: strconv.Itoa(count)},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil
+		}
+		r.logger.Error("failed to start record set count", "error", err, "ownerId", ownerID)
+		return err
+	}
+	return nil
+}
+
+// cancellationReasons returns the per-item reasons a transaction was canceled.
+func cancellationReasons(err error) ([]types.CancellationReason, bool) {
+	var canceledErr *types.TransactionCanceledException
+	if !errors.As(err, &canceledErr) {
+		return nil, false
+	}
+	return canceledErr.CancellationReasons, true
+}
+
+// conditionFailed reports whether a transaction item failed its condition.
+func conditionFailed(reason types.CancellationReason) bool {
+	return aws.ToString(reason.Code) == "ConditionalCheckFailed"
+}
+
+// PutRecordSet replaces an existing custom record set. The write is conditional
+// so that a record set deleted meanwhile isn't recreated without being counted.
+func (r *DynamoDBRepository) PutRecordSet(ctx context.Context, rs domain.RecordSet) error {
+	rs.RecordKey = domain.RecordSetKey(rs.LocationName, rs.Name, rs.Type)
+
+	item, err := attributevalue.MarshalMap(rs)
+	if err != nil {
+		r.logger.Error("failed to marshal record set", "error", err)
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(recordSetsTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(RecordKey)"),
+	}
+
+	_, err = r.client.PutItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrRecordSetNotFound
+		}
+		r.logger.Error("failed to put record set",
+			"error", err,
+			"ownerId", rs.OwnerID,
+			"location", rs.LocationName,
+			"recordKey", rs.RecordKey,
+		)
+		return err
+	}
+
+	r.logger.Info("record set saved",
+		"ownerId", rs.OwnerID,
+		"location", rs.LocationName,
+		"recordKey", rs.RecordKey,
+	)
+	return nil
+}
+
+// GetRecordSet retrieves a custom record set by location, name and type.
+func (r *DynamoDBRepository) GetRecordSet(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error) {
+	recordKey := domain.RecordSetKey(location, name, recordType)
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(recordSetsTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":   &types.AttributeValueMemberS{Value: ownerID},
+			"RecordKey": &types.AttributeValueMemberS{Value: recordKey},
+		},
+	}
+
+	result, err := r.client.GetItem(ctx, input)
+	if err != nil {
+		r.logger.Error("failed to get record set",
+			"error", err,
+			"ownerId", ownerID,
+			"recordKey", recordKey,
+		)
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, domain.ErrRecordSetNotFound
+	}
+
+	var rs domain.RecordSet
+	if err := attributevalue.UnmarshalMap(result.Item, &rs); err != nil {
+		r.logger.Error("failed to unmarshal record set", "error", err)
+		return nil, err
+	}
+
+	return &rs, nil
+}
+
+// ListRecordSets returns all custom record sets for an owner's location.
+func (r *DynamoDBRepository) ListRecordSets(ctx context.Context, ownerID, location string) ([]domain.RecordSet, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(recordSetsTableName),
+		KeyConditionExpression: aws.String("OwnerId = :owner AND begins_with(RecordKey, :prefix)"),
+		// The prefix alone would also match locations that start with "{location}#"
+		FilterExpression: aws.String("LocationName = :location"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner":    &types.AttributeValueMemberS{Value: ownerID},
+			":prefix":   &types.AttributeValueMemberS{Value: location + "#"},
+			":location": &types.AttributeValueMemberS{Value: location},
+		},
+	}
+
+	var recordSets []domain.RecordSet
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to list record sets", "error", err, "ownerId", ownerID, "location", location)
+			return nil, err
+		}
+
+		var page []domain.RecordSet
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			r.logger.Error("failed to unmarshal record sets", "error", err)
+			return nil, err
+		}
+		recordSets = append(recordSets, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return recordSets, nil
+}
+
+// CountRecordSets returns the number of custom record sets an owner has across all locations.
+func (r *DynamoDBRepository) CountRecordSets(ctx context.Context, ownerID string) (int, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(recordSetsTableName),
+		KeyConditionExpression: aws.String("OwnerId = :owner"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		Select: types.SelectCount,
+	}
+
+	count := 0
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to count record sets", "error", err, "ownerId", ownerID)
+			return 0, err
+		}
+		count += int(result.Count)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return count, nil
+}
+
+// DeleteRecordSet removes a custom record set. The delete and the decrement of
+// the owner's RecordSetCount run in one transaction. Owners without a count yet
+// just have the record set deleted; deleting a missing record set does nothing.
+func (r *DynamoDBRepository) DeleteRecordSet(ctx context.Context, ownerID, location, name, recordType string) error {
+	recordKey := domain.RecordSetKey(location, name, recordType)
+	key := map[string]types.AttributeValue{
+		"OwnerId":   &types.AttributeValueMemberS{Value: ownerID},
+		"RecordKey": &types.AttributeValueMemberS{Value: recordKey},
+	}
+
+	_, err := r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(recordSetsTableName),
+					Key:                 key,
+					ConditionExpression: aws.String("attribute_exists(RecordKey)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName: aws.String(ownersTableName),
+					Key: map[string]types.AttributeValue{
+						"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+					},
+					UpdateExpression:    aws.String("ADD RecordSetCount :minusOne"),
+					ConditionExpression: aws.String("RecordSetCount > :zero"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":minusOne": &types.AttributeValueMemberN{Value: "-1"},
+						":zero":     &types.AttributeValueMemberN{Value: "0"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		reasons, canceled := cancellationReasons(err)
+		switch {
+		case canceled && len(reasons) == 2 && conditionFailed(reasons[0]):
+			return nil
+		case canceled && len(reasons) == 2 && conditionFailed(reasons[1]):
+			_, err = r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(recordSetsTableName),
+				Key:       key,
+			})
+		}
+	}
+	if err != nil {
+		r.logger.Error("failed to delete record set",
+			"error", err,
+			"ownerId", ownerID,
+			"recordKey", recordKey,
+		)
+		return err
+	}
+
+	r.logger.Info("record set deleted",
+		"ownerId", ownerID,
+		"recordKey", recordKey,
+	)
+	return nil
+}
+
+// maxAPIKeyCreateAttempts allows one retry after counting the keys of an owner
+// from before ApiKeyCount was kept.
+const maxAPIKeyCreateAttempts = 2
+
+// CreateAPIKey stores a new additional API key. The conditional put and the
+// increment of the owner's ApiKeyCount, conditional on MaxAPIKeys, run in one
+// transaction, so concurrent creates can't exceed the quota.
+func (r *DynamoDBRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	item, err := attributevalue.MarshalMap(key)
+	if err != nil {
+		r.logger.Error("failed to marshal API key", "error", err)
+		return err
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName:           aws.String(apiKeysTableName),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(KeyId)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName: aws.String(ownersTableName),
+					Key: map[string]types.AttributeValue{
+						"OwnerId": &types.AttributeValueMemberS{Value: key.OwnerID},
+					},
+					UpdateExpression:    aws.String("ADD ApiKeyCount :one"),
+					ConditionExpression: aws.String("ApiKeyCount < :limit"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":one":   &types.AttributeValueMemberN{Value: "1"},
+						":limit": &types.AttributeValueMemberN{Value: strconv.Itoa(domain.MaxAPIKeys)},
+					},
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			},
+		},
+	}
+
+	for attempt := 0; attempt < maxAPIKeyCreateAttempts; attempt++ {
+		_, err = r.client.TransactWriteItems(ctx, input)
+		if err == nil {
+			r.logger.Info("API key created",
+				"ownerId", key.OwnerID,
+				"keyId", key.KeyID,
+				"scopes", key.Scopes,
+			)
+			return nil
+		}
+
+		reasons, canceled := cancellationReasons(err)
+		if !canceled || len(reasons) != 2 || conditionFailed(reasons[0]) || !conditionFailed(reasons[1]) {
+			r.logger.Error("failed to create API key",
+				"error", err,
+				"ownerId", key.OwnerID,
+				"keyId", key.KeyID,
+			)
+			return err
+		}
+
+		owner := reasons[1].Item
+		if owner == nil {
+			return domain.ErrOwnerNotFound
+		}
+		if _, counted := owner["ApiKeyCount"]; counted {
+			return domain.ErrAPIKeyQuotaExceeded
+		}
+		if err := r.startAPIKeyCount(ctx, key.OwnerID); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Warn("gave up creating API key after counting", "ownerId", key.OwnerID)
+	return errors.New("API key count conflict")
+}
+
+// startAPIKeyCount sets ApiKeyCount for an owner from before it was kept by
+// counting the owner's additional keys. It does nothing if the owner already
+// has a count.
+func (r *DynamoDBRepository) startAPIKeyCount(ctx context.Context, ownerID string) error {
+	keys, err := r.ListAPIKeys(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("SET ApiKeyCount = :count"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId) AND attribute_not_exists(ApiKeyCount)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":count": &types.AttributeValueMemberN{Value: strconv.Itoa(len(keys))},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil
+		}
+		r.logger.Error("failed to start API key count", "error", err, "ownerId", ownerID)
+		return err
+	}
+	return nil
+}
+
+// ListAPIKeys returns all additional API keys for an owner.
+func (r *DynamoDBRepository) ListAPIKeys(ctx context.Context, ownerID string) ([]domain.APIKey, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(apiKeysTableName),
+		KeyConditionExpression: aws.String("OwnerId = :owner"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	}
+
+	var keys []domain.APIKey
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to list API keys", "error", err, "ownerId", ownerID)
+			return nil, err
+		}
+
+		var page []domain.APIKey
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			r.logger.Error("failed to unmarshal API keys", "error", err)
+			return nil, err
+		}
+		keys = append(keys, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return keys, nil
+}
+
+// GetKeyOwnerID returns the owner of the key with the given embedded key ID.
+// Additional keys are checked first, then primary keys.
+func (r *DynamoDBRepository) GetKeyOwnerID(ctx context.Context, keyID string) (string, error) {
+	lookups := []struct {
+		table, index, attribute string
+	}{
+		{apiKeysTableName, apiKeyIDIndexName, "KeyId"},
+		{ownersTableName, ownerKeyIDIndexName, "ApiKeyId"},
+		{ownersTableName, previousKeyIDIndexName, "PreviousApiKeyId"},
+	}
+
+	for _, lookup := range lookups {
+		result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(lookup.table),
+			IndexName:              aws.String(lookup.index),
+			KeyConditionExpression: aws.String(lookup.attribute + " = :keyId"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":keyId": &types.AttributeValueMemberS{Value: keyID},
+			},
+			Limit: aws.Int32(1),
+		})
+		if err != nil {
+			r.logger.Error("failed to look up key owner", "error", err, "keyId", keyID, "index", lookup.index)
+			return "", err
+		}
+		if len(result.Items) == 0 {
+			continue
+		}
+
+		var item struct {
+			OwnerID string `dynamodbav:"OwnerId"`
+		}
+		if err := attributevalue.UnmarshalMap(result.Items[0], &item); err != nil {
+			r.logger.Error("failed to unmarshal key owner", "error", err)
+			return "", err
+		}
+		return item.OwnerID, nil
+	}
+
+	return "", domain.ErrAPIKeyNotFound
+}
+
+// DeleteAPIKey removes an additional API key. The delete and the decrement of
+// the owner's ApiKeyCount run in one transaction; owners without a count yet
+// just have the key deleted. Returns ErrAPIKeyNotFound if the key does not exist.
+func (r *DynamoDBRepository) DeleteAPIKey(ctx context.Context, ownerID, keyID string) error {
+	key := map[string]types.AttributeValue{
+		"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		"KeyId":   &types.AttributeValueMemberS{Value: keyID},
+	}
+
+	_, err := r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(apiKeysTableName),
+					Key:                 key,
+					ConditionExpression: aws.String("attribute_exists(KeyId)"),
+				},
+			},
+			{
+				Update: &types.Update{
+					TableName: aws.String(ownersTableName),
+					Key: map[string]types.AttributeValue{
+						"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+					},
+					UpdateExpression:    aws.String("ADD ApiKeyCount :minusOne"),
+					ConditionExpression: aws.String("ApiKeyCount > :zero"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":minusOne": &types.AttributeValueMemberN{Value: "-1"},
+						":zero":     &types.AttributeValueMemberN{Value: "0"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		reasons, canceled := cancellationReasons(err)
+		switch {
+		case canceled && len(reasons) == 2 && conditionFailed(reasons[0]):
+			return domain.ErrAPIKeyNotFound
+		case canceled && len(reasons) == 2 && conditionFailed(reasons[1]):
+			_, err = r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName:           aws.String(apiKeysTableName),
+				Key:                 key,
+				ConditionExpression: aws.String("attribute_exists(KeyId)"),
+			})
+			var condErr *types.ConditionalCheckFailedException
+			if errors.As(err, &condErr) {
+				return domain.ErrAPIKeyNotFound
+			}
+		}
+	}
+	if err != nil {
+		r.logger.Error("failed to delete API key",
+			"error", err,
+			"ownerId", ownerID,
+			"keyId", keyID,
+		)
+		return err
+	}
+
+	r.logger.Info("API key deleted",
+		"ownerId", ownerID,
+		"keyId", keyID,
+	)
+	return nil
+}
+
+// RecordAPIKeyUse stores when and from where a key was last used.
+func (r *DynamoDBRepository) RecordAPIKeyUse(ctx context.Context, ownerID, keyID string, usedAt time.Time, ip string) error {
+	input := r.apiKeyUpdate(ownerID, keyID,
+		[]string{"LastUsedAt = :used", "LastUsedIp = :ip"},
+		map[string]types.AttributeValue{
+			":used": timeAttribute(usedAt),
+			":ip":   &types.AttributeValueMemberS{Value: ip},
+		},
+	)
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrAPIKeyNotFound
+		}
+		r.logger.Error("failed to record API key use", "error", err, "ownerId", ownerID, "keyId", keyID)
+		return err
+	}
+	return nil
+}
+
+// MarkAPIKeyExpiryWarned records that the owner was warned about the key's expiry.
+func (r *DynamoDBRepository) MarkAPIKeyExpiryWarned(ctx context.Context, ownerID, keyID string, warnedAt time.Time) error {
+	input := r.apiKeyUpdate(ownerID, keyID,
+		[]string{"ExpiryWarnedAt = :warned"},
+		map[string]types.AttributeValue{
+			":warned": timeAttribute(warnedAt),
+		},
+	)
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrAPIKeyNotFound
+		}
+		r.logger.Error("failed to mark API key expiry warning", "error", err, "ownerId", ownerID, "keyId", keyID)
+		return err
+	}
+	return nil
+}
+
+// apiKeyUpdate builds an update that applies assignments to either the owner item
+// (for the primary and previous keys, where attribute names carry an "ApiKey" or
+// "PreviousApiKey" prefix) or the additional key item.
+func (r *DynamoDBRepository) apiKeyUpdate(ownerID, keyID string, assignments []string, values map[string]types.AttributeValue) *dynamodb.UpdateItemInput {
+	table := aws.String(apiKeysTableName)
+	key := map[string]types.AttributeValue{
+		"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		"KeyId":   &types.AttributeValueMemberS{Value: keyID},
+	}
+	condition := "attribute_exists(KeyId)"
+	prefix := ""
+	switch keyID {
+	case domain.PrimaryKeyID:
+		table = aws.String(ownersTableName)
+		key = map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		}
+		condition = "attribute_exists(OwnerId)"
+		prefix = "ApiKey"
+	case domain.PreviousKeyID:
+		table = aws.String(ownersTableName)
+		key = map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		}
+		condition = "attribute_exists(PreviousApiKeyHash)"
+		prefix = "PreviousApiKey"
+	}
+
+	sets := make([]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		sets = append(sets, prefix+assignment)
+	}
+
+	return &dynamodb.UpdateItemInput{
+		TableName:                 table,
+		Key:                       key,
+		UpdateExpression:          aws.String("SET " + strings.Join(sets, ", ")),
+		ExpressionAttributeValues: values,
+		ConditionExpression:       aws.String(condition),
+	}
+}
+
+// ScanExpiringAPIKeys returns unexpired keys that expire before the given time and
+// whose owners haven't been warned yet. Both owners (primary keys) and additional
+// keys are scanned; the time comparison happens here rather than in DynamoDB
+// because timestamps are stored as strings.
+func (r *DynamoDBRepository) ScanExpiringAPIKeys(ctx context.Context, before time.Time) ([]domain.APIKey, error) {
+	now := time.Now().UTC()
+	window := before.Sub(now)
+
+	var owners []domain.Owner
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(ownersTableName),
+		FilterExpression: aws.String("attribute_exists(ApiKeyExpiresAt) AND attribute_not_exists(ApiKeyExpiryWarnedAt)"),
+	}, &owners); err != nil {
+		r.logger.Error("failed to scan owners for expiring keys", "error", err)
+		return nil, err
+	}
+
+	var additional []domain.APIKey
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(apiKeysTableName),
+		FilterExpression: aws.String("attribute_exists(ExpiresAt) AND attribute_not_exists(ExpiryWarnedAt)"),
+	}, &additional); err != nil {
+		r.logger.Error("failed to scan expiring API keys", "error", err)
+		return nil, err
+	}
+
+	var keys []domain.APIKey
+	for _, owner := range owners {
+		if key := domain.PrimaryAPIKey(owner); key.ExpiresWithin(now, window) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range additional {
+		if key.ExpiresWithin(now, window) {
+			keys = append(keys, key)
+		}
+	}
+
+	r.logger.Info("scanned expiring API keys", "count", len(keys))
+	return keys, nil
+}
+
+// UseNonce records a signed request's nonce for a key. The item expires via the
+// table's TTL once the signature's timestamp is outside the clock-skew window.
+func (r *DynamoDBRepository) UseNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) error {
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(noncesTableName),
+		Item: map[string]types.AttributeValue{
+			"Nonce": &types.AttributeValueMemberS{Value: keyID + "#" + nonce},
+			"TTL":   &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(Nonce)"),
+	}
+
+	_, err := r.client.PutItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrNonceReused
+		}
+		r.logger.Error("failed to store nonce", "error", err, "keyId", keyID)
+		return err
+	}
+	return nil
+}
+
+// ListMappingsByOwner returns every IP mapping for an owner.
+func (r *DynamoDBRepository) ListMappingsByOwner(ctx context.Context, ownerID string) ([]domain.IPMapping, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(mappingsTableName),
+		KeyConditionExpression: aws.String("OwnerId = :owner"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	}
+
+	var mappings []domain.IPMapping
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to list mappings", "error", err, "ownerId", ownerID)
+			return nil, err
+		}
+
+		var page []domain.IPMapping
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			r.logger.Error("failed to unmarshal mappings", "error", err)
+			return nil, err
+		}
+		mappings = append(mappings, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return mappings, nil
+}
+
+// ListOwners returns every owner.
+func (r *DynamoDBRepository) ListOwners(ctx context.Context) ([]domain.Owner, error) {
+	var owners []domain.Owner
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{TableName: aws.String(ownersTableName)}, &owners); err != nil {
+		r.logger.Error("failed to scan owners", "error", err)
+		return nil, err
+	}
+	return owners, nil
+}
+
+// SetOwnerSuspension suspends an owner when suspendedAt is set and lifts the
+// suspension when it is nil. Returns ErrOwnerNotFound if the owner doesn't exist.
+func (r *DynamoDBRepository) SetOwnerSuspension(ctx context.Context, ownerID string, suspendedAt *time.Time, reason string) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("REMOVE SuspendedAt, SuspendedReason"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	}
+	if suspendedAt != nil {
+		input.UpdateExpression = aws.String("SET SuspendedAt = :at, SuspendedReason = :reason")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":at":     timeAttribute(*suspendedAt),
+			":reason": &types.AttributeValueMemberS{Value: reason},
+		}
+	}
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrOwnerNotFound
+		}
+		r.logger.Error("failed to update owner suspension", "error", err, "ownerId", ownerID)
+		return err
+	}
+
+	r.logger.Info("owner suspension updated", "ownerId", ownerID, "suspended", suspendedAt != nil)
+	return nil
+}
+
+// SetOwnerRateLimit assigns an owner's rate limit plan and sets or clears the
+// owner's policy override. Returns ErrOwnerNotFound if the owner doesn't exist.
+func (r *DynamoDBRepository) SetOwnerRateLimit(ctx context.Context, ownerID, plan string, policy *domain.RateLimitPolicy) error {
+	var set, remove []string
+	values := map[string]types.AttributeValue{}
+
+	if plan != "" {
+		set = append(set, "RateLimitPlan = :plan")
+		values[":plan"] = &types.AttributeValueMemberS{Value: plan}
+	} else {
+		remove = append(remove, "RateLimitPlan")
+	}
+
+	if policy != nil {
+		av, err := attributevalue.Marshal(policy)
+		if err != nil {
+			r.logger.Error("failed to marshal rate limit policy", "error", err)
+			return err
+		}
+		set = append(set, "RateLimit = :policy")
+		values[":policy"] = av
+	} else {
+		remove = append(remove, "RateLimit")
+	}
+
+	var update []string
+	if len(set) > 0 {
+		update = append(update, "SET "+strings.Join(set, ", "))
+	}
+	if len(remove) > 0 {
+		update = append(update, "REMOVE "+strings.Join(remove, ", "))
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String(strings.Join(update, " ")),
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrOwnerNotFound
+		}
+		r.logger.Error("failed to update owner rate limit", "error", err, "ownerId", ownerID)
+		return err
+	}
+
+	r.logger.Info("owner rate limit updated", "ownerId", ownerID, "plan", plan, "override", policy != nil)
+	return nil
+}
+
+// maxACMEChallengeAttempts bounds the retries when concurrent challenges race
+// to start a new counting window.
+const maxACMEChallengeAttempts = 3
+
+// ClaimACMEChallenge atomically counts a new ACME challenge against the owner's
+// limit. The limit is checked in the same conditional update that increments the
+// count, so concurrent requests can't overrun it. A count from an earlier window
+// starts over at one.
+func (r *DynamoDBRepository) ClaimACMEChallenge(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error) {
+	window := now.Truncate(limit.Window).Unix()
+	for attempt := 0; attempt < maxACMEChallengeAttempts; attempt++ {
+		count, err := r.countACMEChallenge(ctx, ownerID, limit, window, now)
+		if err != nil || count > 0 {
+			return count, err
+		}
+		count, err = r.startACMEChallenges(ctx, ownerID, window, now)
+		if err != nil || count > 0 {
+			return count, err
+		}
+	}
+	r.logger.Warn("gave up claiming ACME challenge after conflicts", "ownerId", ownerID)
+	return 0, errors.New("ACME challenge count conflict")
+}
+
+// countACMEChallenge increments the owner's count within the current window. It
+// returns zero if the owner's count is from another window.
+func (r *DynamoDBRepository) countACMEChallenge(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, window int64, now time.Time) (int, error) {
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("SET LastAcmeChallengeAt = :at ADD AcmeChallengeCount :one"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId) AND AcmeChallengeWindow = :window AND AcmeChallengeCount < :max"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":at":     timeAttribute(now),
+			":one":    &types.AttributeValueMemberN{Value: "1"},
+			":window": &types.AttributeValueMemberN{Value: strconv.FormatInt(window, 10)},
+			":max":    &types.AttributeValueMemberN{Value: strconv.Itoa(limit.MaxChallenges)},
+		},
+		ReturnValues:                        types.ReturnValueUpdatedNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			if condErr.Item == nil {
+				return 0, domain.ErrOwnerNotFound
+			}
+			var owner domain.Owner
+			if err := attributevalue.UnmarshalMap(condErr.Item, &owner); err != nil {
+				r.logger.Error("failed to unmarshal owner", "error", err)
+				return 0, err
+			}
+			if owner.ACMEChallengeWindow == window {
+				return 0, domain.ErrACMERateLimitExceeded
+			}
+			return 0, nil
+		}
+		r.logger.Error("failed to count ACME challenge", "error", err, "ownerId", ownerID)
+		return 0, err
+	}
+
+	var owner domain.Owner
+	if err := attributevalue.UnmarshalMap(result.Attributes, &owner); err != nil {
+		r.logger.Error("failed to unmarshal ACME challenge count", "error", err)
+		return 0, err
+	}
+	return owner.ACMEChallengeCount, nil
+}
+
+// startACMEChallenges starts a new count for an owner whose count is from an
+// earlier window. It returns zero if another request started one first.
+func (r *DynamoDBRepository) startACMEChallenges(ctx context.Context, ownerID string, window int64, now time.Time) (int, error) {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("SET AcmeChallengeCount = :one, AcmeChallengeWindow = :window, LastAcmeChallengeAt = :at"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId) AND (attribute_not_exists(AcmeChallengeWindow) OR AcmeChallengeWindow < :window)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":    &types.AttributeValueMemberN{Value: "1"},
+			":window": &types.AttributeValueMemberN{Value: strconv.FormatInt(window, 10)},
+			":at":     timeAttribute(now),
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			if condErr.Item == nil {
+				return 0, domain.ErrOwnerNotFound
+			}
+			return 0, nil
+		}
+		r.logger.Error("failed to start ACME challenge count", "error", err, "ownerId", ownerID)
+		return 0, err
+	}
+	return 1, nil
+}
+
+// SetOwnerNotifications replaces or clears an owner's notification preferences.
+// Returns ErrOwnerNotFound if the owner doesn't exist.
+func (r *DynamoDBRepository) SetOwnerNotifications(ctx context.Context, ownerID string, prefs *domain.NotificationPreferences) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("REMOVE Notifications"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	}
+	if prefs != nil {
+		av, err := attributevalue.Marshal(prefs)
+		if err != nil {
+			r.logger.Error("failed to marshal notification preferences", "error", err)
+			return err
+		}
+		input.UpdateExpression = aws.String("SET Notifications = :prefs")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{":prefs": av}
+	}
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrOwnerNotFound
+		}
+		r.logger.Error("failed to update owner notifications", "error", err, "ownerId", ownerID)
+		return err
+	}
+
+	r.logger.Info("owner notifications updated", "ownerId", ownerID)
+	return nil
+}
+
+// SetOwnerLocale sets or clears the locale of an owner's emails.
+// Returns ErrOwnerNotFound if the owner doesn't exist.
+func (r *DynamoDBRepository) SetOwnerLocale(ctx context.Context, ownerID, locale string) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("REMOVE Locale"),
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	}
+	if locale != "" {
+		input.UpdateExpression = aws.String("SET Locale = :locale")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":locale": &types.AttributeValueMemberS{Value: locale},
+		}
+	}
+
+	_, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrOwnerNotFound
+		}
+		r.logger.Error("failed to update owner locale", "error", err, "ownerId", ownerID)
+		return err
+	}
+
+	r.logger.Info("owner locale updated", "ownerId", ownerID, "locale", locale)
+	return nil
+}
+
+// ScanPendingIPChanges returns the mappings with IP changes the owner hasn't
+// been emailed about yet.
+func (r *DynamoDBRepository) ScanPendingIPChanges(ctx context.Context) ([]domain.IPMapping, error) {
+	var mappings []domain.IPMapping
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(mappingsTableName),
+		FilterExpression: aws.String("attribute_exists(PendingIpChanges)"),
+	}, &mappings); err != nil {
+		r.logger.Error("failed to scan pending IP changes", "error", err)
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// MarkIPChangesNotified records when the owner was emailed about a mapping's IP
+// changes and clears the pending changes.
+func (r *DynamoDBRepository) MarkIPChangesNotified(ctx context.Context, ownerID, location string, notifiedAt time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(mappingsTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":      &types.AttributeValueMemberS{Value: ownerID},
+			"LocationName": &types.AttributeValueMemberS{Value: location},
+		},
+		UpdateExpression: aws.String("SET IpChangeNotifiedAt = :notified REMOVE PendingIpChanges"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":notified": timeAttribute(notifiedAt),
+		},
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			// The location was deleted after the scan; there is nothing left to mark
+			return nil
+		}
+		r.logger.Error("failed to mark IP changes notified", "error", err, "ownerId", ownerID, "location", location)
+		return err
+	}
+	return nil
+}
+
+// TouchLocation records that a location checked in at seenAt and clears any
+// stale alert. Only the two attributes are written, which keeps frequent
+// check-ins cheap.
+func (r *DynamoDBRepository) TouchLocation(ctx context.Context, ownerID, location string, seenAt time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(mappingsTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":      &types.AttributeValueMemberS{Value: ownerID},
+			"LocationName": &types.AttributeValueMemberS{Value: location},
+		},
+		UpdateExpression: aws.String("SET LastSeenAt = :seen REMOVE StaleAlertedAt"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":seen": timeAttribute(seenAt),
+		},
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			// The location was deleted since it was read; don't recreate it
+			return nil
+		}
+		r.logger.Error("failed to record location check-in", "error", err, "ownerId", ownerID, "location", location)
+		return err
+	}
+	return nil
+}
+
+// ScanMonitoredLocations returns the mappings with a stale threshold.
+func (r *DynamoDBRepository) ScanMonitoredLocations(ctx context.Context) ([]domain.IPMapping, error) {
+	var mappings []domain.IPMapping
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(mappingsTableName),
+		FilterExpression: aws.String("StaleAfterSeconds > :zero"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+		},
+	}, &mappings); err != nil {
+		r.logger.Error("failed to scan monitored locations", "error", err)
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// MarkLocationStaleAlerted records that the owner was told the location has
+// gone silent.
+func (r *DynamoDBRepository) MarkLocationStaleAlerted(ctx context.Context, ownerID, location string, alertedAt time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(mappingsTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":      &types.AttributeValueMemberS{Value: ownerID},
+			"LocationName": &types.AttributeValueMemberS{Value: location},
+		},
+		UpdateExpression: aws.String("SET StaleAlertedAt = :alerted"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":alerted": timeAttribute(alertedAt),
+		},
+		ConditionExpression: aws.String("attribute_exists(OwnerId)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			// The location was deleted after the scan; there is nothing left to mark
+			return nil
+		}
+		r.logger.Error("failed to mark location stale alerted", "error", err, "ownerId", ownerID, "location", location)
+		return err
+	}
+	return nil
+}
+
+// CreateWebhook stores a new webhook.
+// Uses a conditional write to avoid overwriting an existing webhook ID.
+func (r *DynamoDBRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	item, err := attributevalue.MarshalMap(webhook)
+	if err != nil {
+		r.logger.Error("failed to marshal webhook", "error", err)
+		return err
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(webhooksTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(WebhookId)"),
+	})
+	if err != nil {
+		r.logger.Error("failed to create webhook",
+			"error", err,
+			"ownerId", webhook.OwnerID,
+			"webhookId", webhook.WebhookID,
+		)
+		return err
+	}
+
+	r.logger.Info("webhook created",
+		"ownerId", webhook.OwnerID,
+		"webhookId", webhook.WebhookID,
+		"events", webhook.Events,
+	)
+	return nil
+}
+
+// ListWebhooks returns all webhooks for an owner.
+func (r *DynamoDBRepository) ListWebhooks(ctx context.Context, ownerID string) ([]domain.Webhook, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(webhooksTableName),
+		KeyConditionExpression: aws.String("OwnerId = :owner"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: ownerID},
+		},
+	}
+
+	var webhooks []domain.Webhook
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to list webhooks", "error", err, "ownerId", ownerID)
+			return nil, err
+		}
+
+		var page []domain.Webhook
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			r.logger.Error("failed to unmarshal webhooks", "error", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook retrieves an owner's webhook.
+func (r *DynamoDBRepository) GetWebhook(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(webhooksTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":   &types.AttributeValueMemberS{Value: ownerID},
+			"WebhookId": &types.AttributeValueMemberS{Value: webhookID},
+		},
+	})
+	if err != nil {
+		r.logger.Error("failed to get webhook", "error", err, "ownerId", ownerID, "webhookId", webhookID)
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	var webhook domain.Webhook
+	if err := attributevalue.UnmarshalMap(result.Item, &webhook); err != nil {
+		r.logger.Error("failed to unmarshal webhook", "error", err)
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook. Its deliveries are left to expire from the
+// history; pending ones are dead-lettered when they are next due.
+func (r *DynamoDBRepository) DeleteWebhook(ctx context.Context, ownerID, webhookID string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(webhooksTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId":   &types.AttributeValueMemberS{Value: ownerID},
+			"WebhookId": &types.AttributeValueMemberS{Value: webhookID},
+		},
+		ConditionExpression: aws.String("attribute_exists(WebhookId)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrWebhookNotFound
+		}
+		r.logger.Error("failed to delete webhook",
+			"error", err,
+			"ownerId", ownerID,
+			"webhookId", webhookID,
+		)
+		return err
+	}
+
+	r.logger.Info("webhook deleted",
+		"ownerId", ownerID,
+		"webhookId", webhookID,
+	)
+	return nil
+}
+
+// PutWebhookDelivery creates or updates a webhook delivery.
+func (r *DynamoDBRepository) PutWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	item, err := attributevalue.MarshalMap(delivery)
+	if err != nil {
+		r.logger.Error("failed to marshal webhook delivery", "error", err)
+		return err
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(deliveriesTableName),
+		Item:      item,
+	})
+	if err != nil {
+		r.logger.Error("failed to put webhook delivery",
+			"error", err,
+			"webhookId", delivery.WebhookID,
+			"deliveryId", delivery.DeliveryID,
+		)
+		return err
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns a webhook's latest deliveries, newest first.
+func (r *DynamoDBRepository) ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error) {
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(deliveriesTableName),
+		KeyConditionExpression: aws.String("WebhookId = :webhook"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":webhook": &types.AttributeValueMemberS{Value: webhookID},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		r.logger.Error("failed to list webhook deliveries", "error", err, "webhookId", webhookID)
+		return nil, err
+	}
+
+	var deliveries []domain.WebhookDelivery
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &deliveries); err != nil {
+		r.logger.Error("failed to unmarshal webhook deliveries", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ScanPendingWebhookDeliveries returns the deliveries that are waiting to be
+// retried. Whether a retry is due is checked by the caller, because timestamps
+// are stored as strings.
+func (r *DynamoDBRepository) ScanPendingWebhookDeliveries(ctx context.Context) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(deliveriesTableName),
+		FilterExpression: aws.String("#status = :pending"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: domain.WebhookDeliveryPending},
+		},
+	}, &deliveries); err != nil {
+		r.logger.Error("failed to scan pending webhook deliveries", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetRateLimitPlan retrieves a rate limit plan by name. Returns
+// ErrRateLimitPlanNotFound if not found.
+func (r *DynamoDBRepository) GetRateLimitPlan(ctx context.Context, name string) (*domain.RateLimitPlan, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(rateLimitPlansTableName),
+		Key: map[string]types.AttributeValue{
+			"PlanName": &types.AttributeValueMemberS{Value: name},
+		},
+	})
+	if err != nil {
+		r.logger.Error("failed to get rate limit plan", "error", err, "plan", name)
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, domain.ErrRateLimitPlanNotFound
+	}
+
+	var plan domain.RateLimitPlan
+	if err := attributevalue.UnmarshalMap(result.Item, &plan); err != nil {
+		r.logger.Error("failed to unmarshal rate limit plan", "error", err)
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListRateLimitPlans returns every stored rate limit plan.
+func (r *DynamoDBRepository) ListRateLimitPlans(ctx context.Context) ([]domain.RateLimitPlan, error) {
+	var plans []domain.RateLimitPlan
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{TableName: aws.String(rateLimitPlansTableName)}, &plans); err != nil {
+		r.logger.Error("failed to scan rate limit plans", "error", err)
+		return nil, err
+	}
+	return plans, nil
+}
+
+// PutRateLimitPlan creates or replaces a rate limit plan.
+func (r *DynamoDBRepository) PutRateLimitPlan(ctx context.Context, plan domain.RateLimitPlan) error {
+	item, err := attributevalue.MarshalMap(plan)
+	if err != nil {
+		r.logger.Error("failed to marshal rate limit plan", "error", err)
+		return err
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(rateLimitPlansTableName),
+		Item:      item,
+	})
+	if err != nil {
+		r.logger.Error("failed to put rate limit plan", "error", err, "plan", plan.Name)
+		return err
+	}
+
+	r.logger.Info("rate limit plan saved", "plan", plan.Name)
+	return nil
+}
+
+// DeleteRateLimitPlan removes a rate limit plan. Returns ErrRateLimitPlanNotFound
+// if not found.
+func (r *DynamoDBRepository) DeleteRateLimitPlan(ctx context.Context, name string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(rateLimitPlansTableName),
+		Key: map[string]types.AttributeValue{
+			"PlanName": &types.AttributeValueMemberS{Value: name},
+		},
+		ConditionExpression: aws.String("attribute_exists(PlanName)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrRateLimitPlanNotFound
+		}
+		r.logger.Error("failed to delete rate limit plan", "error", err, "plan", name)
+		return err
+	}
+
+	r.logger.Info("rate limit plan deleted", "plan", name)
+	return nil
+}
+
+// VerifyOwner marks a pending owner's email address as verified, making the owner
+// permanent. Returns ErrOwnerNotFound if there is no pending, unexpired owner with
+// the given primary key hash.
+func (r *DynamoDBRepository) VerifyOwner(ctx context.Context, ownerID, keyHash string, verifiedAt time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(ownersTableName),
+		Key: map[string]types.AttributeValue{
+			"OwnerId": &types.AttributeValueMemberS{Value: ownerID},
+		},
+		UpdateExpression:    aws.String("SET VerifiedAt = :verified REMOVE VerifyBy, #ttl"),
+		ConditionExpression: aws.String("attribute_exists(VerifyBy) AND #ttl > :now AND ApiKeyHash = :hash"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "TTL",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":verified": timeAttribute(verifiedAt),
+			":now":      &types.AttributeValueMemberN{Value: strconv.FormatInt(verifiedAt.Unix(), 10)},
+			":hash":     &types.AttributeValueMemberS{Value: keyHash},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrOwnerNotFound
+		}
+		r.logger.Error("failed to verify owner", "error", err, "ownerId", ownerID)
+		return err
+	}
+
+	r.logger.Info("owner verified", "ownerId", ownerID)
+	return nil
+}
+
+// CreateOperator stores a new operator.
+func (r *DynamoDBRepository) CreateOperator(ctx context.Context, operator domain.Operator) error {
+	item, err := attributevalue.MarshalMap(operator)
+	if err != nil {
+		r.logger.Error("failed to marshal operator", "error", err)
+		return err
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(operatorsTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(KeyId)"),
+	})
+	if err != nil {
+		r.logger.Error("failed to create operator", "error", err, "keyId", operator.KeyID)
+		return err
+	}
+
+	r.logger.Info("operator created", "keyId", operator.KeyID, "name", operator.Name)
+	return nil
+}
+
+// GetOperator retrieves an operator by key ID. Returns ErrOperatorNotFound if not found.
+func (r *DynamoDBRepository) GetOperator(ctx context.Context, keyID string) (*domain.Operator, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(operatorsTableName),
+		Key: map[string]types.AttributeValue{
+			"KeyId": &types.AttributeValueMemberS{Value: keyID},
+		},
+	})
+	if err != nil {
+		r.logger.Error("failed to get operator", "error", err, "keyId", keyID)
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, domain.ErrOperatorNotFound
+	}
+
+	var operator domain.Operator
+	if err := attributevalue.UnmarshalMap(result.Item, &operator); err != nil {
+		r.logger.Error("failed to unmarshal operator", "error", err)
+		return nil, err
+	}
+	return &operator, nil
+}
+
+// DeleteOperator removes an operator. Returns ErrOperatorNotFound if not found.
+func (r *DynamoDBRepository) DeleteOperator(ctx context.Context, keyID string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(operatorsTableName),
+		Key: map[string]types.AttributeValue{
+			"KeyId": &types.AttributeValueMemberS{Value: keyID},
+		},
+		ConditionExpression: aws.String("attribute_exists(KeyId)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return domain.ErrOperatorNotFound
+		}
+		r.logger.Error("failed to delete operator", "error", err, "keyId", keyID)
+		return err
+	}
+
+	r.logger.Info("operator deleted", "keyId", keyID)
+	return nil
+}
+
+// PutAuditEntry appends an entry to the audit log.
+func (r *DynamoDBRepository) PutAuditEntry(ctx context.Context, entry domain.AuditEntry) error {
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		r.logger.Error("failed to marshal audit entry", "error", err)
+		return err
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(auditLogTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(EntryId)"),
+	})
+	if err != nil {
+		r.logger.Error("failed to put audit entry", "error", err, "entryId", entry.EntryID)
+		return err
+	}
+	return nil
+}
+
+// ListAuditEntries returns the audit log entries for a UTC day, newest first.
+func (r *DynamoDBRepository) ListAuditEntries(ctx context.Context, date string) ([]domain.AuditEntry, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(auditLogTableName),
+		KeyConditionExpression: aws.String("AuditDate = :date"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":date": &types.AttributeValueMemberS{Value: date},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	var entries []domain.AuditEntry
+	for {
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			r.logger.Error("failed to list audit entries", "error", err, "date", date)
+			return nil, err
+		}
+
+		var page []domain.AuditEntry
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			r.logger.Error("failed to unmarshal audit entries", "error", err)
+			return nil, err
+		}
+		entries = append(entries, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return entries, nil
+}
+
+// GetAuthFailure returns the failed authentication attempts counted against a
+// subject, or nil if there are none.
+func (r *DynamoDBRepository) GetAuthFailure(ctx context.Context, subject string) (*domain.AuthFailure, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(authFailuresTableName),
+		Key: map[string]types.AttributeValue{
+			"Subject": &types.AttributeValueMemberS{Value: subject},
+		},
+	})
+	if err != nil {
+		r.logger.Error("failed to get auth failures", "error", err, "subject", subject)
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var failure domain.AuthFailure
+	if err := attributevalue.UnmarshalMap(result.Item, &failure); err != nil {
+		r.logger.Error("failed to unmarshal auth failures", "error", err)
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// maxAuthFailureAttempts bounds the retries when concurrent failures race to
+// start a new counting window.
+const maxAuthFailureAttempts = 3
+
+// RecordAuthFailure atomically counts a failed attempt against a subject. A count
+// whose window has passed starts over at one. When the count exceeds the policy's
+// free attempts the subject is locked out.
+func (r *DynamoDBRepository) RecordAuthFailure(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+	var failure *domain.AuthFailure
+	var err error
+	for attempt := 0; attempt < maxAuthFailureAttempts; attempt++ {
+		failure, err = r.countAuthFailure(ctx, subject, ip, policy, now)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			break
+		}
+		failure, err = r.startAuthFailures(ctx, subject, ownerID, ip, policy, now)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			break
+		}
+	}
+	if failure == nil {
+		r.logger.Warn("gave up counting auth failure after conflicts", "subject", subject)
+		return nil, errors.New("auth failure count conflict")
+	}
+
+	delay := policy.LockoutDelay(failure.Failures)
+	if delay == 0 {
+		return failure, nil
+	}
+
+	lockedUntil := now.Add(delay)
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(authFailuresTableName),
+		Key: map[string]types.AttributeValue{
+			"Subject": &types.AttributeValueMemberS{Value: subject},
+		},
+		UpdateExpression: aws.String("SET LockedUntil = :until"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":until": timeAttribute(lockedUntil),
+		},
+		ConditionExpression: aws.String("attribute_exists(Subject)"),
+	})
+	if err != nil {
+		r.logger.Error("failed to lock out subject", "error", err, "subject", subject)
+		return nil, err
+	}
+
+	failure.LockedUntil = &lockedUntil
+	return failure, nil
+}
+
+// countAuthFailure increments a subject's count within its current window. It
+// returns nil if the subject has no count or its window has passed.
+func (r *DynamoDBRepository) countAuthFailure(ctx context.Context, subject, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(authFailuresTableName),
+		Key: map[string]types.AttributeValue{
+			"Subject": &types.AttributeValueMemberS{Value: subject},
+		},
+		UpdateExpression: aws.String("SET LastFailureAt = :now, LastFailureIp = :ip, ResetAt = :reset, #ttl = :ttl ADD Failures :one"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "TTL",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":     timeAttribute(now),
+			":ip":      &types.AttributeValueMemberS{Value: ip},
+			":reset":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(policy.Window).Unix(), 10)},
+			":ttl":     &types.AttributeValueMemberN{Value: strconv.FormatInt(policy.Expiry(now).Unix(), 10)},
+			":one":     &types.AttributeValueMemberN{Value: "1"},
+			":nowUnix": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+		ConditionExpression: aws.String("ResetAt > :nowUnix"),
+		ReturnValues:        types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil, nil
+		}
+		r.logger.Error("failed to count auth failure", "error", err, "subject", subject)
+		return nil, err
+	}
+
+	var failure domain.AuthFailure
+	if err := attributevalue.UnmarshalMap(result.Attributes, &failure); err != nil {
+		r.logger.Error("failed to unmarshal auth failures", "error", err)
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// startAuthFailures starts a new count for a subject with no count or a passed
+// window. It returns nil if another request started one first.
+func (r *DynamoDBRepository) startAuthFailures(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error) {
+	failure := domain.AuthFailure{
+		Subject:        subject,
+		OwnerID:        ownerID,
+		Failures:       1,
+		FirstFailureAt: now,
+		LastFailureAt:  now,
+		LastFailureIP:  ip,
+		ResetAt:        now.Add(policy.Window).Unix(),
+		TTL:            policy.Expiry(now).Unix(),
+	}
+
+	item, err := attributevalue.MarshalMap(failure)
+	if err != nil {
+		r.logger.Error("failed to marshal auth failures", "error", err)
+		return nil, err
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(authFailuresTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(Subject) OR ResetAt <= :nowUnix"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":nowUnix": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil, nil
+		}
+		r.logger.Error("failed to store auth failure", "error", err, "subject", subject)
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// ClearAuthFailures forgets a subject's failed attempts.
+func (r *DynamoDBRepository) ClearAuthFailures(ctx context.Context, subject string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(authFailuresTableName),
+		Key: map[string]types.AttributeValue{
+			"Subject": &types.AttributeValueMemberS{Value: subject},
+		},
+	})
+	if err != nil {
+		r.logger.Error("failed to clear auth failures", "error", err, "subject", subject)
+		return err
+	}
+	return nil
+}
+
+// ScanAuthFailureAlerts returns owner subjects that reached
+// domain.AuthFailureAlertThreshold and haven't been alerted about yet.
+func (r *DynamoDBRepository) ScanAuthFailureAlerts(ctx context.Context) ([]domain.AuthFailure, error) {
+	var failures []domain.AuthFailure
+	if err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(authFailuresTableName),
+		FilterExpression: aws.String("attribute_exists(OwnerId) AND attribute_not_exists(AlertedAt) AND Failures >= :threshold"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":threshold": &types.AttributeValueMemberN{Value: strconv.Itoa(domain.AuthFailureAlertThreshold)},
+		},
+	}, &failures); err != nil {
+		r.logger.Error("failed to scan auth failures", "error", err)
+		return nil, err
+	}
+	return failures, nil
+}
+
+// MarkAuthFailureAlerted records that the owner was alerted about a subject's failures.
+func (r *DynamoDBRepository) MarkAuthFailureAlerted(ctx context.Context, subject string, alertedAt time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(authFailuresTableName),
+		Key: map[string]types.AttributeValue{
+			"Subject": &types.AttributeValueMemberS{Value: subject},
+		},
+		UpdateExpression: aws.String("SET AlertedAt = :alerted"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":alerted": timeAttribute(alertedAt),
+		},
+		ConditionExpression: aws.String("attribute_exists(Subject)"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			// The record expired after the scan; there is nothing left to mark
+			return nil
+		}
+		r.logger.Error("failed to mark auth failure alert", "error", err, "subject", subject)
+		return err
+	}
+	return nil
+}
+
+// maxRequestTokenAttempts bounds the retries when concurrent requests race to
+// refill an idle bucket.
+const maxRequestTokenAttempts = 3
+
+// TakeRequestToken takes one token from a request bucket. Each bucket stores
+// ReadyAt, the Unix millisecond time at which all its tokens are back; see
+// domain.RequestLimit.TakeRequestToken. Busy buckets are charged with a single
+// conditional atomic add; idle or missing buckets are reset to one request's
+// worth. It returns false and how long until a token is available when the
+// bucket is empty.
+func (r *DynamoDBRepository) TakeRequestToken(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error) {
+	for attempt := 0; attempt < maxRequestTokenAttempts; attempt++ {
+		readyAt, spent, err := r.spendRequestToken(ctx, bucket, limit, now)
+		if err != nil {
+			return 0, false, err
+		}
+		if spent {
+			return 0, true, nil
+		}
+
+		if readyAt != nil && !readyAt.Before(now) {
+			_, retryAfter, _ := limit.TakeRequestToken(*readyAt, now)
+			return retryAfter, false, nil
+		}
+
+		started, err := r.startRequestBucket(ctx, bucket, limit, now)
+		if err != nil {
+			return 0, false, err
+		}
+		if started {
+			return 0, true, nil
+		}
+	}
+
+	r.logger.Warn("gave up taking request token after conflicts", "bucket", bucket)
+	return 0, false, errors.New("request token conflict")
+}
+
+// spendRequestToken charges a busy bucket that still has a token. Otherwise it
+// returns the bucket's ReadyAt, or nil if there is no bucket.
+func (r *DynamoDBRepository) spendRequestToken(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (*time.Time, bool, error) {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(requestLimitsTableName),
+		Key: map[string]types.AttributeValue{
+			"Bucket": &types.AttributeValueMemberS{Value: bucket},
+		},
+		UpdateExpression: aws.String("SET #ttl = :ttl ADD ReadyAt :interval"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "TTL",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":interval": millisAttribute(limit.Interval.Milliseconds()),
+			":now":      millisAttribute(now.UnixMilli()),
+			":latest":   millisAttribute(limit.LatestReadyAt(now).UnixMilli()),
+			":ttl":      &types.AttributeValueMemberN{Value: strconv.FormatInt(limit.RequestBucketExpiry(now).Unix()+1, 10)},
+		},
+		ConditionExpression:                 aws.String("ReadyAt BETWEEN :now AND :latest"),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err == nil {
+		return nil, true, nil
+	}
+
+	var condErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &condErr) {
+		r.logger.Error("failed to take request token", "error", err, "bucket", bucket)
+		return nil, false, err
+	}
+
+	attr, ok := condErr.Item["ReadyAt"].(*types.AttributeValueMemberN)
+	if !ok {
+		return nil, false, nil
+	}
+	millis, err := strconv.ParseInt(attr.Value, 10, 64)
+	if err != nil {
+		r.logger.Error("invalid request bucket", "error", err, "bucket", bucket)
+		return nil, false, err
+	}
+	readyAt := time.UnixMilli(millis).UTC()
+	return &readyAt, false, nil
+}
+
+// startRequestBucket creates a bucket, or resets one that has refilled, with one
+// request taken. It returns false if a concurrent request got there first.
+func (r *DynamoDBRepository) startRequestBucket(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (bool, error) {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(requestLimitsTableName),
+		Key: map[string]types.AttributeValue{
+			"Bucket": &types.AttributeValueMemberS{Value: bucket},
+		},
+		UpdateExpression: aws.String("SET ReadyAt = :ready, #ttl = :ttl"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "TTL",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ready": millisAttribute(now.Add(limit.Interval).UnixMilli()),
+			":now":   millisAttribute(now.UnixMilli()),
+			":ttl":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(limit.Interval).Unix()+1, 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(Bucket) OR ReadyAt < :now"),
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return false, nil
+		}
+		r.logger.Error("failed to start request bucket", "error", err, "bucket", bucket)
+		return false, err
+	}
+	return true, nil
+}
+
+func millisAttribute(millis int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(millis, 10)}
+}
+
+// scanAll runs a paginated scan and unmarshals every item into out.
+func (r *DynamoDBRepository) scanAll(ctx context.Context, input *dynamodb.ScanInput, out any) error {
+	var items []map[string]types.AttributeValue
+	for {
+		result, err := r.client.Scan(ctx, input)
+		if err != nil {
+			return err
+		}
+		items = append(items, result.Items...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	return attributevalue.UnmarshalListOfMaps(items, out)
+}
+
+// timeAttribute encodes a time the same way attributevalue marshals time.Time.
+func timeAttribute(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: t.Format(time.RFC3339Nano)}
+}
+
 // Ensure DynamoDBRepository implements Repository.
 var _ Repository = (*DynamoDBRepository)(nil)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -23,6 +24,9 @@ type mockDynamoDBClient struct {
 	updateItemFunc func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
 	deleteItemFunc func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	scanFunc       func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	queryFunc      func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+
+	transactWriteItemsFunc func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 func (m *mockDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
@@ -60,6 +64,20 @@ func (m *mockDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInpu
 	return &dynamodb.ScanOutput{}, nil
 }
 
+func (m *mockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if m.queryFunc != nil {
+		return m.queryFunc(ctx, params, optFns...)
+	}
+	return &dynamodb.QueryOutput{}, nil
+}
+
+func (m *mockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	if m.transactWriteItemsFunc != nil {
+		return m.transactWriteItemsFunc(ctx, params, optFns...)
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
 func newTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 }
@@ -188,6 +206,156 @@ func TestDynamoDBRepository_Get_Error(t *testing.T) {
 // Owner Tests
 // =============================================================================
 
+func TestDynamoDBRepository_Delete(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	var capturedInput *dynamodb.DeleteItemInput
+	client := &mockDynamoDBClient{
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			capturedInput = params
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.Delete(ctx, "test-owner", "home")
+
+	assert.NilError(t, err)
+	assert.Equal(t, "DdnsServiceIpMapping", *capturedInput.TableName)
+	location := capturedInput.Key["LocationName"].(*types.AttributeValueMemberS)
+	assert.Equal(t, "home", location.Value)
+}
+
+func TestDynamoDBRepository_ListMappingsBySubdomain(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	item, _ := attributevalue.MarshalMap(domain.IPMapping{
+		OwnerID:      "test-owner",
+		LocationName: "home",
+		IP:           "192.168.1.1",
+		Subdomain:    "6abf7de6",
+	})
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "DdnsServiceIpMapping", *params.TableName)
+			assert.Equal(t, "SubdomainIndex", *params.IndexName)
+			subdomain := params.ExpressionAttributeValues[":subdomain"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "6abf7de6", subdomain.Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	mappings, err := repo.ListMappingsBySubdomain(ctx, "6abf7de6")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(mappings))
+	assert.Equal(t, "test-owner", mappings[0].OwnerID)
+	assert.Equal(t, "home", mappings[0].LocationName)
+}
+
+func TestDynamoDBRepository_ClaimSubdomain(t *testing.T) {
+	testCases := []struct {
+		name        string
+		putErr      error
+		expectedErr error
+	}{
+		{
+			name: "claimed",
+		},
+		{
+			name: "taken",
+			putErr: &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			},
+			expectedErr: domain.ErrSubdomainTaken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					assert.Equal(t, subdomainsTableName, *params.TableName)
+					assert.Equal(t, "attribute_not_exists(Subdomain) OR (OwnerId = :owner AND LocationName = :location)", *params.ConditionExpression)
+					assert.Equal(t, "a3f8c2d1", params.Item["Subdomain"].(*types.AttributeValueMemberS).Value)
+					assert.Equal(t, "test-owner", params.Item["OwnerId"].(*types.AttributeValueMemberS).Value)
+					assert.Equal(t, "home", params.Item["LocationName"].(*types.AttributeValueMemberS).Value)
+					return &dynamodb.PutItemOutput{}, tc.putErr
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			err := repo.ClaimSubdomain(context.Background(), "a3f8c2d1", "test-owner", "home")
+
+			if tc.expectedErr != nil {
+				assert.Assert(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_ReleaseSubdomain(t *testing.T) {
+	testCases := []struct {
+		name      string
+		deleteErr error
+		expectErr bool
+	}{
+		{name: "released"},
+		{
+			name: "held by another location",
+			deleteErr: &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			},
+		},
+		{name: "failure", deleteErr: errors.New("dynamodb error"), expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+					assert.Equal(t, subdomainsTableName, *params.TableName)
+					assert.Equal(t, "OwnerId = :owner AND LocationName = :location", *params.ConditionExpression)
+					assert.Equal(t, "a3f8c2d1", params.Key["Subdomain"].(*types.AttributeValueMemberS).Value)
+					return &dynamodb.DeleteItemOutput{}, tc.deleteErr
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			err := repo.ReleaseSubdomain(context.Background(), "a3f8c2d1", "test-owner", "home")
+
+			if tc.expectErr {
+				assert.ErrorContains(t, err, "dynamodb error")
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_ListMappingsBySubdomain_Error(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("dynamodb error")
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	mappings, err := repo.ListMappingsBySubdomain(ctx, "6abf7de6")
+
+	assert.Assert(t, mappings == nil)
+	assert.ErrorContains(t, err, "dynamodb error")
+}
+
 func TestDynamoDBRepository_CreateOwner(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -203,7 +371,9 @@ func TestDynamoDBRepository_CreateOwner(t *testing.T) {
 		putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
 			assert.Equal(t, ownersTableName, *params.TableName)
 			assert.Assert(t, params.ConditionExpression != nil)
-			assert.Equal(t, "attribute_not_exists(OwnerId)", *params.ConditionExpression)
+			assert.Equal(t, "attribute_not_exists(OwnerId) OR (attribute_exists(VerifyBy) AND (#ttl <= :now OR Email = :email))", *params.ConditionExpression)
+			email := params.ExpressionAttributeValues[":email"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "user@example.com", email.Value)
 			return &dynamodb.PutItemOutput{}, nil
 		},
 	}
@@ -260,6 +430,48 @@ func TestDynamoDBRepository_CreateOwner_Error(t *testing.T) {
 	assert.Assert(t, errors.Is(err, expectedErr), "expected %v, got %v", expectedErr, err)
 }
 
+func TestDynamoDBRepository_VerifyOwner(t *testing.T) {
+	verifiedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		updateErr   error
+		expectedErr error
+	}{
+		{name: "pending owner"},
+		{
+			name:        "expired, verified or re-registered",
+			updateErr:   &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			expectedErr: domain.ErrOwnerNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, ownersTableName, *params.TableName)
+					assert.Equal(t, "SET VerifiedAt = :verified REMOVE VerifyBy, #ttl", *params.UpdateExpression)
+					hash := params.ExpressionAttributeValues[":hash"].(*types.AttributeValueMemberS)
+					assert.Equal(t, "somehash123", hash.Value)
+					now := params.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN)
+					assert.Equal(t, strconv.FormatInt(verifiedAt.Unix(), 10), now.Value)
+					return &dynamodb.UpdateItemOutput{}, tc.updateErr
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			err := repo.VerifyOwner(context.Background(), "test-owner", "somehash123", verifiedAt)
+
+			if tc.expectedErr != nil {
+				assert.Assert(t, IsOwnerNotFound(err), "expected ErrOwnerNotFound, got %v", err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
 func TestDynamoDBRepository_GetOwner(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -334,23 +546,118 @@ func TestDynamoDBRepository_UpdateOwnerKey(t *testing.T) {
 		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 			assert.Equal(t, ownersTableName, *params.TableName)
 			assert.Assert(t, params.UpdateExpression != nil)
-			assert.Equal(t, "SET ApiKeyHash = :hash", *params.UpdateExpression)
+			assert.Equal(t, "SET ApiKeyHash = :hash, ApiKeyId = :keyId, ApiKeySigningSecret = :secret REMOVE ApiKeyLastUsedAt, ApiKeyLastUsedIp, ApiKeyExpiryWarnedAt, ApiKeyExpiresAt, "+previousKeyAttributes, *params.UpdateExpression)
 			assert.Assert(t, params.ConditionExpression != nil)
 			assert.Equal(t, "attribute_exists(OwnerId)", *params.ConditionExpression)
 
 			// Verify the new hash value is passed
 			hashVal := params.ExpressionAttributeValues[":hash"].(*types.AttributeValueMemberS)
 			assert.Equal(t, "newhash456", hashVal.Value)
+			keyIDVal := params.ExpressionAttributeValues[":keyId"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "9f2c4a1b7e3d", keyIDVal.Value)
+			secretVal := params.ExpressionAttributeValues[":secret"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "sealed-secret", secretVal.Value)
+
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.UpdateOwnerKey(ctx, "test-owner", "9f2c4a1b7e3d", "newhash456", "sealed-secret", nil, nil)
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_UpdateOwnerKey_WithExpiry(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	expiresAt := time.Date(2025, 4, 15, 10, 0, 0, 0, time.UTC)
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, "SET ApiKeyHash = :hash, ApiKeyId = :keyId, ApiKeyExpiresAt = :expires REMOVE ApiKeyLastUsedAt, ApiKeyLastUsedIp, ApiKeyExpiryWarnedAt, ApiKeySigningSecret, "+previousKeyAttributes, *params.UpdateExpression)
+			expires := params.ExpressionAttributeValues[":expires"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "2025-04-15T10:00:00Z", expires.Value)
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.UpdateOwnerKey(ctx, "test-owner", "9f2c4a1b7e3d", "newhash456", "", &expiresAt, nil)
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_UpdateOwnerKey_KeepsPrevious(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
 
+	previous := &domain.PreviousKey{
+		KeyID:         "1a2b3c4d5e6f",
+		KeyHash:       "oldhash123",
+		SigningSecret: "sealed-old-secret",
+		ExpiresAt:     time.Date(2025, 4, 16, 10, 0, 0, 0, time.UTC),
+	}
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, "SET ApiKeyHash = :hash, ApiKeyId = :keyId, ApiKeySigningSecret = :secret, PreviousApiKeyHash = :prevHash, PreviousApiKeyExpiresAt = :prevExpires, PreviousApiKeyId = :prevKeyId, PreviousApiKeySigningSecret = :prevSecret "+
+				"REMOVE ApiKeyLastUsedAt, ApiKeyLastUsedIp, ApiKeyExpiryWarnedAt, ApiKeyExpiresAt, PreviousApiKeyLastUsedAt, PreviousApiKeyLastUsedIp", *params.UpdateExpression)
+			// Only the key being demoted may become the previous key
+			assert.Equal(t, "attribute_exists(OwnerId) AND ApiKeyHash = :oldHash", *params.ConditionExpression)
+			oldHash := params.ExpressionAttributeValues[":oldHash"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "oldhash123", oldHash.Value)
+			prevExpires := params.ExpressionAttributeValues[":prevExpires"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "2025-04-16T10:00:00Z", prevExpires.Value)
+			prevKeyID := params.ExpressionAttributeValues[":prevKeyId"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "1a2b3c4d5e6f", prevKeyID.Value)
+			prevSecret := params.ExpressionAttributeValues[":prevSecret"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "sealed-old-secret", prevSecret.Value)
 			return &dynamodb.UpdateItemOutput{}, nil
 		},
 	}
 
 	repo := NewDynamoDBRepository(client, logger)
-	err := repo.UpdateOwnerKey(ctx, "test-owner", "newhash456")
+	err := repo.UpdateOwnerKey(ctx, "test-owner", "9f2c4a1b7e3d", "newhash456", "sealed-secret", nil, previous)
 	assert.NilError(t, err)
 }
 
+func TestDynamoDBRepository_RevokePreviousOwnerKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	testCases := []struct {
+		name        string
+		updateErr   error
+		expectedErr error
+	}{
+		{name: "revoked"},
+		{
+			name:        "no previous key",
+			updateErr:   &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			expectedErr: domain.ErrAPIKeyNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, ownersTableName, *params.TableName)
+					assert.Equal(t, "REMOVE "+previousKeyAttributes, *params.UpdateExpression)
+					assert.Equal(t, "attribute_exists(PreviousApiKeyHash)", *params.ConditionExpression)
+					return &dynamodb.UpdateItemOutput{}, tc.updateErr
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, logger)
+			err := repo.RevokePreviousOwnerKey(ctx, "test-owner")
+			if tc.expectedErr != nil {
+				assert.Assert(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
 func TestDynamoDBRepository_UpdateOwnerKey_NotFound(t *testing.T) {
 	ctx := context.Background()
 	logger := newTestLogger()
@@ -364,7 +671,7 @@ func TestDynamoDBRepository_UpdateOwnerKey_NotFound(t *testing.T) {
 	}
 
 	repo := NewDynamoDBRepository(client, logger)
-	err := repo.UpdateOwnerKey(ctx, "nonexistent", "newhash456")
+	err := repo.UpdateOwnerKey(ctx, "nonexistent", "9f2c4a1b7e3d", "newhash456", "", nil, nil)
 	assert.Assert(t, errors.Is(err, domain.ErrOwnerNotFound), "expected ErrOwnerNotFound, got %v", err)
 }
 
@@ -380,19 +687,1645 @@ func TestDynamoDBRepository_UpdateOwnerKey_Error(t *testing.T) {
 	}
 
 	repo := NewDynamoDBRepository(client, logger)
-	err := repo.UpdateOwnerKey(ctx, "test-owner", "newhash456")
+	err := repo.UpdateOwnerKey(ctx, "test-owner", "9f2c4a1b7e3d", "newhash456", "", nil, nil)
 	assert.Assert(t, errors.Is(err, expectedErr), "expected %v, got %v", expectedErr, err)
 }
 
+func TestDynamoDBRepository_ReplaceOwnerKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, "SET ApiKeyHash = :hash, ApiKeyId = :keyId, ApiKeySigningSecret = :secret REMOVE ApiKeyLastUsedAt, ApiKeyLastUsedIp, ApiKeyExpiryWarnedAt, ApiKeyExpiresAt, "+previousKeyAttributes, *params.UpdateExpression)
+			assert.Equal(t, "attribute_exists(OwnerId) AND ApiKeyHash = :oldHash", *params.ConditionExpression)
+			oldHash := params.ExpressionAttributeValues[":oldHash"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "oldhash123", oldHash.Value)
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.ReplaceOwnerKey(ctx, "test-owner", "oldhash123", "9f2c4a1b7e3d", "newhash456", "sealed-secret")
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_ReplaceOwnerKey_KeyChanged(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.ReplaceOwnerKey(ctx, "test-owner", "oldhash123", "9f2c4a1b7e3d", "newhash456", "sealed-secret")
+	assert.Assert(t, errors.Is(err, domain.ErrOwnerNotFound), "expected ErrOwnerNotFound, got %v", err)
+}
+
 // =============================================================================
-// Helper Function Tests
+// Record Set Tests
 // =============================================================================
 
-func TestIsOwnerNotFound(t *testing.T) {
-	assert.Assert(t, IsOwnerNotFound(domain.ErrOwnerNotFound))
-	assert.Assert(t, !IsOwnerNotFound(domain.ErrOwnerExists))
-	assert.Assert(t, !IsOwnerNotFound(errors.New("some other error")))
-	assert.Assert(t, !IsOwnerNotFound(nil))
+// countedCanceled returns the error of a transaction that writes an item and
+// the owner's count of such items, canceled by the conditions of the given items.
+func countedCanceled(itemFailed bool, owner map[string]types.AttributeValue) error {
+	reasons := []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("None")}}
+	if itemFailed {
+		reasons[0].Code = aws.String("ConditionalCheckFailed")
+	} else {
+		reasons[1] = types.CancellationReason{Code: aws.String("ConditionalCheckFailed"), Item: owner}
+	}
+	return &types.TransactionCanceledException{
+		Message:             aws.String("Transaction cancelled"),
+		CancellationReasons: reasons,
+	}
+}
+
+func TestDynamoDBRepository_CreateRecordSet(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	rs := domain.RecordSet{
+		OwnerID:      "test-owner",
+		LocationName: "home",
+		Name:         "_minecraft._tcp",
+		Type:         "SRV",
+		Values:       []string{"0 5 25565 mc.example.com"},
+	}
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 2, len(params.TransactItems))
+
+			put := params.TransactItems[0].Put
+			assert.Equal(t, recordSetsTableName, *put.TableName)
+			assert.Equal(t, "attribute_not_exists(RecordKey)", *put.ConditionExpression)
+			recordKey := put.Item["RecordKey"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "home#SRV#_minecraft._tcp", recordKey.Value)
+
+			update := params.TransactItems[1].Update
+			assert.Equal(t, ownersTableName, *update.TableName)
+			assert.Equal(t, "ADD RecordSetCount :one", *update.UpdateExpression)
+			assert.Equal(t, "RecordSetCount < :limit", *update.ConditionExpression)
+			limit := update.ExpressionAttributeValues[":limit"].(*types.AttributeValueMemberN)
+			assert.Equal(t, "20", limit.Value)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateRecordSet(ctx, rs, 20)
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_CreateRecordSet_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(true, nil)
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateRecordSet(ctx, domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT"}, 20)
+	assert.Assert(t, errors.Is(err, domain.ErrRecordSetExists), "expected ErrRecordSetExists, got %v", err)
+}
+
+func TestDynamoDBRepository_CreateRecordSet_QuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(false, map[string]types.AttributeValue{
+				"OwnerId":        &types.AttributeValueMemberS{Value: "test-owner"},
+				"RecordSetCount": &types.AttributeValueMemberN{Value: "20"},
+			})
+		},
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			t.Fatal("should not recount an owner with a count")
+			return nil, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateRecordSet(ctx, domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT"}, 20)
+	assert.Assert(t, errors.Is(err, domain.ErrRecordSetQuotaExceeded), "expected ErrRecordSetQuotaExceeded, got %v", err)
+}
+
+func TestDynamoDBRepository_CreateRecordSet_OwnerNotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(false, nil)
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateRecordSet(ctx, domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT"}, 20)
+	assert.Assert(t, errors.Is(err, domain.ErrOwnerNotFound), "expected ErrOwnerNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_CreateRecordSet_CountsUncountedOwner(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	transactions := 0
+	var started string
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			transactions++
+			if transactions == 1 {
+				return nil, countedCanceled(false, map[string]types.AttributeValue{
+					"OwnerId": &types.AttributeValueMemberS{Value: "test-owner"},
+				})
+			}
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, types.SelectCount, params.Select)
+			return &dynamodb.QueryOutput{Count: 3}, nil
+		},
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, ownersTableName, *params.TableName)
+			assert.Equal(t, "SET RecordSetCount = :count", *params.UpdateExpression)
+			assert.Equal(t, "attribute_exists(OwnerId) AND attribute_not_exists(RecordSetCount)", *params.ConditionExpression)
+			started = params.ExpressionAttributeValues[":count"].(*types.AttributeValueMemberN).Value
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateRecordSet(ctx, domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT"}, 20)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 2, transactions)
+	assert.Equal(t, "3", started)
+}
+
+func TestDynamoDBRepository_PutRecordSet_NotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			assert.Equal(t, "attribute_exists(RecordKey)", *params.ConditionExpression)
+			return nil, &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.PutRecordSet(ctx, domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT"})
+	assert.Assert(t, errors.Is(err, domain.ErrRecordSetNotFound), "expected ErrRecordSetNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_DeleteRecordSet(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 2, len(params.TransactItems))
+
+			del := params.TransactItems[0].Delete
+			assert.Equal(t, recordSetsTableName, *del.TableName)
+			recordKey := del.Key["RecordKey"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "home#TXT#", recordKey.Value)
+
+			update := params.TransactItems[1].Update
+			assert.Equal(t, ownersTableName, *update.TableName)
+			assert.Equal(t, "ADD RecordSetCount :minusOne", *update.UpdateExpression)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			t.Fatal("should delete in the transaction")
+			return nil, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.DeleteRecordSet(ctx, "test-owner", "home", "", "TXT")
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_DeleteRecordSet_UncountedOwner(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	deleted := false
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(false, nil)
+		},
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			assert.Equal(t, recordSetsTableName, *params.TableName)
+			deleted = true
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.DeleteRecordSet(ctx, "test-owner", "home", "", "TXT")
+
+	assert.NilError(t, err)
+	assert.Assert(t, deleted)
+}
+
+func TestDynamoDBRepository_GetRecordSet_NotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		getItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			assert.Equal(t, recordSetsTableName, *params.TableName)
+			recordKey := params.Key["RecordKey"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "home#TXT#", recordKey.Value)
+			return &dynamodb.GetItemOutput{Item: nil}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	rs, err := repo.GetRecordSet(ctx, "test-owner", "home", "", "TXT")
+	assert.Assert(t, rs == nil)
+	assert.Assert(t, errors.Is(err, domain.ErrRecordSetNotFound), "expected ErrRecordSetNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_ListRecordSets_Paginates(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	first, _ := attributevalue.MarshalMap(domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "TXT"})
+	second, _ := attributevalue.MarshalMap(domain.RecordSet{OwnerID: "test-owner", LocationName: "home", Type: "MX"})
+
+	calls := 0
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			calls++
+			prefix := params.ExpressionAttributeValues[":prefix"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "home#", prefix.Value)
+			if calls == 1 {
+				return &dynamodb.QueryOutput{
+					Items:            []map[string]types.AttributeValue{first},
+					LastEvaluatedKey: map[string]types.AttributeValue{"OwnerId": &types.AttributeValueMemberS{Value: "test-owner"}},
+				}, nil
+			}
+			assert.Assert(t, params.ExclusiveStartKey != nil)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{second}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	recordSets, err := repo.ListRecordSets(ctx, "test-owner", "home")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, len(recordSets))
+	assert.Equal(t, "TXT", recordSets[0].Type)
+	assert.Equal(t, "MX", recordSets[1].Type)
+}
+
+func TestDynamoDBRepository_CountRecordSets(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, types.SelectCount, params.Select)
+			return &dynamodb.QueryOutput{Count: 7}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	count, err := repo.CountRecordSets(ctx, "test-owner")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 7, count)
+}
+
+func TestDynamoDBRepository_ListAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	item, _ := attributevalue.MarshalMap(domain.APIKey{
+		OwnerID:   "test-owner",
+		KeyID:     "k1",
+		Label:     "cabin pi",
+		Scopes:    []string{domain.ScopeUpdate},
+		Locations: []string{"cabin"},
+	})
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, apiKeysTableName, *params.TableName)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	keys, err := repo.ListAPIKeys(ctx, "test-owner")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(keys))
+	assert.Equal(t, "k1", keys[0].KeyID)
+	assert.DeepEqual(t, []string{"cabin"}, keys[0].Locations)
+}
+
+func TestDynamoDBRepository_GetKeyOwnerID(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	ownerItem := map[string]types.AttributeValue{
+		"OwnerId":  &types.AttributeValueMemberS{Value: "test-owner"},
+		"ApiKeyId": &types.AttributeValueMemberS{Value: "9f2c4a1b7e3d"},
+	}
+	keyItem := map[string]types.AttributeValue{
+		"OwnerId": &types.AttributeValueMemberS{Value: "key-owner"},
+		"KeyId":   &types.AttributeValueMemberS{Value: "9f2c4a1b7e3d"},
+	}
+
+	testCases := []struct {
+		name          string
+		apiKeyItems   []map[string]types.AttributeValue
+		ownerItems    []map[string]types.AttributeValue
+		previousItems []map[string]types.AttributeValue
+		expectedOwner string
+		expectedErr   error
+	}{
+		{
+			name:          "additional key",
+			apiKeyItems:   []map[string]types.AttributeValue{keyItem},
+			expectedOwner: "key-owner",
+		},
+		{
+			name:          "primary key",
+			ownerItems:    []map[string]types.AttributeValue{ownerItem},
+			expectedOwner: "test-owner",
+		},
+		{
+			name:          "previous key",
+			previousItems: []map[string]types.AttributeValue{ownerItem},
+			expectedOwner: "test-owner",
+		},
+		{
+			name:        "unknown key ID",
+			expectedErr: domain.ErrAPIKeyNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					keyID := params.ExpressionAttributeValues[":keyId"].(*types.AttributeValueMemberS)
+					assert.Equal(t, "9f2c4a1b7e3d", keyID.Value)
+					switch *params.TableName {
+					case apiKeysTableName:
+						assert.Equal(t, apiKeyIDIndexName, *params.IndexName)
+						return &dynamodb.QueryOutput{Items: tc.apiKeyItems}, nil
+					case ownersTableName:
+						switch *params.IndexName {
+						case ownerKeyIDIndexName:
+							return &dynamodb.QueryOutput{Items: tc.ownerItems}, nil
+						case previousKeyIDIndexName:
+							return &dynamodb.QueryOutput{Items: tc.previousItems}, nil
+						}
+					}
+					t.Fatalf("unexpected table %s", *params.TableName)
+					return nil, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, logger)
+			ownerID, err := repo.GetKeyOwnerID(ctx, "9f2c4a1b7e3d")
+
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expectedOwner, ownerID)
+		})
+	}
+}
+
+func TestDynamoDBRepository_CreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 2, len(params.TransactItems))
+
+			put := params.TransactItems[0].Put
+			assert.Equal(t, apiKeysTableName, *put.TableName)
+			assert.Equal(t, "attribute_not_exists(KeyId)", *put.ConditionExpression)
+
+			update := params.TransactItems[1].Update
+			assert.Equal(t, ownersTableName, *update.TableName)
+			assert.Equal(t, "ADD ApiKeyCount :one", *update.UpdateExpression)
+			assert.Equal(t, "ApiKeyCount < :limit", *update.ConditionExpression)
+			limit := update.ExpressionAttributeValues[":limit"].(*types.AttributeValueMemberN)
+			assert.Equal(t, strconv.Itoa(domain.MaxAPIKeys), limit.Value)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateAPIKey(ctx, domain.APIKey{OwnerID: "test-owner", KeyID: "9f2c4a1b7e3d"})
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_CreateAPIKey_QuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(false, map[string]types.AttributeValue{
+				"OwnerId":     &types.AttributeValueMemberS{Value: "test-owner"},
+				"ApiKeyCount": &types.AttributeValueMemberN{Value: strconv.Itoa(domain.MaxAPIKeys)},
+			})
+		},
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			t.Fatal("should not recount an owner with a count")
+			return nil, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateAPIKey(ctx, domain.APIKey{OwnerID: "test-owner", KeyID: "9f2c4a1b7e3d"})
+	assert.Assert(t, errors.Is(err, domain.ErrAPIKeyQuotaExceeded), "expected ErrAPIKeyQuotaExceeded, got %v", err)
+}
+
+func TestDynamoDBRepository_CreateAPIKey_CountsUncountedOwner(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	transactions := 0
+	var started string
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			transactions++
+			if transactions == 1 {
+				return nil, countedCanceled(false, map[string]types.AttributeValue{
+					"OwnerId": &types.AttributeValueMemberS{Value: "test-owner"},
+				})
+			}
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, apiKeysTableName, *params.TableName)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+				{"OwnerId": &types.AttributeValueMemberS{Value: "test-owner"}, "KeyId": &types.AttributeValueMemberS{Value: "a"}},
+				{"OwnerId": &types.AttributeValueMemberS{Value: "test-owner"}, "KeyId": &types.AttributeValueMemberS{Value: "b"}},
+			}}, nil
+		},
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, ownersTableName, *params.TableName)
+			assert.Equal(t, "SET ApiKeyCount = :count", *params.UpdateExpression)
+			assert.Equal(t, "attribute_exists(OwnerId) AND attribute_not_exists(ApiKeyCount)", *params.ConditionExpression)
+			started = params.ExpressionAttributeValues[":count"].(*types.AttributeValueMemberN).Value
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.CreateAPIKey(ctx, domain.APIKey{OwnerID: "test-owner", KeyID: "9f2c4a1b7e3d"})
+
+	assert.NilError(t, err)
+	assert.Equal(t, 2, transactions)
+	assert.Equal(t, "2", started)
+}
+
+func TestDynamoDBRepository_DeleteAPIKey(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 2, len(params.TransactItems))
+			assert.Equal(t, apiKeysTableName, *params.TransactItems[0].Delete.TableName)
+			assert.Equal(t, "ADD ApiKeyCount :minusOne", *params.TransactItems[1].Update.UpdateExpression)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			t.Fatal("should delete in the transaction")
+			return nil, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.DeleteAPIKey(ctx, "test-owner", "9f2c4a1b7e3d")
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_DeleteAPIKey_NotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(true, nil)
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.DeleteAPIKey(ctx, "test-owner", "missing")
+	assert.Assert(t, IsAPIKeyNotFound(err), "expected ErrAPIKeyNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_DeleteAPIKey_UncountedOwner(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	deleted := false
+	client := &mockDynamoDBClient{
+		transactWriteItemsFunc: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			return nil, countedCanceled(false, nil)
+		},
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			assert.Equal(t, apiKeysTableName, *params.TableName)
+			assert.Equal(t, "attribute_exists(KeyId)", *params.ConditionExpression)
+			deleted = true
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	err := repo.DeleteAPIKey(ctx, "test-owner", "9f2c4a1b7e3d")
+
+	assert.NilError(t, err)
+	assert.Assert(t, deleted)
+}
+
+func TestDynamoDBRepository_RecordAPIKeyUse(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+	usedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name               string
+		keyID              string
+		expectedTable      string
+		expectedExpression string
+	}{
+		{
+			name:               "primary key is stored on the owner",
+			keyID:              domain.PrimaryKeyID,
+			expectedTable:      ownersTableName,
+			expectedExpression: "SET ApiKeyLastUsedAt = :used, ApiKeyLastUsedIp = :ip",
+		},
+		{
+			name:               "additional key",
+			keyID:              "k1",
+			expectedTable:      apiKeysTableName,
+			expectedExpression: "SET LastUsedAt = :used, LastUsedIp = :ip",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, tc.expectedTable, *params.TableName)
+					assert.Equal(t, tc.expectedExpression, *params.UpdateExpression)
+					ip := params.ExpressionAttributeValues[":ip"].(*types.AttributeValueMemberS)
+					assert.Equal(t, "203.0.113.50", ip.Value)
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, logger)
+			err := repo.RecordAPIKeyUse(ctx, "test-owner", tc.keyID, usedAt, "203.0.113.50")
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_ScanExpiringAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+
+	now := time.Now().UTC()
+	soon := now.Add(24 * time.Hour)
+	later := now.Add(30 * 24 * time.Hour)
+	past := now.Add(-time.Hour)
+
+	owner, _ := attributevalue.MarshalMap(domain.Owner{OwnerID: "soon-owner", APIKeyExpiresAt: &soon})
+	laterOwner, _ := attributevalue.MarshalMap(domain.Owner{OwnerID: "later-owner", APIKeyExpiresAt: &later})
+	soonKey, _ := attributevalue.MarshalMap(domain.APIKey{OwnerID: "test-owner", KeyID: "k1", ExpiresAt: &soon})
+	expiredKey, _ := attributevalue.MarshalMap(domain.APIKey{OwnerID: "test-owner", KeyID: "k2", ExpiresAt: &past})
+
+	client := &mockDynamoDBClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			if *params.TableName == ownersTableName {
+				return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{owner, laterOwner}}, nil
+			}
+			assert.Equal(t, apiKeysTableName, *params.TableName)
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{soonKey, expiredKey}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, logger)
+	keys, err := repo.ScanExpiringAPIKeys(ctx, now.Add(7*24*time.Hour))
+
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(keys))
+	assert.Equal(t, "soon-owner", keys[0].OwnerID)
+	assert.Assert(t, keys[0].IsPrimary())
+	assert.Equal(t, "k1", keys[1].KeyID)
+}
+
+// =============================================================================
+// Helper Function Tests
+// =============================================================================
+
+func TestIsOwnerNotFound(t *testing.T) {
+	assert.Assert(t, IsOwnerNotFound(domain.ErrOwnerNotFound))
+	assert.Assert(t, !IsOwnerNotFound(domain.ErrOwnerExists))
+	assert.Assert(t, !IsOwnerNotFound(errors.New("some other error")))
+	assert.Assert(t, !IsOwnerNotFound(nil))
+}
+
+func TestDynamoDBRepository_UseNonce(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+	expiresAt := time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		putErr      error
+		expectedErr error
+	}{
+		{
+			name: "first use",
+		},
+		{
+			name: "replayed nonce",
+			putErr: &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			},
+			expectedErr: domain.ErrNonceReused,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					assert.Equal(t, noncesTableName, *params.TableName)
+					assert.Equal(t, "attribute_not_exists(Nonce)", *params.ConditionExpression)
+					nonce := params.Item["Nonce"].(*types.AttributeValueMemberS)
+					assert.Equal(t, "0123456789ab#00112233445566778899aabbccddeeff", nonce.Value)
+					ttl := params.Item["TTL"].(*types.AttributeValueMemberN)
+					assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), ttl.Value)
+					return &dynamodb.PutItemOutput{}, tc.putErr
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, logger)
+			err := repo.UseNonce(ctx, "0123456789ab", "00112233445566778899aabbccddeeff", expiresAt)
+
+			if tc.expectedErr != nil {
+				assert.Assert(t, IsNonceReused(err), "expected ErrNonceReused, got %v", err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_SetOwnerSuspension(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+	suspendedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name               string
+		suspendedAt        *time.Time
+		expectedExpression string
+	}{
+		{
+			name:               "suspend",
+			suspendedAt:        &suspendedAt,
+			expectedExpression: "SET SuspendedAt = :at, SuspendedReason = :reason",
+		},
+		{
+			name:               "unsuspend",
+			expectedExpression: "REMOVE SuspendedAt, SuspendedReason",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, ownersTableName, *params.TableName)
+					assert.Equal(t, tc.expectedExpression, *params.UpdateExpression)
+					assert.Equal(t, "attribute_exists(OwnerId)", *params.ConditionExpression)
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, logger)
+			err := repo.SetOwnerSuspension(ctx, "test-owner", tc.suspendedAt, "abuse")
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_SetOwnerSuspension_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.SetOwnerSuspension(context.Background(), "missing", nil, "")
+	assert.Assert(t, IsOwnerNotFound(err), "expected ErrOwnerNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_ClaimACMEChallenge(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)
+	limit := domain.ACMEChallengeLimit{MaxChallenges: 3, Window: time.Hour}
+	window := strconv.FormatInt(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC).Unix(), 10)
+
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, ownersTableName, *params.TableName)
+			assert.Equal(t, "SET LastAcmeChallengeAt = :at ADD AcmeChallengeCount :one", *params.UpdateExpression)
+			assert.Equal(t, "attribute_exists(OwnerId) AND AcmeChallengeWindow = :window AND AcmeChallengeCount < :max", *params.ConditionExpression)
+			assert.Equal(t, window, params.ExpressionAttributeValues[":window"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "3", params.ExpressionAttributeValues[":max"].(*types.AttributeValueMemberN).Value)
+			return &dynamodb.UpdateItemOutput{
+				Attributes: map[string]types.AttributeValue{
+					"AcmeChallengeCount": &types.AttributeValueMemberN{Value: "2"},
+				},
+			}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	count, err := repo.ClaimACMEChallenge(context.Background(), "test-owner", limit, now)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestDynamoDBRepository_ClaimACMEChallenge_StartsWindow(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)
+	limit := domain.ACMEChallengeLimit{MaxChallenges: 3, Window: time.Hour}
+	previousWindow := strconv.FormatInt(time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC).Unix(), 10)
+
+	var expressions []string
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			expressions = append(expressions, *params.UpdateExpression)
+			if len(expressions) == 1 {
+				return nil, &types.ConditionalCheckFailedException{
+					Message: aws.String("The conditional request failed"),
+					Item: map[string]types.AttributeValue{
+						"OwnerId":             &types.AttributeValueMemberS{Value: "test-owner"},
+						"AcmeChallengeCount":  &types.AttributeValueMemberN{Value: "3"},
+						"AcmeChallengeWindow": &types.AttributeValueMemberN{Value: previousWindow},
+					},
+				}
+			}
+			assert.Equal(t, "attribute_exists(OwnerId) AND (attribute_not_exists(AcmeChallengeWindow) OR AcmeChallengeWindow < :window)", *params.ConditionExpression)
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	count, err := repo.ClaimACMEChallenge(context.Background(), "test-owner", limit, now)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, count)
+	assert.DeepEqual(t, []string{
+		"SET LastAcmeChallengeAt = :at ADD AcmeChallengeCount :one",
+		"SET AcmeChallengeCount = :one, AcmeChallengeWindow = :window, LastAcmeChallengeAt = :at",
+	}, expressions)
+}
+
+func TestDynamoDBRepository_ClaimACMEChallenge_LimitExceeded(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)
+	limit := domain.ACMEChallengeLimit{MaxChallenges: 3, Window: time.Hour}
+	window := strconv.FormatInt(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC).Unix(), 10)
+
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+				Item: map[string]types.AttributeValue{
+					"OwnerId":             &types.AttributeValueMemberS{Value: "test-owner"},
+					"AcmeChallengeCount":  &types.AttributeValueMemberN{Value: "3"},
+					"AcmeChallengeWindow": &types.AttributeValueMemberN{Value: window},
+				},
+			}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	_, err := repo.ClaimACMEChallenge(context.Background(), "test-owner", limit, now)
+	assert.Assert(t, errors.Is(err, domain.ErrACMERateLimitExceeded), "expected ErrACMERateLimitExceeded, got %v", err)
+}
+
+func TestDynamoDBRepository_ClaimACMEChallenge_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	_, err := repo.ClaimACMEChallenge(context.Background(), "missing", domain.DefaultACMEChallengeLimit, time.Now())
+	assert.Assert(t, IsOwnerNotFound(err), "expected ErrOwnerNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_SetOwnerNotifications(t *testing.T) {
+	prefs := &domain.NotificationPreferences{IPChange: &domain.IPChangeNotifications{Locations: []string{"home"}}}
+
+	testCases := []struct {
+		name               string
+		prefs              *domain.NotificationPreferences
+		expectedExpression string
+	}{
+		{name: "set", prefs: prefs, expectedExpression: "SET Notifications = :prefs"},
+		{name: "clear", expectedExpression: "REMOVE Notifications"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, ownersTableName, *params.TableName)
+					assert.Equal(t, tc.expectedExpression, *params.UpdateExpression)
+					assert.Equal(t, "attribute_exists(OwnerId)", *params.ConditionExpression)
+					if tc.prefs != nil {
+						var stored domain.NotificationPreferences
+						assert.NilError(t, attributevalue.Unmarshal(params.ExpressionAttributeValues[":prefs"], &stored))
+						assert.DeepEqual(t, *tc.prefs, stored)
+					}
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			err := repo.SetOwnerNotifications(context.Background(), "test-owner", tc.prefs)
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_SetOwnerNotifications_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.SetOwnerNotifications(context.Background(), "missing", nil)
+	assert.Assert(t, IsOwnerNotFound(err), "expected ErrOwnerNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_SetOwnerLocale(t *testing.T) {
+	testCases := []struct {
+		name               string
+		locale             string
+		expectedExpression string
+	}{
+		{name: "set", locale: "pt-br", expectedExpression: "SET Locale = :locale"},
+		{name: "clear", expectedExpression: "REMOVE Locale"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, ownersTableName, *params.TableName)
+					assert.Equal(t, tc.expectedExpression, *params.UpdateExpression)
+					assert.Equal(t, "attribute_exists(OwnerId)", *params.ConditionExpression)
+					if tc.locale != "" {
+						assert.Equal(t, tc.locale, params.ExpressionAttributeValues[":locale"].(*types.AttributeValueMemberS).Value)
+					}
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			err := repo.SetOwnerLocale(context.Background(), "test-owner", tc.locale)
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_SetOwnerLocale_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.SetOwnerLocale(context.Background(), "missing", "de")
+	assert.Assert(t, IsOwnerNotFound(err), "expected ErrOwnerNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_ScanPendingIPChanges(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.IPMapping{
+		OwnerID:          "test-owner",
+		LocationName:     "home",
+		PendingIPChanges: []domain.IPChange{{PreviousIP: "198.51.100.1", IP: "198.51.100.2"}},
+	})
+
+	client := &mockDynamoDBClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			assert.Equal(t, mappingsTableName, *params.TableName)
+			assert.Equal(t, "attribute_exists(PendingIpChanges)", *params.FilterExpression)
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	mappings, err := repo.ScanPendingIPChanges(context.Background())
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(mappings))
+	assert.Equal(t, "198.51.100.2", mappings[0].PendingIPChanges[0].IP)
+}
+
+func TestDynamoDBRepository_MarkIPChangesNotified(t *testing.T) {
+	notifiedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, mappingsTableName, *params.TableName)
+			assert.Equal(t, "home", params.Key["LocationName"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET IpChangeNotifiedAt = :notified REMOVE PendingIpChanges", *params.UpdateExpression)
+			assert.Equal(t, "2026-03-01T12:00:00Z", params.ExpressionAttributeValues[":notified"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.MarkIPChangesNotified(context.Background(), "test-owner", "home", notifiedAt)
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_MarkIPChangesNotified_Deleted(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.MarkIPChangesNotified(context.Background(), "test-owner", "home", time.Now())
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_TouchLocation(t *testing.T) {
+	seenAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, mappingsTableName, *params.TableName)
+			assert.Equal(t, "home", params.Key["LocationName"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET LastSeenAt = :seen REMOVE StaleAlertedAt", *params.UpdateExpression)
+			assert.Equal(t, "attribute_exists(OwnerId)", *params.ConditionExpression)
+			assert.Equal(t, "2026-10-18T12:00:00Z", params.ExpressionAttributeValues[":seen"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.TouchLocation(context.Background(), "test-owner", "home", seenAt)
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_TouchLocation_Deleted(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.TouchLocation(context.Background(), "test-owner", "home", time.Now())
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_ScanMonitoredLocations(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.IPMapping{
+		OwnerID:           "test-owner",
+		LocationName:      "cabin",
+		StaleAfterSeconds: 3600,
+	})
+
+	client := &mockDynamoDBClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			assert.Equal(t, mappingsTableName, *params.TableName)
+			assert.Equal(t, "StaleAfterSeconds > :zero", *params.FilterExpression)
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	mappings, err := repo.ScanMonitoredLocations(context.Background())
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(mappings))
+	assert.Equal(t, 3600, mappings[0].StaleAfterSeconds)
+}
+
+func TestDynamoDBRepository_MarkLocationStaleAlerted(t *testing.T) {
+	alertedAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, "cabin", params.Key["LocationName"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET StaleAlertedAt = :alerted", *params.UpdateExpression)
+			assert.Equal(t, "2026-10-18T12:00:00Z", params.ExpressionAttributeValues[":alerted"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.MarkLocationStaleAlerted(context.Background(), "test-owner", "cabin", alertedAt)
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_CreateWebhook(t *testing.T) {
+	client := &mockDynamoDBClient{
+		putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			assert.Equal(t, webhooksTableName, *params.TableName)
+			assert.Equal(t, "attribute_not_exists(WebhookId)", *params.ConditionExpression)
+
+			var webhook domain.Webhook
+			assert.NilError(t, attributevalue.UnmarshalMap(params.Item, &webhook))
+			assert.Equal(t, "0123456789ab", webhook.WebhookID)
+			assert.Equal(t, "whsec_test", webhook.Secret)
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.CreateWebhook(context.Background(), domain.Webhook{
+		OwnerID:   "test-owner",
+		WebhookID: "0123456789ab",
+		URL:       "https://hooks.example.com/ddns",
+		Events:    []string{domain.WebhookEventIPChanged},
+		Secret:    "whsec_test",
+	})
+
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_ListWebhooks(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.Webhook{
+		OwnerID:   "test-owner",
+		WebhookID: "0123456789ab",
+		URL:       "https://hooks.example.com/ddns",
+		Events:    []string{domain.WebhookEventIPChanged},
+	})
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, webhooksTableName, *params.TableName)
+			owner := params.ExpressionAttributeValues[":owner"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "test-owner", owner.Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	webhooks, err := repo.ListWebhooks(context.Background(), "test-owner")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(webhooks))
+	assert.Equal(t, "https://hooks.example.com/ddns", webhooks[0].URL)
+	assert.DeepEqual(t, []string{domain.WebhookEventIPChanged}, webhooks[0].Events)
+}
+
+func TestDynamoDBRepository_GetWebhook_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		getItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			assert.Equal(t, webhooksTableName, *params.TableName)
+			return &dynamodb.GetItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	_, err := repo.GetWebhook(context.Background(), "test-owner", "missing")
+
+	assert.Assert(t, IsWebhookNotFound(err), "expected ErrWebhookNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_DeleteWebhook_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			assert.Equal(t, webhooksTableName, *params.TableName)
+			return nil, &types.ConditionalCheckFailedException{
+				Message: aws.String("The conditional request failed"),
+			}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.DeleteWebhook(context.Background(), "test-owner", "missing")
+
+	assert.Assert(t, IsWebhookNotFound(err), "expected ErrWebhookNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_PutWebhookDelivery(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	delivery := domain.NewWebhookDelivery(domain.Webhook{OwnerID: "test-owner", WebhookID: "0123456789ab"}, "delivery-1", domain.WebhookEventIPChanged, `{}`, now)
+
+	client := &mockDynamoDBClient{
+		putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			assert.Equal(t, deliveriesTableName, *params.TableName)
+			assert.Equal(t, "0123456789ab", params.Item["WebhookId"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "delivery-1", params.Item["DeliveryId"].(*types.AttributeValueMemberS).Value)
+			ttl := params.Item["TTL"].(*types.AttributeValueMemberN)
+			assert.Equal(t, strconv.FormatInt(now.Add(domain.WebhookDeliveryRetention).Unix(), 10), ttl.Value)
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.PutWebhookDelivery(context.Background(), delivery)
+
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_ListWebhookDeliveries(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.WebhookDelivery{
+		WebhookID:  "0123456789ab",
+		DeliveryID: "delivery-1",
+		Event:      domain.WebhookEventACMECreated,
+		Status:     domain.WebhookDeliverySucceeded,
+		Attempts:   1,
+	})
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, deliveriesTableName, *params.TableName)
+			assert.Equal(t, false, *params.ScanIndexForward)
+			assert.Equal(t, int32(25), *params.Limit)
+			webhook := params.ExpressionAttributeValues[":webhook"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "0123456789ab", webhook.Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	deliveries, err := repo.ListWebhookDeliveries(context.Background(), "0123456789ab", 25)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, domain.WebhookDeliverySucceeded, deliveries[0].Status)
+}
+
+func TestDynamoDBRepository_ScanPendingWebhookDeliveries(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.WebhookDelivery{
+		WebhookID:  "0123456789ab",
+		DeliveryID: "delivery-1",
+		Status:     domain.WebhookDeliveryPending,
+	})
+
+	client := &mockDynamoDBClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			assert.Equal(t, deliveriesTableName, *params.TableName)
+			assert.Equal(t, "#status = :pending", *params.FilterExpression)
+			pending := params.ExpressionAttributeValues[":pending"].(*types.AttributeValueMemberS)
+			assert.Equal(t, domain.WebhookDeliveryPending, pending.Value)
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	deliveries, err := repo.ScanPendingWebhookDeliveries(context.Background())
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, "delivery-1", deliveries[0].DeliveryID)
+}
+
+func TestDynamoDBRepository_SetOwnerRateLimit(t *testing.T) {
+	policy := &domain.RateLimitPolicy{MaxChanges: 10, WindowSeconds: 3600, Algorithm: domain.RateLimitSlidingWindow}
+
+	testCases := []struct {
+		name               string
+		plan               string
+		policy             *domain.RateLimitPolicy
+		expectedExpression string
+	}{
+		{
+			name:               "plan and override",
+			plan:               "isp-burst",
+			policy:             policy,
+			expectedExpression: "SET RateLimitPlan = :plan, RateLimit = :policy",
+		},
+		{
+			name:               "plan only",
+			plan:               "isp-burst",
+			expectedExpression: "SET RateLimitPlan = :plan REMOVE RateLimit",
+		},
+		{
+			name:               "override only",
+			policy:             policy,
+			expectedExpression: "SET RateLimit = :policy REMOVE RateLimitPlan",
+		},
+		{
+			name:               "reset to default",
+			expectedExpression: "REMOVE RateLimitPlan, RateLimit",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, ownersTableName, *params.TableName)
+					assert.Equal(t, tc.expectedExpression, *params.UpdateExpression)
+					assert.Equal(t, "attribute_exists(OwnerId)", *params.ConditionExpression)
+					if tc.policy != nil {
+						var stored domain.RateLimitPolicy
+						assert.NilError(t, attributevalue.Unmarshal(params.ExpressionAttributeValues[":policy"], &stored))
+						assert.Equal(t, *tc.policy, stored)
+					}
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			err := repo.SetOwnerRateLimit(context.Background(), "test-owner", tc.plan, tc.policy)
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestDynamoDBRepository_SetOwnerRateLimit_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.SetOwnerRateLimit(context.Background(), "missing", "", nil)
+	assert.Assert(t, IsOwnerNotFound(err), "expected ErrOwnerNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_GetRateLimitPlan(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.RateLimitPlan{
+		Name:            "isp-burst",
+		RateLimitPolicy: domain.RateLimitPolicy{MaxChanges: 10, WindowSeconds: 3600, Algorithm: domain.RateLimitSlidingWindow},
+	})
+
+	testCases := []struct {
+		name        string
+		item        map[string]types.AttributeValue
+		expectedErr error
+	}{
+		{name: "found", item: item},
+		{name: "not found", expectedErr: domain.ErrRateLimitPlanNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				getItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					assert.Equal(t, rateLimitPlansTableName, *params.TableName)
+					assert.Equal(t, "isp-burst", params.Key["PlanName"].(*types.AttributeValueMemberS).Value)
+					return &dynamodb.GetItemOutput{Item: tc.item}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			plan, err := repo.GetRateLimitPlan(context.Background(), "isp-burst")
+
+			if tc.expectedErr != nil {
+				assert.Assert(t, IsRateLimitPlanNotFound(err), "expected ErrRateLimitPlanNotFound, got %v", err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, 10, plan.MaxChanges)
+			assert.Equal(t, domain.RateLimitSlidingWindow, plan.Algorithm)
+		})
+	}
+}
+
+func TestDynamoDBRepository_DeleteRateLimitPlan_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		deleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			assert.Equal(t, rateLimitPlansTableName, *params.TableName)
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.DeleteRateLimitPlan(context.Background(), "missing")
+	assert.Assert(t, IsRateLimitPlanNotFound(err), "expected ErrRateLimitPlanNotFound, got %v", err)
+}
+
+func TestDynamoDBRepository_GetOperator(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.Operator{KeyID: "0123456789ab", Name: "alice", KeyHash: "hash"})
+
+	testCases := []struct {
+		name        string
+		item        map[string]types.AttributeValue
+		expectedErr error
+	}{
+		{name: "found", item: item},
+		{name: "not found", expectedErr: domain.ErrOperatorNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockDynamoDBClient{
+				getItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					assert.Equal(t, operatorsTableName, *params.TableName)
+					return &dynamodb.GetItemOutput{Item: tc.item}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			operator, err := repo.GetOperator(context.Background(), "0123456789ab")
+
+			if tc.expectedErr != nil {
+				assert.Assert(t, IsOperatorNotFound(err), "expected ErrOperatorNotFound, got %v", err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, "alice", operator.Name)
+		})
+	}
+}
+
+func TestDynamoDBRepository_ListAuditEntries(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.AuditEntry{
+		AuditDate: "2025-01-15",
+		EntryID:   "2025-01-15T10:00:00Z#abcd",
+		Action:    domain.AuditSuspendOwner,
+		OwnerID:   "test-owner",
+		Details:   map[string]string{"reason": "abuse"},
+	})
+
+	client := &mockDynamoDBClient{
+		queryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, auditLogTableName, *params.TableName)
+			assert.Equal(t, false, *params.ScanIndexForward)
+			date := params.ExpressionAttributeValues[":date"].(*types.AttributeValueMemberS)
+			assert.Equal(t, "2025-01-15", date.Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	entries, err := repo.ListAuditEntries(context.Background(), "2025-01-15")
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, domain.AuditSuspendOwner, entries[0].Action)
+	assert.Equal(t, "abuse", entries[0].Details["reason"])
+}
+
+func TestDynamoDBRepository_GetAuthFailure_NotFound(t *testing.T) {
+	client := &mockDynamoDBClient{
+		getItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			assert.Equal(t, authFailuresTableName, *params.TableName)
+			return &dynamodb.GetItemOutput{}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	failure, err := repo.GetAuthFailure(context.Background(), "ip#203.0.113.50")
+
+	assert.NilError(t, err)
+	assert.Assert(t, failure == nil)
+}
+
+func TestDynamoDBRepository_RecordAuthFailure(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	policy := domain.LockoutPolicy{FreeAttempts: 2, BaseDelay: 30 * time.Second, MaxDelay: time.Minute, Window: time.Hour}
+	conflict := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+
+	testCases := []struct {
+		name             string
+		existing         int
+		expectedFailures int
+		expectedPuts     int
+		expectedLock     bool
+	}{
+		{name: "starts a new count", expectedFailures: 1, expectedPuts: 1},
+		{name: "counts within the window", existing: 1, expectedFailures: 2},
+		{name: "locks out past the free attempts", existing: 2, expectedFailures: 3, expectedLock: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			puts := 0
+			locked := false
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					assert.Equal(t, authFailuresTableName, *params.TableName)
+					if *params.UpdateExpression == "SET LockedUntil = :until" {
+						locked = true
+						return &dynamodb.UpdateItemOutput{}, nil
+					}
+					assert.Equal(t, "ResetAt > :nowUnix", *params.ConditionExpression)
+					if tc.existing == 0 {
+						return nil, conflict
+					}
+					attrs, _ := attributevalue.MarshalMap(domain.AuthFailure{Subject: "ip#203.0.113.50", Failures: tc.existing + 1})
+					return &dynamodb.UpdateItemOutput{Attributes: attrs}, nil
+				},
+				putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					assert.Equal(t, authFailuresTableName, *params.TableName)
+					puts++
+					failures := params.Item["Failures"].(*types.AttributeValueMemberN)
+					assert.Equal(t, "1", failures.Value)
+					ttl := params.Item["TTL"].(*types.AttributeValueMemberN)
+					assert.Equal(t, strconv.FormatInt(policy.Expiry(now).Unix(), 10), ttl.Value)
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			failure, err := repo.RecordAuthFailure(context.Background(), "ip#203.0.113.50", "", "203.0.113.50", policy, now)
+
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expectedFailures, failure.Failures)
+			assert.Equal(t, tc.expectedPuts, puts)
+			assert.Equal(t, tc.expectedLock, locked)
+			if tc.expectedLock {
+				assert.Equal(t, now.Add(30*time.Second), *failure.LockedUntil)
+			}
+		})
+	}
+}
+
+func TestDynamoDBRepository_RecordAuthFailure_GivesUpAfterConflicts(t *testing.T) {
+	conflict := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, conflict
+		},
+		putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			return nil, conflict
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	_, err := repo.RecordAuthFailure(context.Background(), "ip#203.0.113.50", "", "203.0.113.50", domain.IPLockoutPolicy, time.Now())
+	assert.ErrorContains(t, err, "conflict")
+}
+
+func TestDynamoDBRepository_ScanAuthFailureAlerts(t *testing.T) {
+	item, _ := attributevalue.MarshalMap(domain.AuthFailure{Subject: "owner#test-owner", OwnerID: "test-owner", Failures: 12})
+
+	client := &mockDynamoDBClient{
+		scanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			assert.Equal(t, authFailuresTableName, *params.TableName)
+			threshold := params.ExpressionAttributeValues[":threshold"].(*types.AttributeValueMemberN)
+			assert.Equal(t, strconv.Itoa(domain.AuthFailureAlertThreshold), threshold.Value)
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	failures, err := repo.ScanAuthFailureAlerts(context.Background())
+
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(failures))
+	assert.Equal(t, "test-owner", failures[0].OwnerID)
+}
+
+func TestDynamoDBRepository_MarkAuthFailureAlerted_Expired(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, authFailuresTableName, *params.TableName)
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	err := repo.MarkAuthFailureAlerted(context.Background(), "owner#test-owner", time.Now())
+	assert.NilError(t, err)
+}
+
+func TestDynamoDBRepository_TakeRequestToken(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limit := domain.RequestLimit{Burst: 3, Interval: 10 * time.Second}
+	conflict := func(readyAt time.Time) error {
+		return &types.ConditionalCheckFailedException{
+			Message: aws.String("The conditional request failed"),
+			Item: map[string]types.AttributeValue{
+				"Bucket":  &types.AttributeValueMemberS{Value: "update#ip#203.0.113.50"},
+				"ReadyAt": &types.AttributeValueMemberN{Value: strconv.FormatInt(readyAt.UnixMilli(), 10)},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		spendErr           error
+		startErr           error
+		expectedAllowed    bool
+		expectedRetryAfter time.Duration
+		expectedCalls      int
+	}{
+		{
+			name:            "busy bucket with tokens",
+			expectedAllowed: true,
+			expectedCalls:   1,
+		},
+		{
+			name:               "empty bucket",
+			spendErr:           conflict(now.Add(24 * time.Second)),
+			expectedRetryAfter: 4 * time.Second,
+			expectedCalls:      1,
+		},
+		{
+			name:            "refilled bucket",
+			spendErr:        conflict(now.Add(-time.Minute)),
+			expectedAllowed: true,
+			expectedCalls:   2,
+		},
+		{
+			name:            "new bucket",
+			spendErr:        &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			expectedAllowed: true,
+			expectedCalls:   2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			client := &mockDynamoDBClient{
+				updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					calls++
+					assert.Equal(t, requestLimitsTableName, *params.TableName)
+					assert.Equal(t, "update#ip#203.0.113.50", params.Key["Bucket"].(*types.AttributeValueMemberS).Value)
+					if calls == 1 {
+						assert.Equal(t, "SET #ttl = :ttl ADD ReadyAt :interval", *params.UpdateExpression)
+						assert.Equal(t, "10000", params.ExpressionAttributeValues[":interval"].(*types.AttributeValueMemberN).Value)
+						latest := strconv.FormatInt(now.Add(20*time.Second).UnixMilli(), 10)
+						assert.Equal(t, latest, params.ExpressionAttributeValues[":latest"].(*types.AttributeValueMemberN).Value)
+						return &dynamodb.UpdateItemOutput{}, tc.spendErr
+					}
+					assert.Equal(t, "SET ReadyAt = :ready, #ttl = :ttl", *params.UpdateExpression)
+					ready := strconv.FormatInt(now.Add(10*time.Second).UnixMilli(), 10)
+					assert.Equal(t, ready, params.ExpressionAttributeValues[":ready"].(*types.AttributeValueMemberN).Value)
+					return &dynamodb.UpdateItemOutput{}, tc.startErr
+				},
+			}
+
+			repo := NewDynamoDBRepository(client, newTestLogger())
+			retryAfter, allowed, err := repo.TakeRequestToken(context.Background(), "update#ip#203.0.113.50", limit, now)
+
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expectedAllowed, allowed)
+			assert.Equal(t, tc.expectedRetryAfter, retryAfter)
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestDynamoDBRepository_TakeRequestToken_Conflict(t *testing.T) {
+	conflict := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, conflict
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	_, _, err := repo.TakeRequestToken(context.Background(), "update#ip#203.0.113.50", domain.RequestLimit{Burst: 3, Interval: time.Second}, time.Now())
+	assert.ErrorContains(t, err, "conflict")
+}
+
+func TestDynamoDBRepository_TakeRequestToken_Error(t *testing.T) {
+	client := &mockDynamoDBClient{
+		updateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return nil, errors.New("throttled")
+		},
+	}
+
+	repo := NewDynamoDBRepository(client, newTestLogger())
+	_, allowed, err := repo.TakeRequestToken(context.Background(), "update#ip#203.0.113.50", domain.RequestLimit{Burst: 3, Interval: time.Second}, time.Now())
+	assert.ErrorContains(t, err, "throttled")
+	assert.Assert(t, !allowed)
 }
 
 func TestIsOwnerExists(t *testing.T) {
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/grocky/ddns-service/internal/domain"
 )
@@ -15,14 +16,47 @@ type Repository interface {
 	// Get retrieves an IP mapping by owner ID and location.
 	Get(ctx context.Context, ownerID, location string) (*domain.IPMapping, error)
 
-	// CreateOwner creates a new owner. Returns ErrOwnerExists if owner already exists.
+	// Delete removes an IP mapping.
+	Delete(ctx context.Context, ownerID, location string) error
+
+	// ListMappingsBySubdomain returns every mapping that stores the given subdomain.
+	ListMappingsBySubdomain(ctx context.Context, subdomain string) ([]domain.IPMapping, error)
+
+	// ClaimSubdomain reserves a subdomain for an owner's location. Claiming a
+	// subdomain the location already holds succeeds. Returns ErrSubdomainTaken if
+	// another location holds it.
+	ClaimSubdomain(ctx context.Context, subdomain, ownerID, location string) error
+
+	// ReleaseSubdomain gives up a location's claim on a subdomain it has moved
+	// away from. A claim held by another location, or no claim, is left alone.
+	ReleaseSubdomain(ctx context.Context, subdomain, ownerID, location string) error
+
+	// CreateOwner creates a new owner. Returns ErrOwnerExists if owner already exists,
+	// unless it is an unverified owner that has expired or has the same email address.
 	CreateOwner(ctx context.Context, owner domain.Owner) error
 
+	// VerifyOwner marks a pending owner's email address as verified. keyHash must
+	// match the owner's primary key hash. Returns ErrOwnerNotFound if there is no
+	// such pending, unexpired owner.
+	VerifyOwner(ctx context.Context, ownerID, keyHash string, verifiedAt time.Time) error
+
 	// GetOwner retrieves an owner by ID. Returns ErrOwnerNotFound if not found.
 	GetOwner(ctx context.Context, ownerID string) (*domain.Owner, error)
 
-	// UpdateOwnerKey updates the API key hash for an owner.
-	UpdateOwnerKey(ctx context.Context, ownerID, newKeyHash string) error
+	// UpdateOwnerKey replaces the primary API key hash, sealed signing secret and
+	// expiry for an owner, clearing the old key's usage data. When previous is set the old key keeps
+	// working until previous.ExpiresAt, and ErrOwnerNotFound is returned if it is
+	// no longer the primary key; otherwise any previous key is removed.
+	UpdateOwnerKey(ctx context.Context, ownerID, keyID, newKeyHash, signingSecret string, expiresAt *time.Time, previous *domain.PreviousKey) error
+
+	// RevokePreviousOwnerKey stops the previous primary key working before its
+	// grace period ends. Returns ErrAPIKeyNotFound if there is none.
+	RevokePreviousOwnerKey(ctx context.Context, ownerID string) error
+
+	// ReplaceOwnerKey is UpdateOwnerKey for a key without expiry, but only while the
+	// owner's primary key hash is still oldKeyHash. Returns ErrOwnerNotFound if the
+	// owner doesn't exist or their key has changed.
+	ReplaceOwnerKey(ctx context.Context, ownerID, oldKeyHash, keyID, newKeyHash, signingSecret string) error
 
 	// PutChallenge creates or updates an ACME challenge.
 	PutChallenge(ctx context.Context, challenge domain.ACMEChallenge) error
@@ -35,6 +69,184 @@ type Repository interface {
 
 	// ScanExpiredChallenges returns all ACME challenges that have expired.
 	ScanExpiredChallenges(ctx context.Context) ([]domain.ACMEChallenge, error)
+
+	// CreateRecordSet stores a new custom record set and counts it against the
+	// owner's quota of limit record sets. Returns ErrRecordSetExists if it already
+	// exists, ErrRecordSetQuotaExceeded if the owner already has limit record sets
+	// and ErrOwnerNotFound if the owner doesn't exist.
+	CreateRecordSet(ctx context.Context, rs domain.RecordSet, limit int) error
+
+	// PutRecordSet replaces an existing custom record set. Returns
+	// ErrRecordSetNotFound if it doesn't exist.
+	PutRecordSet(ctx context.Context, rs domain.RecordSet) error
+
+	// GetRecordSet retrieves a custom record set. Returns ErrRecordSetNotFound if not found.
+	GetRecordSet(ctx context.Context, ownerID, location, name, recordType string) (*domain.RecordSet, error)
+
+	// ListRecordSets returns all custom record sets for an owner's location.
+	ListRecordSets(ctx context.Context, ownerID, location string) ([]domain.RecordSet, error)
+
+	// CountRecordSets returns the number of custom record sets an owner has across all locations.
+	CountRecordSets(ctx context.Context, ownerID string) (int, error)
+
+	// DeleteRecordSet removes a custom record set and stops counting it against
+	// the owner's quota.
+	DeleteRecordSet(ctx context.Context, ownerID, location, name, recordType string) error
+
+	// CreateAPIKey stores a new additional API key for an owner and counts it
+	// against MaxAPIKeys. Returns ErrAPIKeyQuotaExceeded if the owner already has
+	// MaxAPIKeys keys, or ErrOwnerNotFound if the owner doesn't exist.
+	CreateAPIKey(ctx context.Context, key domain.APIKey) error
+
+	// ListAPIKeys returns all additional API keys for an owner.
+	ListAPIKeys(ctx context.Context, ownerID string) ([]domain.APIKey, error)
+
+	// GetKeyOwnerID returns the owner of the primary, previous or additional key
+	// with the given embedded key ID. Returns ErrAPIKeyNotFound if no key has that ID.
+	GetKeyOwnerID(ctx context.Context, keyID string) (string, error)
+
+	// DeleteAPIKey removes an additional API key and stops counting it against
+	// MaxAPIKeys. Returns ErrAPIKeyNotFound if not found.
+	DeleteAPIKey(ctx context.Context, ownerID, keyID string) error
+
+	// RecordAPIKeyUse stores when and from where a key was last used.
+	// PrimaryKeyID and PreviousKeyID refer to the keys stored on the owner.
+	RecordAPIKeyUse(ctx context.Context, ownerID, keyID string, usedAt time.Time, ip string) error
+
+	// MarkAPIKeyExpiryWarned records that the owner was warned about the key's expiry.
+	// PrimaryKeyID refers to the key stored on the owner.
+	MarkAPIKeyExpiryWarned(ctx context.Context, ownerID, keyID string, warnedAt time.Time) error
+
+	// ScanExpiringAPIKeys returns unexpired keys, primary keys included, that expire
+	// before the given time and whose owners haven't been warned yet.
+	ScanExpiringAPIKeys(ctx context.Context, before time.Time) ([]domain.APIKey, error)
+
+	// UseNonce records a signed request's nonce for a key until expiresAt.
+	// Returns ErrNonceReused if the key already used the nonce.
+	UseNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) error
+
+	// ListMappingsByOwner returns every IP mapping for an owner.
+	ListMappingsByOwner(ctx context.Context, ownerID string) ([]domain.IPMapping, error)
+
+	// ListOwners returns every owner.
+	ListOwners(ctx context.Context) ([]domain.Owner, error)
+
+	// SetOwnerSuspension suspends an owner, or lifts the suspension when suspendedAt
+	// is nil. Returns ErrOwnerNotFound if the owner doesn't exist.
+	SetOwnerSuspension(ctx context.Context, ownerID string, suspendedAt *time.Time, reason string) error
+
+	// SetOwnerRateLimit assigns an owner's rate limit plan, empty for the default
+	// plan, and sets or clears (nil) the owner's policy override. Returns
+	// ErrOwnerNotFound if the owner doesn't exist.
+	SetOwnerRateLimit(ctx context.Context, ownerID, plan string, policy *domain.RateLimitPolicy) error
+
+	// ClaimACMEChallenge atomically counts a new ACME challenge against the
+	// owner's limit and returns the owner's count in the current window.
+	// Returns ErrACMERateLimitExceeded if the window is full and
+	// ErrOwnerNotFound if the owner doesn't exist.
+	ClaimACMEChallenge(ctx context.Context, ownerID string, limit domain.ACMEChallengeLimit, now time.Time) (int, error)
+
+	// SetOwnerNotifications replaces an owner's notification preferences, or
+	// clears them when prefs is nil. Returns ErrOwnerNotFound if the owner
+	// doesn't exist.
+	SetOwnerNotifications(ctx context.Context, ownerID string, prefs *domain.NotificationPreferences) error
+
+	// SetOwnerLocale sets the locale of an owner's emails, or clears it when
+	// locale is empty. Returns ErrOwnerNotFound if the owner doesn't exist.
+	SetOwnerLocale(ctx context.Context, ownerID, locale string) error
+
+	// ScanPendingIPChanges returns the mappings with IP changes the owner hasn't
+	// been emailed about yet.
+	ScanPendingIPChanges(ctx context.Context) ([]domain.IPMapping, error)
+
+	// MarkIPChangesNotified records that the owner was emailed about a mapping's
+	// pending IP changes, and clears them.
+	MarkIPChangesNotified(ctx context.Context, ownerID, location string, notifiedAt time.Time) error
+
+	// TouchLocation records that a location checked in at seenAt and clears any
+	// stale alert, without rewriting the rest of the mapping.
+	TouchLocation(ctx context.Context, ownerID, location string, seenAt time.Time) error
+
+	// ScanMonitoredLocations returns the mappings with a stale threshold.
+	ScanMonitoredLocations(ctx context.Context) ([]domain.IPMapping, error)
+
+	// MarkLocationStaleAlerted records that the owner was told the location has
+	// gone silent.
+	MarkLocationStaleAlerted(ctx context.Context, ownerID, location string, alertedAt time.Time) error
+
+	// CreateWebhook stores a new webhook for an owner.
+	CreateWebhook(ctx context.Context, webhook domain.Webhook) error
+
+	// ListWebhooks returns all webhooks for an owner.
+	ListWebhooks(ctx context.Context, ownerID string) ([]domain.Webhook, error)
+
+	// GetWebhook retrieves an owner's webhook. Returns ErrWebhookNotFound if not found.
+	GetWebhook(ctx context.Context, ownerID, webhookID string) (*domain.Webhook, error)
+
+	// DeleteWebhook removes a webhook. Returns ErrWebhookNotFound if not found.
+	DeleteWebhook(ctx context.Context, ownerID, webhookID string) error
+
+	// PutWebhookDelivery creates or updates a webhook delivery.
+	PutWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+
+	// ListWebhookDeliveries returns a webhook's latest deliveries, newest first,
+	// at most limit of them.
+	ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]domain.WebhookDelivery, error)
+
+	// ScanPendingWebhookDeliveries returns the deliveries that are waiting to be retried.
+	ScanPendingWebhookDeliveries(ctx context.Context) ([]domain.WebhookDelivery, error)
+
+	// GetRateLimitPlan retrieves a rate limit plan by name. Returns
+	// ErrRateLimitPlanNotFound if not found.
+	GetRateLimitPlan(ctx context.Context, name string) (*domain.RateLimitPlan, error)
+
+	// ListRateLimitPlans returns every stored rate limit plan.
+	ListRateLimitPlans(ctx context.Context) ([]domain.RateLimitPlan, error)
+
+	// PutRateLimitPlan creates or replaces a rate limit plan.
+	PutRateLimitPlan(ctx context.Context, plan domain.RateLimitPlan) error
+
+	// DeleteRateLimitPlan removes a rate limit plan. Returns ErrRateLimitPlanNotFound
+	// if not found.
+	DeleteRateLimitPlan(ctx context.Context, name string) error
+
+	// CreateOperator stores a new operator.
+	CreateOperator(ctx context.Context, operator domain.Operator) error
+
+	// GetOperator retrieves an operator by key ID. Returns ErrOperatorNotFound if not found.
+	GetOperator(ctx context.Context, keyID string) (*domain.Operator, error)
+
+	// DeleteOperator removes an operator. Returns ErrOperatorNotFound if not found.
+	DeleteOperator(ctx context.Context, keyID string) error
+
+	// PutAuditEntry appends an entry to the audit log.
+	PutAuditEntry(ctx context.Context, entry domain.AuditEntry) error
+
+	// ListAuditEntries returns the audit log entries for a UTC day, newest first.
+	ListAuditEntries(ctx context.Context, date string) ([]domain.AuditEntry, error)
+
+	// GetAuthFailure returns the failed authentication attempts counted against a
+	// subject, or nil if there are none.
+	GetAuthFailure(ctx context.Context, subject string) (*domain.AuthFailure, error)
+
+	// RecordAuthFailure atomically counts a failed attempt against a subject and
+	// applies the policy's lockout. ownerID is stored for owner subjects.
+	RecordAuthFailure(ctx context.Context, subject, ownerID, ip string, policy domain.LockoutPolicy, now time.Time) (*domain.AuthFailure, error)
+
+	// ClearAuthFailures forgets a subject's failed attempts.
+	ClearAuthFailures(ctx context.Context, subject string) error
+
+	// ScanAuthFailureAlerts returns owner subjects that reached
+	// domain.AuthFailureAlertThreshold and haven't been alerted about yet.
+	ScanAuthFailureAlerts(ctx context.Context) ([]domain.AuthFailure, error)
+
+	// MarkAuthFailureAlerted records that the owner was alerted about a subject's failures.
+	MarkAuthFailureAlerted(ctx context.Context, subject string, alertedAt time.Time) error
+
+	// TakeRequestToken atomically takes one token from a request bucket under the
+	// given limit. When the bucket is empty it returns false and how long until a
+	// token is available.
+	TakeRequestToken(ctx context.Context, bucket string, limit domain.RequestLimit, now time.Time) (time.Duration, bool, error)
 }
 
 // IsOwnerNotFound returns true if the error is ErrOwnerNotFound.
@@ -52,7 +264,42 @@ func IsMappingNotFound(err error) bool {
 	return errors.Is(err, domain.ErrMappingNotFound)
 }
 
+// IsRecordSetNotFound returns true if the error is ErrRecordSetNotFound.
+func IsRecordSetNotFound(err error) bool {
+	return errors.Is(err, domain.ErrRecordSetNotFound)
+}
+
+// IsRecordSetExists returns true if the error is ErrRecordSetExists.
+func IsRecordSetExists(err error) bool {
+	return errors.Is(err, domain.ErrRecordSetExists)
+}
+
 // IsChallengeNotFound returns true if the error is ErrChallengeNotFound.
 func IsChallengeNotFound(err error) bool {
 	return errors.Is(err, domain.ErrChallengeNotFound)
 }
+
+// IsAPIKeyNotFound returns true if the error is ErrAPIKeyNotFound.
+func IsAPIKeyNotFound(err error) bool {
+	return errors.Is(err, domain.ErrAPIKeyNotFound)
+}
+
+// IsOperatorNotFound returns true if the error is ErrOperatorNotFound.
+func IsOperatorNotFound(err error) bool {
+	return errors.Is(err, domain.ErrOperatorNotFound)
+}
+
+// IsRateLimitPlanNotFound returns true if the error is ErrRateLimitPlanNotFound.
+func IsRateLimitPlanNotFound(err error) bool {
+	return errors.Is(err, domain.ErrRateLimitPlanNotFound)
+}
+
+// IsWebhookNotFound returns true if the error is ErrWebhookNotFound.
+func IsWebhookNotFound(err error) bool {
+	return errors.Is(err, domain.ErrWebhookNotFound)
+}
+
+// IsNonceReused returns true if the error is ErrNonceReused.
+func IsNonceReused(err error) bool {
+	return errors.Is(err, domain.ErrNonceReused)
+}
@@ -3,6 +3,7 @@ package response
 import (
 	"encoding/json"
 	"log/slog"
+	"strconv"
 )
 
 // ClientIPResponse represents a successful response containing the client's IP.
@@ -18,8 +19,9 @@ type ClientIPBody struct {
 
 // MappingResponse represents a response containing an IP mapping.
 type MappingResponse struct {
-	Status int
-	Body   MappingBody
+	Status    int
+	Body      MappingBody
+	RateLimit *RateLimitStatus
 }
 
 // MappingBody is the JSON body for a mapping response.
@@ -29,7 +31,16 @@ type MappingBody struct {
 	IP        string `json:"ip"`
 	Subdomain string `json:"subdomain"`
 	Changed   bool   `json:"changed,omitempty"`
+	// Dampened is set when the location is flapping and the change to PendingIP
+	// was held back; IP is still the published address.
+	Dampened  bool   `json:"dampened,omitempty"`
+	PendingIP string `json:"pendingIp,omitempty"`
 	UpdatedAt string `json:"updatedAt"`
+
+	// LastSeenAt and Status are only set by lookups. Status is "online" or
+	// "offline" for locations with a stale threshold.
+	LastSeenAt string `json:"lastSeenAt,omitempty"`
+	Status     string `json:"status,omitempty"`
 }
 
 // OwnerResponse represents a response containing owner information.
@@ -45,6 +56,62 @@ type OwnerBody struct {
 	APIKey    string `json:"apiKey,omitempty"`
 	CreatedAt string `json:"createdAt,omitempty"`
 	RotatedAt string `json:"rotatedAt,omitempty"`
+	// SigningSecret signs requests made with APIKey. Like the key, it is only
+	// returned when the key is issued.
+	SigningSecret string `json:"signingSecret,omitempty"`
+	ExpiresAt     string `json:"expiresAt,omitempty"`
+	// VerifyBy is when a new owner expires unless their email address is verified.
+	VerifyBy string `json:"verifyBy,omitempty"`
+	// PreviousKeyExpiresAt is when the key replaced by a rotation stops working.
+	PreviousKeyExpiresAt string `json:"previousKeyExpiresAt,omitempty"`
+
+	// PrimaryKey describes the primary key without its secret.
+	PrimaryKey *APIKeyBody `json:"primaryKey,omitempty"`
+	// PreviousKey describes the key replaced by the last rotation, if kept.
+	PreviousKey *APIKeyBody `json:"previousKey,omitempty"`
+
+	// Notifications lists the emails the owner has opted in to.
+	Notifications *NotificationsBody `json:"notifications,omitempty"`
+
+	// Locale is the language of the owner's emails, if not the default.
+	Locale string `json:"locale,omitempty"`
+}
+
+// LocaleResponse represents a response containing an owner's email locale.
+type LocaleResponse struct {
+	Status int
+	Body   LocaleBody
+}
+
+// LocaleBody is the JSON body for an owner's email locale. An empty locale
+// means the default.
+type LocaleBody struct {
+	OwnerID string `json:"ownerId"`
+	Locale  string `json:"locale"`
+}
+
+// NotificationsResponse represents a response containing an owner's notification preferences.
+type NotificationsResponse struct {
+	Status int
+	Body   NotificationsBody
+}
+
+// NotificationsBody is the JSON body for an owner's notification preferences.
+type NotificationsBody struct {
+	OwnerID  string                     `json:"ownerId,omitempty"`
+	IPChange *IPChangeNotificationsBody `json:"ipChange,omitempty"`
+	Security *SecurityNotificationsBody `json:"security,omitempty"`
+}
+
+// IPChangeNotificationsBody is the JSON body for IP change notifications. No
+// locations means every location.
+type IPChangeNotificationsBody struct {
+	Locations []string `json:"locations,omitempty"`
+}
+
+// SecurityNotificationsBody is the JSON body for suppressed security notices.
+type SecurityNotificationsBody struct {
+	Suppress []string `json:"suppress"`
 }
 
 // MessageResponse represents a simple message response.
@@ -60,8 +127,9 @@ type MessageBody struct {
 
 // ACMEChallengeResponse represents a response for creating an ACME challenge.
 type ACMEChallengeResponse struct {
-	Status int
-	Body   ACMEChallengeBody
+	Status    int
+	Body      ACMEChallengeBody
+	RateLimit *RateLimitStatus
 }
 
 // ACMEChallengeBody is the JSON body for an ACME challenge response.
@@ -77,8 +145,9 @@ type ACMEChallengeBody struct {
 
 // ACMEDeleteResponse represents a response for deleting an ACME challenge.
 type ACMEDeleteResponse struct {
-	Status int
-	Body   ACMEDeleteBody
+	Status    int
+	Body      ACMEDeleteBody
+	RateLimit *RateLimitStatus
 }
 
 // ACMEDeleteBody is the JSON body for an ACME delete response.
@@ -90,16 +159,318 @@ type ACMEDeleteBody struct {
 	Deleted   bool   `json:"deleted"`
 }
 
+// LocationResponse represents a response containing a location's settings.
+type LocationResponse struct {
+	Status int
+	Body   LocationBody
+}
+
+// LocationBody is the JSON body for a location response.
+type LocationBody struct {
+	OwnerID      string `json:"ownerId"`
+	Location     string `json:"location"`
+	IP           string `json:"ip"`
+	Subdomain    string `json:"subdomain"`
+	Wildcard     bool   `json:"wildcard"`
+	WildcardName string `json:"wildcardName,omitempty"`
+	UpdatedAt    string `json:"updatedAt,omitempty"`
+	Deleted      bool   `json:"deleted,omitempty"`
+
+	Damping *DampingBody `json:"damping,omitempty"`
+
+	LastSeenAt        string `json:"lastSeenAt,omitempty"`
+	StaleAfterSeconds int    `json:"staleAfterSeconds,omitempty"`
+	Status            string `json:"status,omitempty"`
+}
+
+// DampingBody is the JSON body for a location's damping policy.
+type DampingBody struct {
+	Mode              string `json:"mode"`
+	FlapWindowSeconds int    `json:"flapWindowSeconds"`
+	SettleSeconds     int    `json:"settleSeconds,omitempty"`
+	Notify            bool   `json:"notify"`
+	PendingIP         string `json:"pendingIp,omitempty"`
+	PendingSince      string `json:"pendingSince,omitempty"`
+}
+
+// RecordSetResponse represents a response containing a single custom record set.
+type RecordSetResponse struct {
+	Status int
+	Body   RecordSetBody
+}
+
+// RecordSetBody is the JSON body for a custom record set.
+type RecordSetBody struct {
+	Location  string   `json:"location"`
+	Name      string   `json:"name"`
+	FQDN      string   `json:"fqdn"`
+	Type      string   `json:"type"`
+	TTL       int64    `json:"ttl"`
+	Values    []string `json:"values"`
+	CreatedAt string   `json:"createdAt,omitempty"`
+	UpdatedAt string   `json:"updatedAt,omitempty"`
+	Deleted   bool     `json:"deleted,omitempty"`
+}
+
+// RecordSetListResponse represents a response listing a location's custom record sets.
+type RecordSetListResponse struct {
+	Status int
+	Body   RecordSetListBody
+}
+
+// RecordSetListBody is the JSON body for a record set listing.
+type RecordSetListBody struct {
+	OwnerID    string          `json:"ownerId"`
+	Location   string          `json:"location"`
+	Subdomain  string          `json:"subdomain"`
+	RecordSets []RecordSetBody `json:"recordSets"`
+}
+
+// APIKeyResponse represents a response containing a single API key.
+type APIKeyResponse struct {
+	Status int
+	Body   APIKeyBody
+}
+
+// APIKeyBody is the JSON body for an API key. The plaintext key and its signing
+// secret are only included when the key is created.
+type APIKeyBody struct {
+	OwnerID   string   `json:"ownerId"`
+	KeyID     string   `json:"keyId"`
+	Label     string   `json:"label"`
+	Scopes    []string `json:"scopes"`
+	Locations []string `json:"locations,omitempty"`
+	APIKey    string   `json:"apiKey,omitempty"`
+	CreatedAt string   `json:"createdAt,omitempty"`
+	ExpiresAt string   `json:"expiresAt,omitempty"`
+	Expired   bool     `json:"expired,omitempty"`
+
+	SigningSecret string `json:"signingSecret,omitempty"`
+
+	LastUsedAt string `json:"lastUsedAt,omitempty"`
+	LastUsedIP string `json:"lastUsedIp,omitempty"`
+
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// APIKeyListResponse represents a response listing an owner's API keys.
+type APIKeyListResponse struct {
+	Status int
+	Body   APIKeyListBody
+}
+
+// APIKeyListBody is the JSON body for an API key listing.
+type APIKeyListBody struct {
+	OwnerID string       `json:"ownerId"`
+	Keys    []APIKeyBody `json:"keys"`
+}
+
+// WebhookResponse represents a response containing a single webhook.
+type WebhookResponse struct {
+	Status int
+	Body   WebhookBody
+}
+
+// WebhookBody is the JSON body for a webhook. The secret is only included
+// when the webhook is created.
+type WebhookBody struct {
+	OwnerID   string   `json:"ownerId"`
+	WebhookID string   `json:"webhookId"`
+	URL       string   `json:"url,omitempty"`
+	Events    []string `json:"events,omitempty"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedAt string   `json:"createdAt,omitempty"`
+	Deleted   bool     `json:"deleted,omitempty"`
+}
+
+// WebhookListResponse represents a response listing an owner's webhooks.
+type WebhookListResponse struct {
+	Status int
+	Body   WebhookListBody
+}
+
+// WebhookListBody is the JSON body for a webhook listing.
+type WebhookListBody struct {
+	OwnerID  string        `json:"ownerId"`
+	Webhooks []WebhookBody `json:"webhooks"`
+}
+
+// WebhookDeliveryListResponse represents a response listing a webhook's deliveries.
+type WebhookDeliveryListResponse struct {
+	Status int
+	Body   WebhookDeliveryListBody
+}
+
+// WebhookDeliveryListBody is the JSON body for a webhook's delivery history.
+type WebhookDeliveryListBody struct {
+	OwnerID    string                `json:"ownerId"`
+	WebhookID  string                `json:"webhookId"`
+	Deliveries []WebhookDeliveryBody `json:"deliveries"`
+}
+
+// WebhookDeliveryBody is the JSON body for a webhook delivery.
+type WebhookDeliveryBody struct {
+	DeliveryID     string          `json:"deliveryId"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	CreatedAt      string          `json:"createdAt"`
+	LastAttemptAt  string          `json:"lastAttemptAt,omitempty"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	NextAttemptAt  string          `json:"nextAttemptAt,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// AdminOwnerResponse represents an operator's view of a single owner.
+type AdminOwnerResponse struct {
+	Status int
+	Body   AdminOwnerBody
+}
+
+// AdminOwnerBody is the JSON body describing an owner to an operator. Mappings
+// are only included when a single owner is requested.
+type AdminOwnerBody struct {
+	OwnerID         string             `json:"ownerId"`
+	Email           string             `json:"email,omitempty"`
+	CreatedAt       string             `json:"createdAt,omitempty"`
+	Suspended       bool               `json:"suspended"`
+	SuspendedAt     string             `json:"suspendedAt,omitempty"`
+	SuspendedReason string             `json:"suspendedReason,omitempty"`
+	VerifyBy        string             `json:"verifyBy,omitempty"`
+	RateLimit       *RateLimitBody     `json:"rateLimit,omitempty"`
+	Mappings        []AdminMappingBody `json:"mappings,omitempty"`
+}
+
+// AdminOwnerListResponse represents a response listing owners for an operator.
+type AdminOwnerListResponse struct {
+	Status int
+	Body   AdminOwnerListBody
+}
+
+// AdminOwnerListBody is the JSON body for an owner listing.
+type AdminOwnerListBody struct {
+	Owners []AdminOwnerBody `json:"owners"`
+}
+
+// AdminMappingResponse represents an operator's view of a single mapping.
+type AdminMappingResponse struct {
+	Status int
+	Body   AdminMappingBody
+}
+
+// AdminMappingBody is the JSON body describing a mapping, including its rate
+// limit state, to an operator.
+type AdminMappingBody struct {
+	OwnerID           string   `json:"ownerId"`
+	Location          string   `json:"location"`
+	IP                string   `json:"ip"`
+	Subdomain         string   `json:"subdomain"`
+	Wildcard          bool     `json:"wildcard"`
+	UpdatedAt         string   `json:"updatedAt,omitempty"`
+	HourlyChangeCount int      `json:"hourlyChangeCount"`
+	LastIPChangeAt    string   `json:"lastIpChangeAt,omitempty"`
+	RecentIPChanges   []string `json:"recentIpChanges,omitempty"`
+}
+
+// RateLimitPlanResponse represents a response describing a rate limit plan.
+type RateLimitPlanResponse struct {
+	Status int
+	Body   RateLimitBody
+}
+
+// RateLimitPlanListResponse represents a response listing rate limit plans.
+type RateLimitPlanListResponse struct {
+	Status int
+	Body   RateLimitPlanListBody
+}
+
+// RateLimitPlanListBody is the JSON body for a rate limit plan listing.
+type RateLimitPlanListBody struct {
+	Plans []RateLimitBody `json:"plans"`
+}
+
+// AuditListResponse represents a response listing audit log entries.
+type AuditListResponse struct {
+	Status int
+	Body   AuditListBody
+}
+
+// AuditListBody is the JSON body for an audit log listing.
+type AuditListBody struct {
+	Date    string           `json:"date"`
+	Entries []AuditEntryBody `json:"entries"`
+}
+
+// AuditEntryBody is the JSON body for a single audit log entry.
+type AuditEntryBody struct {
+	EntryID      string            `json:"entryId"`
+	OperatorID   string            `json:"operatorId"`
+	OperatorName string            `json:"operatorName"`
+	Action       string            `json:"action"`
+	OwnerID      string            `json:"ownerId,omitempty"`
+	Location     string            `json:"location,omitempty"`
+	Details      map[string]string `json:"details,omitempty"`
+	Outcome      string            `json:"outcome"`
+	Error        string            `json:"error,omitempty"`
+	SourceIP     string            `json:"sourceIp,omitempty"`
+	CreatedAt    string            `json:"createdAt"`
+}
+
+// DynDNSResponse represents a plain-text dyndns2 protocol reply.
+type DynDNSResponse struct {
+	Status int
+	// Body holds one return code per hostname, e.g. "good 203.0.113.42".
+	Body       string
+	RetryAfter int
+}
+
 // ErrorBody is the JSON body for error responses.
 type ErrorBody struct {
-	Description string `json:"description"`
+	Description string         `json:"description"`
+	RateLimit   *RateLimitBody `json:"rateLimit,omitempty"`
+}
+
+// RateLimitBody describes the rate limit policy applied to an owner. Plan is
+// "owner" when an operator set the policy on the owner directly.
+type RateLimitBody struct {
+	Plan          string `json:"plan"`
+	MaxChanges    int    `json:"maxChanges"`
+	WindowSeconds int    `json:"windowSeconds"`
+	Algorithm     string `json:"algorithm"`
+	UpdatedAt     string `json:"updatedAt,omitempty"`
+}
+
+// Rate limit status headers, as in the IETF RateLimit header fields draft.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimitStatus is how much of a rate limit is left, reported in the
+// RateLimit-* headers: a location's IP change limit, or on ACME responses the
+// owner's ACME challenge limit.
+type RateLimitStatus struct {
+	Limit     int
+	Remaining int
+	Reset     int // Seconds until the limit resets
+}
+
+// SetHeaders adds the RateLimit-* headers to headers.
+func (s *RateLimitStatus) SetHeaders(headers map[string]string) {
+	headers[RateLimitLimitHeader] = strconv.Itoa(s.Limit)
+	headers[RateLimitRemainingHeader] = strconv.Itoa(s.Remaining)
+	headers[RateLimitResetHeader] = strconv.Itoa(s.Reset)
 }
 
 // RequestError represents an error with an associated HTTP status code.
 type RequestError struct {
-	Status      int
-	Description string
-	RetryAfter  int // Seconds until retry is allowed (for rate limiting)
+	Status          int
+	Description     string
+	RetryAfter      int              // Seconds until retry is allowed (for rate limiting)
+	RateLimit       *RateLimitBody   // The policy that was exceeded (for rate limiting)
+	RateLimitStatus *RateLimitStatus // The exhausted limit (for rate limiting)
 }
 
 // Error implements the error interface.
@@ -107,6 +478,19 @@ func (e *RequestError) Error() string {
 	return e.Description
 }
 
+// BuildRequestErrorJSON marshals a request error to JSON, including the rate
+// limit policy when one was exceeded.
+// If marshaling fails, it logs the error and returns a fallback message.
+func BuildRequestErrorJSON(reqErr *RequestError, logger *slog.Logger) string {
+	body := ErrorBody{Description: reqErr.Description, RateLimit: reqErr.RateLimit}
+	js, err := json.Marshal(body)
+	if err != nil {
+		logger.Error("failed to marshal error response", "error", err)
+		return `{"description":"internal server error"}`
+	}
+	return string(js)
+}
+
 // BuildErrorJSON marshals an error description to JSON.
 // If marshaling fails, it logs the error and returns a fallback message.
 func BuildErrorJSON(description string, logger *slog.Logger) string {
@@ -54,6 +54,52 @@ func TestBuildErrorJSON(t *testing.T) {
 	}
 }
 
+func TestBuildRequestErrorJSON(t *testing.T) {
+	logger := newTestLogger()
+
+	testCases := []struct {
+		name     string
+		reqErr   *RequestError
+		expected string
+	}{
+		{
+			name:     "description only",
+			reqErr:   &RequestError{Status: 400, Description: "bad request"},
+			expected: `{"description":"bad request"}`,
+		},
+		{
+			name: "rate limited",
+			reqErr: &RequestError{
+				Status:      429,
+				Description: "rate limit exceeded",
+				RetryAfter:  60,
+				RateLimit:   &RateLimitBody{Plan: "default", MaxChanges: 2, WindowSeconds: 3600, Algorithm: "fixed"},
+			},
+			expected: `{"description":"rate limit exceeded","rateLimit":{"plan":"default","maxChanges":2,"windowSeconds":3600,"algorithm":"fixed"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, BuildRequestErrorJSON(tc.reqErr, logger))
+		})
+	}
+}
+
+func TestRateLimitStatus_SetHeaders(t *testing.T) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	status := &RateLimitStatus{Limit: 2, Remaining: 1, Reset: 900}
+
+	status.SetHeaders(headers)
+
+	assert.DeepEqual(t, map[string]string{
+		"Content-Type":        "application/json",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "900",
+	}, headers)
+}
+
 func TestClientIPResponse(t *testing.T) {
 	resp := ClientIPResponse{
 		Status: 200,
@@ -0,0 +1,70 @@
+package signing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// MinSecretKeyLength is the shortest key NewSecretBox accepts.
+const MinSecretKeyLength = 32
+
+// Errors returned by NewSecretBox and Open.
+var (
+	ErrSecretKeyTooShort = errors.New("signing secret key must be at least 32 bytes")
+	ErrSealedSecret      = errors.New("invalid sealed signing secret")
+)
+
+// SecretBox encrypts signing secrets for storage with a server-side key, so the
+// stored form is useless to anyone who can read the tables but not the key.
+// Each secret is sealed with AES-256-GCM and bound to its key ID, so a sealed
+// secret copied to another key doesn't open.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox whose AES key is the SHA-256 of key.
+func NewSecretBox(key string) (*SecretBox, error) {
+	if len(key) < MinSecretKeyLength {
+		return nil, ErrSecretKeyTooShort
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts secret for the key with keyID. The result is base64url
+// without padding: the random nonce followed by the ciphertext.
+func (b *SecretBox) Seal(secret, keyID string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to seal signing secret: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), []byte(keyID))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret sealed for the key with keyID.
+func (b *SecretBox) Open(sealed, keyID string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrSealedSecret
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", ErrSealedSecret
+	}
+	return string(secret), nil
+}
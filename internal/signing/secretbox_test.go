@@ -0,0 +1,64 @@
+package signing
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const testSecretKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewSecretBox_KeyTooShort(t *testing.T) {
+	_, err := NewSecretBox("short")
+
+	assert.Equal(t, ErrSecretKeyTooShort, err)
+}
+
+func TestSecretBox_SealAndOpen(t *testing.T) {
+	box, err := NewSecretBox(testSecretKey)
+	assert.NilError(t, err)
+
+	sealed, err := box.Seal(testSecret, "0123456789ab")
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(sealed, testSecret))
+
+	again, err := box.Seal(testSecret, "0123456789ab")
+	assert.NilError(t, err)
+	assert.Assert(t, sealed != again, "each seal uses a fresh nonce")
+
+	opened, err := box.Open(sealed, "0123456789ab")
+	assert.NilError(t, err)
+	assert.Equal(t, testSecret, opened)
+}
+
+func TestSecretBox_OpenErrors(t *testing.T) {
+	box, err := NewSecretBox(testSecretKey)
+	assert.NilError(t, err)
+	otherBox, err := NewSecretBox("fedcba9876543210fedcba9876543210")
+	assert.NilError(t, err)
+
+	sealed, err := box.Seal(testSecret, "0123456789ab")
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name   string
+		box    *SecretBox
+		sealed string
+		keyID  string
+	}{
+		{name: "other key ID", box: box, sealed: sealed, keyID: "ba9876543210"},
+		{name: "other server key", box: otherBox, sealed: sealed, keyID: "0123456789ab"},
+		{name: "tampered", box: box, sealed: sealed[:len(sealed)-2] + "AA", keyID: "0123456789ab"},
+		{name: "not base64", box: box, sealed: "!!!", keyID: "0123456789ab"},
+		{name: "too short", box: box, sealed: "AAAA", keyID: "0123456789ab"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.box.Open(tc.sealed, tc.keyID)
+
+			assert.Equal(t, ErrSealedSecret, err)
+		})
+	}
+}
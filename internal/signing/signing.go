@@ -0,0 +1,192 @@
+// Package signing implements HMAC request signing, an alternative to sending the
+// API key as a bearer token.
+//
+// A signed request carries three headers:
+//
+//	Authorization: DDNS-HMAC-SHA256 Credential={keyId}, Signature={hex}
+//	X-Ddns-Timestamp: 2025-01-15T10:30:00Z
+//	X-Ddns-Nonce: {random hex}
+//
+// The signature is the hex HMAC-SHA256 of StringToSign, keyed with the key's
+// signing secret. The secret is generated with the key and shown to the owner
+// once, like the key itself. It is unrelated to the key hash the server stores
+// for bearer authentication, so reading that hash isn't enough to sign requests;
+// the server keeps the secret encrypted with a SecretBox.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Scheme is the Authorization scheme for signed requests.
+	Scheme = "DDNS-HMAC-SHA256"
+
+	// TimestampHeader carries the time the request was signed, in RFC 3339.
+	TimestampHeader = "X-Ddns-Timestamp"
+	// NonceHeader carries a random value that may only be used once per key.
+	NonceHeader = "X-Ddns-Nonce"
+
+	// MaxClockSkew is how far the signed timestamp may be from the server's clock.
+	MaxClockSkew = 5 * time.Minute
+
+	// NonceBytes is the number of random bytes in a generated nonce.
+	NonceBytes = 16
+	// MinNonceLength and MaxNonceLength bound the nonce the server accepts.
+	MinNonceLength = 16
+	MaxNonceLength = 128
+
+	// SecretPrefix starts every signing secret, so it can't be mistaken for an API key.
+	SecretPrefix = "ddns_ss_"
+	// SecretBytes is the number of random bytes in a signing secret.
+	SecretBytes = 32
+)
+
+// Errors returned by ParseAuthorization and CheckTimestamp.
+var (
+	ErrMalformedAuthorization = errors.New("malformed signature authorization header")
+	ErrMissingTimestamp       = errors.New("missing signature timestamp")
+	ErrInvalidTimestamp       = errors.New("invalid signature timestamp")
+	ErrClockSkew              = errors.New("signature timestamp outside the allowed clock skew")
+	ErrInvalidNonce           = errors.New("missing or invalid signature nonce")
+)
+
+// Credentials are the parsed values of a signed request's Authorization header.
+type Credentials struct {
+	KeyID     string
+	Signature string
+}
+
+// IsSigned reports whether an Authorization header uses the signing scheme.
+func IsSigned(authHeader string) bool {
+	scheme, _, _ := strings.Cut(authHeader, " ")
+	return scheme == Scheme
+}
+
+// FormatAuthorization builds the Authorization header for a signed request.
+func FormatAuthorization(keyID, signature string) string {
+	return fmt.Sprintf("%s Credential=%s, Signature=%s", Scheme, keyID, signature)
+}
+
+// ParseAuthorization extracts the key ID and signature from an Authorization header.
+func ParseAuthorization(authHeader string) (Credentials, error) {
+	scheme, params, _ := strings.Cut(authHeader, " ")
+	if scheme != Scheme {
+		return Credentials{}, ErrMalformedAuthorization
+	}
+
+	var creds Credentials
+	for _, param := range strings.Split(params, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			return Credentials{}, ErrMalformedAuthorization
+		}
+		switch name {
+		case "Credential":
+			creds.KeyID = value
+		case "Signature":
+			creds.Signature = value
+		}
+	}
+
+	if creds.KeyID == "" || creds.Signature == "" {
+		return Credentials{}, ErrMalformedAuthorization
+	}
+	return creds, nil
+}
+
+// StringToSign returns the canonical string covered by the signature.
+func StringToSign(method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		Scheme,
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// GenerateSecret returns a new random signing secret of the form ddns_ss_{secret}.
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate signing secret: %w", err)
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Sign returns the hex HMAC-SHA256 of stringToSign keyed with secret.
+func Sign(secret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for stringToSign, in constant time.
+func Verify(secret, stringToSign, signature string) bool {
+	expected := Sign(secret, stringToSign)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// CheckTimestamp parses an RFC 3339 timestamp and checks it is within
+// MaxClockSkew of now.
+func CheckTimestamp(timestamp string, now time.Time) (time.Time, error) {
+	if timestamp == "" {
+		return time.Time{}, ErrMissingTimestamp
+	}
+	signedAt, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	skew := now.Sub(signedAt)
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return time.Time{}, ErrClockSkew
+	}
+	return signedAt, nil
+}
+
+// CheckNonce validates the nonce's length.
+func CheckNonce(nonce string) error {
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		return ErrInvalidNonce
+	}
+	return nil
+}
+
+// GenerateNonce returns a random hex nonce.
+func GenerateNonce() (string, error) {
+	b := make([]byte, NonceBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SignRequest signs req for keyID with the key's signing secret. path is the API
+// path the server routes on (e.g. "/update"), which may differ from
+// req.URL.Path when the API is served under a prefix.
+func SignRequest(req *http.Request, path, keyID, secret string, body []byte, now time.Time) error {
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return err
+	}
+	timestamp := now.UTC().Format(time.RFC3339)
+
+	signature := Sign(secret, StringToSign(req.Method, path, timestamp, nonce, body))
+
+	req.Header.Set("Authorization", FormatAuthorization(keyID, signature))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonce)
+	return nil
+}
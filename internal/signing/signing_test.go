@@ -0,0 +1,151 @@
+package signing
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+const testSecret = "ddns_ss_ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopq"
+
+func TestParseAuthorization(t *testing.T) {
+	testCases := []struct {
+		name        string
+		header      string
+		expected    Credentials
+		expectedErr error
+	}{
+		{
+			name:     "valid",
+			header:   "DDNS-HMAC-SHA256 Credential=0123456789ab, Signature=deadbeef",
+			expected: Credentials{KeyID: "0123456789ab", Signature: "deadbeef"},
+		},
+		{
+			name:     "no space after comma",
+			header:   "DDNS-HMAC-SHA256 Credential=0123456789ab,Signature=deadbeef",
+			expected: Credentials{KeyID: "0123456789ab", Signature: "deadbeef"},
+		},
+		{
+			name:        "bearer scheme",
+			header:      "Bearer ddns_sk_abc",
+			expectedErr: ErrMalformedAuthorization,
+		},
+		{
+			name:        "missing signature",
+			header:      "DDNS-HMAC-SHA256 Credential=0123456789ab",
+			expectedErr: ErrMalformedAuthorization,
+		},
+		{
+			name:        "parameter without value",
+			header:      "DDNS-HMAC-SHA256 Credential",
+			expectedErr: ErrMalformedAuthorization,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			creds, err := ParseAuthorization(tc.header)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expected, creds)
+		})
+	}
+}
+
+func TestStringToSign(t *testing.T) {
+	s := StringToSign("post", "/update", "2025-01-15T10:30:00Z", "00112233445566778899aabbccddeeff", []byte(`{"location":"home"}`))
+
+	lines := strings.Split(s, "\n")
+	assert.Equal(t, 6, len(lines))
+	assert.Equal(t, Scheme, lines[0])
+	assert.Equal(t, "POST", lines[1])
+	assert.Equal(t, "/update", lines[2])
+	assert.Equal(t, "2025-01-15T10:30:00Z", lines[3])
+	assert.Equal(t, "00112233445566778899aabbccddeeff", lines[4])
+	assert.Equal(t, 64, len(lines[5]))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	secret := testSecret
+	s := StringToSign("POST", "/update", "2025-01-15T10:30:00Z", "00112233445566778899aabbccddeeff", []byte("{}"))
+	signature := Sign(secret, s)
+
+	assert.Assert(t, Verify(secret, s, signature))
+	assert.Assert(t, !Verify(secret, s+"x", signature), "changed request should not verify")
+	assert.Assert(t, !Verify("ddns_ss_other", s, signature), "other secret should not verify")
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		timestamp   string
+		expectedErr error
+	}{
+		{name: "exact", timestamp: "2025-01-15T10:30:00Z"},
+		{name: "within skew behind", timestamp: "2025-01-15T10:26:00Z"},
+		{name: "within skew ahead", timestamp: "2025-01-15T10:34:00Z"},
+		{name: "too old", timestamp: "2025-01-15T10:24:59Z", expectedErr: ErrClockSkew},
+		{name: "too far ahead", timestamp: "2025-01-15T10:35:01Z", expectedErr: ErrClockSkew},
+		{name: "missing", timestamp: "", expectedErr: ErrMissingTimestamp},
+		{name: "not RFC 3339", timestamp: "1736937000", expectedErr: ErrInvalidTimestamp},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CheckTimestamp(tc.timestamp, now)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestCheckNonce(t *testing.T) {
+	assert.NilError(t, CheckNonce("00112233445566778899aabbccddeeff"))
+	assert.Equal(t, ErrInvalidNonce, CheckNonce(""))
+	assert.Equal(t, ErrInvalidNonce, CheckNonce("short"))
+	assert.Equal(t, ErrInvalidNonce, CheckNonce(strings.Repeat("a", MaxNonceLength+1)))
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`{"location":"home"}`)
+	req, err := http.NewRequest(http.MethodPost, "https://ddns.example.com/prefix/update", nil)
+	assert.NilError(t, err)
+
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	assert.NilError(t, SignRequest(req, "/update", "0123456789ab", testSecret, body, now))
+
+	creds, err := ParseAuthorization(req.Header.Get("Authorization"))
+	assert.NilError(t, err)
+	assert.Equal(t, "0123456789ab", creds.KeyID)
+	assert.Equal(t, "2025-01-15T10:30:00Z", req.Header.Get(TimestampHeader))
+
+	nonce := req.Header.Get(NonceHeader)
+	assert.NilError(t, CheckNonce(nonce))
+
+	// The server verifies the path it routes on, not the full URL path
+	s := StringToSign(http.MethodPost, "/update", req.Header.Get(TimestampHeader), nonce, body)
+	assert.Assert(t, Verify(testSecret, s, creds.Signature))
+	assert.Assert(t, !strings.Contains(req.Header.Get("Authorization"), testSecret), "secret must not be sent")
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NilError(t, err)
+	other, err := GenerateSecret()
+	assert.NilError(t, err)
+
+	assert.Assert(t, strings.HasPrefix(secret, SecretPrefix))
+	assert.Equal(t, len(SecretPrefix)+43, len(secret))
+	assert.Assert(t, secret != other)
+}
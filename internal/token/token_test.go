@@ -0,0 +1,114 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestNewSigner(t *testing.T) {
+	_, err := NewSigner("too-short")
+	assert.Equal(t, ErrSecretTooShort, err)
+
+	_, err = NewSigner(testSecret)
+	assert.NilError(t, err)
+}
+
+func TestSigner_Verify(t *testing.T) {
+	signer, err := NewSigner(testSecret)
+	assert.NilError(t, err)
+	other, err := NewSigner(testSecret + "-other")
+	assert.NilError(t, err)
+
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	token := signer.Sign(PurposeVerifyEmail, "test-owner", "key-hash", now.Add(time.Hour))
+
+	testCases := []struct {
+		name        string
+		signer      *Signer
+		token       string
+		purpose     string
+		subject     string
+		binding     string
+		now         time.Time
+		expectedErr error
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name:        "expired",
+			now:         now.Add(time.Hour),
+			expectedErr: ErrExpired,
+		},
+		{
+			name:        "other purpose",
+			purpose:     "other",
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "other subject",
+			subject:     "other-owner",
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "binding changed",
+			binding:     "new-key-hash",
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "other secret",
+			signer:      other,
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "tampered payload",
+			token:       "eyJwIjoidmVyaWZ5LWVtYWlsIiwicyI6InRlc3Qtb3duZXIiLCJlIjo5OTk5OTk5OTk5fQ" + token[len(token)-44:],
+			expectedErr: ErrInvalid,
+		},
+		{
+			name:        "malformed",
+			token:       "not-a-token",
+			expectedErr: ErrInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := signer
+			if tc.signer != nil {
+				s = tc.signer
+			}
+			tok := token
+			if tc.token != "" {
+				tok = tc.token
+			}
+			purpose := PurposeVerifyEmail
+			if tc.purpose != "" {
+				purpose = tc.purpose
+			}
+			subject := "test-owner"
+			if tc.subject != "" {
+				subject = tc.subject
+			}
+			binding := "key-hash"
+			if tc.binding != "" {
+				binding = tc.binding
+			}
+			verifyAt := now
+			if !tc.now.IsZero() {
+				verifyAt = tc.now
+			}
+
+			err := s.Verify(tok, purpose, subject, binding, verifyAt)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
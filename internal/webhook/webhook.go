@@ -0,0 +1,240 @@
+// Package webhook sends signed event notifications to owners' HTTPS endpoints.
+//
+// Each delivery is a POST of a JSON Payload with these headers:
+//
+//	X-Ddns-Event: ip.changed
+//	X-Ddns-Delivery: {deliveryId}
+//	X-Ddns-Timestamp: 1767225600
+//	X-Ddns-Signature: sha256={hex}
+//
+// The signature is the hex HMAC-SHA256 of "{timestamp}.{body}", keyed with the
+// webhook's secret. Receivers should recompute it, compare in constant time and
+// reject old timestamps.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/grocky/ddns-service/internal/domain"
+)
+
+const (
+	// EventHeader carries the event name.
+	EventHeader = "X-Ddns-Event"
+	// DeliveryHeader carries the delivery ID, which is the same for every attempt.
+	DeliveryHeader = "X-Ddns-Delivery"
+	// TimestampHeader carries the time of the attempt in Unix seconds.
+	TimestampHeader = "X-Ddns-Timestamp"
+	// SignatureHeader carries the payload signature.
+	SignatureHeader = "X-Ddns-Signature"
+
+	// SignaturePrefix prefixes the hex signature.
+	SignaturePrefix = "sha256="
+
+	// SecretPrefix makes webhook secrets recognizable.
+	SecretPrefix = "whsec_"
+
+	// DefaultTimeout bounds a delivery attempt. Deliveries are attempted while
+	// handling the request that caused the event, so it is kept short.
+	DefaultTimeout = 3 * time.Second
+
+	// UserAgent identifies the service to receivers.
+	UserAgent = "ddns-service-webhook/1"
+
+	// deliveryIDTimeFormat prefixes delivery IDs. Unlike RFC3339Nano it keeps
+	// trailing zeros, so delivery IDs sort in the order the events happened.
+	deliveryIDTimeFormat = "2006-01-02T15:04:05.000000000Z"
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	DeliveryID string    `json:"id"`
+	Event      string    `json:"event"`
+	OwnerID    string    `json:"ownerId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Data       any       `json:"data"`
+}
+
+// IPChangedData is the data of an ip.changed event. PreviousIP is empty for a
+// new location.
+type IPChangedData struct {
+	Location   string `json:"location"`
+	Subdomain  string `json:"subdomain"`
+	PreviousIP string `json:"previousIp,omitempty"`
+	IP         string `json:"ip"`
+}
+
+// ACMEChallengeData is the data of acme.created and acme.deleted events.
+type ACMEChallengeData struct {
+	Location  string     `json:"location"`
+	Subdomain string     `json:"subdomain"`
+	TxtRecord string     `json:"txtRecord"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Delivery is one attempt to send a payload to a webhook.
+type Delivery struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Service defines the interface for sending webhook deliveries.
+type Service interface {
+	// Send POSTs a delivery and returns the endpoint's status code, or zero if it
+	// didn't respond. Statuses other than 2xx are returned as errors.
+	Send(ctx context.Context, delivery Delivery) (int, error)
+}
+
+// HTTPClient defines the interface for the HTTP operations we use.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// HTTPService implements Service over HTTP.
+type HTTPService struct {
+	client HTTPClient
+	logger *slog.Logger
+}
+
+// NewHTTPService creates a new webhook service. The client should not follow
+// redirects, so a delivery can't be sent somewhere the owner didn't register.
+func NewHTTPService(client HTTPClient, logger *slog.Logger) *HTTPService {
+	return &HTTPService{
+		client: client,
+		logger: logger,
+	}
+}
+
+// ErrBlockedAddress is returned when a webhook host resolves to an address
+// deliveries may not be sent to.
+var ErrBlockedAddress = errors.New("webhook address not allowed")
+
+// NewHTTPClient returns an HTTP client suitable for NewHTTPService. It refuses
+// to connect to addresses domain.IsPublicAddress rejects, checking the address
+// each connection is made to, so a hostname can't be pointed at an internal
+// service after the webhook is registered.
+func NewHTTPClient() *http.Client {
+	return newHTTPClient(checkAddress)
+}
+
+// newHTTPClient returns a client that calls control before each connection.
+func newHTTPClient(control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DefaultTimeout,
+		Control: control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Through a proxy, control would only see the proxy's address
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   DefaultTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkAddress rejects connections to addresses that aren't public.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	if !domain.IsPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// Send POSTs a signed delivery.
+func (s *HTTPService) Send(ctx context.Context, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+
+	timestamp := time.Now().UTC()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.DeliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(SignatureHeader, SignaturePrefix+Sign(delivery.Secret, timestamp, delivery.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.logger.Warn("webhook delivery failed", "error", err, "deliveryId", delivery.DeliveryID)
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		s.logger.Warn("webhook delivery rejected", "status", resp.StatusCode, "deliveryId", delivery.DeliveryID)
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	s.logger.Info("webhook delivered", "status", resp.StatusCode, "deliveryId", delivery.DeliveryID, "event", delivery.Event)
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "{unix timestamp}.{body}" keyed with secret.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a new random webhook secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateID returns a new random 12 character webhook ID.
+func GenerateID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewDeliveryID returns a delivery ID for an event at now. IDs sort by time.
+func NewDeliveryID(now time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return now.UTC().Format(deliveryIDTimeFormat) + "-" + hex.EncodeToString(b), nil
+}
+
+// Ensure HTTPService implements Service.
+var _ Service = (*HTTPService)(nil)
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// mockHTTPClient is a mock implementation of HTTPClient for testing.
+type mockHTTPClient struct {
+	doFunc func(req *http.Request) (*http.Response, error)
+}
+
+func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return m.doFunc(req)
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+func testDelivery(url string) Delivery {
+	return Delivery{
+		URL:        url,
+		Secret:     "whsec_test",
+		Event:      "ip.changed",
+		DeliveryID: "2026-03-01T12:00:00.000000000Z-01234567",
+		Body:       []byte(`{"event":"ip.changed"}`),
+	}
+}
+
+func TestSign(t *testing.T) {
+	timestamp := time.Unix(1767225600, 0)
+
+	signature := Sign("whsec_test", timestamp, []byte(`{"event":"ip.changed"}`))
+
+	assert.Equal(t, 64, len(signature))
+	assert.Equal(t, signature, Sign("whsec_test", timestamp, []byte(`{"event":"ip.changed"}`)))
+	assert.Assert(t, signature != Sign("whsec_other", timestamp, []byte(`{"event":"ip.changed"}`)))
+	assert.Assert(t, signature != Sign("whsec_test", timestamp.Add(time.Second), []byte(`{"event":"ip.changed"}`)))
+	assert.Assert(t, signature != Sign("whsec_test", timestamp, []byte(`{"event":"acme.created"}`)))
+}
+
+func TestHTTPService_Send(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	svc := NewHTTPService(server.Client(), newTestLogger())
+	delivery := testDelivery(server.URL + "/hook")
+
+	status, err := svc.Send(context.Background(), delivery)
+
+	assert.NilError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "/hook", received.URL.Path)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, "ip.changed", received.Header.Get(EventHeader))
+	assert.Equal(t, delivery.DeliveryID, received.Header.Get(DeliveryHeader))
+	assert.Equal(t, string(delivery.Body), string(body))
+
+	unix, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	assert.NilError(t, err)
+	expected := SignaturePrefix + Sign(delivery.Secret, time.Unix(unix, 0), body)
+	assert.Equal(t, expected, received.Header.Get(SignatureHeader))
+}
+
+func TestHTTPService_Send_Rejected(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	svc := NewHTTPService(server.Client(), newTestLogger())
+
+	status, err := svc.Send(context.Background(), testDelivery(server.URL))
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.ErrorContains(t, err, "unexpected status 503")
+}
+
+func TestHTTPService_Send_DoesNotFollowRedirects(t *testing.T) {
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// The test server is on loopback, which NewHTTPClient refuses
+	svc := NewHTTPService(newHTTPClient(nil), newTestLogger())
+
+	status, err := svc.Send(context.Background(), testDelivery(server.URL))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.Assert(t, err != nil)
+	assert.Assert(t, !redirected)
+}
+
+func TestHTTPService_Send_BlockedHostname(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	// A hostname passes registration, but resolves to loopback when delivering
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	svc := NewHTTPService(NewHTTPClient(), newTestLogger())
+
+	status, err := svc.Send(context.Background(), testDelivery("http://localhost:"+port+"/hook"))
+
+	assert.Equal(t, 0, status)
+	assert.Assert(t, errors.Is(err, ErrBlockedAddress), "expected a blocked address, got %v", err)
+	assert.Assert(t, !reached)
+}
+
+func TestCheckAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "203.0.113.50:443", allowed: true},
+		{address: "[2001:db8::1]:443", allowed: true},
+		{address: "127.0.0.1:443"},
+		{address: "10.0.0.5:443"},
+		{address: "192.168.1.10:443"},
+		{address: "169.254.169.254:80"},
+		{address: "0.0.0.0:443"},
+		{address: "100.64.0.1:443"},
+		{address: "100.127.255.254:443"},
+		{address: "[::1]:443"},
+		{address: "[::]:443"},
+		{address: "[fe80::1]:443"},
+		{address: "[fd00::1]:443"},
+		{address: "[::ffff:127.0.0.1]:443"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			err := checkAddress("tcp", tc.address, nil)
+			if tc.allowed {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, ErrBlockedAddress), "expected a blocked address, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPService_Send_ConnectionError(t *testing.T) {
+	client := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	svc := NewHTTPService(client, newTestLogger())
+
+	status, err := svc.Send(context.Background(), testDelivery("https://hooks.example.com"))
+
+	assert.Equal(t, 0, status)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(secret, SecretPrefix))
+	assert.Equal(t, len(SecretPrefix)+43, len(secret))
+}
+
+func TestGenerateID(t *testing.T) {
+	id, err := GenerateID()
+
+	assert.NilError(t, err)
+	assert.Equal(t, 12, len(id))
+}
+
+func TestNewDeliveryID(t *testing.T) {
+	earlier, err := NewDeliveryID(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	assert.NilError(t, err)
+	later, err := NewDeliveryID(time.Date(2026, 3, 1, 12, 0, 0, 100, time.UTC))
+	assert.NilError(t, err)
+
+	assert.Assert(t, strings.HasPrefix(earlier, "2026-03-01T12:00:00.000000000Z-"))
+	assert.Assert(t, earlier < later, "delivery IDs sort by time")
+}
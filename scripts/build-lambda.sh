@@ -22,10 +22,22 @@ GOOS=linux GOARCH=arm64 CGO_ENABLED=0 go build \
     -o "$DIST_DIR/bootstrap" \
     ./cmd/ddns-service-lambda
 
+# Bundle email template overrides from $EMAIL_TEMPLATES, if set. Set the
+# function's EMAIL_TEMPLATE_DIR to email-templates to use them.
+rm -rf "$DIST_DIR/email-templates"
+if [ -n "$EMAIL_TEMPLATES" ]; then
+    echo "Bundling email templates from $EMAIL_TEMPLATES..."
+    cp -R "$EMAIL_TEMPLATES" "$DIST_DIR/email-templates"
+fi
+
 # Create the deployment zip
 cd "$DIST_DIR"
 rm -f "$LAMBDA_ZIP_NAME"
-zip "$LAMBDA_ZIP_NAME" bootstrap
+if [ -d email-templates ]; then
+    zip -r "$LAMBDA_ZIP_NAME" bootstrap email-templates
+else
+    zip "$LAMBDA_ZIP_NAME" bootstrap
+fi
 
 echo "Build complete: $DIST_DIR/$LAMBDA_ZIP_NAME"
 ls -lh "$DIST_DIR/$LAMBDA_ZIP_NAME"
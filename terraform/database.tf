@@ -18,6 +18,18 @@ resource "aws_dynamodb_table" "ip_mappings" {
     type = "S"
   }
 
+  attribute {
+    name = "Subdomain"
+    type = "S"
+  }
+
+  # Used to detect subdomain collisions before publishing a new location
+  global_secondary_index {
+    name            = "SubdomainIndex"
+    hash_key        = "Subdomain"
+    projection_type = "ALL"
+  }
+
   ttl {
     attribute_name = "TimeToExist"
     enabled        = false
@@ -40,6 +52,38 @@ resource "aws_dynamodb_table" "owners" {
     type = "S"
   }
 
+  attribute {
+    name = "ApiKeyId"
+    type = "S"
+  }
+
+  attribute {
+    name = "PreviousApiKeyId"
+    type = "S"
+  }
+
+  # Resolves the owner from the key ID embedded in a primary API key
+  global_secondary_index {
+    name            = "ApiKeyIdIndex"
+    hash_key        = "ApiKeyId"
+    projection_type = "KEYS_ONLY"
+  }
+
+  # Resolves the owner from a primary key replaced by rotation, during its
+  # grace period
+  global_secondary_index {
+    name            = "PreviousApiKeyIdIndex"
+    hash_key        = "PreviousApiKeyId"
+    projection_type = "KEYS_ONLY"
+  }
+
+  # Deletes owners that never verified their email address; verified owners
+  # have no TTL
+  ttl {
+    attribute_name = "TTL"
+    enabled        = true
+  }
+
   tags = {
     Name        = "DdnsServiceOwners"
     Environment = var.environment
@@ -74,3 +118,264 @@ resource "aws_dynamodb_table" "acme_challenges" {
     Application = "ddns-service"
   }
 }
+
+resource "aws_dynamodb_table" "record_sets" {
+  name         = "DdnsServiceRecordSets"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "OwnerId"
+  range_key    = "RecordKey"
+
+  attribute {
+    name = "OwnerId"
+    type = "S"
+  }
+
+  attribute {
+    name = "RecordKey"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "DdnsServiceRecordSets"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+resource "aws_dynamodb_table" "api_keys" {
+  name         = "DdnsServiceApiKeys"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "OwnerId"
+  range_key    = "KeyId"
+
+  attribute {
+    name = "OwnerId"
+    type = "S"
+  }
+
+  attribute {
+    name = "KeyId"
+    type = "S"
+  }
+
+  # Resolves the owner from the key ID embedded in an additional API key
+  global_secondary_index {
+    name            = "KeyIdIndex"
+    hash_key        = "KeyId"
+    projection_type = "KEYS_ONLY"
+  }
+
+  tags = {
+    Name        = "DdnsServiceApiKeys"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Nonces of signed requests, kept until the signature's timestamp falls outside
+# the clock-skew window so a captured request can't be replayed
+resource "aws_dynamodb_table" "nonces" {
+  name         = "DdnsServiceNonces"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "Nonce"
+
+  attribute {
+    name = "Nonce"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "TTL"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "DdnsServiceNonces"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Subdomains claimed by owner locations, keyed by subdomain. A location claims
+# its subdomain with a conditional write before publishing it, so two locations
+# are never given the same one. Claims are kept after a location is deleted.
+resource "aws_dynamodb_table" "subdomains" {
+  name         = "DdnsServiceSubdomains"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "Subdomain"
+
+  attribute {
+    name = "Subdomain"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "DdnsServiceSubdomains"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Operators allowed to use the /admin endpoints, keyed by the ID embedded in
+# their operator key. Only the key's hash is stored.
+resource "aws_dynamodb_table" "operators" {
+  name         = "DdnsServiceOperators"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "KeyId"
+
+  attribute {
+    name = "KeyId"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "DdnsServiceOperators"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Audit log of operator actions, partitioned by UTC day
+resource "aws_dynamodb_table" "audit_log" {
+  name         = "DdnsServiceAuditLog"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "AuditDate"
+  range_key    = "EntryId"
+
+  attribute {
+    name = "AuditDate"
+    type = "S"
+  }
+
+  attribute {
+    name = "EntryId"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "DdnsServiceAuditLog"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Recent failed authentication attempts per source IP and per owner, used to
+# lock out brute-force guessing. Records expire once they can't lock anyone out.
+resource "aws_dynamodb_table" "auth_failures" {
+  name         = "DdnsServiceAuthFailures"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "Subject"
+
+  attribute {
+    name = "Subject"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "TTL"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "DdnsServiceAuthFailures"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Named rate limit policies for IP changes. Owners without a plan use the
+# "default" plan, which falls back to a built-in policy until one is stored.
+resource "aws_dynamodb_table" "rate_limit_plans" {
+  name         = "DdnsServiceRateLimitPlans"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "PlanName"
+
+  attribute {
+    name = "PlanName"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "DdnsServiceRateLimitPlans"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Per-route request limit buckets keyed by route, subject kind and subject, e.g.
+# "update#ip#203.0.113.50". Records expire once their bucket has refilled.
+resource "aws_dynamodb_table" "request_limits" {
+  name         = "DdnsServiceRequestLimits"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "Bucket"
+
+  attribute {
+    name = "Bucket"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "TTL"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "DdnsServiceRequestLimits"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Owners' event webhooks, including the secrets their payloads are signed with.
+resource "aws_dynamodb_table" "webhooks" {
+  name         = "DdnsServiceWebhooks"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "OwnerId"
+  range_key    = "WebhookId"
+
+  attribute {
+    name = "OwnerId"
+    type = "S"
+  }
+
+  attribute {
+    name = "WebhookId"
+    type = "S"
+  }
+
+  tags = {
+    Name        = "DdnsServiceWebhooks"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+# Webhook delivery history. Delivery IDs sort by time, and records expire
+# 30 days after the event.
+resource "aws_dynamodb_table" "webhook_deliveries" {
+  name         = "DdnsServiceWebhookDeliveries"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "WebhookId"
+  range_key    = "DeliveryId"
+
+  attribute {
+    name = "WebhookId"
+    type = "S"
+  }
+
+  attribute {
+    name = "DeliveryId"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "TTL"
+    enabled        = true
+  }
+
+  tags = {
+    Name        = "DdnsServiceWebhookDeliveries"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
@@ -51,8 +51,22 @@ resource "aws_iam_role_policy" "dynamodb" {
         ]
         Resource = [
           aws_dynamodb_table.ip_mappings.arn,
+          "${aws_dynamodb_table.ip_mappings.arn}/index/*",
           aws_dynamodb_table.owners.arn,
-          aws_dynamodb_table.acme_challenges.arn
+          "${aws_dynamodb_table.owners.arn}/index/*",
+          aws_dynamodb_table.acme_challenges.arn,
+          aws_dynamodb_table.record_sets.arn,
+          aws_dynamodb_table.api_keys.arn,
+          "${aws_dynamodb_table.api_keys.arn}/index/*",
+          aws_dynamodb_table.nonces.arn,
+          aws_dynamodb_table.subdomains.arn,
+          aws_dynamodb_table.operators.arn,
+          aws_dynamodb_table.audit_log.arn,
+          aws_dynamodb_table.auth_failures.arn,
+          aws_dynamodb_table.rate_limit_plans.arn,
+          aws_dynamodb_table.request_limits.arn,
+          aws_dynamodb_table.webhooks.arn,
+          aws_dynamodb_table.webhook_deliveries.arn
         ]
       }
     ]
@@ -126,6 +140,20 @@ resource "aws_cloudwatch_log_group" "lambda" {
   }
 }
 
+# Signs email verification and key recovery tokens. Rotating it invalidates
+# outstanding tokens.
+resource "random_password" "token_signing_secret" {
+  length  = 64
+  special = false
+}
+
+# Encrypts the request signing secrets stored with API keys. Rotating it stops
+# every stored signing secret from working until its key is rotated.
+resource "random_password" "signing_secret_key" {
+  length  = 64
+  special = false
+}
+
 # =============================================================================
 # Lambda Function
 # =============================================================================
@@ -145,8 +173,26 @@ resource "aws_lambda_function" "ddns_service" {
 
   environment {
     variables = {
-      ENVIRONMENT            = var.environment
-      ROUTE53_HOSTED_ZONE_ID = aws_route53_zone.ddns.zone_id
+      ENVIRONMENT               = var.environment
+      ROUTE53_HOSTED_ZONE_ID    = aws_route53_zone.ddns.zone_id
+      SUBDOMAIN_HASH_ALGORITHM  = var.subdomain_hash_algorithm
+      SUBDOMAIN_HASH_LENGTH     = tostring(var.subdomain_hash_length)
+      TOKEN_SIGNING_SECRET      = random_password.token_signing_secret.result
+      SIGNING_SECRET_KEY        = random_password.signing_secret_key.result
+      OWNER_VERIFICATION_WINDOW = var.owner_verification_window
+      KEY_ROTATION_GRACE_PERIOD = var.key_rotation_grace_period
+      REQUEST_LIMITS            = var.request_limits
+      ACME_CHALLENGE_LIMIT      = tostring(var.acme_challenge_limit)
+      ACME_CHALLENGE_WINDOW     = var.acme_challenge_window
+      EMAIL_PROVIDER            = var.email_provider
+      EMAIL_SENDER              = var.email_sender
+      EMAIL_TEMPLATE_DIR        = var.email_template_dir
+      SMTP_HOST                 = var.smtp_host
+      SMTP_PORT                 = var.smtp_port
+      SMTP_SECURITY             = var.smtp_security
+      SMTP_AUTH                 = var.smtp_auth
+      SMTP_USERNAME             = var.smtp_username
+      SMTP_PASSWORD             = var.smtp_password
     }
   }
 
@@ -208,3 +254,178 @@ resource "aws_lambda_permission" "eventbridge_acme_cleanup" {
   principal     = "events.amazonaws.com"
   source_arn    = aws_cloudwatch_event_rule.acme_cleanup.arn
 }
+
+# =============================================================================
+# API Key Expiry Warnings (EventBridge Scheduled Rule)
+# =============================================================================
+
+resource "aws_cloudwatch_event_rule" "key_expiry" {
+  name                = "ddns-key-expiry-warnings"
+  description         = "Daily email to owners whose API keys expire soon"
+  schedule_expression = "cron(0 3 * * ? *)" # Daily at 3 AM UTC
+
+  tags = {
+    Name        = "ddns-key-expiry-${var.environment}"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+resource "aws_cloudwatch_event_target" "key_expiry" {
+  rule      = aws_cloudwatch_event_rule.key_expiry.name
+  target_id = "ddns-key-expiry-lambda"
+  arn       = aws_lambda_function.ddns_service.arn
+
+  input = jsonencode({
+    source = "ddns.key-expiry"
+    action = "send-key-expiry-warnings"
+  })
+}
+
+resource "aws_lambda_permission" "eventbridge_key_expiry" {
+  statement_id  = "AllowEventBridgeKeyExpiry"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.ddns_service.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.key_expiry.arn
+}
+
+# =============================================================================
+# Authentication Failure Alerts (EventBridge Scheduled Rule)
+# =============================================================================
+
+resource "aws_cloudwatch_event_rule" "auth_failure_alerts" {
+  name                = "ddns-auth-failure-alerts"
+  description         = "Email owners whose keys are being guessed"
+  schedule_expression = "rate(15 minutes)"
+
+  tags = {
+    Name        = "ddns-auth-failure-alerts-${var.environment}"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+resource "aws_cloudwatch_event_target" "auth_failure_alerts" {
+  rule      = aws_cloudwatch_event_rule.auth_failure_alerts.name
+  target_id = "ddns-auth-failure-alerts-lambda"
+  arn       = aws_lambda_function.ddns_service.arn
+
+  input = jsonencode({
+    source = "ddns.auth-failure-alerts"
+    action = "send-auth-failure-alerts"
+  })
+}
+
+resource "aws_lambda_permission" "eventbridge_auth_failure_alerts" {
+  statement_id  = "AllowEventBridgeAuthFailureAlerts"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.ddns_service.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.auth_failure_alerts.arn
+}
+
+# =============================================================================
+# IP Change Digests (EventBridge Scheduled Rule)
+# =============================================================================
+
+resource "aws_cloudwatch_event_rule" "ip_change_digest" {
+  name                = "ddns-ip-change-digest"
+  description         = "Email owners digests of their locations' IP changes"
+  schedule_expression = "rate(15 minutes)"
+
+  tags = {
+    Name        = "ddns-ip-change-digest-${var.environment}"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+resource "aws_cloudwatch_event_target" "ip_change_digest" {
+  rule      = aws_cloudwatch_event_rule.ip_change_digest.name
+  target_id = "ddns-ip-change-digest-lambda"
+  arn       = aws_lambda_function.ddns_service.arn
+
+  input = jsonencode({
+    source = "ddns.ip-change-digest"
+    action = "send-ip-change-digests"
+  })
+}
+
+resource "aws_lambda_permission" "eventbridge_ip_change_digest" {
+  statement_id  = "AllowEventBridgeIPChangeDigest"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.ddns_service.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.ip_change_digest.arn
+}
+
+# =============================================================================
+# Webhook Delivery Retries (EventBridge Scheduled Rule)
+# =============================================================================
+
+resource "aws_cloudwatch_event_rule" "webhook_retries" {
+  name                = "ddns-webhook-retries"
+  description         = "Retry failed webhook deliveries"
+  schedule_expression = "rate(5 minutes)"
+
+  tags = {
+    Name        = "ddns-webhook-retries-${var.environment}"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+resource "aws_cloudwatch_event_target" "webhook_retries" {
+  rule      = aws_cloudwatch_event_rule.webhook_retries.name
+  target_id = "ddns-webhook-retries-lambda"
+  arn       = aws_lambda_function.ddns_service.arn
+
+  input = jsonencode({
+    source = "ddns.webhook-retries"
+    action = "retry-webhook-deliveries"
+  })
+}
+
+resource "aws_lambda_permission" "eventbridge_webhook_retries" {
+  statement_id  = "AllowEventBridgeWebhookRetries"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.ddns_service.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.webhook_retries.arn
+}
+
+# =============================================================================
+# Stale Location Check (EventBridge Scheduled Rule)
+# =============================================================================
+
+resource "aws_cloudwatch_event_rule" "stale_locations" {
+  name                = "ddns-stale-locations"
+  description         = "Email owners about locations that stopped checking in"
+  schedule_expression = "rate(15 minutes)"
+
+  tags = {
+    Name        = "ddns-stale-locations-${var.environment}"
+    Environment = var.environment
+    Application = "ddns-service"
+  }
+}
+
+resource "aws_cloudwatch_event_target" "stale_locations" {
+  rule      = aws_cloudwatch_event_rule.stale_locations.name
+  target_id = "ddns-stale-locations-lambda"
+  arn       = aws_lambda_function.ddns_service.arn
+
+  input = jsonencode({
+    source = "ddns.stale-locations"
+    action = "check-stale-locations"
+  })
+}
+
+resource "aws_lambda_permission" "eventbridge_stale_locations" {
+  statement_id  = "AllowEventBridgeStaleLocations"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.ddns_service.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.stale_locations.arn
+}
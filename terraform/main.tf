@@ -6,6 +6,10 @@ terraform {
       source  = "hashicorp/aws"
       version = "~> 5.0"
     }
+    random = {
+      source  = "hashicorp/random"
+      version = "~> 3.0"
+    }
   }
 
   backend "s3" {
@@ -31,6 +35,103 @@ variable "environment" {
   default     = "prod"
 }
 
+variable "subdomain_hash_algorithm" {
+  description = "Hash used to derive new location subdomains (md5 or sha256)"
+  type        = string
+  default     = "md5"
+}
+
+variable "subdomain_hash_length" {
+  description = "Number of hex characters kept from the subdomain hash"
+  type        = number
+  default     = 8
+}
+
+variable "owner_verification_window" {
+  description = "How long a new owner has to verify their email address (Go duration)"
+  type        = string
+  default     = "24h"
+}
+
+variable "key_rotation_grace_period" {
+  description = "How long a rotated-out API key keeps working (Go duration, at most 168h)"
+  type        = string
+  default     = "24h"
+}
+
+variable "request_limits" {
+  description = "JSON overrides of the per-route request limits (see README); empty keeps the defaults"
+  type        = string
+  default     = ""
+}
+
+variable "acme_challenge_limit" {
+  description = "ACME challenges an owner may create per acme_challenge_window; 0 turns the limit off"
+  type        = number
+  default     = 10
+}
+
+variable "acme_challenge_window" {
+  description = "Fixed window for acme_challenge_limit (Go duration, at least 1m)"
+  type        = string
+  default     = "1h"
+}
+
+variable "email_provider" {
+  description = "How emails are sent: ses or smtp"
+  type        = string
+  default     = "ses"
+}
+
+variable "email_sender" {
+  description = "From address for emails; empty keeps the default sender"
+  type        = string
+  default     = ""
+}
+
+variable "email_template_dir" {
+  description = "Directory of email template overrides in the deployment package, e.g. email-templates; empty uses the embedded templates"
+  type        = string
+  default     = ""
+}
+
+variable "smtp_host" {
+  description = "SMTP server when email_provider is smtp"
+  type        = string
+  default     = ""
+}
+
+variable "smtp_port" {
+  description = "SMTP port; empty picks 587, 465 or 25 from smtp_security"
+  type        = string
+  default     = ""
+}
+
+variable "smtp_security" {
+  description = "SMTP connection security: starttls, tls or none"
+  type        = string
+  default     = "starttls"
+}
+
+variable "smtp_auth" {
+  description = "SMTP authentication: plain or login; empty uses plain when smtp_username is set"
+  type        = string
+  default     = ""
+}
+
+variable "smtp_username" {
+  description = "SMTP username"
+  type        = string
+  default     = ""
+}
+
+variable "smtp_password" {
+  description = "SMTP password"
+  type        = string
+  default     = ""
+  sensitive   = true
+}
+
 locals {
   domain_name = "grocky.net"
 }